@@ -17,9 +17,15 @@ package main
 import (
 	"github.com/samber/lo"
 
+	"github.com/aws/karpenter-provider-aws/pkg/batcher"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers"
+	"github.com/aws/karpenter-provider-aws/pkg/iampolicy"
 	"github.com/aws/karpenter-provider-aws/pkg/operator"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/preflight"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
 	"github.com/aws/karpenter-provider-aws/pkg/webhooks"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider/metrics"
@@ -31,32 +37,62 @@ import (
 func main() {
 	ctx, op := operator.NewOperator(coreoperator.NewOperator())
 
+	// Writes to the API server are traced alongside the reconciles and AWS API calls that issue them
+	kubeClient := op.GetClient()
+	if options.FromContext(ctx).TracingEndpoint != "" {
+		kubeClient = tracing.NewClient(kubeClient)
+	}
+
 	awsCloudProvider := cloudprovider.New(
 		op.InstanceTypesProvider,
 		op.InstanceProvider,
 		op.EventRecorder,
-		op.GetClient(),
+		kubeClient,
 		op.AMIProvider,
 		op.SecurityGroupProvider,
+		op.LaunchRamps,
+		op.TerminationHookProvider,
+		op.Clock,
 	)
 	lo.Must0(op.AddHealthzCheck("cloud-provider", awsCloudProvider.LivenessProbe))
+	// The controller isn't ready while it's missing the permissions that launches need
+	lo.Must0(op.AddReadyzCheck("aws-permissions", op.PreflightChecker.ReadyzCheck))
+	// The prices that Karpenter makes decisions with are served alongside the metrics for debugging
+	lo.Must0(op.AddMetricsServerExtraHandler("/pricing", pricing.NewHandler(op.PricingProvider)))
+	// The least-privilege IAM policy of the AWS API calls that were made since startup
+	lo.Must0(op.AddMetricsServerExtraHandler("/iam-policy", iampolicy.NewHandler(op.IAMPolicyRecorder, options.FromContext(ctx).ClusterName, *op.Session.Config.Region)))
+	// The permissions of the controller are checked again when they're requested
+	lo.Must0(op.AddMetricsServerExtraHandler("/preflight", preflight.NewHandler(ctx, op.PreflightChecker)))
 	cloudProvider := metrics.Decorate(awsCloudProvider)
+	if options.FromContext(ctx).TracingEndpoint != "" {
+		cloudProvider = tracing.DecorateCloudProvider(cloudProvider)
+	}
+
+	// The NodeClaim status patches that the core controllers issue are rate limited per NodeClaim
+	nodeClaimStatusClient := batcher.NewNodeClaimStatusClient(kubeClient, op.Clock)
 
 	op.
-		WithControllers(ctx, corecontrollers.NewControllers(
+		WithControllers(ctx, controllers.NewCoreControllers(ctx, corecontrollers.NewControllers(
 			op.Clock,
-			op.GetClient(),
+			nodeClaimStatusClient,
 			op.EventRecorder,
 			cloudProvider,
-		)...).
+		))...).
 		WithWebhooks(ctx, corewebhooks.NewWebhooks()...).
 		WithControllers(ctx, controllers.NewControllers(
 			ctx,
 			op.Session,
+			op.AccountID,
 			op.Clock,
-			op.GetClient(),
+			kubeClient,
+			op.GetAPIReader(),
 			op.EventRecorder,
 			op.UnavailableOfferingsCache,
+			op.BlockedOfferingsCache,
+			op.ZoneHealth,
+			op.ZoneScores,
+			op.VPCCNI,
+			op.VCPUQuotaHeadroom,
 			cloudProvider,
 			op.SubnetProvider,
 			op.SecurityGroupProvider,
@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// instance-type-snapshot generates an instance type snapshot for clusters that can't reach the EC2 and pricing APIs.
+// It must be run with credentials that can call DescribeInstanceTypes, DescribeInstanceTypeOfferings,
+// DescribeSpotPriceHistory and pricing:GetProducts in the target region.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/iampolicy"
+	"github.com/aws/karpenter-provider-aws/pkg/operator"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
+)
+
+func main() {
+	var region, output string
+	var skipSpotPrices bool
+	flag.StringVar(&region, "region", os.Getenv("AWS_REGION"), "The region to snapshot.")
+	flag.StringVar(&output, "output", "instance-type-snapshot.json.gz", "The destination of the snapshot. The snapshot is gzip compressed if the path ends in .gz.")
+	flag.BoolVar(&skipSpotPrices, "skip-spot-prices", false, "If true, spot prices aren't included in the snapshot and default to the on-demand prices.")
+	flag.Parse()
+	if region == "" {
+		log.Fatal("region must be set with --region or AWS_REGION")
+	}
+
+	ctx := options.ToContext(context.Background(), &options.Options{})
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	ec2api := ec2.New(sess)
+	cfg := operator.NewConfigV2(ctx, sess, iampolicy.NewRecorder())
+	pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(ctx, cfg, region), operator.NewEC2Client(ctx, cfg), region, nil)
+	instanceTypeProvider := instancetype.NewDefaultProvider(region, cache.New(awscache.InstanceTypesAndZonesTTL, awscache.DefaultCleanupInterval),
+		ec2api, nil, awscache.NewUnavailableOfferings(), awscache.NewBlockedOfferings(nil), awscache.NewZoneHealth(nil), awscache.NewVPCCNI(), awscache.NewVCPUQuotaHeadroom(), pricingProvider, nil)
+
+	lo.Must0(instanceTypeProvider.UpdateInstanceTypes(ctx))
+	lo.Must0(instanceTypeProvider.UpdateInstanceTypeOfferings(ctx))
+	lo.Must0(pricingProvider.UpdateOnDemandPricing(ctx))
+	if !skipSpotPrices {
+		lo.Must0(pricingProvider.UpdateSpotPricing(ctx))
+	}
+
+	s := &snapshot.Snapshot{Region: region, CreatedAt: time.Now().UTC()}
+	instanceTypeProvider.ExportSnapshot(s)
+	pricingProvider.ExportSnapshot(s)
+	if skipSpotPrices {
+		s.SpotPrices = nil
+	}
+	lo.Must0(s.Save(output))
+	log.Printf("wrote a snapshot of %d instance types in %s to %s", len(s.InstanceTypes), region, output)
+}
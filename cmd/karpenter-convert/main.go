@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// karpenter-convert converts v1alpha5 Provisioners and v1alpha1 AWSNodeTemplates to v1beta1 NodePools and
+// EC2NodeClasses. The resources are read from files, or from the cluster of the current kubeconfig context, and the
+// converted resources are written to stdout.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/aws/karpenter-provider-aws/pkg/convert"
+)
+
+type files []string
+
+func (f *files) String() string     { return strings.Join(*f, ",") }
+func (f *files) Set(v string) error { *f = append(*f, v); return nil }
+
+func main() {
+	var filenames files
+	var fromCluster bool
+	opts := convert.Options{}
+	flag.Var(&filenames, "f", "A file of Provisioners and AWSNodeTemplates to convert, or - for stdin. May be repeated.")
+	flag.BoolVar(&fromCluster, "from-cluster", false, "If true, then the Provisioners and AWSNodeTemplates of the cluster of the current kubeconfig context are converted.")
+	flag.StringVar(&opts.InstanceProfile, "instance-profile", "", "The instance profile of AWSNodeTemplates that don't specify one, usually the former aws.defaultInstanceProfile setting.")
+	flag.StringVar(&opts.Role, "role", "$KARPENTER_NODE_ROLE", "The node role of AWSNodeTemplates that don't specify an instance profile when --instance-profile isn't set.")
+	flag.Parse()
+	if len(filenames) == 0 && !fromCluster {
+		log.Fatal("at least one of -f or --from-cluster must be set")
+	}
+
+	var provisioners []*convert.Provisioner
+	var nodeTemplates []*convert.AWSNodeTemplate
+	read := func(name string, r io.Reader) {
+		p, nt, err := convert.Decode(r)
+		if err != nil {
+			log.Fatalf("reading %s, %s", name, err)
+		}
+		provisioners = append(provisioners, p...)
+		nodeTemplates = append(nodeTemplates, nt...)
+	}
+	for _, filename := range filenames {
+		if filename == "-" {
+			read("stdin", os.Stdin)
+			continue
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			log.Fatalf("opening %s, %s", filename, err)
+		}
+		read(filename, f)
+		f.Close()
+	}
+	if fromCluster {
+		for _, gvk := range []schema.GroupVersionKind{
+			schema.FromAPIVersionAndKind(convert.ProvisionerGroupVersion, convert.ProvisionerKind+"List"),
+			schema.FromAPIVersionAndKind(convert.AWSNodeTemplateGroupVersion, convert.AWSNodeTemplateKind+"List"),
+		} {
+			list, err := listFromCluster(gvk)
+			if err != nil {
+				log.Fatalf("listing %s, %s", gvk.Kind, err)
+			}
+			read(gvk.Kind, bytes.NewReader(list))
+		}
+	}
+
+	result, err := convert.Convert(provisioners, nodeTemplates, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if err := convert.Encode(os.Stdout, result); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// listFromCluster returns the JSON list of the resources of the kind, which is empty if the CRD was already removed
+func listFromCluster(gvk schema.GroupVersionKind) ([]byte, error) {
+	kubeClient, err := client.New(config.GetConfigOrDie(), client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk)
+	if err := kubeClient.List(context.Background(), list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return json.Marshal(list)
+}
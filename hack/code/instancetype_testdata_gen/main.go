@@ -118,6 +118,13 @@ func getInstanceTypeInfo(info *ec2.InstanceTypeInfo) string {
 	fmt.Fprintf(src, "BurstablePerformanceSupported: aws.Bool(%t),\n", lo.FromPtr(info.BurstablePerformanceSupported))
 	fmt.Fprintf(src, "BareMetal: aws.Bool(%t),\n", lo.FromPtr(info.BareMetal))
 	fmt.Fprintf(src, "Hypervisor: aws.String(\"%s\"),\n", lo.FromPtr(info.Hypervisor))
+	fmt.Fprintf(src, "SupportedBootModes: aws.StringSlice([]string{%s}),\n", getStringSliceData(info.SupportedBootModes))
+	fmt.Fprintf(src, "NitroTpmSupport: aws.String(\"%s\"),\n", lo.FromPtr(info.NitroTpmSupport))
+	if info.NitroTpmInfo != nil {
+		fmt.Fprintf(src, "NitroTpmInfo: &ec2.NitroTpmInfo{\n")
+		fmt.Fprintf(src, "SupportedVersions: aws.StringSlice([]string{%s}),\n", getStringSliceData(info.NitroTpmInfo.SupportedVersions))
+		fmt.Fprintf(src, "},\n")
+	}
 	fmt.Fprintf(src, "ProcessorInfo: &ec2.ProcessorInfo{\n")
 	fmt.Fprintf(src, "Manufacturer: aws.String(\"%s\"),\n", lo.FromPtr(info.ProcessorInfo.Manufacturer))
 	fmt.Fprintf(src, "SupportedArchitectures: aws.StringSlice([]string{%s}),\n", getStringSliceData(info.ProcessorInfo.SupportedArchitectures))
@@ -165,6 +172,16 @@ func getInstanceTypeInfo(info *ec2.InstanceTypeInfo) string {
 		fmt.Fprintf(src, "},\n")
 		fmt.Fprintf(src, "},\n")
 	}
+	if info.NeuronInfo != nil {
+		fmt.Fprintf(src, "NeuronInfo: &ec2.NeuronInfo{\n")
+		fmt.Fprintf(src, "NeuronDevices: []*ec2.NeuronDeviceInfo{\n")
+		for _, elem := range info.NeuronInfo.NeuronDevices {
+			fmt.Fprintf(src, getNeuronDeviceInfo(elem))
+		}
+		fmt.Fprintf(src, "},\n")
+		fmt.Fprintf(src, "TotalNeuronDeviceMemoryInMiB: aws.Int64(%d),\n", lo.FromPtr(info.NeuronInfo.TotalNeuronDeviceMemoryInMiB))
+		fmt.Fprintf(src, "},\n")
+	}
 	if info.InstanceStorageInfo != nil {
 		fmt.Fprintf(src, "InstanceStorageInfo: &ec2.InstanceStorageInfo{")
 		fmt.Fprintf(src, "NvmeSupport: aws.String(\"%s\"),\n", lo.FromPtr(info.InstanceStorageInfo.NvmeSupport))
@@ -209,6 +226,22 @@ func getInferenceAcceleratorDeviceInfo(info *ec2.InferenceDeviceInfo) string {
 	return src.String()
 }
 
+func getNeuronDeviceInfo(info *ec2.NeuronDeviceInfo) string {
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "{\n")
+	fmt.Fprintf(src, "Name: aws.String(\"%s\"),\n", lo.FromPtr(info.Name))
+	fmt.Fprintf(src, "Count: aws.Int64(%d),\n", lo.FromPtr(info.Count))
+	fmt.Fprintf(src, "CoreInfo: &ec2.NeuronDeviceCoreInfo{\n")
+	fmt.Fprintf(src, "Count: aws.Int64(%d),\n", lo.FromPtr(info.CoreInfo.Count))
+	fmt.Fprintf(src, "Version: aws.Int64(%d),\n", lo.FromPtr(info.CoreInfo.Version))
+	fmt.Fprintf(src, "},\n")
+	fmt.Fprintf(src, "MemoryInfo: &ec2.NeuronDeviceMemoryInfo{\n")
+	fmt.Fprintf(src, "SizeInMiB: aws.Int64(%d),\n", lo.FromPtr(info.MemoryInfo.SizeInMiB))
+	fmt.Fprintf(src, "},\n")
+	fmt.Fprintf(src, "},\n")
+	return src.String()
+}
+
 func getGPUDeviceInfo(info *ec2.GpuDeviceInfo) string {
 	src := &bytes.Buffer{}
 	fmt.Fprintf(src, "{\n")
@@ -29,10 +29,11 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
-	ec22 "github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
 
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/iampolicy"
+	"github.com/aws/karpenter-provider-aws/pkg/operator"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
@@ -94,7 +95,8 @@ func main() {
 	ctx := context.Background()
 	ctx = options.ToContext(ctx, test.Options())
 	sess := session.Must(session.NewSession())
-	ec2 := ec22.New(sess)
+	cfg := operator.NewConfigV2(ctx, sess, iampolicy.NewRecorder())
+	ec2api := operator.NewEC2Client(ctx, cfg)
 	src := &bytes.Buffer{}
 	fmt.Fprintln(src, "//go:build !ignore_autogenerated")
 	license := lo.Must(os.ReadFile("hack/boilerplate.go.txt"))
@@ -106,7 +108,7 @@ func main() {
 	// record prices for each region we are interested in
 	for _, region := range getAWSRegions(opts.partition) {
 		log.Println("fetching for", region)
-		pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(sess, region), ec2, region)
+		pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(ctx, cfg, region), ec2api, region, nil)
 		controller := controllerspricing.NewController(pricingProvider)
 		_, err := controller.Reconcile(ctx)
 		if err != nil {
@@ -11,3 +11,14 @@ yq eval '.spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.kub
 # EC2NodeClass Validation:
 yq eval '.spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.kubelet.properties.evictionHard.additionalProperties.pattern = "^((\d{1,2}(\.\d{1,2})?|100(\.0{1,2})?)%||(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?)$"' -i pkg/apis/crds/karpenter.k8s.aws_ec2nodeclasses.yaml 
 yq eval '.spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.kubelet.properties.evictionSoft.additionalProperties.pattern = "^((\d{1,2}(\.\d{1,2})?|100(\.0{1,2})?)%||(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?)$"' -i pkg/apis/crds/karpenter.k8s.aws_ec2nodeclasses.yaml 
+
+# The kubelet overlays of each capacity type are validated the same way, in both versions of the EC2NodeClass
+for version in 0 1; do
+  for capacityType in spot onDemand; do
+    overlay=".spec.versions[${version}].schema.openAPIV3Schema.properties.spec.properties.kubeletOverlays.properties.${capacityType}.properties"
+    yq eval "${overlay}.kubeReserved.additionalProperties.pattern = \"^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$\"" -i pkg/apis/crds/karpenter.k8s.aws_ec2nodeclasses.yaml
+    yq eval "${overlay}.systemReserved.additionalProperties.pattern = \"^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$\"" -i pkg/apis/crds/karpenter.k8s.aws_ec2nodeclasses.yaml
+    yq eval "${overlay}.evictionHard.additionalProperties.pattern = \"^((\d{1,2}(\.\d{1,2})?|100(\.0{1,2})?)%||(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?)$\"" -i pkg/apis/crds/karpenter.k8s.aws_ec2nodeclasses.yaml
+    yq eval "${overlay}.evictionSoft.additionalProperties.pattern = \"^((\d{1,2}(\.\d{1,2})?|100(\.0{1,2})?)%||(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?)$\"" -i pkg/apis/crds/karpenter.k8s.aws_ec2nodeclasses.yaml
+  done
+done
@@ -134,11 +134,16 @@ var _ = Describe("Hash", func() {
 	},
 		Entry("UserData", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{UserData: aws.String("userdata-test-2")}}),
 		Entry("Tags", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Tags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
+		Entry("VolumeTags", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{VolumeTags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
+		Entry("FleetTags", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{FleetTags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
 		Entry("Context", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Context: aws.String("context-2")}}),
 		Entry("DetailedMonitoring", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{DetailedMonitoring: aws.Bool(true)}}),
 		Entry("AMIFamily", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{AMIFamily: aws.String(v1.AMIFamilyBottlerocket)}}),
 		Entry("InstanceStorePolicy", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{InstanceStorePolicy: lo.ToPtr(v1.InstanceStorePolicyRAID0)}}),
 		Entry("AssociatePublicIPAddress", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{AssociatePublicIPAddress: lo.ToPtr(true)}}),
+		Entry("PrimaryNetworkInterface", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{PrimaryNetworkInterface: &v1.PrimaryNetworkInterface{IPv4PrefixCount: lo.ToPtr(int64(1))}}}),
+		Entry("AdditionalNetworkInterfaces", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{AdditionalNetworkInterfaces: []v1.AdditionalNetworkInterface{{DeviceIndex: 1}}}}),
+		Entry("Bottlerocket", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Bottlerocket: &v1.BottlerocketConfiguration{Kernel: &v1.BottlerocketKernel{Lockdown: lo.ToPtr("integrity")}}}}),
 		Entry("MetadataOptions HTTPEndpoint", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPEndpoint: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPProtocolIPv6", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPProtocolIPv6: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPPutResponseHopLimit", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPPutResponseHopLimit: lo.ToPtr(int64(10))}}}),
@@ -15,6 +15,8 @@ limitations under the License.
 package v1_test
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -117,6 +119,54 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
 		})
+		It("should fail if tag keys have the aws: prefix", func() {
+			nc.Spec.Tags = map[string]string{
+				"aws:custom-key": "value",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"AWS:custom-key": "value",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail if tag keys or values exceed the EC2 length limits", func() {
+			nc.Spec.Tags = map[string]string{
+				strings.Repeat("k", v1.MaxTagKeyLength): strings.Repeat("v", v1.MaxTagValueLength),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			nc.Spec.Tags = map[string]string{
+				strings.Repeat("k", v1.MaxTagKeyLength+1): "value",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"key": strings.Repeat("v", v1.MaxTagValueLength+1),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail if tags exceed the number that can be applied alongside Karpenter's tags", func() {
+			nc.Spec.Tags = lo.SliceToMap(lo.Range(v1.MaxTags-v1.ReservedTags), func(i int) (string, string) { return fmt.Sprintf("tag-%d", i), "value" })
+			nc.Spec.Tags[v1.TagName] = "name"
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			nc.Spec.Tags = lo.SliceToMap(lo.Range(v1.MaxTags-v1.ReservedTags+1), func(i int) (string, string) { return fmt.Sprintf("tag-%d", i), "value" })
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail if volume or fleet tags contain a restricted domain key", func() {
+			nc.Spec.VolumeTags = map[string]string{
+				corev1beta1.NodePoolLabelKey: "value",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.VolumeTags = nil
+			nc.Spec.FleetTags = map[string]string{
+				"aws:custom-key": "value",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should succeed with volume and fleet tags that override tags", func() {
+			nc.Spec.Tags = map[string]string{"cost-center": "instances"}
+			nc.Spec.VolumeTags = map[string]string{"cost-center": "volumes", "backup": "true"}
+			nc.Spec.FleetTags = map[string]string{"cost-center": "fleets"}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
 	})
 	Context("SubnetSelectorTerms", func() {
 		It("should succeed with a valid subnet selector on tags", func() {
@@ -137,6 +187,32 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Succeed())
 		})
+		It("should succeed with a valid subnet selector on zone id", func() {
+			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{
+				{
+					ZoneID: "use1-az1",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a valid subnet selector on tags and zone id", func() {
+			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{
+				{
+					Tags:   map[string]string{"test": "testvalue"},
+					ZoneID: "use1-az1",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when a subnet selector term has an id and a zone id", func() {
+			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{
+				{
+					ID:     "subnet-12345749",
+					ZoneID: "use1-az1",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 		It("should fail when subnet selector terms is set to nil", func() {
 			nc.Spec.SubnetSelectorTerms = nil
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
@@ -685,6 +761,216 @@ var _ = Describe("CEL/Validation", func() {
 			})
 		})
 	})
+	Context("Bottlerocket", func() {
+		BeforeEach(func() {
+			nc.Spec.AMIFamily = &v1.AMIFamilyBottlerocket
+		})
+		It("should succeed with Bottlerocket settings", func() {
+			nc.Spec.Bottlerocket = &v1.BottlerocketConfiguration{
+				Kernel: &v1.BottlerocketKernel{
+					Lockdown: lo.ToPtr("integrity"),
+					Sysctls:  map[string]string{"net.core.somaxconn": "1024", "net/ipv4/ip_forward": "1"},
+				},
+				HostContainers: map[string]v1.BottlerocketHostContainer{"admin": {Enabled: lo.ToPtr(true), Superpowered: lo.ToPtr(true)}},
+				Updates:        &v1.BottlerocketUpdates{VersionLock: lo.ToPtr("v1.20.0"), IgnoreWaves: lo.ToPtr(true)},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when the AMIFamily isn't Bottlerocket", func() {
+			nc.Spec.AMIFamily = &v1.AMIFamilyAL2
+			nc.Spec.Bottlerocket = &v1.BottlerocketConfiguration{Kernel: &v1.BottlerocketKernel{Lockdown: lo.ToPtr("integrity")}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an unknown lockdown mode", func() {
+			nc.Spec.Bottlerocket = &v1.BottlerocketConfiguration{Kernel: &v1.BottlerocketKernel{Lockdown: lo.ToPtr("strict")}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid sysctl key", func() {
+			nc.Spec.Bottlerocket = &v1.BottlerocketConfiguration{Kernel: &v1.BottlerocketKernel{Sysctls: map[string]string{"net.core.somaxconn = 1\n": "1024"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid host container name", func() {
+			nc.Spec.Bottlerocket = &v1.BottlerocketConfiguration{HostContainers: map[string]v1.BottlerocketHostContainer{"Admin": {Enabled: lo.ToPtr(true)}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid version lock", func() {
+			nc.Spec.Bottlerocket = &v1.BottlerocketConfiguration{Updates: &v1.BottlerocketUpdates{VersionLock: lo.ToPtr("1.20")}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("BootMode", func() {
+		It("should succeed with a boot mode", func() {
+			nc.Spec.BootMode = lo.ToPtr(v1.BootModeLegacyBIOS)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unknown boot mode", func() {
+			nc.Spec.BootMode = lo.ToPtr(v1.BootMode("uefi-preferred"))
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed when enabling nitroTPM with the uefi boot mode", func() {
+			nc.Spec.BootMode = lo.ToPtr(v1.BootModeUEFI)
+			nc.Spec.NitroTPM = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when enabling nitroTPM without a boot mode", func() {
+			nc.Spec.NitroTPM = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when enabling nitroTPM with the legacy-bios boot mode", func() {
+			nc.Spec.BootMode = lo.ToPtr(v1.BootModeLegacyBIOS)
+			nc.Spec.NitroTPM = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed when disabling nitroTPM without a boot mode", func() {
+			nc.Spec.NitroTPM = lo.ToPtr(false)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+	})
+	Context("AdditionalNetworkInterfaces", func() {
+		It("should succeed with additional network interfaces", func() {
+			nc.Spec.AdditionalNetworkInterfaces = []v1.AdditionalNetworkInterface{
+				{
+					DeviceIndex:                1,
+					SubnetSelectorTerms:        []v1.SubnetSelectorTerm{{Tags: map[string]string{"test": "testvalue"}}},
+					SecurityGroupSelectorTerms: []v1.SecurityGroupSelectorTerm{{ID: "sg-12345749"}},
+					DeleteOnTermination:        lo.ToPtr(false),
+				},
+				{
+					DeviceIndex:         2,
+					SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when the device index is the primary network interface's", func() {
+			nc.Spec.AdditionalNetworkInterfaces = []v1.AdditionalNetworkInterface{
+				{
+					DeviceIndex:         0,
+					SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when device indexes are duplicated", func() {
+			nc.Spec.AdditionalNetworkInterfaces = []v1.AdditionalNetworkInterface{
+				{
+					DeviceIndex:         1,
+					SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}},
+				},
+				{
+					DeviceIndex:         1,
+					SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345750"}},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail without subnet selector terms", func() {
+			nc.Spec.AdditionalNetworkInterfaces = []v1.AdditionalNetworkInterface{
+				{
+					DeviceIndex: 1,
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when a subnet selector term sets id with other fields", func() {
+			nc.Spec.AdditionalNetworkInterfaces = []v1.AdditionalNetworkInterface{
+				{
+					DeviceIndex:         1,
+					SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749", Tags: map[string]string{"test": "testvalue"}}},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("PrimaryNetworkInterface", func() {
+		It("should succeed with secondary private IP addresses", func() {
+			nc.Spec.PrimaryNetworkInterface = &v1.PrimaryNetworkInterface{SecondaryPrivateIPAddressCount: lo.ToPtr(int64(10))}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with IPv4 prefixes", func() {
+			nc.Spec.PrimaryNetworkInterface = &v1.PrimaryNetworkInterface{IPv4PrefixCount: lo.ToPtr(int64(1))}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with both secondary private IP addresses and IPv4 prefixes", func() {
+			nc.Spec.PrimaryNetworkInterface = &v1.PrimaryNetworkInterface{SecondaryPrivateIPAddressCount: lo.ToPtr(int64(10)), IPv4PrefixCount: lo.ToPtr(int64(1))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with no secondary private IP addresses", func() {
+			nc.Spec.PrimaryNetworkInterface = &v1.PrimaryNetworkInterface{SecondaryPrivateIPAddressCount: lo.ToPtr(int64(0))}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("SubnetSelectionPolicy", func() {
+		It("should succeed with a subnet selection policy", func() {
+			nc.Spec.SubnetSelectionPolicy = lo.ToPtr(v1.SubnetSelectionPolicyDedicatedPerNodePool)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unknown subnet selection policy", func() {
+			nc.Spec.SubnetSelectionPolicy = lo.ToPtr(v1.SubnetSelectionPolicy("RoundRobin"))
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("AllocationStrategy", func() {
+		It("should succeed with spot and on-demand allocation strategies", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{Spot: lo.ToPtr("capacity-optimized"), OnDemand: lo.ToPtr("prioritized")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with only a spot allocation strategy", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{Spot: lo.ToPtr("diversified")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unknown spot allocation strategy", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{Spot: lo.ToPtr("cheapest")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with a spot allocation strategy for on-demand instances", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{OnDemand: lo.ToPtr("capacity-optimized")}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail without a spot or on-demand allocation strategy", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("KubeletOverlays", func() {
+		It("should succeed with spot and on-demand kubelet overlays", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{
+				Spot: &v1.KubeletOverlay{
+					SystemReserved: map[string]string{"memory": "500Mi"},
+					EvictionHard:   map[string]string{"memory.available": "10%"},
+				},
+				OnDemand: &v1.KubeletOverlay{KubeReserved: map[string]string{"cpu": "200m"}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail without a spot or on-demand kubelet overlay", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with invalid systemReserved keys", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{Spot: &v1.KubeletOverlay{SystemReserved: map[string]string{"gpu": "1"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with negative kubeReserved values", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{OnDemand: &v1.KubeletOverlay{KubeReserved: map[string]string{"memory": "-1Gi"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with invalid evictionHard keys", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{Spot: &v1.KubeletOverlay{EvictionHard: map[string]string{"memory": "10%"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when evictionSoft doesn't have a matching evictionSoftGracePeriod", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{Spot: &v1.KubeletOverlay{EvictionSoft: map[string]string{"memory.available": "5%"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed when evictionSoft has a matching evictionSoftGracePeriod", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{Spot: &v1.KubeletOverlay{
+				EvictionSoft:            map[string]string{"memory.available": "5%"},
+				EvictionSoftGracePeriod: map[string]metav1.Duration{"memory.available": {Duration: time.Minute}},
+			}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+	})
 	Context("MetadataOptions", func() {
 		It("should succeed for valid inputs", func() {
 			nc.Spec.MetadataOptions = &v1.MetadataOptions{
@@ -15,6 +15,9 @@ limitations under the License.
 package v1_test
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -125,6 +128,54 @@ var _ = Describe("Webhook/Validation", func() {
 			}
 			Expect(nc.Validate(ctx)).To(Not(Succeed()))
 		})
+		It("should fail if tag keys have the aws: prefix", func() {
+			nc.Spec.Tags = map[string]string{
+				"aws:custom-key": "value",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"AWS:custom-key": "value",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+		})
+		It("should fail if tag keys or values exceed the EC2 length limits", func() {
+			nc.Spec.Tags = map[string]string{
+				strings.Repeat("k", v1.MaxTagKeyLength): strings.Repeat("v", v1.MaxTagValueLength),
+			}
+			Expect(nc.Validate(ctx)).To(Succeed())
+			nc.Spec.Tags = map[string]string{
+				strings.Repeat("k", v1.MaxTagKeyLength+1): "value",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				"key": strings.Repeat("v", v1.MaxTagValueLength+1),
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+		})
+		It("should fail if tags exceed the number that can be applied alongside Karpenter's tags", func() {
+			nc.Spec.Tags = lo.SliceToMap(lo.Range(v1.MaxTags-v1.ReservedTags), func(i int) (string, string) { return fmt.Sprintf("tag-%d", i), "value" })
+			nc.Spec.Tags[v1.TagName] = "name"
+			Expect(nc.Validate(ctx)).To(Succeed())
+			nc.Spec.Tags = lo.SliceToMap(lo.Range(v1.MaxTags-v1.ReservedTags+1), func(i int) (string, string) { return fmt.Sprintf("tag-%d", i), "value" })
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+		})
+		It("should fail if volume or fleet tags contain a restricted domain key", func() {
+			nc.Spec.VolumeTags = map[string]string{
+				"karpenter.sh/nodepool": "value",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+			nc.Spec.VolumeTags = nil
+			nc.Spec.FleetTags = map[string]string{
+				"aws:custom-key": "value",
+			}
+			Expect(nc.Validate(ctx)).To(Not(Succeed()))
+		})
+		It("should succeed with volume and fleet tags that override tags", func() {
+			nc.Spec.Tags = map[string]string{"cost-center": "instances"}
+			nc.Spec.VolumeTags = map[string]string{"cost-center": "volumes", "backup": "true"}
+			nc.Spec.FleetTags = map[string]string{"cost-center": "fleets"}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
 	})
 	Context("SubnetSelectorTerms", func() {
 		It("should succeed with a valid subnet selector on tags", func() {
@@ -486,6 +537,30 @@ var _ = Describe("Webhook/Validation", func() {
 			Expect(nc.Validate(ctx)).ToNot(Succeed())
 		})
 	})
+	Context("AllocationStrategy", func() {
+		It("should succeed with spot and on-demand allocation strategies", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{Spot: aws.String("price-capacity-optimized"), OnDemand: aws.String("lowest-price")}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should fail with an unknown on-demand allocation strategy", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{OnDemand: aws.String("capacity-optimized-prioritized")}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+		It("should fail without a spot or on-demand allocation strategy", func() {
+			nc.Spec.AllocationStrategy = &v1.AllocationStrategy{}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+	})
+	Context("KubeletOverlays", func() {
+		It("should succeed with a spot kubelet overlay", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{Spot: &v1.KubeletOverlay{SystemReserved: map[string]string{"memory": "500Mi"}}}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should fail without a spot or on-demand kubelet overlay", func() {
+			nc.Spec.KubeletOverlays = &v1.KubeletOverlays{}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+		})
+	})
 	Context("BlockDeviceMappings", func() {
 		It("should fail if more than one root volume is specified", func() {
 			nodeClass := &v1.EC2NodeClass{
@@ -512,6 +587,63 @@ var _ = Describe("Webhook/Validation", func() {
 			}
 			Expect(nodeClass.Validate(ctx)).To(Not(Succeed()))
 		})
+		It("should fail if the root volume isn't on the AMI family's root device", func() {
+			nc.Spec.AMIFamily = &v1.AMIFamilyBottlerocket
+			nc.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{{
+				DeviceName: aws.String("/dev/xvda"),
+				EBS:        &v1.BlockDevice{VolumeSize: resource.NewScaledQuantity(50, resource.Giga)},
+				RootVolume: true,
+			}}
+			Expect(nc.Validate(ctx)).ToNot(Succeed())
+			nc.Spec.BlockDeviceMappings[0].DeviceName = aws.String("/dev/xvdb")
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		It("should allow the root volume on any device for custom AMIs", func() {
+			nc.Spec.AMIFamily = &v1.AMIFamilyCustom
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-123"}}
+			nc.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{{
+				DeviceName: aws.String("/dev/sdh"),
+				EBS:        &v1.BlockDevice{VolumeSize: resource.NewScaledQuantity(50, resource.Giga)},
+				RootVolume: true,
+			}}
+			Expect(nc.Validate(ctx)).To(Succeed())
+		})
+		DescribeTable("should validate iops and throughput against the volume type", func(volumeType *string, iops, throughput *int64, valid bool) {
+			nc.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{{
+				DeviceName: aws.String("/dev/xvda"),
+				EBS: &v1.BlockDevice{
+					VolumeSize: resource.NewScaledQuantity(50, resource.Giga),
+					VolumeType: volumeType,
+					IOPS:       iops,
+					Throughput: throughput,
+				},
+			}}
+			if valid {
+				Expect(nc.Validate(ctx)).To(Succeed())
+			} else {
+				Expect(nc.Validate(ctx)).ToNot(Succeed())
+			}
+		},
+			Entry("gp3 with iops and throughput", lo.ToPtr("gp3"), lo.ToPtr[int64](4000), lo.ToPtr[int64](1000), true),
+			Entry("gp3 by default", nil, lo.ToPtr[int64](3000), lo.ToPtr[int64](125), true),
+			Entry("gp3 with too few iops", lo.ToPtr("gp3"), lo.ToPtr[int64](100), nil, false),
+			Entry("gp3 with more throughput than its iops support", lo.ToPtr("gp3"), lo.ToPtr[int64](3000), lo.ToPtr[int64](1000), false),
+			Entry("io2 with iops", lo.ToPtr("io2"), lo.ToPtr[int64](100000), nil, true),
+			Entry("io1 with throughput", lo.ToPtr("io1"), lo.ToPtr[int64](1000), lo.ToPtr[int64](125), false),
+			Entry("gp2 with iops", lo.ToPtr("gp2"), lo.ToPtr[int64](3000), nil, false),
+			Entry("st1 with throughput", lo.ToPtr("st1"), nil, lo.ToPtr[int64](250), false),
+		)
+		It("should default volumes to encrypted gp3 volumes", func() {
+			nc.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+				{DeviceName: aws.String("/dev/xvda"), EBS: &v1.BlockDevice{VolumeSize: resource.NewScaledQuantity(50, resource.Giga)}},
+				{DeviceName: aws.String("/dev/xvdb"), EBS: &v1.BlockDevice{SnapshotID: aws.String("snap-123"), VolumeType: aws.String("io2")}},
+			}
+			nc.SetDefaults(ctx)
+			Expect(nc.Spec.BlockDeviceMappings[0].EBS.VolumeType).To(Equal(aws.String("gp3")))
+			Expect(nc.Spec.BlockDeviceMappings[0].EBS.Encrypted).To(Equal(aws.Bool(true)))
+			Expect(nc.Spec.BlockDeviceMappings[1].EBS.VolumeType).To(Equal(aws.String("io2")))
+			Expect(nc.Spec.BlockDeviceMappings[1].EBS.Encrypted).To(BeNil())
+		})
 	})
 	Context("Role Immutability", func() {
 		It("should fail when updating the role", func() {
@@ -31,6 +31,7 @@ func init() {
 	v1beta1.WellKnownLabels = v1beta1.WellKnownLabels.Insert(
 		LabelInstanceHypervisor,
 		LabelInstanceEncryptionInTransitSupported,
+		LabelInstanceHibernationSupported,
 		LabelInstanceCategory,
 		LabelInstanceFamily,
 		LabelInstanceGeneration,
@@ -48,7 +49,11 @@ func init() {
 		LabelInstanceAcceleratorName,
 		LabelInstanceAcceleratorManufacturer,
 		LabelInstanceAcceleratorCount,
+		LabelAMIGPUDriverVersion,
+		LabelAMICUDAVersion,
 		LabelTopologyZoneID,
+		LabelTopologyZoneType,
+		LabelTopologyOutpostID,
 		v1.LabelWindowsBuild,
 	)
 }
@@ -97,10 +102,18 @@ var (
 
 	LabelNodeClass = apis.Group + "/ec2nodeclass"
 
-	LabelTopologyZoneID = "topology.k8s.aws/zone-id"
+	LabelTopologyZoneID   = "topology.k8s.aws/zone-id"
+	LabelTopologyZoneType = "topology.k8s.aws/zone-type"
+	// ZoneTypeAvailabilityZone, ZoneTypeLocalZone, and ZoneTypeWavelengthZone are the values of LabelTopologyZoneType
+	ZoneTypeAvailabilityZone = "availability-zone"
+	ZoneTypeLocalZone        = "local-zone"
+	ZoneTypeWavelengthZone   = "wavelength-zone"
+	// LabelTopologyOutpostID is the ID of the AWS Outpost, e.g. op-0123456789abcdef0, that a node is launched on
+	LabelTopologyOutpostID = "topology.k8s.aws/outpost-id"
 
 	LabelInstanceHypervisor                   = apis.Group + "/instance-hypervisor"
 	LabelInstanceEncryptionInTransitSupported = apis.Group + "/instance-encryption-in-transit-supported"
+	LabelInstanceHibernationSupported         = apis.Group + "/instance-hibernation-supported"
 	LabelInstanceCategory                     = apis.Group + "/instance-category"
 	LabelInstanceFamily                       = apis.Group + "/instance-family"
 	LabelInstanceGeneration                   = apis.Group + "/instance-generation"
@@ -122,7 +135,138 @@ var (
 	AnnotationEC2NodeClassHashVersion         = apis.Group + "/ec2nodeclass-hash-version"
 	AnnotationInstanceTagged                  = apis.Group + "/tagged"
 
+	// LabelAMIGPUDriverVersion is the major version of the GPU driver, e.g. 550, of the AMI that a node was launched with
+	LabelAMIGPUDriverVersion = apis.Group + "/ami-gpu-driver-version"
+	// LabelAMICUDAVersion is the CUDA version that the GPU driver of a node's AMI supports, encoded as
+	// 1000 * major + 10 * minor like CUDA_VERSION, e.g. 12040 for CUDA 12.4, so that pods can require a minimum with Gt
+	LabelAMICUDAVersion = apis.Group + "/ami-cuda-version"
+	// TagGPUDriverVersion is set on AMIs to the version of their GPU driver, e.g. 550.54.15
+	TagGPUDriverVersion = apis.Group + "/gpu-driver-version"
+	// TagCUDAVersion is set on AMIs to the CUDA version that their GPU driver supports, e.g. 12.4
+	TagCUDAVersion = apis.Group + "/cuda-version"
+
+	// AnnotationTargetGroupDeregistrationDeadline is set on a terminating NodeClaim once its instance has been
+	// deregistered from load balancer target groups, and holds the time at which the instance can be terminated
+	AnnotationTargetGroupDeregistrationDeadline = apis.Group + "/target-group-deregistration-deadline"
+	// AnnotationRestartCost is set on pods or namespaces to the estimated time that a pod takes to become ready again
+	// after it's evicted, e.g. to pull a large image or load a model. Consolidation prefers to disrupt nodes whose pods
+	// are cheaper to restart.
+	AnnotationRestartCost = apis.Group + "/restart-cost"
+	// AnnotationManagedPodDeletionCost records the pod deletion cost that Karpenter derived from the restart cost, so
+	// that a pod deletion cost set by users isn't overwritten
+	AnnotationManagedPodDeletionCost = apis.Group + "/managed-pod-deletion-cost"
+	// AnnotationDeprioritizedInstanceTypes overrides the deprioritized-instance-types setting for the NodeClaims of a
+	// NodePool when it's set on the NodePool's template
+	AnnotationDeprioritizedInstanceTypes = apis.Group + "/deprioritized-instance-types"
+	// AnnotationPlannedDrainTime is set on nodes that Karpenter has started to voluntarily disrupt, and holds the time
+	// from which the node may be drained
+	AnnotationPlannedDrainTime = apis.Group + "/planned-drain-time"
+	// AnnotationSuggestInstanceTypes opts a NodePool into events that suggest compatible instance types when the
+	// instance types that its NodeClaims are pinned to repeatedly fail to launch
+	AnnotationSuggestInstanceTypes = apis.Group + "/suggest-instance-types"
+	// LabelInterruptionSensitive opts a NodePool into zone suitability scoring when it's set to "true" in the labels of
+	// the NodePool itself, rather than of its template
+	LabelInterruptionSensitive = apis.Group + "/interruption-sensitive"
+	// AnnotationZoneSuitabilityScores is set on interruption sensitive NodePools, and holds the suitability score of each
+	// zone that Karpenter uses to rank the zones of their launches
+	AnnotationZoneSuitabilityScores = apis.Group + "/zone-suitability-scores"
+	// AnnotationDebug opts a NodeClaim into temporary break-glass access to its instance when it's set to "true", so
+	// that operators can debug instances that never joined the cluster
+	AnnotationDebug = apis.Group + "/debug"
+	// AnnotationDebugExpiration is set on NodeClaims that have been granted debug access, and holds the time at which
+	// the access is revoked
+	AnnotationDebugExpiration = apis.Group + "/debug-expiration"
+	// AnnotationDebugEndpoint is set on NodeClaims whose debug access goes through an EC2 Instance Connect Endpoint that
+	// Karpenter created, and holds the ID of the endpoint so that it's deleted when the access is revoked
+	AnnotationDebugEndpoint = apis.Group + "/debug-endpoint"
+	// AnnotationAdoptedProviderID is set on NodeClaims that Karpenter recreated for instances that didn't have one, and
+	// holds the provider ID of the instance so that it's linked to the NodeClaim rather than launched
+	AnnotationAdoptedProviderID = apis.Group + "/adopted-provider-id"
+	// AnnotationMinNodeAge is set on NodePools to the minimum age, e.g. 10m, that their nodes must reach before they can
+	// be voluntarily disrupted
+	AnnotationMinNodeAge = apis.Group + "/min-node-age"
+	// AnnotationMinNodeAgeExpiration is set on nodes whose do-not-disrupt annotation Karpenter set because they're
+	// younger than the minimum node age of their NodePool, and holds the time at which the annotations are removed
+	AnnotationMinNodeAgeExpiration = apis.Group + "/min-node-age-expiration"
+	// AnnotationSpotMaxPrice caps the hourly price, in USD, of the spot instances of a NodePool when it's set on the
+	// NodePool's template
+	AnnotationSpotMaxPrice = apis.Group + "/spot-max-price"
+	// AnnotationSpotMaxPricePercent caps the hourly price of the spot instances of a NodePool to a percentage of the
+	// on-demand price of the instance type when it's set on the NodePool's template
+	AnnotationSpotMaxPricePercent = apis.Group + "/spot-max-price-percent"
+	// AnnotationPrewarmSchedule is set on NodePools to a cron schedule, e.g. "0 8 * * 1-5", in UTC, at which Karpenter
+	// provisions capacity for the NodePool ahead of predictable load
+	AnnotationPrewarmSchedule = apis.Group + "/prewarm-schedule"
+	// AnnotationPrewarmDuration is set on NodePools to how long, e.g. 2h, pre-warmed capacity is held after each
+	// scheduled time
+	AnnotationPrewarmDuration = apis.Group + "/prewarm-duration"
+	// AnnotationPrewarmReplicas is set on NodePools to the number of placeholder pods that hold pre-warmed capacity
+	AnnotationPrewarmReplicas = apis.Group + "/prewarm-replicas"
+	// AnnotationPrewarmRequests is set on NodePools to the resource requests of each placeholder pod, e.g.
+	// "cpu=2,memory=4Gi"
+	AnnotationPrewarmRequests = apis.Group + "/prewarm-requests"
+	// LabelPrewarmNodePool is set on placeholder pods to the name of the NodePool whose capacity they hold
+	LabelPrewarmNodePool = apis.Group + "/prewarm-nodepool"
+	// AnnotationLaunchRamp is set on NodePools to the most nodes that Karpenter launches for the NodePool per period,
+	// e.g. 20/1m, so that large scale-ups don't overwhelm the systems that new nodes depend on
+	AnnotationLaunchRamp = apis.Group + "/launch-ramp"
+	// AnnotationLaunchRampDoublingWindow is set on NodePools with a launch ramp to how long, e.g. 5m, the NodePool must
+	// keep launching before the number of nodes it may launch per period doubles
+	AnnotationLaunchRampDoublingWindow = apis.Group + "/launch-ramp-doubling-window"
+	// AnnotationTerminationProtection is set on NodeClaims, usually through the NodePool template, to "enabled" to launch
+	// their instances with EC2 termination protection and keep Karpenter from disrupting or terminating them
+	AnnotationTerminationProtection = apis.Group + "/termination-protection"
+	// AnnotationTerminationProtected is set on NodeClaims whose instance has EC2 termination protection, and on nodes
+	// whose do-not-disrupt annotation Karpenter set because their NodeClaim is protected
+	AnnotationTerminationProtected = apis.Group + "/termination-protected"
+	// TerminationProtectionEnabled is the value of AnnotationTerminationProtection that enables termination protection
+	TerminationProtectionEnabled = "enabled"
+	// AnnotationTerminationPending is set on NodeClaims whose node has been drained when termination approval is
+	// enabled, and holds the time at which Karpenter started waiting for approval to terminate the instance
+	AnnotationTerminationPending = apis.Group + "/termination-pending"
+	// AnnotationTerminationApproved is set on NodeClaims to "true" by external tooling to approve the termination of
+	// their instance when termination approval is enabled
+	AnnotationTerminationApproved = apis.Group + "/termination-approved"
+	// AnnotationAMIIDOverride is set on NodeClaims to the ID of an AMI that their instance is launched with instead of
+	// the AMIs of their EC2NodeClass, when NodeClaim launch overrides are enabled
+	AnnotationAMIIDOverride = apis.Group + "/ami-id-override"
+	// AnnotationInstanceProfileOverride is set on NodeClaims to the name of an instance profile that their instance is
+	// launched with instead of the instance profile of their EC2NodeClass, when NodeClaim launch overrides are enabled
+	AnnotationInstanceProfileOverride = apis.Group + "/instance-profile-override"
+	// AnnotationLaunchTemplateDataPatch is set on EC2NodeClasses to a JSON patch that is applied to the data of their
+	// launch templates, when launch template data patches are enabled
+	AnnotationLaunchTemplateDataPatch = apis.Group + "/launch-template-data-patch"
+	// AnnotationRepairReboots is set on NodeClaims whose instance Karpenter rebooted to repair their NotReady node, and
+	// holds the number of reboots since the node was last Ready
+	AnnotationRepairReboots = apis.Group + "/repair-reboots"
+	// AnnotationRepairRebootTime is set on NodeClaims whose instance Karpenter rebooted to repair their NotReady node,
+	// and holds the time of the last reboot
+	AnnotationRepairRebootTime = apis.Group + "/repair-reboot-time"
+	// AnnotationHibernation is set on NodeClaims, usually through the NodePool template, to "enabled" to hibernate their
+	// on-demand instances when they're deleted rather than terminating them, and resume them on the next scale-up, when
+	// hibernation is enabled
+	AnnotationHibernation = apis.Group + "/hibernation"
+	// HibernationEnabled is the value of AnnotationHibernation that enables hibernation
+	HibernationEnabled = "enabled"
+
+	// NodeConditionDisruptionPlanned is true on nodes that Karpenter has started to voluntarily disrupt
+	NodeConditionDisruptionPlanned v1.NodeConditionType = "KarpenterDisruptionPlanned"
+	// NodeClaimConditionCreateFleetFailed is true on NodeClaims whose last launch failed with errors from CreateFleet,
+	// and its reason is the category of the errors: Capacity, Quota, Permission, Misconfiguration or Unknown
+	NodeClaimConditionCreateFleetFailed = "CreateFleetFailed"
+
 	TagNodeClaim             = coreapis.Group + "/nodeclaim"
 	TagManagedLaunchTemplate = apis.Group + "/cluster"
 	TagName                  = "Name"
+
+	// MaxTags is the maximum number of tags that EC2 allows on a single resource
+	MaxTags = 50
+	// ReservedTags is the number of tags that Karpenter may apply to an instance on top of the EC2NodeClass tags: the
+	// cluster tag, karpenter.sh/nodepool, karpenter.sh/managed-by, karpenter.k8s.aws/ec2nodeclass, karpenter.sh/nodeclaim
+	// and Name on every instance, karpenter.k8s.aws/warm-pool on warm pool instances, and karpenter.k8s.aws/hibernated and
+	// karpenter.k8s.aws/hibernation-time on hibernated instances
+	ReservedTags = 9
+	// MaxTagKeyLength and MaxTagValueLength are the EC2 limits on the number of characters in a tag key and value
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
 )
@@ -47,6 +47,31 @@ func (in *AMI) DeepCopy() *AMI {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AMISelectionPolicy) DeepCopyInto(out *AMISelectionPolicy) {
+	*out = *in
+	if in.MinimumAge != nil {
+		in, out := &in.MinimumAge, &out.MinimumAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ReleaseVersion != nil {
+		in, out := &in.ReleaseVersion, &out.ReleaseVersion
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMISelectionPolicy.
+func (in *AMISelectionPolicy) DeepCopy() *AMISelectionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AMISelectionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AMISelectorTerm) DeepCopyInto(out *AMISelectorTerm) {
 	*out = *in
@@ -69,6 +94,90 @@ func (in *AMISelectorTerm) DeepCopy() *AMISelectorTerm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdditionalNetworkInterface) DeepCopyInto(out *AdditionalNetworkInterface) {
+	*out = *in
+	if in.SubnetSelectorTerms != nil {
+		in, out := &in.SubnetSelectorTerms, &out.SubnetSelectorTerms
+		*out = make([]SubnetSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecurityGroupSelectorTerms != nil {
+		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
+		*out = make([]SecurityGroupSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeleteOnTermination != nil {
+		in, out := &in.DeleteOnTermination, &out.DeleteOnTermination
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalNetworkInterface.
+func (in *AdditionalNetworkInterface) DeepCopy() *AdditionalNetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(AdditionalNetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdditionalNetworkInterfaceStatus) DeepCopyInto(out *AdditionalNetworkInterfaceStatus) {
+	*out = *in
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]Subnet, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make([]SecurityGroup, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalNetworkInterfaceStatus.
+func (in *AdditionalNetworkInterfaceStatus) DeepCopy() *AdditionalNetworkInterfaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AdditionalNetworkInterfaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllocationStrategy) DeepCopyInto(out *AllocationStrategy) {
+	*out = *in
+	if in.Spot != nil {
+		in, out := &in.Spot, &out.Spot
+		*out = new(string)
+		**out = **in
+	}
+	if in.OnDemand != nil {
+		in, out := &in.OnDemand, &out.OnDemand
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllocationStrategy.
+func (in *AllocationStrategy) DeepCopy() *AllocationStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(AllocationStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlockDevice) DeepCopyInto(out *BlockDevice) {
 	*out = *in
@@ -149,6 +258,152 @@ func (in *BlockDeviceMapping) DeepCopy() *BlockDeviceMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BottlerocketConfiguration) DeepCopyInto(out *BottlerocketConfiguration) {
+	*out = *in
+	if in.Kernel != nil {
+		in, out := &in.Kernel, &out.Kernel
+		*out = new(BottlerocketKernel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostContainers != nil {
+		in, out := &in.HostContainers, &out.HostContainers
+		*out = make(map[string]BottlerocketHostContainer, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Updates != nil {
+		in, out := &in.Updates, &out.Updates
+		*out = new(BottlerocketUpdates)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BottlerocketConfiguration.
+func (in *BottlerocketConfiguration) DeepCopy() *BottlerocketConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(BottlerocketConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BottlerocketHostContainer) DeepCopyInto(out *BottlerocketHostContainer) {
+	*out = *in
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(string)
+		**out = **in
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Superpowered != nil {
+		in, out := &in.Superpowered, &out.Superpowered
+		*out = new(bool)
+		**out = **in
+	}
+	if in.UserData != nil {
+		in, out := &in.UserData, &out.UserData
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BottlerocketHostContainer.
+func (in *BottlerocketHostContainer) DeepCopy() *BottlerocketHostContainer {
+	if in == nil {
+		return nil
+	}
+	out := new(BottlerocketHostContainer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BottlerocketKernel) DeepCopyInto(out *BottlerocketKernel) {
+	*out = *in
+	if in.Lockdown != nil {
+		in, out := &in.Lockdown, &out.Lockdown
+		*out = new(string)
+		**out = **in
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BottlerocketKernel.
+func (in *BottlerocketKernel) DeepCopy() *BottlerocketKernel {
+	if in == nil {
+		return nil
+	}
+	out := new(BottlerocketKernel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BottlerocketUpdates) DeepCopyInto(out *BottlerocketUpdates) {
+	*out = *in
+	if in.VersionLock != nil {
+		in, out := &in.VersionLock, &out.VersionLock
+		*out = new(string)
+		**out = **in
+	}
+	if in.IgnoreWaves != nil {
+		in, out := &in.IgnoreWaves, &out.IgnoreWaves
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BottlerocketUpdates.
+func (in *BottlerocketUpdates) DeepCopy() *BottlerocketUpdates {
+	if in == nil {
+		return nil
+	}
+	out := new(BottlerocketUpdates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRegistries) DeepCopyInto(out *ContainerRegistries) {
+	*out = *in
+	if in.PauseImage != nil {
+		in, out := &in.PauseImage, &out.PauseImage
+		*out = new(string)
+		**out = **in
+	}
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]RegistryMirror, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRegistries.
+func (in *ContainerRegistries) DeepCopy() *ContainerRegistries {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRegistries)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EC2NodeClass) DeepCopyInto(out *EC2NodeClass) {
 	*out = *in
@@ -218,6 +473,11 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SubnetSelectionPolicy != nil {
+		in, out := &in.SubnetSelectionPolicy, &out.SubnetSelectionPolicy
+		*out = new(SubnetSelectionPolicy)
+		**out = **in
+	}
 	if in.SecurityGroupSelectorTerms != nil {
 		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
 		*out = make([]SecurityGroupSelectorTerm, len(*in))
@@ -230,6 +490,18 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.PrimaryNetworkInterface != nil {
+		in, out := &in.PrimaryNetworkInterface, &out.PrimaryNetworkInterface
+		*out = new(PrimaryNetworkInterface)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdditionalNetworkInterfaces != nil {
+		in, out := &in.AdditionalNetworkInterfaces, &out.AdditionalNetworkInterfaces
+		*out = make([]AdditionalNetworkInterface, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.AMISelectorTerms != nil {
 		in, out := &in.AMISelectorTerms, &out.AMISelectorTerms
 		*out = make([]AMISelectorTerm, len(*in))
@@ -237,6 +509,11 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AMISelectionPolicy != nil {
+		in, out := &in.AMISelectionPolicy, &out.AMISelectionPolicy
+		*out = new(AMISelectionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AMIFamily != nil {
 		in, out := &in.AMIFamily, &out.AMIFamily
 		*out = new(string)
@@ -259,6 +536,20 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.VolumeTags != nil {
+		in, out := &in.VolumeTags, &out.VolumeTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FleetTags != nil {
+		in, out := &in.FleetTags, &out.FleetTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Kubelet != nil {
 		in, out := &in.Kubelet, &out.Kubelet
 		*out = new(KubeletConfiguration)
@@ -285,6 +576,26 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.BootMode != nil {
+		in, out := &in.BootMode, &out.BootMode
+		*out = new(BootMode)
+		**out = **in
+	}
+	if in.NitroTPM != nil {
+		in, out := &in.NitroTPM, &out.NitroTPM
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ContainerRegistries != nil {
+		in, out := &in.ContainerRegistries, &out.ContainerRegistries
+		*out = new(ContainerRegistries)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bottlerocket != nil {
+		in, out := &in.Bottlerocket, &out.Bottlerocket
+		*out = new(BottlerocketConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.MetadataOptions != nil {
 		in, out := &in.MetadataOptions, &out.MetadataOptions
 		*out = new(MetadataOptions)
@@ -295,6 +606,16 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.AllocationStrategy != nil {
+		in, out := &in.AllocationStrategy, &out.AllocationStrategy
+		*out = new(AllocationStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeletOverlays != nil {
+		in, out := &in.KubeletOverlays, &out.KubeletOverlays
+		*out = new(KubeletOverlays)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EC2NodeClassSpec.
@@ -327,6 +648,13 @@ func (in *EC2NodeClassStatus) DeepCopyInto(out *EC2NodeClassStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AdditionalNetworkInterfaces != nil {
+		in, out := &in.AdditionalNetworkInterfaces, &out.AdditionalNetworkInterfaces
+		*out = make([]AdditionalNetworkInterfaceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]status.Condition, len(*in))
@@ -431,6 +759,86 @@ func (in *KubeletConfiguration) DeepCopy() *KubeletConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletOverlay) DeepCopyInto(out *KubeletOverlay) {
+	*out = *in
+	if in.SystemReserved != nil {
+		in, out := &in.SystemReserved, &out.SystemReserved
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KubeReserved != nil {
+		in, out := &in.KubeReserved, &out.KubeReserved
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EvictionHard != nil {
+		in, out := &in.EvictionHard, &out.EvictionHard
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EvictionSoft != nil {
+		in, out := &in.EvictionSoft, &out.EvictionSoft
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EvictionSoftGracePeriod != nil {
+		in, out := &in.EvictionSoftGracePeriod, &out.EvictionSoftGracePeriod
+		*out = make(map[string]metav1.Duration, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EvictionMaxPodGracePeriod != nil {
+		in, out := &in.EvictionMaxPodGracePeriod, &out.EvictionMaxPodGracePeriod
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletOverlay.
+func (in *KubeletOverlay) DeepCopy() *KubeletOverlay {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletOverlay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletOverlays) DeepCopyInto(out *KubeletOverlays) {
+	*out = *in
+	if in.Spot != nil {
+		in, out := &in.Spot, &out.Spot
+		*out = new(KubeletOverlay)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OnDemand != nil {
+		in, out := &in.OnDemand, &out.OnDemand
+		*out = new(KubeletOverlay)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletOverlays.
+func (in *KubeletOverlays) DeepCopy() *KubeletOverlays {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletOverlays)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataOptions) DeepCopyInto(out *MetadataOptions) {
 	*out = *in
@@ -466,6 +874,51 @@ func (in *MetadataOptions) DeepCopy() *MetadataOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrimaryNetworkInterface) DeepCopyInto(out *PrimaryNetworkInterface) {
+	*out = *in
+	if in.SecondaryPrivateIPAddressCount != nil {
+		in, out := &in.SecondaryPrivateIPAddressCount, &out.SecondaryPrivateIPAddressCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.IPv4PrefixCount != nil {
+		in, out := &in.IPv4PrefixCount, &out.IPv4PrefixCount
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrimaryNetworkInterface.
+func (in *PrimaryNetworkInterface) DeepCopy() *PrimaryNetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(PrimaryNetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryMirror) DeepCopyInto(out *RegistryMirror) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryMirror.
+func (in *RegistryMirror) DeepCopy() *RegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroup) DeepCopyInto(out *SecurityGroup) {
 	*out = *in
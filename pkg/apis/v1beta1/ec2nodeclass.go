@@ -29,11 +29,18 @@ import (
 type EC2NodeClassSpec struct {
 	// SubnetSelectorTerms is a list of or subnet selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="subnetSelectorTerms cannot be empty",rule="self.size() != 0"
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in subnetSelectorTerms",rule="!self.all(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'zoneID']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.zoneID))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in subnetSelectorTerms",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.zoneID)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	SubnetSelectorTerms []SubnetSelectorTerm `json:"subnetSelectorTerms" hash:"ignore"`
+	// SubnetSelectionPolicy controls how Karpenter chooses between the subnets that match the subnetSelectorTerms in the
+	// same zone. MostAvailableIPs, the default, always launches into the subnet with the most available IP addresses.
+	// Balanced spreads launches across all of the subnets in the zone, which suits many small subnets.
+	// DedicatedPerNodePool consistently launches the nodes of each NodePool into the same subnet in the zone.
+	// +kubebuilder:validation:Enum:={MostAvailableIPs,Balanced,DedicatedPerNodePool}
+	// +optional
+	SubnetSelectionPolicy *SubnetSelectionPolicy `json:"subnetSelectionPolicy,omitempty" hash:"ignore"`
 	// SecurityGroupSelectorTerms is a list of or security group selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="securityGroupSelectorTerms cannot be empty",rule="self.size() != 0"
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
@@ -45,12 +52,27 @@ type EC2NodeClassSpec struct {
 	// AssociatePublicIPAddress controls if public IP addresses are assigned to instances that are launched with the nodeclass.
 	// +optional
 	AssociatePublicIPAddress *bool `json:"associatePublicIPAddress,omitempty"`
+	// PrimaryNetworkInterface configures the addresses that are assigned to the primary network interface of the instances
+	// that are launched with the nodeclass.
+	// +kubebuilder:validation:XValidation:message="secondaryPrivateIPAddressCount and ipv4PrefixCount are mutually exclusive",rule="!(has(self.secondaryPrivateIPAddressCount) && has(self.ipv4PrefixCount))"
+	// +optional
+	PrimaryNetworkInterface *PrimaryNetworkInterface `json:"primaryNetworkInterface,omitempty"`
+	// AdditionalNetworkInterfaces are network interfaces that are created and attached to the instances that are launched
+	// with the nodeclass, in addition to their primary network interface.
+	// +kubebuilder:validation:XValidation:message="deviceIndex must be unique",rule="self.all(x, self.exists_one(y, y.deviceIndex == x.deviceIndex))"
+	// +kubebuilder:validation:MaxItems:=15
+	// +optional
+	AdditionalNetworkInterfaces []AdditionalNetworkInterface `json:"additionalNetworkInterfaces,omitempty"`
 	// AMISelectorTerms is a list of or ami selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
 	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name) || has(x.owner)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +optional
 	AMISelectorTerms []AMISelectorTerm `json:"amiSelectorTerms,omitempty" hash:"ignore"`
+	// AMISelectionPolicy controls which of the discovered AMIs are used to launch nodes. By default, the most recently
+	// created AMI that matches the amiSelectorTerms, or the latest default AMI for the AMIFamily, is always used.
+	// +optional
+	AMISelectionPolicy *AMISelectionPolicy `json:"amiSelectionPolicy,omitempty" hash:"ignore"`
 	// AMIFamily is the AMI family that instances use.
 	// +kubebuilder:validation:Enum:={AL2,AL2023,Bottlerocket,Ubuntu,Custom,Windows2019,Windows2022}
 	// +required
@@ -77,14 +99,58 @@ type EC2NodeClassSpec struct {
 	// +optional
 	InstanceProfile *string `json:"instanceProfile,omitempty"`
 	// Tags to be applied on ec2 resources like instances and launch templates.
+	// +kubebuilder:validation:MaxProperties=50
 	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching kubernetes.io/cluster/",rule="self.all(k, !k.startsWith('kubernetes.io/cluster') )"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/managed-by",rule="self.all(k, k !='karpenter.sh/managed-by')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodeclaim",rule="self.all(k, k !='karpenter.sh/nodeclaim')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:XValidation:message="tag keys with the aws: prefix are reserved for use by AWS",rule="self.all(k, !k.matches('^(?i)aws:'))"
+	// +kubebuilder:validation:XValidation:message="tag keys may not be longer than 128 characters",rule="self.all(k, size(k) <= 128)"
+	// +kubebuilder:validation:XValidation:message="tag values may not be longer than 256 characters",rule="self.all(k, size(self[k]) <= 256)"
+	// +kubebuilder:validation:XValidation:message="at most 41 tags, not counting Name, may be specified since Karpenter reserves 9 of the 50 tags EC2 allows on an instance",rule="self.filter(k, k != 'Name').size() <= 41"
 	// +optional
 	Tags map[string]string `json:"tags,omitempty"`
+	// VolumeTags to be applied on the EBS volumes of instances, in addition to Tags. A VolumeTags value takes precedence
+	// over the Tags value of the same key.
+	// +kubebuilder:validation:MaxProperties=50
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching kubernetes.io/cluster/",rule="self.all(k, !k.startsWith('kubernetes.io/cluster') )"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/managed-by",rule="self.all(k, k !='karpenter.sh/managed-by')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodeclaim",rule="self.all(k, k !='karpenter.sh/nodeclaim')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:XValidation:message="tag keys with the aws: prefix are reserved for use by AWS",rule="self.all(k, !k.matches('^(?i)aws:'))"
+	// +kubebuilder:validation:XValidation:message="tag keys may not be longer than 128 characters",rule="self.all(k, size(k) <= 128)"
+	// +kubebuilder:validation:XValidation:message="tag values may not be longer than 256 characters",rule="self.all(k, size(self[k]) <= 256)"
+	// +kubebuilder:validation:XValidation:message="at most 41 tags, not counting Name, may be specified since Karpenter reserves 9 of the 50 tags EC2 allows on a volume",rule="self.filter(k, k != 'Name').size() <= 41"
+	// +optional
+	VolumeTags map[string]string `json:"volumeTags,omitempty"`
+	// FleetTags to be applied on the EC2 fleets that launch instances, in addition to Tags. A FleetTags value takes
+	// precedence over the Tags value of the same key.
+	// +kubebuilder:validation:MaxProperties=50
+	// +kubebuilder:validation:XValidation:message="empty tag keys aren't supported",rule="self.all(k, k != '')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching kubernetes.io/cluster/",rule="self.all(k, !k.startsWith('kubernetes.io/cluster') )"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/managed-by",rule="self.all(k, k !='karpenter.sh/managed-by')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodeclaim",rule="self.all(k, k !='karpenter.sh/nodeclaim')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:XValidation:message="tag keys with the aws: prefix are reserved for use by AWS",rule="self.all(k, !k.matches('^(?i)aws:'))"
+	// +kubebuilder:validation:XValidation:message="tag keys may not be longer than 128 characters",rule="self.all(k, size(k) <= 128)"
+	// +kubebuilder:validation:XValidation:message="tag values may not be longer than 256 characters",rule="self.all(k, size(self[k]) <= 256)"
+	// +kubebuilder:validation:XValidation:message="at most 41 tags, not counting Name, may be specified since Karpenter reserves 9 of the 50 tags EC2 allows on a fleet",rule="self.filter(k, k != 'Name').size() <= 41"
+	// +optional
+	FleetTags map[string]string `json:"fleetTags,omitempty"`
+	// MetadataLabels are added to the NodeClaims of the instances that are launched, so that nodes are labeled with the
+	// metadata of their instance without a separate labeling DaemonSet. Values are Go templates that are rendered with
+	// the fields .InstanceID, .InstanceType, .ImageID, .Zone, .ZoneID, .SubnetID, .VPCID and .CapacityType, e.g.
+	// {"example.com/zone-id": "{{ .ZoneID }}"}. Labels whose value doesn't render to a valid label value are skipped.
+	// Changing metadataLabels doesn't drift existing nodes.
+	// +kubebuilder:validation:MaxProperties=20
+	// +kubebuilder:validation:XValidation:message="empty label keys aren't supported",rule="self.all(k, k != '')"
+	// +optional
+	MetadataLabels map[string]string `json:"metadataLabels,omitempty" hash:"ignore"`
 	// BlockDeviceMappings to be applied to provisioned nodes.
 	// +kubebuilder:validation:XValidation:message="must have only one blockDeviceMappings with rootVolume",rule="self.filter(x, has(x.rootVolume)?x.rootVolume==true:false).size() <= 1"
 	// +kubebuilder:validation:MaxItems:=50
@@ -96,6 +162,24 @@ type EC2NodeClassSpec struct {
 	// DetailedMonitoring controls if detailed monitoring is enabled for instances that are launched
 	// +optional
 	DetailedMonitoring *bool `json:"detailedMonitoring,omitempty"`
+	// BootMode is the boot mode that instances are launched with. Boot mode isn't a launch template parameter, so
+	// instances boot with the mode of their AMI; AMIs and instance types that can't boot with this mode are excluded.
+	// If omitted, each AMI's own boot mode is used.
+	// +optional
+	BootMode *BootMode `json:"bootMode,omitempty"`
+	// NitroTPM restricts launches to instance types that support NitroTPM 2.0 and AMIs that enable it, so that
+	// instances boot with a TPM for measured boot and attestation. Requires the uefi bootMode.
+	// +optional
+	NitroTPM *bool `json:"nitroTPM,omitempty"`
+	// ContainerRegistries configures where the container runtime on provisioned nodes pulls the pause image and mirrored
+	// registries from, so that nodes can boot in clusters that can't reach public registries. This field is not
+	// supported for the Windows AMI families.
+	// +optional
+	ContainerRegistries *ContainerRegistries `json:"containerRegistries,omitempty"`
+	// Bottlerocket configures settings of Bottlerocket nodes that are validated and rendered into their TOML user data.
+	// These settings take precedence over the same settings in userData. Requires the Bottlerocket amiFamily.
+	// +optional
+	Bottlerocket *BottlerocketConfiguration `json:"bottlerocket,omitempty"`
 	// MetadataOptions for the generated launch template of provisioned nodes.
 	//
 	// This specifies the exposure of the Instance Metadata Service to
@@ -117,6 +201,18 @@ type EC2NodeClassSpec struct {
 	// https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFleet.html
 	// +optional
 	Context *string `json:"context,omitempty"`
+	// AllocationStrategy controls how EC2 Fleet chooses between the instance types and zones that Karpenter offers it
+	// for each launch. If omitted, spot instances are launched with price-capacity-optimized and on-demand instances
+	// with lowest-price, or with capacity-optimized-prioritized and prioritized when Karpenter prioritizes instance types.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['spot', 'onDemand']",rule="has(self.spot) || has(self.onDemand)"
+	// +optional
+	AllocationStrategy *AllocationStrategy `json:"allocationStrategy,omitempty"`
+	// KubeletOverlays are kubelet settings that are applied on top of the NodePool's kubelet configuration for nodes of
+	// each capacity type, so that spot nodes, which churn more, can reserve resources and evict pods differently than
+	// long-lived on-demand nodes.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['spot', 'onDemand']",rule="has(self.spot) || has(self.onDemand)"
+	// +optional
+	KubeletOverlays *KubeletOverlays `json:"kubeletOverlays,omitempty"`
 }
 
 // SubnetSelectorTerm defines selection logic for a subnet used by Karpenter to launch nodes.
@@ -132,6 +228,10 @@ type SubnetSelectorTerm struct {
 	// +kubebuilder:validation:Pattern="subnet-[0-9a-z]+"
 	// +optional
 	ID string `json:"id,omitempty"`
+	// ZoneID selects the subnets in the availability zone with this ID (e.g. use1-az1). Unlike zone names, zone IDs
+	// refer to the same physical zone in every account. If set with tags, subnets must match both.
+	// +optional
+	ZoneID string `json:"zoneID,omitempty"`
 }
 
 // SecurityGroupSelectorTerm defines selection logic for a security group used by Karpenter to launch nodes.
@@ -304,6 +404,245 @@ type BlockDevice struct {
 	VolumeType *string `json:"volumeType,omitempty"`
 }
 
+// AMISelectionPolicy controls how Karpenter moves between AMIs as new ones are discovered.
+// +kubebuilder:validation:XValidation:message="minimumAge can't be set with the Pinned strategy",rule="!(has(self.minimumAge) && has(self.strategy) && self.strategy == 'Pinned')"
+type AMISelectionPolicy struct {
+	// Strategy is the selection strategy. Latest always uses the newest discovered AMI. Pinned keeps using the AMIs that
+	// were resolved when the EC2NodeClass was first reconciled, even after newer AMIs are discovered, until its amiFamily,
+	// amiSelectorTerms or releaseVersion change.
+	// +kubebuilder:validation:Enum:={Latest,Pinned}
+	// +optional
+	Strategy AMISelectionStrategy `json:"strategy,omitempty"`
+	// MinimumAge is the amount of time that must pass after an AMI is created before Karpenter adopts it.
+	// Until then, Karpenter continues to use the newest AMI that is old enough, or the AMI it was already using.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	MinimumAge *metav1.Duration `json:"minimumAge,omitempty"`
+	// ReleaseVersion pins the AMIs that are resolved for the amiFamily to a specific release, rather than the release that
+	// SSM currently recommends (e.g. v20240703 for AL2 and AL2023, or 1.20.3 for Bottlerocket). It only applies when no
+	// amiSelectorTerms are specified. Karpenter publishes an event on the EC2NodeClass when a newer release is available.
+	// +kubebuilder:validation:Pattern:="^[0-9A-Za-z.\\-]+$"
+	// +kubebuilder:validation:MaxLength:=64
+	// +optional
+	ReleaseVersion *string `json:"releaseVersion,omitempty"`
+}
+
+// AllocationStrategy is the EC2 Fleet allocation strategy of each capacity type. Only the prioritized strategies
+// honor the priorities that Karpenter assigns to instance types from its instanceSelectionWeights.
+type AllocationStrategy struct {
+	// Spot is the allocation strategy for spot instances. price-capacity-optimized trades off price against the
+	// likelihood of interruption, capacity-optimized and capacity-optimized-prioritized launch into the deepest
+	// capacity pools, and lowest-price and diversified favor price over interruption rate.
+	// +kubebuilder:validation:Enum:={price-capacity-optimized,capacity-optimized,capacity-optimized-prioritized,lowest-price,diversified}
+	// +optional
+	Spot *string `json:"spot,omitempty"`
+	// OnDemand is the allocation strategy for on-demand instances.
+	// +kubebuilder:validation:Enum:={lowest-price,prioritized}
+	// +optional
+	OnDemand *string `json:"onDemand,omitempty"`
+}
+
+// PrimaryNetworkInterface configures the addresses that EC2 assigns to the primary network interface when it launches an
+// instance. Some CNI configurations start pods faster when the addresses they hand out are already on the interface.
+type PrimaryNetworkInterface struct {
+	// SecondaryPrivateIPAddressCount is the number of secondary private IPv4 addresses to assign to the interface.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=49
+	// +optional
+	SecondaryPrivateIPAddressCount *int64 `json:"secondaryPrivateIPAddressCount,omitempty"`
+	// IPv4PrefixCount is the number of /28 IPv4 prefixes to assign to the interface.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=49
+	// +optional
+	IPv4PrefixCount *int64 `json:"ipv4PrefixCount,omitempty"`
+}
+
+// AdditionalNetworkInterface is a network interface that EC2 creates and attaches to an instance when it launches it.
+// Some appliances need an interface in a separate subnet or security group from the primary network interface.
+type AdditionalNetworkInterface struct {
+	// DeviceIndex is the position of the interface on the instance. The primary network interface has device index 0.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=15
+	// +required
+	DeviceIndex int64 `json:"deviceIndex"`
+	// SubnetSelectorTerms is a list of or subnet selector terms that select the subnets the interface is created in.
+	// Instances are only launched in the zones in which every additional network interface has a subnet.
+	// +kubebuilder:validation:XValidation:message="subnetSelectorTerms cannot be empty",rule="self.size() != 0"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'zoneID']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.zoneID))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in subnetSelectorTerms",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.zoneID)))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +required
+	SubnetSelectorTerms []SubnetSelectorTerm `json:"subnetSelectorTerms"`
+	// SecurityGroupSelectorTerms is a list of or security group selector terms that select the security groups of the
+	// interface. If not specified, the interface is in the security groups of the nodeclass.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelectorTerms",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name)))"
+	// +kubebuilder:validation:XValidation:message="'name' is mutually exclusive, cannot be set with a combination of other fields in securityGroupSelectorTerms",rule="!self.all(x, has(x.name) && (has(x.tags) || has(x.id)))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	SecurityGroupSelectorTerms []SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms,omitempty"`
+	// DeleteOnTermination controls whether the interface is deleted when the instance is terminated. Defaults to true.
+	// +optional
+	DeleteOnTermination *bool `json:"deleteOnTermination,omitempty"`
+}
+
+// ContainerRegistries configures the pause image and the registry mirrors of the container runtime on provisioned nodes.
+type ContainerRegistries struct {
+	// PauseImage is the image of the sandbox (pause) container that the container runtime starts for each pod, e.g.
+	// 111122223333.dkr.ecr.us-west-2.amazonaws.com/eks/pause:3.9. If omitted, the AMI's default pause image is used.
+	// +kubebuilder:validation:Pattern:="^[0-9A-Za-z./:@_\\-]+$"
+	// +optional
+	PauseImage *string `json:"pauseImage,omitempty"`
+	// Mirrors redirect the image pulls of a registry to mirror endpoints.
+	// +kubebuilder:validation:XValidation:message="mirrors must have unique registries",rule="self.all(x, self.exists_one(y, x.registry == y.registry))"
+	// +kubebuilder:validation:MaxItems:=20
+	// +optional
+	Mirrors []RegistryMirror `json:"mirrors,omitempty"`
+}
+
+// RegistryMirror redirects the image pulls of a registry to mirror endpoints.
+type RegistryMirror struct {
+	// Registry is the host of the registry whose pulls are redirected, e.g. public.ecr.aws or docker.io.
+	// +kubebuilder:validation:Pattern:="^[0-9A-Za-z.\\-]+(:[0-9]+)?$"
+	// +required
+	Registry string `json:"registry"`
+	// Endpoints are the URLs of the mirrors, tried in order before falling back to the registry itself.
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:MaxItems:=10
+	// +required
+	Endpoints []string `json:"endpoints"`
+}
+
+// BottlerocketConfiguration is a structured subset of the Bottlerocket settings, see
+// https://bottlerocket.dev/en/os/latest/api/settings/
+type BottlerocketConfiguration struct {
+	// Kernel configures the kernel of the nodes.
+	// +optional
+	Kernel *BottlerocketKernel `json:"kernel,omitempty"`
+	// HostContainers configures the host containers of the nodes by name, e.g. admin or control.
+	// +kubebuilder:validation:XValidation:message="host container names must consist of lowercase alphanumeric characters and '-'",rule="self.all(k, k.matches('^[a-z0-9]([a-z0-9-]*[a-z0-9])?$'))"
+	// +kubebuilder:validation:MaxProperties:=10
+	// +optional
+	HostContainers map[string]BottlerocketHostContainer `json:"hostContainers,omitempty"`
+	// Updates configures how the nodes update Bottlerocket in place.
+	// +optional
+	Updates *BottlerocketUpdates `json:"updates,omitempty"`
+}
+
+// BottlerocketKernel configures the kernel of Bottlerocket nodes.
+type BottlerocketKernel struct {
+	// Lockdown is the kernel lockdown mode.
+	// +kubebuilder:validation:Enum:={none,integrity,confidentiality}
+	// +optional
+	Lockdown *string `json:"lockdown,omitempty"`
+	// Sysctls are the kernel parameters that are set at boot, e.g. net.core.somaxconn.
+	// +kubebuilder:validation:XValidation:message="sysctl keys must be kernel parameter names",rule="self.all(k, k.matches('^[a-z0-9_]+([./][a-z0-9_-]+)*$'))"
+	// +kubebuilder:validation:MaxProperties:=100
+	// +optional
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+}
+
+// BottlerocketHostContainer configures a host container of Bottlerocket nodes.
+type BottlerocketHostContainer struct {
+	// Source is the image of the host container.
+	// +kubebuilder:validation:MinLength:=1
+	// +optional
+	Source *string `json:"source,omitempty"`
+	// Enabled controls if the host container runs.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// Superpowered gives the host container additional privileges on the host.
+	// +optional
+	Superpowered *bool `json:"superpowered,omitempty"`
+	// UserData is base64 encoded data that is passed to the host container.
+	// +kubebuilder:validation:Pattern:="^[A-Za-z0-9+/]*={0,2}$"
+	// +optional
+	UserData *string `json:"userData,omitempty"`
+}
+
+// BottlerocketUpdates configures how Bottlerocket nodes update themselves in place.
+type BottlerocketUpdates struct {
+	// VersionLock is the version of Bottlerocket that nodes update to, e.g. v1.20.0, or latest.
+	// +kubebuilder:validation:Pattern:="^(latest|v[0-9]+\\.[0-9]+\\.[0-9]+)$"
+	// +optional
+	VersionLock *string `json:"versionLock,omitempty"`
+	// IgnoreWaves makes nodes update as soon as an update is released rather than in waves.
+	// +optional
+	IgnoreWaves *bool `json:"ignoreWaves,omitempty"`
+}
+
+// SubnetSelectionPolicy enumerates the policies for choosing between the subnets in a zone.
+type SubnetSelectionPolicy string
+
+const (
+	SubnetSelectionPolicyMostAvailableIPs     SubnetSelectionPolicy = "MostAvailableIPs"
+	SubnetSelectionPolicyBalanced             SubnetSelectionPolicy = "Balanced"
+	SubnetSelectionPolicyDedicatedPerNodePool SubnetSelectionPolicy = "DedicatedPerNodePool"
+)
+
+// KubeletOverlays are the kubelet overlays of each capacity type
+type KubeletOverlays struct {
+	// Spot is applied to the kubelet configuration of spot nodes.
+	// +kubebuilder:validation:XValidation:message="evictionSoft OwnerKey does not have a matching evictionSoftGracePeriod",rule="has(self.evictionSoft) ? self.evictionSoft.all(e, (e in self.evictionSoftGracePeriod)):true"
+	// +kubebuilder:validation:XValidation:message="evictionSoftGracePeriod OwnerKey does not have a matching evictionSoft",rule="has(self.evictionSoftGracePeriod) ? self.evictionSoftGracePeriod.all(e, (e in self.evictionSoft)):true"
+	// +optional
+	Spot *KubeletOverlay `json:"spot,omitempty"`
+	// OnDemand is applied to the kubelet configuration of on-demand nodes.
+	// +kubebuilder:validation:XValidation:message="evictionSoft OwnerKey does not have a matching evictionSoftGracePeriod",rule="has(self.evictionSoft) ? self.evictionSoft.all(e, (e in self.evictionSoftGracePeriod)):true"
+	// +kubebuilder:validation:XValidation:message="evictionSoftGracePeriod OwnerKey does not have a matching evictionSoft",rule="has(self.evictionSoftGracePeriod) ? self.evictionSoftGracePeriod.all(e, (e in self.evictionSoft)):true"
+	// +optional
+	OnDemand *KubeletOverlay `json:"onDemand,omitempty"`
+}
+
+// KubeletOverlay holds the resource reservations and eviction settings of a capacity type's kubelet overlay. Its maps
+// are merged by key into the NodePool's kubelet configuration, and its values take precedence.
+type KubeletOverlay struct {
+	// SystemReserved contains resources reserved for OS system daemons and kernel memory.
+	// +kubebuilder:validation:XValidation:message="valid keys for systemReserved are ['cpu','memory','ephemeral-storage','pid']",rule="self.all(x, x=='cpu' || x=='memory' || x=='ephemeral-storage' || x=='pid')"
+	// +kubebuilder:validation:XValidation:message="systemReserved value cannot be a negative resource quantity",rule="self.all(x, !self[x].startsWith('-'))"
+	// +optional
+	SystemReserved map[string]string `json:"systemReserved,omitempty"`
+	// KubeReserved contains resources reserved for Kubernetes system components.
+	// +kubebuilder:validation:XValidation:message="valid keys for kubeReserved are ['cpu','memory','ephemeral-storage','pid']",rule="self.all(x, x=='cpu' || x=='memory' || x=='ephemeral-storage' || x=='pid')"
+	// +kubebuilder:validation:XValidation:message="kubeReserved value cannot be a negative resource quantity",rule="self.all(x, !self[x].startsWith('-'))"
+	// +optional
+	KubeReserved map[string]string `json:"kubeReserved,omitempty"`
+	// EvictionHard is the map of signal names to quantities that define hard eviction thresholds
+	// +kubebuilder:validation:XValidation:message="valid keys for evictionHard are ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available']",rule="self.all(x, x in ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available'])"
+	// +optional
+	EvictionHard map[string]string `json:"evictionHard,omitempty"`
+	// EvictionSoft is the map of signal names to quantities that define soft eviction thresholds
+	// +kubebuilder:validation:XValidation:message="valid keys for evictionSoft are ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available']",rule="self.all(x, x in ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available'])"
+	// +optional
+	EvictionSoft map[string]string `json:"evictionSoft,omitempty"`
+	// EvictionSoftGracePeriod is the map of signal names to quantities that define grace periods for each eviction signal
+	// +kubebuilder:validation:XValidation:message="valid keys for evictionSoftGracePeriod are ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available']",rule="self.all(x, x in ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available'])"
+	// +optional
+	EvictionSoftGracePeriod map[string]metav1.Duration `json:"evictionSoftGracePeriod,omitempty"`
+	// EvictionMaxPodGracePeriod is the maximum allowed grace period (in seconds) to use when terminating pods in
+	// response to soft eviction thresholds being met.
+	// +optional
+	EvictionMaxPodGracePeriod *int32 `json:"evictionMaxPodGracePeriod,omitempty"`
+}
+
+// AMISelectionStrategy enumerates the strategies for choosing between discovered AMIs.
+type AMISelectionStrategy string
+
+const (
+	AMISelectionStrategyLatest AMISelectionStrategy = "Latest"
+	AMISelectionStrategyPinned AMISelectionStrategy = "Pinned"
+)
+
+// BootMode enumerates the boot modes that instances can be launched with.
+// +kubebuilder:validation:Enum:={uefi,legacy-bios}
+type BootMode string
+
+const (
+	BootModeUEFI       BootMode = "uefi"
+	BootModeLegacyBIOS BootMode = "legacy-bios"
+)
+
 // InstanceStorePolicy enumerates options for configuring instance store disks.
 // +kubebuilder:validation:Enum={RAID0}
 type InstanceStorePolicy string
@@ -326,6 +665,9 @@ type EC2NodeClass struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	// +kubebuilder:validation:XValidation:message="amiSelectorTerms is required when amiFamily == 'Custom'",rule="self.amiFamily == 'Custom' ? self.amiSelectorTerms.size() != 0 : true"
+	// +kubebuilder:validation:XValidation:message="bottlerocket requires amiFamily to be 'Bottlerocket'",rule="has(self.bottlerocket) ? self.amiFamily == 'Bottlerocket' : true"
+	// +kubebuilder:validation:XValidation:message="releaseVersion is only supported when amiFamily is 'AL2', 'AL2023' or 'Bottlerocket'",rule="has(self.amiSelectionPolicy) && has(self.amiSelectionPolicy.releaseVersion) ? self.amiFamily in ['AL2', 'AL2023', 'Bottlerocket'] : true"
+	// +kubebuilder:validation:XValidation:message="nitroTPM requires bootMode to be 'uefi'",rule="has(self.nitroTPM) && self.nitroTPM ? has(self.bootMode) && self.bootMode == 'uefi' : true"
 	// +kubebuilder:validation:XValidation:message="must specify exactly one of ['role', 'instanceProfile']",rule="(has(self.role) && !has(self.instanceProfile)) || (!has(self.role) && has(self.instanceProfile))"
 	// +kubebuilder:validation:XValidation:message="changing from 'instanceProfile' to 'role' is not supported. You must delete and recreate this node class if you want to change this.",rule="(has(oldSelf.role) && has(self.role)) || (has(oldSelf.instanceProfile) && has(self.instanceProfile))"
 	Spec   EC2NodeClassSpec   `json:"spec,omitempty"`
@@ -346,6 +688,27 @@ func (in *EC2NodeClass) Hash() string {
 	})))
 }
 
+// AMISelectionHash returns a hash of the fields of the spec that the AMIs of the EC2NodeClass are resolved from
+func (in *EC2NodeClass) AMISelectionHash() string {
+	var releaseVersion *string
+	if in.Spec.AMISelectionPolicy != nil {
+		releaseVersion = in.Spec.AMISelectionPolicy.ReleaseVersion
+	}
+	return fmt.Sprint(lo.Must(hashstructure.Hash(struct {
+		AMIFamily        *string
+		AMISelectorTerms []AMISelectorTerm
+		ReleaseVersion   *string
+	}{
+		AMIFamily:        in.Spec.AMIFamily,
+		AMISelectorTerms: in.Spec.AMISelectorTerms,
+		ReleaseVersion:   releaseVersion,
+	}, hashstructure.FormatV2, &hashstructure.HashOptions{
+		SlicesAsSets:    true,
+		IgnoreZeroValue: true,
+		ZeroNil:         true,
+	})))
+}
+
 func (in *EC2NodeClass) InstanceProfileName(clusterName, region string) string {
 	return fmt.Sprintf("%s_%d", clusterName, lo.Must(hashstructure.Hash(fmt.Sprintf("%s%s", region, in.Name), hashstructure.FormatV2, nil)))
 }
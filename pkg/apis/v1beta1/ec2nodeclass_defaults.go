@@ -16,7 +16,28 @@ package v1beta1
 
 import (
 	"context"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
 )
 
 // SetDefaults for the EC2NodeClass
-func (in *EC2NodeClass) SetDefaults(_ context.Context) {}
+func (in *EC2NodeClass) SetDefaults(_ context.Context) {
+	in.Spec.setBlockDeviceMappingDefaults()
+}
+
+// setBlockDeviceMappingDefaults defaults EBS volumes to encrypted gp3 volumes, matching the block device mappings
+// that Karpenter configures for each AMI family. Volumes created from snapshots inherit the snapshot's encryption.
+func (in *EC2NodeClassSpec) setBlockDeviceMappingDefaults() {
+	for _, blockDeviceMapping := range in.BlockDeviceMappings {
+		if blockDeviceMapping == nil || blockDeviceMapping.EBS == nil {
+			continue
+		}
+		if blockDeviceMapping.EBS.VolumeType == nil {
+			blockDeviceMapping.EBS.VolumeType = lo.ToPtr(ec2.VolumeTypeGp3)
+		}
+		if blockDeviceMapping.EBS.Encrypted == nil && blockDeviceMapping.EBS.SnapshotID == nil {
+			blockDeviceMapping.EBS.Encrypted = lo.ToPtr(true)
+		}
+	}
+}
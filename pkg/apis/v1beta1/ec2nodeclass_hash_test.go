@@ -104,6 +104,7 @@ var _ = Describe("Hash", func() {
 		Entry("Modified AMISelector", staticHash, v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AMISelectorTerms: []v1beta1.AMISelectorTerm{{Tags: map[string]string{"ami-test-key": "ami-test-value"}}}}}),
 		Entry("Modified SubnetSelector", staticHash, v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"subnet-test-key": "subnet-test-value"}}}}}),
 		Entry("Modified SecurityGroupSelector", staticHash, v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{SecurityGroupSelectorTerms: []v1beta1.SecurityGroupSelectorTerm{{Tags: map[string]string{"security-group-test-key": "security-group-test-value"}}}}}),
+		Entry("Modified MetadataLabels", staticHash, v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataLabels: map[string]string{"example.com/zone-id": "{{ .ZoneID }}"}}}),
 	)
 	// We create a separate test for updating blockDeviceMapping volumeSize, since resource.Quantity is a struct, and mergo.WithSliceDeepCopy
 	// doesn't work well with unexported fields, like the ones that are present in resource.Quantity
@@ -132,11 +133,17 @@ var _ = Describe("Hash", func() {
 	},
 		Entry("UserData", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{UserData: aws.String("userdata-test-2")}}),
 		Entry("Tags", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{Tags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
+		Entry("VolumeTags", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{VolumeTags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
+		Entry("FleetTags", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{FleetTags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
 		Entry("Context", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{Context: aws.String("context-2")}}),
 		Entry("DetailedMonitoring", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{DetailedMonitoring: aws.Bool(true)}}),
 		Entry("AMIFamily", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AMIFamily: aws.String(v1beta1.AMIFamilyBottlerocket)}}),
 		Entry("InstanceStorePolicy", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{InstanceStorePolicy: lo.ToPtr(v1beta1.InstanceStorePolicyRAID0)}}),
 		Entry("AssociatePublicIPAddress", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AssociatePublicIPAddress: lo.ToPtr(true)}}),
+		Entry("PrimaryNetworkInterface", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{PrimaryNetworkInterface: &v1beta1.PrimaryNetworkInterface{IPv4PrefixCount: lo.ToPtr(int64(1))}}}),
+		Entry("AdditionalNetworkInterfaces", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{AdditionalNetworkInterfaces: []v1beta1.AdditionalNetworkInterface{{DeviceIndex: 1}}}}),
+		Entry("Bottlerocket", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{Bottlerocket: &v1beta1.BottlerocketConfiguration{Kernel: &v1beta1.BottlerocketKernel{Lockdown: lo.ToPtr("integrity")}}}}),
+		Entry("KubeletOverlays", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{KubeletOverlays: &v1beta1.KubeletOverlays{Spot: &v1beta1.KubeletOverlay{SystemReserved: map[string]string{"memory": "1Gi"}}}}}),
 		Entry("MetadataOptions HTTPEndpoint", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPEndpoint: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPProtocolIPv6", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPProtocolIPv6: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPPutResponseHopLimit", v1beta1.EC2NodeClass{Spec: v1beta1.EC2NodeClassSpec{MetadataOptions: &v1beta1.MetadataOptions{HTTPPutResponseHopLimit: lo.ToPtr(int64(10))}}}),
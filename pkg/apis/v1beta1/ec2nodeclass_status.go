@@ -30,6 +30,15 @@ type Subnet struct {
 	// The associated availability zone ID
 	// +optional
 	ZoneID string `json:"zoneID,omitempty"`
+	// The type of the zone, which is one of availability-zone, local-zone, or wavelength-zone
+	// +optional
+	ZoneType string `json:"zoneType,omitempty"`
+	// The ARN of the AWS Outpost that the subnet is on, which is empty for subnets in the region
+	// +optional
+	OutpostARN string `json:"outpostARN,omitempty"`
+	// The account that owns the subnet, which is another account for subnets that are shared with AWS RAM
+	// +optional
+	OwnerID string `json:"ownerID,omitempty"`
 }
 
 // SecurityGroup contains resolved SecurityGroup selector values utilized for node launch
@@ -40,6 +49,23 @@ type SecurityGroup struct {
 	// Name of the security group
 	// +optional
 	Name string `json:"name,omitempty"`
+	// The account that owns the security group, which is another account for security groups that are shared with
+	// AWS RAM
+	// +optional
+	OwnerID string `json:"ownerID,omitempty"`
+}
+
+// AdditionalNetworkInterfaceStatus contains the resolved selector values of an additional network interface
+type AdditionalNetworkInterfaceStatus struct {
+	// DeviceIndex of the additional network interface
+	// +required
+	DeviceIndex int64 `json:"deviceIndex"`
+	// Subnets that the interface can be created in
+	// +optional
+	Subnets []Subnet `json:"subnets,omitempty"`
+	// SecurityGroups of the interface
+	// +optional
+	SecurityGroups []SecurityGroup `json:"securityGroups,omitempty"`
 }
 
 // AMI contains resolved AMI selector values utilized for node launch
@@ -50,6 +76,14 @@ type AMI struct {
 	// Name of the AMI
 	// +optional
 	Name string `json:"name,omitempty"`
+	// Variant of a default AMI, e.g. standard, nvidia or neuron, which is resolved for the instance types that
+	// match its requirements. It's unset for AMIs that are selected by amiSelectorTerms.
+	// +optional
+	Variant string `json:"variant,omitempty"`
+	// ENASupport is whether the AMI has ENA enabled. Instance types that don't support ENA can't be launched with AMIs
+	// that have it enabled, and instance types that require ENA can't be launched with AMIs that don't.
+	// +optional
+	ENASupport *bool `json:"enaSupport,omitempty"`
 	// Requirements of the AMI to be utilized on an instance type
 	// +required
 	Requirements []v1.NodeSelectorRequirement `json:"requirements"`
@@ -69,6 +103,14 @@ type EC2NodeClassStatus struct {
 	// cluster under the AMI selectors.
 	// +optional
 	AMIs []AMI `json:"amis,omitempty"`
+	// AMISelectionHash is the hash of the amiFamily, amiSelectorTerms and pinned release version that the AMIs were
+	// resolved from. EC2NodeClasses with the Pinned strategy resolve their AMIs again when it changes.
+	// +optional
+	AMISelectionHash string `json:"amiSelectionHash,omitempty"`
+	// AdditionalNetworkInterfaces contains the resolved subnets and security groups of the additional network
+	// interfaces
+	// +optional
+	AdditionalNetworkInterfaces []AdditionalNetworkInterfaceStatus `json:"additionalNetworkInterfaces,omitempty"`
 	// InstanceProfile contains the resolved instance profile for the role
 	// +optional
 	InstanceProfile string `json:"instanceProfile,omitempty"`
@@ -77,6 +119,21 @@ type EC2NodeClassStatus struct {
 	Conditions []status.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// ConditionTypeAMIResolutionFailed is true when a NodePool that references the EC2NodeClass can launch an
+	// architecture that none of the resolved AMIs support
+	ConditionTypeAMIResolutionFailed = "AMIResolutionFailed"
+	// ConditionTypeSharedResources is true when the EC2NodeClass selects subnets or security groups that are owned by
+	// another account. Karpenter can launch into shared resources but can't tag or modify them.
+	ConditionTypeSharedResources = "SharedResources"
+	// ConditionTypeKMSKeyUnusable is true when a block device mapping of the EC2NodeClass encrypts its volume with a KMS
+	// key that doesn't exist, isn't enabled, or can't be granted to EC2, so launches with it would fail
+	ConditionTypeKMSKeyUnusable = "KMSKeyUnusable"
+	// ConditionTypeMaxPodsExceedENILimits is true when a NodePool that references the EC2NodeClass configures a kubelet
+	// maxPods that's higher than the number of pods that the ENIs of some of its instance types have IPs for
+	ConditionTypeMaxPodsExceedENILimits = "MaxPodsExceedENILimits"
+)
+
 func (in *EC2NodeClass) StatusConditions() status.ConditionSet {
 	return status.NewReadyConditions().For(in)
 }
@@ -18,11 +18,15 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"text/template"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
+	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 )
 
 const (
@@ -30,16 +34,47 @@ const (
 	securityGroupSelectorTermsPath = "securityGroupSelectorTerms"
 	amiSelectorTermsPath           = "amiSelectorTerms"
 	amiFamilyPath                  = "amiFamily"
+	amiSelectionPolicyPath         = "amiSelectionPolicy"
 	tagsPath                       = "tags"
+	volumeTagsPath                 = "volumeTags"
+	fleetTagsPath                  = "fleetTags"
+	metadataLabelsPath             = "metadataLabels"
 	metadataOptionsPath            = "metadataOptions"
 	blockDeviceMappingsPath        = "blockDeviceMappings"
 	rolePath                       = "role"
 	instanceProfilePath            = "instanceProfile"
+	allocationStrategyPath         = "allocationStrategy"
+	kubeletOverlaysPath            = "kubeletOverlays"
 )
 
 var (
 	minVolumeSize = *resource.NewScaledQuantity(1, resource.Giga)
 	maxVolumeSize = *resource.NewScaledQuantity(64, resource.Tera)
+
+	// rootVolumeDeviceNames are the devices that hold the kubelet root dir for each AMI family. Custom AMIs may use any
+	// device.
+	rootVolumeDeviceNames = map[string]string{
+		AMIFamilyAL2:          "/dev/xvda",
+		AMIFamilyAL2023:       "/dev/xvda",
+		AMIFamilyUbuntu:       "/dev/xvda",
+		AMIFamilyBottlerocket: "/dev/xvdb",
+		AMIFamilyWindows2019:  "/dev/sda1",
+		AMIFamilyWindows2022:  "/dev/sda1",
+	}
+	// iopsRanges are the IOPS that can be provisioned for each volume type. Other volume types don't support
+	// provisioned IOPS.
+	iopsRanges = map[string][2]int64{
+		ec2.VolumeTypeGp3: {3000, 16000},
+		ec2.VolumeTypeIo1: {100, 64000},
+		ec2.VolumeTypeIo2: {100, 256000},
+	}
+)
+
+const (
+	minThroughput = 125
+	maxThroughput = 1000
+	// maxThroughputPerIOPS is the highest ratio of throughput (MiB/s) to IOPS that gp3 volumes support
+	maxThroughputPerIOPS = 0.25
 )
 
 func (in *EC2NodeClass) SupportedVerbs() []admissionregistrationv1.OperationType {
@@ -79,8 +114,14 @@ func (in *EC2NodeClassSpec) validate(_ context.Context) (errs *apis.FieldError)
 		in.validateAMISelectorTerms().ViaField(amiSelectorTermsPath),
 		in.validateMetadataOptions().ViaField(metadataOptionsPath),
 		in.validateAMIFamily().ViaField(amiFamilyPath),
+		in.validateAMISelectionPolicy().ViaField(amiSelectionPolicyPath),
 		in.validateBlockDeviceMappings().ViaField(blockDeviceMappingsPath),
-		in.validateTags().ViaField(tagsPath),
+		in.validateAllocationStrategy().ViaField(allocationStrategyPath),
+		in.validateKubeletOverlays().ViaField(kubeletOverlaysPath),
+		validateResourceTags(in.Tags, "instance").ViaField(tagsPath),
+		validateResourceTags(in.VolumeTags, "volume").ViaField(volumeTagsPath),
+		validateResourceTags(in.FleetTags, "fleet").ViaField(fleetTagsPath),
+		in.validateMetadataLabels().ViaField(metadataLabelsPath),
 	)
 }
 
@@ -96,9 +137,9 @@ func (in *EC2NodeClassSpec) validateSubnetSelectorTerms() (errs *apis.FieldError
 
 func (in *SubnetSelectorTerm) validate() (errs *apis.FieldError) {
 	errs = errs.Also(validateTags(in.Tags).ViaField("tags"))
-	if len(in.Tags) == 0 && in.ID == "" {
-		errs = errs.Also(apis.ErrGeneric("expected at least one, got none", "tags", "id"))
-	} else if in.ID != "" && len(in.Tags) > 0 {
+	if len(in.Tags) == 0 && in.ID == "" && in.ZoneID == "" {
+		errs = errs.Also(apis.ErrGeneric("expected at least one, got none", "tags", "id", "zoneID"))
+	} else if in.ID != "" && (len(in.Tags) > 0 || in.ZoneID != "") {
 		errs = errs.Also(apis.ErrGeneric(`"id" is mutually exclusive, cannot be set with a combination of other fields in`))
 	}
 	return errs
@@ -201,6 +242,32 @@ func (in *EC2NodeClassSpec) validateHTTPTokens() *apis.FieldError {
 	return in.validateStringEnum(*in.MetadataOptions.HTTPTokens, "httpTokens", ec2.LaunchTemplateHttpTokensState_Values())
 }
 
+func (in *EC2NodeClassSpec) validateAllocationStrategy() (errs *apis.FieldError) {
+	if in.AllocationStrategy == nil {
+		return nil
+	}
+	if in.AllocationStrategy.Spot == nil && in.AllocationStrategy.OnDemand == nil {
+		return apis.ErrMissingOneOf("spot", "onDemand")
+	}
+	if in.AllocationStrategy.Spot != nil {
+		errs = errs.Also(in.validateStringEnum(*in.AllocationStrategy.Spot, "spot", ec2.SpotAllocationStrategy_Values()))
+	}
+	if in.AllocationStrategy.OnDemand != nil {
+		errs = errs.Also(in.validateStringEnum(*in.AllocationStrategy.OnDemand, "onDemand", ec2.FleetOnDemandAllocationStrategy_Values()))
+	}
+	return errs
+}
+
+func (in *EC2NodeClassSpec) validateKubeletOverlays() *apis.FieldError {
+	if in.KubeletOverlays == nil {
+		return nil
+	}
+	if in.KubeletOverlays.Spot == nil && in.KubeletOverlays.OnDemand == nil {
+		return apis.ErrMissingOneOf("spot", "onDemand")
+	}
+	return nil
+}
+
 func (in *EC2NodeClassSpec) validateStringEnum(value, field string, validValues []string) *apis.FieldError {
 	for _, validValue := range validValues {
 		if value == validValue {
@@ -234,6 +301,13 @@ func (in *EC2NodeClassSpec) validateDeviceName(blockDeviceMapping *BlockDeviceMa
 	if blockDeviceMapping.DeviceName == nil {
 		return apis.ErrMissingField("deviceName")
 	}
+	if !blockDeviceMapping.RootVolume || in.AMIFamily == nil {
+		return nil
+	}
+	// The kubelet root dir is only on the root volume if the device matches the AMI family's
+	if deviceName, ok := rootVolumeDeviceNames[*in.AMIFamily]; ok && *blockDeviceMapping.DeviceName != deviceName {
+		return apis.ErrInvalidValue(fmt.Sprintf("%s must be %s for the %s AMI family", *blockDeviceMapping.DeviceName, deviceName, *in.AMIFamily), "deviceName")
+	}
 	return nil
 }
 
@@ -244,6 +318,8 @@ func (in *EC2NodeClassSpec) validateEBS(blockDeviceMapping *BlockDeviceMapping)
 	for _, err := range []*apis.FieldError{
 		in.validateVolumeType(blockDeviceMapping),
 		in.validateVolumeSize(blockDeviceMapping),
+		in.validateIOPS(blockDeviceMapping),
+		in.validateThroughput(blockDeviceMapping),
 	} {
 		if err != nil {
 			errs = errs.Also(err.ViaField("ebs"))
@@ -259,6 +335,42 @@ func (in *EC2NodeClassSpec) validateVolumeType(blockDeviceMapping *BlockDeviceMa
 	return nil
 }
 
+// volumeType returns the volume type of the block device, which is defaulted to gp3
+func volumeType(blockDeviceMapping *BlockDeviceMapping) string {
+	return lo.FromPtrOr(blockDeviceMapping.EBS.VolumeType, ec2.VolumeTypeGp3)
+}
+
+func (in *EC2NodeClassSpec) validateIOPS(blockDeviceMapping *BlockDeviceMapping) *apis.FieldError {
+	if blockDeviceMapping.EBS.IOPS == nil {
+		return nil
+	}
+	iopsRange, ok := iopsRanges[volumeType(blockDeviceMapping)]
+	if !ok {
+		return apis.ErrGeneric(fmt.Sprintf("iops can't be provisioned for %s volumes", volumeType(blockDeviceMapping)), "iops")
+	}
+	if iops := *blockDeviceMapping.EBS.IOPS; iops < iopsRange[0] || iops > iopsRange[1] {
+		return apis.ErrOutOfBoundsValue(iops, iopsRange[0], iopsRange[1], "iops")
+	}
+	return nil
+}
+
+func (in *EC2NodeClassSpec) validateThroughput(blockDeviceMapping *BlockDeviceMapping) *apis.FieldError {
+	if blockDeviceMapping.EBS.Throughput == nil {
+		return nil
+	}
+	if volumeType(blockDeviceMapping) != ec2.VolumeTypeGp3 {
+		return apis.ErrGeneric(fmt.Sprintf("throughput can't be provisioned for %s volumes", volumeType(blockDeviceMapping)), "throughput")
+	}
+	throughput := *blockDeviceMapping.EBS.Throughput
+	if throughput < minThroughput || throughput > maxThroughput {
+		return apis.ErrOutOfBoundsValue(throughput, minThroughput, maxThroughput, "throughput")
+	}
+	if iops := lo.FromPtrOr(blockDeviceMapping.EBS.IOPS, iopsRanges[ec2.VolumeTypeGp3][0]); float64(throughput) > float64(iops)*maxThroughputPerIOPS {
+		return apis.ErrGeneric(fmt.Sprintf("throughput of %d MiB/s requires at least %d iops", throughput, int64(float64(throughput)/maxThroughputPerIOPS)), "throughput")
+	}
+	return nil
+}
+
 func (in *EC2NodeClassSpec) validateVolumeSize(blockDeviceMapping *BlockDeviceMapping) *apis.FieldError {
 	// If an EBS mapping is present, one of volumeSize or snapshotID must be present
 	if blockDeviceMapping.EBS.SnapshotID != nil && blockDeviceMapping.EBS.VolumeSize == nil {
@@ -281,8 +393,36 @@ func (in *EC2NodeClassSpec) validateAMIFamily() (errs *apis.FieldError) {
 	return errs
 }
 
-func (in *EC2NodeClassSpec) validateTags() (errs *apis.FieldError) {
-	for k, v := range in.Tags {
+// validateAMISelectionPolicy rejects a release version for the AMI families whose default AMIs can't be resolved for a
+// specific release, rather than ignoring it
+func (in *EC2NodeClassSpec) validateAMISelectionPolicy() *apis.FieldError {
+	if in.AMISelectionPolicy == nil || in.AMISelectionPolicy.ReleaseVersion == nil || in.AMIFamily == nil {
+		return nil
+	}
+	if !lo.Contains([]string{AMIFamilyAL2, AMIFamilyAL2023, AMIFamilyBottlerocket}, *in.AMIFamily) {
+		return apis.ErrGeneric(fmt.Sprintf("releaseVersion isn't supported when amiFamily is %q", *in.AMIFamily), "releaseVersion")
+	}
+	return nil
+}
+
+// validateResourceTags validates the tags that are applied on a type of ec2 resource
+func (in *EC2NodeClassSpec) validateMetadataLabels() (errs *apis.FieldError) {
+	for k, v := range in.MetadataLabels {
+		for _, msg := range validation.IsQualifiedName(k) {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, apis.CurrentField, msg))
+		}
+		if v1beta1.IsRestrictedNodeLabel(k) {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, apis.CurrentField, "label domain is restricted"))
+		}
+		if _, err := template.New(k).Parse(v); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("invalid template, %s", err), k))
+		}
+	}
+	return errs
+}
+
+func validateResourceTags(tags map[string]string, resourceType string) (errs *apis.FieldError) {
+	for k, v := range tags {
 		if k == "" {
 			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf(
 				"the tag with key : '' and value : '%s' is invalid because empty tag keys aren't supported", v), "tags"))
@@ -292,6 +432,21 @@ func (in *EC2NodeClassSpec) validateTags() (errs *apis.FieldError) {
 				errs = errs.Also(apis.ErrInvalidKeyName(k, "tags", fmt.Sprintf("tag contains in restricted tag matching %q", pattern.String())))
 			}
 		}
+		if strings.HasPrefix(strings.ToLower(k), "aws:") {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, "tags", "tag keys with the aws: prefix are reserved for use by AWS"))
+		}
+		if len(k) > MaxTagKeyLength {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, "tags", fmt.Sprintf("tag keys may not be longer than %d characters", MaxTagKeyLength)))
+		}
+		if len(v) > MaxTagValueLength {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("tag values may not be longer than %d characters", MaxTagValueLength), fmt.Sprintf("tags[%s]", k)))
+		}
+	}
+	// Karpenter's tags are always applied to every resource, so they count against the EC2 tag limit. A user-specified Name
+	// tag replaces the one Karpenter would otherwise apply, so it doesn't consume an additional tag.
+	if n := len(lo.OmitByKeys(tags, []string{TagName})); n > MaxTags-ReservedTags {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%d tags specified but at most %d, not counting Name, are allowed since Karpenter reserves %d of the %d tags EC2 allows on a %s",
+			n, MaxTags-ReservedTags, ReservedTags, MaxTags, resourceType), "tags"))
 	}
 	return errs
 }
@@ -0,0 +1,209 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auditlog keeps a durable record of the launch decisions that Karpenter makes. Each CreateFleet request is
+// written as a line of JSON to a file or to S3, along with the instances that it launched and the errors that it
+// returned.
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// Launch is the audit record of a CreateFleet request
+type Launch struct {
+	Time               time.Time        `json:"time"`
+	ClusterName        string           `json:"clusterName"`
+	NodeClaim          string           `json:"nodeClaim"`
+	NodePool           string           `json:"nodePool,omitempty"`
+	CapacityType       string           `json:"capacityType,omitempty"`
+	AllocationStrategy string           `json:"allocationStrategy,omitempty"`
+	LaunchTemplates    []LaunchTemplate `json:"launchTemplates"`
+	InstanceIDs        []string         `json:"instanceIDs,omitempty"`
+	FleetErrors        []FleetError     `json:"fleetErrors,omitempty"`
+	// Error is set when the CreateFleet request itself failed
+	Error string `json:"error,omitempty"`
+}
+
+// LaunchTemplate is a launch template of a CreateFleet request and the overrides that were requested for it
+type LaunchTemplate struct {
+	Name      string     `json:"name,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Overrides []Override `json:"overrides"`
+}
+
+type Override struct {
+	InstanceType     string   `json:"instanceType"`
+	SubnetID         string   `json:"subnetID,omitempty"`
+	AvailabilityZone string   `json:"availabilityZone,omitempty"`
+	Priority         *float64 `json:"priority,omitempty"`
+	MaxPrice         string   `json:"maxPrice,omitempty"`
+}
+
+// FleetError is an error that EC2 Fleet returned for an override that it couldn't launch
+type FleetError struct {
+	Code             string `json:"code"`
+	Message          string `json:"message"`
+	InstanceType     string `json:"instanceType,omitempty"`
+	SubnetID         string `json:"subnetID,omitempty"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+}
+
+// NewLaunch returns the audit record of a CreateFleet request for the NodeClaim, its output, and the error that the
+// request returned
+func NewLaunch(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, input *ec2.CreateFleetInput, output *ec2.CreateFleetOutput, err error) *Launch {
+	launch := &Launch{
+		Time:        time.Now().UTC(),
+		ClusterName: options.FromContext(ctx).ClusterName,
+		NodeClaim:   nodeClaim.Name,
+		NodePool:    nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+	}
+	if input.TargetCapacitySpecification != nil {
+		launch.CapacityType = string(input.TargetCapacitySpecification.DefaultTargetCapacityType)
+	}
+	if input.SpotOptions != nil {
+		launch.AllocationStrategy = string(input.SpotOptions.AllocationStrategy)
+	} else if input.OnDemandOptions != nil {
+		launch.AllocationStrategy = string(input.OnDemandOptions.AllocationStrategy)
+	}
+	launch.LaunchTemplates = lo.Map(input.LaunchTemplateConfigs, func(ltc ec2types.FleetLaunchTemplateConfigRequest, _ int) LaunchTemplate {
+		lt := LaunchTemplate{Overrides: lo.Map(ltc.Overrides, func(o ec2types.FleetLaunchTemplateOverridesRequest, _ int) Override {
+			return Override{
+				InstanceType:     string(o.InstanceType),
+				SubnetID:         aws.StringValue(o.SubnetId),
+				AvailabilityZone: aws.StringValue(o.AvailabilityZone),
+				Priority:         o.Priority,
+				MaxPrice:         aws.StringValue(o.MaxPrice),
+			}
+		})}
+		if ltc.LaunchTemplateSpecification != nil {
+			lt.Name = aws.StringValue(ltc.LaunchTemplateSpecification.LaunchTemplateName)
+			lt.ID = aws.StringValue(ltc.LaunchTemplateSpecification.LaunchTemplateId)
+		}
+		return lt
+	})
+	if err != nil {
+		launch.Error = err.Error()
+	}
+	if output == nil {
+		return launch
+	}
+	for _, instance := range output.Instances {
+		launch.InstanceIDs = append(launch.InstanceIDs, instance.InstanceIds...)
+	}
+	launch.FleetErrors = lo.Map(output.Errors, func(e ec2types.CreateFleetError, _ int) FleetError {
+		fleetError := FleetError{Code: aws.StringValue(e.ErrorCode), Message: aws.StringValue(e.ErrorMessage)}
+		if e.LaunchTemplateAndOverrides != nil && e.LaunchTemplateAndOverrides.Overrides != nil {
+			fleetError.InstanceType = string(e.LaunchTemplateAndOverrides.Overrides.InstanceType)
+			fleetError.SubnetID = aws.StringValue(e.LaunchTemplateAndOverrides.Overrides.SubnetId)
+			fleetError.AvailabilityZone = aws.StringValue(e.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)
+		}
+		return fleetError
+	})
+	return launch
+}
+
+type Logger interface {
+	Log(context.Context, *Launch)
+}
+
+// New returns the Logger for the sink of the launch-audit-log setting, which is either the path of a file that
+// records are appended to, or an s3://bucket/prefix URL under which each record is written as its own object. No
+// records are kept when the sink is empty.
+func New(sink string, s3api s3iface.S3API) (Logger, error) {
+	if sink == "" {
+		return NopLogger{}, nil
+	}
+	if strings.HasPrefix(sink, "s3://") {
+		u, err := url.Parse(sink)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s, %w", sink, err)
+		}
+		return &S3Logger{s3api: s3api, bucket: u.Host, prefix: strings.Trim(u.Path, "/")}, nil
+	}
+	file, err := os.OpenFile(sink, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s, %w", sink, err)
+	}
+	return &FileLogger{file: file}, nil
+}
+
+type NopLogger struct{}
+
+func (NopLogger) Log(context.Context, *Launch) {}
+
+// FileLogger appends records to a file, e.g. on a persistent volume or one that's shipped by a log agent
+type FileLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (l *FileLogger) Log(ctx context.Context, launch *Launch) {
+	line, err := json.Marshal(launch)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed marshaling launch audit record")
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err = l.file.Write(append(line, '\n')); err != nil {
+		log.FromContext(ctx).Error(err, "failed writing launch audit record")
+	}
+}
+
+// S3Logger writes each record to its own object, keyed by the time of the launch so that the records of a period can
+// be listed in order. Records aren't buffered, so that none are lost when Karpenter restarts.
+type S3Logger struct {
+	s3api  s3iface.S3API
+	bucket string
+	prefix string
+}
+
+func (l *S3Logger) Log(ctx context.Context, launch *Launch) {
+	line, err := json.Marshal(launch)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed marshaling launch audit record")
+		return
+	}
+	key := path.Join(l.prefix, launch.ClusterName, launch.Time.Format("2006/01/02"), fmt.Sprintf("%s-%s.json", launch.Time.Format("150405.000000000"), uuid.NewUUID()))
+	if _, err = l.s3api.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(l.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(append(line, '\n')),
+		ContentType: aws.String("application/x-ndjson"),
+	}); err != nil {
+		log.FromContext(ctx).WithValues("bucket", l.bucket, "key", key).Error(err, "failed writing launch audit record")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditlog_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/auditlog"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+
+func TestAuditLog(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AuditLog")
+}
+
+var _ = BeforeSuite(func() {
+	ctx = options.ToContext(ctx, test.Options())
+})
+
+// s3API records the objects that are put
+type s3API struct {
+	s3iface.S3API
+	inputs []*s3.PutObjectInput
+	bodies []string
+}
+
+func (s *s3API) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.inputs = append(s.inputs, input)
+	s.bodies = append(s.bodies, string(body))
+	return &s3.PutObjectOutput{}, nil
+}
+
+var _ = Describe("AuditLog", func() {
+	var nodeClaim *corev1beta1.NodeClaim
+	var input *ec2.CreateFleetInput
+	var output *ec2.CreateFleetOutput
+
+	BeforeEach(func() {
+		nodeClaim = &corev1beta1.NodeClaim{ObjectMeta: metav1.ObjectMeta{
+			Name:   "default-abcde",
+			Labels: map[string]string{corev1beta1.NodePoolLabelKey: "default"},
+		}}
+		input = &ec2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{{
+				LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{LaunchTemplateName: aws.String("karpenter.k8s.aws/123")},
+				Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
+					{InstanceType: "m5.large", SubnetId: aws.String("subnet-1"), AvailabilityZone: aws.String("test-zone-1a")},
+					{InstanceType: "m5.large", SubnetId: aws.String("subnet-2"), AvailabilityZone: aws.String("test-zone-1b"), Priority: aws.Float64(1)},
+				},
+			}},
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{DefaultTargetCapacityType: ec2types.DefaultTargetCapacityTypeSpot},
+			SpotOptions:                 &ec2types.SpotOptionsRequest{AllocationStrategy: ec2types.SpotAllocationStrategyPriceCapacityOptimized},
+		}
+		output = &ec2.CreateFleetOutput{
+			Instances: []ec2types.CreateFleetInstance{{InstanceIds: []string{"i-123"}}},
+			Errors: []ec2types.CreateFleetError{{
+				ErrorCode:    aws.String("InsufficientInstanceCapacity"),
+				ErrorMessage: aws.String("insufficient capacity"),
+				LaunchTemplateAndOverrides: &ec2types.LaunchTemplateAndOverridesResponse{Overrides: &ec2types.FleetLaunchTemplateOverrides{
+					InstanceType:     "m5.large",
+					SubnetId:         aws.String("subnet-1"),
+					AvailabilityZone: aws.String("test-zone-1a"),
+				}},
+			}},
+		}
+	})
+	It("should record the request, instances and errors of a launch", func() {
+		launch := auditlog.NewLaunch(ctx, nodeClaim, input, output, nil)
+		Expect(launch.ClusterName).To(Equal(options.FromContext(ctx).ClusterName))
+		Expect(launch.NodeClaim).To(Equal("default-abcde"))
+		Expect(launch.NodePool).To(Equal("default"))
+		Expect(launch.CapacityType).To(Equal(corev1beta1.CapacityTypeSpot))
+		Expect(launch.AllocationStrategy).To(Equal(string(ec2types.SpotAllocationStrategyPriceCapacityOptimized)))
+		Expect(launch.LaunchTemplates).To(Equal([]auditlog.LaunchTemplate{{
+			Name: "karpenter.k8s.aws/123",
+			Overrides: []auditlog.Override{
+				{InstanceType: "m5.large", SubnetID: "subnet-1", AvailabilityZone: "test-zone-1a"},
+				{InstanceType: "m5.large", SubnetID: "subnet-2", AvailabilityZone: "test-zone-1b", Priority: aws.Float64(1)},
+			},
+		}}))
+		Expect(launch.InstanceIDs).To(Equal([]string{"i-123"}))
+		Expect(launch.FleetErrors).To(Equal([]auditlog.FleetError{{
+			Code:             "InsufficientInstanceCapacity",
+			Message:          "insufficient capacity",
+			InstanceType:     "m5.large",
+			SubnetID:         "subnet-1",
+			AvailabilityZone: "test-zone-1a",
+		}}))
+		Expect(launch.Error).To(BeEmpty())
+	})
+	It("should record the error of a failed request", func() {
+		launch := auditlog.NewLaunch(ctx, nodeClaim, input, nil, fmt.Errorf("unauthorized"))
+		Expect(launch.Error).To(Equal("unauthorized"))
+		Expect(launch.InstanceIDs).To(BeEmpty())
+		Expect(launch.LaunchTemplates).To(HaveLen(1))
+	})
+	It("should not keep records when no sink is configured", func() {
+		logger, err := auditlog.New("", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(logger).To(Equal(auditlog.NopLogger{}))
+	})
+	It("should append records to a file as JSON lines", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "launches.jsonl")
+		logger, err := auditlog.New(path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		logger.Log(ctx, auditlog.NewLaunch(ctx, nodeClaim, input, output, nil))
+		logger.Log(ctx, auditlog.NewLaunch(ctx, nodeClaim, input, nil, fmt.Errorf("unauthorized")))
+
+		file := lo.Must(os.Open(path))
+		defer file.Close()
+		var launches []auditlog.Launch
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			launch := auditlog.Launch{}
+			Expect(json.Unmarshal(scanner.Bytes(), &launch)).To(Succeed())
+			launches = append(launches, launch)
+		}
+		Expect(launches).To(HaveLen(2))
+		Expect(launches[0].InstanceIDs).To(Equal([]string{"i-123"}))
+		Expect(launches[1].Error).To(Equal("unauthorized"))
+	})
+	It("should write each record to its own S3 object under the prefix", func() {
+		api := &s3API{}
+		logger, err := auditlog.New("s3://audit/karpenter/launches", api)
+		Expect(err).ToNot(HaveOccurred())
+		launch := auditlog.NewLaunch(ctx, nodeClaim, input, output, nil)
+		logger.Log(ctx, launch)
+
+		Expect(api.inputs).To(HaveLen(1))
+		Expect(aws.StringValue(api.inputs[0].Bucket)).To(Equal("audit"))
+		Expect(aws.StringValue(api.inputs[0].Key)).To(HavePrefix(fmt.Sprintf("karpenter/launches/%s/%s/", launch.ClusterName, launch.Time.Format("2006/01/02"))))
+		recorded := auditlog.Launch{}
+		Expect(json.Unmarshal([]byte(api.bodies[0]), &recorded)).To(Succeed())
+		Expect(recorded.NodeClaim).To(Equal("default-abcde"))
+	})
+})
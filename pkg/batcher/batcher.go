@@ -55,6 +55,8 @@ type request[T input, U output] struct {
 	hash      uint64
 	input     *T
 	requestor chan Result[U]
+	// added is when the request was added to the batcher, to measure how long it waits to be executed
+	added time.Time
 }
 
 // Batcher is used to batch API calls with identical parameters into a single call
@@ -107,6 +109,7 @@ func (b *Batcher[T, U]) Add(ctx context.Context, input *T) Result[U] {
 		// any single caller from blocking the others. Specifically since we register our request and then trigger, the
 		// request may be processed while the triggering blocks.
 		requestor: make(chan Result[U], 1),
+		added:     time.Now(),
 	}
 	b.mu.Lock()
 	b.requests[request.hash] = append(b.requests[request.hash], request)
@@ -185,6 +188,9 @@ func (b *Batcher[T, U]) waitForIdle() {
 func (b *Batcher[T, U]) runCalls(requests []*request[T, U]) {
 	// Measure the size of the request batch
 	batchSize.With(prometheus.Labels{batcherNameLabel: b.options.Name}).Observe(float64(len(requests)))
+	for _, req := range requests {
+		queueDuration.With(prometheus.Labels{batcherNameLabel: b.options.Name}).Observe(time.Since(req.added).Seconds())
+	}
 	requestIdx := 0
 	for _, result := range b.options.BatchExecutor(requests[0].ctx, lo.Map(requests, func(req *request[T, U], _ int) *T { return req.input })) {
 		requests[requestIdx].requestor <- result
@@ -17,25 +17,28 @@ package batcher
 import (
 	"context"
 	"fmt"
-	"time"
+	"sort"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
 
 type CreateFleetBatcher struct {
 	batcher *Batcher[ec2.CreateFleetInput, ec2.CreateFleetOutput]
 }
 
-func NewCreateFleetBatcher(ctx context.Context, ec2api ec2iface.EC2API) *CreateFleetBatcher {
+func NewCreateFleetBatcher(ctx context.Context, ec2api EC2Client) *CreateFleetBatcher {
 	options := Options[ec2.CreateFleetInput, ec2.CreateFleetOutput]{
 		Name:          "create_fleet",
-		IdleTimeout:   35 * time.Millisecond,
-		MaxTimeout:    1 * time.Second,
-		MaxItems:      1_000,
-		RequestHasher: DefaultHasher[ec2.CreateFleetInput],
+		IdleTimeout:   options.FromContext(ctx).CreateFleetBatchIdleDuration,
+		MaxTimeout:    options.FromContext(ctx).CreateFleetBatchMaxDuration,
+		MaxItems:      options.FromContext(ctx).CreateFleetBatchMaxItems,
+		RequestHasher: createFleetHasher,
 		BatchExecutor: execCreateFleetBatch(ec2api),
 	}
 	return &CreateFleetBatcher{batcher: NewBatcher(ctx, options)}
@@ -49,12 +52,52 @@ func (b *CreateFleetBatcher) CreateFleet(ctx context.Context, createFleetInput *
 	return result.Output, result.Err
 }
 
-func execCreateFleetBatch(ec2api ec2iface.EC2API) BatchExecutor[ec2.CreateFleetInput, ec2.CreateFleetOutput] {
+// createFleetBatchKey is the part of a CreateFleet request that determines which requests are batched together. Since
+// a batch is launched with the first request that was added to it, requests are only batched when everything they
+// launch with matches.
+type createFleetBatchKey struct {
+	CapacityType          string
+	LaunchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest
+	SubnetIDs             []string
+	Type                  ec2types.FleetType
+	Context               *string
+	SpotOptions           *ec2types.SpotOptionsRequest
+	OnDemandOptions       *ec2types.OnDemandOptionsRequest
+	TagSpecifications     []ec2types.TagSpecification
+}
+
+// createFleetHasher buckets requests by their capacity type, launch template configs and subnets, ignoring the target
+// capacity which is set for the whole batch when it's executed
+func createFleetHasher(ctx context.Context, input *ec2.CreateFleetInput) uint64 {
+	key := &createFleetBatchKey{
+		LaunchTemplateConfigs: input.LaunchTemplateConfigs,
+		Type:                  input.Type,
+		Context:               input.Context,
+		SpotOptions:           input.SpotOptions,
+		OnDemandOptions:       input.OnDemandOptions,
+		TagSpecifications:     input.TagSpecifications,
+	}
+	if input.TargetCapacitySpecification != nil {
+		key.CapacityType = string(input.TargetCapacitySpecification.DefaultTargetCapacityType)
+	}
+	for _, config := range input.LaunchTemplateConfigs {
+		for _, override := range config.Overrides {
+			if override.SubnetId != nil {
+				key.SubnetIDs = append(key.SubnetIDs, *override.SubnetId)
+			}
+		}
+	}
+	key.SubnetIDs = lo.Uniq(key.SubnetIDs)
+	sort.Strings(key.SubnetIDs)
+	return DefaultHasher(ctx, key)
+}
+
+func execCreateFleetBatch(ec2api EC2Client) BatchExecutor[ec2.CreateFleetInput, ec2.CreateFleetOutput] {
 	return func(ctx context.Context, inputs []*ec2.CreateFleetInput) []Result[ec2.CreateFleetOutput] {
 		results := make([]Result[ec2.CreateFleetOutput], 0, len(inputs))
 		firstInput := inputs[0]
-		firstInput.TargetCapacitySpecification.TotalTargetCapacity = aws.Int64(int64(len(inputs)))
-		output, err := ec2api.CreateFleetWithContext(ctx, firstInput)
+		firstInput.TargetCapacitySpecification.TotalTargetCapacity = aws.Int32(int32(len(inputs)))
+		output, err := ec2api.CreateFleet(ctx, firstInput)
 		if err != nil {
 			for range inputs {
 				results = append(results, Result[ec2.CreateFleetOutput]{Err: err})
@@ -70,16 +113,16 @@ func execCreateFleetBatch(ec2api ec2iface.EC2API) BatchExecutor[ec2.CreateFleetI
 			for _, instanceID := range reservation.InstanceIds {
 				requestIdx++
 				if requestIdx >= len(inputs) {
-					log.FromContext(ctx).Error(fmt.Errorf("received more instances than requested, ignoring instance %s", aws.StringValue(instanceID)), "received error while batching")
+					log.FromContext(ctx).Error(fmt.Errorf("received more instances than requested, ignoring instance %s", instanceID), "received error while batching")
 					continue
 				}
 				results = append(results, Result[ec2.CreateFleetOutput]{
 					Output: &ec2.CreateFleetOutput{
 						FleetId: output.FleetId,
 						Errors:  output.Errors,
-						Instances: []*ec2.CreateFleetInstance{
+						Instances: []ec2types.CreateFleetInstance{
 							{
-								InstanceIds:                []*string{instanceID},
+								InstanceIds:                []string{instanceID},
 								InstanceType:               reservation.InstanceType,
 								LaunchTemplateAndOverrides: reservation.LaunchTemplateAndOverrides,
 								Lifecycle:                  reservation.Lifecycle,
@@ -94,7 +137,7 @@ func execCreateFleetBatch(ec2api ec2iface.EC2API) BatchExecutor[ec2.CreateFleetI
 		if requestIdx != len(inputs) {
 			// we should receive some sort of error, but just in case
 			if len(output.Errors) == 0 {
-				output.Errors = append(output.Errors, &ec2.CreateFleetError{
+				output.Errors = append(output.Errors, ec2types.CreateFleetError{
 					ErrorCode:    aws.String("too few instances returned"),
 					ErrorMessage: aws.String("too few instances returned"),
 				})
@@ -19,10 +19,17 @@ import (
 	"sync/atomic"
 	"time"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
 
 	"github.com/aws/karpenter-provider-aws/pkg/batcher"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	awstest "github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -33,25 +40,25 @@ var _ = Describe("CreateFleet Batching", func() {
 
 	BeforeEach(func() {
 		fakeEC2API.Reset()
-		cfb = batcher.NewCreateFleetBatcher(ctx, fakeEC2API)
+		cfb = batcher.NewCreateFleetBatcher(ctx, fake.NewEC2V2API(fakeEC2API))
 	})
 
 	It("should batch the same inputs into a single call", func() {
-		input := &ec2.CreateFleetInput{
-			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+		input := &ec2v2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
 				{
-					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 						LaunchTemplateName: aws.String("my-template"),
 					},
-					Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+					Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
 						{
 							AvailabilityZone: aws.String("us-east-1"),
 						},
 					},
 				},
 			},
-			TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-				TotalTargetCapacity: aws.Int64(1),
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: awsv2.Int32(1),
 			},
 		}
 		var wg sync.WaitGroup
@@ -67,7 +74,7 @@ var _ = Describe("CreateFleet Batching", func() {
 				var instanceIds []string
 				for _, rsv := range rsp.Instances {
 					for _, id := range rsv.InstanceIds {
-						instanceIds = append(instanceIds, *id)
+						instanceIds = append(instanceIds, id)
 					}
 				}
 				atomic.AddInt64(&receivedInstance, 1)
@@ -82,38 +89,38 @@ var _ = Describe("CreateFleet Batching", func() {
 		Expect(*call.TargetCapacitySpecification.TotalTargetCapacity).To(BeNumerically("==", 5))
 	})
 	It("should batch different inputs into multiple calls", func() {
-		east1input := &ec2.CreateFleetInput{
-			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+		east1input := &ec2v2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
 				{
-					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 						LaunchTemplateName: aws.String("my-template"),
 					},
-					Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+					Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
 						{
 							AvailabilityZone: aws.String("us-east-1"),
 						},
 					},
 				},
 			},
-			TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-				TotalTargetCapacity: aws.Int64(1),
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: awsv2.Int32(1),
 			},
 		}
-		east2input := &ec2.CreateFleetInput{
-			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+		east2input := &ec2v2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
 				{
-					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 						LaunchTemplateName: aws.String("my-template"),
 					},
-					Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+					Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
 						{
 							AvailabilityZone: aws.String("us-east-2"),
 						},
 					},
 				},
 			},
-			TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-				TotalTargetCapacity: aws.Int64(1),
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: awsv2.Int32(1),
 			},
 		}
 		var wg sync.WaitGroup
@@ -134,7 +141,7 @@ var _ = Describe("CreateFleet Batching", func() {
 				var instanceIds []string
 				for _, rsv := range rsp.Instances {
 					for _, id := range rsv.InstanceIds {
-						instanceIds = append(instanceIds, *id)
+						instanceIds = append(instanceIds, id)
 					}
 				}
 				atomic.AddInt64(&receivedInstance, 1)
@@ -157,21 +164,21 @@ var _ = Describe("CreateFleet Batching", func() {
 		Expect(*east1Call.LaunchTemplateConfigs[0].Overrides[0].AvailabilityZone).To(Equal("us-east-1"))
 	})
 	It("should return any errors to callers", func() {
-		input := &ec2.CreateFleetInput{
-			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+		input := &ec2v2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
 				{
-					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 						LaunchTemplateName: aws.String("my-template"),
 					},
-					Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+					Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
 						{
 							AvailabilityZone: aws.String("us-east-1"),
 						},
 					},
 				},
 			},
-			TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-				TotalTargetCapacity: aws.Int64(1),
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: awsv2.Int32(1),
 			},
 		}
 
@@ -232,7 +239,7 @@ var _ = Describe("CreateFleet Batching", func() {
 				var instanceIds []string
 				for _, rsv := range rsp.Instances {
 					for _, id := range rsv.InstanceIds {
-						instanceIds = append(instanceIds, *id)
+						instanceIds = append(instanceIds, id)
 					}
 				}
 				atomic.AddInt64(&receivedInstance, 1)
@@ -250,21 +257,21 @@ var _ = Describe("CreateFleet Batching", func() {
 		Expect(numErrors).To(BeNumerically("==", 5))
 	})
 	It("should handle partial fulfillment", func() {
-		input := &ec2.CreateFleetInput{
-			LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+		input := &ec2v2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
 				{
-					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 						LaunchTemplateName: aws.String("my-template"),
 					},
-					Overrides: []*ec2.FleetLaunchTemplateOverridesRequest{
+					Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
 						{
 							AvailabilityZone: aws.String("us-east-1"),
 						},
 					},
 				},
 			},
-			TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-				TotalTargetCapacity: aws.Int64(1),
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: awsv2.Int32(1),
 			},
 		}
 
@@ -325,7 +332,7 @@ var _ = Describe("CreateFleet Batching", func() {
 				var instanceIds []string
 				for _, rsv := range rsp.Instances {
 					for _, id := range rsv.InstanceIds {
-						instanceIds = append(instanceIds, *id)
+						instanceIds = append(instanceIds, id)
 					}
 				}
 				Expect(instanceIds).To(Or(HaveLen(0), HaveLen(1)))
@@ -344,4 +351,90 @@ var _ = Describe("CreateFleet Batching", func() {
 		Expect(receivedInstance).To(BeNumerically("==", 3))
 		Expect(numErrors).To(BeNumerically("==", 5))
 	})
+	It("should batch inputs of different capacity types into multiple calls", func() {
+		newInput := func(capacityType string) *ec2v2.CreateFleetInput {
+			return &ec2v2.CreateFleetInput{
+				LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
+					{
+						LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
+							LaunchTemplateName: aws.String("my-template"),
+						},
+						Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
+							{
+								AvailabilityZone: aws.String("us-east-1"),
+								SubnetId:         aws.String("subnet-1"),
+							},
+						},
+					},
+				},
+				TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+					DefaultTargetCapacityType: ec2types.DefaultTargetCapacityType(capacityType),
+					TotalTargetCapacity:       awsv2.Int32(1),
+				},
+			}
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				// 3 spot instances and 2 on-demand instances
+				_, err := cfb.CreateFleet(ctx, newInput(lo.Ternary(i < 3, ec2.DefaultTargetCapacityTypeSpot, ec2.DefaultTargetCapacityTypeOnDemand)))
+				Expect(err).To(BeNil())
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(fakeEC2API.CreateFleetBehavior.CalledWithInput.Len()).To(BeNumerically("==", 2))
+		calls := map[string]int64{}
+		for fakeEC2API.CreateFleetBehavior.CalledWithInput.Len() > 0 {
+			call := fakeEC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			calls[*call.TargetCapacitySpecification.DefaultTargetCapacityType] = *call.TargetCapacitySpecification.TotalTargetCapacity
+		}
+		Expect(calls).To(Equal(map[string]int64{
+			ec2.DefaultTargetCapacityTypeSpot:     3,
+			ec2.DefaultTargetCapacityTypeOnDemand: 2,
+		}))
+	})
+	It("should end the batching window when it reaches the configured max items", func() {
+		cfb = batcher.NewCreateFleetBatcher(options.ToContext(ctx, awstest.Options(awstest.OptionsFields{
+			CreateFleetBatchIdleDuration: lo.ToPtr(time.Minute),
+			CreateFleetBatchMaxDuration:  lo.ToPtr(time.Minute),
+			CreateFleetBatchMaxItems:     lo.ToPtr(2),
+		})), fake.NewEC2V2API(fakeEC2API))
+		input := &ec2v2.CreateFleetInput{
+			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
+				{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
+						LaunchTemplateName: aws.String("my-template"),
+					},
+					Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
+						{
+							AvailabilityZone: aws.String("us-east-1"),
+						},
+					},
+				},
+			},
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: awsv2.Int32(1),
+			},
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := cfb.CreateFleet(ctx, input)
+				Expect(err).To(BeNil())
+			}()
+		}
+		// the batch is executed without waiting for the idle or max duration
+		wg.Wait()
+
+		Expect(fakeEC2API.CreateFleetBehavior.CalledWithInput.Len()).To(BeNumerically("==", 1))
+		call := fakeEC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(*call.TargetCapacitySpecification.TotalTargetCapacity).To(BeNumerically("==", 2))
+	})
 })
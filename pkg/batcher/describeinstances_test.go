@@ -19,6 +19,8 @@ import (
 	"sync"
 	"sync/atomic"
 
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
@@ -34,7 +36,7 @@ var _ = Describe("DescribeInstances Batcher", func() {
 
 	BeforeEach(func() {
 		fakeEC2API.Reset()
-		cfb = batcher.NewDescribeInstancesBatcher(ctx, fakeEC2API)
+		cfb = batcher.NewDescribeInstancesBatcher(ctx, fake.NewEC2V2API(fakeEC2API))
 	})
 
 	It("should batch input into a single call", func() {
@@ -50,8 +52,8 @@ var _ = Describe("DescribeInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				rsp, err := cfb.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				rsp, err := cfb.DescribeInstances(ctx, &ec2v2.DescribeInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).To(BeNil())
 				atomic.AddInt64(&receivedInstance, 1)
@@ -79,8 +81,8 @@ var _ = Describe("DescribeInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				rsp, err := cfb.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				rsp, err := cfb.DescribeInstances(ctx, &ec2v2.DescribeInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).To(BeNil())
 				atomic.AddInt64(&receivedInstance, 1)
@@ -109,9 +111,9 @@ var _ = Describe("DescribeInstances Batcher", func() {
 				},
 			},
 		})
-		runningFilter := &ec2.Filter{
+		runningFilter := ec2types.Filter{
 			Name:   aws.String("instance-state-name"),
-			Values: []*string{aws.String(ec2.InstanceStateNameRunning)},
+			Values: []string{string(ec2types.InstanceStateNameRunning)},
 		}
 		var wg sync.WaitGroup
 		var receivedInstance int64
@@ -121,9 +123,9 @@ var _ = Describe("DescribeInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				rsp, err := cfb.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
-					Filters:     []*ec2.Filter{runningFilter},
+				rsp, err := cfb.DescribeInstances(ctx, &ec2v2.DescribeInstancesInput{
+					InstanceIds: []string{instanceID},
+					Filters:     []ec2types.Filter{runningFilter},
 				})
 				Expect(err).To(BeNil())
 				if len(rsp.Reservations) > 0 {
@@ -168,8 +170,8 @@ var _ = Describe("DescribeInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				_, err := cfb.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				_, err := cfb.DescribeInstances(ctx, &ec2v2.DescribeInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).ToNot(BeNil())
 			}(instanceID)
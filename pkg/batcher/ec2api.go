@@ -17,16 +17,23 @@ package batcher
 import (
 	"context"
 
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 )
 
+// EC2Client is the part of the aws-sdk-go-v2 EC2 client that the batchers send their batches with
+type EC2Client interface {
+	CreateFleet(context.Context, *ec2.CreateFleetInput, ...func(*ec2.Options)) (*ec2.CreateFleetOutput, error)
+	DescribeInstances(context.Context, *ec2.DescribeInstancesInput, ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	TerminateInstances(context.Context, *ec2.TerminateInstancesInput, ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+}
+
 type EC2API struct {
 	*CreateFleetBatcher
 	*DescribeInstancesBatcher
 	*TerminateInstancesBatcher
 }
 
-func EC2(ctx context.Context, ec2api ec2iface.EC2API) *EC2API {
+func EC2(ctx context.Context, ec2api EC2Client) *EC2API {
 	return &EC2API{
 		CreateFleetBatcher:        NewCreateFleetBatcher(ctx, ec2api),
 		DescribeInstancesBatcher:  NewDescribeInstancesBatcher(ctx, ec2api),
@@ -48,8 +48,15 @@ var (
 		Help:      "Size of the request batch per batcher",
 		Buckets:   SizeBuckets(),
 	}, []string{batcherNameLabel})
+	queueDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: batcherSubsystem,
+		Name:      "queue_time_seconds",
+		Help:      "Duration that requests wait between being added to a batch and the batch being executed per batcher",
+		Buckets:   metrics.DurationBuckets(),
+	}, []string{batcherNameLabel})
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(batchWindowDuration, batchSize)
+	crmetrics.Registry.MustRegister(batchWindowDuration, batchSize, queueDuration)
 }
@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+const (
+	// NodeClaimStatusWriteInterval is the interval that the status writes of a single NodeClaim are limited to once its
+	// burst is used up
+	NodeClaimStatusWriteInterval = time.Second
+	// NodeClaimStatusWriteBurst is the number of status writes of a single NodeClaim that aren't limited. A NodeClaim's
+	// status is written once per lifecycle reconcile, i.e. when it's launched, registered and initialized, so the burst
+	// only limits NodeClaims whose status keeps changing.
+	NodeClaimStatusWriteBurst = 5
+	// nodeClaimStatusLimiterTTL is how long the limiter of a NodeClaim is kept after its last write. A limiter that has
+	// been idle for longer has refilled its burst, so dropping it doesn't change how writes are limited.
+	nodeClaimStatusLimiterTTL = NodeClaimStatusWriteInterval * NodeClaimStatusWriteBurst
+)
+
+// NodeClaimStatusClient is a client.Client that rate limits the status patches of each NodeClaim, so that NodeClaims
+// whose status keeps changing can't amplify the writes to etcd during large scale-ups. All other reads and writes are
+// passed through to the wrapped client.
+type NodeClaimStatusClient struct {
+	client.Client
+	clock clock.Clock
+
+	mu sync.Mutex
+	// key: NodeClaim name
+	limiters map[string]*nodeClaimStatusLimiter
+}
+
+type nodeClaimStatusLimiter struct {
+	limiter   *rate.Limiter
+	lastWrite time.Time
+}
+
+func NewNodeClaimStatusClient(kubeClient client.Client, clk clock.Clock) *NodeClaimStatusClient {
+	return &NodeClaimStatusClient{
+		Client:   kubeClient,
+		clock:    clk,
+		limiters: map[string]*nodeClaimStatusLimiter{},
+	}
+}
+
+func (c *NodeClaimStatusClient) Status() client.SubResourceWriter {
+	return &nodeClaimStatusWriter{
+		SubResourceWriter: c.Client.Status(),
+		client:            c,
+	}
+}
+
+// wait blocks until the NodeClaim's status can be written again
+func (c *NodeClaimStatusClient) wait(ctx context.Context, name string) error {
+	reservation := c.reserve(name)
+	delay := reservation.DelayFrom(c.clock.Now())
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-c.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	}
+}
+
+func (c *NodeClaimStatusClient) reserve(name string) *rate.Reservation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	for n, l := range c.limiters {
+		if now.Sub(l.lastWrite) > nodeClaimStatusLimiterTTL {
+			delete(c.limiters, n)
+		}
+	}
+	l, ok := c.limiters[name]
+	if !ok {
+		l = &nodeClaimStatusLimiter{limiter: rate.NewLimiter(rate.Every(NodeClaimStatusWriteInterval), NodeClaimStatusWriteBurst)}
+		c.limiters[name] = l
+	}
+	reservation := l.limiter.ReserveN(now, 1)
+	l.lastWrite = now.Add(reservation.DelayFrom(now))
+	return reservation
+}
+
+type nodeClaimStatusWriter struct {
+	client.SubResourceWriter
+	client *NodeClaimStatusClient
+}
+
+func (w *nodeClaimStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if nodeClaim, ok := obj.(*corev1beta1.NodeClaim); ok {
+		if err := w.client.wait(ctx, nodeClaim.Name); err != nil {
+			return err
+		}
+	}
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/batcher"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NodeClaim Status Client", func() {
+	var kubeClient client.Client
+	var statusClient *batcher.NodeClaimStatusClient
+	var fakeClock *clock.FakeClock
+	var writes atomic.Int64
+
+	BeforeEach(func() {
+		writes.Store(0)
+		fakeClock = clock.NewFakeClock(time.Now())
+		kubeClient = crfake.NewClientBuilder().
+			WithObjects(
+				&corev1beta1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: "nodeclaim-1"}},
+				&corev1beta1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: "nodeclaim-2"}},
+			).
+			WithStatusSubresource(&corev1beta1.NodeClaim{}).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+					writes.Add(1)
+					return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+				},
+			}).
+			Build()
+		statusClient = batcher.NewNodeClaimStatusClient(kubeClient, fakeClock)
+	})
+	// patchStatus patches the status of the NodeClaim like the nodeclaim lifecycle controller does at the end of a
+	// reconcile, which is the only caller that patches the status of NodeClaims
+	patchStatus := func(ctx context.Context, name string, mutate func(*corev1beta1.NodeClaim)) (*corev1beta1.NodeClaim, error) {
+		GinkgoHelper()
+		nodeClaim := &corev1beta1.NodeClaim{}
+		Expect(kubeClient.Get(ctx, client.ObjectKey{Name: name}, nodeClaim)).To(Succeed())
+		stored := nodeClaim.DeepCopy()
+		mutate(nodeClaim)
+		return nodeClaim, statusClient.Status().Patch(ctx, nodeClaim, client.MergeFrom(stored))
+	}
+	// lifecycle are the status changes of the reconciles that launch, register and initialize a NodeClaim
+	lifecycle := []func(*corev1beta1.NodeClaim){
+		func(nc *corev1beta1.NodeClaim) {
+			nc.Status.ProviderID = "aws:///test-zone-1a/i-123"
+			nc.StatusConditions().SetTrue(corev1beta1.ConditionTypeLaunched)
+		},
+		func(nc *corev1beta1.NodeClaim) {
+			nc.Status.NodeName = "node-1"
+			nc.StatusConditions().SetTrue(corev1beta1.ConditionTypeRegistered)
+		},
+		func(nc *corev1beta1.NodeClaim) { nc.StatusConditions().SetTrue(corev1beta1.ConditionTypeInitialized) },
+	}
+
+	It("should write each status patch of a NodeClaim's lifecycle without waiting", func() {
+		for _, mutate := range lifecycle {
+			_, err := patchStatus(ctx, "nodeclaim-1", mutate)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(writes.Load()).To(BeNumerically("==", len(lifecycle)))
+		Expect(fakeClock.HasWaiters()).To(BeFalse())
+
+		nodeClaim := &corev1beta1.NodeClaim{}
+		Expect(kubeClient.Get(ctx, client.ObjectKey{Name: "nodeclaim-1"}, nodeClaim)).To(Succeed())
+		Expect(nodeClaim.Status.ProviderID).To(Equal("aws:///test-zone-1a/i-123"))
+		Expect(nodeClaim.Status.NodeName).To(Equal("node-1"))
+		Expect(nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeInitialized).IsTrue()).To(BeTrue())
+	})
+	It("should rate limit the status writes of a NodeClaim whose status keeps changing", func() {
+		for i := 0; i < batcher.NodeClaimStatusWriteBurst; i++ {
+			_, err := patchStatus(ctx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) { nc.Status.ImageID = lo.RandomString(10, lo.LowerCaseLettersCharset) })
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(writes.Load()).To(BeNumerically("==", batcher.NodeClaimStatusWriteBurst))
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := patchStatus(ctx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) { nc.Status.ImageID = "ami-123" })
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Eventually(fakeClock.HasWaiters).Should(BeTrue())
+		Expect(writes.Load()).To(BeNumerically("==", batcher.NodeClaimStatusWriteBurst))
+
+		fakeClock.Step(batcher.NodeClaimStatusWriteInterval)
+		Eventually(done).Should(BeClosed())
+		Expect(writes.Load()).To(BeNumerically("==", batcher.NodeClaimStatusWriteBurst+1))
+	})
+	It("should not rate limit the status writes of other NodeClaims", func() {
+		for i := 0; i < batcher.NodeClaimStatusWriteBurst; i++ {
+			_, err := patchStatus(ctx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) { nc.Status.ImageID = lo.RandomString(10, lo.LowerCaseLettersCharset) })
+			Expect(err).ToNot(HaveOccurred())
+		}
+		for _, mutate := range lifecycle {
+			_, err := patchStatus(ctx, "nodeclaim-2", mutate)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(writes.Load()).To(BeNumerically("==", batcher.NodeClaimStatusWriteBurst+len(lifecycle)))
+		Expect(fakeClock.HasWaiters()).To(BeFalse())
+	})
+	It("should stop waiting to write when the context is canceled", func() {
+		for i := 0; i < batcher.NodeClaimStatusWriteBurst; i++ {
+			_, err := patchStatus(ctx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) { nc.Status.ImageID = lo.RandomString(10, lo.LowerCaseLettersCharset) })
+			Expect(err).ToNot(HaveOccurred())
+		}
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := patchStatus(cancelCtx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) { nc.Status.ImageID = "ami-123" })
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(writes.Load()).To(BeNumerically("==", batcher.NodeClaimStatusWriteBurst))
+	})
+	It("should return the persisted NodeClaim to the caller", func() {
+		nodeClaim, err := patchStatus(ctx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) {
+			nc.Status.Capacity = v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClaim.ResourceVersion).ToNot(BeEmpty())
+		Expect(nodeClaim.Status.Capacity.Cpu().String()).To(Equal("2"))
+	})
+	It("should return conflicts of patches that use optimistic locking", func() {
+		stale := &corev1beta1.NodeClaim{}
+		Expect(kubeClient.Get(ctx, client.ObjectKey{Name: "nodeclaim-1"}, stale)).To(Succeed())
+		_, err := patchStatus(ctx, "nodeclaim-1", func(nc *corev1beta1.NodeClaim) { nc.Status.ImageID = "ami-123" })
+		Expect(err).ToNot(HaveOccurred())
+
+		stored := stale.DeepCopy()
+		stale.Status.ImageID = "ami-456"
+		err = statusClient.Status().Patch(ctx, stale, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{}))
+		Expect(errors.IsConflict(err)).To(BeTrue())
+	})
+	It("should pass through status patches for other kinds", func() {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+		Expect(kubeClient.Create(ctx, pod)).To(Succeed())
+		stored := pod.DeepCopy()
+		pod.Status.Phase = v1.PodRunning
+		Expect(statusClient.Status().Patch(ctx, pod, client.MergeFrom(stored))).To(Succeed())
+		Expect(writes.Load()).To(BeNumerically("==", 1))
+	})
+})
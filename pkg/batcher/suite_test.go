@@ -27,6 +27,8 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/batcher"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	awstest "github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -38,6 +40,7 @@ var ctx context.Context
 
 func TestAWS(t *testing.T) {
 	ctx = TestContextWithLogger(t)
+	ctx = options.ToContext(ctx, awstest.Options())
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Batcher")
 }
@@ -19,6 +19,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
@@ -34,7 +35,7 @@ var _ = Describe("TerminateInstances Batcher", func() {
 
 	BeforeEach(func() {
 		fakeEC2API.Reset()
-		cfb = batcher.NewTerminateInstancesBatcher(ctx, fakeEC2API)
+		cfb = batcher.NewTerminateInstancesBatcher(ctx, fake.NewEC2V2API(fakeEC2API))
 	})
 
 	It("should batch input into a single call", func() {
@@ -50,8 +51,8 @@ var _ = Describe("TerminateInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				rsp, err := cfb.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				rsp, err := cfb.TerminateInstances(ctx, &ec2v2.TerminateInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).To(BeNil())
 				atomic.AddInt64(&receivedInstance, 1)
@@ -78,8 +79,8 @@ var _ = Describe("TerminateInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				rsp, err := cfb.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				rsp, err := cfb.TerminateInstances(ctx, &ec2v2.TerminateInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).To(BeNil())
 				atomic.AddInt64(&receivedInstance, 1)
@@ -113,8 +114,8 @@ var _ = Describe("TerminateInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				rsp, err := cfb.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				rsp, err := cfb.TerminateInstances(ctx, &ec2v2.TerminateInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).To(BeNil())
 				Expect(len(rsp.TerminatingInstances)).To(BeNumerically("<=", 1))
@@ -147,8 +148,8 @@ var _ = Describe("TerminateInstances Batcher", func() {
 			go func(instanceID string) {
 				defer GinkgoRecover()
 				defer wg.Done()
-				_, err := cfb.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-					InstanceIds: []*string{aws.String(instanceID)},
+				_, err := cfb.TerminateInstances(ctx, &ec2v2.TerminateInstancesInput{
+					InstanceIds: []string{instanceID},
 				})
 				Expect(err).ToNot(BeNil())
 			}(instanceID)
@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/utils/clock"
+)
+
+// BlockedOffering is an operator-provided (instance type, zone) pair that should not be launched until the expiration
+// has passed. An empty zone blocks the instance type in every zone.
+type BlockedOffering struct {
+	InstanceType string    `json:"instanceType"`
+	Zone         string    `json:"zone,omitempty"`
+	Expiration   time.Time `json:"expiration"`
+}
+
+// BlockedOfferings stores offerings that operators have explicitly blocked, typically while mitigating an incident
+// that affects a single instance type in a single zone. Unlike UnavailableOfferings, the contents aren't discovered from
+// launch failures; they're replaced wholesale every time the backing source is read.
+type BlockedOfferings struct {
+	mu  sync.RWMutex
+	clk clock.Clock
+	// key: <instanceType>:<zone>, value: expiration
+	entries map[string]time.Time
+	SeqNum  uint64
+}
+
+func NewBlockedOfferings(clk clock.Clock) *BlockedOfferings {
+	return &BlockedOfferings{
+		clk:     clk,
+		entries: map[string]time.Time{},
+	}
+}
+
+// IsBlocked returns true if the instance type is blocked in the zone, either directly or through a zone-wide entry
+func (b *BlockedOfferings) IsBlocked(instanceType, zone string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := b.clk.Now()
+	for _, key := range []string{b.key(instanceType, zone), b.key(instanceType, "")} {
+		if expiration, ok := b.entries[key]; ok && now.Before(expiration) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set replaces the blocked offerings with the unexpired entries that are passed in. It returns true and increments
+// the SeqNum if the set of blocked offerings changed.
+func (b *BlockedOfferings) Set(offerings []BlockedOffering) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clk.Now()
+	entries := map[string]time.Time{}
+	for _, o := range offerings {
+		if !now.Before(o.Expiration) {
+			continue
+		}
+		// If the same offering is listed more than once, honor the latest expiration
+		key := b.key(o.InstanceType, o.Zone)
+		if existing, ok := entries[key]; !ok || o.Expiration.After(existing) {
+			entries[key] = o.Expiration
+		}
+	}
+	// Expired entries are still present in the existing set, so expiry alone is also reported as a change
+	if len(b.entries) == len(entries) && lo.EveryBy(lo.Keys(entries), func(key string) bool {
+		existing, ok := b.entries[key]
+		return ok && existing.Equal(entries[key])
+	}) {
+		return false
+	}
+	b.entries = entries
+	atomic.AddUint64(&b.SeqNum, 1)
+	return true
+}
+
+// NextExpiration returns the earliest expiration across all blocked offerings, or false if nothing is blocked
+func (b *BlockedOfferings) NextExpiration() (time.Time, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.entries) == 0 {
+		return time.Time{}, false
+	}
+	return lo.MinBy(lo.Values(b.entries), func(a, b time.Time) bool { return a.Before(b) }), true
+}
+
+func (b *BlockedOfferings) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = map[string]time.Time{}
+	atomic.AddUint64(&b.SeqNum, 1)
+}
+
+// key returns the cache key for a blocked offering. An empty zone represents every zone.
+func (b *BlockedOfferings) key(instanceType string, zone string) string {
+	return fmt.Sprintf("%s:%s", instanceType, zone)
+}
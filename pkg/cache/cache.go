@@ -27,6 +27,11 @@ const (
 	// UnavailableOfferingsTTL is the time before offerings that were marked as unavailable
 	// are removed from the cache and are available for launch again
 	UnavailableOfferingsTTL = 3 * time.Minute
+	// ZoneImpairedTTL is the time that a zone is removed from the offerings after repeated launch failures mark it as
+	// impaired. It doubles each time the zone is marked again without a successful launch in between, up to ZoneImpairedMaxTTL.
+	ZoneImpairedTTL = 3 * time.Minute
+	// ZoneImpairedMaxTTL is the longest time that a zone is removed from the offerings after repeated launch failures
+	ZoneImpairedMaxTTL = 30 * time.Minute
 	// InstanceTypesAndZonesTTL is the time before we refresh instance types and zones at EC2
 	InstanceTypesAndZonesTTL = 5 * time.Minute
 	// InstanceProfileTTL is the time before we refresh checking instance profile existence at IAM
@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/utils/clock"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// CreationLimitWindow is the trailing window over which created resources are counted against their cap
+const CreationLimitWindow = time.Hour
+
+type CreatedResource string
+
+const (
+	CreatedResourceLaunchTemplate     CreatedResource = "launch_template"
+	CreatedResourceCreateFleetRequest CreatedResource = "create_fleet_request"
+	CreatedResourceInstance           CreatedResource = "instance"
+)
+
+var (
+	resourcesCreated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "cloudprovider",
+			Name:      "resources_created_last_hour",
+			Help:      "Number of EC2 resources created by Karpenter in the trailing hour, by resource.",
+		},
+		[]string{"resource"},
+	)
+	creationLimitExceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "cloudprovider",
+			Name:      "creation_limit_exceeded_total",
+			Help:      "Number of times a resource wasn't created because its hourly creation cap was reached, by resource.",
+		},
+		[]string{"resource"},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(resourcesCreated, creationLimitExceeded)
+}
+
+// CreationLimitExceededError is returned when creating a resource would exceed its hourly cap
+type CreationLimitExceededError struct {
+	Resource CreatedResource
+	Limit    int
+}
+
+func (e *CreationLimitExceededError) Error() string {
+	return fmt.Sprintf("reached the limit of %d %ss per hour", e.Limit, strings.ReplaceAll(string(e.Resource), "_", " "))
+}
+
+func IsCreationLimitExceeded(err error) bool {
+	if err == nil {
+		return false
+	}
+	var limitErr *CreationLimitExceededError
+	return errors.As(err, &limitErr)
+}
+
+// CreationLimits counts the launch templates, CreateFleet requests, and instances that were created in the trailing
+// hour. It caps how much Karpenter can create when a misconfiguration causes it to provision in a loop.
+type CreationLimits struct {
+	mu      sync.Mutex
+	clk     clock.Clock
+	created map[CreatedResource][]time.Time
+}
+
+func NewCreationLimits(clk clock.Clock) *CreationLimits {
+	return &CreationLimits{
+		clk:     clk,
+		created: map[CreatedResource][]time.Time{},
+	}
+}
+
+// Reserve counts one creation of the resource, or returns a CreationLimitExceededError if the resource has already
+// been created as many times as its configured cap in the trailing hour. A cap of 0 never rejects the creation.
+func (c *CreationLimits) Reserve(ctx context.Context, resource CreatedResource) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limit := creationLimit(ctx, resource)
+	c.expire(resource)
+	if limit > 0 && len(c.created[resource]) >= limit {
+		creationLimitExceeded.WithLabelValues(string(resource)).Inc()
+		return &CreationLimitExceededError{Resource: resource, Limit: limit}
+	}
+	c.created[resource] = append(c.created[resource], c.clk.Now())
+	resourcesCreated.WithLabelValues(string(resource)).Set(float64(len(c.created[resource])))
+	return nil
+}
+
+// Release removes the most recent reservation of the resource when it wasn't actually created
+func (c *CreationLimits) Release(resource CreatedResource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n := len(c.created[resource]); n > 0 {
+		c.created[resource] = c.created[resource][:n-1]
+	}
+	c.expire(resource)
+	resourcesCreated.WithLabelValues(string(resource)).Set(float64(len(c.created[resource])))
+}
+
+// Count returns the number of times the resource was created in the trailing hour
+func (c *CreationLimits) Count(resource CreatedResource) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(resource)
+	return len(c.created[resource])
+}
+
+func (c *CreationLimits) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.created = map[CreatedResource][]time.Time{}
+}
+
+func creationLimit(ctx context.Context, resource CreatedResource) int {
+	switch resource {
+	case CreatedResourceLaunchTemplate:
+		return options.FromContext(ctx).MaxLaunchTemplatesPerHour
+	case CreatedResourceCreateFleetRequest:
+		return options.FromContext(ctx).MaxCreateFleetRequestsPerHour
+	case CreatedResourceInstance:
+		return options.FromContext(ctx).MaxInstancesPerHour
+	}
+	return 0
+}
+
+// expire drops the creations that are older than the window. Creations are appended in order, so the expired ones
+// are always at the front.
+func (c *CreationLimits) expire(resource CreatedResource) {
+	cutoff := c.clk.Now().Add(-CreationLimitWindow)
+	created := c.created[resource]
+	i := 0
+	for i < len(created) && !created[i].After(cutoff) {
+		i++
+	}
+	c.created[resource] = created[i:]
+}
@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/utils/clock"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+// maxLaunchRampDoublings bounds how many times the launch limit of a ramp doubles
+const maxLaunchRampDoublings = 16
+
+var launchRampExceeded = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "cloudprovider",
+		Name:      "launch_ramp_exceeded_total",
+		Help:      "Number of launches that were delayed because the launch ramp of their NodePool was reached, by NodePool.",
+	},
+	[]string{metrics.NodePoolLabel},
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(launchRampExceeded)
+}
+
+// LaunchRampPolicy limits how quickly a NodePool launches nodes. At most Limit nodes are launched in any Period. If
+// DoublingWindow is set, the limit doubles after each DoublingWindow that the NodePool keeps launching, so that large
+// scale-ups start slowly and speed up once the systems that new nodes depend on have kept up.
+type LaunchRampPolicy struct {
+	Limit          int
+	Period         time.Duration
+	DoublingWindow time.Duration
+}
+
+// LaunchRampExceededError is returned when launching a node would exceed the launch ramp of its NodePool
+type LaunchRampExceededError struct {
+	NodePool string
+	Limit    int
+	Period   time.Duration
+}
+
+func (e *LaunchRampExceededError) Error() string {
+	return fmt.Sprintf("reached the launch ramp of %d nodes per %s for nodepool %s", e.Limit, e.Period, e.NodePool)
+}
+
+func IsLaunchRampExceeded(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rampErr *LaunchRampExceededError
+	return errors.As(err, &rampErr)
+}
+
+type launchRamp struct {
+	start    time.Time
+	last     time.Time
+	launches []time.Time
+}
+
+// LaunchRamps tracks the recent launches of each NodePool that has a launch ramp policy
+type LaunchRamps struct {
+	mu    sync.Mutex
+	clk   clock.Clock
+	ramps map[string]*launchRamp
+}
+
+func NewLaunchRamps(clk clock.Clock) *LaunchRamps {
+	return &LaunchRamps{
+		clk:   clk,
+		ramps: map[string]*launchRamp{},
+	}
+}
+
+// Reserve counts one launch for the NodePool, or returns a LaunchRampExceededError if the NodePool has already
+// launched as many nodes as its policy currently allows in the trailing period. A ramp starts with the first launch
+// after the NodePool hasn't launched for longer than both twice the period and the doubling window.
+func (l *LaunchRamps) Reserve(nodePool string, policy LaunchRampPolicy) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clk.Now()
+	ramp, ok := l.ramps[nodePool]
+	if !ok {
+		ramp = &launchRamp{}
+		l.ramps[nodePool] = ramp
+	}
+	ramp.expire(now.Add(-policy.Period))
+	// Launches that are held back retry with a backoff, so a ramp only restarts after a pause that's clearly longer
+	if now.Sub(ramp.last) > max(2*policy.Period, policy.DoublingWindow) {
+		ramp.start = now
+	}
+	limit := policy.Limit
+	if policy.DoublingWindow > 0 {
+		limit <<= min(int(now.Sub(ramp.start)/policy.DoublingWindow), maxLaunchRampDoublings)
+	}
+	if len(ramp.launches) >= limit {
+		launchRampExceeded.WithLabelValues(nodePool).Inc()
+		return &LaunchRampExceededError{NodePool: nodePool, Limit: limit, Period: policy.Period}
+	}
+	ramp.launches = append(ramp.launches, now)
+	ramp.last = now
+	return nil
+}
+
+// Release removes the most recent reservation of the NodePool when the node wasn't actually launched
+func (l *LaunchRamps) Release(nodePool string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ramp, ok := l.ramps[nodePool]; ok && len(ramp.launches) > 0 {
+		ramp.launches = ramp.launches[:len(ramp.launches)-1]
+	}
+}
+
+func (l *LaunchRamps) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ramps = map[string]*launchRamp{}
+}
+
+// expire drops the launches that are older than the cutoff. Launches are appended in order, so the expired ones are
+// always at the front.
+func (r *launchRamp) expire(cutoff time.Time) {
+	i := 0
+	for i < len(r.launches) && !r.launches[i].After(cutoff) {
+		i++
+	}
+	r.launches = r.launches[i:]
+}
@@ -17,27 +17,45 @@ package cache
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync/atomic"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/patrickmn/go-cache"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
 
+// UnavailableOffering is an offering in the UnavailableOfferings cache along with the time that it becomes available
+// again. It's the form in which the cache is persisted across restarts. The zone of offerings on an Outpost is the ID
+// of the Outpost, since the capacity of an Outpost is separate from the capacity of its zone.
+type UnavailableOffering struct {
+	CapacityType string    `json:"capacityType"`
+	InstanceType string    `json:"instanceType"`
+	Zone         string    `json:"zone"`
+	Expiration   time.Time `json:"expiration"`
+}
+
 // UnavailableOfferings stores any offerings that return ICE (insufficient capacity errors) when
 // attempting to launch the capacity. These offerings are ignored as long as they are in the cache on
-// GetInstanceTypes responses
+// GetInstanceTypes responses. How long an offering stays in the cache is configured per capacity type, and
+// backs off exponentially for capacity types with a max backoff while the offering keeps failing.
 type UnavailableOfferings struct {
 	// key: <capacityType>:<instanceType>:<zone>, value: struct{}{}
-	cache  *cache.Cache
-	SeqNum uint64
+	cache *cache.Cache
+	// key: <capacityType>:<instanceType>:<zone>, value: the backoff of the last time that the offering was marked unavailable
+	backoffs *cache.Cache
+	SeqNum   uint64
 }
 
 func NewUnavailableOfferings() *UnavailableOfferings {
 	uo := &UnavailableOfferings{
-		cache:  cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
-		SeqNum: 0,
+		cache:    cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
+		backoffs: cache.New(UnavailableOfferingsTTL, DefaultCleanupInterval),
+		SeqNum:   0,
 	}
 	uo.cache.OnEvicted(func(_ string, _ interface{}) {
 		atomic.AddUint64(&uo.SeqNum, 1)
@@ -53,21 +71,92 @@ func (u *UnavailableOfferings) IsUnavailable(instanceType, zone, capacityType st
 
 // MarkUnavailable communicates recently observed temporary capacity shortages in the provided offerings
 func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, unavailableReason, instanceType, zone, capacityType string) {
+	key := u.key(instanceType, zone, capacityType)
+	ttl := u.backoff(ctx, key, capacityType)
 	// even if the key is already in the cache, we still need to call Set to extend the cached entry's TTL
 	log.FromContext(ctx).WithValues(
 		"reason", unavailableReason,
 		"instance-type", instanceType,
 		"zone", zone,
 		"capacity-type", capacityType,
-		"ttl", UnavailableOfferingsTTL).V(1).Info("removing offering from offerings")
-	u.cache.SetDefault(u.key(instanceType, zone, capacityType), struct{}{})
+		"ttl", ttl).V(1).Info("removing offering from offerings")
+	u.cache.Set(key, struct{}{}, ttl)
 	atomic.AddUint64(&u.SeqNum, 1)
 }
 
-func (u *UnavailableOfferings) MarkUnavailableForFleetErr(ctx context.Context, fleetErr *ec2.CreateFleetError, capacityType string) {
-	instanceType := aws.StringValue(fleetErr.LaunchTemplateAndOverrides.Overrides.InstanceType)
-	zone := aws.StringValue(fleetErr.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)
-	u.MarkUnavailable(ctx, aws.StringValue(fleetErr.ErrorCode), instanceType, zone, capacityType)
+// backoff returns how long the offering is unavailable for. When the capacity type has a max backoff, the backoff doubles
+// each time that the offering fails again within twice its last backoff. Offerings that are still unavailable, e.g.
+// because of concurrent launches that failed together, keep their last backoff.
+func (u *UnavailableOfferings) backoff(ctx context.Context, key, capacityType string) time.Duration {
+	ttl, maxTTL := backoffBounds(ctx, capacityType)
+	if maxTTL == ttl {
+		return ttl
+	}
+	if last, ok := u.backoffs.Get(key); ok {
+		ttl = last.(time.Duration)
+		if _, unavailable := u.cache.Get(key); !unavailable {
+			ttl = min(ttl*2, maxTTL)
+		}
+	}
+	u.backoffs.Set(key, ttl, 2*ttl)
+	return ttl
+}
+
+// backoffBounds returns how long offerings of the capacity type are unavailable for after their first failure, and the
+// longest that they're unavailable for after repeated failures
+func backoffBounds(ctx context.Context, capacityType string) (time.Duration, time.Duration) {
+	ttl, ok := options.FromContext(ctx).ICEBackoffDurations[capacityType]
+	if !ok {
+		ttl = UnavailableOfferingsTTL
+	}
+	return ttl, max(ttl, options.FromContext(ctx).ICEBackoffMaxDurations[capacityType])
+}
+
+func (u *UnavailableOfferings) MarkUnavailableForFleetErr(ctx context.Context, fleetErr ec2types.CreateFleetError, capacityType string) {
+	instanceType := string(fleetErr.LaunchTemplateAndOverrides.Overrides.InstanceType)
+	zone := aws.ToString(fleetErr.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)
+	u.MarkUnavailable(ctx, aws.ToString(fleetErr.ErrorCode), instanceType, zone, capacityType)
+}
+
+// List returns the offerings that are currently in the cache
+func (u *UnavailableOfferings) List() []UnavailableOffering {
+	var offerings []UnavailableOffering
+	now := time.Now()
+	for key, item := range u.cache.Items() {
+		parts := strings.SplitN(key, ":", 3)
+		if len(parts) != 3 || (item.Expiration > 0 && now.UnixNano() > item.Expiration) {
+			continue
+		}
+		offerings = append(offerings, UnavailableOffering{
+			CapacityType: parts[0],
+			InstanceType: parts[1],
+			Zone:         parts[2],
+			Expiration:   time.Unix(0, item.Expiration),
+		})
+	}
+	return offerings
+}
+
+// Restore adds offerings to the cache until their expiration, e.g. to rehydrate the cache after a restart. Offerings
+// that have already expired are ignored, and offerings are never kept for longer than the max backoff of their
+// capacity type.
+func (u *UnavailableOfferings) Restore(ctx context.Context, offerings []UnavailableOffering) int {
+	restored := 0
+	for _, o := range offerings {
+		ttl := time.Until(o.Expiration)
+		if ttl <= 0 {
+			continue
+		}
+		if _, maxTTL := backoffBounds(ctx, o.CapacityType); ttl > maxTTL {
+			ttl = maxTTL
+		}
+		u.cache.Set(u.key(o.InstanceType, o.Zone, o.CapacityType), struct{}{}, ttl)
+		restored++
+	}
+	if restored > 0 {
+		atomic.AddUint64(&u.SeqNum, 1)
+	}
+	return restored
 }
 
 func (u *UnavailableOfferings) Delete(instanceType string, zone string, capacityType string) {
@@ -76,6 +165,7 @@ func (u *UnavailableOfferings) Delete(instanceType string, zone string, capacity
 
 func (u *UnavailableOfferings) Flush() {
 	u.cache.Flush()
+	u.backoffs.Flush()
 }
 
 // key returns the cache key for all offerings in the cache
@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// VCPUQuotaHeadroom stores the vCPUs that are left under each EC2 vCPU service quota of the account, so that offerings of
+// instance types that don't fit under their quota are treated as unavailable rather than failing to launch with
+// VcpuLimitExceeded
+type VCPUQuotaHeadroom struct {
+	mu sync.RWMutex
+	// key: <capacityType>:<familyClass>, value: the vCPUs that are left under the quota
+	remaining map[string]float64
+	// SeqNum is incremented whenever the headroom changes, so that instance types whose offerings were computed from it
+	// are recomputed
+	SeqNum uint64
+}
+
+func NewVCPUQuotaHeadroom() *VCPUQuotaHeadroom {
+	return &VCPUQuotaHeadroom{remaining: map[string]float64{}}
+}
+
+// Fits returns true if an instance with the given number of vCPUs fits under the quota of its capacity type and family
+// class, or if the headroom under that quota isn't known
+func (h *VCPUQuotaHeadroom) Fits(capacityType, familyClass string, vcpus int64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	remaining, ok := h.remaining[h.key(capacityType, familyClass)]
+	return !ok || remaining >= float64(vcpus)
+}
+
+// Set replaces the headroom under the quotas, which is keyed by capacity type and then family class, and returns true if
+// it changed
+func (h *VCPUQuotaHeadroom) Set(remaining map[string]map[string]float64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	flattened := map[string]float64{}
+	for capacityType, classes := range remaining {
+		for familyClass, vcpus := range classes {
+			flattened[h.key(capacityType, familyClass)] = vcpus
+		}
+	}
+	if equality.Semantic.DeepEqual(h.remaining, flattened) {
+		return false
+	}
+	h.remaining = flattened
+	atomic.AddUint64(&h.SeqNum, 1)
+	return true
+}
+
+func (h *VCPUQuotaHeadroom) key(capacityType, familyClass string) string {
+	return fmt.Sprintf("%s:%s", capacityType, familyClass)
+}
+
+func (h *VCPUQuotaHeadroom) Flush() {
+	h.Set(nil)
+}
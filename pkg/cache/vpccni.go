@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// VPCCNIWarmTargets are the warm pool settings of the VPC CNI, as configured on the aws-node DaemonSet
+type VPCCNIWarmTargets struct {
+	// WarmIPTarget is the number of free IPs that the VPC CNI keeps assigned to each node
+	WarmIPTarget int64
+	// MinimumIPTarget is the number of IPs that the VPC CNI assigns to each node, regardless of how many pods it runs
+	MinimumIPTarget int64
+	// PrefixDelegation is true if the VPC CNI assigns /28 prefixes rather than individual IPs
+	PrefixDelegation bool
+}
+
+// VPCCNI stores the warm pool settings of the VPC CNI that pod density and subnet IP usage are projected from
+type VPCCNI struct {
+	mu          sync.RWMutex
+	warmTargets *VPCCNIWarmTargets
+	// SeqNum is incremented whenever the warm targets change, so that instance types that were computed from them
+	// are recomputed
+	SeqNum uint64
+}
+
+func NewVPCCNI() *VPCCNI {
+	return &VPCCNI{}
+}
+
+// WarmTargets returns the warm pool settings of the VPC CNI, or false if they aren't known
+func (v *VPCCNI) WarmTargets() (VPCCNIWarmTargets, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.warmTargets == nil {
+		return VPCCNIWarmTargets{}, false
+	}
+	return *v.warmTargets, true
+}
+
+// SetWarmTargets stores the warm pool settings of the VPC CNI, and returns true if they changed. Passing nil clears
+// them.
+func (v *VPCCNI) SetWarmTargets(warmTargets *VPCCNIWarmTargets) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if (v.warmTargets == nil && warmTargets == nil) || (v.warmTargets != nil && warmTargets != nil && *v.warmTargets == *warmTargets) {
+		return false
+	}
+	if warmTargets != nil {
+		copied := *warmTargets
+		warmTargets = &copied
+	}
+	v.warmTargets = warmTargets
+	atomic.AddUint64(&v.SeqNum, 1)
+	return true
+}
+
+func (v *VPCCNI) Flush() {
+	v.SetWarmTargets(nil)
+}
@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ZoneFailureWindow is how long a launch failure in a zone counts towards marking the zone as impaired
+	ZoneFailureWindow = 5 * time.Minute
+	// ZoneFailureThreshold is the number of distinct instance families that need to fail to launch in a zone within the
+	// ZoneFailureWindow for the zone to be marked as impaired. Capacity shortages of a single family are common and are
+	// handled by UnavailableOfferings, while a zonal incident affects every family.
+	ZoneFailureThreshold = 3
+
+	ZoneImpairedSourceLaunchFailures = "launch-failures"
+	ZoneImpairedSourceZoneState      = "zone-state"
+)
+
+// ImpairedZone is a zone that is removed from the offerings along with the reason and the time that it's available again.
+// Zones that EC2 reports as impaired have no expiration, since they're available again once EC2 reports them as available.
+type ImpairedZone struct {
+	Zone       string
+	Source     string
+	Reason     string
+	Expiration time.Time
+}
+
+type zoneImpairment struct {
+	reason     string
+	expiration time.Time
+}
+
+// ZoneHealth stores the zones that are impaired, either because EC2 reports them as impaired or because launches of many
+// instance families have recently failed in them. Offerings in impaired zones are treated as unavailable, so that
+// provisioning shifts to the healthy zones during a zonal incident. Impairments from launch failures back off
+// exponentially while a zone keeps failing, and are reset by a successful launch in the zone.
+type ZoneHealth struct {
+	mu  sync.RWMutex
+	clk clock.Clock
+	// key: <zone>, value: instance family -> time of the last failure
+	failures map[string]map[string]time.Time
+	// key: <zone>, value: the impairment from launch failures
+	impaired map[string]zoneImpairment
+	// key: <zone>, value: the number of times that the zone has been marked as impaired since its last successful launch
+	strikes map[string]int
+	// key: <zone>, value: the state that EC2 reports for the zone
+	reported map[string]string
+}
+
+func NewZoneHealth(clk clock.Clock) *ZoneHealth {
+	return &ZoneHealth{
+		clk:      clk,
+		failures: map[string]map[string]time.Time{},
+		impaired: map[string]zoneImpairment{},
+		strikes:  map[string]int{},
+		reported: map[string]string{},
+	}
+}
+
+// IsImpaired returns true if EC2 reports the zone as impaired or if launch failures have marked it as impaired
+func (z *ZoneHealth) IsImpaired(zone string) bool {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	return z.isImpaired(zone)
+}
+
+func (z *ZoneHealth) isImpaired(zone string) bool {
+	if _, ok := z.reported[zone]; ok {
+		return true
+	}
+	impairment, ok := z.impaired[zone]
+	return ok && z.clk.Now().Before(impairment.expiration)
+}
+
+// ImpairedZones returns the zones that are currently impaired
+func (z *ZoneHealth) ImpairedZones() sets.Set[string] {
+	return sets.New(lo.Map(z.List(), func(i ImpairedZone, _ int) string { return i.Zone })...)
+}
+
+// RecordFailure records a launch of the instance type that failed in the zone for lack of capacity. It returns true if
+// the failure marked the zone as impaired.
+func (z *ZoneHealth) RecordFailure(ctx context.Context, reason, instanceType, zone string) bool {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	now := z.clk.Now()
+	if z.isImpaired(zone) {
+		return false
+	}
+	if _, ok := z.failures[zone]; !ok {
+		z.failures[zone] = map[string]time.Time{}
+	}
+	z.failures[zone][strings.Split(instanceType, ".")[0]] = now
+	for family, failedAt := range z.failures[zone] {
+		if now.Sub(failedAt) > ZoneFailureWindow {
+			delete(z.failures[zone], family)
+		}
+	}
+	if len(z.failures[zone]) < ZoneFailureThreshold {
+		return false
+	}
+	ttl := ZoneImpairedTTL << z.strikes[zone]
+	if ttl > ZoneImpairedMaxTTL || ttl <= 0 {
+		ttl = ZoneImpairedMaxTTL
+	}
+	log.FromContext(ctx).WithValues(
+		"reason", reason,
+		"zone", zone,
+		"instance-families", sets.List(sets.KeySet(z.failures[zone])),
+		"ttl", ttl).Info("removing impaired zone from offerings")
+	z.impaired[zone] = zoneImpairment{reason: reason, expiration: now.Add(ttl)}
+	z.strikes[zone]++
+	delete(z.failures, zone)
+	return true
+}
+
+// RecordSuccess records a successful launch in the zone, which resets the failures and the backoff of the zone
+func (z *ZoneHealth) RecordSuccess(zone string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	delete(z.failures, zone)
+	delete(z.strikes, zone)
+}
+
+// SetReported replaces the zones that EC2 reports as impaired with their states. It returns true if the set of reported
+// zones changed.
+func (z *ZoneHealth) SetReported(states map[string]string) bool {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if len(z.reported) == len(states) && lo.EveryBy(lo.Keys(states), func(zone string) bool {
+		state, ok := z.reported[zone]
+		return ok && state == states[zone]
+	}) {
+		return false
+	}
+	z.reported = lo.Assign(states)
+	return true
+}
+
+// List returns the zones that are currently impaired. A zone that is impaired for both reasons is listed once for each.
+func (z *ZoneHealth) List() []ImpairedZone {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	var zones []ImpairedZone
+	for zone, state := range z.reported {
+		zones = append(zones, ImpairedZone{Zone: zone, Source: ZoneImpairedSourceZoneState, Reason: state})
+	}
+	now := z.clk.Now()
+	for zone, impairment := range z.impaired {
+		if now.Before(impairment.expiration) {
+			zones = append(zones, ImpairedZone{Zone: zone, Source: ZoneImpairedSourceLaunchFailures, Reason: impairment.reason, Expiration: impairment.expiration})
+		}
+	}
+	return zones
+}
+
+func (z *ZoneHealth) Flush() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.failures = map[string]map[string]time.Time{}
+	z.impaired = map[string]zoneImpairment{}
+	z.strikes = map[string]int{}
+	z.reported = map[string]string{}
+}
@@ -0,0 +1,63 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+
+	"github.com/samber/lo"
+)
+
+// ZoneScores stores the suitability score of each zone for the NodePools that are interruption sensitive. Scores are
+// in the range [0, 1], where a higher score is preferred.
+type ZoneScores struct {
+	mu sync.RWMutex
+	// key: <nodePool>, value: zone -> score
+	scores map[string]map[string]float64
+}
+
+func NewZoneScores() *ZoneScores {
+	return &ZoneScores{scores: map[string]map[string]float64{}}
+}
+
+// Get returns the zone scores of the NodePool, or false if the NodePool hasn't been scored
+func (z *ZoneScores) Get(nodePool string) (map[string]float64, bool) {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	scores, ok := z.scores[nodePool]
+	return lo.Assign(scores), ok
+}
+
+func (z *ZoneScores) Set(nodePool string, scores map[string]float64) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.scores[nodePool] = lo.Assign(scores)
+}
+
+func (z *ZoneScores) Delete(nodePool string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	delete(z.scores, nodePool)
+}
+
+func (z *ZoneScores) Flush() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.scores = map[string]map[string]float64{}
+}
@@ -26,19 +26,22 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/clock"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	coreapis "sigs.k8s.io/karpenter/pkg/apis"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/events"
-	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/functional"
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
+	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -49,37 +52,60 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/terminationhook"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 )
 
+// targetGroupDeregistrationTimeout is the maximum amount of time that termination waits for an instance to be
+// deregistered from target groups before the deregistration delay is known
+const targetGroupDeregistrationTimeout = time.Minute
+
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
 
 type CloudProvider struct {
 	kubeClient client.Client
 	recorder   events.Recorder
-
-	instanceTypeProvider  instancetype.Provider
-	instanceProvider      instance.Provider
-	amiProvider           amifamily.Provider
-	securityGroupProvider securitygroup.Provider
+	clock      clock.Clock
+
+	instanceTypeProvider    instancetype.Provider
+	instanceProvider        instance.Provider
+	amiProvider             amifamily.Provider
+	securityGroupProvider   securitygroup.Provider
+	launchRamps             *awscache.LaunchRamps
+	terminationHookProvider terminationhook.Provider
+
+	// key: <nodePool>/<instanceTypes>, value: number of insufficient capacity errors
+	pinnedLaunchFailures *cache.Cache
+	// key: instance ID of instances whose console output was captured
+	consoleOutputs *cache.Cache
+	// key: <decision>/<nodeClaim UID> of the launches and terminations that were reported in read-only mode
+	readOnlyDecisions *cache.Cache
 }
 
 func New(instanceTypeProvider instancetype.Provider, instanceProvider instance.Provider, recorder events.Recorder,
-	kubeClient client.Client, amiProvider amifamily.Provider, securityGroupProvider securitygroup.Provider) *CloudProvider {
+	kubeClient client.Client, amiProvider amifamily.Provider, securityGroupProvider securitygroup.Provider, launchRamps *awscache.LaunchRamps,
+	terminationHookProvider terminationhook.Provider, clk clock.Clock) *CloudProvider {
 	return &CloudProvider{
-		instanceTypeProvider:  instanceTypeProvider,
-		instanceProvider:      instanceProvider,
-		kubeClient:            kubeClient,
-		amiProvider:           amiProvider,
-		securityGroupProvider: securityGroupProvider,
-		recorder:              recorder,
+		instanceTypeProvider:    instanceTypeProvider,
+		instanceProvider:        instanceProvider,
+		kubeClient:              kubeClient,
+		amiProvider:             amiProvider,
+		securityGroupProvider:   securityGroupProvider,
+		launchRamps:             launchRamps,
+		terminationHookProvider: terminationHookProvider,
+		recorder:                recorder,
+		clock:                   clk,
+		pinnedLaunchFailures:    cache.New(pinnedLaunchFailureTTL, awscache.DefaultCleanupInterval),
+		consoleOutputs:          cache.New(consoleOutputTTL, awscache.DefaultCleanupInterval),
+		readOnlyDecisions:       cache.New(readOnlyDecisionTTL, awscache.DefaultCleanupInterval),
 	}
 }
 
 // Create a NodeClaim given the constraints.
-func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (*corev1beta1.NodeClaim, error) {
+func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (_ *corev1beta1.NodeClaim, err error) {
 	nodeClass, err := c.resolveNodeClassFromNodeClaim(ctx, nodeClaim)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -88,25 +114,103 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *corev1beta1.NodeC
 		// We treat a failure to resolve the NodeClass as an ICE since this means there is no capacity possibilities for this NodeClaim
 		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("resolving node class, %w", err))
 	}
+	if providerID, ok := nodeClaim.Annotations[v1beta1.AnnotationAdoptedProviderID]; ok {
+		return c.adopt(ctx, nodeClaim, nodeClass, providerID)
+	}
 	nodeClassReady := nodeClass.StatusConditions().Get(status.ConditionReady)
 	if !nodeClassReady.IsTrue() {
 		return nil, fmt.Errorf("resolving ec2nodeclass, %s", nodeClassReady.Message)
 	}
+	if overridden := launchtemplate.OverriddenMetadataOptions(ctx, nodeClass); len(overridden) > 0 {
+		c.recorder.Publish(cloudproviderevents.EC2NodeClassMetadataOptionsOverridden(nodeClass, overridden))
+	}
+	// The EC2NodeClass hash is taken from the EC2NodeClass itself, so that overrides don't drift the NodeClaim
+	nodeClassHash := nodeClass.Hash()
+	if nodeClass, err = c.applyLaunchOverrides(ctx, nodeClaim, nodeClass); err != nil {
+		return nil, fmt.Errorf("applying launch overrides, %w", err)
+	}
 	instanceTypes, err := c.resolveInstanceTypes(ctx, nodeClaim, nodeClass)
 	if err != nil {
 		return nil, fmt.Errorf("resolving instance types, %w", err)
 	}
 	if len(instanceTypes) == 0 {
+		c.recordPinnedLaunchFailure(ctx, nodeClaim, nodeClass)
+		if err = zoneTypeUnavailableError(nodeClaim, nodeClass); err != nil {
+			c.recorder.Publish(cloudproviderevents.NodeClaimZoneTypeUnavailable(nodeClaim, err))
+			return nil, cloudprovider.NewInsufficientCapacityError(err)
+		}
 		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all requested instance types were unavailable during launch"))
 	}
+	if options.FromContext(ctx).ReadOnly {
+		return nil, c.reportLaunch(ctx, nodeClaim, instanceTypes)
+	}
+	rampedNodePool, err := c.reserveLaunch(ctx, nodeClaim)
+	if err != nil {
+		if awscache.IsLaunchRampExceeded(err) {
+			c.recorder.Publish(cloudproviderevents.NodeClaimLaunchRampExceeded(nodeClaim, err))
+		}
+		return nil, fmt.Errorf("reserving launch, %w", err)
+	}
+	// The reservation is released on every failed launch, so that failures don't count against the NodePool's ramp
+	defer func() {
+		if err != nil && rampedNodePool != "" {
+			c.launchRamps.Release(rampedNodePool)
+		}
+	}()
 	instance, err := c.instanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+	if err := updateCreateFleetFailed(nodeClaim, err); err != nil {
+		return nil, fmt.Errorf("updating %s condition, %w", v1beta1.NodeClaimConditionCreateFleetFailed, err)
+	}
 	if err != nil {
+		if cloudprovider.IsInsufficientCapacityError(err) {
+			c.recordPinnedLaunchFailure(ctx, nodeClaim, nodeClass)
+		}
+		if awscache.IsCreationLimitExceeded(err) {
+			c.recorder.Publish(cloudproviderevents.NodeClaimCreationLimitExceeded(nodeClaim, err))
+		}
 		return nil, fmt.Errorf("creating instance, %w", err)
 	}
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
 		return i.Name == instance.Type
 	})
 	nc := c.instanceToNodeClaim(instance, instanceType, nodeClass)
+	nc.Labels = lo.Assign(metadataLabels(ctx, instance, nodeClass), nc.Labels)
+	nc.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
+		v1beta1.AnnotationEC2NodeClassHash:        nodeClassHash,
+		v1beta1.AnnotationEC2NodeClassHashVersion: v1beta1.EC2NodeClassHashVersion,
+	})
+	return nc, nil
+}
+
+// adopt links the NodeClaim to the existing instance of the provider ID, rather than launching an instance for it
+func (c *CloudProvider) adopt(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass, providerID string) (*corev1beta1.NodeClaim, error) {
+	id, err := utils.ParseInstanceID(providerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting instance ID, %w", err)
+	}
+	instance, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		// The instance was terminated before it was adopted, so the NodeClaim is deleted rather than launched
+		if cloudprovider.IsNodeClaimNotFoundError(err) {
+			return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("adopting instance, %w", err))
+		}
+		return nil, fmt.Errorf("adopting instance, %w", err)
+	}
+	if instance.Tags[corev1beta1.ManagedByAnnotationKey] != options.FromContext(ctx).ClusterName {
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("adopting instance %s, instance isn't managed by the cluster", id))
+	}
+	instanceType, err := c.resolveInstanceTypeFromInstance(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("resolving instance type, %w", err)
+	}
+	// The instance is still tagged with the name of the NodeClaim that launched it, which the tagging controller
+	// doesn't overwrite
+	if err = c.instanceProvider.CreateTags(ctx, id, map[string]string{v1beta1.TagNodeClaim: nodeClaim.Name}); err != nil {
+		return nil, fmt.Errorf("adopting instance, %w", err)
+	}
+	log.FromContext(ctx).WithValues("provider-id", providerID).Info("adopted instance")
+	nc := c.instanceToNodeClaim(instance, instanceType, nodeClass)
+	nc.Labels = lo.Assign(metadataLabels(ctx, instance, nodeClass), nc.Labels)
 	nc.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{
 		v1beta1.AnnotationEC2NodeClassHash:        nodeClass.Hash(),
 		v1beta1.AnnotationEC2NodeClassHashVersion: v1beta1.EC2NodeClassHashVersion,
@@ -176,7 +280,8 @@ func (c *CloudProvider) GetInstanceTypes(ctx context.Context, nodePool *corev1be
 	if err != nil {
 		return nil, err
 	}
-	return instanceTypes, nil
+	// Spot offerings above the NodePool's max price aren't considered for scheduling
+	return instancetype.NewSpotMaxPrice(ctx, nodePool.Spec.Template.Annotations).Apply(instanceTypes), nil
 }
 
 func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) error {
@@ -185,9 +290,50 @@ func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *corev1beta1.NodeC
 		return fmt.Errorf("getting instance ID, %w", err)
 	}
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("id", id))
+	if options.FromContext(ctx).ReadOnly {
+		return c.reportTermination(ctx, nodeClaim, id)
+	}
+	if err = c.checkTerminationProtection(ctx, nodeClaim, id); err != nil {
+		return err
+	}
+	if err = c.waitForTargetGroupDeregistration(ctx, nodeClaim); err != nil {
+		return err
+	}
+	if err = c.waitForTerminationApproval(ctx, nodeClaim, id); err != nil {
+		return err
+	}
+	c.recordConsoleOutput(ctx, nodeClaim, id)
+	if c.hibernate(ctx, nodeClaim, id) {
+		return nil
+	}
 	return c.instanceProvider.Delete(ctx, id)
 }
 
+// waitForTargetGroupDeregistration returns an error, so that termination is retried, until the instance has been
+// deregistered from load balancer target groups and their deregistration delay has elapsed. If the instance isn't
+// deregistered within targetGroupDeregistrationTimeout, termination proceeds so that ELB failures can't block it.
+func (c *CloudProvider) waitForTargetGroupDeregistration(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) error {
+	if !options.FromContext(ctx).TargetGroupDeregistration {
+		return nil
+	}
+	deadline, ok := nodeClaim.Annotations[v1beta1.AnnotationTargetGroupDeregistrationDeadline]
+	if !ok {
+		if !nodeClaim.DeletionTimestamp.IsZero() && c.clock.Since(nodeClaim.DeletionTimestamp.Time) < targetGroupDeregistrationTimeout {
+			return fmt.Errorf("waiting for instance to be deregistered from target groups")
+		}
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, deadline)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed parsing target group deregistration deadline")
+		return nil
+	}
+	if c.clock.Now().Before(t) {
+		return fmt.Errorf("waiting for target group deregistration delay to elapse at %s", deadline)
+	}
+	return nil
+}
+
 func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (cloudprovider.DriftReason, error) {
 	// Not needed when GetInstanceTypes removes nodepool dependency
 	nodePoolName, ok := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
@@ -252,16 +398,11 @@ func (c *CloudProvider) resolveNodeClassFromNodePool(ctx context.Context, nodePo
 }
 
 func (c *CloudProvider) resolveInstanceTypes(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
-	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClaim.Spec.Kubelet, nodeClass)
+	instanceTypes, err := c.instanceTypeProvider.ListCompatible(ctx, nodeClaim, nodeClass)
 	if err != nil {
 		return nil, fmt.Errorf("getting instance types, %w", err)
 	}
-	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	return lo.Filter(instanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
-		return reqs.Compatible(i.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil &&
-			len(i.Offerings.Compatible(reqs).Available()) > 0 &&
-			resources.Fits(nodeClaim.Spec.Resources.Requests, i.Allocatable())
-	}), nil
+	return instanceTypes, nil
 }
 
 func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, instance *instance.Instance) (*cloudprovider.InstanceType, error) {
@@ -334,9 +475,28 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	if nodeClass != nil {
 		if subnet, ok := lo.Find(nodeClass.Status.Subnets, func(s v1beta1.Subnet) bool {
 			return s.Zone == i.Zone
-		}); ok && subnet.ZoneID != "" {
-			labels[v1beta1.LabelTopologyZoneID] = subnet.ZoneID
+		}); ok {
+			if subnet.ZoneID != "" {
+				labels[v1beta1.LabelTopologyZoneID] = subnet.ZoneID
+			}
+			if subnet.ZoneType != "" {
+				labels[v1beta1.LabelTopologyZoneType] = subnet.ZoneType
+			}
 		}
+		// Instances that were just launched by CreateFleet don't report their Outpost, so it's resolved from their subnet
+		if subnet, ok := lo.Find(nodeClass.Status.Subnets, func(s v1beta1.Subnet) bool {
+			return s.ID == i.SubnetID
+		}); ok && i.OutpostARN == "" {
+			i.OutpostARN = subnet.OutpostARN
+		}
+		if ami, ok := lo.Find(nodeClass.Status.AMIs, func(a v1beta1.AMI) bool {
+			return a.ID == i.ImageID
+		}); ok {
+			labels = lo.Assign(labels, amifamily.DriverLabelsFor(ami))
+		}
+	}
+	if i.OutpostARN != "" {
+		labels[v1beta1.LabelTopologyOutpostID] = utils.OutpostID(i.OutpostARN)
 	}
 	labels[corev1beta1.CapacityTypeLabelKey] = i.CapacityType
 	if v, ok := i.Tags[corev1beta1.NodePoolLabelKey]; ok {
@@ -352,7 +512,7 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	if i.State == ec2.InstanceStateNameShuttingDown || i.State == ec2.InstanceStateNameTerminated {
 		nodeClaim.DeletionTimestamp = &metav1.Time{Time: time.Now()}
 	}
-	nodeClaim.Status.ProviderID = fmt.Sprintf("aws:///%s/%s", i.Zone, i.ID)
+	nodeClaim.Status.ProviderID = utils.ProviderID(i.Zone, i.ID)
 	nodeClaim.Status.ImageID = i.ImageID
 	return nodeClaim
 }
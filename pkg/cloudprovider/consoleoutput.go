@@ -0,0 +1,74 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+)
+
+const (
+	// ConsoleOutputEventLength is the number of trailing characters of the console output that are included in the
+	// event on the NodeClaim
+	ConsoleOutputEventLength = 800
+	// consoleOutputLogLength is the number of trailing characters of the console output that are logged
+	consoleOutputLogLength = 16 * 1024
+	// consoleOutputTTL is how long instances whose console output was captured are remembered, so that the retries of
+	// their termination don't capture it again
+	consoleOutputTTL = time.Hour
+)
+
+// recordConsoleOutput captures the console output of an instance whose NodeClaim is terminated before its node
+// registered, e.g. because it didn't register within the registration TTL, and publishes the end of it in an event on
+// the NodeClaim. Bootstrap and userdata failures usually show up there, and the output is gone once the instance is
+// terminated. Failures to capture the output are logged and don't block termination.
+func (c *CloudProvider) recordConsoleOutput(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string) {
+	// NodeClaims without a UID were built from instances by garbage collection and don't exist in the cluster
+	if nodeClaim.UID == "" || nodeClaim.StatusConditions().IsTrue(corev1beta1.ConditionTypeRegistered) {
+		return
+	}
+	if _, ok := c.consoleOutputs.Get(id); ok {
+		return
+	}
+	c.consoleOutputs.SetDefault(id, struct{}{})
+	output, err := c.instanceProvider.GetConsoleOutput(ctx, id)
+	if err != nil {
+		log.FromContext(ctx).V(1).Error(err, "failed getting console output")
+		return
+	}
+	if output = strings.TrimSpace(output); output == "" {
+		return
+	}
+	log.FromContext(ctx).WithValues("console-output", tail(output, consoleOutputLogLength)).Info("captured console output of instance that never registered")
+	c.recorder.Publish(cloudproviderevents.NodeClaimConsoleOutput(nodeClaim, tail(output, ConsoleOutputEventLength)))
+}
+
+// tail returns the last n characters of the output, starting at a line boundary if there is one
+func tail(output string, n int) string {
+	if len(output) <= n {
+		return output
+	}
+	output = output[len(output)-n:]
+	if i := strings.IndexByte(output, '\n'); i >= 0 && i < len(output)-1 {
+		output = output[i+1:]
+	}
+	return output
+}
@@ -26,6 +26,7 @@ import (
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
@@ -77,6 +78,10 @@ func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *corev1beta1
 	if !found {
 		return "", fmt.Errorf(`finding node instance type "%s"`, nodeClaim.Labels[v1.LabelInstanceTypeStable])
 	}
+	// NodeClaims that were launched with an overridden AMI are only drifted once the override changes
+	if amiID, ok := nodeClaim.Annotations[v1beta1.AnnotationAMIIDOverride]; ok && options.FromContext(ctx).NodeClaimLaunchOverrides {
+		return lo.Ternary(instance.ImageID != amiID, AMIDrift, ""), nil
+	}
 	if len(nodeClass.Status.AMIs) == 0 {
 		return "", fmt.Errorf("no amis exist given constraints")
 	}
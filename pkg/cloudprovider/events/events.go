@@ -15,13 +15,19 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	v1 "k8s.io/api/core/v1"
 
-	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 )
 
-func NodePoolFailedToResolveNodeClass(nodePool *v1beta1.NodePool) events.Event {
+func NodePoolFailedToResolveNodeClass(nodePool *corev1beta1.NodePool) events.Event {
 	return events.Event{
 		InvolvedObject: nodePool,
 		Type:           v1.EventTypeWarning,
@@ -30,7 +36,7 @@ func NodePoolFailedToResolveNodeClass(nodePool *v1beta1.NodePool) events.Event {
 	}
 }
 
-func NodeClaimFailedToResolveNodeClass(nodeClaim *v1beta1.NodeClaim) events.Event {
+func NodeClaimFailedToResolveNodeClass(nodeClaim *corev1beta1.NodeClaim) events.Event {
 	return events.Event{
 		InvolvedObject: nodeClaim,
 		Type:           v1.EventTypeWarning,
@@ -38,3 +44,115 @@ func NodeClaimFailedToResolveNodeClass(nodeClaim *v1beta1.NodeClaim) events.Even
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+func NodeClaimLaunchOverridesIgnored(nodeClaim *corev1beta1.NodeClaim, annotations []string) events.Event {
+	sort.Strings(annotations)
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "LaunchOverridesIgnored",
+		Message:        fmt.Sprintf("Ignoring %s since NodeClaim launch overrides aren't enabled", strings.Join(annotations, ", ")),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodePoolPinnedInstanceTypesUnavailable(nodePool *corev1beta1.NodePool, pinned []string, failures int, suggested []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           v1.EventTypeWarning,
+		Reason:         "PinnedInstanceTypesUnavailable",
+		Message: fmt.Sprintf("Instance types %s failed to launch %d times due to insufficient capacity, consider also allowing %s",
+			strings.Join(pinned, ", "), failures, strings.Join(suggested, ", ")),
+		DedupeValues: []string{string(nodePool.UID), strings.Join(pinned, ",")},
+	}
+}
+
+func NodeClaimCreationLimitExceeded(nodeClaim *corev1beta1.NodeClaim, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "CreationLimitExceeded",
+		Message:        fmt.Sprintf("Launch stopped, %s", err),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimLaunchRampExceeded(nodeClaim *corev1beta1.NodeClaim, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "LaunchRampExceeded",
+		Message:        fmt.Sprintf("Launch delayed, %s", err),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimZoneTypeUnavailable(nodeClaim *corev1beta1.NodeClaim, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ZoneTypeUnavailable",
+		Message:        fmt.Sprintf("Launch failed, %s", err),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimTerminationProtected(nodeClaim *corev1beta1.NodeClaim) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "TerminationProtected",
+		Message:        fmt.Sprintf("Termination blocked, remove the %s annotation to terminate the instance", v1beta1.AnnotationTerminationProtection),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimAwaitingTerminationApproval(nodeClaim *corev1beta1.NodeClaim) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "AwaitingTerminationApproval",
+		Message:        fmt.Sprintf("Node was drained, annotate the nodeclaim with %s=true to terminate the instance", v1beta1.AnnotationTerminationApproved),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimConsoleOutput(nodeClaim *corev1beta1.NodeClaim, output string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "ConsoleOutput",
+		Message:        fmt.Sprintf("Instance terminated before its node registered, end of console output:\n%s", output),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func EC2NodeClassMetadataOptionsOverridden(nodeClass *v1beta1.EC2NodeClass, overridden []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeWarning,
+		Reason:         "MetadataOptionsOverridden",
+		Message:        fmt.Sprintf("Metadata options %s are overridden by the enforced metadata settings", strings.Join(overridden, ", ")),
+		DedupeValues:   []string{string(nodeClass.UID), strings.Join(overridden, ",")},
+	}
+}
+
+func NodeClaimReadOnlyLaunch(nodeClaim *corev1beta1.NodeClaim, cheapest string, instanceTypes int) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "ReadOnlyLaunch",
+		Message:        fmt.Sprintf("Would have launched an instance from %d instance types, most likely %s, if read-only mode wasn't enabled", instanceTypes, cheapest),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimReadOnlyTermination(nodeClaim *corev1beta1.NodeClaim, id string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "ReadOnlyTermination",
+		Message:        fmt.Sprintf("Would have terminated instance %s if read-only mode wasn't enabled", id),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+)
+
+// maxConditionMessageLength is the length that condition messages are truncated to, which matches the messages of the
+// conditions that Karpenter's core sets on NodeClaims
+const maxConditionMessageLength = 300
+
+// updateCreateFleetFailed reflects the result of a launch of the NodeClaim in its CreateFleetFailed condition. The
+// condition is set with the category of the errors as its reason when the launch failed with errors from CreateFleet,
+// and is cleared when the launch succeeded.
+func updateCreateFleetFailed(nodeClaim *corev1beta1.NodeClaim, err error) error {
+	fleetErr, ok := awserrors.AsFleetError(err)
+	if err == nil || !ok {
+		return nodeClaim.StatusConditions().Clear(v1beta1.NodeClaimConditionCreateFleetFailed)
+	}
+	message := fleetErr.Error()
+	if len(message) > maxConditionMessageLength {
+		message = message[:maxConditionMessageLength] + "..."
+	}
+	nodeClaim.StatusConditions().SetTrueWithReason(v1beta1.NodeClaimConditionCreateFleetFailed, fleetErr.Category, message)
+	return nil
+}
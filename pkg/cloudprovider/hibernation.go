@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// hibernate hibernates the instance of a deleted NodeClaim, rather than terminating it, when hibernation is enabled for
+// the NodeClaim and the instance is a running on-demand instance that was launched with hibernation configured. The
+// instance is resumed for a later NodeClaim of its NodePool. Drifted and expired NodeClaims are always terminated, since
+// their instances would be replaced again once they're resumed. It returns whether the instance was hibernated, and
+// failures are logged so that the instance is terminated instead.
+func (c *CloudProvider) hibernate(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string) bool {
+	if !options.FromContext(ctx).Hibernation || nodeClaim.Annotations[v1beta1.AnnotationHibernation] != v1beta1.HibernationEnabled {
+		return false
+	}
+	if nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeDrifted).IsTrue() || nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeExpired).IsTrue() {
+		return false
+	}
+	instance, err := c.instanceProvider.Get(ctx, id)
+	if err != nil || !instance.HibernationConfigured || instance.CapacityType != corev1beta1.CapacityTypeOnDemand || instance.State != ec2.InstanceStateNameRunning {
+		return false
+	}
+	if err = c.instanceProvider.Hibernate(ctx, id, nodeClaim.Labels[corev1beta1.NodePoolLabelKey]); err != nil {
+		log.FromContext(ctx).Error(err, "failed hibernating instance, terminating it instead")
+		return false
+	}
+	log.FromContext(ctx).Info("hibernated instance")
+	return true
+}
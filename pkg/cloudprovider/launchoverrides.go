@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+)
+
+// launchOverrides returns the annotations of the NodeClaim that override the launch parameters of its EC2NodeClass
+func launchOverrides(nodeClaim *corev1beta1.NodeClaim) map[string]string {
+	return lo.PickByKeys(nodeClaim.Annotations, []string{v1beta1.AnnotationAMIIDOverride, v1beta1.AnnotationInstanceProfileOverride})
+}
+
+// applyLaunchOverrides returns a copy of the EC2NodeClass with the AMI and instance profile that the NodeClaim is
+// annotated with, so that a single NodeClaim can be launched with e.g. a new AMI before it's rolled into the EC2NodeClass.
+// The EC2NodeClass is returned as is when the NodeClaim has no overrides, or when NodeClaim launch overrides aren't enabled.
+func (c *CloudProvider) applyLaunchOverrides(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass) (*v1beta1.EC2NodeClass, error) {
+	overrides := launchOverrides(nodeClaim)
+	if len(overrides) == 0 {
+		return nodeClass, nil
+	}
+	if !options.FromContext(ctx).NodeClaimLaunchOverrides {
+		c.recorder.Publish(cloudproviderevents.NodeClaimLaunchOverridesIgnored(nodeClaim, lo.Keys(overrides)))
+		return nodeClass, nil
+	}
+	nodeClass = nodeClass.DeepCopy()
+	if amiID, ok := overrides[v1beta1.AnnotationAMIIDOverride]; ok {
+		nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{ID: amiID}}
+		amis, err := c.amiProvider.List(ctx, nodeClass)
+		if err != nil {
+			return nil, fmt.Errorf("getting ami %s, %w", amiID, err)
+		}
+		if len(amis) == 0 {
+			return nil, fmt.Errorf("ami %s doesn't exist", amiID)
+		}
+		nodeClass.Status.AMIs = lo.Map(amis, func(ami amifamily.AMI, _ int) v1beta1.AMI {
+			reqs := lo.Map(ami.Requirements.NodeSelectorRequirements(), func(item corev1beta1.NodeSelectorRequirementWithMinValues, _ int) v1.NodeSelectorRequirement {
+				return item.NodeSelectorRequirement
+			})
+			sort.Slice(reqs, func(i, j int) bool { return reqs[i].Key < reqs[j].Key })
+			return v1beta1.AMI{Name: ami.Name, ID: ami.AmiID, Variant: ami.Variant, Requirements: reqs}
+		})
+	}
+	if instanceProfile, ok := overrides[v1beta1.AnnotationInstanceProfileOverride]; ok {
+		nodeClass.Spec.Role = ""
+		nodeClass.Spec.InstanceProfile = lo.ToPtr(instanceProfile)
+		nodeClass.Status.InstanceProfile = instanceProfile
+	}
+	log.FromContext(ctx).WithValues("overrides", overrides).Info("launching with overrides of the ec2nodeclass")
+	return nodeClass, nil
+}
@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+// reserveLaunch counts the launch of the NodeClaim against the launch ramp of its NodePool. It returns the name of the
+// NodePool whose ramp the launch was counted against, which is empty if the NodePool doesn't have a launch ramp.
+func (c *CloudProvider) reserveLaunch(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (string, error) {
+	nodePoolName, ok := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	if !ok {
+		return "", nil
+	}
+	nodePool := &corev1beta1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		return "", client.IgnoreNotFound(fmt.Errorf("getting nodepool, %w", err))
+	}
+	policy, ok := launchRampPolicy(ctx, nodePool)
+	if !ok {
+		return "", nil
+	}
+	if err := c.launchRamps.Reserve(nodePoolName, policy); err != nil {
+		return "", err
+	}
+	return nodePoolName, nil
+}
+
+// launchRampPolicy returns the launch ramp policy of the NodePool, or false if it doesn't have a valid one
+func launchRampPolicy(ctx context.Context, nodePool *corev1beta1.NodePool) (awscache.LaunchRampPolicy, bool) {
+	value, ok := nodePool.Annotations[v1beta1.AnnotationLaunchRamp]
+	if !ok {
+		return awscache.LaunchRampPolicy{}, false
+	}
+	policy, err := parseLaunchRampPolicy(value, nodePool.Annotations[v1beta1.AnnotationLaunchRampDoublingWindow])
+	if err != nil {
+		// We don't return an error here since retrying won't fix the annotations
+		log.FromContext(ctx).WithValues("NodePool", client.ObjectKeyFromObject(nodePool), "value", value).Error(err, "invalid launch ramp")
+		return awscache.LaunchRampPolicy{}, false
+	}
+	return policy, true
+}
+
+// parseLaunchRampPolicy parses a launch ramp of the form "20/1m" and an optional doubling window, e.g. "5m"
+func parseLaunchRampPolicy(ramp, doublingWindow string) (awscache.LaunchRampPolicy, error) {
+	limit, period, ok := strings.Cut(ramp, "/")
+	if !ok {
+		return awscache.LaunchRampPolicy{}, fmt.Errorf("expected <nodes>/<period>, got %q", ramp)
+	}
+	policy := awscache.LaunchRampPolicy{}
+	var err error
+	if policy.Limit, err = strconv.Atoi(limit); err != nil || policy.Limit <= 0 {
+		return awscache.LaunchRampPolicy{}, fmt.Errorf("nodes must be a positive integer, got %q", limit)
+	}
+	if policy.Period, err = time.ParseDuration(period); err != nil || policy.Period <= 0 {
+		return awscache.LaunchRampPolicy{}, fmt.Errorf("period must be a positive duration, got %q", period)
+	}
+	if doublingWindow != "" {
+		if policy.DoublingWindow, err = time.ParseDuration(doublingWindow); err != nil || policy.DoublingWindow <= 0 {
+			return awscache.LaunchRampPolicy{}, fmt.Errorf("%s must be a positive duration", v1beta1.AnnotationLaunchRampDoublingWindow)
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+)
+
+// instanceMetadata is the data that the metadataLabels of an EC2NodeClass are rendered with
+type instanceMetadata struct {
+	InstanceID   string
+	InstanceType string
+	ImageID      string
+	Zone         string
+	ZoneID       string
+	SubnetID     string
+	VPCID        string
+	CapacityType string
+}
+
+// metadataLabels renders the metadataLabels of the EC2NodeClass with the metadata of the instance. Labels that fail to
+// render, or that render to an invalid label value, are skipped rather than failing the launch since the instance is
+// already running.
+func metadataLabels(ctx context.Context, i *instance.Instance, nodeClass *v1beta1.EC2NodeClass) map[string]string {
+	if len(nodeClass.Spec.MetadataLabels) == 0 {
+		return nil
+	}
+	data := instanceMetadata{
+		InstanceID:   i.ID,
+		InstanceType: i.Type,
+		ImageID:      i.ImageID,
+		Zone:         i.Zone,
+		SubnetID:     i.SubnetID,
+		VPCID:        i.VPCID,
+		CapacityType: i.CapacityType,
+	}
+	if subnet, ok := lo.Find(nodeClass.Status.Subnets, func(s v1beta1.Subnet) bool { return s.Zone == i.Zone }); ok {
+		data.ZoneID = subnet.ZoneID
+	}
+	labels := map[string]string{}
+	for key, value := range nodeClass.Spec.MetadataLabels {
+		// Labels in restricted domains are rejected at admission, but are also skipped here in case validation is bypassed
+		if corev1beta1.IsRestrictedNodeLabel(key) {
+			continue
+		}
+		rendered, err := renderMetadataLabel(key, value, data)
+		if err != nil {
+			log.FromContext(ctx).WithValues("label", key).Error(err, "failed rendering metadata label")
+			continue
+		}
+		labels[key] = rendered
+	}
+	return labels
+}
+
+func renderMetadataLabel(key, value string, data instanceMetadata) (string, error) {
+	tmpl, err := template.New(key).Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("parsing template, %w", err)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing template, %w", err)
+	}
+	if errs := validation.IsValidLabelValue(buf.String()); len(errs) != 0 {
+		return "", fmt.Errorf("invalid label value %q, %s", buf.String(), strings.Join(errs, ", "))
+	}
+	return buf.String(), nil
+}
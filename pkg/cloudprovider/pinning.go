@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+)
+
+const (
+	// maxPinnedInstanceTypes is the largest number of instance types that a NodeClaim can be restricted to for it to be
+	// considered pinned, e.g. by a pod's node.kubernetes.io/instance-type node selector
+	maxPinnedInstanceTypes = 3
+	// PinnedLaunchFailureThreshold is the number of insufficient capacity errors after which Karpenter suggests
+	// compatible instance types for pinned instance types
+	PinnedLaunchFailureThreshold = 3
+	// pinnedLaunchFailureTTL is how long launch failures of pinned instance types are remembered
+	pinnedLaunchFailureTTL = time.Hour
+	// maxSuggestedInstanceTypes is the largest number of instance types that are suggested for each pinned instance type
+	maxSuggestedInstanceTypes = 3
+)
+
+// recordPinnedLaunchFailure counts the insufficient capacity errors of NodeClaims that are pinned to a few instance
+// types and, once the NodePool has opted in and the failures reach PinnedLaunchFailureThreshold, publishes an event
+// that suggests larger sizes of the same families. The launch request itself isn't broadened since the nodes wouldn't
+// carry the instance type label that the pinned pods select on.
+func (c *CloudProvider) recordPinnedLaunchFailure(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass) {
+	pinned := pinnedInstanceTypes(nodeClaim)
+	nodePoolName, ok := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	if len(pinned) == 0 || !ok {
+		return
+	}
+	key := fmt.Sprintf("%s/%s", nodePoolName, strings.Join(pinned, ","))
+	_ = c.pinnedLaunchFailures.Add(key, 0, pinnedLaunchFailureTTL)
+	failures, err := c.pinnedLaunchFailures.IncrementInt(key, 1)
+	if err != nil || failures < PinnedLaunchFailureThreshold {
+		return
+	}
+	nodePool := &corev1beta1.NodePool{}
+	if err = c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		log.FromContext(ctx).V(1).Error(err, "failed resolving nodepool")
+		return
+	}
+	if nodePool.Annotations[v1beta1.AnnotationSuggestInstanceTypes] != "true" {
+		return
+	}
+	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClaim.Spec.Kubelet, nodeClass)
+	if err != nil {
+		log.FromContext(ctx).V(1).Error(err, "failed listing instance types")
+		return
+	}
+	if suggested := suggestInstanceTypes(nodeClaim, pinned, instanceTypes); len(suggested) > 0 {
+		c.recorder.Publish(cloudproviderevents.NodePoolPinnedInstanceTypesUnavailable(nodePool, pinned, failures, suggested))
+	}
+}
+
+// pinnedInstanceTypes returns the instance types that the NodeClaim is restricted to, if there are only a few of them
+func pinnedInstanceTypes(nodeClaim *corev1beta1.NodeClaim) []string {
+	requirement := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelInstanceTypeStable)
+	if requirement.Operator() != v1.NodeSelectorOpIn || requirement.Len() > maxPinnedInstanceTypes {
+		return nil
+	}
+	pinned := requirement.Values()
+	sort.Strings(pinned)
+	return pinned
+}
+
+// suggestInstanceTypes returns the next larger sizes in the families of the pinned instance types that have capacity
+// available in the NodeClaim's zones and capacity types
+func suggestInstanceTypes(nodeClaim *corev1beta1.NodeClaim, pinned []string, allInstanceTypes []*cloudprovider.InstanceType) []string {
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(lo.Reject(nodeClaim.Spec.Requirements, func(r corev1beta1.NodeSelectorRequirementWithMinValues, _ int) bool {
+		return r.Key == v1.LabelInstanceTypeStable
+	})...)
+	var suggested []string
+	for _, name := range pinned {
+		it, ok := lo.Find(allInstanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == name })
+		if !ok {
+			continue
+		}
+		candidates := lo.Filter(allInstanceTypes, func(candidate *cloudprovider.InstanceType, _ int) bool {
+			return family(candidate.Name) == family(name) &&
+				candidate.Capacity.Cpu().Cmp(*it.Capacity.Cpu()) > 0 &&
+				!lo.Contains(pinned, candidate.Name) &&
+				len(candidate.Offerings.Compatible(reqs).Available()) > 0
+		})
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Capacity.Cpu().Cmp(*candidates[j].Capacity.Cpu()) < 0
+		})
+		suggested = append(suggested, lo.Map(lo.Slice(candidates, 0, maxSuggestedInstanceTypes), func(it *cloudprovider.InstanceType, _ int) string { return it.Name })...)
+	}
+	return lo.Uniq(suggested)
+}
+
+func family(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+	"github.com/aws/karpenter-provider-aws/pkg/readonly"
+)
+
+// readOnlyDecisionTTL is how long the NodeClaims whose launch or termination was reported are remembered, so that the
+// retries of the launch or termination aren't counted again
+const readOnlyDecisionTTL = time.Hour
+
+// readOnlyLaunch describes the launch that would have been performed for a NodeClaim outside of read-only mode
+type readOnlyLaunch struct {
+	InstanceTypes []string
+	CapacityTypes []string
+	Zones         []string
+	// Cheapest is the cheapest of the offerings that the instance would have been launched from
+	Cheapest      string
+	CheapestPrice float64
+}
+
+// reportLaunch logs, counts and publishes the launch that would have been performed for the NodeClaim, and returns an
+// error, so that the launch is retried and the NodeClaim is eventually deleted by the registration TTL, without
+// launching an instance
+func (c *CloudProvider) reportLaunch(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) error {
+	decision := newReadOnlyLaunch(nodeClaim, instanceTypes)
+	if c.firstReadOnlyDecision(readonly.DecisionLaunch, nodeClaim, "") {
+		log.FromContext(ctx).WithValues(
+			"instance-types", decision.InstanceTypes,
+			"capacity-types", decision.CapacityTypes,
+			"zones", decision.Zones,
+			"cheapest", decision.Cheapest,
+			"cheapest-price", decision.CheapestPrice,
+		).Info("would have launched instance in read-only mode")
+		readonly.RecordDecision(readonly.DecisionLaunch, nodeClaim.Labels[corev1beta1.NodePoolLabelKey])
+	}
+	c.recorder.Publish(cloudproviderevents.NodeClaimReadOnlyLaunch(nodeClaim, decision.Cheapest, len(decision.InstanceTypes)))
+	return fmt.Errorf("launching instance, read-only mode is enabled")
+}
+
+// reportTermination logs, counts and publishes the termination of the instance that would have been performed for the
+// NodeClaim, and returns an error, so that the termination is retried without terminating the instance
+func (c *CloudProvider) reportTermination(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string) error {
+	if c.firstReadOnlyDecision(readonly.DecisionTerminate, nodeClaim, id) {
+		log.FromContext(ctx).WithValues(
+			"drifted", nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeDrifted).IsTrue(),
+			"expired", nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeExpired).IsTrue(),
+			"empty", nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeEmpty).IsTrue(),
+		).Info("would have terminated instance in read-only mode")
+		readonly.RecordDecision(readonly.DecisionTerminate, nodeClaim.Labels[corev1beta1.NodePoolLabelKey])
+	}
+	// NodeClaims without a UID were built from instances by garbage collection and don't exist in the cluster
+	if nodeClaim.UID != "" {
+		c.recorder.Publish(cloudproviderevents.NodeClaimReadOnlyTermination(nodeClaim, id))
+	}
+	return fmt.Errorf("terminating instance, read-only mode is enabled")
+}
+
+// firstReadOnlyDecision returns whether the decision is reported for the NodeClaim for the first time
+func (c *CloudProvider) firstReadOnlyDecision(decision string, nodeClaim *corev1beta1.NodeClaim, id string) bool {
+	key := fmt.Sprintf("%s/%s", decision, lo.Ternary(nodeClaim.UID != "", string(nodeClaim.UID), id))
+	if _, ok := c.readOnlyDecisions.Get(key); ok {
+		return false
+	}
+	c.readOnlyDecisions.SetDefault(key, struct{}{})
+	return true
+}
+
+// newReadOnlyLaunch returns the instance types, capacity types and zones that the NodeClaim would have been launched
+// with, and the cheapest of its offerings, which is the one that is most likely to be launched
+func newReadOnlyLaunch(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) readOnlyLaunch {
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	decision := readOnlyLaunch{}
+	capacityTypes, zones := sets.New[string](), sets.New[string]()
+	for _, it := range instanceTypes {
+		decision.InstanceTypes = append(decision.InstanceTypes, it.Name)
+		for _, o := range it.Offerings.Available().Compatible(reqs) {
+			capacityTypes.Insert(o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any())
+			zones.Insert(o.Requirements.Get(v1.LabelTopologyZone).Any())
+			if decision.Cheapest == "" || o.Price < decision.CheapestPrice {
+				decision.Cheapest = fmt.Sprintf("%s/%s/%s", it.Name, o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any(), o.Requirements.Get(v1.LabelTopologyZone).Any())
+				decision.CheapestPrice = o.Price
+			}
+		}
+	}
+	decision.CapacityTypes = sets.List(capacityTypes)
+	decision.Zones = sets.List(zones)
+	return decision
+}
@@ -16,8 +16,11 @@ package cloudprovider_test
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -26,10 +29,11 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/client-go/tools/record"
 	clock "k8s.io/utils/clock/testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	opstatus "github.com/awslabs/operatorpkg/status"
@@ -38,11 +42,16 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/terminationhook"
+	"github.com/aws/karpenter-provider-aws/pkg/readonly"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	corecloudproivder "sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -67,7 +76,8 @@ var prov *provisioning.Provisioner
 var cloudProvider *cloudprovider.CloudProvider
 var cluster *state.Cluster
 var fakeClock *clock.FakeClock
-var recorder events.Recorder
+var recorder *coretest.EventRecorder
+var launchRamps *awscache.LaunchRamps
 
 func TestAWS(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -82,9 +92,10 @@ var _ = BeforeSuite(func() {
 	ctx, stop = context.WithCancel(ctx)
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = clock.NewFakeClock(time.Now())
-	recorder = events.NewRecorder(&record.FakeRecorder{})
+	recorder = coretest.NewEventRecorder()
+	launchRamps = awscache.NewLaunchRamps(fakeClock)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, launchRamps, awsEnv.TerminationHookProvider, fakeClock)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, recorder, cloudProvider, cluster)
 })
@@ -100,6 +111,8 @@ var _ = BeforeEach(func() {
 
 	cluster.Reset()
 	awsEnv.Reset()
+	recorder.Reset()
+	launchRamps.Flush()
 
 	awsEnv.LaunchTemplateProvider.KubeDNSIP = net.ParseIP("10.0.100.10")
 	awsEnv.LaunchTemplateProvider.ClusterEndpoint = "https://test-cluster"
@@ -197,6 +210,52 @@ var _ = Describe("CloudProvider", func() {
 		_, err := cloudProvider.Create(ctx, nodeClaim)
 		Expect(err).To(HaveOccurred())
 	})
+	Context("Adoption", func() {
+		var instance *ec2.Instance
+		BeforeEach(func() {
+			instance = &ec2.Instance{
+				InstanceId:     aws.String(fake.InstanceID()),
+				InstanceType:   aws.String("m5.large"),
+				ImageId:        aws.String("ami-test1"),
+				State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+				Placement:      &ec2.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+				PrivateDnsName: aws.String(fake.PrivateDNSName()),
+				LaunchTime:     aws.Time(time.Now().Add(-time.Hour)),
+				Tags: []*ec2.Tag{
+					{Key: aws.String(corev1beta1.NodePoolLabelKey), Value: aws.String(nodePool.Name)},
+					{Key: aws.String(corev1beta1.ManagedByAnnotationKey), Value: aws.String(options.FromContext(ctx).ClusterName)},
+					{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String("previous-nodeclaim")},
+				},
+			}
+			awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+				v1beta1.AnnotationAdoptedProviderID: fake.ProviderID(aws.StringValue(instance.InstanceId)),
+			})
+		})
+		It("should link the NodeClaim to the adopted instance rather than launching one", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Status.ProviderID).To(Equal(fake.ProviderID(aws.StringValue(instance.InstanceId))))
+			Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "m5.large"))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, nodeClass.Hash()))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+			Expect(instance.Tags).To(ContainElement(&ec2.Tag{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String(nodeClaim.Name)}))
+		})
+		It("should return an ICE error when the instance no longer exists", func() {
+			awsEnv.EC2API.Instances.Delete(aws.StringValue(instance.InstanceId))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+		})
+		It("should return an ICE error when the instance isn't managed by the cluster", func() {
+			instance.Tags = lo.Reject(instance.Tags, func(t *ec2.Tag, _ int) bool { return aws.StringValue(t.Key) == corev1beta1.ManagedByAnnotationKey })
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
 	It("should return an ICE error when there are no instance types to launch", func() {
 		// Specify no instance types and expect to receive a capacity error
 		nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
@@ -213,6 +272,89 @@ var _ = Describe("CloudProvider", func() {
 		Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(cloudProviderNodeClaim).To(BeNil())
 	})
+	Context("CreateFleet Errors", func() {
+		fleetErrors := func(code string) *ec2.CreateFleetOutput {
+			return &ec2.CreateFleetOutput{
+				Errors: []*ec2.CreateFleetError{
+					{
+						ErrorCode:    aws.String(code),
+						ErrorMessage: aws.String("synthetic error"),
+						LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+							Overrides: &ec2.FleetLaunchTemplateOverrides{
+								InstanceType:     aws.String("m5.large"),
+								AvailabilityZone: aws.String("test-zone-1a"),
+							},
+						},
+					},
+				},
+			}
+		}
+		It("should categorize quota errors and keep them as insufficient capacity errors", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Output.Set(fleetErrors("VcpuLimitExceeded"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+			fleetErr, ok := awserrors.AsFleetError(err)
+			Expect(ok).To(BeTrue())
+			Expect(fleetErr.Category).To(Equal(awserrors.FleetErrorCategoryQuota))
+			condition := nodeClaim.StatusConditions().Get(v1beta1.NodeClaimConditionCreateFleetFailed)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal(awserrors.FleetErrorCategoryQuota))
+		})
+		It("should categorize misconfiguration errors", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Output.Set(fleetErrors("InvalidParameterCombination"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeFalse())
+			condition := nodeClaim.StatusConditions().Get(v1beta1.NodeClaimConditionCreateFleetFailed)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal(awserrors.FleetErrorCategoryMisconfiguration))
+			Expect(condition.Message).To(ContainSubstring("InvalidParameterCombination: synthetic error"))
+		})
+		It("should categorize CreateFleet requests that are rejected for permissions", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Error.Set(awserr.NewRequestFailure(awserr.New("UnauthorizedOperation", "not authorized", nil), 403, "request-id"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(nodeClaim.StatusConditions().Get(v1beta1.NodeClaimConditionCreateFleetFailed).Reason).To(Equal(awserrors.FleetErrorCategoryPermission))
+		})
+		It("should fail the launch and terminate the instance when CreateFleet launches an unexpected instance type", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Output.Set(&ec2.CreateFleetOutput{
+				Instances: []*ec2.CreateFleetInstance{
+					{
+						InstanceIds:  []*string{aws.String("i-0123456789abcdef0")},
+						InstanceType: aws.String("unexpected.large"),
+						Lifecycle:    aws.String(corev1beta1.CapacityTypeOnDemand),
+						LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+							Overrides: &ec2.FleetLaunchTemplateOverrides{
+								InstanceType:     aws.String("unexpected.large"),
+								AvailabilityZone: aws.String("test-zone-1a"),
+							},
+						},
+					},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(cloudProviderNodeClaim).To(BeNil())
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeFalse())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(aws.StringValueSlice(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Pop().InstanceIds)).To(ConsistOf("i-0123456789abcdef0"))
+			condition := nodeClaim.StatusConditions().Get(v1beta1.NodeClaimConditionCreateFleetFailed)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Reason).To(Equal(awserrors.FleetErrorCategoryUnexpectedInstanceType))
+			Expect(condition.Message).To(ContainSubstring("unexpected.large"))
+		})
+		It("should clear the condition once a launch succeeds", func() {
+			nodeClaim.StatusConditions().SetTrueWithReason(v1beta1.NodeClaimConditionCreateFleetFailed, awserrors.FleetErrorCategoryCapacity, "synthetic error")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeClaim.StatusConditions().Get(v1beta1.NodeClaimConditionCreateFleetFailed)).To(BeNil())
+		})
+	})
 	It("should set ImageID in the status field of the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
@@ -220,6 +362,22 @@ var _ = Describe("CloudProvider", func() {
 		Expect(cloudProviderNodeClaim).ToNot(BeNil())
 		Expect(cloudProviderNodeClaim.Status.ImageID).ToNot(BeEmpty())
 	})
+	It("should create a nodeClaim with fake providers", func() {
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClaim.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		instanceTypeProvider := fake.NewInstanceTypeProvider()
+		instanceTypeProvider.SetInstanceTypes(instanceTypes)
+		instanceProvider := fake.NewInstanceProvider()
+		fakeCloudProvider := cloudprovider.New(instanceTypeProvider, instanceProvider, recorder, env.Client,
+			fake.NewAMIProvider(), fake.NewSecurityGroupProvider(), launchRamps, &fake.TerminationHookProvider{}, fakeClock)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := fakeCloudProvider.Create(ctx, nodeClaim)
+		Expect(err).To(BeNil())
+		instances, err := instanceProvider.List(ctx)
+		Expect(err).To(BeNil())
+		Expect(instances).To(HaveLen(1))
+		Expect(cloudProviderNodeClaim.Status.ProviderID).To(HaveSuffix(instances[0].ID))
+	})
 	It("should return availability zone ID as a label on the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
@@ -235,6 +393,27 @@ var _ = Describe("CloudProvider", func() {
 		Expect(ok).To(BeTrue())
 		Expect(zoneID).To(Equal(subnet.ZoneID))
 	})
+	It("should return the zone type as a label on the nodeClaim", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProviderNodeClaim.GetLabels()).To(HaveKeyWithValue(v1beta1.LabelTopologyZoneType, v1beta1.ZoneTypeAvailabilityZone))
+	})
+	It("should explain why a nodeClaim can't launch in the zone type it requires", func() {
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: v1.NodeSelectorRequirement{
+				Key:      v1beta1.LabelTopologyZoneType,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{v1beta1.ZoneTypeWavelengthZone},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("no subnets of the EC2NodeClass are in a zone of type wavelength-zone"))
+		Expect(cloudProviderNodeClaim).To(BeNil())
+		Expect(recorder.Calls("ZoneTypeUnavailable")).To(Equal(1))
+	})
 	It("should return NodeClass Hash on the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
@@ -252,6 +431,241 @@ var _ = Describe("CloudProvider", func() {
 		Expect(ok).To(BeTrue())
 		Expect(v).To(Equal(v1beta1.EC2NodeClassHashVersion))
 	})
+	Context("Pinned Instance Types", func() {
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large"}},
+			})
+			awsEnv.EC2API.InsufficientCapacityPools.Set(lo.Map([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}, func(zone string, _ int) fake.CapacityPool {
+				return fake.CapacityPool{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: "m5.large", Zone: zone}
+			}))
+		})
+		expectLaunchFailures := func(n int) {
+			GinkgoHelper()
+			for i := 0; i < n; i++ {
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+			}
+		}
+		It("should suggest larger sizes of the family once pinned instance types repeatedly fail to launch", func() {
+			nodePool.Annotations = map[string]string{v1beta1.AnnotationSuggestInstanceTypes: "true"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			expectLaunchFailures(cloudprovider.PinnedLaunchFailureThreshold - 1)
+			Expect(recorder.Calls("PinnedInstanceTypesUnavailable")).To(Equal(0))
+
+			expectLaunchFailures(1)
+			Expect(recorder.Calls("PinnedInstanceTypesUnavailable")).To(Equal(1))
+			Expect(recorder.DetectedEvent(fmt.Sprintf("Instance types m5.large failed to launch %d times due to insufficient capacity, consider also allowing m5.xlarge, m5.2xlarge, m5.4xlarge", cloudprovider.PinnedLaunchFailureThreshold))).To(BeTrue())
+		})
+		It("should not suggest instance types for NodePools that haven't opted in", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			expectLaunchFailures(cloudprovider.PinnedLaunchFailureThreshold)
+			Expect(recorder.Calls("PinnedInstanceTypesUnavailable")).To(Equal(0))
+		})
+		It("should not suggest instance types for NodeClaims that aren't pinned", func() {
+			nodePool.Annotations = map[string]string{v1beta1.AnnotationSuggestInstanceTypes: "true"}
+			nodeClaim.Spec.Requirements[1].Values = []string{"m5.large", "m5.xlarge", "m5.2xlarge", "m5.4xlarge"}
+			awsEnv.EC2API.InsufficientCapacityPools.Set(lo.FlatMap(nodeClaim.Spec.Requirements[1].Values, func(it string, _ int) []fake.CapacityPool {
+				return lo.Map([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}, func(zone string, _ int) fake.CapacityPool {
+					return fake.CapacityPool{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: it, Zone: zone}
+				})
+			}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			expectLaunchFailures(cloudprovider.PinnedLaunchFailureThreshold)
+			Expect(recorder.Calls("PinnedInstanceTypesUnavailable")).To(Equal(0))
+		})
+	})
+	Context("Spot Max Price", func() {
+		var onDemandPrice float64
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large"}}},
+			}
+			var ok bool
+			onDemandPrice, ok = awsEnv.PricingProvider.OnDemandPrice("m5.large")
+			Expect(ok).To(BeTrue())
+		})
+		It("should cap the price of spot overrides at a percentage of the on-demand price", func() {
+			nodeClaim.Annotations = map[string]string{v1beta1.AnnotationSpotMaxPricePercent: "150"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			for _, override := range createFleetInput.LaunchTemplateConfigs[0].Overrides {
+				Expect(lo.Must(strconv.ParseFloat(aws.StringValue(override.MaxPrice), 64))).To(BeNumerically("~", onDemandPrice*1.5, 1e-9))
+			}
+		})
+		It("should apply the lower of the absolute and relative max prices", func() {
+			maxPrice := strconv.FormatFloat(onDemandPrice*1.1, 'f', -1, 64)
+			nodeClaim.Annotations = map[string]string{
+				v1beta1.AnnotationSpotMaxPricePercent: "150",
+				v1beta1.AnnotationSpotMaxPrice:        maxPrice,
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			for _, override := range createFleetInput.LaunchTemplateConfigs[0].Overrides {
+				Expect(aws.StringValue(override.MaxPrice)).To(Equal(maxPrice))
+			}
+		})
+		It("should not launch spot offerings above the max price", func() {
+			nodeClaim.Annotations = map[string]string{v1beta1.AnnotationSpotMaxPricePercent: "50"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should ignore an invalid max price", func() {
+			nodeClaim.Annotations = map[string]string{v1beta1.AnnotationSpotMaxPrice: "cheap"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(createFleetInput.LaunchTemplateConfigs[0].Overrides[0].MaxPrice).To(BeNil())
+		})
+		It("should exclude spot offerings above the max price of the nodepool from scheduling", func() {
+			nodePool.Spec.Template.Annotations = map[string]string{v1beta1.AnnotationSpotMaxPricePercent: "50"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceType, ok := lo.Find(instanceTypes, func(it *corecloudproivder.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			for _, offering := range instanceType.Offerings.Available() {
+				Expect(offering.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any()).To(Equal(corev1beta1.CapacityTypeOnDemand))
+			}
+			Expect(instanceType.Offerings.Available()).ToNot(BeEmpty())
+		})
+	})
+	Context("Creation Limits", func() {
+		It("should stop launching instances once the hourly instance cap is reached", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxInstancesPerHour: lo.ToPtr(1)}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Calls("CreationLimitExceeded")).To(Equal(0))
+
+			_, err = cloudProvider.Create(ctx, nodeClaim)
+			Expect(awscache.IsCreationLimitExceeded(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(recorder.Calls("CreationLimitExceeded")).To(Equal(1))
+			Expect(awsEnv.CreationLimits.Count(awscache.CreatedResourceInstance)).To(Equal(1))
+		})
+		It("should stop calling CreateFleet once the hourly CreateFleet cap is reached", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxCreateFleetRequestsPerHour: lo.ToPtr(2)}))
+			awsEnv.EC2API.InsufficientCapacityPools.Set(lo.Map([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}, func(zone string, _ int) fake.CapacityPool {
+				return fake.CapacityPool{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: "m5.large", Zone: zone}
+			}))
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large"}},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			for i := 0; i < 2; i++ {
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+				// Clear the ICE cache so that the next launch attempt reaches CreateFleet again
+				awsEnv.UnavailableOfferingsCache.Flush()
+			}
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(awscache.IsCreationLimitExceeded(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(2))
+			Expect(recorder.Calls("CreationLimitExceeded")).To(Equal(1))
+			// Instances that failed to launch don't count against the instance cap
+			Expect(awsEnv.CreationLimits.Count(awscache.CreatedResourceInstance)).To(Equal(0))
+		})
+	})
+	Context("Launch Ramp", func() {
+		BeforeEach(func() {
+			nodePool.Annotations = map[string]string{v1beta1.AnnotationLaunchRamp: "2/1m"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		})
+		launch := func(n int) (launched int, err error) {
+			for i := 0; i < n; i++ {
+				if _, err = cloudProvider.Create(ctx, nodeClaim); err != nil {
+					return launched, err
+				}
+				launched++
+			}
+			return launched, nil
+		}
+		It("should delay launches once the launch ramp of the nodepool is reached", func() {
+			launched, err := launch(3)
+			Expect(launched).To(Equal(2))
+			Expect(awscache.IsLaunchRampExceeded(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(2))
+			Expect(recorder.Calls("LaunchRampExceeded")).To(Equal(1))
+		})
+		It("should allow launches again once the period has passed", func() {
+			_, err := launch(3)
+			Expect(awscache.IsLaunchRampExceeded(err)).To(BeTrue())
+			fakeClock.Step(time.Minute)
+			launched, err := launch(3)
+			Expect(launched).To(Equal(2))
+			Expect(awscache.IsLaunchRampExceeded(err)).To(BeTrue())
+		})
+		It("should double the launch ramp after each doubling window", func() {
+			nodePool.Annotations[v1beta1.AnnotationLaunchRampDoublingWindow] = "2m"
+			ExpectApplied(ctx, env.Client, nodePool)
+			launched, _ := launch(10)
+			Expect(launched).To(Equal(2))
+			fakeClock.Step(time.Minute)
+			launched, _ = launch(10)
+			Expect(launched).To(Equal(2))
+			fakeClock.Step(time.Minute)
+			launched, _ = launch(10)
+			Expect(launched).To(Equal(4))
+			fakeClock.Step(time.Minute)
+			launched, _ = launch(10)
+			Expect(launched).To(Equal(4))
+			fakeClock.Step(time.Minute)
+			launched, _ = launch(10)
+			Expect(launched).To(Equal(8))
+		})
+		It("should restart the launch ramp once the nodepool stops launching", func() {
+			nodePool.Annotations[v1beta1.AnnotationLaunchRampDoublingWindow] = "2m"
+			ExpectApplied(ctx, env.Client, nodePool)
+			launch(2)
+			fakeClock.Step(time.Minute)
+			launch(2)
+			fakeClock.Step(3 * time.Minute)
+			launched, _ := launch(10)
+			Expect(launched).To(Equal(2))
+		})
+		It("should not count launches that failed against the launch ramp", func() {
+			awsEnv.EC2API.InsufficientCapacityPools.Set(lo.Map([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}, func(zone string, _ int) fake.CapacityPool {
+				return fake.CapacityPool{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: "m5.large", Zone: zone}
+			}))
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large"}},
+			})
+			for i := 0; i < 3; i++ {
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeTrue())
+				awsEnv.UnavailableOfferingsCache.Flush()
+			}
+			Expect(recorder.Calls("LaunchRampExceeded")).To(Equal(0))
+		})
+		It("should not count launches that failed with an error against the launch ramp", func() {
+			awsEnv.EC2API.CreateFleetBehavior.Error.Set(awserr.New("InternalError", "", nil))
+			for i := 0; i < 3; i++ {
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(err).To(HaveOccurred())
+				Expect(awscache.IsLaunchRampExceeded(err)).To(BeFalse())
+			}
+			awsEnv.EC2API.CreateFleetBehavior.Error.Set(nil)
+			launched, err := launch(3)
+			Expect(launched).To(Equal(2))
+			Expect(awscache.IsLaunchRampExceeded(err)).To(BeTrue())
+		})
+		It("should ignore an invalid launch ramp", func() {
+			nodePool.Annotations[v1beta1.AnnotationLaunchRamp] = "20 per minute"
+			ExpectApplied(ctx, env.Client, nodePool)
+			launched, err := launch(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(launched).To(Equal(3))
+		})
+	})
 	Context("EC2 Context", func() {
 		contextID := "context-1234"
 		It("should set context on the CreateFleet request if specified on the NodePool", func() {
@@ -748,6 +1162,19 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
 		})
+		It("should only drift the AMI of a NodeClaim that was launched with an overridden AMI once the override changes", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeClaimLaunchOverrides: lo.ToPtr(true)}))
+			instance.ImageId = aws.String("ami-override")
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationAMIIDOverride: "ami-override"})
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+
+			nodeClaim.Annotations[v1beta1.AnnotationAMIIDOverride] = "ami-override-2"
+			isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+		})
 		It("should return drifted if there are multiple drift reasons", func() {
 			// Instance is a reference to what we return in the GetInstances call
 			instance.ImageId = aws.String(fake.ImageID())
@@ -1098,7 +1525,7 @@ var _ = Describe("CloudProvider", func() {
 				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100),
 					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
 			}})
-			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+			controller := status.NewController(env.Client, fakeClock, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, fake.DefaultAccount, awsEnv.InstanceTypesProvider, awsEnv.VPCCNI, recorder)
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1a"}})
@@ -1115,7 +1542,7 @@ var _ = Describe("CloudProvider", func() {
 				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(11),
 					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
 			}})
-			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+			controller := status.NewController(env.Client, fakeClock, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, fake.DefaultAccount, awsEnv.InstanceTypesProvider, awsEnv.VPCCNI, recorder)
 			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{MaxPods: aws.Int32(1)}
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
@@ -1133,6 +1560,51 @@ var _ = Describe("CloudProvider", func() {
 			createFleetInput = awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
 			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-1"))
 		})
+		It("should spread launches across the subnets of a zone with the balanced subnet selection policy", func() {
+			awsEnv.SubnetCache.Flush()
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(1000),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
+			}})
+			nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyBalanced)
+			controller := status.NewController(env.Client, fakeClock, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, fake.DefaultAccount, awsEnv.InstanceTypesProvider, awsEnv.VPCCNI, recorder)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			var subnets []string
+			for i := 0; i < 4; i++ {
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1a"}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				subnets = append(subnets, fake.SubnetsFromFleetRequest(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop())...)
+			}
+			// The subnet with the most available IPs breaks the tie between subnets that have had as many launches
+			Expect(subnets).To(Equal([]string{"test-subnet-2", "test-subnet-1", "test-subnet-2", "test-subnet-1"}))
+		})
+		It("should consistently launch the nodes of a NodePool into the same subnet with the dedicated-per-nodepool subnet selection policy", func() {
+			awsEnv.SubnetCache.Flush()
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
+				{SubnetId: aws.String("test-subnet-3"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int64(100),
+					Tags: []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-3")}}},
+			}})
+			nodeClass.Spec.SubnetSelectionPolicy = lo.ToPtr(v1beta1.SubnetSelectionPolicyDedicatedPerNodePool)
+			controller := status.NewController(env.Client, fakeClock, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, fake.DefaultAccount, awsEnv.InstanceTypesProvider, awsEnv.VPCCNI, recorder)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			subnets := sets.New[string]()
+			for i := 0; i < 3; i++ {
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1a"}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				subnets.Insert(fake.SubnetsFromFleetRequest(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop())...)
+			}
+			Expect(subnets.Len()).To(Equal(1))
+		})
 		It("should update in-flight IPs when a CreateFleet error occurs", func() {
 			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{
 				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int64(10),
@@ -1153,7 +1625,7 @@ var _ = Describe("CloudProvider", func() {
 			}})
 			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "test-subnet-1"}}}
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
-			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+			controller := status.NewController(env.Client, fakeClock, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, fake.DefaultAccount, awsEnv.InstanceTypesProvider, awsEnv.VPCCNI, recorder)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 			podSubnet1 := coretest.UnschedulablePod()
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, podSubnet1)
@@ -1275,4 +1747,480 @@ var _ = Describe("CloudProvider", func() {
 			Expect(lo.Keys(cloudProviderNodeClaim.Status.Allocatable)).ToNot(ContainElement(v1beta1.ResourceEFA))
 		})
 	})
+	Context("Target Group Deregistration", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TargetGroupDeregistration: lo.ToPtr(true)}))
+		})
+		It("should not terminate the instance before the deregistration deadline", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			cloudProviderNodeClaim.Annotations = map[string]string{
+				v1beta1.AnnotationTargetGroupDeregistrationDeadline: fakeClock.Now().Add(time.Minute).Format(time.RFC3339),
+			}
+			Expect(cloudProvider.Delete(ctx, cloudProviderNodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+		})
+		It("should terminate the instance once the deregistration deadline has passed", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			cloudProviderNodeClaim.Annotations = map[string]string{
+				v1beta1.AnnotationTargetGroupDeregistrationDeadline: fakeClock.Now().Add(time.Minute).Format(time.RFC3339),
+			}
+			Expect(cloudProvider.Delete(ctx, cloudProviderNodeClaim)).ToNot(Succeed())
+			fakeClock.Step(2 * time.Minute)
+			Expect(cloudProvider.Delete(ctx, cloudProviderNodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should wait for the instance to be deregistered after deletion starts", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			cloudProviderNodeClaim.DeletionTimestamp = &metav1.Time{Time: fakeClock.Now()}
+			Expect(cloudProvider.Delete(ctx, cloudProviderNodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+		})
+		It("should stop waiting for the instance to be deregistered after the timeout", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			cloudProviderNodeClaim.DeletionTimestamp = &metav1.Time{Time: fakeClock.Now()}
+			Expect(cloudProvider.Delete(ctx, cloudProviderNodeClaim)).ToNot(Succeed())
+			fakeClock.Step(2 * time.Minute)
+			Expect(cloudProvider.Delete(ctx, cloudProviderNodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Termination Approval", func() {
+		var instanceID string
+
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			instanceID = lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+		})
+		It("should not terminate the instance until the NodeClaim is approved for termination", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TerminationApproval: lo.ToPtr(true)}))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+			Expect(recorder.Calls("AwaitingTerminationApproval")).To(Equal(1))
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKey(v1beta1.AnnotationTerminationPending))
+
+			nodeClaim.Annotations[v1beta1.AnnotationTerminationApproved] = "true"
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should not wait for approval to terminate an instance that's gone", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TerminationApproval: lo.ToPtr(true)}))
+			awsEnv.EC2API.Instances.Delete(instanceID)
+			Expect(corecloudproivder.IsNodeClaimNotFoundError(cloudProvider.Delete(ctx, nodeClaim))).To(BeTrue())
+		})
+		It("should put an event on the termination notification event bus before terminating the instance", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TerminationNotificationEventBus: lo.ToPtr("termination")}))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EventBridgeAPI.PutEventsBehavior.Calls()).To(Equal(1))
+			entry := awsEnv.EventBridgeAPI.PutEventsBehavior.CalledWithInput.Pop().Entries[0]
+			Expect(aws.StringValue(entry.EventBusName)).To(Equal("termination"))
+			Expect(aws.StringValue(entry.DetailType)).To(Equal(terminationhook.DetailType))
+			detail := terminationhook.Detail{}
+			Expect(json.Unmarshal([]byte(aws.StringValue(entry.Detail)), &detail)).To(Succeed())
+			Expect(detail.InstanceID).To(Equal(instanceID))
+			Expect(detail.NodeClaim).To(Equal(nodeClaim.Name))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should only put one event on the termination notification event bus for a NodeClaim", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				TerminationApproval:             lo.ToPtr(true),
+				TerminationNotificationEventBus: lo.ToPtr("termination"),
+			}))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EventBridgeAPI.PutEventsBehavior.Calls()).To(Equal(1))
+		})
+		It("should not terminate the instance when the event can't be put on the event bus", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TerminationNotificationEventBus: lo.ToPtr("termination")}))
+			awsEnv.EventBridgeAPI.PutEventsBehavior.Error.Set(fmt.Errorf("access denied"))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+		})
+	})
+	Context("Termination Protection", func() {
+		BeforeEach(func() {
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationTerminationProtection: v1beta1.TerminationProtectionEnabled})
+		})
+		It("should launch protected NodeClaims with termination protection", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.BoolValue(input.LaunchTemplateData.DisableApiTermination)).To(BeTrue())
+			})
+		})
+		It("should not launch unprotected NodeClaims with termination protection", func() {
+			delete(nodeClaim.Annotations, v1beta1.AnnotationTerminationProtection)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(input.LaunchTemplateData.DisableApiTermination).To(BeNil())
+			})
+		})
+		It("should refuse to terminate the instance of a protected NodeClaim", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+			Expect(recorder.Calls("TerminationProtected")).To(Equal(1))
+		})
+		It("should not block termination of a protected NodeClaim whose instance is gone", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			awsEnv.EC2API.Instances.Range(func(k, _ any) bool {
+				awsEnv.EC2API.Instances.Delete(k)
+				return true
+			})
+			Expect(corecloudproivder.IsNodeClaimNotFoundError(cloudProvider.Delete(ctx, nodeClaim))).To(BeTrue())
+		})
+		It("should remove termination protection that's still set on the instance before terminating it", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			nodeClaim.Annotations = map[string]string{v1beta1.AnnotationTerminationProtected: "true"}
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.ModifyInstanceAttributeBehavior.Calls()).To(Equal(1))
+			input := awsEnv.EC2API.ModifyInstanceAttributeBehavior.CalledWithInput.Pop()
+			Expect(aws.BoolValue(input.DisableApiTermination.Value)).To(BeFalse())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Hibernation", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{Hibernation: lo.ToPtr(true)}))
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationHibernation: v1beta1.HibernationEnabled})
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+			}
+		})
+		// launchHibernatable launches the NodeClaim and marks its instance as launched with hibernation configured
+		launchHibernatable := func() string {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			raw, ok := awsEnv.EC2API.Instances.Load(instanceID)
+			Expect(ok).To(BeTrue())
+			raw.(*ec2.Instance).HibernationOptions = &ec2.HibernationOptions{Configured: aws.Bool(true)}
+			return instanceID
+		}
+		It("should configure hibernation in the launch templates of instance types that support it", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			instanceInfo := awsutil.CopyOf(out).(*ec2.DescribeInstanceTypesOutput)
+			for _, info := range instanceInfo.InstanceTypes {
+				info.HibernationSupported = aws.Bool(lo.FromPtr(info.InstanceType) == "m5.large")
+			}
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(instanceInfo)
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err = cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			var configured, unconfigured int
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				if input.LaunchTemplateData.HibernationOptions != nil && aws.BoolValue(input.LaunchTemplateData.HibernationOptions.Configured) {
+					configured++
+				} else {
+					unconfigured++
+				}
+			})
+			Expect(configured).To(Equal(1))
+			Expect(unconfigured).To(BeNumerically(">", 0))
+		})
+		It("should not configure hibernation for instance types whose memory doesn't fit on the root volume", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			instanceInfo := awsutil.CopyOf(out).(*ec2.DescribeInstanceTypesOutput)
+			for _, info := range instanceInfo.InstanceTypes {
+				info.HibernationSupported = aws.Bool(lo.FromPtr(info.InstanceType) == "m5.large")
+			}
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(instanceInfo)
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			// m5.large has 8Gi of memory, which doesn't fit on a 10Gi root volume alongside the OS
+			nodeClass.Spec.BlockDeviceMappings = []*v1beta1.BlockDeviceMapping{{
+				DeviceName: aws.String("/dev/xvda"),
+				EBS:        &v1beta1.BlockDevice{Encrypted: aws.Bool(true), VolumeSize: lo.ToPtr(resource.MustParse("10Gi"))},
+			}}
+
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err = cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(input.LaunchTemplateData.HibernationOptions).To(BeNil())
+			})
+		})
+		It("should not configure hibernation in launch templates when the NodeClaim doesn't enable it", func() {
+			delete(nodeClaim.Annotations, v1beta1.AnnotationHibernation)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(input.LaunchTemplateData.HibernationOptions).To(BeNil())
+			})
+		})
+		It("should hibernate the instance rather than terminating it", func() {
+			instanceID := launchHibernatable()
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.StopInstancesBehavior.Calls()).To(Equal(1))
+			input := awsEnv.EC2API.StopInstancesBehavior.CalledWithInput.Pop()
+			Expect(aws.BoolValue(input.Hibernate)).To(BeTrue())
+			Expect(aws.StringValueSlice(input.InstanceIds)).To(ConsistOf(instanceID))
+			// The hibernated instance is no longer linked to the NodeClaim, so its termination completes
+			_, err := cloudProvider.Get(ctx, nodeClaim.Status.ProviderID)
+			Expect(corecloudproivder.IsNodeClaimNotFoundError(err)).To(BeTrue())
+		})
+		It("should terminate the instance of a drifted NodeClaim", func() {
+			launchHibernatable()
+			nodeClaim.StatusConditions().SetTrue(corev1beta1.ConditionTypeDrifted)
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.StopInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should terminate instances that weren't launched with hibernation configured", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.StopInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should terminate the instance when hibernation is disabled", func() {
+			launchHibernatable()
+			ctx = options.ToContext(ctx, test.Options())
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.StopInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should terminate the instance when it can't be hibernated", func() {
+			launchHibernatable()
+			awsEnv.EC2API.StopInstancesBehavior.Error.Set(fmt.Errorf("UnsupportedHibernationConfiguration"))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Console Output", func() {
+		var providerID string
+
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			providerID = cloudProviderNodeClaim.Status.ProviderID
+			nodeClaim.Status.ProviderID = providerID
+			awsEnv.EC2API.GetConsoleOutputBehavior.Output.Set(&ec2.GetConsoleOutputOutput{
+				Output: aws.String(base64.StdEncoding.EncodeToString([]byte("Cloud-init v. 23.2.2 running 'modules:final'\nnodeadm: failed to join cluster\n"))),
+			})
+		})
+		It("should publish the console output of instances whose node never registered", func() {
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.Calls()).To(Equal(1))
+			Expect(recorder.Calls("ConsoleOutput")).To(Equal(1))
+			Expect(recorder.DetectedEvent("Instance terminated before its node registered, end of console output:\nCloud-init v. 23.2.2 running 'modules:final'\nnodeadm: failed to join cluster")).To(BeTrue())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+		It("should only include the end of long console output in the event", func() {
+			awsEnv.EC2API.GetConsoleOutputBehavior.Output.Set(&ec2.GetConsoleOutputOutput{
+				Output: aws.String(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("booting\n", 1000) + "nodeadm: failed to join cluster"))),
+			})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			event, ok := lo.Find(recorder.Events(), func(e events.Event) bool { return e.Reason == "ConsoleOutput" })
+			Expect(ok).To(BeTrue())
+			Expect(len(event.Message)).To(BeNumerically("<", cloudprovider.ConsoleOutputEventLength+100))
+			Expect(event.Message).To(HaveSuffix("booting\nnodeadm: failed to join cluster"))
+		})
+		It("should not get the console output of instances whose node registered", func() {
+			nodeClaim.StatusConditions().SetTrue(corev1beta1.ConditionTypeRegistered)
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.Calls()).To(Equal(0))
+			Expect(recorder.Calls("ConsoleOutput")).To(Equal(0))
+		})
+		It("should not get the console output of instances without a NodeClaim", func() {
+			Expect(cloudProvider.Delete(ctx, &corev1beta1.NodeClaim{Status: corev1beta1.NodeClaimStatus{ProviderID: providerID}})).To(Succeed())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.Calls()).To(Equal(0))
+		})
+		It("should only get the console output once when termination is retried", func() {
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			// The instance is already terminating, so the retry may or may not succeed
+			_ = cloudProvider.Delete(ctx, nodeClaim)
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.Calls()).To(Equal(1))
+		})
+		It("should terminate the instance when the console output can't be retrieved", func() {
+			awsEnv.EC2API.GetConsoleOutputBehavior.Error.Set(fmt.Errorf("failed"))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(recorder.Calls("ConsoleOutput")).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Launch Overrides", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{{
+				Name:         aws.String("override"),
+				ImageId:      aws.String("ami-override"),
+				Architecture: aws.String("x86_64"),
+				CreationDate: aws.String("2022-08-15T12:00:00Z"),
+			}}})
+			nodeClaim.Annotations = map[string]string{
+				v1beta1.AnnotationAMIIDOverride:           "ami-override",
+				v1beta1.AnnotationInstanceProfileOverride: "override-profile",
+			}
+		})
+		It("should launch with the AMI and instance profile that the NodeClaim is annotated with", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeClaimLaunchOverrides: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			created, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(input.LaunchTemplateData.ImageId)).To(Equal("ami-override"))
+				Expect(aws.StringValue(input.LaunchTemplateData.IamInstanceProfile.Name)).To(Equal("override-profile"))
+			})
+			// The overrides don't drift the NodeClaim from its EC2NodeClass
+			Expect(created.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationEC2NodeClassHash, nodeClass.Hash()))
+		})
+		It("should fail the launch when the overridden AMI doesn't exist", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeClaimLaunchOverrides: lo.ToPtr(true)}))
+			nodeClaim.Annotations[v1beta1.AnnotationAMIIDOverride] = "ami-missing"
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ami ami-missing doesn't exist"))
+		})
+		It("should ignore the overrides and publish an event when they aren't enabled", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(input.LaunchTemplateData.ImageId)).ToNot(Equal("ami-override"))
+				Expect(aws.StringValue(input.LaunchTemplateData.IamInstanceProfile.Name)).To(Equal("test-profile"))
+			})
+			Expect(recorder.Calls("LaunchOverridesIgnored")).To(Equal(1))
+		})
+	})
+	Context("Metadata Labels", func() {
+		It("should label the NodeClaim with the rendered metadata of the instance", func() {
+			nodeClass.Spec.MetadataLabels = map[string]string{
+				"example.com/instance-id": "{{ .InstanceID }}",
+				"example.com/zone-id":     "{{ .ZoneID }}",
+				"example.com/host":        "{{ .InstanceType }}-{{ .CapacityType }}",
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			created, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			id, err := utils.ParseInstanceID(created.Status.ProviderID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created.Labels).To(HaveKeyWithValue("example.com/instance-id", id))
+			Expect(created.Labels).To(HaveKeyWithValue("example.com/zone-id", created.Labels[v1beta1.LabelTopologyZoneID]))
+			Expect(created.Labels).To(HaveKeyWithValue("example.com/host", fmt.Sprintf("%s-%s", created.Labels[v1.LabelInstanceTypeStable], corev1beta1.CapacityTypeOnDemand)))
+		})
+		It("should skip labels that don't render to a valid label value", func() {
+			nodeClass.Spec.MetadataLabels = map[string]string{
+				"example.com/unknown": "{{ .Unknown }}",
+				"example.com/invalid": "{{ .InstanceID }}/{{ .Zone }}",
+				"example.com/valid":   "{{ .Zone }}",
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			created, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created.Labels).ToNot(HaveKey("example.com/unknown"))
+			Expect(created.Labels).ToNot(HaveKey("example.com/invalid"))
+			Expect(created.Labels).To(HaveKeyWithValue("example.com/valid", created.Labels[v1.LabelTopologyZone]))
+		})
+		It("should not override well known labels", func() {
+			nodeClass.Spec.MetadataLabels = map[string]string{v1.LabelTopologyZone: "{{ .InstanceID }}"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			created, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created.Labels[v1.LabelTopologyZone]).To(HavePrefix("test-zone-"))
+		})
+	})
+	Context("Read-Only", func() {
+		readOnlyDecisions := func(decision string) float64 {
+			metric, ok := FindMetricWithLabelValues("karpenter_read_only_decisions_total", map[string]string{
+				"decision": decision,
+				"nodepool": nodePool.Name,
+			})
+			if !ok {
+				return 0
+			}
+			return metric.GetCounter().GetValue()
+		}
+		It("should report the launch rather than launching an instance", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReadOnly: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudproivder.IsInsufficientCapacityError(err)).To(BeFalse())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
+			Expect(recorder.Calls("ReadOnlyLaunch")).To(Equal(1))
+			Expect(readOnlyDecisions(readonly.DecisionLaunch)).To(BeNumerically("==", 1))
+		})
+		It("should only count the launch of a NodeClaim once when it's retried", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReadOnly: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			_, err = cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(readOnlyDecisions(readonly.DecisionLaunch)).To(BeNumerically("==", 1))
+		})
+		It("should report the termination rather than terminating the instance", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			nodeClaim.Status.ProviderID = cloudProviderNodeClaim.Status.ProviderID
+
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReadOnly: lo.ToPtr(true)}))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.Calls()).To(Equal(0))
+			Expect(recorder.Calls("ReadOnlyTermination")).To(BeNumerically(">", 0))
+			Expect(readOnlyDecisions(readonly.DecisionTerminate)).To(BeNumerically("==", 1))
+		})
+	})
+	Context("Metadata Options", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.MetadataOptions = &v1beta1.MetadataOptions{HTTPTokens: lo.ToPtr("optional")}
+		})
+		It("should publish an event when the enforce policy overrides the metadata options of the EC2NodeClass", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				MetadataHTTPTokens:    lo.ToPtr("required"),
+				MetadataOptionsPolicy: lo.ToPtr(options.MetadataOptionsPolicyEnforce),
+			}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Calls("MetadataOptionsOverridden")).To(Equal(1))
+			Expect(recorder.DetectedEvent("Metadata options httpTokens are overridden by the enforced metadata settings")).To(BeTrue())
+		})
+		It("should not publish an event with the default policy", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MetadataHTTPTokens: lo.ToPtr("required")}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Calls("MetadataOptionsOverridden")).To(Equal(0))
+		})
+	})
 })
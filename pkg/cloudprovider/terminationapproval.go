@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// waitForTerminationApproval gives external tooling a chance to checkpoint the instance of a drained NodeClaim before
+// it's terminated. The termination notification is published, and the NodeClaim annotated as pending termination,
+// once. When termination approval is enabled, an error is returned, so that termination is retried, until the
+// NodeClaim is approved for termination. Instances that are already gone don't hold up termination.
+func (c *CloudProvider) waitForTerminationApproval(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string) error {
+	if !options.FromContext(ctx).TerminationApproval && options.FromContext(ctx).TerminationNotificationEventBus == "" {
+		return nil
+	}
+	if _, ok := nodeClaim.Annotations[v1beta1.AnnotationTerminationPending]; !ok {
+		if err := c.terminationHookProvider.Notify(ctx, nodeClaim, id); err != nil {
+			return fmt.Errorf("publishing termination notification, %w", err)
+		}
+		stored := nodeClaim.DeepCopy()
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+			v1beta1.AnnotationTerminationPending: time.Now().Format(time.RFC3339),
+		})
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("annotating nodeclaim, %w", err)
+		}
+	}
+	if !options.FromContext(ctx).TerminationApproval || nodeClaim.Annotations[v1beta1.AnnotationTerminationApproved] == "true" {
+		return nil
+	}
+	if _, err := c.instanceProvider.Get(ctx, id); err != nil {
+		return err
+	}
+	c.recorder.Publish(cloudproviderevents.NodeClaimAwaitingTerminationApproval(nodeClaim))
+	return fmt.Errorf("waiting for termination approval, annotate the nodeclaim with %s=true to terminate the instance", v1beta1.AnnotationTerminationApproved)
+}
@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+)
+
+// checkTerminationProtection returns an error, so that termination is retried, while the NodeClaim is protected from
+// termination. Instances that are already gone, e.g. because they were interrupted, don't hold up termination. If
+// protection was removed from the NodeClaim but not yet from its instance, it's removed here so that the instance
+// can be terminated.
+func (c *CloudProvider) checkTerminationProtection(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string) error {
+	if nodeClaim.Annotations[v1beta1.AnnotationTerminationProtection] != v1beta1.TerminationProtectionEnabled {
+		if _, ok := nodeClaim.Annotations[v1beta1.AnnotationTerminationProtected]; ok {
+			if err := c.instanceProvider.SetTerminationProtection(ctx, id, false); err != nil && !cloudprovider.IsNodeClaimNotFoundError(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := c.instanceProvider.Get(ctx, id); err != nil {
+		return err
+	}
+	c.recorder.Publish(cloudproviderevents.NodeClaimTerminationProtected(nodeClaim))
+	return fmt.Errorf("instance has termination protection, remove the %s annotation from the nodeclaim to terminate it", v1beta1.AnnotationTerminationProtection)
+}
@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// zoneTypeUnavailableError explains why none of the instance types of a NodeClaim that requires a zone type could be
+// launched, or returns nil if the NodeClaim doesn't require a zone type. Local Zones and Wavelength Zones have to be
+// opted in to, and only offer a few instance types, so the error tells apart an EC2NodeClass without subnets in the
+// zone type from instance types that aren't offered there.
+func zoneTypeUnavailableError(nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass) error {
+	requirement := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1beta1.LabelTopologyZoneType)
+	if requirement.Operator() != v1.NodeSelectorOpIn {
+		return nil
+	}
+	required := requirement.Values()
+	sort.Strings(required)
+	subnetZoneTypes := sets.New(lo.FilterMap(nodeClass.Status.Subnets, func(s v1beta1.Subnet, _ int) (string, bool) {
+		return s.ZoneType, s.ZoneType != ""
+	})...)
+	if !lo.SomeBy(required, subnetZoneTypes.Has) {
+		return fmt.Errorf("no subnets of the EC2NodeClass are in a zone of type %s, the zone may not be opted in to (subnet zone types: %s)",
+			strings.Join(required, ", "), strings.Join(sets.List(subnetZoneTypes), ", "))
+	}
+	return fmt.Errorf("none of the requested instance types are offered in the zones of type %s of the subnets of the EC2NodeClass", strings.Join(required, ", "))
+}
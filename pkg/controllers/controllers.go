@@ -20,54 +20,166 @@ import (
 	"github.com/awslabs/operatorpkg/controller"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 
+	nodeallocatable "github.com/aws/karpenter-provider-aws/pkg/controllers/node/allocatable"
+	nodedisruption "github.com/aws/karpenter-provider-aws/pkg/controllers/node/disruption"
+	nodeminage "github.com/aws/karpenter-provider-aws/pkg/controllers/node/minage"
+	nodewarmpool "github.com/aws/karpenter-provider-aws/pkg/controllers/node/warmpool"
+	nodeclassamideprecation "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/amideprecation"
+	nodeclassamirelease "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/amirelease"
 	nodeclasshash "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/hash"
 	nodeclassstatus "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
 	nodeclasstermination "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/termination"
+	nodepoolhibernation "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/hibernation"
+	nodepoolinterruptioncoverage "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/interruptioncoverage"
+	nodepoolprewarm "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/prewarm"
+	nodepoolwarmpool "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/warmpool"
+	nodepoolzonesuitability "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/zonesuitability"
+	podrestartcost "github.com/aws/karpenter-provider-aws/pkg/controllers/pod/restartcost"
+	controllersblocklist "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/blocklist"
 	controllersinstancetype "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype"
+	controllerslaunchtemplate "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/launchtemplate"
+	controllerslaunchtemplategarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/launchtemplate/garbagecollection"
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing"
+	controllersquota "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/quota"
+	controllersunavailableofferings "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/unavailableofferings"
+	controllersvpccni "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/vpccni"
+	controllerszonehealth "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/zonehealth"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	servicekms "github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
 	servicesqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	gocache "github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	coredisruption "sigs.k8s.io/karpenter/pkg/controllers/disruption"
+	"sigs.k8s.io/karpenter/pkg/controllers/disruption/orchestration"
 	"sigs.k8s.io/karpenter/pkg/events"
 
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
+	nodeclaimdebug "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/debug"
 	nodeclaimgarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/garbagecollection"
+	nodeclaimlaunchlatency "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/launchlatency"
+	nodeclaimrepair "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/repair"
 	nodeclaimtagging "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/tagging"
+	nodeclaimtargetgroup "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/targetgroup"
+	nodeclaimterminationprotection "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/terminationprotection"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/targetgroup"
 )
 
-func NewControllers(ctx context.Context, sess *session.Session, clk clock.Clock, kubeClient client.Client, recorder events.Recorder,
-	unavailableOfferings *cache.UnavailableOfferings, cloudProvider cloudprovider.CloudProvider, subnetProvider subnet.Provider,
+func NewControllers(ctx context.Context, sess *session.Session, accountID string, clk clock.Clock, kubeClient client.Client, kubeReader client.Reader, recorder events.Recorder,
+	unavailableOfferings *cache.UnavailableOfferings, blockedOfferings *cache.BlockedOfferings, zoneHealth *cache.ZoneHealth, zoneScores *cache.ZoneScores, vpcCNI *cache.VPCCNI, vcpuQuotaHeadroom *cache.VCPUQuotaHeadroom, cloudProvider cloudprovider.CloudProvider, subnetProvider subnet.Provider,
 	securityGroupProvider securitygroup.Provider, instanceProfileProvider instanceprofile.Provider, instanceProvider instance.Provider,
 	pricingProvider pricing.Provider, amiProvider amifamily.Provider, launchTemplateProvider launchtemplate.Provider, instanceTypeProvider instancetype.Provider) []controller.Controller {
 
 	controllers := []controller.Controller{
 		nodeclasshash.NewController(kubeClient),
-		nodeclassstatus.NewController(kubeClient, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider),
+		nodeclassstatus.NewController(kubeClient, clk, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider,
+			kms.NewDefaultProvider(servicekms.New(sess), lo.FromPtr(sess.Config.Region), accountID, gocache.New(cache.DefaultTTL, cache.DefaultCleanupInterval)), accountID,
+			instanceTypeProvider, vpcCNI, recorder),
 		nodeclasstermination.NewController(kubeClient, recorder, instanceProfileProvider, launchTemplateProvider),
-		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider),
+		nodeclassamirelease.NewController(recorder, amiProvider),
+		nodeclassamideprecation.NewController(clk, recorder, amiProvider),
+		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider, instanceProvider),
 		nodeclaimtagging.NewController(kubeClient, instanceProvider),
+		nodeclaimterminationprotection.NewController(kubeClient, instanceProvider),
+		nodeclaimlaunchlatency.NewController(clk, instanceProvider),
 		controllerspricing.NewController(pricingProvider),
 		controllersinstancetype.NewController(instanceTypeProvider),
+		controllerslaunchtemplate.NewController(launchTemplateProvider),
+		controllersblocklist.NewController(kubeReader, clk, blockedOfferings),
+		controllersunavailableofferings.NewController(kubeClient, kubeReader, unavailableOfferings),
+		nodedisruption.NewController(kubeClient, clk),
+		nodeminage.NewController(kubeClient, clk),
+		nodepoolzonesuitability.NewController(kubeClient, ec2.New(sess), lo.FromPtr(sess.Config.Region), instanceTypeProvider, subnetProvider, zoneScores),
 	}
+	if options.FromContext(ctx).AllocatableEstimation {
+		controllers = append(controllers, nodeallocatable.NewController(kubeClient, kubeReader, cloudProvider))
+	}
+	if options.FromContext(ctx).PodRestartCost {
+		controllers = append(controllers, podrestartcost.NewController(kubeClient))
+	}
+	if options.FromContext(ctx).Prewarm {
+		controllers = append(controllers, nodepoolprewarm.NewController(kubeClient, clk))
+	}
+	if options.FromContext(ctx).ZoneFailover {
+		controllers = append(controllers, controllerszonehealth.NewController(ec2.New(sess), zoneHealth))
+	}
+	if options.FromContext(ctx).VPCCNIWarmTargets {
+		controllers = append(controllers, controllersvpccni.NewController(kubeClient, vpcCNI))
+	}
+	if options.FromContext(ctx).TargetGroupDeregistration {
+		targetGroupProvider := targetgroup.NewDefaultProvider(elbv2.New(sess), gocache.New(cache.DefaultTTL, cache.DefaultCleanupInterval))
+		controllers = append(controllers, nodeclaimtargetgroup.NewController(kubeClient, clk, targetGroupProvider))
+	}
+	if options.FromContext(ctx).VCPUQuotaReporting {
+		quotaProvider := quota.NewDefaultProvider(servicequotas.New(sess), ec2.New(sess), gocache.New(cache.DefaultTTL, cache.DefaultCleanupInterval))
+		controllers = append(controllers, controllersquota.NewController(kubeClient, recorder, quotaProvider, vcpuQuotaHeadroom))
+	}
+	if options.FromContext(ctx).NodeRepair {
+		controllers = append(controllers, nodeclaimrepair.NewController(kubeClient, clk, recorder, ec2.New(sess)))
+	}
+	if options.FromContext(ctx).LaunchTemplateGarbageCollectionAge > 0 {
+		controllers = append(controllers, controllerslaunchtemplategarbagecollection.NewController(launchTemplateProvider))
+	}
+	if options.FromContext(ctx).WarmPools {
+		controllers = append(controllers, nodepoolwarmpool.NewController(kubeClient, clk, instanceTypeProvider, instanceProvider), nodewarmpool.NewController(kubeClient))
+	}
+	if options.FromContext(ctx).Hibernation {
+		controllers = append(controllers, nodepoolhibernation.NewController(kubeClient, clk, instanceProvider))
+	}
+	if options.FromContext(ctx).BreakGlassDebug {
+		controllers = append(controllers, nodeclaimdebug.NewController(kubeClient, clk, recorder, ec2.New(sess), ssm.New(sess), instanceProvider))
+	}
+	var sqsProvider sqs.Provider
 	if options.FromContext(ctx).InterruptionQueue != "" {
 		sqsapi := servicesqs.New(sess)
 		out := lo.Must(sqsapi.GetQueueUrlWithContext(ctx, &servicesqs.GetQueueUrlInput{QueueName: lo.ToPtr(options.FromContext(ctx).InterruptionQueue)}))
-		controllers = append(controllers, interruption.NewController(kubeClient, clk, recorder, lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl))), unavailableOfferings))
+		sqsProvider = lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl)))
+		var deadLetterQueue sqs.Provider
+		if options.FromContext(ctx).InterruptionDeadLetterQueue != "" {
+			dlq := lo.Must(sqsapi.GetQueueUrlWithContext(ctx, &servicesqs.GetQueueUrlInput{QueueName: lo.ToPtr(options.FromContext(ctx).InterruptionDeadLetterQueue)}))
+			deadLetterQueue = lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(dlq.QueueUrl)))
+		}
+		// Messages can't be deleted in read-only mode, so they're left for the autoscaler that manages the cluster
+		if !options.FromContext(ctx).ReadOnly {
+			controllers = append(controllers, interruption.NewController(kubeClient, clk, recorder, sqsProvider, deadLetterQueue, instanceProvider, unavailableOfferings))
+		}
 	}
+	controllers = append(controllers, nodepoolinterruptioncoverage.NewController(kubeClient, recorder, sqsProvider, eventbridge.New(sess)))
 	return controllers
 }
+
+// NewCoreControllers returns the controllers of core Karpenter. In read-only mode the disruption controllers aren't
+// registered, since they taint and drain the nodes that they disrupt before their instances would be terminated.
+func NewCoreControllers(ctx context.Context, controllers []controller.Controller) []controller.Controller {
+	if !options.FromContext(ctx).ReadOnly {
+		return controllers
+	}
+	return lo.Reject(controllers, func(c controller.Controller, _ int) bool {
+		switch c.(type) {
+		case *coredisruption.Controller, *orchestration.Queue:
+			return true
+		}
+		return false
+	})
+}
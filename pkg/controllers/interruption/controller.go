@@ -17,8 +17,12 @@ package interruption
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	sqsapi "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/awslabs/operatorpkg/singleton"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,13 +41,17 @@ import (
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
 	interruptionevents "github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/events"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/statechange"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -64,22 +72,32 @@ type Controller struct {
 	clk                       clock.Clock
 	recorder                  events.Recorder
 	sqsProvider               sqs.Provider
+	deadLetterQueue           sqs.Provider
+	instanceProvider          instance.Provider
 	unavailableOfferingsCache *cache.UnavailableOfferings
 	parser                    *EventParser
 	cm                        *pretty.ChangeMonitor
+
+	mu            sync.Mutex
+	pendingDrains map[string]pendingDrain
 }
 
+// NewController constructs the interruption controller. The dead-letter queue is optional; if it's nil, quarantined
+// messages are only logged before they're deleted.
 func NewController(kubeClient client.Client, clk clock.Clock, recorder events.Recorder,
-	sqsProvider sqs.Provider, unavailableOfferingsCache *cache.UnavailableOfferings) *Controller {
+	sqsProvider sqs.Provider, deadLetterQueue sqs.Provider, instanceProvider instance.Provider, unavailableOfferingsCache *cache.UnavailableOfferings) *Controller {
 
 	return &Controller{
 		kubeClient:                kubeClient,
 		clk:                       clk,
 		recorder:                  recorder,
 		sqsProvider:               sqsProvider,
+		deadLetterQueue:           deadLetterQueue,
+		instanceProvider:          instanceProvider,
 		unavailableOfferingsCache: unavailableOfferingsCache,
 		parser:                    NewEventParser(DefaultParsers...),
 		cm:                        pretty.NewChangeMonitor(),
+		pendingDrains:             map[string]pendingDrain{},
 	}
 }
 
@@ -94,6 +112,9 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 		return reconcile.Result{}, fmt.Errorf("getting messages from queue, %w", err)
 	}
 	if len(sqsMessages) == 0 {
+		if err = c.drainPending(ctx); err != nil {
+			return reconcile.Result{}, fmt.Errorf("draining interrupted nodes, %w", err)
+		}
 		return reconcile.Result{RequeueAfter: singleton.RequeueImmediately}, nil
 	}
 	nodeClaimInstanceIDMap, err := c.makeNodeClaimInstanceIDMap(ctx)
@@ -108,13 +129,24 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	workqueue.ParallelizeUntil(ctx, 10, len(sqsMessages), func(i int) {
 		msg, e := c.parseMessage(sqsMessages[i])
 		if e != nil {
-			// If we fail to parse, then we should delete the message but still log the error
-			log.FromContext(ctx).Error(err, "failed parsing interruption message")
-			errs[i] = c.deleteMessage(ctx, sqsMessages[i])
+			// Messages that fail to parse will never parse, so they're quarantined rather than retried
+			errs[i] = c.quarantineMessage(ctx, sqsMessages[i], quarantineReasonParse, e)
 			return
 		}
+		if options.FromContext(ctx).InterruptionQueueShared {
+			foreign, e := c.isForeignMessage(ctx, nodeClaimInstanceIDMap, msg)
+			if e != nil {
+				errs[i] = c.failMessage(ctx, sqsMessages[i], fmt.Errorf("resolving message ownership, %w", e))
+				return
+			}
+			if foreign {
+				skippedMessages.WithLabelValues(string(msg.Kind())).Inc()
+				errs[i] = c.releaseMessage(ctx, sqsMessages[i])
+				return
+			}
+		}
 		if e = c.handleMessage(ctx, nodeClaimInstanceIDMap, nodeInstanceIDMap, msg); e != nil {
-			errs[i] = fmt.Errorf("handling message, %w", e)
+			errs[i] = c.failMessage(ctx, sqsMessages[i], fmt.Errorf("handling message, %w", e))
 			return
 		}
 		errs[i] = c.deleteMessage(ctx, sqsMessages[i])
@@ -122,6 +154,9 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err = multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, err
 	}
+	if err = c.drainPending(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("draining interrupted nodes, %w", err)
+	}
 	return reconcile.Result{RequeueAfter: singleton.RequeueImmediately}, nil
 }
 
@@ -129,7 +164,7 @@ func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("interruption").
 		WatchesRawSource(singleton.Source()).
-		Complete(singleton.AsReconciler(c))
+		Complete(tracing.Reconciler("interruption", singleton.AsReconciler(c)))
 }
 
 // parseMessage parses the passed SQS message into an internal Message interface
@@ -181,6 +216,83 @@ func (c *Controller) deleteMessage(ctx context.Context, msg *sqsapi.Message) err
 	return nil
 }
 
+// failMessage returns the error of a message that failed to be handled, so that the message is received again once its
+// visibility timeout expires, unless it has been received interruption-queue-max-receives times, in which case it's
+// quarantined instead
+func (c *Controller) failMessage(ctx context.Context, msg *sqsapi.Message, err error) error {
+	if maxReceives := options.FromContext(ctx).InterruptionQueueMaxReceives; maxReceives > 0 && receiveCount(msg) >= maxReceives {
+		return c.quarantineMessage(ctx, msg, quarantineReasonHandle, err)
+	}
+	return err
+}
+
+// quarantineMessage removes a message that can't be parsed, or that failed to be handled every time it was received,
+// from the queue so that it isn't retried forever. The message is logged with its payload, and forwarded to the
+// dead-letter queue first if there is one.
+func (c *Controller) quarantineMessage(ctx context.Context, msg *sqsapi.Message, reason string, cause error) error {
+	log.FromContext(ctx).WithValues(
+		"message-id", aws.StringValue(msg.MessageId),
+		"receive-count", receiveCount(msg),
+		"reason", reason,
+		"body", aws.StringValue(msg.Body),
+	).Error(cause, "quarantining interruption message")
+	if c.deadLetterQueue != nil {
+		if err := c.deadLetterQueue.SendSQSMessage(ctx, msg); err != nil {
+			return fmt.Errorf("forwarding message to dead-letter queue, %w", err)
+		}
+	}
+	if err := c.deleteMessage(ctx, msg); err != nil {
+		return err
+	}
+	quarantinedMessages.WithLabelValues(reason).Inc()
+	return nil
+}
+
+// receiveCount returns the number of times the message was received from the queue, including this time
+func receiveCount(msg *sqsapi.Message) int {
+	count, err := strconv.Atoi(aws.StringValue(msg.Attributes[sqsapi.MessageSystemAttributeNameApproximateReceiveCount]))
+	if err != nil {
+		return 1
+	}
+	return count
+}
+
+// releaseMessage returns the passed SQS message to the queue so that the cluster that owns it can handle it
+func (c *Controller) releaseMessage(ctx context.Context, msg *sqsapi.Message) error {
+	if err := c.sqsProvider.ReleaseSQSMessage(ctx, msg); err != nil {
+		return fmt.Errorf("releasing sqs message, %w", err)
+	}
+	return nil
+}
+
+// isForeignMessage returns true if every instance in the message is tagged as owned by a different cluster. Messages
+// for instances that this cluster owns, that no longer exist, or that aren't tagged with any cluster are handled as usual.
+func (c *Controller) isForeignMessage(ctx context.Context, nodeClaimInstanceIDMap map[string]*v1beta1.NodeClaim, msg messages.Message) (bool, error) {
+	if msg.Kind() == messages.NoOpKind || len(msg.EC2InstanceIDs()) == 0 {
+		return false, nil
+	}
+	clusterTagKey := fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)
+	for _, instanceID := range msg.EC2InstanceIDs() {
+		if _, ok := nodeClaimInstanceIDMap[instanceID]; ok {
+			return false, nil
+		}
+		inst, err := c.instanceProvider.Get(ctx, instanceID)
+		if err != nil {
+			if cloudprovider.IsNodeClaimNotFoundError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if _, ok := inst.Tags[clusterTagKey]; ok {
+			return false, nil
+		}
+		if _, ok := lo.FindKeyBy(inst.Tags, func(k, _ string) bool { return strings.HasPrefix(k, "kubernetes.io/cluster/") }); !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // handleNodeClaim retrieves the action for the message and then performs the appropriate action against the node
 func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message, nodeClaim *v1beta1.NodeClaim, node *v1.Node) error {
 	action := actionForMessage(msg)
@@ -207,6 +319,10 @@ func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message,
 		}
 	}
 	if action != NoAction {
+		if msg.Kind() == messages.SpotInterruptionKind && options.FromContext(ctx).MaxConcurrentInterruptionDrains > 0 {
+			c.queueDrain(nodeClaim, node, msg.StartTime().Add(spotInterruptionNotice))
+			return nil
+		}
 		return c.deleteNodeClaim(ctx, nodeClaim, node)
 	}
 	return nil
@@ -0,0 +1,139 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruption
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// spotInterruptionNotice is how long EC2 waits after a spot interruption warning before it reclaims the instance
+const spotInterruptionNotice = 2 * time.Minute
+
+// pendingDrain is a NodeClaim with a spot interruption warning that is waiting for a drain slot
+type pendingDrain struct {
+	nodeClaim *v1beta1.NodeClaim
+	node      *v1.Node
+	deadline  time.Time
+}
+
+// queueDrain defers the deletion of a spot interrupted NodeClaim until drainPending finds a free slot for it, so that
+// a zone-wide reclamation replaces capacity incrementally rather than draining every node at once. Pending drains are
+// only kept in memory; if Karpenter restarts, the NodeClaims are deleted when EC2 reports the instances as terminated.
+func (c *Controller) queueDrain(nodeClaim *v1beta1.NodeClaim, node *v1.Node, deadline time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pendingDrains[nodeClaim.Name]; ok {
+		return
+	}
+	c.pendingDrains[nodeClaim.Name] = pendingDrain{nodeClaim: nodeClaim, node: node, deadline: deadline}
+}
+
+// drainPending deletes pending NodeClaims in order of their interruption deadline while fewer than
+// max-concurrent-interruption-drains NodeClaims are deleting, and while their NodePool's disruption budgets allow it.
+// NodeClaims that are past their deadline are deleted regardless, since their capacity is already gone.
+func (c *Controller) drainPending(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pendingDrains) == 0 {
+		return nil
+	}
+	nodeClaimList := &v1beta1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	nodeClaims := lo.SliceToMap(nodeClaimList.Items, func(nc v1beta1.NodeClaim) (string, v1beta1.NodeClaim) { return nc.Name, nc })
+	for name := range c.pendingDrains {
+		if nc, ok := nodeClaims[name]; !ok || !nc.DeletionTimestamp.IsZero() {
+			delete(c.pendingDrains, name)
+		}
+	}
+	deleting := lo.Filter(nodeClaimList.Items, func(nc v1beta1.NodeClaim, _ int) bool { return !nc.DeletionTimestamp.IsZero() })
+	drainingByNodePool := lo.CountValuesBy(deleting, func(nc v1beta1.NodeClaim) string { return nc.Labels[v1beta1.NodePoolLabelKey] })
+	draining := len(deleting)
+	allowed, err := c.allowedDrains(ctx, lo.CountValuesBy(nodeClaimList.Items, func(nc v1beta1.NodeClaim) string { return nc.Labels[v1beta1.NodePoolLabelKey] }))
+	if err != nil {
+		return err
+	}
+
+	pending := lo.Values(c.pendingDrains)
+	sort.Slice(pending, func(i, j int) bool { return pending[i].deadline.Before(pending[j].deadline) })
+	var errs error
+	for _, p := range pending {
+		nodePool := p.nodeClaim.Labels[v1beta1.NodePoolLabelKey]
+		nodePoolLimit, ok := allowed[nodePool]
+		if !ok {
+			nodePoolLimit = math.MaxInt32
+		}
+		if c.clk.Now().Before(p.deadline) && (draining >= options.FromContext(ctx).MaxConcurrentInterruptionDrains || drainingByNodePool[nodePool] >= nodePoolLimit) {
+			continue
+		}
+		nodeClaim := nodeClaims[p.nodeClaim.Name]
+		drainCtx := log.IntoContext(ctx, log.FromContext(ctx).WithValues("NodeClaim", klog.KRef("", nodeClaim.Name), "action", string(CordonAndDrain)))
+		if err := c.deleteNodeClaim(drainCtx, &nodeClaim, p.node); err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		delete(c.pendingDrains, p.nodeClaim.Name)
+		draining++
+		drainingByNodePool[nodePool]++
+	}
+	pendingDrains.Reset()
+	for nodePool, count := range lo.CountValuesBy(lo.Values(c.pendingDrains), func(p pendingDrain) string { return p.nodeClaim.Labels[v1beta1.NodePoolLabelKey] }) {
+		pendingDrains.WithLabelValues(nodePool).Set(float64(count))
+	}
+	return errs
+}
+
+// allowedDrains returns the number of NodeClaims of each NodePool that may be deleting at once. NodePools that no
+// longer exist are absent, and aren't limited. Interruption isn't a disruption reason that budgets can target, so only
+// the budgets that apply to every reason are considered.
+func (c *Controller) allowedDrains(ctx context.Context, nodesByNodePool map[string]int) (map[string]int, error) {
+	nodePoolList := &v1beta1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return nil, fmt.Errorf("listing nodepools, %w", err)
+	}
+	allowed := map[string]int{}
+	for _, nodePool := range nodePoolList.Items {
+		allowed[nodePool.Name] = math.MaxInt32
+		for _, budget := range nodePool.Spec.Disruption.Budgets {
+			if budget.Reasons != nil {
+				continue
+			}
+			// Misconfigured budgets fail closed, the same as they do for disruption
+			val, err := budget.GetAllowedDisruptions(c.clk, nodesByNodePool[nodePool.Name])
+			if err != nil {
+				val = 0
+			}
+			allowed[nodePool.Name] = lo.Min([]int{allowed[nodePool.Name], val})
+		}
+	}
+	return allowed, nil
+}
@@ -114,7 +114,7 @@ func benchmarkNotificationController(b *testing.B, messageCount int) {
 	unavailableOfferingsCache = awscache.NewUnavailableOfferings()
 
 	// Set-up the controllers
-	interruptionController := interruption.NewController(env.Client, fakeClock, recorder, providers.sqsProvider, unavailableOfferingsCache)
+	interruptionController := interruption.NewController(env.Client, fakeClock, recorder, providers.sqsProvider, nil, nil, unavailableOfferingsCache)
 
 	messages, nodes := makeDiverseMessagesAndNodes(messageCount)
 	log.FromContext(ctx).Info("provisioning nodes")
@@ -26,6 +26,10 @@ const (
 	messageTypeLabel       = "message_type"
 	actionTypeLabel        = "action_type"
 	terminationReasonLabel = "interruption"
+	quarantineReasonLabel  = "reason"
+
+	quarantineReasonParse  = "parse"
+	quarantineReasonHandle = "handle"
 )
 
 var (
@@ -46,6 +50,24 @@ var (
 			Help:      "Count of messages deleted from the SQS queue.",
 		},
 	)
+	skippedMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "skipped_messages",
+			Help:      "Count of messages returned to a shared SQS queue because every instance in the message is owned by another cluster. Broken down by message type.",
+		},
+		[]string{messageTypeLabel},
+	)
+	quarantinedMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "quarantined_messages",
+			Help:      "Count of messages deleted from the SQS queue because they couldn't be parsed or repeatedly failed to be handled. Broken down by reason.",
+		},
+		[]string{quarantineReasonLabel},
+	)
 	messageLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: metrics.Namespace,
@@ -67,8 +89,17 @@ var (
 			metrics.NodePoolLabel,
 		},
 	)
+	pendingDrains = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "pending_drains",
+			Help:      "Number of nodes with a spot interruption warning that are waiting for a drain slot. Labeled by nodepool",
+		},
+		[]string{metrics.NodePoolLabel},
+	)
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(receivedMessages, deletedMessages, messageLatency, actionsPerformed)
+	crmetrics.Registry.MustRegister(receivedMessages, deletedMessages, skippedMessages, quarantinedMessages, messageLatency, actionsPerformed, pendingDrains)
 }
@@ -23,6 +23,8 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
 	servicesqs "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
@@ -45,7 +47,9 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/spotinterruption"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/statechange"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -63,7 +67,10 @@ const (
 var ctx context.Context
 var env *coretest.Environment
 var sqsapi *fake.SQSAPI
+var eventbridgeapi *fake.EventBridgeAPI
 var sqsProvider *sqs.DefaultProvider
+var deadLetterQueue *fake.SQSProvider
+var awsEnv *test.Environment
 var unavailableOfferingsCache *awscache.UnavailableOfferings
 var fakeClock *clock.FakeClock
 var controller *interruption.Controller
@@ -76,11 +83,15 @@ func TestAPIs(t *testing.T) {
 
 var _ = BeforeSuite(func() {
 	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
 	unavailableOfferingsCache = awscache.NewUnavailableOfferings()
-	sqsapi = &fake.SQSAPI{}
+	sqsapi = &fake.SQSAPI{Clock: fakeClock}
+	eventbridgeapi = &fake.EventBridgeAPI{Queues: map[string]*fake.SQSAPI{fake.DummyQueueARN: sqsapi}}
 	sqsProvider = lo.Must(sqs.NewDefaultProvider(sqsapi, fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/test-cluster", fake.DefaultRegion, fake.DefaultAccount)))
-	controller = interruption.NewController(env.Client, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, unavailableOfferingsCache)
+	deadLetterQueue = &fake.SQSProvider{}
+	controller = interruption.NewController(env.Client, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, deadLetterQueue, awsEnv.InstanceProvider, unavailableOfferingsCache)
 })
 
 var _ = AfterSuite(func() {
@@ -89,8 +100,12 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
 	unavailableOfferingsCache.Flush()
 	sqsapi.Reset()
+	deadLetterQueue.Reset()
+	eventbridgeapi.Reset()
+	awsEnv.Reset()
 })
 
 var _ = AfterEach(func() {
@@ -228,6 +243,238 @@ var _ = Describe("InterruptionHandling", func() {
 			Expect(unavailableOfferingsCache.IsUnavailable("t3.large", "coretest-zone-1a", corev1beta1.CapacityTypeSpot)).To(BeTrue())
 		})
 	})
+	Context("Shared Queue", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueueShared: lo.ToPtr(true)}))
+		})
+		It("should return the message to the queue when the instance is owned by another cluster", func() {
+			instanceID := fake.InstanceID()
+			ExpectInstanceCreated(instanceID, "other-cluster")
+			ExpectMessagesCreated(spotInterruptionMessage(instanceID))
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(0))
+			Expect(sqsapi.ChangeMessageVisibilityBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(aws.Int64Value(sqsapi.ChangeMessageVisibilityBehavior.CalledWithInput.Pop().VisibilityTimeout)).To(BeNumerically("==", 0))
+		})
+		It("should delete the message when the instance is owned by this cluster", func() {
+			instanceID := fake.InstanceID()
+			ExpectInstanceCreated(instanceID, options.FromContext(ctx).ClusterName)
+			ExpectMessagesCreated(spotInterruptionMessage(instanceID))
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(sqsapi.ChangeMessageVisibilityBehavior.SuccessfulCalls()).To(Equal(0))
+		})
+		It("should delete the message when the instance no longer exists", func() {
+			ExpectMessagesCreated(spotInterruptionMessage(fake.InstanceID()))
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(sqsapi.ChangeMessageVisibilityBehavior.SuccessfulCalls()).To(Equal(0))
+		})
+		It("should act on the NodeClaim without resolving the instance when it's owned by a NodeClaim", func() {
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(Equal(0))
+		})
+		It("should delete messages for instances owned by another cluster when the queue isn't shared", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueueShared: lo.ToPtr(false)}))
+			instanceID := fake.InstanceID()
+			ExpectInstanceCreated(instanceID, "other-cluster")
+			ExpectMessagesCreated(spotInterruptionMessage(instanceID))
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(sqsapi.ChangeMessageVisibilityBehavior.SuccessfulCalls()).To(Equal(0))
+		})
+	})
+	Context("Queue Semantics", func() {
+		BeforeEach(func() {
+			// The rules that route interruption events to the queue, as created by the getting started CloudFormation
+			for _, rule := range []*fake.EventBridgeRule{
+				{Name: "spot-interruption", EventPattern: `{"source":["aws.ec2"],"detail-type":["EC2 Spot Instance Interruption Warning"]}`},
+				{Name: "rebalance", EventPattern: `{"source":["aws.ec2"],"detail-type":["EC2 Instance Rebalance Recommendation"]}`},
+				{Name: "instance-state-change", EventPattern: `{"source":["aws.ec2"],"detail-type":["EC2 Instance State-change Notification"]}`},
+				{Name: "scheduled-change", EventPattern: `{"source":["aws.health"],"detail-type":["AWS Health Event"]}`},
+			} {
+				rule.State = eventbridge.RuleStateEnabled
+				rule.Targets = []string{fake.DummyQueueARN}
+				eventbridgeapi.Rules.Add(rule)
+			}
+		})
+		It("should handle events that the rules route to the queue", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			ExpectEventsPut(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			Expect(sqsapi.Messages()).To(HaveLen(1))
+
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.Messages()).To(BeEmpty())
+		})
+		It("should not deliver events that don't match a rule", func() {
+			msg := stateChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)), "stopping")
+			msg.DetailType = "EC2 AMI State Change"
+			ExpectEventsPut(msg)
+			Expect(sqsapi.Messages()).To(BeEmpty())
+		})
+		It("should receive a message again after its visibility timeout when it fails to be deleted", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			ExpectEventsPut(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			sqsapi.DeleteMessageBehavior.Error.Set(fmt.Errorf("failed"))
+
+			_ = ExpectSingletonReconcileFailed(ctx, controller)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.Messages()).To(HaveLen(1))
+			Expect(sqsapi.Messages()[0].ReceiveCount).To(Equal(1))
+
+			// The message is hidden from other receives until the visibility timeout passes
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.Messages()[0].ReceiveCount).To(Equal(1))
+
+			fakeClock.Step(time.Minute)
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.Messages()).To(BeEmpty())
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		})
+		It("should make messages for other clusters visible again immediately when the queue is shared", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueueShared: lo.ToPtr(true)}))
+			instanceID := fake.InstanceID()
+			ExpectInstanceCreated(instanceID, "other-cluster")
+			ExpectEventsPut(spotInterruptionMessage(instanceID))
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.Messages()).To(HaveLen(1))
+			Expect(sqsapi.Messages()[0].ReceiveCount).To(Equal(1))
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.Messages()).To(HaveLen(1))
+			Expect(sqsapi.Messages()[0].ReceiveCount).To(Equal(2))
+		})
+	})
+	Context("Quarantine", func() {
+		It("should forward a message that can't be parsed to the dead-letter queue", func() {
+			body := string(lo.Must(json.Marshal(map[string]string{"field1": "value1"})))
+			_, err := sqsapi.SendMessageWithContext(ctx, &servicesqs.SendMessageInput{MessageBody: aws.String(body)})
+			Expect(err).ToNot(HaveOccurred())
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.Messages()).To(BeEmpty())
+			quarantined := lo.Must(deadLetterQueue.GetSQSMessages(ctx))
+			Expect(quarantined).To(HaveLen(1))
+			Expect(aws.StringValue(quarantined[0].Body)).To(Equal(body))
+		})
+		It("should quarantine a message once it fails to be handled interruption-queue-max-receives times", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				InterruptionQueueShared:      lo.ToPtr(true),
+				InterruptionQueueMaxReceives: lo.ToPtr(2),
+			}))
+			awsEnv.EC2API.DescribeInstancesBehavior.Error.Set(fmt.Errorf("failed"), fake.MaxCalls(0))
+			_, err := sqsProvider.SendMessage(ctx, spotInterruptionMessage(fake.InstanceID()))
+			Expect(err).ToNot(HaveOccurred())
+
+			_ = ExpectSingletonReconcileFailed(ctx, controller)
+			Expect(sqsapi.Messages()).To(HaveLen(1))
+			Expect(lo.Must(deadLetterQueue.GetSQSMessages(ctx))).To(BeEmpty())
+
+			fakeClock.Step(time.Minute)
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.Messages()).To(BeEmpty())
+			Expect(lo.Must(deadLetterQueue.GetSQSMessages(ctx))).To(HaveLen(1))
+		})
+		It("should retry a message that fails to be handled until it expires when interruption-queue-max-receives isn't set", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				InterruptionQueueShared: lo.ToPtr(true),
+			}))
+			awsEnv.EC2API.DescribeInstancesBehavior.Error.Set(fmt.Errorf("failed"), fake.MaxCalls(0))
+			_, err := sqsProvider.SendMessage(ctx, spotInterruptionMessage(fake.InstanceID()))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 10; i++ {
+				_ = ExpectSingletonReconcileFailed(ctx, controller)
+				fakeClock.Step(time.Minute)
+			}
+			Expect(sqsapi.Messages()).To(HaveLen(1))
+			Expect(sqsapi.Messages()[0].ReceiveCount).To(Equal(10))
+			Expect(lo.Must(deadLetterQueue.GetSQSMessages(ctx))).To(BeEmpty())
+		})
+	})
+	Context("Drain Limits", func() {
+		var nodeClaims []*corev1beta1.NodeClaim
+		BeforeEach(func() {
+			fakeClock.SetTime(time.Time{})
+			nodeClaims = nil
+			var messages []interface{}
+			for i := 0; i < 5; i++ {
+				instanceID := fake.InstanceID()
+				nc, n := coretest.NodeClaimAndNode(corev1beta1.NodeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							corev1beta1.NodePoolLabelKey: "default",
+						},
+						// Keeps the NodeClaims around while they're deleting, so that they count as draining
+						Finalizers: []string{corev1beta1.TerminationFinalizer},
+					},
+					Status: corev1beta1.NodeClaimStatus{
+						ProviderID: fake.ProviderID(instanceID),
+					},
+				})
+				ExpectApplied(ctx, env.Client, nc, n)
+				nodeClaims = append(nodeClaims, nc)
+				messages = append(messages, spotInterruptionMessage(instanceID))
+			}
+			ExpectMessagesCreated(messages...)
+		})
+		expectDeleting := func(count int) {
+			GinkgoHelper()
+			Expect(lo.CountBy(nodeClaims, func(nc *corev1beta1.NodeClaim) bool {
+				return !ExpectExists(ctx, env.Client, nc).DeletionTimestamp.IsZero()
+			})).To(Equal(count))
+		}
+		It("should only drain max-concurrent-interruption-drains nodes at once", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxConcurrentInterruptionDrains: lo.ToPtr(2)}))
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(5))
+			expectDeleting(2)
+
+			// Draining nodes still hold their slots
+			ExpectMessagesCreated()
+			ExpectSingletonReconciled(ctx, controller)
+			expectDeleting(2)
+		})
+		It("should drain pending nodes once their interruption deadline passes", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxConcurrentInterruptionDrains: lo.ToPtr(1)}))
+			ExpectSingletonReconciled(ctx, controller)
+			expectDeleting(1)
+
+			fakeClock.SetTime(time.Now().Add(5 * time.Minute))
+			ExpectMessagesCreated()
+			ExpectSingletonReconciled(ctx, controller)
+			expectDeleting(5)
+		})
+		It("should respect the disruption budgets of the NodePool", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxConcurrentInterruptionDrains: lo.ToPtr(10)}))
+			nodePool := coretest.NodePool(corev1beta1.NodePool{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec: corev1beta1.NodePoolSpec{
+					Disruption: corev1beta1.Disruption{
+						Budgets: []corev1beta1.Budget{{Nodes: "1"}},
+					},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodePool)
+			ExpectSingletonReconciled(ctx, controller)
+			expectDeleting(1)
+		})
+		It("should drain every interrupted node at once when unlimited", func() {
+			ExpectSingletonReconciled(ctx, controller)
+			expectDeleting(5)
+		})
+	})
 })
 
 var _ = Describe("Error Handling", func() {
@@ -245,6 +492,17 @@ var _ = Describe("Error Handling", func() {
 	})
 })
 
+func ExpectInstanceCreated(instanceID string, clusterName string) {
+	GinkgoHelper()
+	awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+		InstanceId:   aws.String(instanceID),
+		InstanceType: aws.String("m5.large"),
+		State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+		Placement:    &ec2.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+		Tags:         []*ec2.Tag{{Key: aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)), Value: aws.String("owned")}},
+	})
+}
+
 func ExpectMessagesCreated(messages ...interface{}) {
 	raw := lo.Map(messages, func(m interface{}, _ int) *servicesqs.Message {
 		return &servicesqs.Message{
@@ -259,6 +517,28 @@ func ExpectMessagesCreated(messages ...interface{}) {
 	)
 }
 
+// ExpectEventsPut puts the messages on the event bus, which delivers them to the queue if they match a rule
+func ExpectEventsPut(messages ...interface{}) {
+	GinkgoHelper()
+	entries := lo.Map(messages, func(m interface{}, _ int) *eventbridge.PutEventsRequestEntry {
+		event := map[string]json.RawMessage{}
+		Expect(json.Unmarshal(lo.Must(json.Marshal(m)), &event)).To(Succeed())
+		var source, detailType string
+		var resources []string
+		Expect(json.Unmarshal(event["source"], &source)).To(Succeed())
+		Expect(json.Unmarshal(event["detail-type"], &detailType)).To(Succeed())
+		Expect(json.Unmarshal(event["resources"], &resources)).To(Succeed())
+		return &eventbridge.PutEventsRequestEntry{
+			Source:     aws.String(source),
+			DetailType: aws.String(detailType),
+			Detail:     aws.String(string(event["detail"])),
+			Resources:  aws.StringSlice(resources),
+		}
+	})
+	_, err := eventbridgeapi.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{Entries: entries})
+	Expect(err).ToNot(HaveOccurred())
+}
+
 func awsErrWithCode(code string) awserr.Error {
 	return awserr.New(code, "", fmt.Errorf(""))
 }
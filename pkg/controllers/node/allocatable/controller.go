@@ -0,0 +1,210 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package allocatable
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/system"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// ConfigMapName is the name of the ConfigMap in Karpenter's namespace where the suggested VM_MEMORY_OVERHEAD_PERCENT is
+// recorded. The ConfigMap is only written to; Karpenter never reads the suggestion back into its own settings.
+const ConfigMapName = "karpenter-allocatable-estimation"
+
+const (
+	// VMMemoryOverheadPercentKey is the ConfigMap key of the smallest VM_MEMORY_OVERHEAD_PERCENT that wouldn't have
+	// overestimated the memory capacity of any registered node
+	VMMemoryOverheadPercentKey = "vmMemoryOverheadPercent"
+	// NodeCountKey is the ConfigMap key of the number of nodes that the suggestion was computed from
+	NodeCountKey = "nodeCount"
+)
+
+const pollingPeriod = 5 * time.Minute
+
+// Controller continuously performs the comparison that tools/allocatable-diff does on demand: it compares the capacity
+// and allocatable that registered nodes report against the estimates of the instance types that they were launched as.
+type Controller struct {
+	kubeClient    client.Client
+	kubeReader    client.Reader
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, kubeReader client.Reader, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		kubeReader:    kubeReader,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "node.allocatable")
+
+	nodeList := &v1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodeList, client.HasLabels{corev1beta1.NodeRegisteredLabelKey}); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodes, %w", err)
+	}
+	// Instance type estimates depend on the NodePool's EC2NodeClass, so they're resolved once per NodePool
+	instanceTypes := map[string]map[string]*cloudprovider.InstanceType{}
+	estimationErrors := map[string]map[v1.ResourceName]float64{}
+	var overheadPercents []float64
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		// Allocatable isn't reported until the kubelet has registered the node and posted its status
+		if node.Status.Allocatable.Memory().IsZero() {
+			continue
+		}
+		nodePoolName := node.Labels[corev1beta1.NodePoolLabelKey]
+		if _, ok := instanceTypes[nodePoolName]; !ok {
+			its, err := c.getInstanceTypes(ctx, nodePoolName)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			instanceTypes[nodePoolName] = its
+		}
+		instanceType, ok := instanceTypes[nodePoolName][node.Labels[v1.LabelInstanceTypeStable]]
+		if !ok {
+			continue
+		}
+		estimated := instanceType.Allocatable()
+		if _, ok := estimationErrors[instanceType.Name]; !ok {
+			estimationErrors[instanceType.Name] = map[v1.ResourceName]float64{}
+		}
+		for _, resourceName := range []v1.ResourceName{v1.ResourceMemory, v1.ResourceEphemeralStorage} {
+			actual := node.Status.Allocatable[resourceName]
+			estimate := estimated[resourceName]
+			diff := estimate.AsApproximateFloat64() - actual.AsApproximateFloat64()
+			if existing, ok := estimationErrors[instanceType.Name][resourceName]; !ok || diff > existing {
+				estimationErrors[instanceType.Name][resourceName] = diff
+			}
+		}
+		if percent, ok := vmMemoryOverheadPercent(node); ok {
+			overheadPercents = append(overheadPercents, percent)
+		}
+	}
+	allocatableEstimationError.Reset()
+	for instanceTypeName, resourceErrors := range estimationErrors {
+		for resourceName, diff := range resourceErrors {
+			allocatableEstimationError.With(map[string]string{
+				instanceTypeLabel: instanceTypeName,
+				resourceTypeLabel: string(resourceName),
+			}).Set(diff)
+		}
+	}
+	if len(overheadPercents) != 0 {
+		if err := c.recordSuggestion(ctx, lo.Max(overheadPercents), len(overheadPercents)); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: pollingPeriod}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("node.allocatable").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("node.allocatable", singleton.AsReconciler(c)))
+}
+
+func (c *Controller) getInstanceTypes(ctx context.Context, nodePoolName string) (map[string]*cloudprovider.InstanceType, error) {
+	nodePool := &corev1beta1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		if errors.IsNotFound(err) {
+			return map[string]*cloudprovider.InstanceType{}, nil
+		}
+		return nil, fmt.Errorf("getting nodepool, %w", err)
+	}
+	instanceTypes, err := c.cloudProvider.GetInstanceTypes(ctx, nodePool)
+	if err != nil {
+		return nil, fmt.Errorf("getting instance types, %w", err)
+	}
+	return lo.SliceToMap(instanceTypes, func(it *cloudprovider.InstanceType) (string, *cloudprovider.InstanceType) {
+		return it.Name, it
+	}), nil
+}
+
+// recordSuggestion writes the suggested VM_MEMORY_OVERHEAD_PERCENT into the ConfigMap. The ConfigMap is read through
+// the API reader so that Karpenter doesn't need to watch every ConfigMap in the cluster.
+func (c *Controller) recordSuggestion(ctx context.Context, percent float64, nodeCount int) error {
+	data := map[string]string{
+		VMMemoryOverheadPercentKey: strconv.FormatFloat(percent, 'f', 3, 64),
+		NodeCountKey:               strconv.Itoa(nodeCount),
+	}
+	cm := &v1.ConfigMap{}
+	if err := c.kubeReader.Get(ctx, types.NamespacedName{Namespace: system.Namespace(), Name: ConfigMapName}, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("getting allocatable estimation configmap, %w", err)
+		}
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: system.Namespace(), Name: ConfigMapName},
+			Data:       data,
+		}
+		if err = c.kubeClient.Create(ctx, cm); err != nil {
+			return fmt.Errorf("creating allocatable estimation configmap, %w", err)
+		}
+		log.FromContext(ctx).WithValues(VMMemoryOverheadPercentKey, data[VMMemoryOverheadPercentKey]).Info("recorded suggested vm memory overhead percent")
+		return nil
+	}
+	if equality.Semantic.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	stored := cm.DeepCopy()
+	cm.Data = data
+	if err := c.kubeClient.Patch(ctx, cm, client.MergeFrom(stored)); err != nil {
+		return fmt.Errorf("patching allocatable estimation configmap, %w", err)
+	}
+	log.FromContext(ctx).WithValues(VMMemoryOverheadPercentKey, data[VMMemoryOverheadPercentKey]).Info("recorded suggested vm memory overhead percent")
+	return nil
+}
+
+// vmMemoryOverheadPercent returns the VM memory overhead percent that would have estimated the node's memory capacity
+// exactly. The memory that EC2 describes for the instance type is taken from the node's instance-memory label, with
+// the same Graviton adjustment that the instancetype provider makes.
+func vmMemoryOverheadPercent(node *v1.Node) (float64, bool) {
+	describedMiB, err := strconv.ParseFloat(node.Labels[v1beta1.LabelInstanceMemory], 64)
+	if err != nil || describedMiB == 0 {
+		return 0, false
+	}
+	if node.Labels[v1.LabelArchStable] == corev1beta1.ArchitectureArm64 {
+		describedMiB -= 64
+	}
+	capacityMiB := node.Status.Capacity.Memory().AsApproximateFloat64() / 1024 / 1024
+	// Round up so that the suggestion never overestimates the node that it was computed from
+	return math.Max(0, math.Ceil((1-capacityMiB/describedMiB)*1000)/1000), true
+}
@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package allocatable
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	awsSubsystem      = "aws"
+	instanceTypeLabel = "instance_type"
+	resourceTypeLabel = "resource_type"
+)
+
+var (
+	allocatableEstimationError = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: awsSubsystem,
+			Name:      "allocatable_estimation_error_bytes",
+			Help:      "Difference, in bytes, between the estimated and actual allocatable of registered nodes, based on instance type and resource type. Positive values mean that the allocatable was overestimated. When there are multiple nodes of an instance type, the largest error is reported.",
+		},
+		[]string{
+			instanceTypeLabel,
+			resourceTypeLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(allocatableEstimationError)
+}
@@ -0,0 +1,166 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package allocatable_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/system"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/node/allocatable"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var cloudProvider *fake.CloudProvider
+var controller *allocatable.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Allocatable")
+}
+
+var _ = BeforeSuite(func() {
+	lo.Must0(os.Setenv(system.NamespaceEnvKey, "default"))
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	cloudProvider = fake.NewCloudProvider()
+	controller = allocatable.NewController(env.Client, env.Client, cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	cloudProvider.Reset()
+	cloudProvider.InstanceTypes = []*cloudprovider.InstanceType{
+		fake.NewInstanceType(fake.InstanceTypeOptions{
+			Name: "m5.large",
+			Resources: v1.ResourceList{
+				v1.ResourceMemory:           resource.MustParse("7500Mi"),
+				v1.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+			},
+		}),
+	}
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+	ExpectDeleted(ctx, env.Client, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: allocatable.ConfigMapName}})
+})
+
+var _ = Describe("Allocatable", func() {
+	var nodePool *corev1beta1.NodePool
+	var node *v1.Node
+	BeforeEach(func() {
+		nodePool = coretest.NodePool()
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					corev1beta1.NodePoolLabelKey:       nodePool.Name,
+					corev1beta1.NodeRegisteredLabelKey: "true",
+					v1.LabelInstanceTypeStable:         "m5.large",
+					v1.LabelArchStable:                 corev1beta1.ArchitectureAmd64,
+					v1beta1.LabelInstanceMemory:        "8192",
+				},
+			},
+			Capacity: v1.ResourceList{
+				v1.ResourceMemory:           resource.MustParse("7600Mi"),
+				v1.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+			},
+			Allocatable: v1.ResourceList{
+				v1.ResourceMemory:           resource.MustParse("7000Mi"),
+				v1.ResourceEphemeralStorage: resource.MustParse("18Gi"),
+			},
+		})
+	})
+	It("should publish the allocatable estimation error for registered nodes", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectSingletonReconciled(ctx, controller)
+
+		metric, ok := FindMetricWithLabelValues("karpenter_aws_allocatable_estimation_error_bytes", map[string]string{
+			"instance_type": "m5.large",
+			"resource_type": string(v1.ResourceMemory),
+		})
+		Expect(ok).To(BeTrue())
+		// The fake instance type reserves 10Mi of memory for the kubelet
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 490*1024*1024))
+		metric, ok = FindMetricWithLabelValues("karpenter_aws_allocatable_estimation_error_bytes", map[string]string{
+			"instance_type": "m5.large",
+			"resource_type": string(v1.ResourceEphemeralStorage),
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 2*1024*1024*1024))
+	})
+	It("should ignore nodes that haven't registered", func() {
+		delete(node.Labels, corev1beta1.NodeRegisteredLabelKey)
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, ok := FindMetricWithLabelValues("karpenter_aws_allocatable_estimation_error_bytes", map[string]string{
+			"instance_type": "m5.large",
+		})
+		Expect(ok).To(BeFalse())
+	})
+	It("should record the suggested vm memory overhead percent", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectSingletonReconciled(ctx, controller)
+
+		cm := &v1.ConfigMap{}
+		Expect(env.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: allocatable.ConfigMapName}, cm)).To(Succeed())
+		// 1 - 7600/8192 = 0.0722...
+		Expect(cm.Data).To(HaveKeyWithValue(allocatable.VMMemoryOverheadPercentKey, "0.073"))
+		Expect(cm.Data).To(HaveKeyWithValue(allocatable.NodeCountKey, "1"))
+	})
+	It("should suggest the overhead that fits every node", func() {
+		other := node.DeepCopy()
+		other.Name = "other"
+		other.Status.Capacity[v1.ResourceMemory] = resource.MustParse("7400Mi")
+		ExpectApplied(ctx, env.Client, nodePool, node, other)
+		ExpectSingletonReconciled(ctx, controller)
+
+		cm := &v1.ConfigMap{}
+		Expect(env.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: allocatable.ConfigMapName}, cm)).To(Succeed())
+		// 1 - 7400/8192 = 0.0966...
+		Expect(cm.Data).To(HaveKeyWithValue(allocatable.VMMemoryOverheadPercentKey, "0.097"))
+		Expect(cm.Data).To(HaveKeyWithValue(allocatable.NodeCountKey, "2"))
+	})
+})
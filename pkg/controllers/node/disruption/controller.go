@@ -0,0 +1,123 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruption
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// Controller announces voluntary disruptions to the workloads on a node. Karpenter taints the nodes that it's about to
+// disrupt before it launches their replacements; this controller mirrors the taint into a node condition and an
+// annotation, which are easier for node agents and sidecars to watch for, so that they can checkpoint state before
+// the node is drained.
+type Controller struct {
+	kubeClient client.Client
+	clk        clock.Clock
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+		clk:        clk,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, node *v1.Node) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "node.disruption")
+
+	_, announced := node.Annotations[v1beta1.AnnotationPlannedDrainTime]
+	if isDisrupting(node) == announced {
+		return reconcile.Result{}, nil
+	}
+	now := c.clk.Now()
+	stored := node.DeepCopy()
+	if announced {
+		// The disruption was abandoned, e.g. because the replacements failed to launch, and the node was untainted
+		delete(node.Annotations, v1beta1.AnnotationPlannedDrainTime)
+	} else {
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1beta1.AnnotationPlannedDrainTime: now.Format(time.RFC3339)})
+	}
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching node annotations, %w", err))
+	}
+	stored = node.DeepCopy()
+	setCondition(node, lo.Ternary(announced, v1.NodeCondition{
+		Type:    v1beta1.NodeConditionDisruptionPlanned,
+		Status:  v1.ConditionFalse,
+		Reason:  "DisruptionCancelled",
+		Message: "Karpenter is no longer disrupting the node",
+	}, v1.NodeCondition{
+		Type:    v1beta1.NodeConditionDisruptionPlanned,
+		Status:  v1.ConditionTrue,
+		Reason:  "Disrupting",
+		Message: fmt.Sprintf("Karpenter may drain the node from %s", now.Format(time.RFC3339)),
+	}), now)
+	if err := c.kubeClient.Status().Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching node conditions, %w", err))
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("node.disruption").
+		For(&v1.Node{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			_, ok := o.GetLabels()[corev1beta1.NodePoolLabelKey]
+			return ok
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("node.disruption", reconcile.AsReconciler(m.GetClient(), c)))
+}
+
+// isDisrupting returns true if Karpenter has tainted the node in preparation for a voluntary disruption. Nodes that
+// are already being deleted keep the announcement until they're gone.
+func isDisrupting(node *v1.Node) bool {
+	return !node.DeletionTimestamp.IsZero() || lo.ContainsBy(node.Spec.Taints, corev1beta1.IsDisruptingTaint)
+}
+
+func setCondition(node *v1.Node, condition v1.NodeCondition, now time.Time) {
+	condition.LastHeartbeatTime = metav1.NewTime(now)
+	condition.LastTransitionTime = metav1.NewTime(now)
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == condition.Type {
+			node.Status.Conditions[i] = condition
+			return
+		}
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+}
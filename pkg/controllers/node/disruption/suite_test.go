@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruption_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/node/disruption"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *disruption.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeDisruptionController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	fakeClock = clock.NewFakeClock(time.Now())
+	controller = disruption.NewController(env.Client, fakeClock)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodeDisruptionController", func() {
+	var node *v1.Node
+
+	BeforeEach(func() {
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{corev1beta1.NodePoolLabelKey: "default"},
+			},
+		})
+	})
+	expectCondition := func(status v1.ConditionStatus) {
+		GinkgoHelper()
+		condition, ok := lo.Find(node.Status.Conditions, func(c v1.NodeCondition) bool {
+			return c.Type == v1beta1.NodeConditionDisruptionPlanned
+		})
+		Expect(ok).To(BeTrue())
+		Expect(condition.Status).To(Equal(status))
+	}
+
+	It("should announce the disruption of tainted nodes", func() {
+		node.Spec.Taints = []v1.Taint{corev1beta1.DisruptionNoScheduleTaint}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationPlannedDrainTime, fakeClock.Now().Format(time.RFC3339)))
+		expectCondition(v1.ConditionTrue)
+	})
+	It("should not announce the disruption of nodes that aren't tainted", func() {
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationPlannedDrainTime))
+		Expect(node.Status.Conditions).ToNot(ContainElement(HaveField("Type", v1beta1.NodeConditionDisruptionPlanned)))
+	})
+	It("should not move the planned drain time once it's announced", func() {
+		node.Spec.Taints = []v1.Taint{corev1beta1.DisruptionNoScheduleTaint}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		planned := fakeClock.Now().Format(time.RFC3339)
+
+		fakeClock.Step(time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationPlannedDrainTime, planned))
+	})
+	It("should withdraw the announcement when the disruption is abandoned", func() {
+		node.Spec.Taints = []v1.Taint{corev1beta1.DisruptionNoScheduleTaint}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		node = ExpectExists(ctx, env.Client, node)
+		node.Spec.Taints = nil
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationPlannedDrainTime))
+		expectCondition(v1.ConditionFalse)
+	})
+})
@@ -0,0 +1,147 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// Controller enforces the minimum node age of NodePools. Nodes that are younger than the minimum age of their NodePool
+// are annotated with karpenter.sh/do-not-disrupt, which excludes them from consolidation, drift and expiration, and the
+// annotation is removed once they reach it. This keeps a node that was just launched from being consolidated away as
+// soon as a slightly cheaper combination of nodes appears.
+type Controller struct {
+	kubeClient client.Client
+	clk        clock.Clock
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+		clk:        clk,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, node *v1.Node) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "node.minage")
+
+	if !node.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	current, managed := node.Annotations[v1beta1.AnnotationMinNodeAgeExpiration]
+	// The do-not-disrupt annotation was set by someone else, who decides when the node can be disrupted
+	if _, ok := node.Annotations[corev1beta1.DoNotDisruptAnnotationKey]; ok && !managed {
+		return reconcile.Result{}, nil
+	}
+	minAge, err := c.minAge(ctx, node)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	expiration := node.CreationTimestamp.Add(minAge)
+	remaining := expiration.Sub(c.clk.Now())
+	desired := lo.Ternary(remaining > 0, expiration.UTC().Format(time.RFC3339), "")
+	if desired != current {
+		stored := node.DeepCopy()
+		if desired == "" {
+			// Nodes of NodeClaims with termination protection stay exempt from disruption after their minimum age
+			if _, ok := node.Annotations[v1beta1.AnnotationTerminationProtected]; !ok {
+				delete(node.Annotations, corev1beta1.DoNotDisruptAnnotationKey)
+			}
+			delete(node.Annotations, v1beta1.AnnotationMinNodeAgeExpiration)
+		} else {
+			node.Annotations = lo.Assign(node.Annotations, map[string]string{
+				corev1beta1.DoNotDisruptAnnotationKey:  "true",
+				v1beta1.AnnotationMinNodeAgeExpiration: desired,
+			})
+		}
+		if err = c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching node annotations, %w", err))
+		}
+	}
+	if remaining > 0 {
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// minAge returns the minimum age of the node's NodePool, or 0 if it doesn't have one
+func (c *Controller) minAge(ctx context.Context, node *v1.Node) (time.Duration, error) {
+	nodePool := &corev1beta1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: node.Labels[corev1beta1.NodePoolLabelKey]}, nodePool); err != nil {
+		return 0, client.IgnoreNotFound(fmt.Errorf("getting nodepool, %w", err))
+	}
+	value, ok := nodePool.Annotations[v1beta1.AnnotationMinNodeAge]
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		// We don't return an error here since retrying won't fix the annotation
+		log.FromContext(ctx).WithValues("NodePool", client.ObjectKeyFromObject(nodePool), "value", value).Error(err, "invalid minimum node age")
+		return 0, nil
+	}
+	return d, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("node.minage").
+		For(&v1.Node{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			if _, ok := o.(*corev1beta1.NodePool); ok {
+				return true
+			}
+			_, ok := o.GetLabels()[corev1beta1.NodePoolLabelKey]
+			return ok
+		})).
+		Watches(
+			&corev1beta1.NodePool{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+				nodes := &v1.NodeList{}
+				if err := m.GetClient().List(ctx, nodes, client.MatchingLabels{corev1beta1.NodePoolLabelKey: o.GetName()}); err != nil {
+					return nil
+				}
+				return lo.Map(nodes.Items, func(n v1.Node, _ int) reconcile.Request {
+					return reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&n)}
+				})
+			}),
+		).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("node.minage", reconcile.AsReconciler(m.GetClient(), c)))
+}
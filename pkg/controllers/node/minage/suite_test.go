@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/node/minage"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *minage.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeMinAgeController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	fakeClock = clock.NewFakeClock(time.Now())
+	controller = minage.NewController(env.Client, fakeClock)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodeMinAgeController", func() {
+	var nodePool *corev1beta1.NodePool
+	var node *v1.Node
+
+	BeforeEach(func() {
+		nodePool = coretest.NodePool(corev1beta1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1beta1.AnnotationMinNodeAge: "10m"},
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{corev1beta1.NodePoolLabelKey: nodePool.Name},
+			},
+		})
+	})
+	It("should block disruption of nodes younger than the minimum age of their nodepool", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Minute))
+		result := ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 9*time.Minute, time.Second))
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationMinNodeAgeExpiration, node.CreationTimestamp.Add(10*time.Minute).UTC().Format(time.RFC3339)))
+	})
+	It("should unblock disruption once nodes reach the minimum age", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Minute))
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		fakeClock.Step(10 * time.Minute)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(result.RequeueAfter).To(BeZero())
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationMinNodeAgeExpiration))
+	})
+	It("should unblock disruption when the minimum age is removed from the nodepool", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Minute))
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		delete(nodePool.Annotations, v1beta1.AnnotationMinNodeAge)
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+	})
+	It("should not block disruption of nodes older than the minimum age", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Hour))
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+	})
+	It("should ignore an invalid minimum age", func() {
+		nodePool.Annotations[v1beta1.AnnotationMinNodeAge] = "ten minutes"
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Minute))
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+	})
+	It("should not remove a do-not-disrupt annotation that it didn't set", func() {
+		node.Annotations = map[string]string{corev1beta1.DoNotDisruptAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Hour))
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationMinNodeAgeExpiration))
+	})
+	It("should not unblock disruption of nodes that are protected from termination", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		fakeClock.SetTime(node.CreationTimestamp.Time.Add(time.Minute))
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		node.Annotations[v1beta1.AnnotationTerminationProtected] = "true"
+		ExpectApplied(ctx, env.Client, node)
+
+		fakeClock.Step(10 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationMinNodeAgeExpiration))
+	})
+})
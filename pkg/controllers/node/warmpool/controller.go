@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmpool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// Controller removes the warm pool taint from the nodes of warm pool instances that were started for a NodeClaim. The
+// nodes of warm pool instances are registered with the taint so that no pods are scheduled to them while they
+// bootstrap, and the kubelet registers it again when the instance is started, since the instance keeps its user data.
+// The taint is removed once the NodeClaim is registered, so that the node can run the pods that it was launched for.
+type Controller struct {
+	kubeClient client.Client
+}
+
+func NewController(kubeClient client.Client) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, node *v1.Node) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "node.warmpool")
+
+	if !node.DeletionTimestamp.IsZero() || node.Labels[corev1beta1.NodeRegisteredLabelKey] != "true" {
+		return reconcile.Result{}, nil
+	}
+	if !hasWarmPoolTaint(node) {
+		return reconcile.Result{}, nil
+	}
+	stored := node.DeepCopy()
+	node.Spec.Taints = lo.Reject(node.Spec.Taints, func(t v1.Taint, _ int) bool { return t.MatchTaint(&v1beta1.WarmPoolNoScheduleTaint) })
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("removing warm pool taint, %w", err))
+	}
+	log.FromContext(ctx).WithValues("Node", client.ObjectKeyFromObject(node)).V(1).Info("removed warm pool taint from started node")
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("node.warmpool").
+		For(&v1.Node{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return hasWarmPoolTaint(o.(*v1.Node))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("node.warmpool", reconcile.AsReconciler(m.GetClient(), c)))
+}
+
+func hasWarmPoolTaint(node *v1.Node) bool {
+	return lo.ContainsBy(node.Spec.Taints, func(t v1.Taint) bool { return t.MatchTaint(&v1beta1.WarmPoolNoScheduleTaint) })
+}
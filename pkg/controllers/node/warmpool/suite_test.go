@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmpool_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/node/warmpool"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var controller *warmpool.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeWarmPoolController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	controller = warmpool.NewController(env.Client)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodeWarmPoolController", func() {
+	var node *v1.Node
+
+	BeforeEach(func() {
+		node = coretest.Node(coretest.NodeOptions{
+			Taints: []v1.Taint{v1beta1.WarmPoolNoScheduleTaint, {Key: "other", Effect: v1.TaintEffectNoSchedule}},
+		})
+	})
+	It("should keep warm pool nodes unschedulable while they aren't registered for a NodeClaim", func() {
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Spec.Taints).To(ContainElement(v1beta1.WarmPoolNoScheduleTaint))
+		Expect(scheduling.Taints(node.Spec.Taints).Tolerates(coretest.Pod(coretest.PodOptions{
+			Tolerations: []v1.Toleration{{Key: "other", Operator: v1.TolerationOpExists}},
+		}))).ToNot(Succeed())
+	})
+	It("should remove the warm pool taint once the node is registered for a NodeClaim", func() {
+		node.Labels = map[string]string{corev1beta1.NodeRegisteredLabelKey: "true"}
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Spec.Taints).ToNot(ContainElement(v1beta1.WarmPoolNoScheduleTaint))
+		Expect(node.Spec.Taints).To(ContainElement(v1.Taint{Key: "other", Effect: v1.TaintEffectNoSchedule}))
+		Expect(scheduling.Taints(node.Spec.Taints).Tolerates(coretest.Pod(coretest.PodOptions{
+			Tolerations: []v1.Toleration{{Key: "other", Operator: v1.TolerationOpExists}},
+		}))).To(Succeed())
+	})
+})
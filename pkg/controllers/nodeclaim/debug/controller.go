@@ -0,0 +1,253 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// accessDuration is how long debug access is granted for before it's revoked
+const accessDuration = time.Hour
+
+// Controller grants temporary break-glass access to the instances of NodeClaims that are annotated with
+// karpenter.k8s.aws/debug: "true", so that bootstrap failures can be debugged on nodes that never joined the cluster.
+// Instances whose SSM agent is online are reached through Session Manager. Otherwise, an EC2 Instance Connect Endpoint
+// in the instance's VPC is used, and created if there isn't one. The connection info is reported in an event on the
+// NodeClaim, and the access is revoked when it expires, when the annotation is removed, or when the NodeClaim is deleted.
+type Controller struct {
+	kubeClient       client.Client
+	clk              clock.Clock
+	recorder         events.Recorder
+	ec2api           ec2iface.EC2API
+	ssmapi           ssmiface.SSMAPI
+	instanceProvider instance.Provider
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock, recorder events.Recorder, ec2api ec2iface.EC2API, ssmapi ssmiface.SSMAPI,
+	instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		clk:              clk,
+		recorder:         recorder,
+		ec2api:           ec2api,
+		ssmapi:           ssmapi,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.debug")
+
+	if nodeClaim.Annotations[v1beta1.AnnotationDebug] != "true" || !nodeClaim.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, c.revoke(ctx, nodeClaim, false)
+	}
+	if raw, ok := nodeClaim.Annotations[v1beta1.AnnotationDebugExpiration]; ok {
+		// An expiration that can't be parsed is treated as expired, so that access is never granted indefinitely
+		if expiration, err := time.Parse(time.RFC3339, raw); err == nil && c.clk.Now().Before(expiration) {
+			return reconcile.Result{RequeueAfter: expiration.Sub(c.clk.Now())}, nil
+		}
+		return reconcile.Result{}, c.revoke(ctx, nodeClaim, true)
+	}
+	// The NodeClaim is reconciled again once it's launched and its ProviderID is set
+	if nodeClaim.Status.ProviderID == "" {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	if err := c.grant(ctx, nodeClaim, id); err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	}
+	return reconcile.Result{RequeueAfter: accessDuration}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.debug").
+		For(&corev1beta1.NodeClaim{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return lo.Some(lo.Keys(o.GetAnnotations()), []string{v1beta1.AnnotationDebug, v1beta1.AnnotationDebugExpiration, v1beta1.AnnotationDebugEndpoint})
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(tracing.Reconciler("nodeclaim.debug", reconcile.AsReconciler(m.GetClient(), c)))
+}
+
+// grant opens access to the instance, records when the access expires on the NodeClaim, and reports how to connect
+func (c *Controller) grant(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string) error {
+	online, err := c.sessionManagerOnline(ctx, id)
+	if err != nil {
+		return err
+	}
+	stored := nodeClaim.DeepCopy()
+	expiration := c.clk.Now().Add(accessDuration).Truncate(time.Second)
+	var event events.Event
+	if online {
+		event = SessionManagerAccessEvent(nodeClaim, id, expiration)
+	} else {
+		inst, err := c.instanceProvider.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("getting instance, %w", err)
+		}
+		endpoint, managed, err := c.instanceConnectEndpoint(ctx, nodeClaim, inst)
+		if err != nil {
+			return err
+		}
+		// Endpoints that Karpenter didn't create are left alone when the access is revoked
+		if managed {
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationDebugEndpoint: aws.StringValue(endpoint.InstanceConnectEndpointId)})
+		}
+		event = InstanceConnectAccessEvent(nodeClaim, id, aws.StringValue(endpoint.InstanceConnectEndpointId), expiration)
+	}
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationDebugExpiration: expiration.Format(time.RFC3339)})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).WithValues("expiration", expiration.Format(time.RFC3339)).Info("granted debug access")
+	c.recorder.Publish(event)
+	return nil
+}
+
+// revoke deletes the EC2 Instance Connect Endpoint that Karpenter created for the NodeClaim, if no other NodeClaim is
+// using it, and removes the debug annotations. Expired access also removes the debug annotation, so that it isn't
+// granted again until it's renewed.
+func (c *Controller) revoke(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, expired bool) error {
+	if !expired && !lo.Some(lo.Keys(nodeClaim.Annotations), []string{v1beta1.AnnotationDebugExpiration, v1beta1.AnnotationDebugEndpoint}) {
+		return nil
+	}
+	if endpointID, ok := nodeClaim.Annotations[v1beta1.AnnotationDebugEndpoint]; ok {
+		if err := c.deleteInstanceConnectEndpoint(ctx, nodeClaim, endpointID); err != nil {
+			return err
+		}
+	}
+	stored := nodeClaim.DeepCopy()
+	delete(nodeClaim.Annotations, v1beta1.AnnotationDebugExpiration)
+	delete(nodeClaim.Annotations, v1beta1.AnnotationDebugEndpoint)
+	if expired {
+		delete(nodeClaim.Annotations, v1beta1.AnnotationDebug)
+	}
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("revoked debug access")
+	if expired {
+		c.recorder.Publish(AccessExpiredEvent(nodeClaim))
+	}
+	return nil
+}
+
+// sessionManagerOnline returns true if the SSM agent of the instance is registered and online
+func (c *Controller) sessionManagerOnline(ctx context.Context, id string) (bool, error) {
+	out, err := c.ssmapi.DescribeInstanceInformationWithContext(ctx, &ssm.DescribeInstanceInformationInput{
+		Filters: []*ssm.InstanceInformationStringFilter{{Key: aws.String("InstanceIds"), Values: aws.StringSlice([]string{id})}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("describing ssm instance information, %w", err)
+	}
+	return lo.ContainsBy(out.InstanceInformationList, func(i *ssm.InstanceInformation) bool {
+		return aws.StringValue(i.PingStatus) == ssm.PingStatusOnline
+	}), nil
+}
+
+// instanceConnectEndpoint returns an EC2 Instance Connect Endpoint in the VPC of the instance, and whether it was created
+// by Karpenter. EC2 limits the number of endpoints per VPC, so an existing endpoint is reused rather than creating one
+// for every NodeClaim.
+func (c *Controller) instanceConnectEndpoint(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, inst *instance.Instance) (*ec2.Ec2InstanceConnectEndpoint, bool, error) {
+	out, err := c.ec2api.DescribeInstanceConnectEndpointsWithContext(ctx, &ec2.DescribeInstanceConnectEndpointsInput{
+		Filters: []*ec2.Filter{{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{inst.VPCID})}},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("describing instance connect endpoints, %w", err)
+	}
+	if endpoint, ok := lo.Find(out.InstanceConnectEndpoints, func(e *ec2.Ec2InstanceConnectEndpoint) bool {
+		return lo.Contains([]string{ec2.Ec2InstanceConnectEndpointStateCreateInProgress, ec2.Ec2InstanceConnectEndpointStateCreateComplete}, aws.StringValue(e.State))
+	}); ok {
+		return endpoint, lo.ContainsBy(endpoint.Tags, func(t *ec2.Tag) bool {
+			return aws.StringValue(t.Key) == v1beta1.TagManagedLaunchTemplate && aws.StringValue(t.Value) == options.FromContext(ctx).ClusterName
+		}), nil
+	}
+	// The endpoint uses the instance's security groups, which typically allow traffic between their members
+	created, err := c.ec2api.CreateInstanceConnectEndpointWithContext(ctx, &ec2.CreateInstanceConnectEndpointInput{
+		ClientToken:      aws.String(string(nodeClaim.UID)),
+		SubnetId:         aws.String(inst.SubnetID),
+		SecurityGroupIds: aws.StringSlice(inst.SecurityGroupIDs),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstanceConnectEndpoint),
+			Tags: utils.MergeTags(map[string]string{
+				v1beta1.TagManagedLaunchTemplate: options.FromContext(ctx).ClusterName,
+				v1beta1.TagNodeClaim:             nodeClaim.Name,
+			}),
+		}},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("creating instance connect endpoint, %w", err)
+	}
+	log.FromContext(ctx).WithValues("id", aws.StringValue(created.InstanceConnectEndpoint.InstanceConnectEndpointId)).Info("created instance connect endpoint")
+	return created.InstanceConnectEndpoint, true, nil
+}
+
+func (c *Controller) deleteInstanceConnectEndpoint(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, endpointID string) error {
+	nodeClaimList := &corev1beta1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	if lo.ContainsBy(nodeClaimList.Items, func(nc corev1beta1.NodeClaim) bool {
+		return nc.Name != nodeClaim.Name && nc.Annotations[v1beta1.AnnotationDebugEndpoint] == endpointID
+	}) {
+		return nil
+	}
+	if _, err := c.ec2api.DeleteInstanceConnectEndpointWithContext(ctx, &ec2.DeleteInstanceConnectEndpointInput{
+		InstanceConnectEndpointId: aws.String(endpointID),
+	}); awserrors.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting instance connect endpoint, %w", err)
+	}
+	log.FromContext(ctx).WithValues("id", endpointID).Info("deleted instance connect endpoint")
+	return nil
+}
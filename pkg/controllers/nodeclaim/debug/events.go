@@ -0,0 +1,58 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+func SessionManagerAccessEvent(nodeClaim *corev1beta1.NodeClaim, instanceID string, expiration time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "DebugAccessGranted",
+		Message:        fmt.Sprintf("Debug access granted until %s, connect with `aws ssm start-session --target %s`", expiration.Format(time.RFC3339), instanceID),
+		DedupeValues:   []string{string(nodeClaim.UID), expiration.String()},
+	}
+}
+
+func InstanceConnectAccessEvent(nodeClaim *corev1beta1.NodeClaim, instanceID, endpointID string, expiration time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "DebugAccessGranted",
+		Message: fmt.Sprintf("Debug access granted until %s through EC2 Instance Connect Endpoint %s, connect with `aws ec2-instance-connect ssh --instance-id %s --connection-type eice` once the endpoint is available",
+			expiration.Format(time.RFC3339), endpointID, instanceID),
+		DedupeValues: []string{string(nodeClaim.UID), expiration.String()},
+	}
+}
+
+func AccessExpiredEvent(nodeClaim *corev1beta1.NodeClaim) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "DebugAccessExpired",
+		Message:        fmt.Sprintf("Debug access expired, annotate the NodeClaim with %s: \"true\" again to renew it", v1beta1.AnnotationDebug),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
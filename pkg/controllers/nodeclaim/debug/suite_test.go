@@ -0,0 +1,264 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/debug"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var recorder *coretest.EventRecorder
+var controller *debug.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Debug")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	recorder = coretest.NewEventRecorder()
+	controller = debug.NewController(env.Client, fakeClock, recorder, awsEnv.EC2API, awsEnv.SSMAPI, awsEnv.InstanceProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+	recorder.Reset()
+	fakeClock.SetTime(time.Now())
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Debug", func() {
+	var ec2Instance *ec2.Instance
+	var nodeClaim *corev1beta1.NodeClaim
+	BeforeEach(func() {
+		ec2Instance = &ec2.Instance{
+			State: &ec2.InstanceState{
+				Name: aws.String(ec2.InstanceStateNameRunning),
+			},
+			Tags: []*ec2.Tag{
+				{
+					Key:   aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)),
+					Value: aws.String("owned"),
+				},
+				{
+					Key:   aws.String(corev1beta1.NodePoolLabelKey),
+					Value: aws.String("default"),
+				},
+				{
+					Key:   aws.String(corev1beta1.ManagedByAnnotationKey),
+					Value: aws.String(options.FromContext(ctx).ClusterName),
+				},
+			},
+			PrivateDnsName: aws.String(fake.PrivateDNSName()),
+			Placement: &ec2.Placement{
+				AvailabilityZone: aws.String(fake.DefaultRegion),
+			},
+			InstanceId:     aws.String(fake.InstanceID()),
+			InstanceType:   aws.String("m5.large"),
+			SubnetId:       aws.String("subnet-test1"),
+			VpcId:          aws.String("vpc-test1"),
+			SecurityGroups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-test1")}},
+		}
+		awsEnv.EC2API.Instances.Store(*ec2Instance.InstanceId, ec2Instance)
+		nodeClaim = coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1beta1.AnnotationDebug: "true"},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+			},
+		})
+	})
+	endpoints := func() []*ec2.Ec2InstanceConnectEndpoint {
+		var out []*ec2.Ec2InstanceConnectEndpoint
+		awsEnv.EC2API.InstanceConnectEndpoints.Range(func(_, v any) bool {
+			out = append(out, v.(*ec2.Ec2InstanceConnectEndpoint))
+			return true
+		})
+		return out
+	}
+	It("should grant access through Session Manager when the SSM agent is online", func() {
+		awsEnv.SSMAPI.DescribeInstanceInformationBehavior.Output.Set(&ssm.DescribeInstanceInformationOutput{
+			InstanceInformationList: []*ssm.InstanceInformation{{InstanceId: ec2Instance.InstanceId, PingStatus: aws.String(ssm.PingStatusOnline)}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKey(v1beta1.AnnotationDebugExpiration))
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebugEndpoint))
+		Expect(awsEnv.EC2API.CreateInstanceConnectEndpointBehavior.Calls()).To(Equal(0))
+		Expect(recorder.Calls("DebugAccessGranted")).To(Equal(1))
+		Expect(recorder.Events()[0].Message).To(ContainSubstring(fmt.Sprintf("aws ssm start-session --target %s", *ec2Instance.InstanceId)))
+	})
+	It("should create an EC2 Instance Connect Endpoint when the SSM agent isn't online", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(awsEnv.EC2API.CreateInstanceConnectEndpointBehavior.Calls()).To(Equal(1))
+		input := awsEnv.EC2API.CreateInstanceConnectEndpointBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValue(input.SubnetId)).To(Equal("subnet-test1"))
+		Expect(aws.StringValueSlice(input.SecurityGroupIds)).To(ConsistOf("sg-test1"))
+		Expect(input.TagSpecifications[0].Tags).To(ContainElement(&ec2.Tag{Key: aws.String(v1beta1.TagNodeClaim), Value: aws.String(nodeClaim.Name)}))
+
+		Expect(endpoints()).To(HaveLen(1))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationDebugEndpoint, aws.StringValue(endpoints()[0].InstanceConnectEndpointId)))
+		Expect(nodeClaim.Annotations).To(HaveKey(v1beta1.AnnotationDebugExpiration))
+		Expect(recorder.Calls("DebugAccessGranted")).To(Equal(1))
+		Expect(recorder.Events()[0].Message).To(ContainSubstring("--connection-type eice"))
+	})
+	It("should reuse an existing EC2 Instance Connect Endpoint in the instance's VPC without taking ownership of it", func() {
+		awsEnv.EC2API.InstanceConnectEndpoints.Store("eice-existing", &ec2.Ec2InstanceConnectEndpoint{
+			InstanceConnectEndpointId: aws.String("eice-existing"),
+			VpcId:                     aws.String("vpc-test1"),
+			State:                     aws.String(ec2.Ec2InstanceConnectEndpointStateCreateComplete),
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(awsEnv.EC2API.CreateInstanceConnectEndpointBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebugEndpoint))
+		Expect(recorder.Events()[0].Message).To(ContainSubstring("eice-existing"))
+
+		// Revoking the access leaves the endpoint in place
+		delete(nodeClaim.Annotations, v1beta1.AnnotationDebug)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(awsEnv.EC2API.DeleteInstanceConnectEndpointBehavior.Calls()).To(Equal(0))
+		Expect(endpoints()).To(HaveLen(1))
+	})
+	It("should not grant access before the NodeClaim is launched", func() {
+		nodeClaim.Status.ProviderID = ""
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebugExpiration))
+		Expect(awsEnv.SSMAPI.DescribeInstanceInformationBehavior.Calls()).To(Equal(0))
+	})
+	It("should revoke access when the annotation is removed", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(endpoints()).To(HaveLen(1))
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		delete(nodeClaim.Annotations, v1beta1.AnnotationDebug)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(endpoints()).To(BeEmpty())
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebugExpiration))
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebugEndpoint))
+	})
+	It("should revoke access and remove the annotation once the access expires", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(endpoints()).To(HaveLen(1))
+
+		fakeClock.Step(30 * time.Minute)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("<=", 30*time.Minute))
+		Expect(endpoints()).To(HaveLen(1))
+
+		fakeClock.Step(31 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(endpoints()).To(BeEmpty())
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebug))
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationDebugExpiration))
+		Expect(recorder.Calls("DebugAccessExpired")).To(Equal(1))
+	})
+	It("should not delete an endpoint that another NodeClaim is using", func() {
+		other := coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1beta1.AnnotationDebug:           "true",
+					v1beta1.AnnotationDebugExpiration: fakeClock.Now().Add(time.Hour).Format(time.RFC3339),
+					v1beta1.AnnotationDebugEndpoint:   "eice-shared",
+				},
+			},
+		})
+		awsEnv.EC2API.InstanceConnectEndpoints.Store("eice-shared", &ec2.Ec2InstanceConnectEndpoint{
+			InstanceConnectEndpointId: aws.String("eice-shared"),
+			VpcId:                     aws.String("vpc-test1"),
+			State:                     aws.String(ec2.Ec2InstanceConnectEndpointStateCreateComplete),
+			Tags:                      []*ec2.Tag{{Key: aws.String(v1beta1.TagManagedLaunchTemplate), Value: aws.String(options.FromContext(ctx).ClusterName)}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, other)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationDebugEndpoint, "eice-shared"))
+
+		delete(nodeClaim.Annotations, v1beta1.AnnotationDebug)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(awsEnv.EC2API.DeleteInstanceConnectEndpointBehavior.Calls()).To(Equal(0))
+
+		delete(other.Annotations, v1beta1.AnnotationDebug)
+		ExpectApplied(ctx, env.Client, other)
+		ExpectObjectReconciled(ctx, env.Client, controller, other)
+		Expect(awsEnv.EC2API.DeleteInstanceConnectEndpointBehavior.Calls()).To(Equal(1))
+		Expect(lo.Map(endpoints(), func(e *ec2.Ec2InstanceConnectEndpoint, _ int) string {
+			return aws.StringValue(e.InstanceConnectEndpointId)
+		})).ToNot(ContainElement("eice-shared"))
+	})
+})
@@ -19,10 +19,15 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/operatorpkg/object"
 	"github.com/awslabs/operatorpkg/singleton"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -33,21 +38,30 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
 
-	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 type Controller struct {
-	kubeClient      client.Client
-	cloudProvider   cloudprovider.CloudProvider
-	successfulCount uint64 // keeps track of successful reconciles for more aggressive requeueing near the start of the controller
+	kubeClient       client.Client
+	cloudProvider    cloudprovider.CloudProvider
+	instanceProvider instance.Provider
+	successfulCount  uint64 // keeps track of successful reconciles for more aggressive requeueing near the start of the controller
 }
 
-func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider) *Controller {
 	return &Controller{
-		kubeClient:      kubeClient,
-		cloudProvider:   cloudProvider,
-		successfulCount: 0,
+		kubeClient:       kubeClient,
+		cloudProvider:    cloudProvider,
+		instanceProvider: instanceProvider,
+		successfulCount:  0,
 	}
 }
 
@@ -61,10 +75,10 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("listing cloudprovider machines, %w", err)
 	}
-	managedRetrieved := lo.Filter(retrieved, func(nc *v1beta1.NodeClaim, _ int) bool {
-		return nc.Annotations[v1beta1.ManagedByAnnotationKey] != "" && nc.DeletionTimestamp.IsZero()
+	managedRetrieved := lo.Filter(retrieved, func(nc *corev1beta1.NodeClaim, _ int) bool {
+		return nc.Annotations[corev1beta1.ManagedByAnnotationKey] != "" && nc.DeletionTimestamp.IsZero()
 	})
-	nodeClaimList := &v1beta1.NodeClaimList{}
+	nodeClaimList := &corev1beta1.NodeClaimList{}
 	if err = c.kubeClient.List(ctx, nodeClaimList); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -72,24 +86,35 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err = c.kubeClient.List(ctx, nodeList); err != nil {
 		return reconcile.Result{}, err
 	}
-	resolvedProviderIDs := sets.New[string](lo.FilterMap(nodeClaimList.Items, func(n v1beta1.NodeClaim, _ int) (string, bool) {
+	resolvedProviderIDs := sets.New[string](lo.FilterMap(nodeClaimList.Items, func(n corev1beta1.NodeClaim, _ int) (string, bool) {
 		return n.Status.ProviderID, n.Status.ProviderID != ""
 	})...)
+	// NodeClaims that adopt an instance don't have its provider ID until they're launched
+	resolvedProviderIDs.Insert(lo.FilterMap(nodeClaimList.Items, func(n corev1beta1.NodeClaim, _ int) (string, bool) {
+		return n.Annotations[v1beta1.AnnotationAdoptedProviderID], n.Annotations[v1beta1.AnnotationAdoptedProviderID] != ""
+	})...)
 	errs := make([]error, len(retrieved))
 	workqueue.ParallelizeUntil(ctx, 100, len(managedRetrieved), func(i int) {
 		if !resolvedProviderIDs.Has(managedRetrieved[i].Status.ProviderID) &&
 			time.Since(managedRetrieved[i].CreationTimestamp.Time) > time.Second*30 {
-			errs[i] = c.garbageCollect(ctx, managedRetrieved[i], nodeList)
+			if options.FromContext(ctx).AdoptUnmanagedInstances {
+				errs[i] = c.adopt(ctx, managedRetrieved[i], nodeList)
+			} else {
+				errs[i] = c.garbageCollect(ctx, managedRetrieved[i], nodeList)
+			}
 		}
 	})
 	if err = multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, err
 	}
+	if err = c.reapStuckInstances(ctx, nodeClaimList); err != nil {
+		return reconcile.Result{}, err
+	}
 	c.successfulCount++
 	return reconcile.Result{RequeueAfter: lo.Ternary(c.successfulCount <= 20, time.Second*10, time.Minute*2)}, nil
 }
 
-func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *v1beta1.NodeClaim, nodeList *v1.NodeList) error {
+func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeList *v1.NodeList) error {
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
 	if err := c.cloudProvider.Delete(ctx, nodeClaim); err != nil {
 		return cloudprovider.IgnoreNodeClaimNotFoundError(err)
@@ -108,9 +133,115 @@ func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *v1beta1.Node
 	return nil
 }
 
+// reapStuckInstances terminates the instances that have been pending for longer than the stuck-instance-deadline, and
+// deletes their NodeClaims so that they're replaced. Instances can be stuck pending indefinitely, e.g. when EC2 can't
+// place them, which isn't caught until the NodeClaim's registration TTL expires.
+func (c *Controller) reapStuckInstances(ctx context.Context, nodeClaimList *corev1beta1.NodeClaimList) error {
+	deadline := options.FromContext(ctx).StuckInstanceDeadline
+	if deadline <= 0 {
+		return nil
+	}
+	instances, err := c.instanceProvider.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing instances, %w", err)
+	}
+	stuck := lo.Filter(instances, func(i *instance.Instance, _ int) bool {
+		return i.State == ec2.InstanceStateNamePending && time.Since(i.LaunchTime) > deadline
+	})
+	if len(stuck) == 0 {
+		return nil
+	}
+	nodeClaims := lo.SliceToMap(lo.Filter(nodeClaimList.Items, func(n corev1beta1.NodeClaim, _ int) bool {
+		return n.Status.ProviderID != ""
+	}), func(n corev1beta1.NodeClaim) (string, corev1beta1.NodeClaim) {
+		id, _ := utils.ParseInstanceID(n.Status.ProviderID)
+		return id, n
+	})
+	errs := make([]error, len(stuck))
+	workqueue.ParallelizeUntil(ctx, 100, len(stuck), func(i int) {
+		nodeClaim, ok := nodeClaims[stuck[i].ID]
+		errs[i] = c.reapStuckInstance(ctx, stuck[i], lo.Ternary(ok, &nodeClaim, nil))
+	})
+	return multierr.Combine(errs...)
+}
+
+func (c *Controller) reapStuckInstance(ctx context.Context, i *instance.Instance, nodeClaim *corev1beta1.NodeClaim) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instance", i.ID, "launch-time", i.LaunchTime))
+	if err := c.instanceProvider.Delete(ctx, i.ID); cloudprovider.IgnoreNodeClaimNotFoundError(err) != nil {
+		return fmt.Errorf("terminating stuck instance, %w", err)
+	}
+	instance.RecordStuckInstanceTerminated(instance.LaunchPhaseInstanceRunning, i.Tags[corev1beta1.NodePoolLabelKey], i.CapacityType)
+	log.FromContext(ctx).Info("terminated instance stuck pending")
+	if nodeClaim == nil || !nodeClaim.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	// Deleting the NodeClaim lets the provisioner launch a replacement for the pods that were scheduled to it
+	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).WithValues("NodeClaim", klog.KRef("", nodeClaim.Name)).Info("deleted nodeclaim of stuck instance")
+	return nil
+}
+
+// adopt creates a NodeClaim for the instance from the NodePool that launched it. Instances whose NodePool no longer
+// exists are garbage collected instead.
+func (c *Controller) adopt(ctx context.Context, retrieved *corev1beta1.NodeClaim, nodeList *v1.NodeList) error {
+	nodePool := &corev1beta1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: retrieved.Labels[corev1beta1.NodePoolLabelKey]}, nodePool); err != nil {
+		if errors.IsNotFound(err) {
+			return c.garbageCollect(ctx, retrieved, nodeList)
+		}
+		return fmt.Errorf("getting nodepool, %w", err)
+	}
+	if !nodePool.DeletionTimestamp.IsZero() {
+		return c.garbageCollect(ctx, retrieved, nodeList)
+	}
+	nodeClaim := adoptedNodeClaim(nodePool, retrieved)
+	if err := c.kubeClient.Create(ctx, nodeClaim); err != nil {
+		return fmt.Errorf("creating nodeclaim, %w", err)
+	}
+	log.FromContext(ctx).WithValues("provider-id", retrieved.Status.ProviderID, "NodeClaim", klog.KRef("", nodeClaim.Name)).Info("adopting cloudprovider instance")
+	return nil
+}
+
+// adoptedNodeClaim reconstructs the NodeClaim of an instance from the template of its NodePool, with the instance
+// type, zone and capacity type of the instance in place of the requirements of the template
+func adoptedNodeClaim(nodePool *corev1beta1.NodePool, retrieved *corev1beta1.NodeClaim) *corev1beta1.NodeClaim {
+	instanceLabels := lo.PickByKeys(retrieved.Labels, []string{v1.LabelInstanceTypeStable, v1.LabelTopologyZone, corev1beta1.CapacityTypeLabelKey})
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(lo.Reject(nodePool.Spec.Template.Spec.Requirements, func(r corev1beta1.NodeSelectorRequirementWithMinValues, _ int) bool {
+		_, ok := instanceLabels[r.Key]
+		return ok
+	})...)
+	requirements.Add(scheduling.NewLabelRequirements(instanceLabels).Values()...)
+
+	nodeClaim := &corev1beta1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", nodePool.Name),
+			Annotations: lo.Assign(nodePool.Spec.Template.Annotations, map[string]string{
+				corev1beta1.NodePoolHashAnnotationKey:        nodePool.Hash(),
+				corev1beta1.NodePoolHashVersionAnnotationKey: corev1beta1.NodePoolHashVersion,
+				v1beta1.AnnotationAdoptedProviderID:          retrieved.Status.ProviderID,
+			}),
+			Labels: lo.Assign(nodePool.Spec.Template.Labels, retrieved.Labels, map[string]string{corev1beta1.NodePoolLabelKey: nodePool.Name}),
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         object.GVK(nodePool).GroupVersion().String(),
+					Kind:               object.GVK(nodePool).Kind,
+					Name:               nodePool.Name,
+					UID:                nodePool.UID,
+					BlockOwnerDeletion: lo.ToPtr(true),
+				},
+			},
+		},
+		Spec: *nodePool.Spec.Template.Spec.DeepCopy(),
+	}
+	nodeClaim.Spec.Requirements = requirements.NodeSelectorRequirements()
+	return nodeClaim
+}
+
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("nodeclaim.garbagecollection").
 		WatchesRawSource(singleton.Source()).
-		Complete(singleton.AsReconciler(c))
+		Complete(tracing.Reconciler("nodeclaim.garbagecollection", singleton.AsReconciler(c)))
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -62,8 +63,8 @@ var _ = BeforeSuite(func() {
 	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
-	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.LaunchRamps, awsEnv.TerminationHookProvider, clock.RealClock{})
+	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider, awsEnv.InstanceProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -71,19 +72,21 @@ var _ = AfterSuite(func() {
 })
 
 var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
 	awsEnv.Reset()
 })
 
 var _ = Describe("GarbageCollection", func() {
 	var instance *ec2.Instance
 	var nodeClass *v1beta1.EC2NodeClass
+	var nodePool *corev1beta1.NodePool
 	var providerID string
 
 	BeforeEach(func() {
 		instanceID := fake.InstanceID()
 		providerID = fake.ProviderID(instanceID)
 		nodeClass = test.EC2NodeClass()
-		nodePool := coretest.NodePool(corev1beta1.NodePool{
+		nodePool = coretest.NodePool(corev1beta1.NodePool{
 			Spec: corev1beta1.NodePoolSpec{
 				Template: corev1beta1.NodeClaimTemplate{
 					Spec: corev1beta1.NodeClaimSpec{
@@ -390,4 +393,101 @@ var _ = Describe("GarbageCollection", func() {
 		}
 		wg.Wait()
 	})
+	Context("Stuck Instances", func() {
+		var nodeClaim *corev1beta1.NodeClaim
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StuckInstanceDeadline: lo.ToPtr(10 * time.Minute)}))
+			instance.State.Name = aws.String(ec2.InstanceStateNamePending)
+			nodeClaim = coretest.NodeClaim(corev1beta1.NodeClaim{
+				Spec: corev1beta1.NodeClaimSpec{
+					NodeClassRef: &corev1beta1.NodeClassReference{
+						Name: nodeClass.Name,
+					},
+				},
+				Status: corev1beta1.NodeClaimStatus{
+					ProviderID: providerID,
+				},
+			})
+		})
+		It("should terminate an instance that is pending for longer than the stuck-instance-deadline and delete its NodeClaim", func() {
+			instance.LaunchTime = aws.Time(time.Now().Add(-15 * time.Minute))
+			awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, err := cloudProvider.Get(ctx, providerID)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should not terminate an instance that is pending for less than the stuck-instance-deadline", func() {
+			instance.LaunchTime = aws.Time(time.Now().Add(-5 * time.Minute))
+			awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, err := cloudProvider.Get(ctx, providerID)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectExists(ctx, env.Client, nodeClaim)
+		})
+		It("should not terminate a running instance that was launched before the stuck-instance-deadline", func() {
+			instance.State.Name = aws.String(ec2.InstanceStateNameRunning)
+			instance.LaunchTime = aws.Time(time.Now().Add(-15 * time.Minute))
+			awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, err := cloudProvider.Get(ctx, providerID)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectExists(ctx, env.Client, nodeClaim)
+		})
+		It("should not terminate stuck instances when the stuck-instance-deadline isn't set", func() {
+			ctx = options.ToContext(ctx, test.Options())
+			instance.LaunchTime = aws.Time(time.Now().Add(-time.Hour))
+			awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, err := cloudProvider.Get(ctx, providerID)
+			Expect(err).ToNot(HaveOccurred())
+			ExpectExists(ctx, env.Client, nodeClaim)
+		})
+	})
+	Context("Adoption", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AdoptUnmanagedInstances: lo.ToPtr(true)}))
+			// Launch time was 1m ago
+			instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
+			awsEnv.EC2API.Instances.Store(aws.StringValue(instance.InstanceId), instance)
+		})
+		It("should create a NodeClaim for an instance without one rather than deleting it", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, err := cloudProvider.Get(ctx, providerID)
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeClaims := ExpectNodeClaims(ctx, env.Client)
+			Expect(nodeClaims).To(HaveLen(1))
+			Expect(nodeClaims[0].Annotations).To(HaveKeyWithValue(v1beta1.AnnotationAdoptedProviderID, providerID))
+			Expect(nodeClaims[0].Labels).To(HaveKeyWithValue(corev1beta1.NodePoolLabelKey, nodePool.Name))
+			Expect(nodeClaims[0].OwnerReferences).To(HaveLen(1))
+			Expect(nodeClaims[0].OwnerReferences[0].Name).To(Equal(nodePool.Name))
+			Expect(nodeClaims[0].Spec.NodeClassRef.Name).To(Equal(nodeClass.Name))
+			Expect(nodeClaims[0].Spec.Requirements).To(ContainElement(corev1beta1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large"}},
+			}))
+		})
+		It("should not adopt an instance twice while its NodeClaim is launching", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			Expect(ExpectNodeClaims(ctx, env.Client)).To(HaveLen(1))
+		})
+		It("should delete an instance whose NodePool doesn't exist", func() {
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, err := cloudProvider.Get(ctx, providerID)
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			Expect(ExpectNodeClaims(ctx, env.Client)).To(HaveLen(0))
+		})
+	})
 })
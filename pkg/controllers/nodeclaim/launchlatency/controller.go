@@ -0,0 +1,129 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchlatency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/patrickmn/go-cache"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// PollInterval is how often the instance of a launched NodeClaim is described until it's running
+const PollInterval = 5 * time.Second
+
+// Controller records the phases of node launches that happen after the instance is created: until DescribeInstances
+// reports the instance as running, until the node registers, and until the node is initialized. The phases before,
+// resolving the launch templates and the CreateFleet call, are recorded by the instance provider. Only launches that
+// happened since the controller started are recorded, so that a restart doesn't record the launches of existing
+// NodeClaims again.
+type Controller struct {
+	clk              clock.Clock
+	instanceProvider instance.Provider
+	startTime        time.Time
+	// observed holds the phases that have been recorded for each NodeClaim, by "<uid>/<phase>"
+	observed *cache.Cache
+}
+
+func NewController(clk clock.Clock, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		clk:              clk,
+		instanceProvider: instanceProvider,
+		startTime:        clk.Now(),
+		observed:         cache.New(time.Hour, time.Minute),
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.launchlatency")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	launched := nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeLaunched)
+	if !launched.IsTrue() || launched.LastTransitionTime.Time.Before(c.startTime) {
+		return reconcile.Result{}, nil
+	}
+	registered := nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeRegistered)
+	if registered.IsTrue() {
+		c.observe(nodeClaim, instance.LaunchPhaseRegistration, registered.LastTransitionTime.Sub(launched.LastTransitionTime.Time))
+		if initialized := nodeClaim.StatusConditions().Get(corev1beta1.ConditionTypeInitialized); initialized.IsTrue() {
+			c.observe(nodeClaim, instance.LaunchPhaseInitialization, initialized.LastTransitionTime.Sub(registered.LastTransitionTime.Time))
+		}
+		return reconcile.Result{}, nil
+	}
+	if _, ok := c.observed.Get(key(nodeClaim, instance.LaunchPhaseInstanceRunning)); ok {
+		return reconcile.Result{}, nil
+	}
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	inst, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		// Instances may not be described right after they're created
+		if cloudprovider.IsNodeClaimNotFoundError(err) {
+			return reconcile.Result{RequeueAfter: PollInterval}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("getting instance, %w", err)
+	}
+	if inst.State != ec2.InstanceStateNameRunning {
+		return reconcile.Result{RequeueAfter: PollInterval}, nil
+	}
+	c.observe(nodeClaim, instance.LaunchPhaseInstanceRunning, c.clk.Since(launched.LastTransitionTime.Time))
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.launchlatency").
+		For(&corev1beta1.NodeClaim{}).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodeclaim.launchlatency", reconcile.AsReconciler(m.GetClient(), c)))
+}
+
+// observe records the duration of the phase of the NodeClaim's launch, unless it's already been recorded
+func (c *Controller) observe(nodeClaim *corev1beta1.NodeClaim, phase string, duration time.Duration) {
+	if err := c.observed.Add(key(nodeClaim, phase), struct{}{}, cache.DefaultExpiration); err != nil {
+		return
+	}
+	instance.ObserveLaunchPhase(phase, nodeClaim.Labels[corev1beta1.NodePoolLabelKey], nodeClaim.Labels[corev1beta1.CapacityTypeLabelKey], duration)
+}
+
+func key(nodeClaim *corev1beta1.NodeClaim, phase string) string {
+	return fmt.Sprintf("%s/%s", nodeClaim.UID, phase)
+}
@@ -0,0 +1,143 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchlatency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/launchlatency"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *launchlatency.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchLatency")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+	fakeClock.SetTime(time.Now().Add(-time.Minute))
+	controller = launchlatency.NewController(fakeClock, awsEnv.InstanceProvider)
+	fakeClock.SetTime(time.Now())
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("LaunchLatency", func() {
+	var ec2Instance *ec2.Instance
+	var nodeClaim *corev1beta1.NodeClaim
+	BeforeEach(func() {
+		ec2Instance = &ec2.Instance{
+			State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			InstanceId:   aws.String(fake.InstanceID()),
+			InstanceType: aws.String("m5.large"),
+			Placement:    &ec2.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+		}
+		awsEnv.EC2API.Instances.Store(*ec2Instance.InstanceId, ec2Instance)
+		nodeClaim = coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					corev1beta1.NodePoolLabelKey:     coretest.RandomName(),
+					corev1beta1.CapacityTypeLabelKey: corev1beta1.CapacityTypeOnDemand,
+				},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+			},
+		})
+		nodeClaim.StatusConditions().SetTrue(corev1beta1.ConditionTypeLaunched)
+	})
+	samples := func(phase string) uint64 {
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_launch_phase_duration_seconds", map[string]string{
+			"phase":         phase,
+			"nodepool":      nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+			"capacity_type": corev1beta1.CapacityTypeOnDemand,
+		})
+		if !ok {
+			return 0
+		}
+		return metric.GetHistogram().GetSampleCount()
+	}
+	It("should record when the instance of a launched NodeClaim is running", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(samples(instance.LaunchPhaseInstanceRunning)).To(BeNumerically("==", 1))
+	})
+	It("should poll until the instance of a launched NodeClaim is running", func() {
+		ec2Instance.State.Name = aws.String(ec2.InstanceStateNamePending)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(launchlatency.PollInterval))
+		Expect(samples(instance.LaunchPhaseInstanceRunning)).To(BeNumerically("==", 0))
+	})
+	It("should record the registration and initialization of a launched NodeClaim once", func() {
+		nodeClaim.StatusConditions().SetTrue(corev1beta1.ConditionTypeRegistered)
+		nodeClaim.StatusConditions().SetTrue(corev1beta1.ConditionTypeInitialized)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(samples(instance.LaunchPhaseRegistration)).To(BeNumerically("==", 1))
+		Expect(samples(instance.LaunchPhaseInitialization)).To(BeNumerically("==", 1))
+	})
+	It("should not record NodeClaims that were launched before the controller started", func() {
+		controller = launchlatency.NewController(clock.NewFakeClock(time.Now().Add(time.Hour)), awsEnv.InstanceProvider)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(samples(instance.LaunchPhaseInstanceRunning)).To(BeNumerically("==", 0))
+	})
+})
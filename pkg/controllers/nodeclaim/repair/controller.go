@@ -0,0 +1,221 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repair
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	nodeclaimutil "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// NotReadyToleration is how long a node has to be NotReady before it's repaired, so that nodes aren't rebooted for
+// short blips in their heartbeats
+const NotReadyToleration = 10 * time.Minute
+
+// MaxNotReadyShare is the share of the nodes of a NodePool, or of the cluster for nodes without one, that can be
+// NotReady for them to be repaired. When more are NotReady, they're likely NotReady for a shared reason, like a network
+// or control plane outage, that rebooting and replacing nodes doesn't fix but makes worse.
+const MaxNotReadyShare = 0.2
+
+// Controller repairs the nodes of NodeClaims that stay NotReady. The instance of the node is rebooted first, since a
+// reboot recovers a kubelet that crashed or an instance that hung without the churn of replacing the node, and the node
+// is given the reboot timeout to become Ready again. Once the reboot attempts are used up, the NodeClaim is deleted so
+// that its pods are rescheduled and the node is replaced. The reboots are recorded on the NodeClaim, and are forgotten
+// once the node is Ready again.
+type Controller struct {
+	kubeClient client.Client
+	clk        clock.Clock
+	recorder   events.Recorder
+	ec2api     ec2iface.EC2API
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock, recorder events.Recorder, ec2api ec2iface.EC2API) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+		clk:        clk,
+		recorder:   recorder,
+		ec2api:     ec2api,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.repair")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Status.ProviderID == "" || nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	node := &v1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	ready := nodeutils.GetCondition(node, v1.NodeReady)
+	// Nodes whose kubelet never reported are handled by the registration timeout of the NodeClaim
+	if ready.Type == "" {
+		return reconcile.Result{}, nil
+	}
+	if ready.Status == v1.ConditionTrue {
+		return reconcile.Result{}, c.recovered(ctx, nodeClaim, node)
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID, "Node", client.ObjectKeyFromObject(node)))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+
+	reboots, rebootTime := rebootsOf(nodeClaim)
+	// A rebooted node is given the reboot timeout to recover, rather than the toleration from when it became NotReady
+	if rebootTime.IsZero() {
+		if wait := NotReadyToleration - c.clk.Since(ready.LastTransitionTime.Time); wait > 0 {
+			return reconcile.Result{RequeueAfter: wait}, nil
+		}
+	} else if wait := options.FromContext(ctx).NodeRepairRebootTimeout - c.clk.Since(rebootTime); wait > 0 {
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+	nodePool := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	notReady, total, err := c.notReadyNodes(ctx, nodePool)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if float64(notReady) > math.Ceil(MaxNotReadyShare*float64(total)) {
+		log.FromContext(ctx).WithValues("not-ready", notReady, "nodes", total).V(1).Info("skipping repair, too many nodes are NotReady")
+		c.recorder.Publish(SkippedEvent(nodeClaim, node, nodePool, notReady, total))
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	if reboots < options.FromContext(ctx).NodeRepairRebootAttempts {
+		if err = c.reboot(ctx, nodeClaim, node, id, reboots+1); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: options.FromContext(ctx).NodeRepairRebootTimeout}, nil
+	}
+	return reconcile.Result{}, c.replace(ctx, nodeClaim, node, reboots)
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.repair").
+		For(&corev1beta1.NodeClaim{}).
+		Watches(&v1.Node{}, nodeclaimutil.NodeEventHandler(m.GetClient())).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodeclaim.repair", reconcile.AsReconciler(m.GetClient(), c)))
+}
+
+// notReadyNodes returns the number of NotReady nodes of the NodePool, or of the cluster if the NodePool is empty, and
+// the number of nodes that they're a share of
+func (c *Controller) notReadyNodes(ctx context.Context, nodePool string) (int, int, error) {
+	nodes := &v1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodes, lo.Ternary(nodePool != "", client.MatchingLabels{corev1beta1.NodePoolLabelKey: nodePool}, client.MatchingLabels{})); err != nil {
+		return 0, 0, fmt.Errorf("listing nodes, %w", err)
+	}
+	notReady := lo.CountBy(nodes.Items, func(node v1.Node) bool {
+		ready := nodeutils.GetCondition(&node, v1.NodeReady)
+		return ready.Type != "" && ready.Status != v1.ConditionTrue
+	})
+	return notReady, len(nodes.Items), nil
+}
+
+// reboot reboots the instance of the NodeClaim, and records the attempt on the NodeClaim
+func (c *Controller) reboot(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, node *v1.Node, id string, attempt int) error {
+	if _, err := c.ec2api.RebootInstancesWithContext(ctx, &ec2.RebootInstancesInput{InstanceIds: aws.StringSlice([]string{id})}); err != nil {
+		// The instance is gone, so the NodeClaim is garbage collected rather than repaired
+		if awserrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("rebooting instance, %w", err)
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1beta1.AnnotationRepairReboots:    strconv.Itoa(attempt),
+		v1beta1.AnnotationRepairRebootTime: c.clk.Now().UTC().Format(time.RFC3339),
+	})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching nodeclaim annotations, %w", err))
+	}
+	log.FromContext(ctx).WithValues("attempt", attempt).Info("rebooted instance of NotReady node")
+	c.recorder.Publish(RebootedEvent(nodeClaim, node, attempt))
+	return nil
+}
+
+// replace deletes the NodeClaim, so that its node is drained and replaced. NodeClaims with termination protection are
+// left alone, since their instance can't be terminated.
+func (c *Controller) replace(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, node *v1.Node, reboots int) error {
+	if nodeClaim.Annotations[v1beta1.AnnotationTerminationProtection] == v1beta1.TerminationProtectionEnabled {
+		return nil
+	}
+	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("deleting nodeclaim, %w", err))
+	}
+	log.FromContext(ctx).WithValues("reboots", reboots).Info("replacing NotReady node")
+	c.recorder.Publish(ReplacedEvent(nodeClaim, node, reboots))
+	return nil
+}
+
+// recovered forgets the reboots of a NodeClaim whose node is Ready again
+func (c *Controller) recovered(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, node *v1.Node) error {
+	reboots, rebootTime := rebootsOf(nodeClaim)
+	if rebootTime.IsZero() && reboots == 0 {
+		return nil
+	}
+	stored := nodeClaim.DeepCopy()
+	delete(nodeClaim.Annotations, v1beta1.AnnotationRepairReboots)
+	delete(nodeClaim.Annotations, v1beta1.AnnotationRepairRebootTime)
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching nodeclaim annotations, %w", err))
+	}
+	log.FromContext(ctx).WithValues("Node", client.ObjectKeyFromObject(node), "reboots", reboots).Info("rebooted node recovered")
+	c.recorder.Publish(RecoveredEvent(nodeClaim, node, reboots))
+	return nil
+}
+
+// rebootsOf returns the number of times that the instance of the NodeClaim was rebooted since its node was last Ready,
+// and the time of the last reboot. Annotations that can't be parsed are ignored.
+func rebootsOf(nodeClaim *corev1beta1.NodeClaim) (int, time.Time) {
+	reboots, _ := strconv.Atoi(nodeClaim.Annotations[v1beta1.AnnotationRepairReboots])
+	rebootTime, _ := time.Parse(time.RFC3339, nodeClaim.Annotations[v1beta1.AnnotationRepairRebootTime])
+	return reboots, rebootTime
+}
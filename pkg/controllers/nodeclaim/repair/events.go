@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repair
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func RebootedEvent(nodeClaim *corev1beta1.NodeClaim, node *v1.Node, attempt int) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "NodeRepairRebooted",
+		Message:        fmt.Sprintf("Rebooted the instance of NotReady node %s (attempt %d)", node.Name, attempt),
+		DedupeValues:   []string{string(nodeClaim.UID), strconv.Itoa(attempt)},
+	}
+}
+
+func SkippedEvent(nodeClaim *corev1beta1.NodeClaim, node *v1.Node, nodePool string, notReady, total int) events.Event {
+	scope := lo.Ternary(nodePool != "", fmt.Sprintf("nodepool %s", nodePool), "the cluster")
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "NodeRepairSkipped",
+		Message:        fmt.Sprintf("Not repairing NotReady node %s, %d of the %d nodes of %s are NotReady", node.Name, notReady, total, scope),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func RecoveredEvent(nodeClaim *corev1beta1.NodeClaim, node *v1.Node, reboots int) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeNormal,
+		Reason:         "NodeRepairRecovered",
+		Message:        fmt.Sprintf("Node %s is Ready again after %d reboot(s)", node.Name, reboots),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func ReplacedEvent(nodeClaim *corev1beta1.NodeClaim, node *v1.Node, reboots int) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           v1.EventTypeWarning,
+		Reason:         "NodeRepairReplaced",
+		Message:        fmt.Sprintf("Replacing node %s, which is still NotReady after %d reboot(s)", node.Name, reboots),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repair_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/repair"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var recorder *coretest.EventRecorder
+var controller *repair.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Repair")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeRepair: lo.ToPtr(true)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	recorder = coretest.NewEventRecorder()
+	controller = repair.NewController(env.Client, fakeClock, recorder, awsEnv.EC2API)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeRepair: lo.ToPtr(true)}))
+	awsEnv.Reset()
+	recorder.Reset()
+	fakeClock.SetTime(time.Now())
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Repair", func() {
+	var ec2Instance *ec2.Instance
+	var nodeClaim *corev1beta1.NodeClaim
+	var node *v1.Node
+	BeforeEach(func() {
+		ec2Instance = &ec2.Instance{
+			State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			InstanceId:   aws.String(fake.InstanceID()),
+			InstanceType: aws.String("m5.large"),
+		}
+		awsEnv.EC2API.Instances.Store(*ec2Instance.InstanceId, ec2Instance)
+		node = coretest.Node(coretest.NodeOptions{ProviderID: fake.ProviderID(*ec2Instance.InstanceId)})
+		nodeClaim = coretest.NodeClaim(corev1beta1.NodeClaim{
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+				NodeName:   node.Name,
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectMakeNodesNotReady(ctx, env.Client, node)
+	})
+	It("should wait for the toleration before repairing a NotReady node", func() {
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", repair.NotReadyToleration, time.Minute))
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+	})
+	It("should reboot the instance of a node that stays NotReady", func() {
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(options.FromContext(ctx).NodeRepairRebootTimeout))
+
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+		input := awsEnv.EC2API.RebootInstancesBehavior.CalledWithInput.Pop()
+		Expect(aws.StringValueSlice(input.InstanceIds)).To(ConsistOf(*ec2Instance.InstanceId))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationRepairReboots, "1"))
+		Expect(nodeClaim.Annotations).To(HaveKey(v1beta1.AnnotationRepairRebootTime))
+		Expect(recorder.Calls("NodeRepairRebooted")).To(Equal(1))
+	})
+	It("should forget the reboots once the node is Ready again", func() {
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationRepairReboots))
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationRepairRebootTime))
+		Expect(recorder.Calls("NodeRepairRecovered")).To(Equal(1))
+	})
+	It("should replace a node that a reboot doesn't recover", func() {
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+
+		// the node is given the reboot timeout to recover
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		ExpectExists(ctx, env.Client, nodeClaim)
+
+		fakeClock.Step(options.FromContext(ctx).NodeRepairRebootTimeout)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(1))
+		Expect(recorder.Calls("NodeRepairReplaced")).To(Equal(1))
+	})
+	It("should replace a NotReady node without rebooting it when reboots are disabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{NodeRepair: lo.ToPtr(true), NodeRepairRebootAttempts: lo.ToPtr(0)}))
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+	})
+	It("should not replace the node of a NodeClaim with termination protection", func() {
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+			v1beta1.AnnotationTerminationProtection: v1beta1.TerminationProtectionEnabled,
+			v1beta1.AnnotationRepairReboots:         "1",
+			v1beta1.AnnotationRepairRebootTime:      fakeClock.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(recorder.Calls("NodeRepairReplaced")).To(Equal(0))
+	})
+	It("should not repair nodes when too many nodes of the cluster are NotReady", func() {
+		nodes := lo.Times(4, func(_ int) *v1.Node { return coretest.Node() })
+		ExpectApplied(ctx, env.Client, lo.Map(nodes, func(n *v1.Node, _ int) client.Object { return n })...)
+		ExpectMakeNodesNotReady(ctx, env.Client, nodes...)
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+		Expect(recorder.Calls("NodeRepairSkipped")).To(Equal(1))
+	})
+	It("should only count the NotReady nodes of the NodeClaim's NodePool", func() {
+		nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{corev1beta1.NodePoolLabelKey: "default"})
+		node.Labels = lo.Assign(node.Labels, map[string]string{corev1beta1.NodePoolLabelKey: "default"})
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectMakeNodesNotReady(ctx, env.Client, node)
+		nodes := lo.Times(4, func(_ int) *v1.Node {
+			return coretest.Node(coretest.NodeOptions{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1beta1.NodePoolLabelKey: "other"}}})
+		})
+		ExpectApplied(ctx, env.Client, lo.Map(nodes, func(n *v1.Node, _ int) client.Object { return n })...)
+		ExpectMakeNodesNotReady(ctx, env.Client, nodes...)
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(1))
+		Expect(recorder.Calls("NodeRepairSkipped")).To(Equal(0))
+	})
+	It("should not repair Ready nodes", func() {
+		ExpectMakeNodesReady(ctx, env.Client, node)
+		node = ExpectExists(ctx, env.Client, node)
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(fakeClock.Now().Add(-time.Hour))
+		ExpectApplied(ctx, env.Client, node)
+		fakeClock.Step(repair.NotReadyToleration + time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+	})
+})
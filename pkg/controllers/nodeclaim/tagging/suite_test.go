@@ -31,7 +31,6 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/tagging"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
-	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -210,7 +209,7 @@ var _ = Describe("TaggingController", func() {
 				v1beta1.TagName:      nodeClaim.Status.NodeName,
 				v1beta1.TagNodeClaim: nodeClaim.Name,
 			}
-			instanceTags := instance.NewInstance(ec2Instance).Tags
+			instanceTags := lo.SliceToMap(ec2Instance.Tags, func(t *ec2.Tag) (string, string) { return *t.Key, *t.Value })
 			for tag, value := range expectedTags {
 				if lo.Contains(customTags, tag) {
 					value = "custom-tag"
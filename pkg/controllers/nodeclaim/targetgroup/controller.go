@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package targetgroup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/targetgroup"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Controller deregisters the instances of terminating NodeClaims from load balancer target groups so that connections
+// are drained at the load balancer while the node's pods are being drained. The deadline that it records on the
+// NodeClaim is honored by the CloudProvider, which won't terminate the instance until the deregistration delay elapses.
+type Controller struct {
+	kubeClient          client.Client
+	clk                 clock.Clock
+	targetGroupProvider targetgroup.Provider
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock, targetGroupProvider targetgroup.Provider) *Controller {
+	return &Controller{
+		kubeClient:          kubeClient,
+		clk:                 clk,
+		targetGroupProvider: targetGroupProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.targetgroup")
+
+	if !isDeregistrable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	if err = c.excludeNode(ctx, nodeClaim); err != nil {
+		return reconcile.Result{}, fmt.Errorf("excluding node from load balancers, %w", err)
+	}
+	delay, err := c.targetGroupProvider.Deregister(ctx, id)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("deregistering instance from target groups, %w", err)
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1beta1.AnnotationTargetGroupDeregistrationDeadline: c.clk.Now().Add(delay).Format(time.RFC3339),
+	})
+	if err = c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// excludeNode labels the node so that load balancer controllers don't register it with target groups again after it
+// has been deregistered
+func (c *Controller) excludeNode(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) error {
+	if nodeClaim.Status.NodeName == "" {
+		return nil
+	}
+	node := &v1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if _, ok := node.Labels[v1.LabelNodeExcludeBalancers]; ok {
+		return nil
+	}
+	stored := node.DeepCopy()
+	node.Labels = lo.Assign(node.Labels, map[string]string{v1.LabelNodeExcludeBalancers: "true"})
+	return client.IgnoreNotFound(c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)))
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.targetgroup").
+		For(&corev1beta1.NodeClaim{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isDeregistrable(o.(*corev1beta1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodeclaim.targetgroup", reconcile.AsReconciler(m.GetClient(), c)))
+}
+
+func isDeregistrable(nc *corev1beta1.NodeClaim) bool {
+	// Instance has already been deregistered
+	if _, ok := nc.Annotations[v1beta1.AnnotationTargetGroupDeregistrationDeadline]; ok {
+		return false
+	}
+	// Instance hasn't been launched yet
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// NodeClaim isn't terminating
+	return !nc.DeletionTimestamp.IsZero()
+}
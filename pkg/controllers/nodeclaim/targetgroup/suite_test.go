@@ -0,0 +1,173 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package targetgroup_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/patrickmn/go-cache"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/targetgroup"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	targetgroupprovider "github.com/aws/karpenter-provider-aws/pkg/providers/targetgroup"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var elbv2api *fake.ELBV2API
+var controller *targetgroup.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TargetGroupController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TargetGroupDeregistration: aws.Bool(true)}))
+	fakeClock = clock.NewFakeClock(time.Now())
+	elbv2api = fake.NewELBV2API()
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	elbv2api.Reset()
+	// Recreate the provider so that the target group cache doesn't leak between tests
+	controller = targetgroup.NewController(env.Client, fakeClock, targetgroupprovider.NewDefaultProvider(elbv2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)))
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("TargetGroupController", func() {
+	var instanceID string
+	var nodeClaim *corev1beta1.NodeClaim
+	var node *v1.Node
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		nodeClaim, node = coretest.NodeClaimAndNode(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{"testing/finalizer"},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+			},
+		})
+		nodeClaim.Status.NodeName = node.Name
+		elbv2api.TargetGroups = []*elbv2.TargetGroup{
+			{TargetGroupArn: aws.String("tg-instance-a"), TargetGroupName: aws.String("a"), TargetType: aws.String(elbv2.TargetTypeEnumInstance)},
+			{TargetGroupArn: aws.String("tg-instance-b"), TargetGroupName: aws.String("b"), TargetType: aws.String(elbv2.TargetTypeEnumInstance)},
+			{TargetGroupArn: aws.String("tg-ip"), TargetGroupName: aws.String("ip"), TargetType: aws.String(elbv2.TargetTypeEnumIp)},
+		}
+		elbv2api.Targets = map[string][]string{
+			"tg-instance-a": {instanceID},
+			"tg-instance-b": {instanceID, fake.InstanceID()},
+		}
+		elbv2api.DeregistrationDelays = map[string]string{
+			"tg-instance-a": "30",
+			"tg-instance-b": "120",
+		}
+	})
+
+	It("should deregister the instance from every instance target group when the NodeClaim is terminating", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(elbv2api.DeregisterTargetsBehavior.Calls()).To(Equal(2))
+		Expect(elbv2api.Targets["tg-instance-a"]).To(BeEmpty())
+		Expect(elbv2api.Targets["tg-instance-b"]).To(HaveLen(1))
+		Expect(elbv2api.Targets["tg-instance-b"]).ToNot(ContainElement(instanceID))
+	})
+	It("should record a deadline that honors the longest deregistration delay", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationTargetGroupDeregistrationDeadline, fakeClock.Now().Add(120*time.Second).Format(time.RFC3339)))
+	})
+	It("should use the default deregistration delay when the attribute isn't set", func() {
+		elbv2api.DeregistrationDelays = map[string]string{}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationTargetGroupDeregistrationDeadline, fakeClock.Now().Add(300*time.Second).Format(time.RFC3339)))
+	})
+	It("should record an immediate deadline when the instance isn't registered with any target group", func() {
+		elbv2api.Targets = map[string][]string{}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(elbv2api.DeregisterTargetsBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationTargetGroupDeregistrationDeadline, fakeClock.Now().Format(time.RFC3339)))
+	})
+	It("should exclude the node from load balancers", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelNodeExcludeBalancers, "true"))
+	})
+	It("should not deregister instances of NodeClaims that aren't terminating", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(elbv2api.DescribeTargetGroupsBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationTargetGroupDeregistrationDeadline))
+	})
+	It("should not deregister instances again once a deadline is recorded", func() {
+		nodeClaim.Annotations = map[string]string{v1beta1.AnnotationTargetGroupDeregistrationDeadline: fakeClock.Now().Format(time.RFC3339)}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(elbv2api.DescribeTargetGroupsBehavior.Calls()).To(Equal(0))
+	})
+})
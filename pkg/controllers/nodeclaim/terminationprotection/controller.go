@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminationprotection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutil "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Controller keeps the EC2 termination protection of instances and the do-not-disrupt annotation of their nodes in
+// sync with the termination-protection annotation of their NodeClaims. Instances are launched with termination
+// protection when their NodeClaim is protected, but the annotation can also be added or removed afterwards, e.g. to
+// let Karpenter replace a node that was protected during a critical workload.
+type Controller struct {
+	kubeClient       client.Client
+	instanceProvider instance.Provider
+}
+
+func NewController(kubeClient client.Client, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.terminationprotection")
+
+	if nodeClaim.Status.ProviderID == "" {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	protected := nodeClaim.Annotations[v1beta1.AnnotationTerminationProtection] == v1beta1.TerminationProtectionEnabled
+	// Nodes are protected before the instance so that Karpenter stops disrupting them first, and are unprotected after
+	// the instance so that Karpenter doesn't start to disrupt them until the instance can be terminated
+	if protected {
+		if err = c.syncNode(ctx, nodeClaim, true); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if err = c.syncInstance(ctx, nodeClaim, id, protected); err != nil {
+		return reconcile.Result{}, err
+	}
+	if !protected {
+		if err = c.syncNode(ctx, nodeClaim, false); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// syncInstance enables or disables the termination protection of the instance, and records on the NodeClaim that its
+// instance is protected so that protection is only disabled on instances that Karpenter protected
+func (c *Controller) syncInstance(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, id string, protected bool) error {
+	_, ok := nodeClaim.Annotations[v1beta1.AnnotationTerminationProtected]
+	if ok == protected {
+		return nil
+	}
+	if err := c.instanceProvider.SetTerminationProtection(ctx, id, protected); err != nil {
+		// The instance is gone, so there's nothing left to protect
+		if !cloudprovider.IsNodeClaimNotFoundError(err) {
+			return err
+		}
+		if protected {
+			return nil
+		}
+	}
+	stored := nodeClaim.DeepCopy()
+	if protected {
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationTerminationProtected: "true"})
+	} else {
+		delete(nodeClaim.Annotations, v1beta1.AnnotationTerminationProtected)
+	}
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching nodeclaim annotations, %w", err))
+	}
+	return nil
+}
+
+// syncNode annotates the node of a protected NodeClaim with karpenter.sh/do-not-disrupt, which excludes it from
+// consolidation, drift and expiration, and removes the annotation once the NodeClaim is no longer protected. A
+// do-not-disrupt annotation that was set by someone else is left alone, since they decide when the node can be
+// disrupted.
+func (c *Controller) syncNode(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, protected bool) error {
+	if nodeClaim.Status.NodeName == "" {
+		return nil
+	}
+	node := &v1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	stored := node.DeepCopy()
+	_, managed := node.Annotations[v1beta1.AnnotationTerminationProtected]
+	_, disruptionBlocked := node.Annotations[corev1beta1.DoNotDisruptAnnotationKey]
+	_, minAgeManaged := node.Annotations[v1beta1.AnnotationMinNodeAgeExpiration]
+	switch {
+	case protected && (!disruptionBlocked || minAgeManaged):
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{
+			corev1beta1.DoNotDisruptAnnotationKey:  "true",
+			v1beta1.AnnotationTerminationProtected: "true",
+		})
+	case !protected && managed:
+		delete(node.Annotations, v1beta1.AnnotationTerminationProtected)
+		// The minimum node age controller removes the annotation itself once the node reaches its minimum age
+		if !minAgeManaged {
+			delete(node.Annotations, corev1beta1.DoNotDisruptAnnotationKey)
+		}
+	}
+	if equality.Semantic.DeepEqual(node, stored) {
+		return nil
+	}
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching node annotations, %w", err))
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.terminationprotection").
+		For(&corev1beta1.NodeClaim{}).
+		Watches(&v1.Node{}, nodeclaimutil.NodeEventHandler(m.GetClient())).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodeclaim.terminationprotection", reconcile.AsReconciler(m.GetClient(), c)))
+}
@@ -0,0 +1,196 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminationprotection_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/terminationprotection"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var controller *terminationprotection.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TerminationProtectionController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = terminationprotection.NewController(env.Client, awsEnv.InstanceProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("TerminationProtectionController", func() {
+	var instanceID string
+	var nodeClaim *corev1beta1.NodeClaim
+	var node *v1.Node
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+			InstanceId:   aws.String(instanceID),
+			InstanceType: aws.String("m5.large"),
+			State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: fake.ProviderID(instanceID)})
+		nodeClaim = coretest.NodeClaim(corev1beta1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1beta1.AnnotationTerminationProtection: v1beta1.TerminationProtectionEnabled},
+			},
+			Status: corev1beta1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+				NodeName:   node.Name,
+			},
+		})
+	})
+	protectionCalls := func() []bool {
+		var calls []bool
+		awsEnv.EC2API.ModifyInstanceAttributeBehavior.CalledWithInput.ForEach(func(input *ec2.ModifyInstanceAttributeInput) {
+			Expect(aws.StringValue(input.InstanceId)).To(Equal(instanceID))
+			calls = append(calls, aws.BoolValue(input.DisableApiTermination.Value))
+		})
+		return calls
+	}
+
+	It("should protect the instance and block disruption of the node", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(protectionCalls()).To(Equal([]bool{true}))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationTerminationProtected, "true"))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationTerminationProtected, "true"))
+	})
+	It("should only protect the instance once", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyInstanceAttributeBehavior.Calls()).To(Equal(1))
+	})
+	It("should unprotect the instance and unblock disruption once the annotation is removed", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		delete(nodeClaim.Annotations, v1beta1.AnnotationTerminationProtection)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(protectionCalls()).To(Equal([]bool{true, false}))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationTerminationProtected))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationTerminationProtected))
+	})
+	It("should not modify unprotected instances", func() {
+		nodeClaim.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyInstanceAttributeBehavior.Calls()).To(Equal(0))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+	})
+	It("should not take over a do-not-disrupt annotation that it didn't set", func() {
+		node.Annotations = map[string]string{corev1beta1.DoNotDisruptAnnotationKey: "true"}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationTerminationProtected))
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		delete(nodeClaim.Annotations, v1beta1.AnnotationTerminationProtection)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+	})
+	It("should leave do-not-disrupt to the minimum node age controller while the node is younger than its minimum age", func() {
+		node.Annotations = map[string]string{
+			corev1beta1.DoNotDisruptAnnotationKey:  "true",
+			v1beta1.AnnotationMinNodeAgeExpiration: "2024-01-01T00:10:00Z",
+		}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationTerminationProtected, "true"))
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		delete(nodeClaim.Annotations, v1beta1.AnnotationTerminationProtection)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(corev1beta1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).ToNot(HaveKey(v1beta1.AnnotationTerminationProtected))
+	})
+	It("should protect the instance before its node registers", func() {
+		nodeClaim.Status.NodeName = ""
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(protectionCalls()).To(Equal([]bool{true}))
+	})
+	It("should unblock disruption when the instance is gone", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		awsEnv.EC2API.Instances.Delete(instanceID)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		delete(nodeClaim.Annotations, v1beta1.AnnotationTerminationProtection)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1beta1.AnnotationTerminationProtected))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(corev1beta1.DoNotDisruptAnnotationKey))
+	})
+})
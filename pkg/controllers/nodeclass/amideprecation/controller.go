@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amideprecation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// Controller surfaces when the AMIs that an EC2NodeClass uses are deprecated, or will be deprecated within the
+// ami-deprecation-warning-window, so that operators can move to newer AMIs before EC2 stops listing them.
+type Controller struct {
+	clk         clock.Clock
+	recorder    events.Recorder
+	amiProvider amifamily.Provider
+}
+
+func NewController(clk clock.Clock, recorder events.Recorder, amiProvider amifamily.Provider) *Controller {
+	return &Controller{
+		clk:         clk,
+		recorder:    recorder,
+		amiProvider: amiProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclass.amideprecation")
+
+	AMIDeprecationTimestamp.DeletePartialMatch(prometheus.Labels{nodeClassLabel: nodeClass.Name})
+	if !nodeClass.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	deprecationTimes, err := c.amiProvider.DeprecationTimes(ctx, lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID }))
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting ami deprecation times, %w", err)
+	}
+	for _, ami := range nodeClass.Status.AMIs {
+		deprecationTime, ok := deprecationTimes[ami.ID]
+		if !ok {
+			continue
+		}
+		AMIDeprecationTimestamp.With(prometheus.Labels{
+			nodeClassLabel: nodeClass.Name,
+			amiIDLabel:     ami.ID,
+		}).Set(float64(deprecationTime.Unix()))
+		switch {
+		case !c.clk.Now().Before(deprecationTime):
+			log.FromContext(ctx).WithValues("id", ami.ID, "deprecation-time", deprecationTime).V(1).Info("discovered deprecated ami")
+			c.recorder.Publish(AMIDeprecatedEvent(nodeClass, ami, deprecationTime))
+		case !c.clk.Now().Add(options.FromContext(ctx).AMIDeprecationWarningWindow).Before(deprecationTime):
+			c.recorder.Publish(AMIDeprecationApproachingEvent(nodeClass, ami, deprecationTime))
+		}
+	}
+	return reconcile.Result{RequeueAfter: time.Hour}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclass.amideprecation").
+		For(&v1beta1.EC2NodeClass{}).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodeclass.amideprecation", reconcile.AsReconciler(m.GetClient(), c)))
+}
@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amideprecation
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+func AMIDeprecatedEvent(nodeClass *v1beta1.EC2NodeClass, ami v1beta1.AMI, deprecationTime time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeWarning,
+		Reason:         "AMIDeprecated",
+		Message:        fmt.Sprintf("AMI %s (%s) was deprecated at %s", ami.ID, ami.Name, deprecationTime.Format(time.RFC3339)),
+		DedupeValues:   []string{string(nodeClass.UID), ami.ID},
+	}
+}
+
+func AMIDeprecationApproachingEvent(nodeClass *v1beta1.EC2NodeClass, ami v1beta1.AMI, deprecationTime time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeWarning,
+		Reason:         "AMIDeprecationApproaching",
+		Message:        fmt.Sprintf("AMI %s (%s) will be deprecated at %s", ami.ID, ami.Name, deprecationTime.Format(time.RFC3339)),
+		DedupeValues:   []string{string(nodeClass.UID), ami.ID},
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amideprecation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	nodeClassLabel         = "nodeclass"
+	amiIDLabel             = "ami_id"
+)
+
+var (
+	AMIDeprecationTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "ami_deprecation_timestamp_seconds",
+			Help:      "Unix timestamp that an AMI used by an EC2NodeClass is, or was, deprecated at, labeled by nodeclass and ami_id. AMIs that aren't scheduled to be deprecated aren't reported.",
+		},
+		[]string{nodeClassLabel, amiIDLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(AMIDeprecationTimestamp)
+}
@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amideprecation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/amideprecation"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var fakeClock *clock.FakeClock
+var fakeRecorder *record.FakeRecorder
+var controller *amideprecation.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AMIDeprecation")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AMIDeprecationWarningWindow: lo.ToPtr(72 * time.Hour)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	fakeClock.SetTime(time.Now())
+	// A new recorder is created for each test so that deduplicated events are published again
+	fakeRecorder = record.NewFakeRecorder(10)
+	controller = amideprecation.NewController(fakeClock, events.NewRecorder(fakeRecorder), awsEnv.AMIProvider)
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("AMI Deprecation Controller", func() {
+	var nodeClass *v1beta1.EC2NodeClass
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		nodeClass.Status.AMIs = []v1beta1.AMI{{Name: "test-ami", ID: "ami-test"}}
+	})
+	deprecateAt := func(deprecationTime string) {
+		awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+			Images: []*ec2.Image{
+				{
+					Name:            aws.String("test-ami"),
+					ImageId:         aws.String("ami-test"),
+					CreationDate:    aws.String(fakeClock.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)),
+					DeprecationTime: lo.EmptyableToPtr(deprecationTime),
+					Architecture:    aws.String("x86_64"),
+				},
+			},
+		})
+	}
+	It("should publish an event when an AMI is deprecated", func() {
+		deprecateAt(fakeClock.Now().Add(-time.Hour).Format(time.RFC3339))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).To(Receive(ContainSubstring("AMIDeprecated")))
+	})
+	It("should publish an event when an AMI will be deprecated within the warning window", func() {
+		deprecateAt(fakeClock.Now().Add(48 * time.Hour).Format(time.RFC3339))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).To(Receive(ContainSubstring("AMIDeprecationApproaching")))
+	})
+	It("should not publish an event when an AMI will be deprecated after the warning window", func() {
+		deprecateAt(fakeClock.Now().Add(96 * time.Hour).Format(time.RFC3339))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).ToNot(Receive())
+	})
+	It("should not publish an event when an AMI isn't scheduled to be deprecated", func() {
+		deprecateAt("")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).ToNot(Receive())
+		_, found := FindMetricWithLabelValues("karpenter_cloudprovider_ami_deprecation_timestamp_seconds", map[string]string{"nodeclass": nodeClass.Name})
+		Expect(found).To(BeFalse())
+	})
+	It("should report the deprecation time of the AMIs", func() {
+		deprecationTime := fakeClock.Now().Add(96 * time.Hour)
+		deprecateAt(deprecationTime.Format(time.RFC3339))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		metric, found := FindMetricWithLabelValues("karpenter_cloudprovider_ami_deprecation_timestamp_seconds", map[string]string{"nodeclass": nodeClass.Name, "ami_id": "ami-test"})
+		Expect(found).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", deprecationTime.Unix()))
+		amideprecation.AMIDeprecationTimestamp.DeletePartialMatch(prometheus.Labels{"nodeclass": nodeClass.Name})
+	})
+})
@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amirelease
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// Controller surfaces when an EC2NodeClass that pins its default AMIs to a release version falls behind the release
+// that SSM currently recommends, so that operators can roll forward on their own schedule.
+type Controller struct {
+	recorder    events.Recorder
+	amiProvider amifamily.Provider
+}
+
+func NewController(recorder events.Recorder, amiProvider amifamily.Provider) *Controller {
+	return &Controller{
+		recorder:    recorder,
+		amiProvider: amiProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclass.amirelease")
+
+	pinned := amifamily.ReleaseVersion(nodeClass)
+	if pinned == "" || len(nodeClass.Spec.AMISelectorTerms) != 0 {
+		return reconcile.Result{}, nil
+	}
+	latest, err := c.amiProvider.LatestReleaseVersion(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting latest release version, %w", err)
+	}
+	if latest != "" && latest != pinned {
+		log.FromContext(ctx).WithValues("pinned", pinned, "latest", latest).V(1).Info("discovered newer ami release")
+		c.recorder.Publish(NewerReleaseAvailableEvent(nodeClass, pinned, latest))
+	}
+	return reconcile.Result{RequeueAfter: time.Hour}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclass.amirelease").
+		For(&v1beta1.EC2NodeClass{}).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodeclass.amirelease", reconcile.AsReconciler(m.GetClient(), c)))
+}
@@ -0,0 +1,35 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amirelease
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+func NewerReleaseAvailableEvent(nodeClass *v1beta1.EC2NodeClass, pinned, latest string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeNormal,
+		Reason:         "NewerAMIReleaseAvailable",
+		Message:        fmt.Sprintf("AMI release %s is available, amiSelectionPolicy.releaseVersion is pinned to %s", latest, pinned),
+		DedupeValues:   []string{string(nodeClass.UID), latest},
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amirelease_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/amirelease"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var fakeRecorder *record.FakeRecorder
+var controller *amirelease.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AMIRelease")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	awsEnv.Reset()
+	// A new recorder is created for each test so that deduplicated events are published again
+	fakeRecorder = record.NewFakeRecorder(10)
+	controller = amirelease.NewController(events.NewRecorder(fakeRecorder), awsEnv.AMIProvider)
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("AMI Release Controller", func() {
+	var nodeClass *v1beta1.EC2NodeClass
+	var version string
+	BeforeEach(func() {
+		version = lo.Must(awsEnv.VersionProvider.Get(ctx))
+		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			Spec: v1beta1.EC2NodeClassSpec{
+				AMIFamily:          &v1beta1.AMIFamilyAL2,
+				AMISelectionPolicy: &v1beta1.AMISelectionPolicy{ReleaseVersion: lo.ToPtr("v20240703")},
+			},
+		})
+		awsEnv.SSMAPI.Parameters = map[string]string{
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/recommended/release_version", version): fmt.Sprintf("%s.0-20240710", version),
+		}
+	})
+	It("should publish an event when a newer release is available", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).To(Receive(ContainSubstring("NewerAMIReleaseAvailable")))
+	})
+	It("should not publish an event when the pinned release is the latest", func() {
+		nodeClass.Spec.AMISelectionPolicy.ReleaseVersion = lo.ToPtr("v20240710")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).ToNot(Receive())
+	})
+	It("should ignore EC2NodeClasses that don't pin a release", func() {
+		nodeClass.Spec.AMISelectionPolicy = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).ToNot(Receive())
+	})
+	It("should ignore EC2NodeClasses that select AMIs with amiSelectorTerms", func() {
+		nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		Expect(fakeRecorder.Events).ToNot(Receive())
+	})
+})
@@ -18,32 +18,67 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 )
 
 type AMI struct {
+	kubeClient  client.Client
 	amiProvider amifamily.Provider
+	recorder    events.Recorder
+	clock       clock.Clock
 }
 
 func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	res, err := a.resolve(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if options.FromContext(ctx).AMIDeprecationStrict {
+		if err = a.excludeDeprecated(ctx, nodeClass); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if err = a.validateArchitectures(ctx, nodeClass); err != nil {
+		return reconcile.Result{}, err
+	}
+	return res, nil
+}
+
+func (a *AMI) resolve(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	hash := nodeClass.AMISelectionHash()
+	// Once AMIs have been resolved for a pinned EC2NodeClass, they aren't replaced when newer AMIs are discovered, only
+	// when the amiFamily, amiSelectorTerms or release version that they were resolved from change
+	if a.isPinned(nodeClass) && len(nodeClass.Status.AMIs) != 0 {
+		// AMIs that were pinned before the hash was recorded are assumed to match the current spec, rather than being
+		// replaced with the newest AMIs
+		if nodeClass.Status.AMISelectionHash == "" {
+			nodeClass.Status.AMISelectionHash = hash
+		}
+		if nodeClass.Status.AMISelectionHash == hash {
+			return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+		}
+	}
 	amis, err := a.amiProvider.List(ctx, nodeClass)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("getting amis, %w", err)
 	}
-	if len(amis) == 0 {
-		nodeClass.Status.AMIs = nil
-		return reconcile.Result{}, nil
-	}
-	nodeClass.Status.AMIs = lo.Map(amis, func(ami amifamily.AMI, _ int) v1beta1.AMI {
+	resolved := lo.Map(amis, func(ami amifamily.AMI, _ int) v1beta1.AMI {
 		reqs := lo.Map(ami.Requirements.NodeSelectorRequirements(), func(item corev1beta1.NodeSelectorRequirementWithMinValues, _ int) v1.NodeSelectorRequirement {
 			return item.NodeSelectorRequirement
 		})
@@ -57,8 +92,99 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (r
 		return v1beta1.AMI{
 			Name:         ami.Name,
 			ID:           ami.AmiID,
+			Variant:      ami.Variant,
+			ENASupport:   ami.ENASupport,
 			Requirements: reqs,
 		}
 	})
+	// AMIs are only resolved before they reach the minimum age when there's nothing older to resolve: default AMIs are
+	// resolved from SSM, which only returns the latest release, and the images that amiSelectorTerms select may all be
+	// newer. Rather than adopting these AMIs, keep using the AMI that was previously resolved for the same requirements.
+	// Default AMIs without one are adopted, while AMIs selected by amiSelectorTerms without one are left out.
+	if minimumAge := amifamily.MinimumAge(nodeClass); minimumAge != 0 {
+		resolved = lo.FilterMap(resolved, func(ami v1beta1.AMI, i int) (v1beta1.AMI, bool) {
+			if amifamily.IsOlderThan(a.clock, amis[i].CreationDate, minimumAge) {
+				return ami, true
+			}
+			if previous, ok := lo.Find(nodeClass.Status.AMIs, func(previous v1beta1.AMI) bool {
+				return equality.Semantic.DeepEqual(previous.Requirements, ami.Requirements)
+			}); ok {
+				return previous, true
+			}
+			return ami, len(nodeClass.Spec.AMISelectorTerms) == 0
+		})
+	}
+	if len(resolved) == 0 {
+		nodeClass.Status.AMIs = nil
+		nodeClass.Status.AMISelectionHash = ""
+		return reconcile.Result{}, nil
+	}
+	nodeClass.Status.AMIs = resolved
+	nodeClass.Status.AMISelectionHash = hash
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
+
+// excludeDeprecated removes the AMIs that were deprecated from the status of the EC2NodeClass, so that no instances are
+// launched with them. Pinned EC2NodeClasses whose AMIs were all deprecated resolve their AMIs again.
+func (a *AMI) excludeDeprecated(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) error {
+	deprecationTimes, err := a.amiProvider.DeprecationTimes(ctx, lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID }))
+	if err != nil {
+		return fmt.Errorf("getting ami deprecation times, %w", err)
+	}
+	amis := lo.Reject(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) bool {
+		deprecationTime, ok := deprecationTimes[ami.ID]
+		if !ok || time.Now().Before(deprecationTime) {
+			return false
+		}
+		a.recorder.Publish(DeprecatedAMIExcludedEvent(nodeClass, ami.ID, deprecationTime))
+		return true
+	})
+	nodeClass.Status.AMIs = lo.Ternary(len(amis) == 0, nil, amis)
+	return nil
+}
+
+// validateArchitectures surfaces the architectures that NodePools referencing the EC2NodeClass can launch, but that
+// none of the resolved AMIs support. Instance types of these architectures are never launched, which otherwise only
+// becomes apparent when a pod that requires one of them fails to schedule.
+func (a *AMI) validateArchitectures(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) error {
+	// Readiness already reports when no AMIs were resolved at all
+	if len(nodeClass.Status.AMIs) == 0 {
+		return nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeAMIResolutionFailed)
+	}
+	nodePools := &corev1beta1.NodePoolList{}
+	if err := a.kubeClient.List(ctx, nodePools); err != nil {
+		return fmt.Errorf("listing nodepools, %w", err)
+	}
+	missing := map[string][]string{}
+	for _, nodePool := range nodePools.Items {
+		if nodePool.Spec.Template.Spec.NodeClassRef == nil || nodePool.Spec.Template.Spec.NodeClassRef.Name != nodeClass.Name {
+			continue
+		}
+		requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+		for _, arch := range []string{corev1beta1.ArchitectureAmd64, corev1beta1.ArchitectureArm64} {
+			if !requirements.Get(v1.LabelArchStable).Has(arch) {
+				continue
+			}
+			if lo.ContainsBy(nodeClass.Status.AMIs, func(ami v1beta1.AMI) bool {
+				return scheduling.NewNodeSelectorRequirements(ami.Requirements...).Get(v1.LabelArchStable).Has(arch)
+			}) {
+				continue
+			}
+			missing[arch] = append(missing[arch], nodePool.Name)
+		}
+	}
+	if len(missing) == 0 {
+		return nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeAMIResolutionFailed)
+	}
+	archs := lo.Keys(missing)
+	sort.Strings(archs)
+	nodeClass.StatusConditions().SetTrueWithReason(v1beta1.ConditionTypeAMIResolutionFailed, "ArchitectureNotSupported", strings.Join(lo.Map(archs, func(arch string, _ int) string {
+		sort.Strings(missing[arch])
+		return fmt.Sprintf("no AMI supports %s, which nodepools %s can launch", arch, strings.Join(missing[arch], ", "))
+	}), "; "))
+	return nil
+}
+
+func (a *AMI) isPinned(nodeClass *v1beta1.EC2NodeClass) bool {
+	return nodeClass.Spec.AMISelectionPolicy != nil && nodeClass.Spec.AMISelectionPolicy.Strategy == v1beta1.AMISelectionStrategyPinned
+}
@@ -20,11 +20,16 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/operatorpkg/status"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -137,8 +142,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.AMIs).To(Equal([]v1beta1.AMI{
 			{
-				Name: "test-ami-3",
-				ID:   "ami-id-789",
+				Name:    "test-ami-3",
+				ID:      "ami-id-789",
+				Variant: amifamily.VariantStandard,
 				Requirements: []v1.NodeSelectorRequirement{
 					{
 						Key:      v1.LabelArchStable,
@@ -156,8 +162,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			},
 			{
-				Name: "test-ami-2",
-				ID:   "ami-id-456",
+				Name:    "test-ami-2",
+				ID:      "ami-id-456",
+				Variant: amifamily.VariantNvidia,
 				Requirements: []v1.NodeSelectorRequirement{
 					{
 						Key:      v1.LabelArchStable,
@@ -171,8 +178,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			},
 			{
-				Name: "test-ami-2",
-				ID:   "ami-id-456",
+				Name:    "test-ami-2",
+				ID:      "ami-id-456",
+				Variant: amifamily.VariantNeuron,
 				Requirements: []v1.NodeSelectorRequirement{
 					{
 						Key:      v1.LabelArchStable,
@@ -186,8 +194,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			},
 			{
-				Name: "test-ami-1",
-				ID:   "ami-id-123",
+				Name:    "test-ami-1",
+				ID:      "ami-id-123",
+				Variant: amifamily.VariantStandard,
 				Requirements: []v1.NodeSelectorRequirement{
 					{
 						Key:      v1.LabelArchStable,
@@ -245,8 +254,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 
 		Expect(nodeClass.Status.AMIs).To(Equal([]v1beta1.AMI{
 			{
-				Name: "test-ami-2",
-				ID:   "ami-id-456",
+				Name:    "test-ami-2",
+				ID:      "ami-id-456",
+				Variant: amifamily.VariantStandard,
 				Requirements: []v1.NodeSelectorRequirement{
 					{
 						Key:      v1.LabelArchStable,
@@ -264,8 +274,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			},
 			{
-				Name: "test-ami-1",
-				ID:   "ami-id-123",
+				Name:    "test-ami-1",
+				ID:      "ami-id-123",
+				Variant: amifamily.VariantStandard,
 				Requirements: []v1.NodeSelectorRequirement{
 					{
 						Key:      v1.LabelArchStable,
@@ -303,4 +314,295 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			},
 		))
 	})
+	Context("AMI Selection Policy", func() {
+		It("should skip AMIs that haven't reached the minimum age", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-1"),
+						ImageId:      aws.String("ami-test1"),
+						CreationDate: aws.String(time.Now().Add(-72 * time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+					{
+						Name:         aws.String("test-ami-2"),
+						ImageId:      aws.String("ami-test2"),
+						CreationDate: aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{MinimumAge: &metav1.Duration{Duration: 48 * time.Hour}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test1"))
+		})
+		It("should keep the previously resolved AMI when no selected AMI has reached the minimum age", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-1"),
+						ImageId:      aws.String("ami-test1"),
+						CreationDate: aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{MinimumAge: &metav1.Duration{Duration: 48 * time.Hour}}
+			nodeClass.Status.AMIs = []v1beta1.AMI{
+				{
+					Name: "test-ami-0",
+					ID:   "ami-test0",
+					Requirements: []v1.NodeSelectorRequirement{{
+						Key:      v1.LabelArchStable,
+						Operator: v1.NodeSelectorOpIn,
+						Values:   []string{corev1beta1.ArchitectureAmd64},
+					}},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test0"))
+		})
+		It("should not resolve AMIs when no selected AMI has reached the minimum age and none were previously resolved", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-1"),
+						ImageId:      aws.String("ami-test1"),
+						CreationDate: aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{MinimumAge: &metav1.Duration{Duration: 48 * time.Hour}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(BeEmpty())
+		})
+		It("should keep the previously resolved default AMI until the latest release reaches the minimum age", func() {
+			version := lo.Must(awsEnv.VersionProvider.Get(ctx))
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/recommended/image_id", version): "ami-id-123",
+			}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-1"),
+						ImageId:      aws.String("ami-id-123"),
+						CreationDate: aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			nodeClass.Spec.AMISelectorTerms = nil
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{MinimumAge: &metav1.Duration{Duration: 48 * time.Hour}}
+			nodeClass.Status.AMIs = []v1beta1.AMI{
+				{
+					Name: "test-ami-0",
+					ID:   "ami-id-000",
+					Requirements: []v1.NodeSelectorRequirement{
+						{
+							Key:      v1.LabelArchStable,
+							Operator: v1.NodeSelectorOpIn,
+							Values:   []string{corev1beta1.ArchitectureAmd64},
+						},
+						{
+							Key:      v1beta1.LabelInstanceGPUCount,
+							Operator: v1.NodeSelectorOpDoesNotExist,
+						},
+						{
+							Key:      v1beta1.LabelInstanceAcceleratorCount,
+							Operator: v1.NodeSelectorOpDoesNotExist,
+						},
+					},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-id-000"))
+		})
+		It("should keep the resolved AMIs when the strategy is Pinned", func() {
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{Strategy: v1beta1.AMISelectionStrategyPinned}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test3"))
+
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-4"),
+						ImageId:      aws.String("ami-test4"),
+						CreationDate: aws.String(time.Now().Add(time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			awsEnv.EC2Cache.Flush()
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test3"))
+		})
+		It("should resolve the AMIs of a pinned EC2NodeClass again when its amiSelectorTerms change", func() {
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{Strategy: v1beta1.AMISelectionStrategyPinned}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test3"))
+			Expect(nodeClass.Status.AMISelectionHash).To(Equal(nodeClass.AMISelectionHash()))
+
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-4"),
+						ImageId:      aws.String("ami-test4"),
+						CreationDate: aws.String(time.Now().Add(time.Hour).Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+				},
+			})
+			awsEnv.EC2Cache.Flush()
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{ID: "ami-test4"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test4"))
+			Expect(nodeClass.Status.AMISelectionHash).To(Equal(nodeClass.AMISelectionHash()))
+		})
+		It("should keep the AMIs of a pinned EC2NodeClass that were resolved before the selection hash was recorded", func() {
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{Strategy: v1beta1.AMISelectionStrategyPinned}
+			nodeClass.Status.AMIs = []v1beta1.AMI{{ID: "ami-test1", Requirements: []v1.NodeSelectorRequirement{{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}}}}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-test1"))
+			Expect(nodeClass.Status.AMISelectionHash).To(Equal(nodeClass.AMISelectionHash()))
+		})
+	})
+	Context("AMI Deprecation", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:            aws.String("test-ami-1"),
+						ImageId:         aws.String("ami-test1"),
+						CreationDate:    aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+						DeprecationTime: aws.String(time.Now().Add(-time.Minute).Format(time.RFC3339)),
+						Architecture:    aws.String("x86_64"),
+					},
+					{
+						Name:            aws.String("test-ami-2"),
+						ImageId:         aws.String("ami-test2"),
+						CreationDate:    aws.String(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+						DeprecationTime: aws.String(time.Now().Add(time.Hour).Format(time.RFC3339)),
+						Architecture:    aws.String("arm64"),
+					},
+				},
+			})
+		})
+		It("should keep deprecated AMIs when strict mode is disabled", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-test1", "ami-test2"))
+			Expect(recorder.Calls("DeprecatedAMIExcluded")).To(BeZero())
+		})
+		It("should exclude deprecated AMIs when strict mode is enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AMIDeprecationStrict: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1beta1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-test2"))
+			Expect(recorder.Calls("DeprecatedAMIExcluded")).To(Equal(1))
+		})
+		It("should not be ready when all of the AMIs are deprecated in strict mode", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AMIDeprecationStrict: lo.ToPtr(true)}))
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{ID: "ami-test1"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(BeEmpty())
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
+		})
+	})
+	Context("Architecture Validation", func() {
+		var nodePool *corev1beta1.NodePool
+		BeforeEach(func() {
+			nodePool = coretest.NodePool(corev1beta1.NodePool{
+				Spec: corev1beta1.NodePoolSpec{
+					Template: corev1beta1.NodeClaimTemplate{
+						Spec: corev1beta1.NodeClaimSpec{
+							NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+						},
+					},
+				},
+			})
+		})
+		It("should surface architectures that a NodePool can launch without a matching AMI", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().Get(v1beta1.ConditionTypeAMIResolutionFailed)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Message).To(ContainSubstring(corev1beta1.ArchitectureArm64))
+			Expect(condition.Message).To(ContainSubstring(nodePool.Name))
+			Expect(condition.Message).ToNot(ContainSubstring(corev1beta1.ArchitectureAmd64))
+		})
+		It("should not surface architectures that NodePools can't launch", func() {
+			nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}}},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeAMIResolutionFailed)).To(BeNil())
+		})
+		It("should ignore NodePools that reference other EC2NodeClasses", func() {
+			nodePool.Spec.Template.Spec.NodeClassRef.Name = "other"
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeAMIResolutionFailed)).To(BeNil())
+		})
+		It("should clear the condition once an AMI supports the architecture", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeAMIResolutionFailed)).ToNot(BeNil())
+
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("test-ami-amd64"),
+						ImageId:      aws.String("ami-amd64"),
+						CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+						Architecture: aws.String("x86_64"),
+					},
+					{
+						Name:         aws.String("test-ami-arm64"),
+						ImageId:      aws.String("ami-arm64"),
+						CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+						Architecture: aws.String("arm64"),
+					},
+				},
+			})
+			awsEnv.EC2Cache.Flush()
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeAMIResolutionFailed)).To(BeNil())
+		})
+	})
 })
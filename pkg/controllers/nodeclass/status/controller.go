@@ -19,12 +19,14 @@ import (
 
 	"go.uber.org/multierr"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/clock"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"sigs.k8s.io/karpenter/pkg/utils/result"
@@ -32,11 +34,15 @@ import (
 	"github.com/awslabs/operatorpkg/reasonable"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
 )
 
 type nodeClassStatusReconciler interface {
@@ -46,23 +52,32 @@ type nodeClassStatusReconciler interface {
 type Controller struct {
 	kubeClient client.Client
 
-	ami             *AMI
-	instanceprofile *InstanceProfile
-	subnet          *Subnet
-	securitygroup   *SecurityGroup
-	readiness       *Readiness //TODO : Remove this when we have sub status conditions
+	ami              *AMI
+	instanceprofile  *InstanceProfile
+	subnet           *Subnet
+	securitygroup    *SecurityGroup
+	networkinterface *NetworkInterface
+	ownership        *Ownership
+	kms              *KMS
+	maxpods          *MaxPods
+	readiness        *Readiness //TODO : Remove this when we have sub status conditions
 }
 
-func NewController(kubeClient client.Client, subnetProvider subnet.Provider, securityGroupProvider securitygroup.Provider,
-	amiProvider amifamily.Provider, instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider) *Controller {
+func NewController(kubeClient client.Client, clk clock.Clock, subnetProvider subnet.Provider, securityGroupProvider securitygroup.Provider,
+	amiProvider amifamily.Provider, instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider, kmsProvider kms.Provider, accountID string,
+	instanceTypeProvider instancetype.Provider, vpcCNI *awscache.VPCCNI, recorder events.Recorder) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
 
-		ami:             &AMI{amiProvider: amiProvider},
-		subnet:          &Subnet{subnetProvider: subnetProvider},
-		securitygroup:   &SecurityGroup{securityGroupProvider: securityGroupProvider},
-		instanceprofile: &InstanceProfile{instanceProfileProvider: instanceProfileProvider},
-		readiness:       &Readiness{launchTemplateProvider: launchTemplateProvider},
+		ami:              &AMI{kubeClient: kubeClient, amiProvider: amiProvider, recorder: recorder, clock: clk},
+		subnet:           &Subnet{subnetProvider: subnetProvider},
+		securitygroup:    &SecurityGroup{securityGroupProvider: securityGroupProvider},
+		networkinterface: &NetworkInterface{subnetProvider: subnetProvider, securityGroupProvider: securityGroupProvider},
+		ownership:        &Ownership{accountID: accountID},
+		kms:              &KMS{kmsProvider: kmsProvider},
+		maxpods:          &MaxPods{kubeClient: kubeClient, instanceTypeProvider: instanceTypeProvider, vpcCNI: vpcCNI, recorder: recorder},
+		instanceprofile:  &InstanceProfile{instanceProfileProvider: instanceProfileProvider},
+		readiness:        &Readiness{launchTemplateProvider: launchTemplateProvider},
 	}
 }
 
@@ -84,6 +99,10 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 		c.ami,
 		c.subnet,
 		c.securitygroup,
+		c.networkinterface,
+		c.ownership,
+		c.kms,
+		c.maxpods,
 		c.instanceprofile,
 		c.readiness,
 	} {
@@ -111,5 +130,5 @@ func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 			RateLimiter:             reasonable.RateLimiter(),
 			MaxConcurrentReconciles: 10,
 		}).
-		Complete(reconcile.AsReconciler(m.GetClient(), c))
+		Complete(tracing.Reconciler("nodeclass.status", reconcile.AsReconciler(m.GetClient(), c)))
 }
@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+func MaxPodsExceedENILimitsEvent(nodePool *corev1beta1.NodePool, nodeClass *v1beta1.EC2NodeClass, maxPods int64, instanceTypes []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           v1.EventTypeWarning,
+		Reason:         "MaxPodsExceedENILimits",
+		Message: fmt.Sprintf("MaxPods %d is more than the ENIs of %d instance types of EC2NodeClass %s support without prefix delegation, pods beyond their limit can't be assigned an IP (e.g. %s)",
+			maxPods, len(instanceTypes), nodeClass.Name, strings.Join(lo.Slice(instanceTypes, 0, 3), ", ")),
+		DedupeValues: []string{string(nodePool.UID), fmt.Sprint(maxPods)},
+	}
+}
+
+func DeprecatedAMIExcludedEvent(nodeClass *v1beta1.EC2NodeClass, id string, deprecationTime time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           v1.EventTypeWarning,
+		Reason:         "DeprecatedAMIExcluded",
+		Message:        fmt.Sprintf("AMI %s was deprecated at %s and isn't used to launch instances", id, deprecationTime.Format(time.RFC3339)),
+		DedupeValues:   []string{string(nodeClass.UID), id},
+	}
+}
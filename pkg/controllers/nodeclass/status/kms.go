@@ -0,0 +1,66 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+)
+
+// KMS reports the KMS keys of the EC2NodeClass's block device mappings that volumes can't be encrypted with. A launch
+// with such a key can fail, or the instance can launch without its volumes attached, so the key is surfaced before any
+// launch is attempted.
+type KMS struct {
+	kmsProvider kms.Provider
+}
+
+func (k *KMS) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	keyIDs := lo.Uniq(lo.FilterMap(nodeClass.Spec.BlockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping, _ int) (string, bool) {
+		if bdm.EBS == nil {
+			return "", false
+		}
+		return lo.FromPtr(bdm.EBS.KMSKeyID), lo.FromPtr(bdm.EBS.KMSKeyID) != ""
+	}))
+	var unusable []*kms.UnusableKeyError
+	var errs error
+	for _, keyID := range keyIDs {
+		err := k.kmsProvider.Validate(ctx, keyID)
+		var ukErr *kms.UnusableKeyError
+		if errors.As(err, &ukErr) {
+			unusable = append(unusable, ukErr)
+			continue
+		}
+		errs = multierr.Append(errs, err)
+	}
+	if errs != nil {
+		return reconcile.Result{}, errs
+	}
+	if len(unusable) == 0 {
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeKMSKeyUnusable)
+	}
+	nodeClass.StatusConditions().SetTrueWithReason(v1beta1.ConditionTypeKMSKeyUnusable, unusable[0].Reason, strings.Join(lo.Map(unusable, func(e *kms.UnusableKeyError, _ int) string {
+		return e.Error()
+	}), "; "))
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
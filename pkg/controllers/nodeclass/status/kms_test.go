@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass KMS Status Controller", func() {
+	BeforeEach(func() {
+		nodeClass.Spec.BlockDeviceMappings = []*v1beta1.BlockDeviceMapping{
+			{DeviceName: lo.ToPtr("/dev/xvda"), EBS: &v1beta1.BlockDevice{Encrypted: lo.ToPtr(true), KMSKeyID: lo.ToPtr("test-key")}},
+			{DeviceName: lo.ToPtr("/dev/xvdb"), EBS: &v1beta1.BlockDevice{Encrypted: lo.ToPtr(true)}},
+		}
+	})
+	It("should validate the kms keys of block device mappings", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeKMSKeyUnusable)).To(BeNil())
+		Expect(awsEnv.KMSAPI.DescribeKeyBehavior.CalledWithInput.Len()).To(Equal(1))
+		Expect(awsEnv.KMSAPI.CreateGrantBehavior.Calls()).To(Equal(1))
+	})
+	It("should report a kms key that doesn't exist", func() {
+		awsEnv.KMSAPI.DescribeKeyBehavior.Error.Set(awserr.New("NotFoundException", "Key 'test-key' does not exist", nil))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().Get(v1beta1.ConditionTypeKMSKeyUnusable)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Reason).To(Equal(kms.ReasonKeyNotFound))
+		Expect(condition.Message).To(ContainSubstring("test-key"))
+	})
+	It("should report a kms key that can't be granted to EC2", func() {
+		awsEnv.KMSAPI.CreateGrantBehavior.Error.Set(awserr.New("AccessDeniedException", "not authorized to perform: kms:CreateGrant", nil))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().Get(v1beta1.ConditionTypeKMSKeyUnusable)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(kms.ReasonGrantNotPermitted))
+	})
+	It("should clear the condition once the kms key is removed", func() {
+		awsEnv.KMSAPI.DescribeKeyBehavior.Error.Set(awserr.New("NotFoundException", "Key 'test-key' does not exist", nil))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeKMSKeyUnusable)).ToNot(BeNil())
+
+		nodeClass.Spec.BlockDeviceMappings[0].EBS.KMSKeyID = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeKMSKeyUnusable)).To(BeNil())
+	})
+	It("should not report a kms key whose validation failed for another reason", func() {
+		awsEnv.KMSAPI.DescribeKeyBehavior.Error.Set(awserr.New("ThrottlingException", "Rate exceeded", nil))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeKMSKeyUnusable)).To(BeNil())
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+)
+
+// MaxPods reports the NodePools referencing the EC2NodeClass whose kubelet maxPods is higher than the number of pods
+// that the ENIs of their instance types have IPs for. Without prefix delegation, the pods beyond that are stuck
+// ContainerCreating since the VPC CNI can't assign them an IP.
+type MaxPods struct {
+	kubeClient           client.Client
+	instanceTypeProvider instancetype.Provider
+	vpcCNI               *awscache.VPCCNI
+	recorder             events.Recorder
+}
+
+func (m *MaxPods) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	if !amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{}).FeatureFlags().SupportsENILimitedPodDensity {
+		return reconcile.Result{}, nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeMaxPodsExceedENILimits)
+	}
+	if warmTargets, ok := m.vpcCNI.WarmTargets(); ok && warmTargets.PrefixDelegation {
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeMaxPodsExceedENILimits)
+	}
+	nodePools := &corev1beta1.NodePoolList{}
+	if err := m.kubeClient.List(ctx, nodePools); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodepools, %w", err)
+	}
+	nodePools.Items = lo.Filter(nodePools.Items, func(nodePool corev1beta1.NodePool, _ int) bool {
+		return nodePool.Spec.Template.Spec.NodeClassRef != nil && nodePool.Spec.Template.Spec.NodeClassRef.Name == nodeClass.Name &&
+			nodePool.Spec.Template.Spec.Kubelet != nil && nodePool.Spec.Template.Spec.Kubelet.MaxPods != nil
+	})
+	if len(nodePools.Items) == 0 {
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeMaxPodsExceedENILimits)
+	}
+	// Without a configured maxPods, the pod capacity of each instance type is the number of pods its ENIs support
+	instanceTypes, err := m.instanceTypeProvider.List(ctx, &corev1beta1.KubeletConfiguration{}, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing instance types, %w", err)
+	}
+	var messages []string
+	for i := range nodePools.Items {
+		nodePool := &nodePools.Items[i]
+		maxPods := int64(lo.FromPtr(nodePool.Spec.Template.Spec.Kubelet.MaxPods))
+		requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+		exceeded := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (string, bool) {
+			return it.Name, it.Requirements.Compatible(requirements, scheduling.AllowUndefinedWellKnownLabels) == nil && it.Capacity.Pods().Value() < maxPods
+		})
+		if len(exceeded) == 0 {
+			continue
+		}
+		sort.Strings(exceeded)
+		m.recorder.Publish(MaxPodsExceedENILimitsEvent(nodePool, nodeClass, maxPods, exceeded))
+		messages = append(messages, fmt.Sprintf("nodepool %s sets maxPods to %d, which is more than the ENIs of %d of its instance types support (e.g. %s)",
+			nodePool.Name, maxPods, len(exceeded), strings.Join(lo.Slice(exceeded, 0, 3), ", ")))
+	}
+	if len(messages) == 0 {
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeMaxPodsExceedENILimits)
+	}
+	sort.Strings(messages)
+	nodeClass.StatusConditions().SetTrueWithReason(v1beta1.ConditionTypeMaxPodsExceedENILimits, "MaxPodsExceedENILimits", strings.Join(messages, "; "))
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass MaxPods Status Controller", func() {
+	var nodePool *corev1beta1.NodePool
+	BeforeEach(func() {
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		nodePool = coretest.NodePool(corev1beta1.NodePool{
+			Spec: corev1beta1.NodePoolSpec{
+				Template: corev1beta1.NodeClaimTemplate{
+					Spec: corev1beta1.NodeClaimSpec{
+						NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+						Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{
+							{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"t3.large"}}},
+						},
+						// The ENIs of a t3.large have IPs for 35 pods
+						Kubelet: &corev1beta1.KubeletConfiguration{MaxPods: lo.ToPtr(int32(50))},
+					},
+				},
+			},
+		})
+	})
+	It("should report a nodepool whose maxPods is higher than its instance types' ENIs support", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().Get(v1beta1.ConditionTypeMaxPodsExceedENILimits)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Message).To(ContainSubstring(nodePool.Name))
+		Expect(condition.Message).To(ContainSubstring("t3.large"))
+		Expect(recorder.Calls("MaxPodsExceedENILimits")).To(Equal(1))
+	})
+	It("should not report a nodepool whose maxPods its instance types' ENIs support", func() {
+		nodePool.Spec.Template.Spec.Kubelet.MaxPods = lo.ToPtr(int32(35))
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeMaxPodsExceedENILimits)).To(BeNil())
+		Expect(recorder.Calls("MaxPodsExceedENILimits")).To(Equal(0))
+	})
+	It("should not report a nodepool when the VPC CNI uses prefix delegation", func() {
+		awsEnv.VPCCNI.SetWarmTargets(&awscache.VPCCNIWarmTargets{PrefixDelegation: true})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeMaxPodsExceedENILimits)).To(BeNil())
+	})
+	It("should not report a nodepool whose AMI family doesn't limit pod density by ENIs", func() {
+		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyWindows2022
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeMaxPodsExceedENILimits)).To(BeNil())
+	})
+	It("should clear the condition once maxPods is lowered", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeMaxPodsExceedENILimits)).ToNot(BeNil())
+
+		nodePool.Spec.Template.Spec.Kubelet.MaxPods = lo.ToPtr(int32(20))
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1beta1.ConditionTypeMaxPodsExceedENILimits)).To(BeNil())
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+)
+
+// NetworkInterface resolves the subnets and security groups of the additional network interfaces of the EC2NodeClass
+type NetworkInterface struct {
+	subnetProvider        subnet.Provider
+	securityGroupProvider securitygroup.Provider
+}
+
+func (n *NetworkInterface) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	if len(nodeClass.Spec.AdditionalNetworkInterfaces) == 0 {
+		nodeClass.Status.AdditionalNetworkInterfaces = nil
+		return reconcile.Result{}, nil
+	}
+	var statuses []v1beta1.AdditionalNetworkInterfaceStatus
+	for _, networkInterface := range nodeClass.Spec.AdditionalNetworkInterfaces {
+		// The interfaces are resolved with the providers of the nodeclass's own subnets and security groups, under a name
+		// that keeps the discovered resources of each interface from being logged as changes to the others
+		selector := &v1beta1.EC2NodeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s/network-interface-%d", nodeClass.Name, networkInterface.DeviceIndex)},
+			Spec: v1beta1.EC2NodeClassSpec{
+				SubnetSelectorTerms:        networkInterface.SubnetSelectorTerms,
+				SecurityGroupSelectorTerms: networkInterface.SecurityGroupSelectorTerms,
+			},
+		}
+		subnets, err := n.subnetProvider.List(ctx, selector)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("getting subnets of network interface %d, %w", networkInterface.DeviceIndex, err)
+		}
+		sort.Slice(subnets, func(i, j int) bool {
+			if int(*subnets[i].AvailableIpAddressCount) != int(*subnets[j].AvailableIpAddressCount) {
+				return int(*subnets[i].AvailableIpAddressCount) > int(*subnets[j].AvailableIpAddressCount)
+			}
+			return *subnets[i].SubnetId < *subnets[j].SubnetId
+		})
+		status := v1beta1.AdditionalNetworkInterfaceStatus{
+			DeviceIndex: networkInterface.DeviceIndex,
+			Subnets: lo.Map(subnets, func(ec2subnet ec2types.Subnet, _ int) v1beta1.Subnet {
+				return v1beta1.Subnet{
+					ID:         *ec2subnet.SubnetId,
+					Zone:       *ec2subnet.AvailabilityZone,
+					ZoneID:     *ec2subnet.AvailabilityZoneId,
+					OutpostARN: lo.FromPtr(ec2subnet.OutpostArn),
+					OwnerID:    lo.FromPtr(ec2subnet.OwnerId),
+				}
+			}),
+		}
+		// Interfaces without security group selector terms are in the security groups of the nodeclass
+		if len(networkInterface.SecurityGroupSelectorTerms) != 0 {
+			securityGroups, err := n.securityGroupProvider.List(ctx, selector)
+			if err != nil {
+				return reconcile.Result{}, fmt.Errorf("getting security groups of network interface %d, %w", networkInterface.DeviceIndex, err)
+			}
+			sort.Slice(securityGroups, func(i, j int) bool {
+				return *securityGroups[i].GroupId < *securityGroups[j].GroupId
+			})
+			status.SecurityGroups = lo.Map(securityGroups, func(securityGroup ec2types.SecurityGroup, _ int) v1beta1.SecurityGroup {
+				return v1beta1.SecurityGroup{
+					ID:      *securityGroup.GroupId,
+					Name:    *securityGroup.GroupName,
+					OwnerID: lo.FromPtr(securityGroup.OwnerId),
+				}
+			})
+		}
+		statuses = append(statuses, status)
+	}
+	nodeClass.Status.AdditionalNetworkInterfaces = statuses
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// additionalNetworkInterfacesResolved returns true if every additional network interface of the EC2NodeClass has
+// subnets, and security groups if it selects them
+func additionalNetworkInterfacesResolved(nodeClass *v1beta1.EC2NodeClass) bool {
+	if len(nodeClass.Status.AdditionalNetworkInterfaces) != len(nodeClass.Spec.AdditionalNetworkInterfaces) {
+		return false
+	}
+	for i, networkInterface := range nodeClass.Spec.AdditionalNetworkInterfaces {
+		status := nodeClass.Status.AdditionalNetworkInterfaces[i]
+		if status.DeviceIndex != networkInterface.DeviceIndex || len(status.Subnets) == 0 {
+			return false
+		}
+		if len(networkInterface.SecurityGroupSelectorTerms) != 0 && len(status.SecurityGroups) == 0 {
+			return false
+		}
+	}
+	return true
+}
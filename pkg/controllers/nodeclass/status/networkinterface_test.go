@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"github.com/awslabs/operatorpkg/status"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Network Interface Status Controller", func() {
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			Spec: v1beta1.EC2NodeClassSpec{
+				SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				SecurityGroupSelectorTerms: []v1beta1.SecurityGroupSelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				AMISelectorTerms: []v1beta1.AMISelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				AdditionalNetworkInterfaces: []v1beta1.AdditionalNetworkInterface{
+					{
+						DeviceIndex: 1,
+						SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{
+							{
+								ID: "subnet-test2",
+							},
+						},
+						SecurityGroupSelectorTerms: []v1beta1.SecurityGroupSelectorTerm{
+							{
+								ID: "sg-test2",
+							},
+						},
+					},
+				},
+			},
+		})
+	})
+	It("should update EC2NodeClass status for the additional network interfaces", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces).To(Equal([]v1beta1.AdditionalNetworkInterfaceStatus{
+			{
+				DeviceIndex: 1,
+				Subnets: []v1beta1.Subnet{
+					{
+						ID:     "subnet-test2",
+						Zone:   "test-zone-1b",
+						ZoneID: "tstz1-1b",
+					},
+				},
+				SecurityGroups: []v1beta1.SecurityGroup{
+					{
+						ID:   "sg-test2",
+						Name: "securityGroup-test2",
+					},
+				},
+			},
+		}))
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+	})
+	It("should not resolve security groups for interfaces that use the security groups of the EC2NodeClass", func() {
+		nodeClass.Spec.AdditionalNetworkInterfaces[0].SecurityGroupSelectorTerms = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces).To(HaveLen(1))
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces[0].Subnets).To(HaveLen(1))
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces[0].SecurityGroups).To(BeEmpty())
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+	})
+	It("should update status condition as Not Ready when the subnets of an additional network interface can't be resolved", func() {
+		nodeClass.Spec.AdditionalNetworkInterfaces[0].SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{
+			{
+				Tags: map[string]string{"foo": "invalid"},
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces[0].Subnets).To(BeEmpty())
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).Message).To(Equal("Failed to resolve additional network interfaces"))
+	})
+	It("should clear the status of the additional network interfaces when they're removed", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces).To(HaveLen(1))
+
+		nodeClass.Spec.AdditionalNetworkInterfaces = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AdditionalNetworkInterfaces).To(BeNil())
+	})
+})
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// Ownership reports the subnets and security groups of the EC2NodeClass that are shared with Karpenter's account
+// through AWS RAM. Shared resources can be launched into, but their tags and attributes can only be changed by their
+// owner, so they are surfaced as a condition rather than failing the operations that would modify them.
+type Ownership struct {
+	accountID string
+}
+
+func (o *Ownership) Reconcile(_ context.Context, nodeClass *v1beta1.EC2NodeClass) (reconcile.Result, error) {
+	// The account is resolved on a best-effort basis, so ownership can't be compared without it
+	if o.accountID == "" {
+		return reconcile.Result{}, nil
+	}
+	var shared []string
+	for _, subnet := range nodeClass.Status.Subnets {
+		if subnet.OwnerID != "" && subnet.OwnerID != o.accountID {
+			shared = append(shared, fmt.Sprintf("%s (owner: %s)", subnet.ID, subnet.OwnerID))
+		}
+	}
+	for _, securityGroup := range nodeClass.Status.SecurityGroups {
+		if securityGroup.OwnerID != "" && securityGroup.OwnerID != o.accountID {
+			shared = append(shared, fmt.Sprintf("%s (owner: %s)", securityGroup.ID, securityGroup.OwnerID))
+		}
+	}
+	if len(shared) == 0 {
+		return reconcile.Result{}, nodeClass.StatusConditions().Clear(v1beta1.ConditionTypeSharedResources)
+	}
+	nodeClass.StatusConditions().SetTrueWithReason(v1beta1.ConditionTypeSharedResources, "OwnedByOtherAccounts",
+		fmt.Sprintf("selected resources are shared with account %s by their owner, %s", o.accountID, strings.Join(shared, ", ")))
+	return reconcile.Result{}, nil
+}
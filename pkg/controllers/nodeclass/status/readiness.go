@@ -45,10 +45,20 @@ func (n Readiness) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClas
 		nodeClass.StatusConditions().SetFalse(status.ConditionReady, "NodeClassNotReady", "Failed to resolve security groups")
 		return reconcile.Result{}, nil
 	}
+	if !additionalNetworkInterfacesResolved(nodeClass) {
+		nodeClass.StatusConditions().SetFalse(status.ConditionReady, "NodeClassNotReady", "Failed to resolve additional network interfaces")
+		return reconcile.Result{}, nil
+	}
 	if len(nodeClass.Status.InstanceProfile) == 0 {
 		nodeClass.StatusConditions().SetFalse(status.ConditionReady, "NodeClassNotReady", "Failed to resolve instance profile")
 		return reconcile.Result{}, nil
 	}
+	if patch := launchtemplate.LaunchTemplateDataPatch(ctx, nodeClass); patch != "" {
+		if err := launchtemplate.ValidateLaunchTemplateDataPatch(patch); err != nil {
+			nodeClass.StatusConditions().SetFalse(status.ConditionReady, "NodeClassNotReady", fmt.Sprintf("Invalid launch template data patch, %s", err))
+			return reconcile.Result{}, nil
+		}
+	}
 	// A NodeClass that uses AL2023 requires the cluster CIDR for launching nodes.
 	// To allow Karpenter to be used for Non-EKS clusters, resolving the Cluster CIDR
 	// will not be done at startup but instead in a reconcile loop.
@@ -16,8 +16,10 @@ package status_test
 
 import (
 	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -67,4 +69,40 @@ var _ = Describe("NodeClass Status Condition Controller", func() {
 		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
 		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).Message).To(Equal("Failed to resolve security groups"))
 	})
+	Context("Launch Template Data Patches", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateDataPatches: lo.ToPtr(true)}))
+		})
+		It("should be ready with a patch of fields that Karpenter doesn't manage", func() {
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationLaunchTemplateDataPatch: `[{"op": "add", "path": "/MaintenanceOptions", "value": {"AutoRecovery": "disabled"}}]`}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+		})
+		It("should not be ready with a patch of fields that Karpenter manages", func() {
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationLaunchTemplateDataPatch: `[{"op": "add", "path": "/ImageId", "value": "ami-123"}]`}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).Message).To(ContainSubstring("patching ImageId conflicts with the launch template data that Karpenter manages"))
+		})
+		It("should not be ready with a patch of fields that aren't part of the launch template data", func() {
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationLaunchTemplateDataPatch: `[{"op": "add", "path": "/MaintenanceOption", "value": {"AutoRecovery": "disabled"}}]`}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).Message).To(ContainSubstring("Invalid launch template data patch"))
+		})
+		It("should ignore the patch when launch template data patches aren't enabled", func() {
+			ctx = options.ToContext(ctx, test.Options())
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationLaunchTemplateDataPatch: `[{"op": "add", "path": "/ImageId", "value": "ami-123"}]`}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+		})
+	})
 })
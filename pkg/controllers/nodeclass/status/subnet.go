@@ -20,7 +20,7 @@ import (
 	"sort"
 	"time"
 
-	"github.com/aws/aws-sdk-go/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -47,11 +47,18 @@ func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1beta1.EC2NodeClass)
 		}
 		return *subnets[i].SubnetId < *subnets[j].SubnetId
 	})
-	nodeClass.Status.Subnets = lo.Map(subnets, func(ec2subnet *ec2.Subnet, _ int) v1beta1.Subnet {
+	zoneTypes, err := s.subnetProvider.ZoneTypes(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting zone types, %w", err)
+	}
+	nodeClass.Status.Subnets = lo.Map(subnets, func(ec2subnet ec2types.Subnet, _ int) v1beta1.Subnet {
 		return v1beta1.Subnet{
-			ID:     *ec2subnet.SubnetId,
-			Zone:   *ec2subnet.AvailabilityZone,
-			ZoneID: *ec2subnet.AvailabilityZoneId,
+			ID:         *ec2subnet.SubnetId,
+			Zone:       *ec2subnet.AvailabilityZone,
+			ZoneID:     *ec2subnet.AvailabilityZoneId,
+			ZoneType:   zoneTypes[*ec2subnet.AvailabilityZone],
+			OutpostARN: lo.FromPtr(ec2subnet.OutpostArn),
+			OwnerID:    lo.FromPtr(ec2subnet.OwnerId),
 		}
 	})
 
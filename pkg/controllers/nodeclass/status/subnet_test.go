@@ -55,24 +55,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:       "subnet-test3",
+				Zone:     "test-zone-1c",
+				ZoneID:   "tstz1-1c",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:       "subnet-test4",
+				Zone:     "test-zone-1a-local",
+				ZoneID:   "tstz1-1alocal",
+				ZoneType: "local-zone",
 			},
 		}))
 	})
@@ -87,19 +91,22 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:       "subnet-test3",
+				Zone:     "test-zone-1c",
+				ZoneID:   "tstz1-1c",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 		}))
 	})
@@ -117,14 +124,16 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 		}))
 	})
@@ -139,9 +148,10 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 		}))
 	})
@@ -151,24 +161,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:       "subnet-test3",
+				Zone:     "test-zone-1c",
+				ZoneID:   "tstz1-1c",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:       "subnet-test4",
+				Zone:     "test-zone-1a-local",
+				ZoneID:   "tstz1-1alocal",
+				ZoneType: "local-zone",
 			},
 		}))
 
@@ -189,14 +203,16 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 		}))
 	})
@@ -206,24 +222,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:       "subnet-test3",
+				Zone:     "test-zone-1c",
+				ZoneID:   "tstz1-1c",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:       "subnet-test4",
+				Zone:     "test-zone-1a-local",
+				ZoneID:   "tstz1-1alocal",
+				ZoneType: "local-zone",
 			},
 		}))
 
@@ -237,9 +257,10 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 		}))
 	})
@@ -262,24 +283,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1beta1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:       "subnet-test1",
+				Zone:     "test-zone-1a",
+				ZoneID:   "tstz1-1a",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:       "subnet-test2",
+				Zone:     "test-zone-1b",
+				ZoneID:   "tstz1-1b",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:       "subnet-test3",
+				Zone:     "test-zone-1c",
+				ZoneID:   "tstz1-1c",
+				ZoneType: "availability-zone",
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:       "subnet-test4",
+				Zone:     "test-zone-1a-local",
+				ZoneID:   "tstz1-1alocal",
+				ZoneType: "local-zone",
 			},
 		}))
 
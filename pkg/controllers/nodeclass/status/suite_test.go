@@ -18,12 +18,14 @@ import (
 	"context"
 	"testing"
 
+	"k8s.io/utils/clock"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
@@ -38,6 +40,7 @@ var env *coretest.Environment
 var awsEnv *test.Environment
 var nodeClass *v1beta1.EC2NodeClass
 var statusController *status.Controller
+var recorder *coretest.EventRecorder
 
 func TestAPIs(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -50,14 +53,21 @@ var _ = BeforeSuite(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
+	recorder = coretest.NewEventRecorder()
 
 	statusController = status.NewController(
 		env.Client,
+		clock.RealClock{},
 		awsEnv.SubnetProvider,
 		awsEnv.SecurityGroupProvider,
 		awsEnv.AMIProvider,
 		awsEnv.InstanceProfileProvider,
 		awsEnv.LaunchTemplateProvider,
+		awsEnv.KMSProvider,
+		fake.DefaultAccount,
+		awsEnv.InstanceTypesProvider,
+		awsEnv.VPCCNI,
+		recorder,
 	)
 })
 
@@ -67,8 +77,10 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
 	nodeClass = test.EC2NodeClass()
 	awsEnv.Reset()
+	recorder.Reset()
 })
 
 var _ = AfterEach(func() {
@@ -44,6 +44,7 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
 )
 
 type Controller struct {
@@ -133,5 +134,5 @@ func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 			RateLimiter:             reasonable.RateLimiter(),
 			MaxConcurrentReconciles: 10,
 		}).
-		Complete(reconcile.AsReconciler(m.GetClient(), c))
+		Complete(tracing.Reconciler("nodeclass.termination", reconcile.AsReconciler(m.GetClient(), c)))
 }
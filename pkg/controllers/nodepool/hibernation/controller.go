@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hibernation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const (
+	// pollingPeriod is how often hibernated instances are checked
+	pollingPeriod = 5 * time.Minute
+	// MaxHibernationDuration is how long an instance can stay hibernated before it's terminated, since EC2 doesn't
+	// support resuming instances that have been hibernated for longer than 60 days
+	MaxHibernationDuration = 60 * 24 * time.Hour
+)
+
+// Controller terminates the hibernated instances that won't be resumed: instances of NodePools that were deleted or
+// whose template no longer enables hibernation, and instances that have been hibernated for too long to be resumed.
+type Controller struct {
+	kubeClient       client.Client
+	clk              clock.Clock
+	instanceProvider instance.Provider
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		clk:              clk,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.hibernation")
+
+	nodePoolList := &corev1beta1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodepools, %w", err)
+	}
+	instances, err := c.instanceProvider.ListHibernated(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing hibernated instances, %w", err)
+	}
+	hibernatingNodePools := lo.SliceToMap(lo.Filter(nodePoolList.Items, func(np corev1beta1.NodePool, _ int) bool {
+		return np.DeletionTimestamp.IsZero() && np.Spec.Template.Annotations[v1beta1.AnnotationHibernation] == v1beta1.HibernationEnabled
+	}), func(np corev1beta1.NodePool) (string, struct{}) { return np.Name, struct{}{} })
+
+	var errs error
+	for _, i := range instances {
+		if i.State == ec2.InstanceStateNameShuttingDown {
+			continue
+		}
+		reason, ok := c.terminationReason(i, hibernatingNodePools)
+		if !ok {
+			continue
+		}
+		if err := c.instanceProvider.Delete(ctx, i.ID); cloudprovider.IgnoreNodeClaimNotFoundError(err) != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		log.FromContext(ctx).WithValues("id", i.ID, "NodePool", i.Tags[v1beta1.TagHibernated], "reason", reason).Info("terminated hibernated instance")
+	}
+	return reconcile.Result{RequeueAfter: pollingPeriod}, errs
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.hibernation").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("nodepool.hibernation", singleton.AsReconciler(c)))
+}
+
+// terminationReason returns why the hibernated instance is terminated, and false if it can still be resumed
+func (c *Controller) terminationReason(i *instance.Instance, hibernatingNodePools map[string]struct{}) (string, bool) {
+	if _, ok := hibernatingNodePools[i.Tags[v1beta1.TagHibernated]]; !ok {
+		return "nodepool deleted or hibernation disabled", true
+	}
+	// Instances without a valid hibernation time are aged from their launch, which is never later than their hibernation
+	hibernationTime, err := time.Parse(time.RFC3339, i.Tags[v1beta1.TagHibernationTime])
+	if err != nil {
+		hibernationTime = i.LaunchTime
+	}
+	if c.clk.Since(hibernationTime) > MaxHibernationDuration {
+		return "hibernated for too long", true
+	}
+	return "", false
+}
@@ -0,0 +1,127 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hibernation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/hibernation"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var instanceProvider *fake.InstanceProvider
+var controller *hibernation.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hibernation")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{Hibernation: lo.ToPtr(true)}))
+	fakeClock = clock.NewFakeClock(time.Now())
+	instanceProvider = fake.NewInstanceProvider()
+	controller = hibernation.NewController(env.Client, fakeClock, instanceProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now())
+	instanceProvider.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Hibernation", func() {
+	var nodePool *corev1beta1.NodePool
+	BeforeEach(func() {
+		nodePool = coretest.NodePool()
+		nodePool.Spec.Template.Annotations = map[string]string{v1beta1.AnnotationHibernation: v1beta1.HibernationEnabled}
+	})
+	hibernatedInstance := func(nodePoolName string, hibernationTime time.Time) *instance.Instance {
+		i := &instance.Instance{
+			ID:         fake.InstanceID(),
+			State:      "stopped",
+			LaunchTime: hibernationTime.Add(-time.Hour),
+			Tags: map[string]string{
+				v1beta1.TagHibernated:      nodePoolName,
+				v1beta1.TagHibernationTime: hibernationTime.Format(time.RFC3339),
+			},
+		}
+		instanceProvider.Add(i)
+		return i
+	}
+	hibernatedIDs := func() []string {
+		instances, err := instanceProvider.ListHibernated(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		return lo.Map(instances, func(i *instance.Instance, _ int) string { return i.ID })
+	}
+	It("should keep hibernated instances of NodePools that enable hibernation", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		i := hibernatedInstance(nodePool.Name, fakeClock.Now())
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(hibernatedIDs()).To(ConsistOf(i.ID))
+	})
+	It("should terminate hibernated instances of NodePools that were deleted", func() {
+		hibernatedInstance("deleted", fakeClock.Now())
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(hibernatedIDs()).To(BeEmpty())
+	})
+	It("should terminate hibernated instances of NodePools that no longer enable hibernation", func() {
+		nodePool.Spec.Template.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodePool)
+		hibernatedInstance(nodePool.Name, fakeClock.Now())
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(hibernatedIDs()).To(BeEmpty())
+	})
+	It("should terminate instances that have been hibernated for too long to be resumed", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		i := hibernatedInstance(nodePool.Name, fakeClock.Now())
+		stale := hibernatedInstance(nodePool.Name, fakeClock.Now().Add(-hibernation.MaxHibernationDuration-time.Minute))
+		ExpectSingletonReconciled(ctx, controller)
+		ids := hibernatedIDs()
+		Expect(ids).To(ContainElement(i.ID))
+		Expect(ids).ToNot(ContainElement(stale.ID))
+	})
+})
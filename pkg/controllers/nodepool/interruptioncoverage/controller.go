@@ -0,0 +1,199 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruptioncoverage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/metrics"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// pollingPeriod is the maximum amount of time before changes to the queue, its rules or NodePools are reflected
+const pollingPeriod = 5 * time.Minute
+
+// eventType is an EventBridge event that must be routed to the interruption queue for nodes to be covered
+type eventType struct {
+	source     string
+	detailType string
+}
+
+func (e eventType) String() string {
+	return e.detailType
+}
+
+var (
+	SpotInterruption = eventType{source: "aws.ec2", detailType: "EC2 Spot Instance Interruption Warning"}
+	StateChange      = eventType{source: "aws.ec2", detailType: "EC2 Instance State-change Notification"}
+	ScheduledChange  = eventType{source: "aws.health", detailType: "AWS Health Event"}
+)
+
+// Controller reports whether the nodes of each NodePool are covered by interruption handling. Nodes are covered if
+// the interruption queue is configured and reachable, and enabled EventBridge rules route the state change and
+// scheduled change events, and the spot interruption warnings of NodePools that can launch spot, to the queue.
+type Controller struct {
+	kubeClient     client.Client
+	recorder       events.Recorder
+	sqsProvider    sqs.Provider
+	eventBridgeAPI eventbridgeiface.EventBridgeAPI
+	covered        map[string]bool
+}
+
+// NewController constructs a controller for reporting interruption handling coverage. sqsProvider is nil if no
+// interruption queue is configured.
+func NewController(kubeClient client.Client, recorder events.Recorder, sqsProvider sqs.Provider, eventBridgeAPI eventbridgeiface.EventBridgeAPI) *Controller {
+	return &Controller{
+		kubeClient:     kubeClient,
+		recorder:       recorder,
+		sqsProvider:    sqsProvider,
+		eventBridgeAPI: eventBridgeAPI,
+		covered:        map[string]bool{},
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.interruptioncoverage")
+
+	nodePoolList := &corev1beta1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodepools, %w", err)
+	}
+	routed, queueErr := c.routedEventTypes(ctx)
+	current := sets.New[string]()
+	for i := range nodePoolList.Items {
+		nodePool := &nodePoolList.Items[i]
+		current.Insert(nodePool.Name)
+		reason := uncoveredReason(nodePool, routed, queueErr)
+		covered, known := c.covered[nodePool.Name]
+		c.covered[nodePool.Name] = reason == ""
+		NodePoolCovered.With(prometheus.Labels{metrics.NodePoolLabel: nodePool.Name}).Set(lo.Ternary(reason == "", 1.0, 0.0))
+		// Clusters without an interruption queue aren't covered by design, so only changes are published for them
+		if c.sqsProvider == nil && !known {
+			continue
+		}
+		switch {
+		case reason != "" && (covered || !known):
+			log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "reason", reason).Info("nodepool not covered by interruption handling")
+			c.recorder.Publish(UncoveredEvent(nodePool, reason))
+		case reason == "" && !covered:
+			c.recorder.Publish(CoveredEvent(nodePool))
+		}
+	}
+	for name := range c.covered {
+		if !current.Has(name) {
+			delete(c.covered, name)
+			NodePoolCovered.Delete(prometheus.Labels{metrics.NodePoolLabel: name})
+		}
+	}
+	return reconcile.Result{RequeueAfter: pollingPeriod}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.interruptioncoverage").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("nodepool.interruptioncoverage", singleton.AsReconciler(c)))
+}
+
+// routedEventTypes returns a predicate for the event types that enabled rules route to the interruption queue, or an
+// error if the queue isn't configured or its rules can't be read
+func (c *Controller) routedEventTypes(ctx context.Context) (func(eventType) bool, error) {
+	if c.sqsProvider == nil {
+		return nil, fmt.Errorf("no interruption queue is configured")
+	}
+	queueARN, err := c.sqsProvider.GetQueueARN(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("interruption queue %s is unreachable, %w", c.sqsProvider.Name(), err)
+	}
+	var patterns []eventPattern
+	input := &eventbridge.ListRuleNamesByTargetInput{TargetArn: aws.String(queueARN)}
+	for {
+		out, err := c.eventBridgeAPI.ListRuleNamesByTargetWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listing rules targeting interruption queue %s, %w", c.sqsProvider.Name(), err)
+		}
+		for _, name := range out.RuleNames {
+			rule, err := c.eventBridgeAPI.DescribeRuleWithContext(ctx, &eventbridge.DescribeRuleInput{Name: name})
+			if err != nil {
+				return nil, fmt.Errorf("describing rule %s, %w", aws.StringValue(name), err)
+			}
+			if aws.StringValue(rule.State) != eventbridge.RuleStateEnabled {
+				continue
+			}
+			pattern := eventPattern{}
+			if err := json.Unmarshal([]byte(aws.StringValue(rule.EventPattern)), &pattern); err != nil {
+				log.FromContext(ctx).WithValues("rule", aws.StringValue(name)).Error(err, "failed parsing event pattern")
+				continue
+			}
+			patterns = append(patterns, pattern)
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+	return func(e eventType) bool {
+		return lo.ContainsBy(patterns, func(p eventPattern) bool { return p.matches(e) })
+	}, nil
+}
+
+// eventPattern is the subset of an EventBridge event pattern that selects the event type. A rule without a
+// detail-type matches every event type of its sources.
+type eventPattern struct {
+	Source     []string `json:"source"`
+	DetailType []string `json:"detail-type"`
+}
+
+func (p eventPattern) matches(e eventType) bool {
+	return lo.Contains(p.Source, e.source) && (len(p.DetailType) == 0 || lo.Contains(p.DetailType, e.detailType))
+}
+
+// uncoveredReason returns why the nodes of the NodePool aren't covered by interruption handling, or "" if they are
+func uncoveredReason(nodePool *corev1beta1.NodePool, routed func(eventType) bool, queueErr error) string {
+	if queueErr != nil {
+		return queueErr.Error()
+	}
+	required := []eventType{StateChange, ScheduledChange}
+	if scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...).Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+		required = append(required, SpotInterruption)
+	}
+	missing := lo.Reject(required, func(e eventType, _ int) bool { return routed(e) })
+	if len(missing) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("no enabled rule routes %s events to the interruption queue", strings.Join(lo.Map(missing, func(e eventType, _ int) string { return e.String() }), ", "))
+}
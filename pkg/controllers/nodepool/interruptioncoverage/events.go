@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruptioncoverage
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func UncoveredEvent(nodePool *corev1beta1.NodePool, reason string) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           v1.EventTypeWarning,
+		Reason:         "InterruptionHandlingUncovered",
+		Message:        fmt.Sprintf("Nodes aren't covered by interruption handling, %s", reason),
+		DedupeValues:   []string{string(nodePool.UID), reason},
+	}
+}
+
+func CoveredEvent(nodePool *corev1beta1.NodePool) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           v1.EventTypeNormal,
+		Reason:         "InterruptionHandlingCovered",
+		Message:        "Nodes are covered by interruption handling",
+		DedupeValues:   []string{string(nodePool.UID)},
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruptioncoverage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+var (
+	NodePoolCovered = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "interruption",
+			Name:      "nodepool_covered",
+			Help:      "Whether the nodes of a nodepool are covered by interruption handling, 1 if the interruption queue is reachable and enabled rules route every required event type to it, 0 otherwise. Labeled by nodepool.",
+		},
+		[]string{metrics.NodePoolLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(NodePoolCovered)
+}
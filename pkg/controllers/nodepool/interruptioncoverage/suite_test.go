@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruptioncoverage_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/metrics"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/interruptioncoverage"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var sqsapi *fake.SQSAPI
+var eventbridgeapi *fake.EventBridgeAPI
+var sqsProvider *sqs.DefaultProvider
+var controller *interruptioncoverage.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "InterruptionCoverage")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	sqsapi = &fake.SQSAPI{}
+	eventbridgeapi = &fake.EventBridgeAPI{}
+	sqsProvider = lo.Must(sqs.NewDefaultProvider(sqsapi, fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/test-cluster", fake.DefaultRegion, fake.DefaultAccount)))
+	controller = interruptioncoverage.NewController(env.Client, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, eventbridgeapi)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	sqsapi.Reset()
+	eventbridgeapi.Reset()
+	for _, rule := range []*fake.EventBridgeRule{
+		{Name: "spot-interruption", EventPattern: `{"source":["aws.ec2"],"detail-type":["EC2 Spot Instance Interruption Warning"]}`},
+		{Name: "state-change", EventPattern: `{"source":["aws.ec2"],"detail-type":["EC2 Instance State-change Notification"]}`},
+		{Name: "scheduled-change", EventPattern: `{"source":["aws.health"],"detail-type":["AWS Health Event"]}`},
+	} {
+		rule.State = eventbridge.RuleStateEnabled
+		rule.Targets = []string{fake.DummyQueueARN}
+		eventbridgeapi.Rules.Add(rule)
+	}
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Interruption Coverage", func() {
+	var spotNodePool, onDemandNodePool *corev1beta1.NodePool
+	BeforeEach(func() {
+		spotNodePool = coretest.NodePool()
+		spotNodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+		}
+		onDemandNodePool = coretest.NodePool()
+		onDemandNodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+		}
+		ExpectApplied(ctx, env.Client, spotNodePool, onDemandNodePool)
+	})
+	It("should report nodepools as covered when rules route every event type to the queue", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 1, map[string]string{metrics.NodePoolLabel: spotNodePool.Name})
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 1, map[string]string{metrics.NodePoolLabel: onDemandNodePool.Name})
+	})
+	It("should only require spot interruption warnings for nodepools that can launch spot", func() {
+		eventbridgeapi.Rules.Reset()
+		eventbridgeapi.Rules.Add(&fake.EventBridgeRule{Name: "state-change", State: eventbridge.RuleStateEnabled, Targets: []string{fake.DummyQueueARN},
+			EventPattern: `{"source":["aws.ec2"],"detail-type":["EC2 Instance State-change Notification"]}`})
+		eventbridgeapi.Rules.Add(&fake.EventBridgeRule{Name: "scheduled-change", State: eventbridge.RuleStateEnabled, Targets: []string{fake.DummyQueueARN},
+			EventPattern: `{"source":["aws.health"],"detail-type":["AWS Health Event"]}`})
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: spotNodePool.Name})
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 1, map[string]string{metrics.NodePoolLabel: onDemandNodePool.Name})
+	})
+	It("should match every event type of a source when a rule doesn't select detail types", func() {
+		eventbridgeapi.Rules.Reset()
+		eventbridgeapi.Rules.Add(&fake.EventBridgeRule{Name: "ec2", State: eventbridge.RuleStateEnabled, Targets: []string{fake.DummyQueueARN},
+			EventPattern: `{"source":["aws.ec2"]}`})
+		eventbridgeapi.Rules.Add(&fake.EventBridgeRule{Name: "health", State: eventbridge.RuleStateEnabled, Targets: []string{fake.DummyQueueARN},
+			EventPattern: `{"source":["aws.health"]}`})
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 1, map[string]string{metrics.NodePoolLabel: spotNodePool.Name})
+	})
+	It("should not count disabled rules", func() {
+		eventbridgeapi.Rules.ForEach(func(rule *fake.EventBridgeRule) {
+			if rule.Name == "scheduled-change" {
+				rule.State = eventbridge.RuleStateDisabled
+			}
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: spotNodePool.Name})
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: onDemandNodePool.Name})
+	})
+	It("should not count rules that target other queues", func() {
+		eventbridgeapi.Rules.ForEach(func(rule *fake.EventBridgeRule) {
+			rule.Targets = []string{"arn:aws:sqs:us-west-2:000000000000:other-queue"}
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: onDemandNodePool.Name})
+	})
+	It("should report nodepools as uncovered when the queue is unreachable", func() {
+		sqsapi.GetQueueAttributesBehavior.Error.Set(fmt.Errorf("access denied"))
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: spotNodePool.Name})
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: onDemandNodePool.Name})
+	})
+	It("should report nodepools as uncovered when no queue is configured", func() {
+		noQueueController := interruptioncoverage.NewController(env.Client, events.NewRecorder(&record.FakeRecorder{}), nil, eventbridgeapi)
+		ExpectSingletonReconciled(ctx, noQueueController)
+		ExpectMetricGaugeValue(interruptioncoverage.NodePoolCovered, 0, map[string]string{metrics.NodePoolLabel: spotNodePool.Name})
+		Expect(eventbridgeapi.ListRuleNamesByTargetBehavior.Calls()).To(Equal(0))
+	})
+})
@@ -0,0 +1,238 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prewarm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/robfig/cron/v3"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/system"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const (
+	// PriorityClassName is the priority class of placeholder pods. The chart creates it with a negative priority so
+	// that placeholder pods are preempted by any workload that needs the pre-warmed capacity.
+	PriorityClassName = "karpenter-prewarm"
+	// PauseImage is the image of placeholder pods, which only hold their resource requests
+	PauseImage = "public.ecr.aws/eks-distro/kubernetes/pause:3.9"
+)
+
+// Controller pre-warms NodePool capacity on a schedule. While the window that starts at each scheduled time of a
+// NodePool is active, the controller keeps the annotated number of low priority placeholder pods pending against the
+// NodePool, which Karpenter provisions capacity for like any other pod. Workloads that arrive during the window
+// preempt the placeholder pods and land on the warm capacity. Once the window ends the placeholder pods are deleted
+// and consolidation removes whatever capacity is left unused.
+type Controller struct {
+	kubeClient client.Client
+	clk        clock.Clock
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+		clk:        clk,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *corev1beta1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.prewarm")
+
+	pods, err := c.placeholders(ctx, nodePool)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	var desired *v1.Pod
+	var replicas int
+	var requeueAfter time.Duration
+	if p, ok := parse(ctx, nodePool); ok && nodePool.DeletionTimestamp.IsZero() {
+		now := c.clk.Now()
+		// The window is active if it started less than its duration ago
+		start := p.schedule.Next(now.Add(-p.duration))
+		if !start.After(now) {
+			desired = placeholder(nodePool, p.requests)
+			replicas = p.replicas
+			requeueAfter = start.Add(p.duration).Sub(now)
+		} else {
+			requeueAfter = start.Sub(now)
+		}
+	}
+
+	var errs error
+	var kept int
+	for i := range pods {
+		if kept < replicas && !podutils.IsTerminal(&pods[i]) && desired != nil &&
+			equality.Semantic.DeepEqual(pods[i].Spec.Containers[0].Resources.Requests, desired.Spec.Containers[0].Resources.Requests) {
+			kept++
+			continue
+		}
+		if err := c.kubeClient.Delete(ctx, &pods[i]); client.IgnoreNotFound(err) != nil {
+			errs = multierr.Append(errs, fmt.Errorf("deleting placeholder pod, %w", err))
+		}
+	}
+	for ; kept < replicas; kept++ {
+		if err := c.kubeClient.Create(ctx, desired.DeepCopy()); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("creating placeholder pod, %w", err))
+		}
+	}
+	if errs != nil {
+		return reconcile.Result{}, errs
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// placeholders returns the placeholder pods of the NodePool that aren't already terminating
+func (c *Controller) placeholders(ctx context.Context, nodePool *corev1beta1.NodePool) ([]v1.Pod, error) {
+	pods := &v1.PodList{}
+	if err := c.kubeClient.List(ctx, pods, client.InNamespace(system.Namespace()), client.MatchingLabels{v1beta1.LabelPrewarmNodePool: nodePool.Name}); err != nil {
+		return nil, fmt.Errorf("listing placeholder pods, %w", err)
+	}
+	return lo.Filter(pods.Items, func(p v1.Pod, _ int) bool { return p.DeletionTimestamp.IsZero() }), nil
+}
+
+// placeholder returns a placeholder pod that can only schedule to nodes of the NodePool
+func placeholder(nodePool *corev1beta1.NodePool, requests v1.ResourceList) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("prewarm-%s-", nodePool.Name),
+			Namespace:    system.Namespace(),
+			Labels:       map[string]string{v1beta1.LabelPrewarmNodePool: nodePool.Name},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         object.GVK(nodePool).GroupVersion().String(),
+				Kind:               object.GVK(nodePool).Kind,
+				Name:               nodePool.Name,
+				UID:                nodePool.UID,
+				BlockOwnerDeletion: lo.ToPtr(true),
+			}},
+		},
+		Spec: v1.PodSpec{
+			PriorityClassName:             PriorityClassName,
+			NodeSelector:                  map[string]string{corev1beta1.NodePoolLabelKey: nodePool.Name},
+			TerminationGracePeriodSeconds: lo.ToPtr[int64](0),
+			Tolerations: lo.Map(append(append([]v1.Taint{}, nodePool.Spec.Template.Spec.Taints...), nodePool.Spec.Template.Spec.StartupTaints...), func(t v1.Taint, _ int) v1.Toleration {
+				return v1.Toleration{Key: t.Key, Operator: v1.TolerationOpEqual, Value: t.Value, Effect: t.Effect}
+			}),
+			Containers: []v1.Container{{
+				Name:      "pause",
+				Image:     PauseImage,
+				Resources: v1.ResourceRequirements{Requests: requests},
+			}},
+		},
+	}
+}
+
+type prewarm struct {
+	schedule cron.Schedule
+	duration time.Duration
+	replicas int
+	requests v1.ResourceList
+}
+
+// parse returns the pre-warm configuration of the NodePool, or false if it doesn't have a valid one
+func parse(ctx context.Context, nodePool *corev1beta1.NodePool) (prewarm, bool) {
+	value, ok := nodePool.Annotations[v1beta1.AnnotationPrewarmSchedule]
+	if !ok {
+		return prewarm{}, false
+	}
+	p, err := parseAnnotations(nodePool.Annotations)
+	if err != nil {
+		// We don't return an error here since retrying won't fix the annotations
+		log.FromContext(ctx).WithValues("NodePool", client.ObjectKeyFromObject(nodePool), "schedule", value).Error(err, "invalid pre-warm configuration")
+		return prewarm{}, false
+	}
+	return p, true
+}
+
+func parseAnnotations(annotations map[string]string) (prewarm, error) {
+	schedule, err := cron.ParseStandard(fmt.Sprintf("TZ=UTC %s", annotations[v1beta1.AnnotationPrewarmSchedule]))
+	if err != nil {
+		return prewarm{}, fmt.Errorf("parsing %s, %w", v1beta1.AnnotationPrewarmSchedule, err)
+	}
+	duration, err := time.ParseDuration(annotations[v1beta1.AnnotationPrewarmDuration])
+	if err != nil || duration <= 0 {
+		return prewarm{}, fmt.Errorf("%s must be a positive duration", v1beta1.AnnotationPrewarmDuration)
+	}
+	replicas, err := strconv.Atoi(annotations[v1beta1.AnnotationPrewarmReplicas])
+	if err != nil || replicas < 0 {
+		return prewarm{}, fmt.Errorf("%s must be a non-negative integer", v1beta1.AnnotationPrewarmReplicas)
+	}
+	requests, err := parseRequests(annotations[v1beta1.AnnotationPrewarmRequests])
+	if err != nil {
+		return prewarm{}, fmt.Errorf("parsing %s, %w", v1beta1.AnnotationPrewarmRequests, err)
+	}
+	return prewarm{schedule: schedule, duration: duration, replicas: replicas, requests: requests}, nil
+}
+
+// parseRequests parses resource requests of the form "cpu=2,memory=4Gi"
+func parseRequests(value string) (v1.ResourceList, error) {
+	requests := v1.ResourceList{}
+	for _, request := range strings.Split(value, ",") {
+		name, quantity, ok := strings.Cut(strings.TrimSpace(request), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected <resource>=<quantity>, got %q", request)
+		}
+		q, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, fmt.Errorf("parsing quantity of %s, %w", name, err)
+		}
+		requests[v1.ResourceName(name)] = q
+	}
+	return requests, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.prewarm").
+		For(&corev1beta1.NodePool{}).
+		Watches(
+			&v1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(func(_ context.Context, o client.Object) []reconcile.Request {
+				if name, ok := o.GetLabels()[v1beta1.LabelPrewarmNodePool]; ok && o.GetNamespace() == system.Namespace() {
+					return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: name}}}
+				}
+				return nil
+			}),
+		).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodepool.prewarm", reconcile.AsReconciler(m.GetClient(), c)))
+}
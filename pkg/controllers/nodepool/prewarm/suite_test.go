@@ -0,0 +1,185 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prewarm_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/system"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/prewarm"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *prewarm.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodePoolPrewarm")
+}
+
+var _ = BeforeSuite(func() {
+	os.Setenv(system.NamespaceEnvKey, "default")
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	fakeClock = clock.NewFakeClock(time.Now())
+	controller = prewarm.NewController(env.Client, fakeClock)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodePoolPrewarm", func() {
+	var nodePool *corev1beta1.NodePool
+
+	BeforeEach(func() {
+		// Weekdays at 08:00 UTC for an hour
+		fakeClock.SetTime(time.Date(2024, time.January, 1, 8, 30, 0, 0, time.UTC))
+		nodePool = coretest.NodePool(corev1beta1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1beta1.AnnotationPrewarmSchedule: "0 8 * * 1-5",
+					v1beta1.AnnotationPrewarmDuration: "1h",
+					v1beta1.AnnotationPrewarmReplicas: "3",
+					v1beta1.AnnotationPrewarmRequests: "cpu=2,memory=4Gi",
+				},
+			},
+		})
+	})
+	podNames := func(pods []v1.Pod) []string {
+		return lo.Map(pods, func(p v1.Pod, _ int) string { return p.Name })
+	}
+	placeholders := func() []v1.Pod {
+		pods := &v1.PodList{}
+		Expect(env.Client.List(ctx, pods, client.MatchingLabels{v1beta1.LabelPrewarmNodePool: nodePool.Name})).To(Succeed())
+		return pods.Items
+	}
+
+	It("should create placeholder pods for the nodepool while the window is active", func() {
+		nodePool.Spec.Template.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "batch", Effect: v1.TaintEffectNoSchedule}}
+		ExpectApplied(ctx, env.Client, nodePool)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(Equal(30 * time.Minute))
+
+		pods := placeholders()
+		Expect(pods).To(HaveLen(3))
+		for _, pod := range pods {
+			Expect(pod.Namespace).To(Equal("default"))
+			Expect(pod.Spec.PriorityClassName).To(Equal(prewarm.PriorityClassName))
+			Expect(pod.Spec.NodeSelector).To(HaveKeyWithValue(corev1beta1.NodePoolLabelKey, nodePool.Name))
+			Expect(pod.Spec.Tolerations).To(ContainElement(v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "batch", Effect: v1.TaintEffectNoSchedule}))
+			Expect(pod.Spec.Containers[0].Resources.Requests.Cpu().String()).To(Equal("2"))
+			Expect(pod.Spec.Containers[0].Resources.Requests.Memory().String()).To(Equal("4Gi"))
+			Expect(pod.OwnerReferences).To(HaveLen(1))
+			Expect(pod.OwnerReferences[0].UID).To(Equal(nodePool.UID))
+		}
+	})
+	It("should not create placeholder pods outside of the window", func() {
+		// Saturday
+		fakeClock.SetTime(time.Date(2024, time.January, 6, 8, 30, 0, 0, time.UTC))
+		ExpectApplied(ctx, env.Client, nodePool)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(Equal(47*time.Hour + 30*time.Minute))
+		Expect(placeholders()).To(BeEmpty())
+	})
+	It("should delete placeholder pods once the window ends", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(placeholders()).To(HaveLen(3))
+
+		fakeClock.Step(30 * time.Minute)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(Equal(23 * time.Hour))
+		Expect(placeholders()).To(BeEmpty())
+	})
+	It("should not recreate placeholder pods that already exist", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		names := podNames(placeholders())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(podNames(placeholders())).To(ConsistOf(names))
+	})
+	It("should delete extra placeholder pods when replicas are reduced", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		nodePool.Annotations[v1beta1.AnnotationPrewarmReplicas] = "1"
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(placeholders()).To(HaveLen(1))
+	})
+	It("should replace placeholder pods when requests change", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		names := podNames(placeholders())
+
+		nodePool.Annotations[v1beta1.AnnotationPrewarmRequests] = "cpu=4"
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		pods := placeholders()
+		Expect(pods).To(HaveLen(3))
+		Expect(podNames(pods)).ToNot(ContainElements(names))
+		for _, pod := range pods {
+			Expect(pod.Spec.Containers[0].Resources.Requests.Cpu().String()).To(Equal("4"))
+			Expect(pod.Spec.Containers[0].Resources.Requests).ToNot(HaveKey(v1.ResourceMemory))
+		}
+	})
+	It("should delete placeholder pods when the schedule is removed", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		delete(nodePool.Annotations, v1beta1.AnnotationPrewarmSchedule)
+		ExpectApplied(ctx, env.Client, nodePool)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(placeholders()).To(BeEmpty())
+	})
+	It("should ignore an invalid configuration", func() {
+		nodePool.Annotations[v1beta1.AnnotationPrewarmRequests] = "two cpus"
+		ExpectApplied(ctx, env.Client, nodePool)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(placeholders()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,249 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmpool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	// pollingPeriod is how often warm pools are resized and their bootstrapped instances are stopped
+	pollingPeriod = 30 * time.Second
+	// BootstrapTimeout is how long a warm pool instance has for its node to become Ready before it's terminated
+	BootstrapTimeout = 15 * time.Minute
+)
+
+// Controller maintains the warm pools of NodePools annotated with karpenter.k8s.aws/warm-pool-size. Warm pool
+// instances are launched like the instances of the NodePool, but on-demand since spot instances can't be stopped, and
+// are left running until their node is Ready so that they're fully bootstrapped. They're then stopped and their node is
+// deleted, and the node registers again when the instance is started for a NodeClaim. Instances of NodePools whose warm
+// pool shrank or that were deleted are terminated.
+type Controller struct {
+	kubeClient           client.Client
+	clk                  clock.Clock
+	instanceTypeProvider instancetype.Provider
+	instanceProvider     instance.Provider
+}
+
+func NewController(kubeClient client.Client, clk clock.Clock, instanceTypeProvider instancetype.Provider, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		clk:                  clk,
+		instanceTypeProvider: instanceTypeProvider,
+		instanceProvider:     instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.warmpool")
+
+	nodePoolList := &corev1beta1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodepools, %w", err)
+	}
+	nodeList := &v1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodeList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodes, %w", err)
+	}
+	instances, err := c.instanceProvider.ListWarm(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing warm pool instances, %w", err)
+	}
+	nodes := lo.SliceToMap(nodeList.Items, func(n v1.Node) (string, *v1.Node) { return n.Spec.ProviderID, lo.ToPtr(n) })
+	warmPools := lo.GroupBy(lo.Reject(instances, func(i *instance.Instance, _ int) bool {
+		return i.State == ec2.InstanceStateNameShuttingDown
+	}), func(i *instance.Instance) string { return i.Tags[v1beta1.TagWarmPool] })
+
+	var errs error
+	for i := range nodePoolList.Items {
+		nodePool := &nodePoolList.Items[i]
+		errs = multierr.Append(errs, c.reconcileWarmPool(ctx, nodePool, warmPools[nodePool.Name], nodes))
+		delete(warmPools, nodePool.Name)
+	}
+	// The remaining warm pools belong to NodePools that were deleted
+	for name, instances := range warmPools {
+		errs = multierr.Append(errs, c.terminate(log.IntoContext(ctx, log.FromContext(ctx).WithValues("NodePool", name)), instances, nodes))
+	}
+	return reconcile.Result{RequeueAfter: pollingPeriod}, errs
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.warmpool").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("nodepool.warmpool", singleton.AsReconciler(c)))
+}
+
+// reconcileWarmPool stops the instances of the warm pool that finished bootstrapping, and launches or terminates
+// instances so that the warm pool has the size of the NodePool annotation
+func (c *Controller) reconcileWarmPool(ctx context.Context, nodePool *corev1beta1.NodePool, instances []*instance.Instance, nodes map[string]*v1.Node) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("NodePool", nodePool.Name))
+	size := warmPoolSize(ctx, nodePool)
+	// Instances that are terminated for being excess are the newest ones, since the oldest are more likely to be stopped
+	sort.Slice(instances, func(i, j int) bool { return instances[i].LaunchTime.Before(instances[j].LaunchTime) })
+	if len(instances) > size {
+		if err := c.terminate(ctx, instances[size:], nodes); err != nil {
+			return err
+		}
+		instances = instances[:size]
+	}
+	var errs error
+	for _, i := range instances {
+		errs = multierr.Append(errs, c.stopBootstrapped(ctx, i, nodes[utils.ProviderID(i.Zone, i.ID)]))
+	}
+	for n := len(instances); n < size; n++ {
+		if err := c.launch(ctx, nodePool); err != nil {
+			return multierr.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// stopBootstrapped stops the instance once its node is Ready, and deletes the node once the instance is stopped so that
+// the kubelet can't update it anymore. The kubelet registers the node again when the instance is started. Instances
+// whose node doesn't become Ready in time are terminated.
+func (c *Controller) stopBootstrapped(ctx context.Context, i *instance.Instance, node *v1.Node) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("id", i.ID))
+	switch i.State {
+	case ec2.InstanceStateNameStopped:
+		return c.deleteNode(ctx, node)
+	case ec2.InstanceStateNameStopping:
+		return nil
+	}
+	if node == nil || nodeutils.GetCondition(node, v1.NodeReady).Status != v1.ConditionTrue {
+		if c.clk.Since(i.LaunchTime) < BootstrapTimeout {
+			return nil
+		}
+		log.FromContext(ctx).Info("terminating warm pool instance whose node didn't become ready")
+		return c.terminate(ctx, []*instance.Instance{i}, map[string]*v1.Node{utils.ProviderID(i.Zone, i.ID): node})
+	}
+	if err := c.instanceProvider.Stop(ctx, i.ID); err != nil {
+		return cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	}
+	log.FromContext(ctx).Info("stopped warm pool instance")
+	return nil
+}
+
+// launch launches an instance for the warm pool of the NodePool with a NodeClaim built from the NodePool template
+func (c *Controller) launch(ctx context.Context, nodePool *corev1beta1.NodePool) error {
+	if nodePool.Spec.Template.Spec.NodeClassRef == nil {
+		return nil
+	}
+	nodeClass := &v1beta1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("getting ec2nodeclass, %w", err))
+	}
+	nodeClaim := warmNodeClaim(nodePool)
+	instanceTypes, err := c.instanceTypeProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+	if err != nil {
+		return fmt.Errorf("listing instance types, %w", err)
+	}
+	if len(instanceTypes) == 0 {
+		log.FromContext(ctx).Info("no on-demand instance types are compatible with the warm pool")
+		return nil
+	}
+	i, err := c.instanceProvider.LaunchWarm(ctx, nodeClass, nodeClaim, instanceTypes)
+	if err != nil {
+		return fmt.Errorf("launching warm pool instance, %w", err)
+	}
+	log.FromContext(ctx).WithValues("id", i.ID, "instance-type", i.Type, "zone", i.Zone).Info("launched warm pool instance")
+	return nil
+}
+
+func (c *Controller) terminate(ctx context.Context, instances []*instance.Instance, nodes map[string]*v1.Node) error {
+	var errs error
+	for _, i := range instances {
+		if err := c.instanceProvider.Delete(ctx, i.ID); cloudprovider.IgnoreNodeClaimNotFoundError(err) != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		log.FromContext(ctx).WithValues("id", i.ID).Info("terminated warm pool instance")
+		errs = multierr.Append(errs, c.deleteNode(ctx, nodes[utils.ProviderID(i.Zone, i.ID)]))
+	}
+	return errs
+}
+
+func (c *Controller) deleteNode(ctx context.Context, node *v1.Node) error {
+	if node == nil {
+		return nil
+	}
+	if err := c.kubeClient.Delete(ctx, node); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting node, %w", err)
+	}
+	return nil
+}
+
+// warmNodeClaim returns the NodeClaim that the instances of the warm pool of the NodePool are launched for. It mirrors
+// the NodeClaims that Karpenter creates for the NodePool, restricted to on-demand.
+func warmNodeClaim(nodePool *corev1beta1.NodePool) *corev1beta1.NodeClaim {
+	labels := lo.Assign(nodePool.Spec.Template.Labels, map[string]string{corev1beta1.NodePoolLabelKey: nodePool.Name})
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+	requirements.Add(scheduling.NewLabelRequirements(labels).Values()...)
+	requirements.Add(scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeOnDemand))
+	nodeClaim := &corev1beta1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodePool.Name,
+			Labels:      labels,
+			Annotations: nodePool.Spec.Template.Annotations,
+		},
+		Spec: *nodePool.Spec.Template.Spec.DeepCopy(),
+	}
+	nodeClaim.Spec.Requirements = requirements.NodeSelectorRequirements()
+	return nodeClaim
+}
+
+// warmPoolSize returns the size of the warm pool of the NodePool, which is 0 if the annotation isn't set or is invalid
+func warmPoolSize(ctx context.Context, nodePool *corev1beta1.NodePool) int {
+	value, ok := nodePool.Annotations[v1beta1.AnnotationWarmPoolSize]
+	if !ok || !nodePool.DeletionTimestamp.IsZero() {
+		return 0
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		log.FromContext(ctx).WithValues("value", value).Error(fmt.Errorf("expected a non-negative integer"), "invalid warm pool size")
+		return 0
+	}
+	return size
+}
@@ -0,0 +1,211 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warmpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/warmpool"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var fakeClock *clock.FakeClock
+var instanceProvider *fake.InstanceProvider
+var instanceTypeProvider *fake.InstanceTypeProvider
+var controller *warmpool.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WarmPool")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{WarmPools: lo.ToPtr(true)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	instanceProvider = fake.NewInstanceProvider()
+	instanceTypeProvider = fake.NewInstanceTypeProvider()
+	controller = warmpool.NewController(env.Client, fakeClock, instanceTypeProvider, instanceProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{WarmPools: lo.ToPtr(true)}))
+	awsEnv.Reset()
+	fakeClock.SetTime(time.Now())
+	instanceProvider.Reset()
+	instanceTypeProvider.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("WarmPool", func() {
+	var nodeClass *v1beta1.EC2NodeClass
+	var nodePool *corev1beta1.NodePool
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		nodePool = coretest.NodePool(corev1beta1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1beta1.AnnotationWarmPoolSize: "2"},
+			},
+			Spec: corev1beta1.NodePoolSpec{
+				Template: corev1beta1.NodeClaimTemplate{
+					Spec: corev1beta1.NodeClaimSpec{
+						NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					},
+				},
+			},
+		})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nil, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypeProvider.SetInstanceTypes(instanceTypes)
+	})
+	warmInstances := func() []*instance.Instance {
+		instances, err := instanceProvider.ListWarm(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		return instances
+	}
+	It("should launch on-demand instances up to the warm pool size", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		instances := warmInstances()
+		Expect(instances).To(HaveLen(2))
+		for _, i := range instances {
+			Expect(i.CapacityType).To(Equal(corev1beta1.CapacityTypeOnDemand))
+			Expect(i.Tags).To(HaveKeyWithValue(v1beta1.TagWarmPool, nodePool.Name))
+		}
+		// Warm pool instances don't have NodeClaims, so they aren't listed for garbage collection
+		listed, err := instanceProvider.List(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(BeEmpty())
+
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(HaveLen(2))
+	})
+	It("should not launch instances for NodePools without a warm pool size", func() {
+		delete(nodePool.Annotations, v1beta1.AnnotationWarmPoolSize)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(BeEmpty())
+	})
+	It("should not launch instances for an invalid warm pool size", func() {
+		nodePool.Annotations[v1beta1.AnnotationWarmPoolSize] = "two"
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(BeEmpty())
+	})
+	It("should stop instances once their node is ready and then delete the node", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		instances := warmInstances()
+		ready := coretest.Node(coretest.NodeOptions{ProviderID: utils.ProviderID(instances[0].Zone, instances[0].ID)})
+		notReady := coretest.Node(coretest.NodeOptions{ProviderID: utils.ProviderID(instances[1].Zone, instances[1].ID), ReadyStatus: v1.ConditionFalse})
+		ExpectApplied(ctx, env.Client, ready, notReady)
+
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(getInstance(instances[0].ID).State).To(Equal("stopped"))
+		Expect(getInstance(instances[1].ID).State).To(Equal("running"))
+		ExpectExists(ctx, env.Client, ready)
+
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectNotFound(ctx, env.Client, ready)
+		ExpectExists(ctx, env.Client, notReady)
+		ExpectDeleted(ctx, env.Client, notReady)
+	})
+	It("should terminate instances whose node doesn't become ready in time", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		launched := warmInstances()
+		fakeClock.Step(warmpool.BootstrapTimeout + time.Minute)
+		ExpectSingletonReconciled(ctx, controller)
+		_, err := instanceProvider.Get(ctx, launched[0].ID)
+		Expect(err).To(HaveOccurred())
+		_, err = instanceProvider.Get(ctx, launched[1].ID)
+		Expect(err).To(HaveOccurred())
+		Expect(warmInstances()).To(BeEmpty())
+		// The terminated instances are replaced
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(HaveLen(2))
+	})
+	It("should terminate the newest instances when the warm pool shrinks", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		oldest := lo.Map(warmInstances(), func(i *instance.Instance, _ int) string { return i.ID })
+		time.Sleep(10 * time.Millisecond)
+		nodePool.Annotations[v1beta1.AnnotationWarmPoolSize] = "3"
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(HaveLen(3))
+
+		nodePool.Annotations[v1beta1.AnnotationWarmPoolSize] = "2"
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(lo.Map(warmInstances(), func(i *instance.Instance, _ int) string { return i.ID })).To(ConsistOf(oldest))
+
+		nodePool.Annotations[v1beta1.AnnotationWarmPoolSize] = "0"
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(BeEmpty())
+	})
+	It("should terminate the instances of deleted NodePools", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(HaveLen(2))
+		ExpectDeleted(ctx, env.Client, nodePool)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(warmInstances()).To(BeEmpty())
+	})
+})
+
+func getInstance(id string) *instance.Instance {
+	i, err := instanceProvider.Get(ctx, id)
+	Expect(err).ToNot(HaveOccurred())
+	return i
+}
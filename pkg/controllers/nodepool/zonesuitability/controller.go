@@ -0,0 +1,223 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonesuitability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const (
+	// scoreInterval is how often the zones of a NodePool are scored. Spot placement scores are throttled per account,
+	// and change slowly, so there's no benefit to requesting them more often.
+	scoreInterval = time.Hour
+	// maxScoredInstanceTypes is the number of the cheapest spot instance types of a NodePool that spot placement
+	// scores are requested for
+	maxScoredInstanceTypes = 10
+
+	// placementScoreWeight and freeIPWeight are the weights of the spot placement score and of the share of free subnet
+	// IPs in the suitability score of a zone
+	placementScoreWeight = 0.75
+	freeIPWeight         = 0.25
+	// maxPlacementScore is the highest spot placement score that EC2 returns
+	maxPlacementScore = 10
+)
+
+// Controller periodically scores the zones of interruption sensitive NodePools by combining their spot placement scores
+// and the free IPs of their subnets. The scores are published on the NodePool, and used to rank the zones of launches.
+type Controller struct {
+	kubeClient           client.Client
+	ec2api               ec2iface.EC2API
+	region               string
+	instanceTypeProvider instancetype.Provider
+	subnetProvider       subnet.Provider
+	zoneScores           *cache.ZoneScores
+}
+
+func NewController(kubeClient client.Client, ec2api ec2iface.EC2API, region string, instanceTypeProvider instancetype.Provider,
+	subnetProvider subnet.Provider, zoneScores *cache.ZoneScores) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		ec2api:               ec2api,
+		region:               region,
+		instanceTypeProvider: instanceTypeProvider,
+		subnetProvider:       subnetProvider,
+		zoneScores:           zoneScores,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *corev1beta1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.zonesuitability")
+
+	if nodePool.Labels[v1beta1.LabelInterruptionSensitive] != "true" || nodePool.Spec.Template.Spec.NodeClassRef == nil {
+		c.zoneScores.Delete(nodePool.Name)
+		return reconcile.Result{}, c.publish(ctx, nodePool, nil)
+	}
+	nodeClass := &v1beta1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	scores, err := c.score(ctx, nodePool, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	c.zoneScores.Set(nodePool.Name, scores)
+	if err := c.publish(ctx, nodePool, scores); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: scoreInterval}, nil
+}
+
+// score returns the suitability of each zone of the EC2NodeClass's subnets for the spot instances of the NodePool
+func (c *Controller) score(ctx context.Context, nodePool *corev1beta1.NodePool, nodeClass *v1beta1.EC2NodeClass) (map[string]float64, error) {
+	freeIPs := map[string]int64{}
+	// Spot placement scores identify zones by their ID, which differ between accounts, so they're mapped back to zone
+	// names through the subnets
+	zones := map[string]string{}
+	subnets, err := c.subnetProvider.List(ctx, nodeClass)
+	if err != nil {
+		return nil, fmt.Errorf("listing subnets, %w", err)
+	}
+	for _, s := range subnets {
+		freeIPs[aws.StringValue(s.AvailabilityZone)] += int64(lo.FromPtr(s.AvailableIpAddressCount))
+		zones[aws.StringValue(s.AvailabilityZoneId)] = aws.StringValue(s.AvailabilityZone)
+	}
+	if len(freeIPs) == 0 {
+		return nil, nil
+	}
+	placementScores, err := c.placementScores(ctx, nodePool, nodeClass, zones)
+	if err != nil {
+		return nil, err
+	}
+	mostFreeIPs := lo.Max(lo.Values(freeIPs))
+	return lo.MapValues(freeIPs, func(ips int64, zone string) float64 {
+		if ips == 0 {
+			return 0
+		}
+		score := placementScoreWeight*float64(placementScores[zone])/maxPlacementScore + freeIPWeight*float64(ips)/float64(mostFreeIPs)
+		return math.Round(score*100) / 100
+	}), nil
+}
+
+// placementScores returns the spot placement score of each zone, for the cheapest spot instance types of the NodePool
+func (c *Controller) placementScores(ctx context.Context, nodePool *corev1beta1.NodePool, nodeClass *v1beta1.EC2NodeClass, zones map[string]string) (map[string]int64, error) {
+	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+	if err != nil {
+		return nil, fmt.Errorf("listing instance types, %w", err)
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+	if !reqs.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+		return nil, nil
+	}
+	spotReqs := scheduling.NewRequirements(scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeSpot))
+	instanceTypes = lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+		return reqs.Compatible(it.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil && it.Offerings.Available().HasCompatible(spotReqs)
+	})
+	if len(instanceTypes) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(instanceTypes, func(i, j int) bool {
+		return instanceTypes[i].Offerings.Available().Compatible(spotReqs).Cheapest().Price < instanceTypes[j].Offerings.Available().Compatible(spotReqs).Cheapest().Price
+	})
+	out, err := c.ec2api.GetSpotPlacementScoresWithContext(ctx, &ec2.GetSpotPlacementScoresInput{
+		InstanceTypes: lo.Map(lo.Slice(instanceTypes, 0, maxScoredInstanceTypes), func(it *cloudprovider.InstanceType, _ int) *string {
+			return aws.String(it.Name)
+		}),
+		RegionNames:            aws.StringSlice([]string{c.region}),
+		SingleAvailabilityZone: aws.Bool(true),
+		TargetCapacity:         aws.Int64(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting spot placement scores, %w", err)
+	}
+	scores := map[string]int64{}
+	for _, s := range out.SpotPlacementScores {
+		if zone, ok := zones[aws.StringValue(s.AvailabilityZoneId)]; ok {
+			scores[zone] = aws.Int64Value(s.Score)
+		}
+	}
+	return scores, nil
+}
+
+// publish records the zone scores in an annotation on the NodePool, since the NodePool status has no room for
+// provider-specific fields. The annotation is removed if there are no scores.
+func (c *Controller) publish(ctx context.Context, nodePool *corev1beta1.NodePool, scores map[string]float64) error {
+	stored := nodePool.DeepCopy()
+	if len(scores) == 0 {
+		delete(nodePool.Annotations, v1beta1.AnnotationZoneSuitabilityScores)
+	} else {
+		// Maps are marshaled with sorted keys, so the annotation only changes when the scores do
+		raw, err := json.Marshal(scores)
+		if err != nil {
+			return fmt.Errorf("marshaling zone scores, %w", err)
+		}
+		nodePool.Annotations = lo.Assign(nodePool.Annotations, map[string]string{v1beta1.AnnotationZoneSuitabilityScores: string(raw)})
+	}
+	if equality.Semantic.DeepEqual(stored, nodePool) {
+		return nil
+	}
+	if err := c.kubeClient.Patch(ctx, nodePool, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if len(scores) > 0 {
+		log.FromContext(ctx).WithValues("scores", scores).V(1).Info("updated zone suitability scores")
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.zonesuitability").
+		// The scores are refreshed on an interval rather than on every status update, so that spot placement scores
+		// are only requested when the NodePool's spec or labels change
+		For(&corev1beta1.NodePool{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("nodepool.zonesuitability", reconcile.AsReconciler(m.GetClient(), c)))
+}
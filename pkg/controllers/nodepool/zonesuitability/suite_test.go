@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonesuitability_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/zonesuitability"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *zonesuitability.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ZoneSuitability")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = zonesuitability.NewController(env.Client, awsEnv.EC2API, fake.DefaultRegion, awsEnv.InstanceTypesProvider,
+		awsEnv.SubnetProvider, awsEnv.ZoneScores)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Zone Suitability", func() {
+	var nodeClass *v1beta1.EC2NodeClass
+	var nodePool *corev1beta1.NodePool
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass(v1beta1.EC2NodeClass{
+			Status: v1beta1.EC2NodeClassStatus{
+				Subnets: []v1beta1.Subnet{
+					{ID: "subnet-test1", Zone: "test-zone-1a", ZoneID: "tstz1-1a"},
+					{ID: "subnet-test2", Zone: "test-zone-1b", ZoneID: "tstz1-1b"},
+					{ID: "subnet-test3", Zone: "test-zone-1c", ZoneID: "tstz1-1c"},
+				},
+			},
+		})
+		nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"foo": "bar"}}}
+		nodePool = coretest.NodePool(corev1beta1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{v1beta1.LabelInterruptionSensitive: "true"},
+			},
+			Spec: corev1beta1.NodePoolSpec{
+				Template: corev1beta1.NodeClaimTemplate{
+					Spec: corev1beta1.NodeClaimSpec{
+						Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{
+							{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+						},
+						NodeClassRef: &corev1beta1.NodeClassReference{Name: nodeClass.Name},
+					},
+				},
+			},
+		})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		awsEnv.EC2API.GetSpotPlacementScoresBehavior.Output.Set(&ec2.GetSpotPlacementScoresOutput{
+			SpotPlacementScores: []*ec2.SpotPlacementScore{
+				{AvailabilityZoneId: aws.String("tstz1-1a"), Region: aws.String(fake.DefaultRegion), Score: aws.Int64(8)},
+				{AvailabilityZoneId: aws.String("tstz1-1b"), Region: aws.String(fake.DefaultRegion), Score: aws.Int64(4)},
+			},
+		})
+	})
+	expectScores := func() map[string]float64 {
+		GinkgoHelper()
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		scores := map[string]float64{}
+		Expect(json.Unmarshal([]byte(nodePool.Annotations[v1beta1.AnnotationZoneSuitabilityScores]), &scores)).To(Succeed())
+		cached, ok := awsEnv.ZoneScores.Get(nodePool.Name)
+		Expect(ok).To(BeTrue())
+		Expect(cached).To(Equal(scores))
+		return scores
+	}
+	It("should combine spot placement scores and free IPs into zone scores", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).ToNot(BeZero())
+
+		Expect(expectScores()).To(Equal(map[string]float64{"test-zone-1a": 0.85, "test-zone-1b": 0.55, "test-zone-1c": 0.25}))
+		Expect(awsEnv.EC2API.GetSpotPlacementScoresBehavior.Calls()).To(Equal(1))
+		input := awsEnv.EC2API.GetSpotPlacementScoresBehavior.CalledWithInput.Pop()
+		Expect(aws.BoolValue(input.SingleAvailabilityZone)).To(BeTrue())
+		Expect(aws.StringValueSlice(input.RegionNames)).To(ConsistOf(fake.DefaultRegion))
+		Expect(len(input.InstanceTypes)).To(BeNumerically(">", 0))
+		Expect(len(input.InstanceTypes)).To(BeNumerically("<=", 10))
+	})
+	It("should score zones on free IPs only when the NodePool can't launch spot instances", func() {
+		nodePool.Spec.Template.Spec.Requirements[0].Values = []string{corev1beta1.CapacityTypeOnDemand}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		Expect(expectScores()).To(Equal(map[string]float64{"test-zone-1a": 0.25, "test-zone-1b": 0.25, "test-zone-1c": 0.25}))
+		Expect(awsEnv.EC2API.GetSpotPlacementScoresBehavior.Calls()).To(Equal(0))
+	})
+	It("should remove the scores when the NodePool is no longer interruption sensitive", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		expectScores()
+
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		delete(nodePool.Labels, v1beta1.LabelInterruptionSensitive)
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Annotations).ToNot(HaveKey(v1beta1.AnnotationZoneSuitabilityScores))
+		_, ok := awsEnv.ZoneScores.Get(nodePool.Name)
+		Expect(ok).To(BeFalse())
+		Expect(awsEnv.EC2API.GetSpotPlacementScoresBehavior.Calls()).To(Equal(1))
+	})
+	It("should not score NodePools that aren't interruption sensitive", func() {
+		nodePool.Labels = nil
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Annotations).ToNot(HaveKey(v1beta1.AnnotationZoneSuitabilityScores))
+		Expect(awsEnv.EC2API.GetSpotPlacementScoresBehavior.Calls()).To(Equal(0))
+	})
+})
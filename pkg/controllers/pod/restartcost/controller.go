@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restartcost
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const (
+	// MaxRestartCost is the most that a pod's restart cost can add to the cost of disrupting it. Consolidation clamps the
+	// cost of evicting a single pod to 10, where a pod without a deletion cost or priority costs 1.
+	MaxRestartCost = 9.0
+	// podDeletionCostScale is the pod deletion cost that consolidation counts as the cost of evicting one more pod
+	podDeletionCostScale = 1 << 27
+)
+
+// Controller translates the restart cost annotation of pods and namespaces into the pod deletion cost, which
+// consolidation adds to the disruption cost of the pod's node when ranking candidates. Every minute of estimated
+// restart time counts as much as evicting one more pod, up to MaxRestartCost.
+type Controller struct {
+	kubeClient client.Client
+}
+
+func NewController(kubeClient client.Client) *Controller {
+	return &Controller{kubeClient: kubeClient}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, pod *v1.Pod) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "pod.restartcost")
+
+	if !pod.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	current, managed := pod.Annotations[v1.PodDeletionCost], pod.Annotations[v1beta1.AnnotationManagedPodDeletionCost]
+	// The pod deletion cost was set by someone else, who knows better than the restart cost
+	if current != "" && current != managed {
+		return reconcile.Result{}, nil
+	}
+	restartCost, err := c.restartCost(ctx, pod)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	desired := lo.Ternary(restartCost == 0, "", strconv.Itoa(int(restartCost*podDeletionCostScale)))
+	if desired == current {
+		return reconcile.Result{}, nil
+	}
+	stored := pod.DeepCopy()
+	if desired == "" {
+		delete(pod.Annotations, v1.PodDeletionCost)
+		delete(pod.Annotations, v1beta1.AnnotationManagedPodDeletionCost)
+	} else {
+		pod.Annotations = lo.Assign(pod.Annotations, map[string]string{
+			v1.PodDeletionCost:                       desired,
+			v1beta1.AnnotationManagedPodDeletionCost: desired,
+		})
+	}
+	if err = c.kubeClient.Patch(ctx, pod, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// restartCost returns the restart cost of the pod in units of evicted pods. The pod's annotation takes precedence over
+// its namespace's.
+func (c *Controller) restartCost(ctx context.Context, pod *v1.Pod) (float64, error) {
+	value, ok := pod.Annotations[v1beta1.AnnotationRestartCost]
+	if !ok {
+		namespace := &v1.Namespace{}
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: pod.Namespace}, namespace); err != nil {
+			return 0, client.IgnoreNotFound(fmt.Errorf("getting namespace, %w", err))
+		}
+		if value, ok = namespace.Annotations[v1beta1.AnnotationRestartCost]; !ok {
+			return 0, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("restart cost must not be negative")
+	}
+	if err != nil {
+		// We don't return an error here since retrying won't fix the annotation
+		log.FromContext(ctx).WithValues("Pod", client.ObjectKeyFromObject(pod), "value", value).Error(err, "invalid restart cost")
+		return 0, nil
+	}
+	return math.Min(d.Minutes(), MaxRestartCost), nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("pod.restartcost").
+		For(&v1.Pod{}).
+		Watches(
+			&v1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+				pods := &v1.PodList{}
+				if err := m.GetClient().List(ctx, pods, client.InNamespace(o.GetName())); err != nil {
+					return nil
+				}
+				return lo.Map(pods.Items, func(p v1.Pod, _ int) reconcile.Request {
+					return reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&p)}
+				})
+			}),
+		).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(tracing.Reconciler("pod.restartcost", reconcile.AsReconciler(m.GetClient(), c)))
+}
@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restartcost_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/pod/restartcost"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var controller *restartcost.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RestartCostController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	controller = restartcost.NewController(env.Client)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("RestartCostController", func() {
+	var namespace *v1.Namespace
+	var pod *v1.Pod
+
+	BeforeEach(func() {
+		namespace = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: coretest.RandomName()}}
+		pod = coretest.Pod(coretest.PodOptions{ObjectMeta: metav1.ObjectMeta{Namespace: namespace.Name}})
+	})
+
+	It("should set the pod deletion cost from the pod's restart cost", func() {
+		pod.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "3m"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(v1.PodDeletionCost, strconv.Itoa(3<<27)))
+		Expect(pod.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationManagedPodDeletionCost, strconv.Itoa(3<<27)))
+	})
+	It("should set the pod deletion cost from the namespace's restart cost", func() {
+		namespace.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "2m"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(v1.PodDeletionCost, strconv.Itoa(2<<27)))
+	})
+	It("should prefer the pod's restart cost to the namespace's", func() {
+		namespace.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "2m"}
+		pod.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "1m"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(v1.PodDeletionCost, strconv.Itoa(1<<27)))
+	})
+	It("should cap the restart cost", func() {
+		pod.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "20m"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(v1.PodDeletionCost, strconv.Itoa(int(restartcost.MaxRestartCost)<<27)))
+	})
+	It("should not overwrite a pod deletion cost that it didn't set", func() {
+		pod.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "3m", v1.PodDeletionCost: "100"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(v1.PodDeletionCost, "100"))
+		Expect(pod.Annotations).ToNot(HaveKey(v1beta1.AnnotationManagedPodDeletionCost))
+	})
+	It("should remove the pod deletion cost when the restart cost is removed", func() {
+		pod.Annotations = map[string]string{v1.PodDeletionCost: "42", v1beta1.AnnotationManagedPodDeletionCost: "42"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).ToNot(HaveKey(v1.PodDeletionCost))
+		Expect(pod.Annotations).ToNot(HaveKey(v1beta1.AnnotationManagedPodDeletionCost))
+	})
+	It("should ignore restart costs that aren't durations", func() {
+		pod.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "expensive"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).ToNot(HaveKey(v1.PodDeletionCost))
+	})
+	It("should ignore negative restart costs", func() {
+		pod.Annotations = map[string]string{v1beta1.AnnotationRestartCost: "-5m"}
+		ExpectApplied(ctx, env.Client, namespace, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, pod)
+
+		pod = ExpectExists(ctx, env.Client, pod)
+		Expect(pod.Annotations).ToNot(HaveKey(v1.PodDeletionCost))
+	})
+})
@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blocklist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/system"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// ConfigMapName is the name of the ConfigMap in Karpenter's namespace that holds the instance type blocklist.
+// Each data entry is keyed by a free-form identifier (e.g. an incident ID) and holds a JSON encoded BlockedOffering:
+//
+//	data:
+//	  incident-1234: '{"instanceType":"m7i.large","zone":"us-west-2a","expiration":"2024-08-01T00:00:00Z"}'
+const ConfigMapName = "karpenter-instance-type-blocklist"
+
+// pollingPeriod is the maximum amount of time before changes to the ConfigMap are reflected in the blocklist
+const pollingPeriod = time.Minute
+
+// Controller periodically reads the blocklist ConfigMap and hydrates the blocked offerings that the instancetype provider
+// consults when building offerings. The ConfigMap is read directly from the API server, rather than through an informer,
+// so that Karpenter only needs access to this single ConfigMap.
+type Controller struct {
+	kubeReader       client.Reader
+	clk              clock.Clock
+	blockedOfferings *cache.BlockedOfferings
+}
+
+func NewController(kubeReader client.Reader, clk clock.Clock, blockedOfferings *cache.BlockedOfferings) *Controller {
+	return &Controller{
+		kubeReader:       kubeReader,
+		clk:              clk,
+		blockedOfferings: blockedOfferings,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.blocklist")
+
+	cm := &v1.ConfigMap{}
+	if err := c.kubeReader.Get(ctx, types.NamespacedName{Namespace: system.Namespace(), Name: ConfigMapName}, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("getting blocklist configmap, %w", err)
+		}
+		// A missing ConfigMap means that nothing is blocked
+		cm = &v1.ConfigMap{}
+	}
+	var offerings []cache.BlockedOffering
+	for _, key := range lo.Keys(cm.Data) {
+		offering := cache.BlockedOffering{}
+		if err := json.Unmarshal([]byte(cm.Data[key]), &offering); err != nil {
+			log.FromContext(ctx).WithValues("key", key).Error(err, "failed parsing blocklist entry")
+			continue
+		}
+		if offering.InstanceType == "" || offering.Expiration.IsZero() {
+			log.FromContext(ctx).WithValues("key", key).Error(fmt.Errorf("instanceType and expiration are required"), "failed parsing blocklist entry")
+			continue
+		}
+		offerings = append(offerings, offering)
+	}
+	if c.blockedOfferings.Set(offerings) {
+		blocked := lo.FilterMap(offerings, func(o cache.BlockedOffering, _ int) (string, bool) {
+			return fmt.Sprintf("%s/%s", o.InstanceType, lo.Ternary(o.Zone == "", "*", o.Zone)), c.clk.Now().Before(o.Expiration)
+		})
+		sort.Strings(blocked)
+		log.FromContext(ctx).WithValues("offerings", blocked).Info("updated blocked offerings")
+	}
+	// Requeue early if an entry expires before the next poll so that offerings become available again promptly
+	requeueAfter := pollingPeriod
+	if expiration, ok := c.blockedOfferings.NextExpiration(); ok {
+		requeueAfter = lo.Clamp(expiration.Sub(c.clk.Now()), time.Second, pollingPeriod)
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.blocklist").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.blocklist", singleton.AsReconciler(c)))
+}
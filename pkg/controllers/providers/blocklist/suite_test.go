@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blocklist_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/system"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	controllersblocklist "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/blocklist"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var blockedOfferings *cache.BlockedOfferings
+var controller *controllersblocklist.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Blocklist")
+}
+
+var _ = BeforeSuite(func() {
+	lo.Must0(os.Setenv(system.NamespaceEnvKey, "default"))
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	fakeClock = clock.NewFakeClock(time.Now())
+	blockedOfferings = cache.NewBlockedOfferings(fakeClock)
+	controller = controllersblocklist.NewController(env.Client, fakeClock, blockedOfferings)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	blockedOfferings.Flush()
+})
+
+var _ = Describe("Blocklist", func() {
+	var cm *v1.ConfigMap
+	BeforeEach(func() {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: controllersblocklist.ConfigMapName, Namespace: "default"},
+			Data: map[string]string{
+				"incident-1": `{"instanceType":"m5.large","zone":"test-zone-1a","expiration":"` + fakeClock.Now().Add(time.Hour).Format(time.RFC3339) + `"}`,
+				"incident-2": `{"instanceType":"c5.large","expiration":"` + fakeClock.Now().Add(2*time.Hour).Format(time.RFC3339) + `"}`,
+			},
+		}
+	})
+	AfterEach(func() {
+		ExpectDeleted(ctx, env.Client, cm)
+	})
+	It("should block offerings listed in the configmap", func() {
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(blockedOfferings.IsBlocked("m5.large", "test-zone-1a")).To(BeTrue())
+		Expect(blockedOfferings.IsBlocked("m5.large", "test-zone-1b")).To(BeFalse())
+		Expect(blockedOfferings.IsBlocked("c5.large", "test-zone-1a")).To(BeTrue())
+		Expect(blockedOfferings.IsBlocked("c5.large", "test-zone-1b")).To(BeTrue())
+	})
+	It("should ignore malformed entries", func() {
+		cm.Data["incident-3"] = `{"zone":"test-zone-1a"}`
+		cm.Data["incident-4"] = `not-json`
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(blockedOfferings.IsBlocked("m5.large", "test-zone-1a")).To(BeTrue())
+		Expect(blockedOfferings.IsBlocked("", "test-zone-1a")).To(BeFalse())
+	})
+	It("should unblock offerings once they expire", func() {
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		seqNum := blockedOfferings.SeqNum
+
+		fakeClock.Step(90 * time.Minute)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(blockedOfferings.IsBlocked("m5.large", "test-zone-1a")).To(BeFalse())
+		Expect(blockedOfferings.IsBlocked("c5.large", "test-zone-1a")).To(BeTrue())
+		Expect(blockedOfferings.SeqNum).To(BeNumerically(">", seqNum))
+	})
+	It("should unblock all offerings when the configmap is removed", func() {
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectDeleted(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(blockedOfferings.IsBlocked("m5.large", "test-zone-1a")).To(BeFalse())
+		Expect(blockedOfferings.IsBlocked("c5.large", "test-zone-1a")).To(BeFalse())
+	})
+	It("should not change the sequence number when the blocklist is unchanged", func() {
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		seqNum := blockedOfferings.SeqNum
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(blockedOfferings.SeqNum).To(Equal(seqNum))
+	})
+})
@@ -24,13 +24,17 @@ import (
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	controllersinstancetype "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -167,4 +171,45 @@ var _ = Describe("InstanceType", func() {
 		_, err := awsEnv.InstanceTypesProvider.List(ctx, &corev1beta1.KubeletConfiguration{}, &v1beta1.EC2NodeClass{})
 		Expect(err).ToNot(BeNil())
 	})
+	It("should use the instance types and offerings from the instance type snapshot instead of the EC2 API", func() {
+		ec2InstanceTypes := fake.MakeInstances()
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{
+			InstanceTypes: ec2InstanceTypes,
+		})
+		awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+			InstanceTypeOfferings: fake.MakeInstanceOfferings(ec2InstanceTypes),
+		})
+		snapshotInstanceTypes := lo.Filter(ec2InstanceTypes, func(info *ec2.InstanceTypeInfo, _ int) bool {
+			return lo.FromPtr(info.InstanceType) == "m5.large"
+		})
+		Expect(snapshotInstanceTypes).To(HaveLen(1))
+		provider := instancetype.NewDefaultProvider(fake.DefaultRegion, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), awsEnv.EC2API, awsEnv.SubnetProvider,
+			awsEnv.UnavailableOfferingsCache, awsEnv.BlockedOfferingsCache, awsEnv.ZoneHealth, awsEnv.VPCCNI, awsEnv.VCPUQuotaHeadroom, awsEnv.PricingProvider, &snapshot.Snapshot{
+				Region:        fake.DefaultRegion,
+				InstanceTypes: snapshotInstanceTypes,
+				Offerings:     map[string][]string{"m5.large": {"test-zone-1b"}},
+			})
+
+		ExpectSingletonReconciled(ctx, controllersinstancetype.NewController(provider))
+		instanceTypes, err := provider.List(ctx, &corev1beta1.KubeletConfiguration{}, &v1beta1.EC2NodeClass{
+			Status: v1beta1.EC2NodeClassStatus{
+				Subnets: []v1beta1.Subnet{
+					{
+						ID:   "subnet-test1",
+						Zone: "test-zone-1a",
+					},
+					{
+						ID:   "subnet-test2",
+						Zone: "test-zone-1b",
+					},
+				},
+			},
+		})
+		Expect(err).To(BeNil())
+		Expect(instanceTypes).To(HaveLen(1))
+		Expect(instanceTypes[0].Name).To(Equal("m5.large"))
+		for _, offering := range instanceTypes[0].Offerings {
+			Expect(offering.Requirements.Get(v1.LabelTopologyZone).Any()).To(Equal("test-zone-1b"))
+		}
+	})
 })
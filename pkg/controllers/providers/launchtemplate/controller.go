@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// syncPeriod is the maximum amount of time before launch templates that are deleted out of band are invalidated
+const syncPeriod = 5 * time.Minute
+
+// Controller periodically syncs the launch template cache with the launch templates in EC2, so that launches don't
+// fail on cached launch templates that were deleted out of band. The first sync hydrates the cache once elected leader.
+type Controller struct {
+	launchTemplateProvider launchtemplate.Provider
+}
+
+func NewController(launchTemplateProvider launchtemplate.Provider) *Controller {
+	return &Controller{
+		launchTemplateProvider: launchTemplateProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.launchtemplate")
+
+	if err := c.launchTemplateProvider.SyncCache(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("syncing launch template cache, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: syncPeriod}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.launchtemplate").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.launchtemplate", singleton.AsReconciler(c)))
+}
@@ -0,0 +1,62 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const garbageCollectionPeriod = time.Hour
+
+// Controller deletes the launch templates of the cluster that have been unused for longer than the configured age. These
+// are normally deleted as they expire from the launch template cache, but leak when Karpenter isn't running to delete
+// them, e.g. when a cluster is deleted and recreated with the same name without uninstalling Karpenter.
+type Controller struct {
+	launchTemplateProvider launchtemplate.Provider
+}
+
+func NewController(launchTemplateProvider launchtemplate.Provider) *Controller {
+	return &Controller{
+		launchTemplateProvider: launchTemplateProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.launchtemplate.garbagecollection")
+
+	if err := c.launchTemplateProvider.DeleteUnreferenced(ctx, options.FromContext(ctx).LaunchTemplateGarbageCollectionAge); err != nil {
+		return reconcile.Result{}, fmt.Errorf("garbage collecting launch templates, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: garbageCollectionPeriod}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.launchtemplate.garbagecollection").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.launchtemplate.garbagecollection", singleton.AsReconciler(c)))
+}
@@ -0,0 +1,126 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/launchtemplate/garbagecollection"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *garbagecollection.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchTemplateGarbageCollection")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateGarbageCollectionAge: lo.ToPtr(24 * time.Hour)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = garbagecollection.NewController(awsEnv.LaunchTemplateProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateGarbageCollectionAge: lo.ToPtr(24 * time.Hour)}))
+	awsEnv.Reset()
+})
+
+var _ = Describe("LaunchTemplateGarbageCollection", func() {
+	managedLaunchTemplate := func(name string, createTime time.Time) *ec2.LaunchTemplate {
+		return &ec2.LaunchTemplate{
+			LaunchTemplateName: aws.String(name),
+			LaunchTemplateId:   aws.String("lt-" + name),
+			CreateTime:         aws.Time(createTime),
+			Tags:               []*ec2.Tag{{Key: aws.String(v1beta1.TagManagedLaunchTemplate), Value: aws.String(options.FromContext(ctx).ClusterName)}},
+		}
+	}
+	expectLaunchTemplateExists := func(name string, exists bool) {
+		_, ok := awsEnv.EC2API.LaunchTemplates.Load(name)
+		ExpectWithOffset(1, ok).To(Equal(exists))
+	}
+	It("should delete launch templates of the cluster that are unreferenced and older than the age", func() {
+		awsEnv.EC2API.LaunchTemplates.Store("leaked", managedLaunchTemplate("leaked", time.Now().Add(-48*time.Hour)))
+		ExpectSingletonReconciled(ctx, controller)
+		expectLaunchTemplateExists("leaked", false)
+	})
+	It("should not delete launch templates that are younger than the age", func() {
+		awsEnv.EC2API.LaunchTemplates.Store("recent", managedLaunchTemplate("recent", time.Now().Add(-time.Hour)))
+		ExpectSingletonReconciled(ctx, controller)
+		expectLaunchTemplateExists("recent", true)
+	})
+	It("should not delete launch templates of other clusters", func() {
+		lt := managedLaunchTemplate("other", time.Now().Add(-48*time.Hour))
+		lt.Tags = []*ec2.Tag{{Key: aws.String(v1beta1.TagManagedLaunchTemplate), Value: aws.String("other-cluster")}}
+		awsEnv.EC2API.LaunchTemplates.Store("other", lt)
+		ExpectSingletonReconciled(ctx, controller)
+		expectLaunchTemplateExists("other", true)
+	})
+	It("should not delete launch templates that are cached", func() {
+		lt := managedLaunchTemplate("cached", time.Now().Add(-48*time.Hour))
+		awsEnv.EC2API.LaunchTemplates.Store("cached", lt)
+		awsEnv.LaunchTemplateCache.SetDefault("cached", lt)
+		ExpectSingletonReconciled(ctx, controller)
+		expectLaunchTemplateExists("cached", true)
+	})
+	It("should not delete launch templates that instances reference", func() {
+		awsEnv.EC2API.LaunchTemplates.Store("referenced", managedLaunchTemplate("referenced", time.Now().Add(-48*time.Hour)))
+		awsEnv.EC2API.Instances.Store("i-test", &ec2.Instance{
+			InstanceId: aws.String("i-test"),
+			State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			Tags:       []*ec2.Tag{{Key: aws.String("aws:ec2launchtemplate:id"), Value: aws.String("lt-referenced")}},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		expectLaunchTemplateExists("referenced", true)
+	})
+	It("should delete launch templates that only terminated instances reference", func() {
+		awsEnv.EC2API.LaunchTemplates.Store("terminated", managedLaunchTemplate("terminated", time.Now().Add(-48*time.Hour)))
+		awsEnv.EC2API.Instances.Store("i-test", &ec2.Instance{
+			InstanceId: aws.String("i-test"),
+			State:      &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameTerminated)},
+			Tags:       []*ec2.Tag{{Key: aws.String("aws:ec2launchtemplate:id"), Value: aws.String("lt-terminated")}},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		expectLaunchTemplateExists("terminated", false)
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *launchtemplate.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchTemplate")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = launchtemplate.NewController(awsEnv.LaunchTemplateProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+var _ = Describe("LaunchTemplate", func() {
+	managedLaunchTemplate := func(name string, createTime time.Time) *ec2.LaunchTemplate {
+		return &ec2.LaunchTemplate{
+			LaunchTemplateName: aws.String(name),
+			LaunchTemplateId:   aws.String("lt-" + name),
+			CreateTime:         aws.Time(createTime),
+			Tags:               []*ec2.Tag{{Key: aws.String(v1beta1.TagManagedLaunchTemplate), Value: aws.String(options.FromContext(ctx).ClusterName)}},
+		}
+	}
+	It("should hydrate the cache with the launch templates of the cluster", func() {
+		awsEnv.EC2API.LaunchTemplates.Store("karpenter.k8s.aws/managed", managedLaunchTemplate("karpenter.k8s.aws/managed", time.Now().Add(-time.Hour)))
+		awsEnv.EC2API.LaunchTemplates.Store("unmanaged", &ec2.LaunchTemplate{LaunchTemplateName: aws.String("unmanaged")})
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, ok := awsEnv.LaunchTemplateCache.Get("karpenter.k8s.aws/managed")
+		Expect(ok).To(BeTrue())
+		_, ok = awsEnv.LaunchTemplateCache.Get("unmanaged")
+		Expect(ok).To(BeFalse())
+	})
+	It("should invalidate cached launch templates that were deleted out of band", func() {
+		invalidations := func() float64 {
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_launch_template_cache_events_total", map[string]string{"event": "invalidation"})
+			if !ok {
+				return 0
+			}
+			return metric.GetCounter().GetValue()
+		}
+		before := invalidations()
+		awsEnv.LaunchTemplateCache.SetDefault("karpenter.k8s.aws/deleted", managedLaunchTemplate("karpenter.k8s.aws/deleted", time.Now().Add(-time.Hour)))
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, ok := awsEnv.LaunchTemplateCache.Get("karpenter.k8s.aws/deleted")
+		Expect(ok).To(BeFalse())
+		Expect(invalidations()).To(Equal(before + 1))
+	})
+	It("should keep launch templates that were just created before they're listed", func() {
+		awsEnv.LaunchTemplateCache.SetDefault("karpenter.k8s.aws/created", managedLaunchTemplate("karpenter.k8s.aws/created", time.Now()))
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, ok := awsEnv.LaunchTemplateCache.Get("karpenter.k8s.aws/created")
+		Expect(ok).To(BeTrue())
+	})
+	It("should not extend the expiration of cached launch templates", func() {
+		lt := managedLaunchTemplate("karpenter.k8s.aws/cached", time.Now().Add(-time.Hour))
+		awsEnv.EC2API.LaunchTemplates.Store("karpenter.k8s.aws/cached", lt)
+		awsEnv.LaunchTemplateCache.Set("karpenter.k8s.aws/cached", lt, time.Minute)
+		_, expiration, _ := awsEnv.LaunchTemplateCache.GetWithExpiration("karpenter.k8s.aws/cached")
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, updatedExpiration, ok := awsEnv.LaunchTemplateCache.GetWithExpiration("karpenter.k8s.aws/cached")
+		Expect(ok).To(BeTrue())
+		Expect(updatedExpiration).To(Equal(expiration))
+	})
+})
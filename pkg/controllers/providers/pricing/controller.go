@@ -22,16 +22,30 @@ import (
 	"github.com/awslabs/operatorpkg/singleton"
 	lop "github.com/samber/lo/parallel"
 	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/wait"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const (
+	// spotPricingInterval is how often spot prices are refreshed. Spot prices change far more often than on-demand
+	// prices, so consolidation would otherwise compare on-demand prices to spot prices that are hours old.
+	spotPricingInterval = time.Hour
+	// spotPricingJitter spreads the spot price refreshes of clusters that started together, so that they don't all call
+	// DescribeSpotPriceHistory at once
+	spotPricingJitter       = 0.2
+	onDemandPricingInterval = 12 * time.Hour
 )
 
 type Controller struct {
 	pricingProvider pricing.Provider
+	// onDemandPricingUpdated is when on-demand prices were last updated successfully
+	onDemandPricingUpdated time.Time
 }
 
 func NewController(pricingProvider pricing.Provider) *Controller {
@@ -45,7 +59,9 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 
 	work := []func(ctx context.Context) error{
 		c.pricingProvider.UpdateSpotPricing,
-		c.pricingProvider.UpdateOnDemandPricing,
+	}
+	if time.Since(c.onDemandPricingUpdated) >= onDemandPricingInterval {
+		work = append(work, c.updateOnDemandPricing)
 	}
 	errs := make([]error, len(work))
 	lop.ForEach(work, func(f func(ctx context.Context) error, i int) {
@@ -56,12 +72,20 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err := multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, fmt.Errorf("updating pricing, %w", err)
 	}
-	return reconcile.Result{RequeueAfter: 12 * time.Hour}, nil
+	return reconcile.Result{RequeueAfter: wait.Jitter(spotPricingInterval, spotPricingJitter)}, nil
+}
+
+func (c *Controller) updateOnDemandPricing(ctx context.Context) error {
+	if err := c.pricingProvider.UpdateOnDemandPricing(ctx); err != nil {
+		return err
+	}
+	c.onDemandPricingUpdated = time.Now()
+	return nil
 }
 
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("providers.pricing").
 		WatchesRawSource(singleton.Source()).
-		Complete(singleton.AsReconciler(c))
+		Complete(tracing.Reconciler("providers.pricing", singleton.AsReconciler(c)))
 }
@@ -16,13 +16,17 @@ package pricing_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awspricing "github.com/aws/aws-sdk-go-v2/service/pricing"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	awspricing "github.com/aws/aws-sdk-go/service/pricing"
 	"github.com/samber/lo"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
@@ -32,6 +36,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -58,7 +63,6 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	ctx, stop = context.WithCancel(ctx)
 	awsEnv = test.NewEnvironment(ctx, env)
-	controller = controllerspricing.NewController(awsEnv.PricingProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -71,6 +75,7 @@ var _ = BeforeEach(func() {
 	ctx = options.ToContext(ctx, test.Options())
 
 	awsEnv.Reset()
+	controller = controllerspricing.NewController(awsEnv.PricingProvider)
 })
 
 var _ = AfterEach(func() {
@@ -82,7 +87,7 @@ var _ = Describe("Pricing", func() {
 		"should return correct static data for all partitions",
 		func(staticPricing map[string]map[string]float64) {
 			for region, prices := range staticPricing {
-				provider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, region)
+				provider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, fake.NewEC2V2API(awsEnv.EC2API), region, nil)
 				for instance, price := range prices {
 					val, ok := provider.OnDemandPrice(instance)
 					Expect(ok).To(BeTrue())
@@ -112,7 +117,7 @@ var _ = Describe("Pricing", func() {
 		// modify our API before creating the pricing provider as it performs an initial update on creation. The pricing
 		// API provides on-demand prices, the ec2 API provides spot prices
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPrice("c98.large", 1.20),
 				fake.NewOnDemandPrice("c99.large", 1.23),
 			},
@@ -158,7 +163,7 @@ var _ = Describe("Pricing", func() {
 			},
 		})
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPrice("c98.large", 1.20),
 				fake.NewOnDemandPrice("c99.large", 1.23),
 			},
@@ -192,7 +197,7 @@ var _ = Describe("Pricing", func() {
 			},
 		})
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPrice("c98.large", 1.20),
 				fake.NewOnDemandPrice("c99.large", 1.23),
 			},
@@ -206,6 +211,181 @@ var _ = Describe("Pricing", func() {
 		_, ok = awsEnv.PricingProvider.SpotPrice("c98.large", "test-zone-1b")
 		Expect(ok).ToNot(BeTrue())
 	})
+	It("should use the newest spot price of an instance type in a zone", func() {
+		now := time.Now()
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("c99.large"),
+					SpotPrice:        aws.String("1.23"),
+					Timestamp:        &now,
+				},
+				{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("c99.large"),
+					SpotPrice:        aws.String("2.00"),
+					Timestamp:        aws.Time(now.Add(-time.Hour)),
+				},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{
+				fake.NewOnDemandPrice("c99.large", 1.23),
+			},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.SpotPrice("c99.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.23))
+	})
+	It("should refresh spot pricing more often than on-demand pricing", func() {
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("c99.large"),
+					SpotPrice:        aws.String("1.23"),
+					Timestamp:        aws.Time(time.Now()),
+				},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{
+				fake.NewOnDemandPrice("c99.large", 1.23),
+			},
+		})
+		result := ExpectSingletonReconciled(ctx, controller)
+		Expect(result.RequeueAfter).To(BeNumerically(">=", time.Hour))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", 72*time.Minute))
+
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("c99.large"),
+					SpotPrice:        aws.String("0.50"),
+					Timestamp:        aws.Time(time.Now()),
+				},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{
+				fake.NewOnDemandPrice("c99.large", 2.00),
+			},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.SpotPrice("c99.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.50))
+		price, ok = awsEnv.PricingProvider.OnDemandPrice("c99.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.23))
+	})
+	It("should publish the staleness of the spot prices of each zone", func() {
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("c99.large"),
+					SpotPrice:        aws.String("1.23"),
+					Timestamp:        aws.Time(time.Now()),
+				},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{
+				fake.NewOnDemandPrice("c99.large", 1.23),
+			},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_spot_price_staleness_seconds", map[string]string{"zone": "test-zone-1a"})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically(">", 0))
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("<", 60))
+	})
+	It("should keep the on-demand prices that were retrieved before a page failed", func() {
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{
+				fake.NewOnDemandPrice("c98.large", 1.20),
+				fake.NewOnDemandPrice("c99.large", 1.23),
+			},
+		})
+		awsEnv.PricingAPI.PageError.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.20))
+		price, ok = awsEnv.PricingProvider.OnDemandPrice("c99.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.23))
+		// instance types that weren't retrieved keep their previous prices
+		price, ok = awsEnv.PricingProvider.OnDemandPrice("c5.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically(">", 0))
+	})
+	It("should keep the spot prices that were retrieved before a page failed", func() {
+		now := time.Now()
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{AvailabilityZone: aws.String("test-zone-1a"), InstanceType: aws.String("c99.large"), SpotPrice: aws.String("1.23"), Timestamp: &now},
+				{AvailabilityZone: aws.String("test-zone-1b"), InstanceType: aws.String("c99.large"), SpotPrice: aws.String("1.50"), Timestamp: &now},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c99.large", 1.23)},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+
+		later := now.Add(time.Minute)
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{AvailabilityZone: aws.String("test-zone-1a"), InstanceType: aws.String("c99.large"), SpotPrice: aws.String("1.30"), Timestamp: &later},
+			},
+		})
+		awsEnv.EC2API.DescribeSpotPriceHistoryPageError.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.SpotPrice("c99.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.30))
+		price, ok = awsEnv.PricingProvider.SpotPrice("c99.large", "test-zone-1b")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.50))
+	})
+	It("should publish when the prices were last updated without a failure", func() {
+		now := time.Now()
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{AvailabilityZone: aws.String("test-zone-1a"), InstanceType: aws.String("c99.large"), SpotPrice: aws.String("1.23"), Timestamp: &now},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c99.large", 1.23)},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+
+		lastUpdated := map[string]float64{}
+		for _, capacityType := range []string{ec2.DefaultTargetCapacityTypeOnDemand, ec2.DefaultTargetCapacityTypeSpot} {
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_pricing_last_updated_timestamp_seconds", map[string]string{"capacity_type": capacityType})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("~", float64(now.Unix()), 60))
+			lastUpdated[capacityType] = metric.GetGauge().GetValue()
+		}
+
+		awsEnv.PricingAPI.PageError.Set(fmt.Errorf("failed"))
+		awsEnv.EC2API.DescribeSpotPriceHistoryPageError.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+		for capacityType, value := range lastUpdated {
+			ExpectMetricGaugeValue(pricing.PricingLastUpdated, value, map[string]string{"capacity_type": capacityType})
+		}
+	})
 	It("should respond with false if price doesn't exist in zone", func() {
 		now := time.Now()
 		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
@@ -219,7 +399,7 @@ var _ = Describe("Pricing", func() {
 			},
 		})
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPrice("c98.large", 1.20),
 				fake.NewOnDemandPrice("c99.large", 1.23),
 			},
@@ -246,7 +426,7 @@ var _ = Describe("Pricing", func() {
 			},
 		})
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPrice("c98.large", 1.20),
 				fake.NewOnDemandPrice("c99.large", 1.23),
 			},
@@ -281,7 +461,7 @@ var _ = Describe("Pricing", func() {
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
 			// these are incorrect prices which are here to ensure that
 			// results from only static pricing are used
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPrice("c3.2xlarge", 1.20),
 				fake.NewOnDemandPrice("c5.xlarge", 1.23),
 			},
@@ -296,7 +476,7 @@ var _ = Describe("Pricing", func() {
 		Expect(price).To(BeNumerically("==", 1.10))
 	})
 	It("should update on-demand pricing with response from the pricing API when in the CN partition", func() {
-		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "cn-anywhere-1")
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, fake.NewEC2V2API(awsEnv.EC2API), "cn-anywhere-1", nil)
 		tmpController := controllerspricing.NewController(tmpPricingProvider)
 
 		now := time.Now()
@@ -311,7 +491,7 @@ var _ = Describe("Pricing", func() {
 			},
 		})
 		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
-			PriceList: []aws.JSONValue{
+			PriceList: []string{
 				fake.NewOnDemandPriceWithCurrency("c98.large", 1.20, "CNY"),
 				fake.NewOnDemandPriceWithCurrency("c99.large", 1.23, "CNY"),
 			},
@@ -326,4 +506,155 @@ var _ = Describe("Pricing", func() {
 		Expect(ok).To(BeTrue())
 		Expect(price).To(BeNumerically("==", 1.23))
 	})
+	It("should return static on-demand data in partitions without a pricing API", func() {
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, nil, fake.NewEC2V2API(awsEnv.EC2API), "us-gov-west-1", nil)
+		tmpController := controllerspricing.NewController(tmpPricingProvider)
+		ExpectSingletonReconciled(ctx, tmpController)
+
+		price, ok := tmpPricingProvider.OnDemandPrice("m5.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(Equal(pricing.InitialOnDemandPricesUSGov["us-gov-west-1"]["m5.large"]))
+	})
+	DescribeTable("should call the pricing API of the partition of the config",
+		func(configRegion string, region string, apiRegion string) {
+			api := pricing.NewAPI(ctx, awsv2.Config{Region: configRegion}, region)
+			if apiRegion == "" {
+				Expect(api).To(BeNil())
+				return
+			}
+			Expect(api.(*awspricing.Client).Options().Region).To(Equal(apiRegion))
+		},
+		Entry("aws", "us-west-2", "us-west-2", "us-east-1"),
+		Entry("aws in Europe", "eu-west-1", "eu-west-1", "eu-central-1"),
+		Entry("aws-cn", "cn-north-1", "cn-north-1", "cn-northwest-1"),
+		Entry("aws-us-gov", "us-gov-west-1", "us-gov-west-1", ""),
+		Entry("aws-us-gov from the aws partition", "us-east-1", "us-gov-west-1", "us-east-1"),
+	)
+	It("should use the prices from the instance type snapshot instead of the pricing APIs", func() {
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, fake.NewEC2V2API(awsEnv.EC2API), fake.DefaultRegion, &snapshot.Snapshot{
+			Region:         fake.DefaultRegion,
+			OnDemandPrices: map[string]float64{"c98.large": 1.20},
+			SpotPrices:     map[string]map[string]float64{"c98.large": {"test-zone-1a": 0.42}},
+		})
+		tmpController := controllerspricing.NewController(tmpPricingProvider)
+		now := time.Now()
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     aws.String("c98.large"),
+					SpotPrice:        aws.String("1.10"),
+					Timestamp:        &now,
+				},
+			},
+		})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{
+				fake.NewOnDemandPrice("c98.large", 2.40),
+			},
+		})
+		ExpectSingletonReconciled(ctx, tmpController)
+
+		price, ok := tmpPricingProvider.OnDemandPrice("c98.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.20))
+		price, ok = tmpPricingProvider.SpotPrice("c98.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.42))
+		_, ok = tmpPricingProvider.OnDemandPrice("m5.large")
+		Expect(ok).To(BeFalse())
+	})
+	It("should update on-demand pricing of local zones and wavelength zones from their own locations", func() {
+		awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
+			{ZoneName: aws.String("test-zone-1a"), GroupName: aws.String("test-zone-1"), ZoneType: aws.String("availability-zone")},
+			{ZoneName: aws.String("test-zone-1-lax-1a"), GroupName: aws.String("test-zone-1-lax-1"), ZoneType: aws.String("local-zone")},
+			{ZoneName: aws.String("test-zone-1-wl1-bos-wlz-1"), GroupName: aws.String("test-zone-1-wl1"), ZoneType: aws.String("wavelength-zone")},
+		}})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.20)},
+		})
+		awsEnv.PricingAPI.LocationProducts.Store("test-zone-1-lax-1", &awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.44)},
+		})
+		awsEnv.PricingAPI.LocationProducts.Store("test-zone-1-wl1-bos-wlz-1", &awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.56)},
+		})
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.ZonalOnDemandPrice("c98.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.20))
+		price, ok = awsEnv.PricingProvider.ZonalOnDemandPrice("c98.large", "test-zone-1-lax-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.44))
+		price, ok = awsEnv.PricingProvider.ZonalOnDemandPrice("c98.large", "test-zone-1-wl1-bos-wlz-1")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.56))
+		price, ok = awsEnv.PricingProvider.OnDemandPrice("c98.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.20))
+	})
+	It("should fall back to regional on-demand pricing when local zone pricing isn't available", func() {
+		awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{
+			{ZoneName: aws.String("test-zone-1-lax-1a"), GroupName: aws.String("test-zone-1-lax-1"), ZoneType: aws.String("local-zone")},
+		}})
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.20)},
+		})
+		awsEnv.PricingAPI.LocationProducts.Store("test-zone-1-lax-1", &awspricing.GetProductsOutput{})
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.ZonalOnDemandPrice("c98.large", "test-zone-1-lax-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.20))
+	})
+	It("should publish price estimates for on-demand and spot prices", func() {
+		now := time.Now()
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.20)},
+		})
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{AvailabilityZone: aws.String("test-zone-1a"), InstanceType: aws.String("c98.large"), SpotPrice: aws.String("0.50"), Timestamp: &now},
+			},
+		})
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		ExpectMetricGaugeValue(pricing.InstanceTypePriceEstimate, 1.20, map[string]string{
+			"instance_type": "c98.large",
+			"capacity_type": ec2.DefaultTargetCapacityTypeOnDemand,
+			"zone":          fake.DefaultRegion,
+		})
+		ExpectMetricGaugeValue(pricing.InstanceTypePriceEstimate, 0.50, map[string]string{
+			"instance_type": "c98.large",
+			"capacity_type": ec2.DefaultTargetCapacityTypeSpot,
+			"zone":          "test-zone-1a",
+		})
+	})
+	It("should serve the current prices as JSON", func() {
+		now := time.Now()
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.20)},
+		})
+		awsEnv.EC2API.DescribeSpotPriceHistoryOutput.Set(&ec2.DescribeSpotPriceHistoryOutput{
+			SpotPriceHistory: []*ec2.SpotPrice{
+				{AvailabilityZone: aws.String("test-zone-1a"), InstanceType: aws.String("c98.large"), SpotPrice: aws.String("0.50"), Timestamp: &now},
+			},
+		})
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		recorder := httptest.NewRecorder()
+		pricing.NewHandler(awsEnv.PricingProvider).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/pricing", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		prices := pricing.Prices{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &prices)).To(Succeed())
+		Expect(prices.OnDemand).To(HaveKeyWithValue("c98.large", 1.20))
+		Expect(prices.Spot).To(HaveKeyWithValue("c98.large", HaveKeyWithValue("test-zone-1a", 0.50)))
+		Expect(prices.SpotPricingUpdated).To(BeTrue())
+	})
+	It("should reject requests that aren't reads", func() {
+		recorder := httptest.NewRecorder()
+		pricing.NewHandler(awsEnv.PricingProvider).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/pricing", nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
 })
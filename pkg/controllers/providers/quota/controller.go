@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// Controller periodically reports the vCPU usage of the account against its EC2 vCPU service quotas, so that capacity
+// planners are warned before launches start to fail with VcpuLimitExceeded. Pods that are waiting for capacity are sent
+// an event when every quota that their capacity could count against is too close to its limit to fit them. With quota
+// filtering enabled, the headroom under each quota is stored so that offerings that wouldn't fit under it are filtered.
+type Controller struct {
+	kubeClient        client.Client
+	recorder          events.Recorder
+	quotaProvider     quota.Provider
+	vcpuQuotaHeadroom *cache.VCPUQuotaHeadroom
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, quotaProvider quota.Provider, vcpuQuotaHeadroom *cache.VCPUQuotaHeadroom) *Controller {
+	return &Controller{
+		kubeClient:        kubeClient,
+		recorder:          recorder,
+		quotaProvider:     quotaProvider,
+		vcpuQuotaHeadroom: vcpuQuotaHeadroom,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.quota")
+
+	usage, err := c.quotaProvider.VCPUUsage(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting vcpu usage, %w", err)
+	}
+	for _, u := range usage {
+		VCPUQuota.WithLabelValues(u.CapacityType, u.FamilyClass).Set(u.Quota)
+		VCPUUsage.WithLabelValues(u.CapacityType, u.FamilyClass).Set(u.Used)
+		VCPUQuotaUtilization.WithLabelValues(u.CapacityType, u.FamilyClass).Set(u.Utilization())
+		VCPUQuotaHeadroom.WithLabelValues(u.CapacityType, u.FamilyClass).Set(u.Remaining())
+	}
+	if options.FromContext(ctx).VCPUQuotaFiltering {
+		headroom := map[string]map[string]float64{}
+		for _, u := range usage {
+			if _, ok := headroom[u.CapacityType]; !ok {
+				headroom[u.CapacityType] = map[string]float64{}
+			}
+			headroom[u.CapacityType][u.FamilyClass] = u.Remaining()
+		}
+		if c.vcpuQuotaHeadroom.Set(headroom) {
+			log.FromContext(ctx).V(1).Info("updated vcpu quota headroom")
+		}
+	}
+	if err = c.warnPendingPods(ctx, usage); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// warnPendingPods publishes an event on each pod that's waiting for capacity whose vCPUs don't fit in any quota that
+// its capacity could count against. Pods that could count against a quota whose value isn't known aren't warned.
+func (c *Controller) warnPendingPods(ctx context.Context, usage []quota.VCPUUsage) error {
+	byKey := lo.SliceToMap(usage, func(u quota.VCPUUsage) (quota.Key, quota.VCPUUsage) { return u.Key, u })
+	pods := &v1.PodList{}
+	if err := c.kubeClient.List(ctx, pods, client.MatchingFields{"spec.nodeName": ""}); err != nil {
+		return fmt.Errorf("listing pods, %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !podutils.IsProvisionable(pod) {
+			continue
+		}
+		requests := resources.Ceiling(pod).Requests
+		vcpus := max(int64(math.Ceil(requests.Cpu().AsApproximateFloat64())), 1)
+		keys := quota.Keys(scheduling.NewPodRequirements(pod), requests)
+		candidates := lo.FilterMap(keys, func(key quota.Key, _ int) (quota.VCPUUsage, bool) {
+			u, ok := byKey[key]
+			return u, ok
+		})
+		if len(keys) == 0 || len(candidates) != len(keys) {
+			continue
+		}
+		if lo.SomeBy(candidates, func(u quota.VCPUUsage) bool { return u.Remaining() >= float64(vcpus) }) {
+			continue
+		}
+		remaining := lo.MaxBy(candidates, func(a, b quota.VCPUUsage) bool { return a.Remaining() > b.Remaining() }).Remaining()
+		c.recorder.Publish(VCPUQuotaExceededEvent(pod, vcpus, remaining, lo.Map(candidates, func(u quota.VCPUUsage, _ int) string {
+			return fmt.Sprintf("%s %s", u.CapacityType, u.FamilyClass)
+		})))
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.quota").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.quota", singleton.AsReconciler(c)))
+}
@@ -0,0 +1,35 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func VCPUQuotaExceededEvent(pod *v1.Pod, vcpus int64, remaining float64, quotas []string) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           v1.EventTypeWarning,
+		Reason:         "VCPUQuotaExceeded",
+		Message: fmt.Sprintf("Launching capacity for the pod is likely to be rejected for quota, it needs %d vCPUs but at most %d remain in the %s vCPU quotas",
+			vcpus, int64(remaining), strings.Join(quotas, ", ")),
+		DedupeValues: []string{string(pod.UID)},
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	familyClassLabel       = "family_class"
+)
+
+var (
+	VCPUQuota = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_quota",
+			Help:      "EC2 service quota for the running vCPUs of the account, labeled by capacity type and instance family class.",
+		},
+		[]string{metrics.CapacityTypeLabel, familyClassLabel},
+	)
+	VCPUUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_usage",
+			Help:      "vCPUs of the running and pending instances of the account that count against an EC2 vCPU service quota, labeled by capacity type and instance family class.",
+		},
+		[]string{metrics.CapacityTypeLabel, familyClassLabel},
+	)
+	VCPUQuotaUtilization = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_quota_utilization",
+			Help:      "Fraction of an EC2 vCPU service quota of the account that's in use, labeled by capacity type and instance family class.",
+		},
+		[]string{metrics.CapacityTypeLabel, familyClassLabel},
+	)
+	VCPUQuotaHeadroom = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_quota_headroom",
+			Help:      "vCPUs that are left under an EC2 vCPU service quota of the account, labeled by capacity type and instance family class.",
+		},
+		[]string{metrics.CapacityTypeLabel, familyClassLabel},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(VCPUQuota, VCPUUsage, VCPUQuotaUtilization, VCPUQuotaHeadroom)
+}
@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	controllersquota "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/quota"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var recorder *coretest.EventRecorder
+var ec2api *fake.EC2API
+var servicequotasapi *fake.ServiceQuotasAPI
+var quotaProvider *quota.DefaultProvider
+var vcpuQuotaHeadroom *awscache.VCPUQuotaHeadroom
+var controller *controllersquota.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VCPUQuota")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	recorder = coretest.NewEventRecorder()
+	ec2api = fake.NewEC2API()
+	servicequotasapi = fake.NewServiceQuotasAPI()
+	quotaProvider = quota.NewDefaultProvider(servicequotasapi, ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	vcpuQuotaHeadroom = awscache.NewVCPUQuotaHeadroom()
+	controller = controllersquota.NewController(env.Client, recorder, quotaProvider, vcpuQuotaHeadroom)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	recorder.Reset()
+	ec2api.Reset()
+	servicequotasapi.Reset()
+	quotaProvider.Reset()
+	vcpuQuotaHeadroom.Flush()
+	controllersquota.VCPUQuota.Reset()
+	controllersquota.VCPUUsage.Reset()
+	controllersquota.VCPUQuotaUtilization.Reset()
+	controllersquota.VCPUQuotaHeadroom.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("VCPUQuota", func() {
+	BeforeEach(func() {
+		servicequotasapi.ListServiceQuotasOutput.Set(&servicequotas.ListServiceQuotasOutput{Quotas: []*servicequotas.ServiceQuota{
+			fake.NewServiceQuota("L-1216C47A", 16), // on-demand standard
+			fake.NewServiceQuota("L-34B43A08", 16), // spot standard
+			fake.NewServiceQuota("L-DB2E81BA", 8),  // on-demand g
+			fake.NewServiceQuota("L-417A185B", 0),  // on-demand p
+			fake.NewServiceQuota("L-3819A6DF", 0),  // spot g
+			fake.NewServiceQuota("L-7212CCBC", 0),  // spot p
+		}})
+		instanceID := fake.InstanceID()
+		ec2api.Instances.Store(instanceID, &ec2.Instance{
+			InstanceId:   aws.String(instanceID),
+			InstanceType: aws.String("g5.xlarge"),
+			State:        &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+			CpuOptions:   &ec2.CpuOptions{CoreCount: aws.Int64(2), ThreadsPerCore: aws.Int64(2)},
+		})
+	})
+	gpuPod := func(cpu string) *v1.Pod {
+		return coretest.UnschedulablePod(coretest.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1beta1.ResourceNVIDIAGPU: resource.MustParse("1")},
+				Limits:   v1.ResourceList{v1beta1.ResourceNVIDIAGPU: resource.MustParse("1")},
+			},
+		})
+	}
+
+	It("should report the vcpu usage and quotas of the account", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_vcpu_quota_utilization", map[string]string{
+			"capacity_type": corev1beta1.CapacityTypeOnDemand,
+			"family_class":  quota.FamilyClassG,
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 0.5))
+		metric, ok = FindMetricWithLabelValues("karpenter_cloudprovider_vcpu_quota", map[string]string{
+			"capacity_type": corev1beta1.CapacityTypeSpot,
+			"family_class":  quota.FamilyClassStandard,
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 16))
+	})
+	It("should report the vcpu headroom of the account", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_vcpu_quota_headroom", map[string]string{
+			"capacity_type": corev1beta1.CapacityTypeOnDemand,
+			"family_class":  quota.FamilyClassG,
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 4))
+	})
+	It("should store the vcpu headroom when quota filtering is enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{VCPUQuotaReporting: lo.ToPtr(true), VCPUQuotaFiltering: lo.ToPtr(true)}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(vcpuQuotaHeadroom.Fits(corev1beta1.CapacityTypeOnDemand, quota.FamilyClassG, 4)).To(BeTrue())
+		Expect(vcpuQuotaHeadroom.Fits(corev1beta1.CapacityTypeOnDemand, quota.FamilyClassG, 8)).To(BeFalse())
+		Expect(vcpuQuotaHeadroom.Fits(corev1beta1.CapacityTypeSpot, quota.FamilyClassP, 1)).To(BeFalse())
+		// Quotas that aren't known don't filter anything
+		Expect(vcpuQuotaHeadroom.Fits(corev1beta1.CapacityTypeSpot, quota.FamilyClassHPC, 1000)).To(BeTrue())
+	})
+	It("should not store the vcpu headroom when quota filtering is disabled", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(vcpuQuotaHeadroom.Fits(corev1beta1.CapacityTypeSpot, quota.FamilyClassP, 1)).To(BeTrue())
+	})
+	It("should warn pending pods that don't fit in any of their quotas", func() {
+		pod := gpuPod("6")
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(recorder.Calls("VCPUQuotaExceeded")).To(Equal(1))
+		Expect(recorder.DetectedEvent("Launching capacity for the pod is likely to be rejected for quota, it needs 6 vCPUs but at most 4 remain in the on-demand g, on-demand p, spot g, spot p vCPU quotas")).To(BeTrue())
+	})
+	It("should not warn pending pods that fit in one of their quotas", func() {
+		ExpectApplied(ctx, env.Client, gpuPod("4"), coretest.UnschedulablePod())
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(recorder.Calls("VCPUQuotaExceeded")).To(Equal(0))
+	})
+	It("should not warn pending pods that could count against a quota that isn't known", func() {
+		servicequotasapi.ListServiceQuotasOutput.Set(&servicequotas.ListServiceQuotasOutput{Quotas: []*servicequotas.ServiceQuota{
+			fake.NewServiceQuota("L-DB2E81BA", 4),
+		}})
+		ExpectApplied(ctx, env.Client, gpuPod("6"))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(recorder.Calls("VCPUQuotaExceeded")).To(Equal(0))
+	})
+	It("should not warn pods that are scheduled", func() {
+		pod := gpuPod("6")
+		pod.Spec.NodeName = "node"
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(recorder.Calls("VCPUQuotaExceeded")).To(Equal(0))
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unavailableofferings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/system"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap in Karpenter's namespace that the unavailable offerings are persisted to
+	ConfigMapName = "karpenter-unavailable-offerings"
+	// ConfigMapKey is the data key that holds the JSON encoded list of cache.UnavailableOffering
+	ConfigMapKey = "offerings"
+	// persistPeriod is the maximum amount of time before changes to the cache are persisted
+	persistPeriod = 10 * time.Second
+)
+
+// Controller persists the UnavailableOfferings cache to a ConfigMap so that a restarted or newly elected Karpenter
+// doesn't immediately retry the offerings that recently returned insufficient capacity errors. The cache is rehydrated
+// from the ConfigMap on the first reconcile, before anything is written back. Like the blocklist, the ConfigMap is read
+// directly from the API server so that Karpenter only needs access to this single ConfigMap.
+type Controller struct {
+	kubeClient           client.Client
+	kubeReader           client.Reader
+	unavailableOfferings *cache.UnavailableOfferings
+
+	hydrated bool
+	seqNum   uint64
+}
+
+func NewController(kubeClient client.Client, kubeReader client.Reader, unavailableOfferings *cache.UnavailableOfferings) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		kubeReader:           kubeReader,
+		unavailableOfferings: unavailableOfferings,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.unavailableofferings")
+
+	cm := &v1.ConfigMap{}
+	if err := c.kubeReader.Get(ctx, types.NamespacedName{Namespace: system.Namespace(), Name: ConfigMapName}, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("getting unavailable offerings configmap, %w", err)
+		}
+		cm = nil
+	}
+	if !c.hydrated {
+		c.hydrate(ctx, cm)
+		c.hydrated = true
+	}
+	seqNum := atomic.LoadUint64(&c.unavailableOfferings.SeqNum)
+	if cm != nil && seqNum == c.seqNum {
+		return reconcile.Result{RequeueAfter: persistPeriod}, nil
+	}
+	if err := c.persist(ctx, cm); err != nil {
+		return reconcile.Result{}, err
+	}
+	c.seqNum = seqNum
+	return reconcile.Result{RequeueAfter: persistPeriod}, nil
+}
+
+func (c *Controller) hydrate(ctx context.Context, cm *v1.ConfigMap) {
+	if cm == nil || cm.Data[ConfigMapKey] == "" {
+		return
+	}
+	var offerings []cache.UnavailableOffering
+	if err := json.Unmarshal([]byte(cm.Data[ConfigMapKey]), &offerings); err != nil {
+		// The cache is only an optimization, so we start from scratch rather than failing
+		log.FromContext(ctx).Error(err, "failed parsing persisted unavailable offerings")
+		return
+	}
+	if restored := c.unavailableOfferings.Restore(ctx, offerings); restored > 0 {
+		log.FromContext(ctx).WithValues("count", restored).Info("restored unavailable offerings")
+	}
+}
+
+func (c *Controller) persist(ctx context.Context, cm *v1.ConfigMap) error {
+	offerings := c.unavailableOfferings.List()
+	// Sort the offerings so that the ConfigMap is only updated when the contents change
+	sort.Slice(offerings, func(i, j int) bool {
+		return fmt.Sprintf("%s:%s:%s", offerings[i].CapacityType, offerings[i].InstanceType, offerings[i].Zone) <
+			fmt.Sprintf("%s:%s:%s", offerings[j].CapacityType, offerings[j].InstanceType, offerings[j].Zone)
+	})
+	data, err := json.Marshal(lo.Ternary(offerings == nil, []cache.UnavailableOffering{}, offerings))
+	if err != nil {
+		return fmt.Errorf("marshaling unavailable offerings, %w", err)
+	}
+	if cm == nil {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: system.Namespace(), Name: ConfigMapName},
+			Data:       map[string]string{ConfigMapKey: string(data)},
+		}
+		if err = c.kubeClient.Create(ctx, cm); err != nil {
+			return fmt.Errorf("creating unavailable offerings configmap, %w", err)
+		}
+		return nil
+	}
+	if cm.Data[ConfigMapKey] == string(data) {
+		return nil
+	}
+	stored := cm.DeepCopy()
+	cm.Data = lo.Assign(cm.Data, map[string]string{ConfigMapKey: string(data)})
+	if err = c.kubeClient.Patch(ctx, cm, client.MergeFrom(stored)); err != nil {
+		return fmt.Errorf("patching unavailable offerings configmap, %w", err)
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.unavailableofferings").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.unavailableofferings", singleton.AsReconciler(c)))
+}
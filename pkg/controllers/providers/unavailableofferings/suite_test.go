@@ -0,0 +1,168 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unavailableofferings_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	controllersunavailableofferings "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/unavailableofferings"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "UnavailableOfferings")
+}
+
+var _ = BeforeSuite(func() {
+	lo.Must0(os.Setenv(system.NamespaceEnvKey, "default"))
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = Describe("UnavailableOfferings", func() {
+	var unavailableOfferings *cache.UnavailableOfferings
+	var controller *controllersunavailableofferings.Controller
+	var cm *v1.ConfigMap
+
+	BeforeEach(func() {
+		unavailableOfferings = cache.NewUnavailableOfferings()
+		controller = controllersunavailableofferings.NewController(env.Client, env.Client, unavailableOfferings)
+		cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: controllersunavailableofferings.ConfigMapName, Namespace: "default"}}
+	})
+	AfterEach(func() {
+		ExpectDeleted(ctx, env.Client, cm)
+	})
+	persisted := func() []cache.UnavailableOffering {
+		GinkgoHelper()
+		cm = ExpectExists(ctx, env.Client, cm)
+		var offerings []cache.UnavailableOffering
+		Expect(json.Unmarshal([]byte(cm.Data[controllersunavailableofferings.ConfigMapKey]), &offerings)).To(Succeed())
+		return offerings
+	}
+
+	It("should persist unavailable offerings to the configmap", func() {
+		unavailableOfferings.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "spot")
+		ExpectSingletonReconciled(ctx, controller)
+
+		offerings := persisted()
+		Expect(offerings).To(HaveLen(1))
+		Expect(offerings[0].InstanceType).To(Equal("m5.large"))
+		Expect(offerings[0].Zone).To(Equal("test-zone-1a"))
+		Expect(offerings[0].CapacityType).To(Equal("spot"))
+		Expect(offerings[0].Expiration).To(BeTemporally(">", time.Now()))
+	})
+	It("should rehydrate the cache from the configmap", func() {
+		cm.Data = map[string]string{controllersunavailableofferings.ConfigMapKey: string(lo.Must(json.Marshal([]cache.UnavailableOffering{
+			{CapacityType: "spot", InstanceType: "m5.large", Zone: "test-zone-1a", Expiration: time.Now().Add(time.Minute)},
+			{CapacityType: "spot", InstanceType: "c5.large", Zone: "test-zone-1a", Expiration: time.Now().Add(-time.Minute)},
+		})))}
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+
+		Expect(unavailableOfferings.IsUnavailable("m5.large", "test-zone-1a", "spot")).To(BeTrue())
+		Expect(unavailableOfferings.IsUnavailable("c5.large", "test-zone-1a", "spot")).To(BeFalse())
+		Expect(persisted()).To(HaveLen(1))
+	})
+	It("should remove offerings from the configmap once they're available again", func() {
+		unavailableOfferings.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "spot")
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(persisted()).To(HaveLen(1))
+
+		unavailableOfferings.Delete("m5.large", "test-zone-1a", "spot")
+		unavailableOfferings.SeqNum++
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(persisted()).To(BeEmpty())
+	})
+	It("should start from an empty cache when the configmap can't be parsed", func() {
+		cm.Data = map[string]string{controllersunavailableofferings.ConfigMapKey: "not-json"}
+		ExpectApplied(ctx, env.Client, cm)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(persisted()).To(BeEmpty())
+	})
+	Context("Backoff", func() {
+		var backoffCtx context.Context
+		BeforeEach(func() {
+			backoffCtx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				ICEBackoffDurations:    map[string]time.Duration{"spot": time.Minute, "on-demand": 10 * time.Minute},
+				ICEBackoffMaxDurations: map[string]time.Duration{"spot": 4 * time.Minute},
+			}))
+		})
+		expiration := func(capacityType string) time.Time {
+			GinkgoHelper()
+			offering, ok := lo.Find(unavailableOfferings.List(), func(o cache.UnavailableOffering) bool { return o.CapacityType == capacityType })
+			Expect(ok).To(BeTrue())
+			return offering.Expiration
+		}
+
+		It("should keep offerings unavailable for the backoff of their capacity type", func() {
+			unavailableOfferings.MarkUnavailable(backoffCtx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "on-demand")
+			Expect(expiration("on-demand")).To(BeTemporally("~", time.Now().Add(10*time.Minute), 5*time.Second))
+		})
+		It("should double the backoff of offerings that fail again, up to the max backoff", func() {
+			for _, backoff := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+				unavailableOfferings.MarkUnavailable(backoffCtx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "spot")
+				Expect(expiration("spot")).To(BeTemporally("~", time.Now().Add(backoff), 5*time.Second))
+				// The offering is available again once its backoff has passed
+				unavailableOfferings.Delete("m5.large", "test-zone-1a", "spot")
+			}
+		})
+		It("should not double the backoff of offerings that are still unavailable", func() {
+			unavailableOfferings.MarkUnavailable(backoffCtx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "spot")
+			unavailableOfferings.MarkUnavailable(backoffCtx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "spot")
+			Expect(expiration("spot")).To(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+		})
+		It("should not back off exponentially for capacity types without a max backoff", func() {
+			unavailableOfferings.MarkUnavailable(backoffCtx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "on-demand")
+			unavailableOfferings.Delete("m5.large", "test-zone-1a", "on-demand")
+			unavailableOfferings.MarkUnavailable(backoffCtx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", "on-demand")
+			Expect(expiration("on-demand")).To(BeTemporally("~", time.Now().Add(10*time.Minute), 5*time.Second))
+		})
+		It("should restore offerings for up to the max backoff of their capacity type", func() {
+			Expect(unavailableOfferings.Restore(backoffCtx, []cache.UnavailableOffering{
+				{CapacityType: "spot", InstanceType: "m5.large", Zone: "test-zone-1a", Expiration: time.Now().Add(time.Hour)},
+			})).To(Equal(1))
+			Expect(expiration("spot")).To(BeTemporally("~", time.Now().Add(4*time.Minute), 5*time.Second))
+		})
+	})
+})
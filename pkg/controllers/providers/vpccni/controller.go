@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vpccni
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// DaemonSet is the VPC CNI DaemonSet that the warm pool settings are read from
+var DaemonSet = types.NamespacedName{Namespace: "kube-system", Name: "aws-node"}
+
+// containerName is the name of the VPC CNI container of the DaemonSet
+const containerName = "aws-node"
+
+// pollingPeriod is the maximum amount of time before changes to the DaemonSet are reflected in instance types
+const pollingPeriod = time.Minute
+
+// Controller periodically reads the warm pool settings of the VPC CNI from the aws-node DaemonSet, so that pod density
+// and subnet IP usage are projected with the IPs that the VPC CNI keeps warm on every node.
+type Controller struct {
+	kubeClient client.Client
+	vpcCNI     *cache.VPCCNI
+}
+
+func NewController(kubeClient client.Client, vpcCNI *cache.VPCCNI) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+		vpcCNI:     vpcCNI,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.vpccni")
+
+	ds := &appsv1.DaemonSet{}
+	if err := c.kubeClient.Get(ctx, DaemonSet, ds); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("getting vpc cni daemonset, %w", err)
+		}
+		// Clusters that use another CNI don't keep warm IPs
+		if c.vpcCNI.SetWarmTargets(nil) {
+			log.FromContext(ctx).Info("cleared vpc cni warm targets, daemonset not found")
+		}
+		return reconcile.Result{RequeueAfter: pollingPeriod}, nil
+	}
+	warmTargets := WarmTargets(ds)
+	if c.vpcCNI.SetWarmTargets(warmTargets) {
+		log.FromContext(ctx).WithValues(
+			"warmIPTarget", lo.FromPtr(warmTargets).WarmIPTarget,
+			"minimumIPTarget", lo.FromPtr(warmTargets).MinimumIPTarget,
+			"prefixDelegation", lo.FromPtr(warmTargets).PrefixDelegation,
+		).Info("updated vpc cni warm targets")
+	}
+	return reconcile.Result{RequeueAfter: pollingPeriod}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.vpccni").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.vpccni", singleton.AsReconciler(c)))
+}
+
+// WarmTargets returns the warm pool settings of the VPC CNI container of the DaemonSet, or nil if the DaemonSet has no
+// VPC CNI container. Settings that aren't set, or that are set from another resource, are left at 0.
+func WarmTargets(ds *appsv1.DaemonSet) *cache.VPCCNIWarmTargets {
+	container, ok := lo.Find(ds.Spec.Template.Spec.Containers, func(c v1.Container) bool { return c.Name == containerName })
+	if !ok {
+		return nil
+	}
+	env := lo.SliceToMap(container.Env, func(e v1.EnvVar) (string, string) { return e.Name, e.Value })
+	parse := func(name string) int64 {
+		v, err := strconv.ParseInt(env[name], 10, 64)
+		return lo.Ternary(err == nil && v > 0, v, 0)
+	}
+	prefixDelegation, _ := strconv.ParseBool(env["ENABLE_PREFIX_DELEGATION"])
+	return &cache.VPCCNIWarmTargets{
+		WarmIPTarget:     parse("WARM_IP_TARGET"),
+		MinimumIPTarget:  parse("MINIMUM_IP_TARGET"),
+		PrefixDelegation: prefixDelegation,
+	}
+}
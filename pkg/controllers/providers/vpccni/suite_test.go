@@ -0,0 +1,132 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vpccni_test
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/vpccni"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *vpccni.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VPCCNI")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = vpccni.NewController(env.Client, awsEnv.VPCCNI)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+var _ = Describe("VPC CNI", func() {
+	var ds *appsv1.DaemonSet
+	BeforeEach(func() {
+		ds = coretest.DaemonSet(coretest.DaemonSetOptions{
+			ObjectMeta: metav1.ObjectMeta{Namespace: vpccni.DaemonSet.Namespace, Name: vpccni.DaemonSet.Name},
+		})
+		ds.Spec.Template.Spec.Containers[0].Name = "aws-node"
+		ds.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{
+			{Name: "WARM_IP_TARGET", Value: "5"},
+			{Name: "MINIMUM_IP_TARGET", Value: "10"},
+		}
+	})
+	AfterEach(func() {
+		ExpectDeleted(ctx, env.Client, ds)
+	})
+	It("should read the warm targets from the aws-node DaemonSet", func() {
+		ExpectApplied(ctx, env.Client, ds)
+		ExpectSingletonReconciled(ctx, controller)
+
+		warmTargets, ok := awsEnv.VPCCNI.WarmTargets()
+		Expect(ok).To(BeTrue())
+		Expect(warmTargets).To(Equal(cache.VPCCNIWarmTargets{WarmIPTarget: 5, MinimumIPTarget: 10}))
+	})
+	It("should read prefix delegation from the aws-node DaemonSet", func() {
+		ds.Spec.Template.Spec.Containers[0].Env = append(ds.Spec.Template.Spec.Containers[0].Env, v1.EnvVar{Name: "ENABLE_PREFIX_DELEGATION", Value: "true"})
+		ExpectApplied(ctx, env.Client, ds)
+		ExpectSingletonReconciled(ctx, controller)
+
+		warmTargets, ok := awsEnv.VPCCNI.WarmTargets()
+		Expect(ok).To(BeTrue())
+		Expect(warmTargets.PrefixDelegation).To(BeTrue())
+	})
+	It("should ignore invalid warm targets", func() {
+		ds.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{
+			{Name: "WARM_IP_TARGET", Value: "-1"},
+			{Name: "MINIMUM_IP_TARGET", Value: "ten"},
+		}
+		ExpectApplied(ctx, env.Client, ds)
+		ExpectSingletonReconciled(ctx, controller)
+
+		warmTargets, ok := awsEnv.VPCCNI.WarmTargets()
+		Expect(ok).To(BeTrue())
+		Expect(warmTargets).To(Equal(cache.VPCCNIWarmTargets{}))
+	})
+	It("should clear the warm targets when the aws-node DaemonSet is deleted", func() {
+		ExpectApplied(ctx, env.Client, ds)
+		ExpectSingletonReconciled(ctx, controller)
+		seqNum := awsEnv.VPCCNI.SeqNum
+
+		ExpectDeleted(ctx, env.Client, ds)
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, ok := awsEnv.VPCCNI.WarmTargets()
+		Expect(ok).To(BeFalse())
+		Expect(awsEnv.VPCCNI.SeqNum).To(BeNumerically(">", seqNum))
+	})
+	It("should not change the sequence number when the warm targets are unchanged", func() {
+		ExpectApplied(ctx, env.Client, ds)
+		ExpectSingletonReconciled(ctx, controller)
+		seqNum := awsEnv.VPCCNI.SeqNum
+
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.VPCCNI.SeqNum).To(Equal(seqNum))
+	})
+})
@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonehealth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+)
+
+// pollingPeriod is the maximum amount of time before a change in the state of a zone is reflected in the offerings
+const pollingPeriod = time.Minute
+
+// impairedStates are the zone states that EC2 reports for zones that launches shouldn't be attempted in
+var impairedStates = sets.New(ec2.AvailabilityZoneStateImpaired, ec2.AvailabilityZoneStateUnavailable)
+
+// Controller periodically reads the state of the zones of the region from EC2, so that zones that EC2 reports as impaired
+// are removed from the offerings, and publishes the zones that are impaired for either reason as metrics.
+type Controller struct {
+	ec2api     ec2iface.EC2API
+	zoneHealth *cache.ZoneHealth
+}
+
+func NewController(ec2api ec2iface.EC2API, zoneHealth *cache.ZoneHealth) *Controller {
+	return &Controller{
+		ec2api:     ec2api,
+		zoneHealth: zoneHealth,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.zonehealth")
+
+	out, err := c.ec2api.DescribeAvailabilityZonesWithContext(ctx, &ec2.DescribeAvailabilityZonesInput{})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("describing availability zones, %w", err)
+	}
+	states := map[string]string{}
+	for _, az := range out.AvailabilityZones {
+		if impairedStates.Has(aws.StringValue(az.State)) {
+			states[aws.StringValue(az.ZoneName)] = aws.StringValue(az.State)
+		}
+	}
+	if c.zoneHealth.SetReported(states) {
+		zones := lo.Keys(states)
+		sort.Strings(zones)
+		log.FromContext(ctx).WithValues("zones", zones).Info("updated zones that are reported as impaired")
+	}
+	ZoneImpaired.Reset()
+	for _, zone := range c.zoneHealth.List() {
+		ZoneImpaired.With(prometheus.Labels{zoneLabel: zone.Zone, sourceLabel: zone.Source}).Set(1)
+	}
+	return reconcile.Result{RequeueAfter: pollingPeriod}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.zonehealth").
+		WatchesRawSource(singleton.Source()).
+		Complete(tracing.Reconciler("providers.zonehealth", singleton.AsReconciler(c)))
+}
@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonehealth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	clock "k8s.io/utils/clock/testing"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	controllerszonehealth "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/zonehealth"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllerszonehealth.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ZoneHealth")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = controllerszonehealth.NewController(awsEnv.EC2API, awsEnv.ZoneHealth)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+func zones(states map[string]string) *ec2.DescribeAvailabilityZonesOutput {
+	out := &ec2.DescribeAvailabilityZonesOutput{}
+	for zone, state := range states {
+		out.AvailabilityZones = append(out.AvailabilityZones, &ec2.AvailabilityZone{ZoneName: aws.String(zone), State: aws.String(state)})
+	}
+	return out
+}
+
+var _ = Describe("ZoneHealth", func() {
+	It("should mark zones that EC2 reports as impaired or unavailable", func() {
+		awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(zones(map[string]string{
+			"test-zone-1a": ec2.AvailabilityZoneStateImpaired,
+			"test-zone-1b": ec2.AvailabilityZoneStateUnavailable,
+			"test-zone-1c": ec2.AvailabilityZoneStateAvailable,
+		}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1a")).To(BeTrue())
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1b")).To(BeTrue())
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1c")).To(BeFalse())
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_zone_impaired", map[string]string{"zone": "test-zone-1a", "source": awscache.ZoneImpairedSourceZoneState})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 1))
+		_, ok = FindMetricWithLabelValues("karpenter_cloudprovider_zone_impaired", map[string]string{"zone": "test-zone-1c"})
+		Expect(ok).To(BeFalse())
+	})
+	It("should not mark zones with informational states", func() {
+		awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(zones(map[string]string{
+			"test-zone-1a": ec2.AvailabilityZoneStateInformation,
+			"test-zone-1b": ec2.AvailabilityZoneStateConstrained,
+		}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1a")).To(BeFalse())
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1b")).To(BeFalse())
+	})
+	It("should unmark zones once EC2 reports them as available again", func() {
+		awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(zones(map[string]string{"test-zone-1a": ec2.AvailabilityZoneStateImpaired}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1a")).To(BeTrue())
+
+		awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(zones(map[string]string{"test-zone-1a": ec2.AvailabilityZoneStateAvailable}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1a")).To(BeFalse())
+	})
+	It("should publish zones that are impaired by launch failures", func() {
+		for _, instanceType := range []string{"m5.large", "c6g.large", "t3.large"} {
+			awsEnv.ZoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", instanceType, "test-zone-1c")
+		}
+		ExpectSingletonReconciled(ctx, controller)
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_zone_impaired", map[string]string{"zone": "test-zone-1c", "source": awscache.ZoneImpairedSourceLaunchFailures})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 1))
+	})
+	It("should back off exponentially while a zone keeps failing and reset after a successful launch", func() {
+		fakeClock := clock.NewFakeClock(time.Now())
+		zoneHealth := awscache.NewZoneHealth(fakeClock)
+		fail := func() {
+			for _, instanceType := range []string{"m5.large", "c6g.large", "t3.large"} {
+				zoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", instanceType, "test-zone-1c")
+			}
+		}
+		expectImpairedFor := func(ttl time.Duration) {
+			zones := zoneHealth.List()
+			Expect(zones).To(HaveLen(1))
+			Expect(zones[0].Expiration).To(Equal(fakeClock.Now().Add(ttl)))
+		}
+
+		fail()
+		expectImpairedFor(awscache.ZoneImpairedTTL)
+		// Failures while the zone is impaired don't extend the impairment
+		fakeClock.Step(time.Minute)
+		fail()
+		expectImpairedFor(awscache.ZoneImpairedTTL - time.Minute)
+
+		fakeClock.Step(awscache.ZoneImpairedTTL)
+		Expect(zoneHealth.IsImpaired("test-zone-1c")).To(BeFalse())
+		fail()
+		expectImpairedFor(2 * awscache.ZoneImpairedTTL)
+
+		fakeClock.Step(time.Hour)
+		for i := 0; i < 5; i++ {
+			fail()
+			fakeClock.Step(time.Hour)
+		}
+		fail()
+		expectImpairedFor(awscache.ZoneImpairedMaxTTL)
+
+		fakeClock.Step(time.Hour)
+		zoneHealth.RecordSuccess("test-zone-1c")
+		fail()
+		expectImpairedFor(awscache.ZoneImpairedTTL)
+	})
+	It("should forget failures that are older than the failure window", func() {
+		fakeClock := clock.NewFakeClock(time.Now())
+		zoneHealth := awscache.NewZoneHealth(fakeClock)
+		zoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1c")
+		zoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", "c6g.large", "test-zone-1c")
+		fakeClock.Step(awscache.ZoneFailureWindow + time.Second)
+		Expect(zoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", "t3.large", "test-zone-1c")).To(BeFalse())
+		Expect(zoneHealth.IsImpaired("test-zone-1c")).To(BeFalse())
+	})
+})
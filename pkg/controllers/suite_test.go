@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/awslabs/operatorpkg/controller"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	corecontrollers "sigs.k8s.io/karpenter/pkg/controllers"
+	coredisruption "sigs.k8s.io/karpenter/pkg/controllers/disruption"
+	"sigs.k8s.io/karpenter/pkg/controllers/disruption/orchestration"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/controllers"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Controllers")
+}
+
+var _ = Describe("CoreControllers", func() {
+	var isDisruption = func(c controller.Controller) bool {
+		switch c.(type) {
+		case *coredisruption.Controller, *orchestration.Queue:
+			return true
+		}
+		return false
+	}
+	var coreControllers = func() []controller.Controller {
+		return controllers.NewCoreControllers(ctx, corecontrollers.NewControllers(clock.NewFakeClock(time.Now()), fake.NewClientBuilder().Build(),
+			events.NewRecorder(&record.FakeRecorder{}), corecloudprovider.NewCloudProvider()))
+	}
+
+	It("should register the disruption controllers", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		Expect(lo.CountBy(coreControllers(), isDisruption)).To(Equal(2))
+	})
+	It("should not register the disruption controllers in read-only mode", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReadOnly: lo.ToPtr(true)}))
+		Expect(lo.CountBy(coreControllers(), isDisruption)).To(BeZero())
+		Expect(coreControllers()).ToNot(BeEmpty())
+	})
+})
@@ -0,0 +1,339 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package convert converts v1alpha5 Provisioners and v1alpha1 AWSNodeTemplates to v1beta1 NodePools and
+// EC2NodeClasses.
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+const (
+	// provisionerNameLabelKey is the v1alpha5 label that was replaced by karpenter.sh/nodepool
+	provisionerNameLabelKey = "karpenter.sh/provisioner-name"
+	// doNotEvictAnnotationKey and doNotConsolidateAnnotationKey are the v1alpha5 annotations that were replaced by
+	// karpenter.sh/do-not-disrupt
+	doNotEvictAnnotationKey       = "karpenter.sh/do-not-evict"
+	doNotConsolidateAnnotationKey = "karpenter.sh/do-not-consolidate"
+)
+
+// Options configures the fields that can't be derived from the v1alpha5 and v1alpha1 resources
+type Options struct {
+	// InstanceProfile is used for AWSNodeTemplates that don't specify one, since v1alpha1 defaulted it from the
+	// aws.defaultInstanceProfile global setting, which no longer exists
+	InstanceProfile string
+	// Role is used for AWSNodeTemplates that don't specify an instance profile when InstanceProfile isn't set
+	Role string
+}
+
+// Result holds the converted resources, and warnings about the fields that couldn't be converted
+type Result struct {
+	NodePools      []*corev1beta1.NodePool
+	EC2NodeClasses []*v1beta1.EC2NodeClass
+	Warnings       []string
+}
+
+// Convert converts Provisioners to NodePools and AWSNodeTemplates to EC2NodeClasses. The provider inlined in a
+// Provisioner is converted to an EC2NodeClass with the name of the Provisioner.
+func Convert(provisioners []*Provisioner, nodeTemplates []*AWSNodeTemplate, opts Options) (*Result, error) {
+	result := &Result{}
+	for _, nodeTemplate := range nodeTemplates {
+		result.EC2NodeClasses = append(result.EC2NodeClasses, result.ec2NodeClass(nodeTemplate.ObjectMeta, nodeTemplate.Spec, opts))
+	}
+	for _, provisioner := range provisioners {
+		if provisioner.Spec.Provider != nil {
+			spec := AWSNodeTemplateSpec{}
+			if err := json.Unmarshal(provisioner.Spec.Provider.Raw, &spec); err != nil {
+				return nil, fmt.Errorf("decoding provider of provisioner %q, %w", provisioner.Name, err)
+			}
+			result.EC2NodeClasses = append(result.EC2NodeClasses, result.ec2NodeClass(metav1.ObjectMeta{Name: provisioner.Name}, spec, opts))
+		}
+		result.NodePools = append(result.NodePools, result.nodePool(provisioner))
+	}
+	sort.Slice(result.EC2NodeClasses, func(i, j int) bool { return result.EC2NodeClasses[i].Name < result.EC2NodeClasses[j].Name })
+	sort.Slice(result.NodePools, func(i, j int) bool { return result.NodePools[i].Name < result.NodePools[j].Name })
+	return result, nil
+}
+
+func (r *Result) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (r *Result) nodePool(provisioner *Provisioner) *corev1beta1.NodePool {
+	spec := provisioner.Spec
+	nodePool := &corev1beta1.NodePool{
+		TypeMeta:   metav1.TypeMeta{APIVersion: NodePoolGroupVersion, Kind: "NodePool"},
+		ObjectMeta: metav1.ObjectMeta{Name: provisioner.Name, Labels: provisioner.Labels, Annotations: lo.OmitByKeys(provisioner.Annotations, []string{v1.LastAppliedConfigAnnotation})},
+		Spec: corev1beta1.NodePoolSpec{
+			Template: corev1beta1.NodeClaimTemplate{
+				ObjectMeta: corev1beta1.ObjectMeta{
+					Labels:      spec.Labels,
+					Annotations: r.annotations(provisioner.Name, spec.Annotations),
+				},
+				Spec: corev1beta1.NodeClaimSpec{
+					Taints:        spec.Taints,
+					StartupTaints: spec.StartupTaints,
+					Requirements: lo.Map(spec.Requirements, func(req v1.NodeSelectorRequirement, _ int) corev1beta1.NodeSelectorRequirementWithMinValues {
+						if req.Key == provisionerNameLabelKey {
+							req.Key = corev1beta1.NodePoolLabelKey
+						}
+						return corev1beta1.NodeSelectorRequirementWithMinValues{NodeSelectorRequirement: req}
+					}),
+					NodeClassRef: &corev1beta1.NodeClassReference{
+						APIVersion: EC2NodeClassGroupVersion,
+						Kind:       "EC2NodeClass",
+						Name:       provisioner.Name,
+					},
+				},
+			},
+			Disruption: r.disruption(spec),
+			Weight:     spec.Weight,
+		},
+	}
+	if spec.ProviderRef != nil {
+		nodePool.Spec.Template.Spec.NodeClassRef.Name = spec.ProviderRef.Name
+	} else if spec.Provider == nil {
+		r.warnf("provisioner %q references no provider, set spec.template.spec.nodeClassRef of the nodepool to an existing ec2nodeclass", provisioner.Name)
+	}
+	if spec.KubeletConfiguration != nil {
+		if spec.KubeletConfiguration.ContainerRuntime != nil {
+			r.warnf("provisioner %q sets kubeletConfiguration.containerRuntime, which was removed since containerd is the only supported runtime", provisioner.Name)
+		}
+		nodePool.Spec.Template.Spec.Kubelet = lo.ToPtr(spec.KubeletConfiguration.KubeletConfiguration)
+	}
+	if spec.Limits != nil {
+		nodePool.Spec.Limits = corev1beta1.Limits(spec.Limits.Resources)
+	}
+	return nodePool
+}
+
+// annotations renames the v1alpha5 annotations that prevent nodes from being disrupted
+func (r *Result) annotations(name string, annotations map[string]string) map[string]string {
+	if annotations == nil {
+		return nil
+	}
+	converted := lo.OmitByKeys(annotations, []string{doNotEvictAnnotationKey, doNotConsolidateAnnotationKey})
+	for _, key := range []string{doNotEvictAnnotationKey, doNotConsolidateAnnotationKey} {
+		if value, ok := annotations[key]; ok {
+			r.warnf("provisioner %q sets annotation %s, which was replaced by %s", name, key, corev1beta1.DoNotDisruptAnnotationKey)
+			converted[corev1beta1.DoNotDisruptAnnotationKey] = value
+		}
+	}
+	return converted
+}
+
+// disruption converts consolidation and the TTLs of a Provisioner. Nodes of Provisioners without ttlSecondsAfterEmpty
+// or ttlSecondsUntilExpired were never removed for being empty or expired, which is expressed as Never.
+func (r *Result) disruption(spec ProvisionerSpec) corev1beta1.Disruption {
+	disruption := corev1beta1.Disruption{
+		ConsolidationPolicy: corev1beta1.ConsolidationPolicyWhenEmpty,
+		ConsolidateAfter:    &corev1beta1.NillableDuration{},
+	}
+	if spec.Consolidation != nil && lo.FromPtr(spec.Consolidation.Enabled) {
+		disruption.ConsolidationPolicy = corev1beta1.ConsolidationPolicyWhenUnderutilized
+		disruption.ConsolidateAfter = nil
+	} else if spec.TTLSecondsAfterEmpty != nil {
+		disruption.ConsolidateAfter = &corev1beta1.NillableDuration{Duration: lo.ToPtr(time.Duration(*spec.TTLSecondsAfterEmpty) * time.Second)}
+	}
+	if spec.TTLSecondsUntilExpired != nil {
+		disruption.ExpireAfter = corev1beta1.NillableDuration{Duration: lo.ToPtr(time.Duration(*spec.TTLSecondsUntilExpired) * time.Second)}
+	}
+	return disruption
+}
+
+func (r *Result) ec2NodeClass(meta metav1.ObjectMeta, spec AWSNodeTemplateSpec, opts Options) *v1beta1.EC2NodeClass {
+	nodeClass := &v1beta1.EC2NodeClass{
+		TypeMeta:   metav1.TypeMeta{APIVersion: EC2NodeClassGroupVersion, Kind: "EC2NodeClass"},
+		ObjectMeta: metav1.ObjectMeta{Name: meta.Name, Labels: meta.Labels, Annotations: lo.OmitByKeys(meta.Annotations, []string{v1.LastAppliedConfigAnnotation})},
+		Spec: v1beta1.EC2NodeClassSpec{
+			SubnetSelectorTerms: lo.Map(selectorTerms(spec.SubnetSelector), func(t selectorTerm, _ int) v1beta1.SubnetSelectorTerm {
+				return v1beta1.SubnetSelectorTerm{Tags: t.tags, ID: t.id}
+			}),
+			SecurityGroupSelectorTerms: lo.Map(selectorTerms(spec.SecurityGroupSelector), func(t selectorTerm, _ int) v1beta1.SecurityGroupSelectorTerm {
+				return v1beta1.SecurityGroupSelectorTerm{Tags: t.tags, ID: t.id}
+			}),
+			AMISelectorTerms: lo.Map(selectorTerms(spec.AMISelector), func(t selectorTerm, _ int) v1beta1.AMISelectorTerm {
+				return v1beta1.AMISelectorTerm{Tags: t.tags, ID: t.id, Name: t.name, Owner: t.owner}
+			}),
+			// AL2 was the default AMI family of v1alpha1, and is used with an amiSelector if no family is set
+			AMIFamily:           lo.Ternary(spec.AMIFamily != nil, spec.AMIFamily, lo.ToPtr(v1beta1.AMIFamilyAL2)),
+			Context:             spec.Context,
+			UserData:            spec.UserData,
+			DetailedMonitoring:  spec.DetailedMonitoring,
+			MetadataOptions:     spec.MetadataOptions,
+			BlockDeviceMappings: spec.BlockDeviceMappings,
+			Tags: lo.OmitBy(spec.Tags, func(k, _ string) bool {
+				restricted := lo.ContainsBy(v1beta1.RestrictedTagPatterns, func(pattern *regexp.Regexp) bool { return pattern.MatchString(k) })
+				if restricted {
+					r.warnf("dropping tag %q of %q, since it's reserved for karpenter", k, meta.Name)
+				}
+				return restricted
+			}),
+		},
+	}
+	switch {
+	case spec.InstanceProfile != nil:
+		nodeClass.Spec.InstanceProfile = spec.InstanceProfile
+	case opts.InstanceProfile != "":
+		nodeClass.Spec.InstanceProfile = lo.ToPtr(opts.InstanceProfile)
+	default:
+		nodeClass.Spec.Role = opts.Role
+		r.warnf("%q doesn't specify an instance profile, so spec.role of the ec2nodeclass is set to %q", meta.Name, opts.Role)
+	}
+	if spec.LaunchTemplateName != nil {
+		r.warnf("%q uses launch template %q, which is no longer supported. Its settings must be moved to the ec2nodeclass", meta.Name, *spec.LaunchTemplateName)
+	}
+	if len(spec.SubnetSelector) == 0 || len(spec.SecurityGroupSelector) == 0 {
+		r.warnf("%q has no subnetSelector or securityGroupSelector, which are required by ec2nodeclasses", meta.Name)
+	}
+	return nodeClass
+}
+
+type selectorTerm struct {
+	tags  map[string]string
+	id    string
+	name  string
+	owner string
+}
+
+// selectorTerms converts a v1alpha1 selector, whose keys are ANDed, to v1beta1 selector terms, which are ORed. IDs
+// select resources on their own, so each ID is a term. The owners of an amiSelector are ORed, so each owner is a term.
+func selectorTerms(selector map[string]string) []selectorTerm {
+	if len(selector) == 0 {
+		return nil
+	}
+	for _, key := range []string{"aws-ids", "aws::ids"} {
+		if ids, ok := selector[key]; ok {
+			return lo.Map(splitList(ids), func(id string, _ int) selectorTerm { return selectorTerm{id: id} })
+		}
+	}
+	term := selectorTerm{
+		name: selector["aws::name"],
+		tags: lo.OmitByKeys(selector, []string{"aws::name", "aws::owners"}),
+	}
+	if len(term.tags) == 0 {
+		term.tags = nil
+	}
+	owners, ok := selector["aws::owners"]
+	if !ok {
+		return []selectorTerm{term}
+	}
+	return lo.Map(splitList(owners), func(owner string, _ int) selectorTerm {
+		t := term
+		t.owner = owner
+		return t
+	})
+}
+
+func splitList(s string) []string {
+	return lo.FilterMap(strings.Split(s, ","), func(v string, _ int) (string, bool) {
+		v = strings.TrimSpace(v)
+		return v, v != ""
+	})
+}
+
+// Decode reads Provisioners and AWSNodeTemplates from YAML or JSON documents, including lists such as those written by
+// kubectl get -o yaml
+func Decode(reader io.Reader) ([]*Provisioner, []*AWSNodeTemplate, error) {
+	var provisioners []*Provisioner
+	var nodeTemplates []*AWSNodeTemplate
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return provisioners, nodeTemplates, nil
+			}
+			return nil, nil, fmt.Errorf("decoding document, %w", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		objects := []unstructured.Unstructured{*u}
+		if u.IsList() {
+			list, err := u.ToList()
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding list, %w", err)
+			}
+			objects = list.Items
+		}
+		for i := range objects {
+			switch gvk := objects[i].GroupVersionKind(); {
+			case gvk.GroupVersion().String() == ProvisionerGroupVersion && gvk.Kind == ProvisionerKind:
+				provisioner := &Provisioner{}
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objects[i].Object, provisioner); err != nil {
+					return nil, nil, fmt.Errorf("decoding provisioner %q, %w", objects[i].GetName(), err)
+				}
+				provisioners = append(provisioners, provisioner)
+			case gvk.GroupVersion().String() == AWSNodeTemplateGroupVersion && gvk.Kind == AWSNodeTemplateKind:
+				nodeTemplate := &AWSNodeTemplate{}
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objects[i].Object, nodeTemplate); err != nil {
+					return nil, nil, fmt.Errorf("decoding awsnodetemplate %q, %w", objects[i].GetName(), err)
+				}
+				nodeTemplates = append(nodeTemplates, nodeTemplate)
+			default:
+				return nil, nil, fmt.Errorf("%s %q is not a %s provisioner or %s awsnodetemplate", gvk.String(), objects[i].GetName(), ProvisionerGroupVersion, AWSNodeTemplateGroupVersion)
+			}
+		}
+	}
+}
+
+// Encode writes the EC2NodeClasses and then the NodePools of the result as YAML documents, without the status and
+// server-populated metadata, so that they can be applied as is
+func Encode(writer io.Writer, result *Result) error {
+	objects := append(
+		lo.Map(result.EC2NodeClasses, func(nc *v1beta1.EC2NodeClass, _ int) runtime.Object { return nc }),
+		lo.Map(result.NodePools, func(np *corev1beta1.NodePool, _ int) runtime.Object { return np })...,
+	)
+	for i, object := range objects {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			return fmt.Errorf("converting to unstructured, %w", err)
+		}
+		delete(u, "status")
+		unstructured.RemoveNestedField(u, "metadata", "creationTimestamp")
+		out, err := sigsyaml.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("marshaling, %w", err)
+		}
+		if i > 0 {
+			if _, err := io.WriteString(writer, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := writer.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
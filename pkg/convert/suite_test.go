@@ -0,0 +1,315 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/convert"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestConvert(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Convert")
+}
+
+const provisioner = `apiVersion: karpenter.sh/v1alpha5
+kind: Provisioner
+metadata:
+  name: default
+spec:
+  labels:
+    team: a
+  annotations:
+    karpenter.sh/do-not-consolidate: "true"
+  taints:
+  - key: dedicated
+    value: a
+    effect: NoSchedule
+  requirements:
+  - key: karpenter.sh/capacity-type
+    operator: In
+    values: ["spot", "on-demand"]
+  - key: karpenter.sh/provisioner-name
+    operator: Exists
+  kubeletConfiguration:
+    maxPods: 110
+    containerRuntime: containerd
+    evictionSoftGracePeriod:
+      memory.available: 1m
+  limits:
+    resources:
+      cpu: "1000"
+  providerRef:
+    name: template
+  ttlSecondsAfterEmpty: 30
+  ttlSecondsUntilExpired: 2592000
+  weight: 10
+`
+
+const nodeTemplate = `apiVersion: karpenter.k8s.aws/v1alpha1
+kind: AWSNodeTemplate
+metadata:
+  name: template
+spec:
+  subnetSelector:
+    karpenter.sh/discovery: my-cluster
+  securityGroupSelector:
+    aws-ids: sg-1, sg-2
+  amiSelector:
+    aws::name: my-ami-*
+    aws::owners: self,amazon
+    team: a
+  instanceProfile: my-profile
+  amiFamily: Bottlerocket
+  tags:
+    cost-center: "1234"
+    karpenter.sh/nodepool: default
+  metadataOptions:
+    httpTokens: required
+  blockDeviceMappings:
+  - deviceName: /dev/xvda
+    ebs:
+      volumeSize: 100Gi
+      volumeType: gp3
+  detailedMonitoring: true
+`
+
+func decodeAndConvert(manifests string, opts convert.Options) *convert.Result {
+	provisioners, nodeTemplates, err := convert.Decode(strings.NewReader(manifests))
+	Expect(err).ToNot(HaveOccurred())
+	result, err := convert.Convert(provisioners, nodeTemplates, opts)
+	Expect(err).ToNot(HaveOccurred())
+	return result
+}
+
+var _ = Describe("Convert", func() {
+	Context("NodePool", func() {
+		It("should convert a provisioner to a nodepool", func() {
+			result := decodeAndConvert(provisioner, convert.Options{})
+			Expect(result.NodePools).To(HaveLen(1))
+			nodePool := result.NodePools[0]
+			Expect(nodePool.Name).To(Equal("default"))
+			Expect(nodePool.Spec.Template.Labels).To(Equal(map[string]string{"team": "a"}))
+			Expect(nodePool.Spec.Template.Spec.Taints).To(Equal([]v1.Taint{{Key: "dedicated", Value: "a", Effect: v1.TaintEffectNoSchedule}}))
+			Expect(nodePool.Spec.Template.Spec.Requirements).To(ConsistOf(
+				corev1beta1.NodeSelectorRequirementWithMinValues{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{"spot", "on-demand"}}},
+				corev1beta1.NodeSelectorRequirementWithMinValues{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.NodePoolLabelKey, Operator: v1.NodeSelectorOpExists}},
+			))
+			Expect(nodePool.Spec.Template.Spec.NodeClassRef).To(Equal(&corev1beta1.NodeClassReference{APIVersion: "karpenter.k8s.aws/v1beta1", Kind: "EC2NodeClass", Name: "template"}))
+			Expect(nodePool.Spec.Limits).To(HaveKeyWithValue(v1.ResourceCPU, resource.MustParse("1000")))
+			Expect(lo.FromPtr(nodePool.Spec.Weight)).To(BeEquivalentTo(10))
+		})
+		It("should convert the kubelet configuration and warn about the container runtime", func() {
+			result := decodeAndConvert(provisioner, convert.Options{})
+			kubelet := result.NodePools[0].Spec.Template.Spec.Kubelet
+			Expect(kubelet).ToNot(BeNil())
+			Expect(lo.FromPtr(kubelet.MaxPods)).To(BeEquivalentTo(110))
+			Expect(kubelet.EvictionSoftGracePeriod["memory.available"].Duration).To(Equal(time.Minute))
+			Expect(result.Warnings).To(ContainElement(ContainSubstring("containerRuntime")))
+		})
+		It("should replace the do-not-consolidate annotation with do-not-disrupt", func() {
+			result := decodeAndConvert(provisioner, convert.Options{})
+			Expect(result.NodePools[0].Spec.Template.Annotations).To(Equal(map[string]string{corev1beta1.DoNotDisruptAnnotationKey: "true"}))
+		})
+		It("should convert the TTLs of a provisioner without consolidation", func() {
+			result := decodeAndConvert(provisioner, convert.Options{})
+			disruption := result.NodePools[0].Spec.Disruption
+			Expect(disruption.ConsolidationPolicy).To(Equal(corev1beta1.ConsolidationPolicyWhenEmpty))
+			Expect(lo.FromPtr(disruption.ConsolidateAfter.Duration)).To(Equal(30 * time.Second))
+			Expect(lo.FromPtr(disruption.ExpireAfter.Duration)).To(Equal(30 * 24 * time.Hour))
+		})
+		It("should never expire or remove empty nodes if the provisioner has no TTLs", func() {
+			result := decodeAndConvert(`apiVersion: karpenter.sh/v1alpha5
+kind: Provisioner
+metadata:
+  name: default
+spec:
+  providerRef:
+    name: template
+`, convert.Options{})
+			disruption := result.NodePools[0].Spec.Disruption
+			Expect(disruption.ConsolidationPolicy).To(Equal(corev1beta1.ConsolidationPolicyWhenEmpty))
+			Expect(disruption.ConsolidateAfter.Duration).To(BeNil())
+			Expect(disruption.ExpireAfter.Duration).To(BeNil())
+		})
+		It("should convert consolidation to the WhenUnderutilized policy", func() {
+			result := decodeAndConvert(`apiVersion: karpenter.sh/v1alpha5
+kind: Provisioner
+metadata:
+  name: default
+spec:
+  consolidation:
+    enabled: true
+  providerRef:
+    name: template
+`, convert.Options{})
+			disruption := result.NodePools[0].Spec.Disruption
+			Expect(disruption.ConsolidationPolicy).To(Equal(corev1beta1.ConsolidationPolicyWhenUnderutilized))
+			Expect(disruption.ConsolidateAfter).To(BeNil())
+		})
+	})
+	Context("EC2NodeClass", func() {
+		It("should convert an awsnodetemplate to an ec2nodeclass", func() {
+			result := decodeAndConvert(nodeTemplate, convert.Options{})
+			Expect(result.EC2NodeClasses).To(HaveLen(1))
+			nodeClass := result.EC2NodeClasses[0]
+			Expect(nodeClass.Name).To(Equal("template"))
+			Expect(nodeClass.Spec.SubnetSelectorTerms).To(Equal([]v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"karpenter.sh/discovery": "my-cluster"}}}))
+			Expect(nodeClass.Spec.SecurityGroupSelectorTerms).To(Equal([]v1beta1.SecurityGroupSelectorTerm{{ID: "sg-1"}, {ID: "sg-2"}}))
+			Expect(nodeClass.Spec.AMISelectorTerms).To(Equal([]v1beta1.AMISelectorTerm{
+				{Name: "my-ami-*", Owner: "self", Tags: map[string]string{"team": "a"}},
+				{Name: "my-ami-*", Owner: "amazon", Tags: map[string]string{"team": "a"}},
+			}))
+			Expect(lo.FromPtr(nodeClass.Spec.InstanceProfile)).To(Equal("my-profile"))
+			Expect(nodeClass.Spec.Role).To(BeEmpty())
+			Expect(lo.FromPtr(nodeClass.Spec.AMIFamily)).To(Equal(v1beta1.AMIFamilyBottlerocket))
+			Expect(lo.FromPtr(nodeClass.Spec.MetadataOptions.HTTPTokens)).To(Equal("required"))
+			Expect(nodeClass.Spec.BlockDeviceMappings).To(HaveLen(1))
+			Expect(nodeClass.Spec.BlockDeviceMappings[0].EBS.VolumeSize.String()).To(Equal("100Gi"))
+			Expect(lo.FromPtr(nodeClass.Spec.DetailedMonitoring)).To(BeTrue())
+		})
+		It("should drop tags that are reserved for karpenter", func() {
+			result := decodeAndConvert(nodeTemplate, convert.Options{})
+			Expect(result.EC2NodeClasses[0].Spec.Tags).To(Equal(map[string]string{"cost-center": "1234"}))
+			Expect(result.Warnings).To(ContainElement(ContainSubstring("karpenter.sh/nodepool")))
+		})
+		It("should default the AMI family to AL2", func() {
+			result := decodeAndConvert(`apiVersion: karpenter.k8s.aws/v1alpha1
+kind: AWSNodeTemplate
+metadata:
+  name: template
+spec:
+  subnetSelector:
+    aws-ids: subnet-1
+  securityGroupSelector:
+    Name: my-sg
+  instanceProfile: my-profile
+`, convert.Options{})
+			Expect(lo.FromPtr(result.EC2NodeClasses[0].Spec.AMIFamily)).To(Equal(v1beta1.AMIFamilyAL2))
+			Expect(result.EC2NodeClasses[0].Spec.SubnetSelectorTerms).To(Equal([]v1beta1.SubnetSelectorTerm{{ID: "subnet-1"}}))
+		})
+		It("should use the default instance profile, then the role, if the awsnodetemplate has no instance profile", func() {
+			manifest := `apiVersion: karpenter.k8s.aws/v1alpha1
+kind: AWSNodeTemplate
+metadata:
+  name: template
+spec:
+  subnetSelector:
+    karpenter.sh/discovery: my-cluster
+  securityGroupSelector:
+    karpenter.sh/discovery: my-cluster
+`
+			result := decodeAndConvert(manifest, convert.Options{InstanceProfile: "default-profile", Role: "my-role"})
+			Expect(lo.FromPtr(result.EC2NodeClasses[0].Spec.InstanceProfile)).To(Equal("default-profile"))
+			Expect(result.EC2NodeClasses[0].Spec.Role).To(BeEmpty())
+
+			result = decodeAndConvert(manifest, convert.Options{Role: "my-role"})
+			Expect(result.EC2NodeClasses[0].Spec.InstanceProfile).To(BeNil())
+			Expect(result.EC2NodeClasses[0].Spec.Role).To(Equal("my-role"))
+			Expect(result.Warnings).To(ContainElement(ContainSubstring("my-role")))
+		})
+		It("should warn about launch templates", func() {
+			result := decodeAndConvert(`apiVersion: karpenter.k8s.aws/v1alpha1
+kind: AWSNodeTemplate
+metadata:
+  name: template
+spec:
+  launchTemplate: my-launch-template
+  subnetSelector:
+    karpenter.sh/discovery: my-cluster
+  securityGroupSelector:
+    karpenter.sh/discovery: my-cluster
+`, convert.Options{})
+			Expect(result.Warnings).To(ContainElement(ContainSubstring("my-launch-template")))
+		})
+		It("should convert the provider inlined in a provisioner to an ec2nodeclass of the same name", func() {
+			result := decodeAndConvert(`apiVersion: karpenter.sh/v1alpha5
+kind: Provisioner
+metadata:
+  name: inline
+spec:
+  provider:
+    apiVersion: extensions.karpenter.sh/v1alpha1
+    kind: AWS
+    instanceProfile: my-profile
+    subnetSelector:
+      karpenter.sh/discovery: my-cluster
+    securityGroupSelector:
+      karpenter.sh/discovery: my-cluster
+`, convert.Options{})
+			Expect(result.EC2NodeClasses).To(HaveLen(1))
+			Expect(result.EC2NodeClasses[0].Name).To(Equal("inline"))
+			Expect(lo.FromPtr(result.EC2NodeClasses[0].Spec.InstanceProfile)).To(Equal("my-profile"))
+			Expect(result.NodePools[0].Spec.Template.Spec.NodeClassRef.Name).To(Equal("inline"))
+		})
+	})
+	Context("Decode", func() {
+		It("should decode lists and multiple documents", func() {
+			list := `apiVersion: v1
+kind: List
+items:
+- apiVersion: karpenter.sh/v1alpha5
+  kind: Provisioner
+  metadata:
+    name: a
+- apiVersion: karpenter.sh/v1alpha5
+  kind: Provisioner
+  metadata:
+    name: b
+`
+			provisioners, nodeTemplates, err := convert.Decode(strings.NewReader(list + "---\n" + nodeTemplate))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(provisioners, func(p *convert.Provisioner, _ int) string { return p.Name })).To(Equal([]string{"a", "b"}))
+			Expect(nodeTemplates).To(HaveLen(1))
+		})
+		It("should fail on resources that aren't provisioners or awsnodetemplates", func() {
+			_, _, err := convert.Decode(strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Encode", func() {
+		It("should write ec2nodeclasses and nodepools that can be applied", func() {
+			result := decodeAndConvert(provisioner+"---\n"+nodeTemplate, convert.Options{})
+			out := &bytes.Buffer{}
+			Expect(convert.Encode(out, result)).To(Succeed())
+			documents := strings.Split(out.String(), "---\n")
+			Expect(documents).To(HaveLen(2))
+			nodeClass := map[string]interface{}{}
+			Expect(yaml.Unmarshal([]byte(documents[0]), &nodeClass)).To(Succeed())
+			Expect(nodeClass).To(HaveKeyWithValue("kind", "EC2NodeClass"))
+			Expect(nodeClass).ToNot(HaveKey("status"))
+			Expect(nodeClass["metadata"]).ToNot(HaveKey("creationTimestamp"))
+			nodePool := map[string]interface{}{}
+			Expect(yaml.Unmarshal([]byte(documents[1]), &nodePool)).To(Succeed())
+			Expect(nodePool).To(HaveKeyWithValue("apiVersion", "karpenter.sh/v1beta1"))
+			Expect(nodePool).To(HaveKeyWithValue("kind", "NodePool"))
+			Expect(nodePool["spec"].(map[string]interface{})["disruption"]).To(HaveKeyWithValue("expireAfter", "720h0m0s"))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// The v1alpha5 and v1alpha1 APIs were removed from Karpenter, so the fields that are converted are redeclared here.
+// Fields whose schema didn't change in v1beta1 reuse the v1beta1 types.
+
+const (
+	ProvisionerGroupVersion     = "karpenter.sh/v1alpha5"
+	ProvisionerKind             = "Provisioner"
+	AWSNodeTemplateGroupVersion = "karpenter.k8s.aws/v1alpha1"
+	AWSNodeTemplateKind         = "AWSNodeTemplate"
+	NodePoolGroupVersion        = "karpenter.sh/v1beta1"
+	EC2NodeClassGroupVersion    = "karpenter.k8s.aws/v1beta1"
+)
+
+// Provisioner is a v1alpha5 Provisioner
+type Provisioner struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ProvisionerSpec `json:"spec,omitempty"`
+}
+
+type ProvisionerSpec struct {
+	Annotations            map[string]string            `json:"annotations,omitempty"`
+	Labels                 map[string]string            `json:"labels,omitempty"`
+	Taints                 []v1.Taint                   `json:"taints,omitempty"`
+	StartupTaints          []v1.Taint                   `json:"startupTaints,omitempty"`
+	Requirements           []v1.NodeSelectorRequirement `json:"requirements,omitempty"`
+	KubeletConfiguration   *KubeletConfiguration        `json:"kubeletConfiguration,omitempty"`
+	Provider               *runtime.RawExtension        `json:"provider,omitempty"`
+	ProviderRef            *ProviderRef                 `json:"providerRef,omitempty"`
+	TTLSecondsAfterEmpty   *int64                       `json:"ttlSecondsAfterEmpty,omitempty"`
+	TTLSecondsUntilExpired *int64                       `json:"ttlSecondsUntilExpired,omitempty"`
+	Limits                 *Limits                      `json:"limits,omitempty"`
+	Weight                 *int32                       `json:"weight,omitempty"`
+	Consolidation          *Consolidation               `json:"consolidation,omitempty"`
+}
+
+// KubeletConfiguration is the v1alpha5 kubelet configuration, which differs from v1beta1 only by the container runtime
+type KubeletConfiguration struct {
+	corev1beta1.KubeletConfiguration `json:",inline"`
+	ContainerRuntime                 *string `json:"containerRuntime,omitempty"`
+}
+
+type ProviderRef struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name"`
+}
+
+type Limits struct {
+	Resources v1.ResourceList `json:"resources,omitempty"`
+}
+
+type Consolidation struct {
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// AWSNodeTemplate is a v1alpha1 AWSNodeTemplate
+type AWSNodeTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AWSNodeTemplateSpec `json:"spec,omitempty"`
+}
+
+// AWSNodeTemplateSpec is the spec of an AWSNodeTemplate, or of the provider inlined in a Provisioner
+type AWSNodeTemplateSpec struct {
+	SubnetSelector        map[string]string             `json:"subnetSelector,omitempty"`
+	SecurityGroupSelector map[string]string             `json:"securityGroupSelector,omitempty"`
+	AMISelector           map[string]string             `json:"amiSelector,omitempty"`
+	InstanceProfile       *string                       `json:"instanceProfile,omitempty"`
+	AMIFamily             *string                       `json:"amiFamily,omitempty"`
+	Context               *string                       `json:"context,omitempty"`
+	LaunchTemplateName    *string                       `json:"launchTemplate,omitempty"`
+	Tags                  map[string]string             `json:"tags,omitempty"`
+	MetadataOptions       *v1beta1.MetadataOptions      `json:"metadataOptions,omitempty"`
+	BlockDeviceMappings   []*v1beta1.BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
+	UserData              *string                       `json:"userData,omitempty"`
+	DetailedMonitoring    *bool                         `json:"detailedMonitoring,omitempty"`
+}
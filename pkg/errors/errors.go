@@ -16,11 +16,15 @@ package errors
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -28,12 +32,32 @@ const (
 	launchTemplateNameNotFoundCode = "InvalidLaunchTemplateName.NotFoundException"
 )
 
+// The categories of CreateFleet errors, which let automation respond differently to e.g. running out of quota than to
+// running out of capacity
+const (
+	// FleetErrorCategoryCapacity is EC2 not having the capacity for the launch, which is usually temporary
+	FleetErrorCategoryCapacity = "Capacity"
+	// FleetErrorCategoryQuota is the launch exceeding a limit of the account, which lasts until the limit is raised
+	FleetErrorCategoryQuota = "Quota"
+	// FleetErrorCategoryPermission is the controller or the instance role lacking a permission that the launch needs
+	FleetErrorCategoryPermission = "Permission"
+	// FleetErrorCategoryMisconfiguration is the launch being rejected for its parameters, e.g. security groups that
+	// aren't in the VPC of the subnet
+	FleetErrorCategoryMisconfiguration = "Misconfiguration"
+	// FleetErrorCategoryUnknown is any other error
+	FleetErrorCategoryUnknown = "Unknown"
+	// FleetErrorCategoryUnexpectedInstanceType is CreateFleet launching an instance type that wasn't requested, which
+	// is terminated rather than registered as a node that Karpenter can't model
+	FleetErrorCategoryUnexpectedInstanceType = "UnexpectedInstanceType"
+)
+
 var (
 	// This is not an exhaustive list, add to it as needed
 	notFoundErrorCodes = sets.New[string](
 		"InvalidInstanceID.NotFound",
 		launchTemplateNameNotFoundCode,
 		"InvalidLaunchTemplateId.NotFound",
+		"InvalidInstanceConnectEndpointId.NotFound",
 		sqs.ErrCodeQueueDoesNotExist,
 		iam.ErrCodeNoSuchEntityException,
 	)
@@ -48,21 +72,38 @@ var (
 		"UnfulfillableCapacity",
 		"Unsupported",
 		"InsufficientFreeAddressesInSubnet",
+		"InsufficientCapacityOnOutpost",
+	)
+	quotaErrorCodes = sets.New[string](
+		"MaxSpotInstanceCountExceeded",
+		"VcpuLimitExceeded",
+		"InstanceLimitExceeded",
+		"MaxFleetCountExceeded",
+		"MaxTargetCapacityExceeded",
+	)
+	permissionErrorCodes = sets.New[string](
+		"UnauthorizedOperation",
+		"AuthFailure",
+		"AccessDenied",
+		"OptInRequired",
 	)
+	// fleetErrorCategoryPrecedence orders the categories from the one that most needs an operator's attention, which
+	// is the category of a CreateFleet request that failed with errors of several categories
+	fleetErrorCategoryPrecedence = []string{
+		FleetErrorCategoryPermission,
+		FleetErrorCategoryMisconfiguration,
+		FleetErrorCategoryQuota,
+		FleetErrorCategoryCapacity,
+		FleetErrorCategoryUnknown,
+	}
 )
 
 // IsNotFound returns true if the err is an AWS error (even if it's
 // wrapped) and is a known to mean "not found" (as opposed to a more
 // serious or unexpected error)
 func IsNotFound(err error) bool {
-	if err == nil {
-		return false
-	}
-	var awsError awserr.Error
-	if errors.As(err, &awsError) {
-		return notFoundErrorCodes.Has(awsError.Code())
-	}
-	return false
+	code, ok := ErrorCode(err)
+	return ok && notFoundErrorCodes.Has(code)
 }
 
 func IgnoreNotFound(err error) error {
@@ -73,14 +114,8 @@ func IgnoreNotFound(err error) error {
 }
 
 func IsAlreadyExists(err error) bool {
-	if err == nil {
-		return false
-	}
-	var awsError awserr.Error
-	if errors.As(err, &awsError) {
-		return alreadyExistsErrorCodes.Has(awsError.Code())
-	}
-	return false
+	code, ok := ErrorCode(err)
+	return ok && alreadyExistsErrorCodes.Has(code)
 }
 
 func IgnoreAlreadyExists(err error) error {
@@ -93,17 +128,90 @@ func IgnoreAlreadyExists(err error) error {
 // IsUnfulfillableCapacity returns true if the Fleet err means
 // capacity is temporarily unavailable for launching.
 // This could be due to account limits, insufficient ec2 capacity, etc.
-func IsUnfulfillableCapacity(err *ec2.CreateFleetError) bool {
-	return unfulfillableCapacityErrorCodes.Has(*err.ErrorCode)
+func IsUnfulfillableCapacity(err ec2types.CreateFleetError) bool {
+	return unfulfillableCapacityErrorCodes.Has(lo.FromPtr(err.ErrorCode))
 }
 
 func IsLaunchTemplateNotFound(err error) bool {
+	code, ok := ErrorCode(err)
+	return ok && code == launchTemplateNameNotFoundCode
+}
+
+// ErrorCode returns the code of the AWS error that err wraps, whether it's an error of aws-sdk-go or of
+// aws-sdk-go-v2, and false if err doesn't wrap an AWS error
+func ErrorCode(err error) (string, bool) {
 	if err == nil {
-		return false
+		return "", false
 	}
 	var awsError awserr.Error
 	if errors.As(err, &awsError) {
-		return awsError.Code() == launchTemplateNameNotFoundCode
+		return awsError.Code(), true
+	}
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		return apiError.ErrorCode(), true
+	}
+	return "", false
+}
+
+// FleetErrorCategory returns the category of a CreateFleet error code
+func FleetErrorCategory(code string) string {
+	switch {
+	case quotaErrorCodes.Has(code):
+		return FleetErrorCategoryQuota
+	case unfulfillableCapacityErrorCodes.Has(code):
+		return FleetErrorCategoryCapacity
+	case permissionErrorCodes.Has(strings.TrimPrefix(code, "Client.")):
+		return FleetErrorCategoryPermission
+	case strings.HasPrefix(strings.TrimPrefix(code, "Client."), "Invalid"):
+		return FleetErrorCategoryMisconfiguration
+	default:
+		return FleetErrorCategoryUnknown
+	}
+}
+
+// FleetError is the error of a CreateFleet request that didn't launch an instance, along with the category of its
+// errors
+type FleetError struct {
+	error
+	Category string
+}
+
+// NewFleetError returns a FleetError for the errors of a CreateFleet request. When the errors have several categories,
+// the category that most needs an operator's attention is used.
+func NewFleetError(err error, codes ...string) *FleetError {
+	categories := sets.New[string]()
+	for _, code := range codes {
+		categories.Insert(FleetErrorCategory(code))
+	}
+	category := FleetErrorCategoryUnknown
+	for _, c := range fleetErrorCategoryPrecedence {
+		if categories.Has(c) {
+			category = c
+			break
+		}
+	}
+	return &FleetError{error: err, Category: category}
+}
+
+// NewUnexpectedInstanceTypeError returns a FleetError for a CreateFleet request that launched an instance type that
+// wasn't one of the requested instance types
+func NewUnexpectedInstanceTypeError(instanceID, instanceType string) *FleetError {
+	return &FleetError{
+		error:    fmt.Errorf("creating fleet, launched instance %s with unexpected instance type %q", instanceID, instanceType),
+		Category: FleetErrorCategoryUnexpectedInstanceType,
+	}
+}
+
+func (e *FleetError) Unwrap() error {
+	return e.error
+}
+
+// AsFleetError returns the FleetError that err wraps, if any
+func AsFleetError(err error) (*FleetError, bool) {
+	var fleetErr *FleetError
+	if errors.As(err, &fleetErr) {
+		return fleetErr, true
 	}
-	return false
+	return nil, false
 }
@@ -0,0 +1,139 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+)
+
+var _ amifamily.Provider = (*AMIProvider)(nil)
+var _ amifamily.Resolver = (*AMIResolver)(nil)
+
+// AMIProvider is a fake amifamily.Provider that returns the AMIs and release version that it's set up with
+type AMIProvider struct {
+	mu               sync.RWMutex
+	amis             amifamily.AMIs
+	releaseVersion   string
+	deprecationTimes map[string]time.Time
+	NextError        AtomicError
+}
+
+func NewAMIProvider() *AMIProvider {
+	return &AMIProvider{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *AMIProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.amis = nil
+	p.releaseVersion = ""
+	p.deprecationTimes = nil
+	p.NextError.Reset()
+}
+
+// SetAMIs sets the AMIs that are returned for every EC2NodeClass
+func (p *AMIProvider) SetAMIs(amis amifamily.AMIs) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.amis = amis
+}
+
+// SetLatestReleaseVersion sets the release version that's returned for every EC2NodeClass
+func (p *AMIProvider) SetLatestReleaseVersion(releaseVersion string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.releaseVersion = releaseVersion
+}
+
+// SetDeprecationTimes sets the times that AMIs are deprecated at
+func (p *AMIProvider) SetDeprecationTimes(deprecationTimes map[string]time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deprecationTimes = deprecationTimes
+}
+
+func (p *AMIProvider) List(_ context.Context, _ *v1beta1.EC2NodeClass) (amifamily.AMIs, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append(amifamily.AMIs{}, p.amis...), nil
+}
+
+func (p *AMIProvider) LatestReleaseVersion(_ context.Context, _ *v1beta1.EC2NodeClass) (string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return "", err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.releaseVersion, nil
+}
+
+func (p *AMIProvider) DeprecationTimes(_ context.Context, ids []string) (map[string]time.Time, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.PickByKeys(p.deprecationTimes, ids), nil
+}
+
+// AMIResolver is a fake amifamily.Resolver that resolves a launch template for the first AMI in the status of the
+// EC2NodeClass, which all of the instance types are launched with
+type AMIResolver struct {
+	NextError AtomicError
+}
+
+func NewAMIResolver() *AMIResolver {
+	return &AMIResolver{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (r *AMIResolver) Reset() {
+	r.NextError.Reset()
+}
+
+func (r *AMIResolver) Resolve(nodeClass *v1beta1.EC2NodeClass, _ *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, options *amifamily.Options) ([]*amifamily.LaunchTemplate, error) {
+	if err := r.NextError.Get(); err != nil {
+		return nil, err
+	}
+	if len(nodeClass.Status.AMIs) == 0 {
+		return nil, fmt.Errorf("no amis exist given constraints")
+	}
+	return []*amifamily.LaunchTemplate{{
+		Options:             options,
+		BlockDeviceMappings: nodeClass.Spec.BlockDeviceMappings,
+		MetadataOptions:     nodeClass.Spec.MetadataOptions,
+		AMIID:               nodeClass.Status.AMIs[0].ID,
+		InstanceTypes:       instanceTypes,
+		DetailedMonitoring:  lo.FromPtr(nodeClass.Spec.DetailedMonitoring),
+		CapacityType:        capacityType,
+	}}, nil
+}
@@ -0,0 +1,34 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/karpenter-provider-aws/pkg/auditlog"
+)
+
+// AuditLogger records the launches that are audited instead of writing them to a sink
+type AuditLogger struct {
+	Launches AtomicPtrSlice[auditlog.Launch]
+}
+
+func (l *AuditLogger) Log(_ context.Context, launch *auditlog.Launch) {
+	l.Launches.Add(launch)
+}
+
+func (l *AuditLogger) Reset() {
+	l.Launches.Reset()
+}
@@ -55,16 +55,31 @@ type EC2Behavior struct {
 	DescribeAvailabilityZonesOutput     AtomicPtr[ec2.DescribeAvailabilityZonesOutput]
 	DescribeSpotPriceHistoryInput       AtomicPtr[ec2.DescribeSpotPriceHistoryInput]
 	DescribeSpotPriceHistoryOutput      AtomicPtr[ec2.DescribeSpotPriceHistoryOutput]
-	CreateFleetBehavior                 MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
-	TerminateInstancesBehavior          MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
-	DescribeInstancesBehavior           MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
-	CreateTagsBehavior                  MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
-	CalledWithCreateLaunchTemplateInput AtomicPtrSlice[ec2.CreateLaunchTemplateInput]
-	CalledWithDescribeImagesInput       AtomicPtrSlice[ec2.DescribeImagesInput]
-	Instances                           sync.Map
-	LaunchTemplates                     sync.Map
-	InsufficientCapacityPools           atomic.Slice[CapacityPool]
-	NextError                           AtomicError
+	// DescribeSpotPriceHistoryPageError is returned after the spot prices have been paged, as if a later page had failed
+	DescribeSpotPriceHistoryPageError        AtomicError
+	CreateFleetBehavior                      MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
+	TerminateInstancesBehavior               MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
+	DescribeInstancesBehavior                MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
+	CreateTagsBehavior                       MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
+	GetSpotPlacementScoresBehavior           MockedFunction[ec2.GetSpotPlacementScoresInput, ec2.GetSpotPlacementScoresOutput]
+	CreateInstanceConnectEndpointBehavior    MockedFunction[ec2.CreateInstanceConnectEndpointInput, ec2.CreateInstanceConnectEndpointOutput]
+	DeleteInstanceConnectEndpointBehavior    MockedFunction[ec2.DeleteInstanceConnectEndpointInput, ec2.DeleteInstanceConnectEndpointOutput]
+	DescribeInstanceConnectEndpointsBehavior MockedFunction[ec2.DescribeInstanceConnectEndpointsInput, ec2.DescribeInstanceConnectEndpointsOutput]
+	GetConsoleOutputBehavior                 MockedFunction[ec2.GetConsoleOutputInput, ec2.GetConsoleOutputOutput]
+	ModifyInstanceAttributeBehavior          MockedFunction[ec2.ModifyInstanceAttributeInput, ec2.ModifyInstanceAttributeOutput]
+	RebootInstancesBehavior                  MockedFunction[ec2.RebootInstancesInput, ec2.RebootInstancesOutput]
+	StartInstancesBehavior                   MockedFunction[ec2.StartInstancesInput, ec2.StartInstancesOutput]
+	StopInstancesBehavior                    MockedFunction[ec2.StopInstancesInput, ec2.StopInstancesOutput]
+	DeleteTagsBehavior                       MockedFunction[ec2.DeleteTagsInput, ec2.DeleteTagsOutput]
+	CalledWithCreateLaunchTemplateInput      AtomicPtrSlice[ec2.CreateLaunchTemplateInput]
+	CalledWithDescribeImagesInput            AtomicPtrSlice[ec2.DescribeImagesInput]
+	CalledWithDescribeSubnetsInput           AtomicPtrSlice[ec2.DescribeSubnetsInput]
+	CalledWithDescribeSecurityGroupsInput    AtomicPtrSlice[ec2.DescribeSecurityGroupsInput]
+	Instances                                sync.Map
+	LaunchTemplates                          sync.Map
+	InstanceConnectEndpoints                 sync.Map
+	InsufficientCapacityPools                atomic.Slice[CapacityPool]
+	NextError                                AtomicError
 }
 
 type EC2API struct {
@@ -92,10 +107,23 @@ func (e *EC2API) Reset() {
 	e.CreateFleetBehavior.Reset()
 	e.TerminateInstancesBehavior.Reset()
 	e.DescribeInstancesBehavior.Reset()
+	e.GetSpotPlacementScoresBehavior.Reset()
+	e.CreateInstanceConnectEndpointBehavior.Reset()
+	e.DeleteInstanceConnectEndpointBehavior.Reset()
+	e.DescribeInstanceConnectEndpointsBehavior.Reset()
+	e.GetConsoleOutputBehavior.Reset()
+	e.ModifyInstanceAttributeBehavior.Reset()
+	e.RebootInstancesBehavior.Reset()
+	e.StartInstancesBehavior.Reset()
+	e.StopInstancesBehavior.Reset()
+	e.DeleteTagsBehavior.Reset()
 	e.CalledWithCreateLaunchTemplateInput.Reset()
 	e.CalledWithDescribeImagesInput.Reset()
+	e.CalledWithDescribeSubnetsInput.Reset()
+	e.CalledWithDescribeSecurityGroupsInput.Reset()
 	e.DescribeSpotPriceHistoryInput.Reset()
 	e.DescribeSpotPriceHistoryOutput.Reset()
+	e.DescribeSpotPriceHistoryPageError.Reset()
 	e.Instances.Range(func(k, v any) bool {
 		e.Instances.Delete(k)
 		return true
@@ -104,6 +132,10 @@ func (e *EC2API) Reset() {
 		e.LaunchTemplates.Delete(k)
 		return true
 	})
+	e.InstanceConnectEndpoints.Range(func(k, v any) bool {
+		e.InstanceConnectEndpoints.Delete(k)
+		return true
+	})
 	e.InsufficientCapacityPools.Reset()
 	e.NextError.Reset()
 }
@@ -213,6 +245,114 @@ func (e *EC2API) TerminateInstancesWithContext(_ context.Context, input *ec2.Ter
 	})
 }
 
+func (e *EC2API) GetSpotPlacementScoresWithContext(_ context.Context, input *ec2.GetSpotPlacementScoresInput, _ ...request.Option) (*ec2.GetSpotPlacementScoresOutput, error) {
+	return e.GetSpotPlacementScoresBehavior.Invoke(input, func(_ *ec2.GetSpotPlacementScoresInput) (*ec2.GetSpotPlacementScoresOutput, error) {
+		return &ec2.GetSpotPlacementScoresOutput{}, nil
+	})
+}
+
+func (e *EC2API) GetConsoleOutputWithContext(_ context.Context, input *ec2.GetConsoleOutputInput, _ ...request.Option) (*ec2.GetConsoleOutputOutput, error) {
+	return e.GetConsoleOutputBehavior.Invoke(input, func(input *ec2.GetConsoleOutputInput) (*ec2.GetConsoleOutputOutput, error) {
+		return &ec2.GetConsoleOutputOutput{InstanceId: input.InstanceId}, nil
+	})
+}
+
+func (e *EC2API) ModifyInstanceAttributeWithContext(_ context.Context, input *ec2.ModifyInstanceAttributeInput, _ ...request.Option) (*ec2.ModifyInstanceAttributeOutput, error) {
+	return e.ModifyInstanceAttributeBehavior.Invoke(input, func(input *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+		if _, ok := e.Instances.Load(aws.StringValue(input.InstanceId)); !ok {
+			return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("instance %s not found", aws.StringValue(input.InstanceId)), nil)
+		}
+		return &ec2.ModifyInstanceAttributeOutput{}, nil
+	})
+}
+
+func (e *EC2API) RebootInstancesWithContext(_ context.Context, input *ec2.RebootInstancesInput, _ ...request.Option) (*ec2.RebootInstancesOutput, error) {
+	return e.RebootInstancesBehavior.Invoke(input, func(input *ec2.RebootInstancesInput) (*ec2.RebootInstancesOutput, error) {
+		for _, id := range input.InstanceIds {
+			if _, ok := e.Instances.Load(aws.StringValue(id)); !ok {
+				return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("instance %s not found", aws.StringValue(id)), nil)
+			}
+		}
+		return &ec2.RebootInstancesOutput{}, nil
+	})
+}
+
+func (e *EC2API) StartInstancesWithContext(_ context.Context, input *ec2.StartInstancesInput, _ ...request.Option) (*ec2.StartInstancesOutput, error) {
+	return e.StartInstancesBehavior.Invoke(input, func(input *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error) {
+		return &ec2.StartInstancesOutput{StartingInstances: e.setInstanceStates(input.InstanceIds, ec2.InstanceStateNamePending)}, nil
+	})
+}
+
+func (e *EC2API) StopInstancesWithContext(_ context.Context, input *ec2.StopInstancesInput, _ ...request.Option) (*ec2.StopInstancesOutput, error) {
+	return e.StopInstancesBehavior.Invoke(input, func(input *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+		return &ec2.StopInstancesOutput{StoppingInstances: e.setInstanceStates(input.InstanceIds, ec2.InstanceStateNameStopped)}, nil
+	})
+}
+
+func (e *EC2API) setInstanceStates(ids []*string, state string) []*ec2.InstanceStateChange {
+	var instanceStateChanges []*ec2.InstanceStateChange
+	for _, id := range ids {
+		raw, ok := e.Instances.Load(aws.StringValue(id))
+		if !ok {
+			continue
+		}
+		instance := raw.(*ec2.Instance)
+		instanceStateChanges = append(instanceStateChanges, &ec2.InstanceStateChange{
+			PreviousState: instance.State,
+			CurrentState:  &ec2.InstanceState{Name: aws.String(state)},
+			InstanceId:    id,
+		})
+		instance.State = &ec2.InstanceState{Name: aws.String(state)}
+	}
+	return instanceStateChanges
+}
+
+func (e *EC2API) CreateInstanceConnectEndpointWithContext(_ context.Context, input *ec2.CreateInstanceConnectEndpointInput, _ ...request.Option) (*ec2.CreateInstanceConnectEndpointOutput, error) {
+	return e.CreateInstanceConnectEndpointBehavior.Invoke(input, func(input *ec2.CreateInstanceConnectEndpointInput) (*ec2.CreateInstanceConnectEndpointOutput, error) {
+		endpoint := &ec2.Ec2InstanceConnectEndpoint{
+			InstanceConnectEndpointId: aws.String(fmt.Sprintf("eice-%s", test.RandomName())),
+			SubnetId:                  input.SubnetId,
+			SecurityGroupIds:          input.SecurityGroupIds,
+			State:                     aws.String(ec2.Ec2InstanceConnectEndpointStateCreateInProgress),
+		}
+		for _, spec := range input.TagSpecifications {
+			endpoint.Tags = append(endpoint.Tags, spec.Tags...)
+		}
+		e.InstanceConnectEndpoints.Store(aws.StringValue(endpoint.InstanceConnectEndpointId), endpoint)
+		return &ec2.CreateInstanceConnectEndpointOutput{InstanceConnectEndpoint: endpoint}, nil
+	})
+}
+
+func (e *EC2API) DeleteInstanceConnectEndpointWithContext(_ context.Context, input *ec2.DeleteInstanceConnectEndpointInput, _ ...request.Option) (*ec2.DeleteInstanceConnectEndpointOutput, error) {
+	return e.DeleteInstanceConnectEndpointBehavior.Invoke(input, func(input *ec2.DeleteInstanceConnectEndpointInput) (*ec2.DeleteInstanceConnectEndpointOutput, error) {
+		raw, ok := e.InstanceConnectEndpoints.LoadAndDelete(aws.StringValue(input.InstanceConnectEndpointId))
+		if !ok {
+			return nil, awserr.New("InvalidInstanceConnectEndpointId.NotFound", fmt.Sprintf("the instance connect endpoint '%s' does not exist", aws.StringValue(input.InstanceConnectEndpointId)), nil)
+		}
+		return &ec2.DeleteInstanceConnectEndpointOutput{InstanceConnectEndpoint: raw.(*ec2.Ec2InstanceConnectEndpoint)}, nil
+	})
+}
+
+func (e *EC2API) DescribeInstanceConnectEndpointsWithContext(_ context.Context, input *ec2.DescribeInstanceConnectEndpointsInput, _ ...request.Option) (*ec2.DescribeInstanceConnectEndpointsOutput, error) {
+	return e.DescribeInstanceConnectEndpointsBehavior.Invoke(input, func(input *ec2.DescribeInstanceConnectEndpointsInput) (*ec2.DescribeInstanceConnectEndpointsOutput, error) {
+		out := &ec2.DescribeInstanceConnectEndpointsOutput{}
+		e.InstanceConnectEndpoints.Range(func(_, v any) bool {
+			endpoint := v.(*ec2.Ec2InstanceConnectEndpoint)
+			if len(input.InstanceConnectEndpointIds) > 0 && !lo.Contains(aws.StringValueSlice(input.InstanceConnectEndpointIds), aws.StringValue(endpoint.InstanceConnectEndpointId)) {
+				return true
+			}
+			for _, filter := range input.Filters {
+				if aws.StringValue(filter.Name) == "vpc-id" && !lo.Contains(aws.StringValueSlice(filter.Values), aws.StringValue(endpoint.VpcId)) {
+					return true
+				}
+			}
+			out.InstanceConnectEndpoints = append(out.InstanceConnectEndpoints, endpoint)
+			return true
+		})
+		return out, nil
+	})
+}
+
 func (e *EC2API) CreateLaunchTemplateWithContext(_ context.Context, input *ec2.CreateLaunchTemplateInput, _ ...request.Option) (*ec2.CreateLaunchTemplateOutput, error) {
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
@@ -247,6 +387,21 @@ func (e *EC2API) CreateTagsWithContext(_ context.Context, input *ec2.CreateTagsI
 	})
 }
 
+func (e *EC2API) DeleteTagsWithContext(_ context.Context, input *ec2.DeleteTagsInput, _ ...request.Option) (*ec2.DeleteTagsOutput, error) {
+	return e.DeleteTagsBehavior.Invoke(input, func(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+		for _, id := range input.Resources {
+			raw, ok := e.Instances.Load(aws.StringValue(id))
+			if !ok {
+				return nil, fmt.Errorf("instance with id '%s' does not exist", aws.StringValue(id))
+			}
+			instance := raw.(*ec2.Instance)
+			keys := sets.New(lo.Map(input.Tags, func(t *ec2.Tag, _ int) string { return aws.StringValue(t.Key) })...)
+			instance.Tags = lo.Reject(instance.Tags, func(t *ec2.Tag, _ int) bool { return keys.Has(aws.StringValue(t.Key)) })
+		}
+		return &ec2.DeleteTagsOutput{}, nil
+	})
+}
+
 func (e *EC2API) DescribeInstancesWithContext(_ context.Context, input *ec2.DescribeInstancesInput, _ ...request.Option) (*ec2.DescribeInstancesOutput, error) {
 	return e.DescribeInstancesBehavior.Invoke(input, func(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
 		var instances []*ec2.Instance
@@ -400,11 +555,19 @@ func (e *EC2API) DeleteLaunchTemplateWithContext(_ context.Context, input *ec2.D
 		defer e.NextError.Reset()
 		return nil, e.NextError.Get()
 	}
-	e.LaunchTemplates.Delete(input.LaunchTemplateName)
+	e.LaunchTemplates.Range(func(key, value interface{}) bool {
+		launchTemplate := value.(*ec2.LaunchTemplate)
+		if (input.LaunchTemplateName != nil && aws.StringValue(launchTemplate.LaunchTemplateName) == aws.StringValue(input.LaunchTemplateName)) ||
+			(input.LaunchTemplateId != nil && aws.StringValue(launchTemplate.LaunchTemplateId) == aws.StringValue(input.LaunchTemplateId)) {
+			e.LaunchTemplates.Delete(key)
+		}
+		return true
+	})
 	return nil, nil
 }
 
 func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.DescribeSubnetsInput, _ ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	e.CalledWithDescribeSubnetsInput.Add(input)
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
 		return nil, e.NextError.Get()
@@ -466,6 +629,7 @@ func (e *EC2API) DescribeSubnetsWithContext(_ context.Context, input *ec2.Descri
 }
 
 func (e *EC2API) DescribeSecurityGroupsWithContext(_ context.Context, input *ec2.DescribeSecurityGroupsInput, _ ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	e.CalledWithDescribeSecurityGroupsInput.Add(input)
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
 		return nil, e.NextError.Get()
@@ -694,5 +858,5 @@ func (e *EC2API) DescribeSpotPriceHistoryPagesWithContext(ctx aws.Context, input
 		return err
 	}
 	fn(out, false)
-	return nil
+	return e.DescribeSpotPriceHistoryPageError.Get()
 }
@@ -0,0 +1,163 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+)
+
+// EC2V2API serves the calls of the aws-sdk-go-v2 EC2 client from an EC2API, so that the providers that were migrated
+// to aws-sdk-go-v2 and the ones that weren't share the instances, launch templates and outputs of a test. The types of
+// both SDKs are generated from the same model, so their fields are copied by name.
+type EC2V2API struct {
+	*EC2API
+}
+
+func NewEC2V2API(ec2api *EC2API) *EC2V2API {
+	return &EC2V2API{EC2API: ec2api}
+}
+
+// spotPriceHistoryPageErrorToken is returned as the NextToken of the spot price history when a later page fails, so
+// that the paginator requests the page that fails
+const spotPriceHistoryPageErrorToken = "page-error"
+
+func (a *EC2V2API) CreateFleet(ctx context.Context, input *ec2v2.CreateFleetInput, _ ...func(*ec2v2.Options)) (*ec2v2.CreateFleetOutput, error) {
+	return call[ec2v2.CreateFleetOutput](a.EC2API.CreateFleetWithContext(ctx, convert[ec2.CreateFleetInput](input)))
+}
+
+func (a *EC2V2API) TerminateInstances(ctx context.Context, input *ec2v2.TerminateInstancesInput, _ ...func(*ec2v2.Options)) (*ec2v2.TerminateInstancesOutput, error) {
+	return call[ec2v2.TerminateInstancesOutput](a.EC2API.TerminateInstancesWithContext(ctx, convert[ec2.TerminateInstancesInput](input)))
+}
+
+func (a *EC2V2API) DescribeInstances(ctx context.Context, input *ec2v2.DescribeInstancesInput, _ ...func(*ec2v2.Options)) (*ec2v2.DescribeInstancesOutput, error) {
+	return call[ec2v2.DescribeInstancesOutput](a.EC2API.DescribeInstancesWithContext(ctx, convert[ec2.DescribeInstancesInput](input)))
+}
+
+func (a *EC2V2API) CreateTags(ctx context.Context, input *ec2v2.CreateTagsInput, _ ...func(*ec2v2.Options)) (*ec2v2.CreateTagsOutput, error) {
+	return call[ec2v2.CreateTagsOutput](a.EC2API.CreateTagsWithContext(ctx, convert[ec2.CreateTagsInput](input)))
+}
+
+func (a *EC2V2API) DeleteTags(ctx context.Context, input *ec2v2.DeleteTagsInput, _ ...func(*ec2v2.Options)) (*ec2v2.DeleteTagsOutput, error) {
+	return call[ec2v2.DeleteTagsOutput](a.EC2API.DeleteTagsWithContext(ctx, convert[ec2.DeleteTagsInput](input)))
+}
+
+func (a *EC2V2API) GetConsoleOutput(ctx context.Context, input *ec2v2.GetConsoleOutputInput, _ ...func(*ec2v2.Options)) (*ec2v2.GetConsoleOutputOutput, error) {
+	return call[ec2v2.GetConsoleOutputOutput](a.EC2API.GetConsoleOutputWithContext(ctx, convert[ec2.GetConsoleOutputInput](input)))
+}
+
+func (a *EC2V2API) ModifyInstanceAttribute(ctx context.Context, input *ec2v2.ModifyInstanceAttributeInput, _ ...func(*ec2v2.Options)) (*ec2v2.ModifyInstanceAttributeOutput, error) {
+	return call[ec2v2.ModifyInstanceAttributeOutput](a.EC2API.ModifyInstanceAttributeWithContext(ctx, convert[ec2.ModifyInstanceAttributeInput](input)))
+}
+
+func (a *EC2V2API) StartInstances(ctx context.Context, input *ec2v2.StartInstancesInput, _ ...func(*ec2v2.Options)) (*ec2v2.StartInstancesOutput, error) {
+	return call[ec2v2.StartInstancesOutput](a.EC2API.StartInstancesWithContext(ctx, convert[ec2.StartInstancesInput](input)))
+}
+
+func (a *EC2V2API) StopInstances(ctx context.Context, input *ec2v2.StopInstancesInput, _ ...func(*ec2v2.Options)) (*ec2v2.StopInstancesOutput, error) {
+	return call[ec2v2.StopInstancesOutput](a.EC2API.StopInstancesWithContext(ctx, convert[ec2.StopInstancesInput](input)))
+}
+
+func (a *EC2V2API) CreateLaunchTemplate(ctx context.Context, input *ec2v2.CreateLaunchTemplateInput, _ ...func(*ec2v2.Options)) (*ec2v2.CreateLaunchTemplateOutput, error) {
+	return call[ec2v2.CreateLaunchTemplateOutput](a.EC2API.CreateLaunchTemplateWithContext(ctx, convert[ec2.CreateLaunchTemplateInput](input)))
+}
+
+func (a *EC2V2API) DescribeLaunchTemplates(ctx context.Context, input *ec2v2.DescribeLaunchTemplatesInput, _ ...func(*ec2v2.Options)) (*ec2v2.DescribeLaunchTemplatesOutput, error) {
+	return call[ec2v2.DescribeLaunchTemplatesOutput](a.EC2API.DescribeLaunchTemplatesWithContext(ctx, convert[ec2.DescribeLaunchTemplatesInput](input)))
+}
+
+func (a *EC2V2API) DeleteLaunchTemplate(ctx context.Context, input *ec2v2.DeleteLaunchTemplateInput, _ ...func(*ec2v2.Options)) (*ec2v2.DeleteLaunchTemplateOutput, error) {
+	output, err := call[ec2v2.DeleteLaunchTemplateOutput](a.EC2API.DeleteLaunchTemplateWithContext(ctx, convert[ec2.DeleteLaunchTemplateInput](input)))
+	if err != nil {
+		return nil, err
+	}
+	// the fake deletes launch templates without an output, which the client always returns
+	return lo.Ternary(output != nil, output, &ec2v2.DeleteLaunchTemplateOutput{}), nil
+}
+
+func (a *EC2V2API) DescribeSubnets(ctx context.Context, input *ec2v2.DescribeSubnetsInput, _ ...func(*ec2v2.Options)) (*ec2v2.DescribeSubnetsOutput, error) {
+	return call[ec2v2.DescribeSubnetsOutput](a.EC2API.DescribeSubnetsWithContext(ctx, convert[ec2.DescribeSubnetsInput](input)))
+}
+
+func (a *EC2V2API) DescribeSecurityGroups(ctx context.Context, input *ec2v2.DescribeSecurityGroupsInput, _ ...func(*ec2v2.Options)) (*ec2v2.DescribeSecurityGroupsOutput, error) {
+	return call[ec2v2.DescribeSecurityGroupsOutput](a.EC2API.DescribeSecurityGroupsWithContext(ctx, convert[ec2.DescribeSecurityGroupsInput](input)))
+}
+
+func (a *EC2V2API) DescribeAvailabilityZones(ctx context.Context, input *ec2v2.DescribeAvailabilityZonesInput, _ ...func(*ec2v2.Options)) (*ec2v2.DescribeAvailabilityZonesOutput, error) {
+	return call[ec2v2.DescribeAvailabilityZonesOutput](a.EC2API.DescribeAvailabilityZonesWithContext(ctx, convert[ec2.DescribeAvailabilityZonesInput](input)))
+}
+
+func (a *EC2V2API) DescribeSpotPriceHistory(ctx context.Context, input *ec2v2.DescribeSpotPriceHistoryInput, _ ...func(*ec2v2.Options)) (*ec2v2.DescribeSpotPriceHistoryOutput, error) {
+	if lo.FromPtr(input.NextToken) == spotPriceHistoryPageErrorToken {
+		return nil, convertError(a.EC2API.DescribeSpotPriceHistoryPageError.Get())
+	}
+	output, err := call[ec2v2.DescribeSpotPriceHistoryOutput](a.EC2API.DescribeSpotPriceHistoryWithContext(ctx, convert[ec2.DescribeSpotPriceHistoryInput](input)))
+	if err != nil {
+		return nil, err
+	}
+	if !a.EC2API.DescribeSpotPriceHistoryPageError.IsNil() {
+		output.NextToken = lo.ToPtr(spotPriceHistoryPageErrorToken)
+	}
+	return output, nil
+}
+
+// call converts the output and error of a call to the fake to the output and error of the aws-sdk-go-v2 client
+func call[T any, S any](output *S, err error) (*T, error) {
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return convert[T](output), nil
+}
+
+// convertError returns the error of a call to the fake as the aws-sdk-go-v2 client returns it, so that the errors that
+// a test sets are matched by their code
+func convertError(err error) error {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return err
+	}
+	return &smithy.GenericAPIError{Code: aerr.Code(), Message: aerr.Message()}
+}
+
+// convert copies the fields of a type of one SDK to the type of the other. Enums are pointers in aws-sdk-go and values
+// in aws-sdk-go-v2, so empty strings are dropped to leave the enums that aren't set nil.
+func convert[T any, S any](in *S) *T {
+	if in == nil {
+		return nil
+	}
+	var fields any
+	lo.Must0(json.Unmarshal(lo.Must(json.Marshal(in)), &fields))
+	out := new(T)
+	lo.Must0(json.Unmarshal(lo.Must(json.Marshal(dropEmptyStrings(fields))), out))
+	return out
+}
+
+func dropEmptyStrings(fields any) any {
+	switch v := fields.(type) {
+	case map[string]any:
+		return lo.MapValues(lo.OmitBy(v, func(_ string, value any) bool { return value == "" }), func(value any, _ string) any { return dropEmptyStrings(value) })
+	case []any:
+		return lo.Map(v, func(value any, _ int) any { return dropEmptyStrings(value) })
+	default:
+		return v
+	}
+}
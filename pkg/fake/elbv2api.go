@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/samber/lo"
+)
+
+// ELBV2APIBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type ELBV2APIBehavior struct {
+	DescribeTargetGroupsBehavior          MockedFunction[elbv2.DescribeTargetGroupsInput, elbv2.DescribeTargetGroupsOutput]
+	DescribeTargetHealthBehavior          MockedFunction[elbv2.DescribeTargetHealthInput, elbv2.DescribeTargetHealthOutput]
+	DescribeTargetGroupAttributesBehavior MockedFunction[elbv2.DescribeTargetGroupAttributesInput, elbv2.DescribeTargetGroupAttributesOutput]
+	DeregisterTargetsBehavior             MockedFunction[elbv2.DeregisterTargetsInput, elbv2.DeregisterTargetsOutput]
+}
+
+type ELBV2API struct {
+	sync.Mutex
+
+	elbv2iface.ELBV2API
+	ELBV2APIBehavior
+
+	TargetGroups []*elbv2.TargetGroup
+	// Targets maps a target group ARN to the IDs of the instances that are registered with it
+	Targets map[string][]string
+	// DeregistrationDelays maps a target group ARN to its deregistration_delay.timeout_seconds attribute
+	DeregistrationDelays map[string]string
+}
+
+func NewELBV2API() *ELBV2API {
+	return &ELBV2API{Targets: map[string][]string{}, DeregistrationDelays: map[string]string{}}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (e *ELBV2API) Reset() {
+	e.DescribeTargetGroupsBehavior.Reset()
+	e.DescribeTargetHealthBehavior.Reset()
+	e.DescribeTargetGroupAttributesBehavior.Reset()
+	e.DeregisterTargetsBehavior.Reset()
+	e.TargetGroups = nil
+	e.Targets = map[string][]string{}
+	e.DeregistrationDelays = map[string]string{}
+}
+
+func (e *ELBV2API) DescribeTargetGroupsPagesWithContext(_ context.Context, input *elbv2.DescribeTargetGroupsInput, fn func(*elbv2.DescribeTargetGroupsOutput, bool) bool, _ ...request.Option) error {
+	out, err := e.DescribeTargetGroupsBehavior.Invoke(input, func(*elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+		e.Lock()
+		defer e.Unlock()
+		return &elbv2.DescribeTargetGroupsOutput{TargetGroups: e.TargetGroups}, nil
+	})
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (e *ELBV2API) DescribeTargetHealthWithContext(_ context.Context, input *elbv2.DescribeTargetHealthInput, _ ...request.Option) (*elbv2.DescribeTargetHealthOutput, error) {
+	return e.DescribeTargetHealthBehavior.Invoke(input, func(*elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+		e.Lock()
+		defer e.Unlock()
+		registered := e.Targets[aws.StringValue(input.TargetGroupArn)]
+		return &elbv2.DescribeTargetHealthOutput{
+			TargetHealthDescriptions: lo.Map(input.Targets, func(t *elbv2.TargetDescription, _ int) *elbv2.TargetHealthDescription {
+				state := lo.Ternary(lo.Contains(registered, aws.StringValue(t.Id)), elbv2.TargetHealthStateEnumHealthy, elbv2.TargetHealthStateEnumUnused)
+				return &elbv2.TargetHealthDescription{
+					Target:       &elbv2.TargetDescription{Id: t.Id, Port: aws.Int64(80)},
+					TargetHealth: &elbv2.TargetHealth{State: aws.String(state)},
+				}
+			}),
+		}, nil
+	})
+}
+
+func (e *ELBV2API) DescribeTargetGroupAttributesWithContext(_ context.Context, input *elbv2.DescribeTargetGroupAttributesInput, _ ...request.Option) (*elbv2.DescribeTargetGroupAttributesOutput, error) {
+	return e.DescribeTargetGroupAttributesBehavior.Invoke(input, func(*elbv2.DescribeTargetGroupAttributesInput) (*elbv2.DescribeTargetGroupAttributesOutput, error) {
+		e.Lock()
+		defer e.Unlock()
+		out := &elbv2.DescribeTargetGroupAttributesOutput{}
+		if delay, ok := e.DeregistrationDelays[aws.StringValue(input.TargetGroupArn)]; ok {
+			out.Attributes = []*elbv2.TargetGroupAttribute{{Key: aws.String("deregistration_delay.timeout_seconds"), Value: aws.String(delay)}}
+		}
+		return out, nil
+	})
+}
+
+func (e *ELBV2API) DeregisterTargetsWithContext(_ context.Context, input *elbv2.DeregisterTargetsInput, _ ...request.Option) (*elbv2.DeregisterTargetsOutput, error) {
+	return e.DeregisterTargetsBehavior.Invoke(input, func(*elbv2.DeregisterTargetsInput) (*elbv2.DeregisterTargetsOutput, error) {
+		e.Lock()
+		defer e.Unlock()
+		arn := aws.StringValue(input.TargetGroupArn)
+		e.Targets[arn] = lo.Without(e.Targets[arn], lo.Map(input.Targets, func(t *elbv2.TargetDescription, _ int) string { return aws.StringValue(t.Id) })...)
+		return &elbv2.DeregisterTargetsOutput{}, nil
+	})
+}
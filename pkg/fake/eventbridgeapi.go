@@ -0,0 +1,205 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// EventBridgeRule is a rule and the ARNs of its targets
+type EventBridgeRule struct {
+	Name         string
+	EventPattern string
+	State        string
+	Targets      []string
+}
+
+// EventBridgeBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type EventBridgeBehavior struct {
+	ListRuleNamesByTargetBehavior MockedFunction[eventbridge.ListRuleNamesByTargetInput, eventbridge.ListRuleNamesByTargetOutput]
+	DescribeRuleBehavior          MockedFunction[eventbridge.DescribeRuleInput, eventbridge.DescribeRuleOutput]
+	PutEventsBehavior             MockedFunction[eventbridge.PutEventsInput, eventbridge.PutEventsOutput]
+	Rules                         AtomicPtrSlice[EventBridgeRule]
+}
+
+// EventBridgeAPI is a fake of the default event bus. Unless an output is set, events that are put on the bus are
+// matched against the event patterns of the enabled rules and delivered to the queues that the rules target.
+type EventBridgeAPI struct {
+	eventbridgeiface.EventBridgeAPI
+	EventBridgeBehavior
+	// Queues are the fake queues that events are delivered to, keyed by queue ARN
+	Queues map[string]*SQSAPI
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (e *EventBridgeAPI) Reset() {
+	e.ListRuleNamesByTargetBehavior.Reset()
+	e.DescribeRuleBehavior.Reset()
+	e.PutEventsBehavior.Reset()
+	e.Rules.Reset()
+}
+
+func (e *EventBridgeAPI) ListRuleNamesByTargetWithContext(_ context.Context, input *eventbridge.ListRuleNamesByTargetInput, _ ...request.Option) (*eventbridge.ListRuleNamesByTargetOutput, error) {
+	return e.ListRuleNamesByTargetBehavior.Invoke(input, func(input *eventbridge.ListRuleNamesByTargetInput) (*eventbridge.ListRuleNamesByTargetOutput, error) {
+		out := &eventbridge.ListRuleNamesByTargetOutput{}
+		e.Rules.ForEach(func(rule *EventBridgeRule) {
+			if lo.Contains(rule.Targets, aws.StringValue(input.TargetArn)) {
+				out.RuleNames = append(out.RuleNames, aws.String(rule.Name))
+			}
+		})
+		return out, nil
+	})
+}
+
+func (e *EventBridgeAPI) DescribeRuleWithContext(_ context.Context, input *eventbridge.DescribeRuleInput, _ ...request.Option) (*eventbridge.DescribeRuleOutput, error) {
+	return e.DescribeRuleBehavior.Invoke(input, func(input *eventbridge.DescribeRuleInput) (*eventbridge.DescribeRuleOutput, error) {
+		var out *eventbridge.DescribeRuleOutput
+		e.Rules.ForEach(func(rule *EventBridgeRule) {
+			if rule.Name == aws.StringValue(input.Name) {
+				out = &eventbridge.DescribeRuleOutput{Name: aws.String(rule.Name), EventPattern: aws.String(rule.EventPattern), State: aws.String(rule.State)}
+			}
+		})
+		if out == nil {
+			return nil, fmt.Errorf("rule %s not found", aws.StringValue(input.Name))
+		}
+		return out, nil
+	})
+}
+
+func (e *EventBridgeAPI) PutEventsWithContext(ctx context.Context, input *eventbridge.PutEventsInput, _ ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	return e.PutEventsBehavior.Invoke(input, func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+		out := &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}
+		for _, entry := range input.Entries {
+			event, err := newEvent(entry)
+			if err != nil {
+				return nil, err
+			}
+			raw, err := json.Marshal(event)
+			if err != nil {
+				return nil, err
+			}
+			var targets []string
+			e.Rules.ForEach(func(rule *EventBridgeRule) {
+				if rule.State != eventbridge.RuleStateEnabled {
+					return
+				}
+				pattern := map[string]interface{}{}
+				if json.Unmarshal([]byte(rule.EventPattern), &pattern) != nil {
+					return
+				}
+				if matchesPattern(pattern, event) {
+					targets = append(targets, rule.Targets...)
+				}
+			})
+			for _, target := range lo.Uniq(targets) {
+				if queue, ok := e.Queues[target]; ok {
+					if _, err = queue.SendMessageWithContext(ctx, &sqs.SendMessageInput{MessageBody: aws.String(string(raw))}); err != nil {
+						return nil, err
+					}
+				}
+			}
+			out.Entries = append(out.Entries, &eventbridge.PutEventsResultEntry{EventId: aws.String(event["id"].(string))})
+		}
+		return out, nil
+	})
+}
+
+// newEvent returns the event that EventBridge delivers to targets for the entry
+func newEvent(entry *eventbridge.PutEventsRequestEntry) (map[string]interface{}, error) {
+	detail := map[string]interface{}{}
+	if entry.Detail != nil {
+		if err := json.Unmarshal([]byte(aws.StringValue(entry.Detail)), &detail); err != nil {
+			return nil, fmt.Errorf("parsing event detail, %w", err)
+		}
+	}
+	return map[string]interface{}{
+		"version":     "0",
+		"id":          string(uuid.NewUUID()),
+		"detail-type": aws.StringValue(entry.DetailType),
+		"source":      aws.StringValue(entry.Source),
+		"account":     DefaultAccount,
+		"time":        lo.Ternary(entry.Time != nil, aws.TimeValue(entry.Time), time.Now()).UTC().Format(time.RFC3339),
+		"region":      DefaultRegion,
+		"resources":   lo.Map(entry.Resources, func(r *string, _ int) interface{} { return aws.StringValue(r) }),
+		"detail":      detail,
+	}, nil
+}
+
+// matchesPattern returns true if the event matches the EventBridge event pattern. Fields of the pattern are either
+// nested patterns or lists of values, which match if any of them matches the field, or any element of the field if it's
+// a list. Besides literal values, the prefix, exists, and anything-but content filters are supported.
+func matchesPattern(pattern map[string]interface{}, event map[string]interface{}) bool {
+	for key, p := range pattern {
+		value, exists := event[key]
+		switch typed := p.(type) {
+		case map[string]interface{}:
+			nested, ok := value.(map[string]interface{})
+			if !ok || !matchesPattern(typed, nested) {
+				return false
+			}
+		case []interface{}:
+			values, ok := value.([]interface{})
+			if !ok {
+				values = []interface{}{value}
+			}
+			if !lo.SomeBy(typed, func(filter interface{}) bool {
+				if f, ok := filter.(map[string]interface{}); ok {
+					if e, ok := f["exists"].(bool); ok {
+						return e == exists
+					}
+				}
+				return exists && lo.SomeBy(values, func(v interface{}) bool { return matchesFilter(filter, v) })
+			}) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func matchesFilter(filter interface{}, value interface{}) bool {
+	f, ok := filter.(map[string]interface{})
+	if !ok {
+		return filter == value
+	}
+	if prefix, ok := f["prefix"].(string); ok {
+		s, ok := value.(string)
+		return ok && strings.HasPrefix(s, prefix)
+	}
+	if anythingBut, ok := f["anything-but"]; ok {
+		excluded, ok := anythingBut.([]interface{})
+		if !ok {
+			excluded = []interface{}{anythingBut}
+		}
+		return !lo.Contains(excluded, value)
+	}
+	return false
+}
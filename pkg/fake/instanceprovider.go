@@ -0,0 +1,260 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+)
+
+var _ instance.Provider = (*InstanceProvider)(nil)
+
+// InstanceProvider is a fake instance.Provider that keeps the instances that it launches in memory. Instances are
+// launched as the cheapest available offering that's compatible with the NodeClaim.
+type InstanceProvider struct {
+	mu                    sync.RWMutex
+	instances             map[string]*instance.Instance
+	terminationProtection map[string]bool
+	ConsoleOutput         string
+	NextError             AtomicError
+}
+
+func NewInstanceProvider() *InstanceProvider {
+	return &InstanceProvider{
+		instances:             map[string]*instance.Instance{},
+		terminationProtection: map[string]bool{},
+	}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *InstanceProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instances = map[string]*instance.Instance{}
+	p.terminationProtection = map[string]bool{}
+	p.ConsoleOutput = ""
+	p.NextError.Reset()
+}
+
+// Add adds an instance, as if it had been launched outside of the provider
+func (p *InstanceProvider) Add(i *instance.Instance) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instances[i.ID] = i
+}
+
+// TerminationProtection returns true if the termination protection of the instance is enabled
+func (p *InstanceProvider) TerminationProtection(id string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.terminationProtection[id]
+}
+
+func (p *InstanceProvider) Create(_ context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) (*instance.Instance, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	return p.launch(nodeClass, nodeClaim, instanceTypes, map[string]string{corev1beta1.NodePoolLabelKey: nodeClaim.Labels[corev1beta1.NodePoolLabelKey]})
+}
+
+func (p *InstanceProvider) LaunchWarm(_ context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) (*instance.Instance, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	return p.launch(nodeClass, nodeClaim, instanceTypes, map[string]string{
+		corev1beta1.NodePoolLabelKey: nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+		v1beta1.TagWarmPool:          nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+	})
+}
+
+func (p *InstanceProvider) launch(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, tags map[string]string) (*instance.Instance, error) {
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	var instanceType *cloudprovider.InstanceType
+	var offering *cloudprovider.Offering
+	for _, it := range instanceTypes {
+		for _, o := range it.Offerings.Compatible(reqs).Available() {
+			if offering == nil || o.Price < offering.Price {
+				instanceType, offering = it, lo.ToPtr(o)
+			}
+		}
+	}
+	if offering == nil {
+		return nil, cloudprovider.NewInsufficientCapacityError(nil)
+	}
+	i := &instance.Instance{
+		LaunchTime:   time.Now(),
+		State:        "running",
+		ID:           InstanceID(),
+		Type:         instanceType.Name,
+		Zone:         offering.Requirements.Get(v1.LabelTopologyZone).Any(),
+		CapacityType: offering.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any(),
+		SecurityGroupIDs: lo.Map(nodeClass.Status.SecurityGroups, func(sg v1beta1.SecurityGroup, _ int) string {
+			return sg.ID
+		}),
+		Tags: tags,
+	}
+	if len(nodeClass.Status.AMIs) > 0 {
+		i.ImageID = nodeClass.Status.AMIs[0].ID
+	}
+	if subnet, ok := lo.Find(nodeClass.Status.Subnets, func(s v1beta1.Subnet) bool { return s.Zone == i.Zone }); ok {
+		i.SubnetID = subnet.ID
+	}
+	p.Add(i)
+	return i, nil
+}
+
+func (p *InstanceProvider) Get(_ context.Context, id string) (*instance.Instance, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	i, ok := p.instances[id]
+	if !ok {
+		return nil, cloudprovider.NewNodeClaimNotFoundError(nil)
+	}
+	if _, ok = i.Tags[v1beta1.TagHibernated]; ok {
+		return nil, cloudprovider.NewNodeClaimNotFoundError(nil)
+	}
+	return i, nil
+}
+
+func (p *InstanceProvider) List(_ context.Context) ([]*instance.Instance, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.Reject(lo.Values(p.instances), func(i *instance.Instance, _ int) bool {
+		_, warm := i.Tags[v1beta1.TagWarmPool]
+		_, hibernated := i.Tags[v1beta1.TagHibernated]
+		return warm || hibernated
+	}), nil
+}
+
+func (p *InstanceProvider) ListWarm(_ context.Context) ([]*instance.Instance, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.Filter(lo.Values(p.instances), func(i *instance.Instance, _ int) bool {
+		_, ok := i.Tags[v1beta1.TagWarmPool]
+		return ok
+	}), nil
+}
+
+func (p *InstanceProvider) Delete(_ context.Context, id string) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.instances[id]; !ok {
+		return cloudprovider.NewNodeClaimNotFoundError(nil)
+	}
+	delete(p.instances, id)
+	delete(p.terminationProtection, id)
+	return nil
+}
+
+func (p *InstanceProvider) CreateTags(_ context.Context, id string, tags map[string]string) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, ok := p.instances[id]
+	if !ok {
+		return cloudprovider.NewNodeClaimNotFoundError(nil)
+	}
+	i.Tags = lo.Assign(i.Tags, tags)
+	return nil
+}
+
+func (p *InstanceProvider) GetConsoleOutput(_ context.Context, _ string) (string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return "", err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ConsoleOutput, nil
+}
+
+func (p *InstanceProvider) SetTerminationProtection(_ context.Context, id string, enabled bool) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.terminationProtection[id] = enabled
+	return nil
+}
+
+func (p *InstanceProvider) Stop(_ context.Context, id string) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, ok := p.instances[id]
+	if !ok {
+		return cloudprovider.NewNodeClaimNotFoundError(nil)
+	}
+	i.State = "stopped"
+	return nil
+}
+
+func (p *InstanceProvider) Hibernate(_ context.Context, id string, nodePool string) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i, ok := p.instances[id]
+	if !ok {
+		return cloudprovider.NewNodeClaimNotFoundError(nil)
+	}
+	i.Tags = lo.Assign(i.Tags, map[string]string{
+		v1beta1.TagHibernated:      nodePool,
+		v1beta1.TagHibernationTime: time.Now().Format(time.RFC3339),
+	})
+	i.State = "stopped"
+	return nil
+}
+
+func (p *InstanceProvider) ListHibernated(_ context.Context) ([]*instance.Instance, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.Filter(lo.Values(p.instances), func(i *instance.Instance, _ int) bool {
+		_, ok := i.Tags[v1beta1.TagHibernated]
+		return ok
+	}), nil
+}
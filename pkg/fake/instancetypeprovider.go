@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/samber/lo"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+)
+
+var _ instancetype.Provider = (*InstanceTypeProvider)(nil)
+
+// InstanceTypeProvider is a fake instancetype.Provider that returns the instance types that it's set up with for every
+// EC2NodeClass and kubelet configuration
+type InstanceTypeProvider struct {
+	mu            sync.RWMutex
+	instanceTypes []*cloudprovider.InstanceType
+	NextError     AtomicError
+}
+
+func NewInstanceTypeProvider() *InstanceTypeProvider {
+	return &InstanceTypeProvider{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *InstanceTypeProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instanceTypes = nil
+	p.NextError.Reset()
+}
+
+// SetInstanceTypes sets the instance types that are returned
+func (p *InstanceTypeProvider) SetInstanceTypes(instanceTypes []*cloudprovider.InstanceType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instanceTypes = instanceTypes
+}
+
+func (p *InstanceTypeProvider) LivenessProbe(_ *http.Request) error {
+	return nil
+}
+
+func (p *InstanceTypeProvider) List(_ context.Context, _ *corev1beta1.KubeletConfiguration, _ *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]*cloudprovider.InstanceType{}, p.instanceTypes...), nil
+}
+
+func (p *InstanceTypeProvider) ListCompatible(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
+	instanceTypes, err := p.List(ctx, nodeClaim.Spec.Kubelet, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	return lo.Filter(instanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
+		return reqs.Compatible(i.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil &&
+			len(i.Offerings.Compatible(reqs).Available()) > 0 &&
+			resources.Fits(nodeClaim.Spec.Resources.Requests, i.Allocatable())
+	}), nil
+}
+
+func (p *InstanceTypeProvider) UpdateInstanceTypes(_ context.Context) error {
+	return p.NextError.Get()
+}
+
+func (p *InstanceTypeProvider) UpdateInstanceTypeOfferings(_ context.Context) error {
+	return p.NextError.Get()
+}
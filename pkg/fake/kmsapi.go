@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// KMSBehavior must be reset between tests otherwise tests will
+// pollute each other.
+type KMSBehavior struct {
+	DescribeKeyBehavior MockedFunction[kms.DescribeKeyInput, kms.DescribeKeyOutput]
+	CreateGrantBehavior MockedFunction[kms.CreateGrantInput, kms.CreateGrantOutput]
+}
+
+// KMSAPI describes every key as an enabled symmetric encryption key, and allows grants for every key, unless an output
+// or error is set
+type KMSAPI struct {
+	kmsiface.KMSAPI
+	KMSBehavior
+}
+
+func NewKMSAPI() *KMSAPI {
+	return &KMSAPI{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (k *KMSAPI) Reset() {
+	k.DescribeKeyBehavior.Reset()
+	k.CreateGrantBehavior.Reset()
+}
+
+func (k *KMSAPI) DescribeKeyWithContext(_ context.Context, input *kms.DescribeKeyInput, _ ...request.Option) (*kms.DescribeKeyOutput, error) {
+	return k.DescribeKeyBehavior.Invoke(input, func(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+		return &kms.DescribeKeyOutput{KeyMetadata: &kms.KeyMetadata{
+			KeyId:    input.KeyId,
+			KeyState: aws.String(kms.KeyStateEnabled),
+			KeySpec:  aws.String(kms.KeySpecSymmetricDefault),
+			KeyUsage: aws.String(kms.KeyUsageTypeEncryptDecrypt),
+		}}, nil
+	})
+}
+
+func (k *KMSAPI) CreateGrantWithContext(_ context.Context, input *kms.CreateGrantInput, _ ...request.Option) (*kms.CreateGrantOutput, error) {
+	return k.CreateGrantBehavior.Invoke(input, func(input *kms.CreateGrantInput) (*kms.CreateGrantOutput, error) {
+		if aws.BoolValue(input.DryRun) {
+			return nil, awserr.New("DryRunOperationException", "The request would have succeeded, but the DryRun option is set.", nil)
+		}
+		return &kms.CreateGrantOutput{GrantId: aws.String("test-grant-id")}, nil
+	})
+}
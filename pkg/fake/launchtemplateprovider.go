@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+)
+
+var _ launchtemplate.Provider = (*LaunchTemplateProvider)(nil)
+
+// LaunchTemplateProvider is a fake launchtemplate.Provider that keeps a launch template per EC2NodeClass in memory,
+// which all of the instance types are launched from
+type LaunchTemplateProvider struct {
+	mu              sync.RWMutex
+	launchTemplates map[string]*launchtemplate.LaunchTemplate
+	NextError       AtomicError
+}
+
+func NewLaunchTemplateProvider() *LaunchTemplateProvider {
+	return &LaunchTemplateProvider{
+		launchTemplates: map[string]*launchtemplate.LaunchTemplate{},
+	}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *LaunchTemplateProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.launchTemplates = map[string]*launchtemplate.LaunchTemplate{}
+	p.NextError.Reset()
+}
+
+// LaunchTemplates returns the launch templates that exist, keyed by the name of their EC2NodeClass
+func (p *LaunchTemplateProvider) LaunchTemplates() map[string]*launchtemplate.LaunchTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.Assign(p.launchTemplates)
+}
+
+func (p *LaunchTemplateProvider) EnsureAll(_ context.Context, nodeClass *v1beta1.EC2NodeClass, _ *corev1beta1.NodeClaim,
+	instanceTypes []*cloudprovider.InstanceType, _ string, _ map[string]string) ([]*launchtemplate.LaunchTemplate, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	if len(nodeClass.Status.AMIs) == 0 {
+		return nil, fmt.Errorf("no amis exist given constraints")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lt := &launchtemplate.LaunchTemplate{
+		Name:          fmt.Sprintf("karpenter.k8s.aws/%s", nodeClass.Name),
+		InstanceTypes: instanceTypes,
+		ImageID:       nodeClass.Status.AMIs[0].ID,
+	}
+	p.launchTemplates[nodeClass.Name] = lt
+	return []*launchtemplate.LaunchTemplate{lt}, nil
+}
+
+func (p *LaunchTemplateProvider) DeleteAll(_ context.Context, nodeClass *v1beta1.EC2NodeClass) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.launchTemplates, nodeClass.Name)
+	return nil
+}
+
+func (p *LaunchTemplateProvider) InvalidateCache(_ context.Context, _ string, _ string) {}
+
+func (p *LaunchTemplateProvider) SyncCache(_ context.Context) error {
+	return p.NextError.Get()
+}
+
+func (p *LaunchTemplateProvider) DeleteUnreferenced(_ context.Context, _ time.Duration) error {
+	return p.NextError.Get()
+}
+
+func (p *LaunchTemplateProvider) ResolveClusterCIDR(_ context.Context) error {
+	return p.NextError.Get()
+}
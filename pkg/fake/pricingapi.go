@@ -15,47 +15,83 @@ limitations under the License.
 package fake
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/service/pricing"
-	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/samber/lo"
 )
 
+// pageErrorToken is returned as the NextToken of the products when a later page fails, so that the paginator requests
+// the page that fails
+const pageErrorToken = "page-error"
+
 type PricingAPI struct {
-	pricingiface.PricingAPI
 	PricingBehavior
 }
 type PricingBehavior struct {
 	NextError         AtomicError
 	GetProductsOutput AtomicPtr[pricing.GetProductsOutput]
+	// LocationProducts are returned instead of GetProductsOutput for requests that filter on their region code, which
+	// is how Local Zones and Wavelength Zones are priced
+	LocationProducts sync.Map
+	// PageError is returned after the products have been paged, as if a later page had failed
+	PageError AtomicError
 }
 
 func (p *PricingAPI) Reset() {
 	p.NextError.Reset()
 	p.GetProductsOutput.Reset()
+	p.PageError.Reset()
+	p.LocationProducts.Range(func(k, _ any) bool {
+		p.LocationProducts.Delete(k)
+		return true
+	})
 }
 
-func (p *PricingAPI) GetProductsPagesWithContext(_ aws.Context, _ *pricing.GetProductsInput, fn func(*pricing.GetProductsOutput, bool) bool, _ ...request.Option) error {
+func (p *PricingAPI) GetProducts(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
 	if !p.NextError.IsNil() {
-		return p.NextError.Get()
+		return nil, p.NextError.Get()
+	}
+	if lo.FromPtr(input.NextToken) == pageErrorToken {
+		return nil, p.PageError.Get()
+	}
+	output, err := p.getProducts(input)
+	if err != nil {
+		return nil, err
+	}
+	if !p.PageError.IsNil() {
+		output.NextToken = lo.ToPtr(pageErrorToken)
+	}
+	return output, nil
+}
+
+func (p *PricingAPI) getProducts(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+	for _, filter := range input.Filters {
+		if lo.FromPtr(filter.Field) != "regionCode" {
+			continue
+		}
+		if out, ok := p.LocationProducts.Load(lo.FromPtr(filter.Value)); ok {
+			return &pricing.GetProductsOutput{PriceList: out.(*pricing.GetProductsOutput).PriceList}, nil
+		}
 	}
 	if !p.GetProductsOutput.IsNil() {
-		fn(p.GetProductsOutput.Clone(), false)
-		return nil
+		return p.GetProductsOutput.Clone(), nil
 	}
 	// fail if the test doesn't provide specific data which causes our pricing provider to use its static price list
-	return errors.New("no pricing data provided")
+	return nil, errors.New("no pricing data provided")
 }
 
-func NewOnDemandPrice(instanceType string, price float64) aws.JSONValue {
+func NewOnDemandPrice(instanceType string, price float64) string {
 	return NewOnDemandPriceWithCurrency(instanceType, price, "USD")
 }
 
-func NewOnDemandPriceWithCurrency(instanceType string, price float64, currency string) aws.JSONValue {
-	return aws.JSONValue{
+// NewOnDemandPriceWithCurrency returns a product of the price list, which the pricing API returns as a JSON document
+func NewOnDemandPriceWithCurrency(instanceType string, price float64, currency string) string {
+	return string(lo.Must(json.Marshal(map[string]interface{}{
 		"product": map[string]interface{}{
 			"attributes": map[string]interface{}{
 				"instanceType": instanceType,
@@ -73,5 +109,5 @@ func NewOnDemandPriceWithCurrency(instanceType string, price float64, currency s
 				},
 			},
 		},
-	}
+	})))
 }
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+var _ pricing.Provider = (*PricingProvider)(nil)
+
+// PricingProvider is a fake pricing.Provider that returns the prices that it's set up with
+type PricingProvider struct {
+	mu        sync.RWMutex
+	prices    pricing.Prices
+	NextError AtomicError
+}
+
+func NewPricingProvider() *PricingProvider {
+	return &PricingProvider{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *PricingProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices = pricing.Prices{}
+	p.NextError.Reset()
+}
+
+// SetPrices sets the prices that are returned
+func (p *PricingProvider) SetPrices(prices pricing.Prices) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices = prices
+}
+
+func (p *PricingProvider) LivenessProbe(_ *http.Request) error {
+	return nil
+}
+
+func (p *PricingProvider) InstanceTypes() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.Union(lo.Keys(p.prices.OnDemand), lo.Keys(p.prices.Spot))
+}
+
+func (p *PricingProvider) OnDemandPrice(instanceType string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	price, ok := p.prices.OnDemand[instanceType]
+	return price, ok
+}
+
+func (p *PricingProvider) ZonalOnDemandPrice(instanceType string, zone string) (float64, bool) {
+	p.mu.RLock()
+	price, ok := p.prices.ZonalOnDemand[zone][instanceType]
+	p.mu.RUnlock()
+	if ok {
+		return price, true
+	}
+	return p.OnDemandPrice(instanceType)
+}
+
+func (p *PricingProvider) SpotPrice(instanceType string, zone string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	price, ok := p.prices.Spot[instanceType][zone]
+	return price, ok
+}
+
+func (p *PricingProvider) UpdateOnDemandPricing(_ context.Context) error {
+	return p.NextError.Get()
+}
+
+func (p *PricingProvider) UpdateSpotPricing(_ context.Context) error {
+	return p.NextError.Get()
+}
+
+func (p *PricingProvider) Prices() pricing.Prices {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.prices
+}
@@ -0,0 +1,294 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/samber/lo"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+	sqsprovider "github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/targetgroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/terminationhook"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+)
+
+var _ instanceprofile.Provider = (*InstanceProfileProvider)(nil)
+var _ kms.Provider = (*KMSProvider)(nil)
+var _ quota.Provider = (*QuotaProvider)(nil)
+var _ sqsprovider.Provider = (*SQSProvider)(nil)
+var _ targetgroup.Provider = (*TargetGroupProvider)(nil)
+var _ terminationhook.Provider = (*TerminationHookProvider)(nil)
+var _ version.Provider = (*VersionProvider)(nil)
+
+// InstanceProfileProvider is a fake instanceprofile.Provider that keeps the instance profiles that it creates in
+// memory, keyed by the UID of their owner
+type InstanceProfileProvider struct {
+	mu               sync.RWMutex
+	instanceProfiles map[string]string
+	NextError        AtomicError
+}
+
+func NewInstanceProfileProvider() *InstanceProfileProvider {
+	return &InstanceProfileProvider{instanceProfiles: map[string]string{}}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *InstanceProfileProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instanceProfiles = map[string]string{}
+	p.NextError.Reset()
+}
+
+// InstanceProfile returns the name of the instance profile of the owner, and false if it doesn't exist
+func (p *InstanceProfileProvider) InstanceProfile(owner instanceprofile.ResourceOwner) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	name, ok := p.instanceProfiles[string(owner.GetUID())]
+	return name, ok
+}
+
+func (p *InstanceProfileProvider) Create(_ context.Context, owner instanceprofile.ResourceOwner) (string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name := owner.InstanceProfileName("test-cluster", DefaultRegion)
+	p.instanceProfiles[string(owner.GetUID())] = name
+	return name, nil
+}
+
+func (p *InstanceProfileProvider) Delete(_ context.Context, owner instanceprofile.ResourceOwner) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.instanceProfiles, string(owner.GetUID()))
+	return nil
+}
+
+// KMSProvider is a fake kms.Provider that considers every key valid
+type KMSProvider struct {
+	NextError AtomicError
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *KMSProvider) Reset() {
+	p.NextError.Reset()
+}
+
+func (p *KMSProvider) Validate(_ context.Context, _ string) error {
+	return p.NextError.Get()
+}
+
+// QuotaProvider is a fake quota.Provider that returns the vCPU usage that it's set up with
+type QuotaProvider struct {
+	mu        sync.RWMutex
+	usage     []quota.VCPUUsage
+	NextError AtomicError
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *QuotaProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.usage = nil
+	p.NextError.Reset()
+}
+
+// SetVCPUUsage sets the vCPU usage that's returned
+func (p *QuotaProvider) SetVCPUUsage(usage []quota.VCPUUsage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.usage = usage
+}
+
+func (p *QuotaProvider) VCPUUsage(_ context.Context) ([]quota.VCPUUsage, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]quota.VCPUUsage{}, p.usage...), nil
+}
+
+// SQSProvider is a fake sqs.Provider that keeps its queue in memory. Released messages are received again, and
+// deleted messages aren't.
+type SQSProvider struct {
+	mu        sync.Mutex
+	messages  []*sqs.Message
+	NextError AtomicError
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *SQSProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+	p.NextError.Reset()
+}
+
+func (p *SQSProvider) Name() string {
+	return "test-cluster"
+}
+
+func (p *SQSProvider) GetQueueARN(_ context.Context) (string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return "", err
+	}
+	return "arn:aws:sqs:" + DefaultRegion + ":" + DefaultAccount + ":" + p.Name(), nil
+}
+
+func (p *SQSProvider) GetSQSMessages(_ context.Context) ([]*sqs.Message, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	messages := p.messages
+	p.messages = nil
+	return messages, nil
+}
+
+func (p *SQSProvider) SendMessage(_ context.Context, body interface{}) (string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := test.RandomName()
+	p.messages = append(p.messages, &sqs.Message{MessageId: &id, ReceiptHandle: &id, Body: lo.ToPtr(string(raw))})
+	return id, nil
+}
+
+func (p *SQSProvider) SendSQSMessage(_ context.Context, message *sqs.Message) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := test.RandomName()
+	p.messages = append(p.messages, &sqs.Message{MessageId: &id, ReceiptHandle: &id, Body: message.Body, MessageAttributes: message.MessageAttributes})
+	return nil
+}
+
+func (p *SQSProvider) DeleteSQSMessage(_ context.Context, _ *sqs.Message) error {
+	return p.NextError.Get()
+}
+
+func (p *SQSProvider) ReleaseSQSMessage(_ context.Context, message *sqs.Message) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+// TargetGroupProvider is a fake targetgroup.Provider that deregisters instances immediately
+type TargetGroupProvider struct {
+	Deregistered AtomicPtrSlice[string]
+	NextError    AtomicError
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *TargetGroupProvider) Reset() {
+	p.Deregistered.Reset()
+	p.NextError.Reset()
+}
+
+func (p *TargetGroupProvider) Deregister(_ context.Context, instanceID string) (time.Duration, error) {
+	if err := p.NextError.Get(); err != nil {
+		return 0, err
+	}
+	p.Deregistered.Add(&instanceID)
+	return 0, nil
+}
+
+// TerminationHookProvider is a fake terminationhook.Provider that records the NodeClaims that it's notified of
+type TerminationHookProvider struct {
+	Notified  AtomicPtrSlice[corev1beta1.NodeClaim]
+	NextError AtomicError
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *TerminationHookProvider) Reset() {
+	p.Notified.Reset()
+	p.NextError.Reset()
+}
+
+func (p *TerminationHookProvider) Notify(_ context.Context, nodeClaim *corev1beta1.NodeClaim, _ string) error {
+	if err := p.NextError.Get(); err != nil {
+		return err
+	}
+	p.Notified.Add(nodeClaim)
+	return nil
+}
+
+// VersionProvider is a fake version.Provider that returns the Kubernetes version that it's set up with
+type VersionProvider struct {
+	mu        sync.RWMutex
+	version   string
+	NextError AtomicError
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *VersionProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.version = ""
+	p.NextError.Reset()
+}
+
+// SetVersion sets the Kubernetes version that's returned
+func (p *VersionProvider) SetVersion(version string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.version = version
+}
+
+func (p *VersionProvider) Get(_ context.Context) (string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return "", err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version, nil
+}
@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+)
+
+var _ securitygroup.Provider = (*SecurityGroupProvider)(nil)
+
+// SecurityGroupProvider is a fake securitygroup.Provider that returns the security groups that it's set up with for
+// every EC2NodeClass
+type SecurityGroupProvider struct {
+	mu             sync.RWMutex
+	securityGroups []ec2types.SecurityGroup
+	NextError      AtomicError
+}
+
+func NewSecurityGroupProvider() *SecurityGroupProvider {
+	return &SecurityGroupProvider{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *SecurityGroupProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.securityGroups = nil
+	p.NextError.Reset()
+}
+
+// SetSecurityGroups sets the security groups that are returned
+func (p *SecurityGroupProvider) SetSecurityGroups(securityGroups []ec2types.SecurityGroup) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.securityGroups = securityGroups
+}
+
+func (p *SecurityGroupProvider) List(_ context.Context, _ *v1beta1.EC2NodeClass) ([]ec2types.SecurityGroup, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]ec2types.SecurityGroup{}, p.securityGroups...), nil
+}
@@ -0,0 +1,66 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+)
+
+type ServiceQuotasAPI struct {
+	servicequotasiface.ServiceQuotasAPI
+	ServiceQuotasBehavior
+}
+
+type ServiceQuotasBehavior struct {
+	ListServiceQuotasOutput AtomicPtr[servicequotas.ListServiceQuotasOutput]
+	ListServiceQuotasCalls  AtomicPtrSlice[servicequotas.ListServiceQuotasInput]
+	NextError               AtomicError
+}
+
+func NewServiceQuotasAPI() *ServiceQuotasAPI {
+	return &ServiceQuotasAPI{}
+}
+
+func (s *ServiceQuotasAPI) Reset() {
+	s.ListServiceQuotasOutput.Reset()
+	s.ListServiceQuotasCalls.Reset()
+	s.NextError.Reset()
+}
+
+func (s *ServiceQuotasAPI) ListServiceQuotasPagesWithContext(_ aws.Context, input *servicequotas.ListServiceQuotasInput, fn func(*servicequotas.ListServiceQuotasOutput, bool) bool, _ ...request.Option) error {
+	s.ListServiceQuotasCalls.Add(input)
+	if !s.NextError.IsNil() {
+		defer s.NextError.Reset()
+		return s.NextError.Get()
+	}
+	if !s.ListServiceQuotasOutput.IsNil() {
+		fn(s.ListServiceQuotasOutput.Clone(), true)
+		return nil
+	}
+	fn(&servicequotas.ListServiceQuotasOutput{}, true)
+	return nil
+}
+
+// NewServiceQuota returns an EC2 service quota with the code and value
+func NewServiceQuota(code string, value float64) *servicequotas.ServiceQuota {
+	return &servicequotas.ServiceQuota{
+		ServiceCode: aws.String("ec2"),
+		QuotaCode:   aws.String(code),
+		Value:       aws.Float64(value),
+	}
+}
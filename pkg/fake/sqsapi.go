@@ -16,28 +16,62 @@ package fake
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/utils/clock"
 )
 
 const (
 	dummyQueueURL = "https://sqs.us-west-2.amazonaws.com/000000000000/Karpenter-cluster-Queue"
+	// DummyQueueARN is the ARN of the queue that the fake returns the URL of
+	DummyQueueARN = "arn:aws:sqs:us-west-2:000000000000:Karpenter-cluster-Queue"
+	// DefaultVisibilityTimeout is the visibility timeout of the queue, which applies when a receive doesn't set one
+	DefaultVisibilityTimeout = 30 * time.Second
 )
 
+// SQSMessage is a message in the queue of the fake
+type SQSMessage struct {
+	ID            string
+	Body          string
+	SentAt        time.Time
+	ReceiveCount  int
+	ReceiptHandle string
+	// VisibleAt is when the message can be received again after it was last received
+	VisibleAt time.Time
+}
+
 // SQSBehavior must be reset between tests otherwise tests will
 // pollute each other.
 type SQSBehavior struct {
-	GetQueueURLBehavior    MockedFunction[sqs.GetQueueUrlInput, sqs.GetQueueUrlOutput]
-	ReceiveMessageBehavior MockedFunction[sqs.ReceiveMessageInput, sqs.ReceiveMessageOutput]
-	DeleteMessageBehavior  MockedFunction[sqs.DeleteMessageInput, sqs.DeleteMessageOutput]
+	GetQueueURLBehavior             MockedFunction[sqs.GetQueueUrlInput, sqs.GetQueueUrlOutput]
+	ReceiveMessageBehavior          MockedFunction[sqs.ReceiveMessageInput, sqs.ReceiveMessageOutput]
+	DeleteMessageBehavior           MockedFunction[sqs.DeleteMessageInput, sqs.DeleteMessageOutput]
+	ChangeMessageVisibilityBehavior MockedFunction[sqs.ChangeMessageVisibilityInput, sqs.ChangeMessageVisibilityOutput]
+	GetQueueAttributesBehavior      MockedFunction[sqs.GetQueueAttributesInput, sqs.GetQueueAttributesOutput]
+	SendMessageBehavior             MockedFunction[sqs.SendMessageInput, sqs.SendMessageOutput]
 }
 
+// SQSAPI is a fake of a single queue. Unless an output is set for a call, messages that are sent to the queue are
+// received, deleted, and released with the semantics of SQS: a received message is hidden for the visibility timeout,
+// its receive count is incremented, and it can only be deleted or released with the receipt handle of its last receive.
 type SQSAPI struct {
 	sqsiface.SQSAPI
 	SQSBehavior
+	// Clock determines when received messages become visible again, which is the real clock if it isn't set
+	Clock clock.Clock
+
+	mu       sync.Mutex
+	messages []*SQSMessage
 }
 
 // Reset must be called between tests otherwise tests will pollute
@@ -46,6 +80,37 @@ func (s *SQSAPI) Reset() {
 	s.GetQueueURLBehavior.Reset()
 	s.ReceiveMessageBehavior.Reset()
 	s.DeleteMessageBehavior.Reset()
+	s.ChangeMessageVisibilityBehavior.Reset()
+	s.GetQueueAttributesBehavior.Reset()
+	s.SendMessageBehavior.Reset()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// Messages returns a copy of the messages in the queue, including the ones that aren't visible
+func (s *SQSAPI) Messages() []SQSMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return lo.Map(s.messages, func(m *SQSMessage, _ int) SQSMessage { return *m })
+}
+
+func (s *SQSAPI) now() time.Time {
+	if s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+// message returns the message that was last received with the receipt handle. The caller must hold mu.
+func (s *SQSAPI) message(receiptHandle *string) (*SQSMessage, error) {
+	if m, ok := lo.Find(s.messages, func(m *SQSMessage) bool {
+		return m.ReceiptHandle != "" && m.ReceiptHandle == aws.StringValue(receiptHandle)
+	}); ok {
+		return m, nil
+	}
+	return nil, awserr.New(sqs.ErrCodeReceiptHandleIsInvalid, fmt.Sprintf("the receipt handle %q isn't valid", aws.StringValue(receiptHandle)), nil)
 }
 
 //nolint:revive,stylecheck
@@ -58,13 +123,97 @@ func (s *SQSAPI) GetQueueUrlWithContext(_ context.Context, input *sqs.GetQueueUr
 }
 
 func (s *SQSAPI) ReceiveMessageWithContext(_ context.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
-	return s.ReceiveMessageBehavior.Invoke(input, func(_ *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
-		return nil, nil
+	return s.ReceiveMessageBehavior.Invoke(input, func(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := s.now()
+		visibilityTimeout := lo.Ternary(input.VisibilityTimeout != nil, time.Duration(aws.Int64Value(input.VisibilityTimeout))*time.Second, DefaultVisibilityTimeout)
+		out := &sqs.ReceiveMessageOutput{}
+		for _, m := range s.messages {
+			if int64(len(out.Messages)) >= lo.Max([]int64{aws.Int64Value(input.MaxNumberOfMessages), 1}) {
+				break
+			}
+			if m.VisibleAt.After(now) {
+				continue
+			}
+			m.ReceiveCount++
+			m.ReceiptHandle = string(uuid.NewUUID())
+			m.VisibleAt = now.Add(visibilityTimeout)
+			out.Messages = append(out.Messages, &sqs.Message{
+				MessageId:     aws.String(m.ID),
+				Body:          aws.String(m.Body),
+				ReceiptHandle: aws.String(m.ReceiptHandle),
+				Attributes: map[string]*string{
+					sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(strconv.Itoa(m.ReceiveCount)),
+					sqs.MessageSystemAttributeNameSentTimestamp:           aws.String(strconv.FormatInt(m.SentAt.UnixMilli(), 10)),
+				},
+			})
+		}
+		return out, nil
 	})
 }
 
 func (s *SQSAPI) DeleteMessageWithContext(_ context.Context, input *sqs.DeleteMessageInput, _ ...request.Option) (*sqs.DeleteMessageOutput, error) {
-	return s.DeleteMessageBehavior.Invoke(input, func(_ *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
-		return nil, nil
+	return s.DeleteMessageBehavior.Invoke(input, func(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Messages that are returned through a ReceiveMessage output aren't in the queue, so they're deleted as is
+		if input.ReceiptHandle == nil {
+			return &sqs.DeleteMessageOutput{}, nil
+		}
+		m, err := s.message(input.ReceiptHandle)
+		if err != nil {
+			return nil, err
+		}
+		s.messages = lo.Without(s.messages, m)
+		return &sqs.DeleteMessageOutput{}, nil
+	})
+}
+
+func (s *SQSAPI) ChangeMessageVisibilityWithContext(_ context.Context, input *sqs.ChangeMessageVisibilityInput, _ ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	return s.ChangeMessageVisibilityBehavior.Invoke(input, func(input *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if input.ReceiptHandle == nil {
+			return &sqs.ChangeMessageVisibilityOutput{}, nil
+		}
+		m, err := s.message(input.ReceiptHandle)
+		if err != nil {
+			return nil, err
+		}
+		m.VisibleAt = s.now().Add(time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second)
+		return &sqs.ChangeMessageVisibilityOutput{}, nil
+	})
+}
+
+func (s *SQSAPI) SendMessageWithContext(_ context.Context, input *sqs.SendMessageInput, _ ...request.Option) (*sqs.SendMessageOutput, error) {
+	return s.SendMessageBehavior.Invoke(input, func(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := s.now()
+		m := &SQSMessage{
+			ID:        string(uuid.NewUUID()),
+			Body:      aws.StringValue(input.MessageBody),
+			SentAt:    now,
+			VisibleAt: now.Add(time.Duration(aws.Int64Value(input.DelaySeconds)) * time.Second),
+		}
+		s.messages = append(s.messages, m)
+		return &sqs.SendMessageOutput{MessageId: aws.String(m.ID)}, nil
+	})
+}
+
+func (s *SQSAPI) GetQueueAttributesWithContext(_ context.Context, input *sqs.GetQueueAttributesInput, _ ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	return s.GetQueueAttributesBehavior.Invoke(input, func(_ *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := s.now()
+		visible := lo.CountBy(s.messages, func(m *SQSMessage) bool { return !m.VisibleAt.After(now) })
+		return &sqs.GetQueueAttributesOutput{
+			Attributes: map[string]*string{
+				sqs.QueueAttributeNameQueueArn:                              aws.String(DummyQueueARN),
+				sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String(strconv.Itoa(visible)),
+				sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String(strconv.Itoa(len(s.messages) - visible)),
+			},
+		}, nil
 	})
 }
@@ -32,13 +32,15 @@ type SSMAPI struct {
 	Parameters         map[string]string
 	GetParameterOutput *ssm.GetParameterOutput
 	WantErr            error
+
+	DescribeInstanceInformationBehavior MockedFunction[ssm.DescribeInstanceInformationInput, ssm.DescribeInstanceInformationOutput]
 }
 
 func NewSSMAPI() *SSMAPI {
 	return &SSMAPI{}
 }
 
-func (a SSMAPI) GetParameterWithContext(_ context.Context, input *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
+func (a *SSMAPI) GetParameterWithContext(_ context.Context, input *ssm.GetParameterInput, _ ...request.Option) (*ssm.GetParameterOutput, error) {
 	if a.WantErr != nil {
 		return nil, a.WantErr
 	}
@@ -60,8 +62,16 @@ func (a SSMAPI) GetParameterWithContext(_ context.Context, input *ssm.GetParamet
 	}, nil
 }
 
+// DescribeInstanceInformationWithContext reports no managed instances unless an output is set
+func (a *SSMAPI) DescribeInstanceInformationWithContext(_ context.Context, input *ssm.DescribeInstanceInformationInput, _ ...request.Option) (*ssm.DescribeInstanceInformationOutput, error) {
+	return a.DescribeInstanceInformationBehavior.Invoke(input, func(*ssm.DescribeInstanceInformationInput) (*ssm.DescribeInstanceInformationOutput, error) {
+		return &ssm.DescribeInstanceInformationOutput{}, nil
+	})
+}
+
 func (a *SSMAPI) Reset() {
 	a.GetParameterOutput = nil
 	a.Parameters = nil
 	a.WantErr = nil
+	a.DescribeInstanceInformationBehavior.Reset()
 }
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"errors"
+
+	ssmv2 "github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmv2types "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+)
+
+// SSMV2API serves the calls of the aws-sdk-go-v2 SSM client from an SSMAPI, so that the providers that were migrated
+// to aws-sdk-go-v2 and the ones that weren't share the parameters of a test
+type SSMV2API struct {
+	*SSMAPI
+}
+
+func NewSSMV2API(ssmapi *SSMAPI) *SSMV2API {
+	return &SSMV2API{SSMAPI: ssmapi}
+}
+
+func (a *SSMV2API) GetParameter(ctx context.Context, input *ssmv2.GetParameterInput, _ ...func(*ssmv2.Options)) (*ssmv2.GetParameterOutput, error) {
+	output, err := a.SSMAPI.GetParameterWithContext(ctx, &ssm.GetParameterInput{Name: input.Name, WithDecryption: input.WithDecryption})
+	if err != nil {
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) {
+			return nil, err
+		}
+		if aerr.Code() == ssm.ErrCodeParameterNotFound {
+			return nil, &ssmv2types.ParameterNotFound{Message: lo.ToPtr(aerr.Message())}
+		}
+		return nil, &smithy.GenericAPIError{Code: aerr.Code(), Message: aerr.Message()}
+	}
+	return &ssmv2.GetParameterOutput{Parameter: &ssmv2types.Parameter{
+		Name:    output.Parameter.Name,
+		Value:   output.Parameter.Value,
+		Version: lo.FromPtr(output.Parameter.Version),
+	}}, nil
+}
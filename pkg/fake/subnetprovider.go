@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+)
+
+var _ subnet.Provider = (*SubnetProvider)(nil)
+
+// SubnetProvider is a fake subnet.Provider that returns the subnets that it's set up with for every EC2NodeClass
+type SubnetProvider struct {
+	mu        sync.RWMutex
+	subnets   []ec2types.Subnet
+	NextError AtomicError
+}
+
+func NewSubnetProvider() *SubnetProvider {
+	return &SubnetProvider{}
+}
+
+// Reset must be called between tests otherwise tests will pollute
+// each other.
+func (p *SubnetProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subnets = nil
+	p.NextError.Reset()
+}
+
+// SetSubnets sets the subnets that are returned
+func (p *SubnetProvider) SetSubnets(subnets []ec2types.Subnet) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subnets = subnets
+}
+
+func (p *SubnetProvider) LivenessProbe(_ *http.Request) error {
+	return nil
+}
+
+func (p *SubnetProvider) List(_ context.Context, _ *v1beta1.EC2NodeClass) ([]ec2types.Subnet, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]ec2types.Subnet{}, p.subnets...), nil
+}
+
+// ZoneTypes returns the zones of the subnets as availability zones
+func (p *SubnetProvider) ZoneTypes(_ context.Context) (map[string]string, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return lo.SliceToMap(p.subnets, func(s ec2types.Subnet) (string, string) {
+		return lo.FromPtr(s.AvailabilityZone), string(ec2types.LocationTypeAvailabilityZone)
+	}), nil
+}
+
+// ZonalSubnetsForLaunch returns the first subnet of each zone in the status of the EC2NodeClass
+func (p *SubnetProvider) ZonalSubnetsForLaunch(_ context.Context, nodeClass *v1beta1.EC2NodeClass, _ string, _ []*cloudprovider.InstanceType, _ string) (map[string]*subnet.Subnet, error) {
+	if err := p.NextError.Get(); err != nil {
+		return nil, err
+	}
+	if len(nodeClass.Status.Subnets) == 0 {
+		return nil, fmt.Errorf("no subnets matched selector %v", nodeClass.Spec.SubnetSelectorTerms)
+	}
+	zonalSubnets := map[string]*subnet.Subnet{}
+	for _, s := range nodeClass.Status.Subnets {
+		if _, ok := zonalSubnets[s.Zone]; !ok {
+			zonalSubnets[s.Zone] = &subnet.Subnet{ID: s.ID, Zone: s.Zone, ZoneID: s.ZoneID, OutpostARN: s.OutpostARN}
+		}
+	}
+	return zonalSubnets, nil
+}
+
+func (p *SubnetProvider) UpdateInflightIPs(_ *ec2.CreateFleetInput, _ *ec2.CreateFleetOutput, _ []*cloudprovider.InstanceType, _ []*subnet.Subnet, _ string) {
+}
@@ -97,7 +97,11 @@ func FilterDescribeSecurtyGroups(sgs []*ec2.SecurityGroup, filters []*ec2.Filter
 // Filters are chained with a logical "AND"
 func FilterDescribeSubnets(subnets []*ec2.Subnet, filters []*ec2.Filter) []*ec2.Subnet {
 	return lo.Filter(subnets, func(subnet *ec2.Subnet, _ int) bool {
-		return Filter(filters, *subnet.SubnetId, "", subnet.Tags)
+		isZoneID := func(filter *ec2.Filter, _ int) bool { return aws.StringValue(filter.Name) == "availability-zone-id" }
+		zoneIDFilters := lo.Filter(filters, isZoneID)
+		return Filter(lo.Reject(filters, isZoneID), *subnet.SubnetId, "", subnet.Tags) && lo.EveryBy(zoneIDFilters, func(filter *ec2.Filter) bool {
+			return lo.Contains(aws.StringValueSlice(filter.Values), aws.StringValue(subnet.AvailabilityZoneId))
+		})
 	})
 }
 
@@ -166,7 +170,7 @@ func MakeInstances() []*ec2.InstanceTypeInfo {
 	ctx := options.ToContext(context.Background(), &options.Options{IsolatedVPC: true})
 	// Use keys from the static pricing data so that we guarantee pricing for the data
 	// Create uniform instance data so all of them schedule for a given pod
-	for _, it := range pricing.NewDefaultProvider(ctx, nil, nil, "us-east-1").InstanceTypes() {
+	for _, it := range pricing.NewDefaultProvider(ctx, nil, nil, "us-east-1", nil).InstanceTypes() {
 		instanceTypes = append(instanceTypes, &ec2.InstanceTypeInfo{
 			InstanceType: aws.String(it),
 			ProcessorInfo: &ec2.ProcessorInfo{
@@ -34,6 +34,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("AWS"),
 				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
@@ -78,6 +83,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("unsupported"),
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -152,6 +159,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -214,6 +226,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -267,6 +284,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -320,6 +342,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -364,6 +391,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(true),
 			Hypervisor:                    aws.String(""),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("unsupported"),
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -408,6 +437,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -452,6 +486,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -506,6 +545,8 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("xen"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios"}),
+			NitroTpmSupport:               aws.String("unsupported"),
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -562,6 +603,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -606,6 +652,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("AWS"),
 				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
@@ -650,6 +701,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("AWS"),
 				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
@@ -694,6 +750,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(true),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("AWS"),
 				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
@@ -738,6 +799,11 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			Hypervisor:                    aws.String("nitro"),
+			SupportedBootModes:            aws.StringSlice([]string{"legacy-bios", "uefi"}),
+			NitroTpmSupport:               aws.String("supported"),
+			NitroTpmInfo: &ec2.NitroTpmInfo{
+				SupportedVersions: aws.StringSlice([]string{"2.0"}),
+			},
 			ProcessorInfo: &ec2.ProcessorInfo{
 				Manufacturer:           aws.String("Intel"),
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
@@ -762,6 +828,22 @@ var defaultDescribeInstanceTypesOutput = &ec2.DescribeInstanceTypesOutput{
 				EncryptionSupport:   aws.String("supported"),
 				NvmeSupport:         aws.String("required"),
 			},
+			NeuronInfo: &ec2.NeuronInfo{
+				NeuronDevices: []*ec2.NeuronDeviceInfo{
+					{
+						Name:  aws.String("Trainium"),
+						Count: aws.Int64(1),
+						CoreInfo: &ec2.NeuronDeviceCoreInfo{
+							Count:   aws.Int64(2),
+							Version: aws.Int64(2),
+						},
+						MemoryInfo: &ec2.NeuronDeviceMemoryInfo{
+							SizeInMiB: aws.Int64(32768),
+						},
+					},
+				},
+				TotalNeuronDeviceMemoryInMiB: aws.Int64(32768),
+			},
 			InstanceStorageInfo: &ec2.InstanceStorageInfo{NvmeSupport: aws.String("required"),
 				TotalSizeInGB: aws.Int64(474),
 			},
@@ -0,0 +1,217 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package iampolicy records the AWS API actions that the controller invokes and renders them as a least-privilege IAM
+// policy, so that the controller policy can track the actions that a build actually uses rather than the broad
+// published policy.
+package iampolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Recorder records the IAM actions of the AWS API calls that are made through a session
+type Recorder struct {
+	mu      sync.RWMutex
+	actions sets.Set[string]
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{actions: sets.New[string]()}
+}
+
+// WithRecorder records the action of each AWS API call that is made through the session. Calls that fail are recorded
+// too, since a call that was denied is one that the policy needs to allow.
+func WithRecorder(sess *session.Session, recorder *Recorder) *session.Session {
+	sess.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "karpenter.iampolicy.RecordAction",
+		Fn: func(r *request.Request) {
+			service := lo.Ternary(r.ClientInfo.SigningName != "", r.ClientInfo.SigningName, r.ClientInfo.ServiceName)
+			recorder.Record(fmt.Sprintf("%s:%s", service, r.Operation.Name))
+		},
+	})
+	return sess
+}
+
+// WithRecorderV2 records the action of each call of the aws-sdk-go-v2 clients created from the config, like
+// WithRecorder does for the calls made through a session
+func WithRecorderV2(cfg awsv2.Config, recorder *Recorder) awsv2.Config {
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("karpenter.iampolicy.RecordAction",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				recorder.Record(utils.ActionV2(ctx))
+				return next.HandleInitialize(ctx, in)
+			}), middleware.After)
+	})
+	return cfg
+}
+
+// Record records an action, e.g. ec2:DescribeInstances
+func (r *Recorder) Record(action string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions.Insert(action)
+}
+
+// Actions returns the recorded actions in order
+func (r *Recorder) Actions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sets.List(r.actions)
+}
+
+// Policy is an IAM policy document
+type Policy struct {
+	Version   string      `json:"Version"`
+	Statement []Statement `json:"Statement"`
+}
+
+type Statement struct {
+	Sid       string                            `json:"Sid"`
+	Effect    string                            `json:"Effect"`
+	Resource  string                            `json:"Resource"`
+	Action    []string                          `json:"Action"`
+	Condition map[string]map[string]interface{} `json:"Condition,omitempty"`
+}
+
+type scope int
+
+const (
+	// scopeRegional actions are only allowed in the region of the cluster
+	scopeRegional scope = iota
+	// scopeRequestTag actions create resources, which must be tagged as owned by the cluster
+	scopeRequestTag
+	// scopeResourceTag actions act on existing resources, which must be tagged as owned by the cluster
+	scopeResourceTag
+	// scopeGlobal actions are of services that don't have regional endpoints, so they can't be scoped to the region
+	scopeGlobal
+)
+
+// globalServices are the services whose requests aren't made to the region of the cluster
+var globalServices = sets.New("iam", "pricing")
+
+// scopes are the actions that create or act on resources that Karpenter tags as owned by the cluster. Every other action
+// is scoped to the region.
+var scopes = map[string]scope{
+	"ec2:CreateFleet":                   scopeRequestTag,
+	"ec2:RunInstances":                  scopeRequestTag,
+	"ec2:CreateLaunchTemplate":          scopeRequestTag,
+	"iam:CreateInstanceProfile":         scopeRequestTag,
+	"ec2:CreateTags":                    scopeResourceTag,
+	"ec2:TerminateInstances":            scopeResourceTag,
+	"ec2:DeleteLaunchTemplate":          scopeResourceTag,
+	"ec2:GetConsoleOutput":              scopeResourceTag,
+	"iam:AddRoleToInstanceProfile":      scopeResourceTag,
+	"iam:RemoveRoleFromInstanceProfile": scopeResourceTag,
+	"iam:DeleteInstanceProfile":         scopeResourceTag,
+	"iam:TagInstanceProfile":            scopeResourceTag,
+}
+
+// implied are the actions that IAM authorizes on behalf of an action, which aren't API calls of their own. CreateFleet
+// launches its instances with RunInstances, and tags the resources that it creates with CreateTags.
+var implied = map[string][]string{
+	"ec2:CreateFleet":              {"ec2:RunInstances", "ec2:CreateTags", "iam:PassRole"},
+	"ec2:RunInstances":             {"ec2:CreateTags", "iam:PassRole"},
+	"ec2:CreateLaunchTemplate":     {"ec2:CreateTags"},
+	"iam:AddRoleToInstanceProfile": {"iam:PassRole"},
+}
+
+// Policy returns the least-privilege policy that allows the recorded actions for the cluster in the region. Actions
+// that create resources are conditioned on tagging them as owned by the cluster, and actions on existing resources on
+// them being tagged as owned by it.
+func (r *Recorder) Policy(clusterName string, region string) Policy {
+	actions := sets.New(r.Actions()...)
+	var creationActions []string
+	passRole := false
+	for _, action := range sets.List(actions) {
+		for _, a := range implied[action] {
+			switch a {
+			case "ec2:CreateTags":
+				creationActions = append(creationActions, strings.TrimPrefix(action, "ec2:"))
+			case "iam:PassRole":
+				passRole = true
+			default:
+				actions.Insert(a)
+				if lo.Contains(implied[a], "ec2:CreateTags") {
+					creationActions = append(creationActions, strings.TrimPrefix(a, "ec2:"))
+				}
+			}
+		}
+	}
+	clusterTag := fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)
+	byScope := lo.GroupBy(sets.List(actions), func(action string) scope {
+		if s, ok := scopes[action]; ok {
+			return s
+		}
+		return lo.Ternary(globalServices.Has(strings.Split(action, ":")[0]), scopeGlobal, scopeRegional)
+	})
+	policy := Policy{Version: "2012-10-17"}
+	add := func(sid string, actions []string, condition map[string]map[string]interface{}) {
+		if len(actions) == 0 {
+			return
+		}
+		policy.Statement = append(policy.Statement, Statement{Sid: sid, Effect: "Allow", Resource: "*", Action: actions, Condition: condition})
+	}
+	add("AllowScopedResourceCreationActions", byScope[scopeRequestTag], map[string]map[string]interface{}{
+		"StringEquals": {"aws:RequestTag/" + clusterTag: "owned"},
+	})
+	if len(creationActions) > 0 {
+		add("AllowScopedResourceCreationTagging", []string{"ec2:CreateTags"}, map[string]map[string]interface{}{
+			"StringEquals": {"aws:RequestTag/" + clusterTag: "owned", "ec2:CreateAction": sets.List(sets.New(creationActions...))},
+		})
+	}
+	add("AllowScopedResourceActions", byScope[scopeResourceTag], map[string]map[string]interface{}{
+		"StringEquals": {"aws:ResourceTag/" + clusterTag: "owned"},
+	})
+	if passRole {
+		add("AllowPassingInstanceRole", []string{"iam:PassRole"}, map[string]map[string]interface{}{
+			"StringEquals": {"iam:PassedToService": utils.ServicePrincipal("ec2", region)},
+		})
+	}
+	add("AllowRegionalActions", byScope[scopeRegional], map[string]map[string]interface{}{
+		"StringEquals": {"aws:RequestedRegion": region},
+	})
+	add("AllowGlobalActions", byScope[scopeGlobal], nil)
+	return policy
+}
+
+// NewHandler returns a read-only HTTP handler that serves the policy of the recorded actions as JSON
+func NewHandler(recorder *Recorder, clusterName string, region string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(recorder.Policy(clusterName, region)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
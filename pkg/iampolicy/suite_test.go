@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iampolicy_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	ssmv2 "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/iampolicy"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "IAMPolicy")
+}
+
+var _ = Describe("IAMPolicy", func() {
+	var recorder *iampolicy.Recorder
+	BeforeEach(func() {
+		recorder = iampolicy.NewRecorder()
+	})
+	statement := func(policy iampolicy.Policy, sid string) iampolicy.Statement {
+		GinkgoHelper()
+		s, ok := lo.Find(policy.Statement, func(s iampolicy.Statement) bool { return s.Sid == sid })
+		Expect(ok).To(BeTrue(), "expected statement %s", sid)
+		return s
+	}
+	It("should record the actions of the calls made through the session, including failed ones", func() {
+		sess := session.Must(session.NewSession(&aws.Config{Region: aws.String("us-west-2"), Credentials: credentials.AnonymousCredentials}))
+		// Calls fail before they're sent, which is still recorded
+		sess.Handlers.Send.PushFront(func(r *request.Request) { r.Error = fmt.Errorf("not sent") })
+		sess = iampolicy.WithRecorder(sess, recorder)
+
+		_, err := ec2.New(sess).DescribeInstances(&ec2.DescribeInstancesInput{})
+		Expect(err).To(HaveOccurred())
+		_, err = pricing.New(sess, &aws.Config{Region: aws.String("us-east-1")}).GetProducts(&pricing.GetProductsInput{ServiceCode: aws.String("AmazonEC2")})
+		Expect(err).To(HaveOccurred())
+		Expect(recorder.Actions()).To(ConsistOf("ec2:DescribeInstances", "pricing:GetProducts"))
+	})
+	It("should record the actions of the calls made by aws-sdk-go-v2 clients, including failed ones", func() {
+		ssmapi := ssmv2.NewFromConfig(iampolicy.WithRecorderV2(awsv2.Config{
+			Region:      "us-west-2",
+			Credentials: awsv2.AnonymousCredentials{},
+			Retryer:     func() awsv2.Retryer { return awsv2.NopRetryer{} },
+			HTTPClient:  smithyhttp.ClientDoFunc(func(*http.Request) (*http.Response, error) { return nil, fmt.Errorf("not sent") }),
+		}, recorder))
+		_, err := ssmapi.GetParameter(context.Background(), &ssmv2.GetParameterInput{Name: lo.ToPtr("test")})
+		Expect(err).To(HaveOccurred())
+		Expect(recorder.Actions()).To(ConsistOf("ssm:GetParameter"))
+	})
+	It("should scope read actions to the region, except for global services", func() {
+		recorder.Record("ec2:DescribeSubnets")
+		recorder.Record("pricing:GetProducts")
+		policy := recorder.Policy("test-cluster", "us-west-2")
+		Expect(statement(policy, "AllowRegionalActions").Action).To(ConsistOf("ec2:DescribeSubnets"))
+		Expect(statement(policy, "AllowRegionalActions").Condition).To(HaveKeyWithValue("StringEquals", HaveKeyWithValue("aws:RequestedRegion", "us-west-2")))
+		Expect(statement(policy, "AllowGlobalActions").Action).To(ConsistOf("pricing:GetProducts"))
+		Expect(statement(policy, "AllowGlobalActions").Condition).To(BeEmpty())
+	})
+	It("should require resources that are created to be tagged as owned by the cluster", func() {
+		recorder.Record("ec2:CreateFleet")
+		policy := recorder.Policy("test-cluster", "us-west-2")
+		creation := statement(policy, "AllowScopedResourceCreationActions")
+		// CreateFleet launches its instances with RunInstances
+		Expect(creation.Action).To(ConsistOf("ec2:CreateFleet", "ec2:RunInstances"))
+		Expect(creation.Condition).To(HaveKeyWithValue("StringEquals", HaveKeyWithValue("aws:RequestTag/kubernetes.io/cluster/test-cluster", "owned")))
+		tagging := statement(policy, "AllowScopedResourceCreationTagging")
+		Expect(tagging.Action).To(ConsistOf("ec2:CreateTags"))
+		Expect(tagging.Condition).To(HaveKeyWithValue("StringEquals", HaveKeyWithValue("ec2:CreateAction", ConsistOf("CreateFleet", "RunInstances"))))
+		Expect(statement(policy, "AllowPassingInstanceRole").Action).To(ConsistOf("iam:PassRole"))
+	})
+	DescribeTable("should only allow passing the instance role to the EC2 service principal of the partition",
+		func(region string, principal string) {
+			recorder.Record("ec2:CreateFleet")
+			policy := recorder.Policy("test-cluster", region)
+			Expect(statement(policy, "AllowPassingInstanceRole").Condition).To(HaveKeyWithValue("StringEquals", HaveKeyWithValue("iam:PassedToService", principal)))
+		},
+		Entry("aws", "us-west-2", "ec2.amazonaws.com"),
+		Entry("aws-us-gov", "us-gov-west-1", "ec2.amazonaws.com"),
+		Entry("aws-cn", "cn-north-1", "ec2.amazonaws.com.cn"),
+	)
+	It("should require existing resources to be tagged as owned by the cluster", func() {
+		recorder.Record("ec2:TerminateInstances")
+		recorder.Record("ec2:CreateTags")
+		policy := recorder.Policy("test-cluster", "us-west-2")
+		scoped := statement(policy, "AllowScopedResourceActions")
+		Expect(scoped.Action).To(ConsistOf("ec2:CreateTags", "ec2:TerminateInstances"))
+		Expect(scoped.Condition).To(HaveKeyWithValue("StringEquals", HaveKeyWithValue("aws:ResourceTag/kubernetes.io/cluster/test-cluster", "owned")))
+		Expect(lo.Map(policy.Statement, func(s iampolicy.Statement, _ int) string { return s.Sid })).ToNot(ContainElement("AllowPassingInstanceRole"))
+	})
+	It("should serve the policy as JSON", func() {
+		recorder.Record("ec2:DescribeSubnets")
+		response := httptest.NewRecorder()
+		iampolicy.NewHandler(recorder, "test-cluster", "us-west-2").ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/iam-policy", nil))
+		Expect(response.Code).To(Equal(http.StatusOK))
+		policy := iampolicy.Policy{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &policy)).To(Succeed())
+		Expect(policy.Version).To(Equal("2012-10-17"))
+		Expect(statement(policy, "AllowRegionalActions").Action).To(ConsistOf("ec2:DescribeSubnets"))
+	})
+})
@@ -23,9 +23,16 @@ import (
 	"os"
 	"time"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	pricingv2 "github.com/aws/aws-sdk-go-v2/service/pricing"
+	ssmv2 "github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
@@ -35,12 +42,21 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/aws/smithy-go/middleware"
 	prometheusv1 "github.com/jonathan-innis/aws-sdk-go-prometheus/v1"
+	prometheusv2 "github.com/jonathan-innis/aws-sdk-go-prometheus/v2"
 	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -51,8 +67,11 @@ import (
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/operator"
 
+	"github.com/aws/karpenter-provider-aws/pkg/auditlog"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/iampolicy"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/preflight"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
@@ -60,10 +79,19 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/terminationhook"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+	"github.com/aws/karpenter-provider-aws/pkg/readonly"
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
+// EC2MaxAttempts is the number of times an EC2 call is attempted. The batchers already coalesce calls, and the adaptive
+// retry mode slows the client down while it's throttled, so calls are attempted fewer times than other calls are.
+const EC2MaxAttempts = 3
+
 func init() {
 	corev1beta1.NormalizedLabels = lo.Assign(corev1beta1.NormalizedLabels, map[string]string{"topology.ebs.csi.aws.com/zone": corev1.LabelTopologyZone})
 }
@@ -73,27 +101,35 @@ type Operator struct {
 	*operator.Operator
 
 	Session                   *session.Session
+	AccountID                 string
+	IAMPolicyRecorder         *iampolicy.Recorder
 	UnavailableOfferingsCache *awscache.UnavailableOfferings
+	BlockedOfferingsCache     *awscache.BlockedOfferings
+	ZoneHealth                *awscache.ZoneHealth
+	CreationLimits            *awscache.CreationLimits
+	LaunchRamps               *awscache.LaunchRamps
+	ZoneScores                *awscache.ZoneScores
+	VPCCNI                    *awscache.VPCCNI
+	VCPUQuotaHeadroom         *awscache.VCPUQuotaHeadroom
 	EC2API                    ec2iface.EC2API
 	SubnetProvider            subnet.Provider
 	SecurityGroupProvider     securitygroup.Provider
 	InstanceProfileProvider   instanceprofile.Provider
 	AMIProvider               amifamily.Provider
-	AMIResolver               *amifamily.Resolver
+	AMIResolver               amifamily.Resolver
 	LaunchTemplateProvider    launchtemplate.Provider
 	PricingProvider           pricing.Provider
 	VersionProvider           version.Provider
 	InstanceTypesProvider     instancetype.Provider
 	InstanceProvider          instance.Provider
+	TerminationHookProvider   terminationhook.Provider
+	PreflightChecker          *preflight.Checker
 }
 
 func NewOperator(ctx context.Context, operator *operator.Operator) (context.Context, *Operator) {
-	config := &aws.Config{
-		STSRegionalEndpoint: endpoints.RegionalSTSEndpoint,
-	}
-
+	config := NewConfig(ctx)
 	if assumeRoleARN := options.FromContext(ctx).AssumeRoleARN; assumeRoleARN != "" {
-		config.Credentials = stscreds.NewCredentials(session.Must(session.NewSession()), assumeRoleARN,
+		config.Credentials = stscreds.NewCredentials(session.Must(session.NewSession(&aws.Config{EndpointResolver: config.EndpointResolver, UseFIPSEndpoint: config.UseFIPSEndpoint})), assumeRoleARN,
 			func(provider *stscreds.AssumeRoleProvider) { SetDurationAndExpiry(ctx, provider) })
 	}
 
@@ -107,6 +143,23 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		),
 	))), crmetrics.Registry)
 
+	// The actions of the AWS API calls are recorded so that the least-privilege policy of this build can be generated
+	iamPolicyRecorder := iampolicy.NewRecorder()
+	sess = iampolicy.WithRecorder(sess, iamPolicyRecorder)
+
+	if options.FromContext(ctx).ReadOnly {
+		log.FromContext(ctx).Info("read-only mode is enabled, launches and terminations are reported rather than performed")
+		sess = readonly.WithReadOnly(sess)
+	}
+
+	if options.FromContext(ctx).TracingEndpoint != "" {
+		if err := tracing.Start(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "failed starting tracing")
+			os.Exit(1)
+		}
+		sess = tracing.WithTracing(sess)
+	}
+
 	if *sess.Config.Region == "" {
 		log.FromContext(ctx).V(1).Info("retrieving region from IMDS")
 		region, err := ec2metadata.New(sess).Region()
@@ -117,7 +170,11 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		log.FromContext(ctx).Error(err, "ec2 api connectivity check failed")
 		os.Exit(1)
 	}
-	log.FromContext(ctx).WithValues("region", *sess.Config.Region).V(1).Info("discovered region")
+	log.FromContext(ctx).WithValues("region", *sess.Config.Region, "partition", utils.Partition(*sess.Config.Region)).V(1).Info("discovered region")
+	// The clients that have been migrated to aws-sdk-go-v2 are created from a config that shares the session's region,
+	// credentials and instrumentation
+	cfg := NewConfigV2(ctx, sess, iamPolicyRecorder)
+	ec2v2api := NewEC2Client(ctx, cfg)
 	clusterEndpoint, err := ResolveClusterEndpoint(ctx, eks.New(sess))
 	if err != nil {
 		log.FromContext(ctx).Error(err, "failed detecting cluster endpoint")
@@ -125,39 +182,70 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 	} else {
 		log.FromContext(ctx).WithValues("cluster-endpoint", clusterEndpoint).V(1).Info("discovered cluster endpoint")
 	}
-	// We perform best-effort on resolving the kube-dns IP
-	kubeDNSIP, err := KubeDNSIP(ctx, operator.KubernetesInterface)
-	if err != nil {
+	// We perform best-effort on resolving the kube-dns IP, unless it's configured
+	kubeDNSIP := net.ParseIP(options.FromContext(ctx).ClusterDNS)
+	if kubeDNSIP != nil {
+		log.FromContext(ctx).WithValues("kube-dns-ip", kubeDNSIP).V(1).Info("using configured cluster dns")
+	} else if kubeDNSIP, err = KubeDNSIP(ctx, operator.KubernetesInterface); err != nil {
 		// If we fail to get the kube-dns IP, we don't want to crash because this causes issues with custom DNS setups
-		// https://github.com/aws/karpenter-provider-aws/issues/2787
-		log.FromContext(ctx).V(1).Info(fmt.Sprintf("unable to detect the IP of the kube-dns service, %s", err))
+		// https://github.com/aws/karpenter-provider-aws/issues/2787. Nodes fall back to the cluster DNS IP that their
+		// bootstrap derives, which is wrong for clusters with a non-default service CIDR.
+		log.FromContext(ctx).Info(fmt.Sprintf("unable to detect the IP of the kube-dns service, nodes use the default cluster dns of their AMI unless cluster-dns is set, %s", err))
 	} else {
 		log.FromContext(ctx).WithValues("kube-dns-ip", kubeDNSIP).V(1).Info("discovered kube dns")
 	}
 
+	// We perform best-effort on resolving the account, which is only used to report resources shared from other accounts
+	accountID, err := ResolveAccountID(ctx, sts.New(sess))
+	if err != nil {
+		log.FromContext(ctx).V(1).Info(fmt.Sprintf("unable to detect the account, %s", err))
+	} else {
+		log.FromContext(ctx).WithValues("account-id", accountID).V(1).Info("discovered account")
+	}
+
+	var instanceTypeSnapshot *snapshot.Snapshot
+	if path := options.FromContext(ctx).InstanceTypeSnapshotFile; path != "" {
+		instanceTypeSnapshot = lo.Must(snapshot.Load(path))
+		if instanceTypeSnapshot.Region != *sess.Config.Region {
+			log.FromContext(ctx).WithValues("snapshot-region", instanceTypeSnapshot.Region, "region", *sess.Config.Region).Info("instance type snapshot was generated for a different region")
+		}
+		log.FromContext(ctx).WithValues("path", path, "created-at", instanceTypeSnapshot.CreatedAt).V(1).Info("loaded instance type snapshot")
+	}
+
 	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
-	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval))
-	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	blockedOfferingsCache := awscache.NewBlockedOfferings(operator.Clock)
+	zoneHealth := awscache.NewZoneHealth(operator.Clock)
+	creationLimits := awscache.NewCreationLimits(operator.Clock)
+	launchRamps := awscache.NewLaunchRamps(operator.Clock)
+	zoneScores := awscache.NewZoneScores()
+	vpcCNI := awscache.NewVPCCNI()
+	vcpuQuotaHeadroom := awscache.NewVCPUQuotaHeadroom()
+	subnetProvider := subnet.NewDefaultProvider(ec2v2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval), vpcCNI)
+	securityGroupProvider := securitygroup.NewDefaultProvider(ec2v2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	instanceProfileProvider := instanceprofile.NewDefaultProvider(*sess.Config.Region, iam.New(sess), cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
+	pricingAPI := pricing.NewAPI(ctx, cfg, *sess.Config.Region, func(o *pricingv2.Options) {
+		o.BaseEndpoint, _ = endpointOptions(ctx, "api.pricing", pricing.APIRegion(*sess.Config.Region))
+	})
 	pricingProvider := pricing.NewDefaultProvider(
 		ctx,
-		pricing.NewAPI(sess, *sess.Config.Region),
-		ec2api,
+		pricingAPI,
+		ec2v2api,
 		*sess.Config.Region,
+		instanceTypeSnapshot,
 	)
 	versionProvider := version.NewDefaultProvider(operator.KubernetesInterface, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
-	amiProvider := amifamily.NewDefaultProvider(versionProvider, ssm.New(sess), ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
-	amiResolver := amifamily.NewResolver(amiProvider)
+	amiProvider := amifamily.NewDefaultProvider(operator.Clock, versionProvider, NewSSMClient(ctx, cfg), ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	amiResolver := amifamily.NewDefaultResolver(amiProvider)
 	launchTemplateProvider := launchtemplate.NewDefaultProvider(
 		ctx,
 		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
-		ec2api,
+		creationLimits,
+		ec2v2api,
 		eks.New(sess),
 		amiResolver,
 		securityGroupProvider,
 		subnetProvider,
 		lo.Must(GetCABundle(ctx, operator.GetConfig())),
-		operator.Elected(),
 		kubeDNSIP,
 		clusterEndpoint,
 	)
@@ -167,22 +255,50 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		ec2api,
 		subnetProvider,
 		unavailableOfferingsCache,
+		blockedOfferingsCache,
+		zoneHealth,
+		vpcCNI,
+		vcpuQuotaHeadroom,
 		pricingProvider,
+		instanceTypeSnapshot,
 	)
+	// Missing permissions are reported at startup, rather than when the first launch fails. They're checked in the
+	// background so that slow or throttled calls don't delay startup.
+	preflightChecker := preflight.NewChecker(ctx, ec2api, ssm.New(sess), pricingAPI, sqs.New(sess), iam.New(sess), operator.Clock)
+	go preflightChecker.Check(ctx)
+	auditLogger, err := auditlog.New(options.FromContext(ctx).LaunchAuditLog, s3.New(sess))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed creating launch audit log")
+		os.Exit(1)
+	}
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
 		aws.StringValue(sess.Config.Region),
-		ec2api,
+		ec2v2api,
 		unavailableOfferingsCache,
+		zoneHealth,
+		creationLimits,
+		zoneScores,
 		instanceTypeProvider,
 		subnetProvider,
 		launchTemplateProvider,
+		auditLogger,
+		operator.Clock,
 	)
 
 	return ctx, &Operator{
 		Operator:                  operator,
 		Session:                   sess,
+		AccountID:                 accountID,
+		IAMPolicyRecorder:         iamPolicyRecorder,
 		UnavailableOfferingsCache: unavailableOfferingsCache,
+		BlockedOfferingsCache:     blockedOfferingsCache,
+		ZoneHealth:                zoneHealth,
+		CreationLimits:            creationLimits,
+		LaunchRamps:               launchRamps,
+		ZoneScores:                zoneScores,
+		VPCCNI:                    vpcCNI,
+		VCPUQuotaHeadroom:         vcpuQuotaHeadroom,
 		EC2API:                    ec2api,
 		SubnetProvider:            subnetProvider,
 		SecurityGroupProvider:     securityGroupProvider,
@@ -194,6 +310,8 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		PricingProvider:           pricingProvider,
 		InstanceTypesProvider:     instanceTypeProvider,
 		InstanceProvider:          instanceProvider,
+		TerminationHookProvider:   terminationhook.NewDefaultProvider(eventbridge.New(sess)),
+		PreflightChecker:          preflightChecker,
 	}
 }
 
@@ -204,6 +322,123 @@ func WithUserAgent(sess *session.Session) *session.Session {
 	return sess
 }
 
+// NewConfig returns the configuration of the AWS clients with the endpoint options of the controller
+func NewConfig(ctx context.Context) *aws.Config {
+	config := &aws.Config{
+		STSRegionalEndpoint: endpoints.RegionalSTSEndpoint,
+	}
+	if dnsSuffix := options.FromContext(ctx).AWSDNSSuffix; dnsSuffix != "" {
+		config.EndpointResolver = EndpointResolver(dnsSuffix)
+	}
+	// The SDK resolves the FIPS endpoint of each service, which in GovCloud is often the service's default endpoint
+	if options.FromContext(ctx).AWSFIPSEndpoints {
+		config.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
+	}
+	return config
+}
+
+// EndpointResolver resolves the endpoints of AWS services under a custom DNS suffix, for partitions whose endpoints
+// aren't known to the SDK. The SDK's endpoint model is still consulted for the signing name of each service. FIPS
+// endpoints are resolved as <service>-fips.<region>.<suffix>.
+func EndpointResolver(dnsSuffix string) endpoints.Resolver {
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		resolved, err := endpoints.DefaultResolver().EndpointFor(service, region, append(opts, func(o *endpoints.Options) {
+			o.ResolveUnknownService = true
+		})...)
+		if err != nil {
+			resolved = endpoints.ResolvedEndpoint{SigningName: service, SigningMethod: "v4"}
+		}
+		options := endpoints.Options{}
+		for _, opt := range opts {
+			opt(&options)
+		}
+		resolved.URL = serviceURL(service, region, dnsSuffix, options.UseFIPSEndpoint == endpoints.FIPSEndpointStateEnabled)
+		resolved.SigningRegion = region
+		resolved.PartitionID = utils.Partition(region)
+		return resolved, nil
+	})
+}
+
+func serviceURL(service, region, dnsSuffix string, fips bool) string {
+	return fmt.Sprintf("https://%s%s.%s.%s", service, lo.Ternary(fips, "-fips", ""), region, dnsSuffix)
+}
+
+// NewConfigV2 returns the configuration of the aws-sdk-go-v2 clients. They share the region and credentials of the
+// session, are retried as often as its clients are unless a client configures its own retryer, and are instrumented
+// with the same metrics, tracing, read-only mode and IAM policy recorder, so that calls are reported the same way
+// whichever SDK they're made with.
+func NewConfigV2(ctx context.Context, sess *session.Session, iamPolicyRecorder *iampolicy.Recorder) awsv2.Config {
+	cfg := awsv2.Config{
+		Region:      aws.StringValue(sess.Config.Region),
+		Credentials: credentialsV2{credentials: sess.Config.Credentials},
+		Retryer: func() awsv2.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) { o.MaxAttempts = awsclient.DefaultRetryerMaxNumRetries + 1 })
+		},
+		APIOptions: []func(*middleware.Stack) error{awsmiddleware.AddUserAgentKey(fmt.Sprintf("karpenter.sh-%s", operator.Version))},
+	}
+	// The collectors of the metrics are shared with the session, which registers them, so they're registered with a
+	// registry of their own here rather than registered twice
+	cfg = prometheusv2.WithPrometheusMetrics(cfg, prometheus.NewRegistry())
+	cfg = iampolicy.WithRecorderV2(cfg, iamPolicyRecorder)
+	if options.FromContext(ctx).ReadOnly {
+		cfg = readonly.WithReadOnlyV2(cfg)
+	}
+	if options.FromContext(ctx).TracingEndpoint != "" {
+		cfg = tracing.WithTracingV2(cfg)
+	}
+	return cfg
+}
+
+// NewSSMClient returns an aws-sdk-go-v2 SSM client with the endpoint options of the controller
+func NewSSMClient(ctx context.Context, cfg awsv2.Config) *ssmv2.Client {
+	return ssmv2.NewFromConfig(cfg, func(o *ssmv2.Options) {
+		o.BaseEndpoint, o.EndpointOptions.UseFIPSEndpoint = endpointOptions(ctx, "ssm", cfg.Region)
+	})
+}
+
+// NewEC2Client returns an aws-sdk-go-v2 EC2 client with the endpoint options of the controller. It's retried in the
+// adaptive mode, which rate limits the client while EC2 throttles it, rather than retrying into the throttle.
+func NewEC2Client(ctx context.Context, cfg awsv2.Config) *ec2v2.Client {
+	return ec2v2.NewFromConfig(cfg, func(o *ec2v2.Options) {
+		o.BaseEndpoint, o.EndpointOptions.UseFIPSEndpoint = endpointOptions(ctx, "ec2", cfg.Region)
+		o.Retryer = retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, func(o *retry.StandardOptions) { o.MaxAttempts = EC2MaxAttempts })
+		})
+	})
+}
+
+// endpointOptions returns the endpoint of a service under the custom DNS suffix, or whether the FIPS endpoint of the
+// service is resolved otherwise, since aws-sdk-go-v2 resolves endpoints per client rather than per config
+func endpointOptions(ctx context.Context, service, region string) (*string, awsv2.FIPSEndpointState) {
+	fips := options.FromContext(ctx).AWSFIPSEndpoints
+	if dnsSuffix := options.FromContext(ctx).AWSDNSSuffix; dnsSuffix != "" {
+		return lo.ToPtr(serviceURL(service, region, dnsSuffix, fips)), awsv2.FIPSEndpointStateUnset
+	}
+	return nil, lo.Ternary(fips, awsv2.FIPSEndpointStateEnabled, awsv2.FIPSEndpointStateUnset)
+}
+
+// credentialsV2 provides the credentials of the session to aws-sdk-go-v2 clients, so that the clients of both SDKs
+// use the same, e.g. assumed, role
+type credentialsV2 struct {
+	credentials *credentials.Credentials
+}
+
+func (c credentialsV2) Retrieve(ctx context.Context) (awsv2.Credentials, error) {
+	value, err := c.credentials.GetWithContext(ctx)
+	if err != nil {
+		return awsv2.Credentials{}, err
+	}
+	expires, err := c.credentials.ExpiresAt()
+	return awsv2.Credentials{
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+		Source:          value.ProviderName,
+		CanExpire:       err == nil,
+		Expires:         expires,
+	}, nil
+}
+
 // CheckEC2Connectivity makes a dry-run call to DescribeInstanceTypes.  If it fails, we provide an early indicator that we
 // are having issues connecting to the EC2 API.
 func CheckEC2Connectivity(ctx context.Context, api ec2iface.EC2API) error {
@@ -229,6 +464,15 @@ func ResolveClusterEndpoint(ctx context.Context, eksAPI eksiface.EKSAPI) (string
 	return *out.Cluster.Endpoint, nil
 }
 
+// ResolveAccountID returns the account of Karpenter's credentials
+func ResolveAccountID(ctx context.Context, stsAPI stsiface.STSAPI) (string, error) {
+	out, err := stsAPI.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("getting caller identity, %w", err)
+	}
+	return aws.StringValue(out.Account), nil
+}
+
 func GetCABundle(ctx context.Context, restConfig *rest.Config) (*string, error) {
 	// Discover CA Bundle from the REST client. We could alternatively
 	// have used the simpler client-go InClusterConfig() method.
@@ -248,19 +492,40 @@ func GetCABundle(ctx context.Context, restConfig *rest.Config) (*string, error)
 	return lo.ToPtr(base64.StdEncoding.EncodeToString(transportConfig.TLS.CAData)), nil
 }
 
+// KubeDNSIP returns the cluster IP of the kube-dns service in the kube-system namespace. If the service doesn't exist,
+// the cluster IP of a service that's labeled k8s-app=kube-dns is returned instead, which CoreDNS deployments that name
+// their service differently keep for compatibility.
 func KubeDNSIP(ctx context.Context, kubernetesInterface kubernetes.Interface) (net.IP, error) {
 	if kubernetesInterface == nil {
 		return nil, fmt.Errorf("no K8s client provided")
 	}
 	dnsService, err := kubernetesInterface.CoreV1().Services("kube-system").Get(ctx, "kube-dns", metav1.GetOptions{})
+	if err == nil {
+		return clusterIP(dnsService)
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting kube-dns service, %w", err)
+	}
+	dnsServices, err := kubernetesInterface.CoreV1().Services("kube-system").List(ctx, metav1.ListOptions{LabelSelector: "k8s-app=kube-dns"})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("listing services labeled k8s-app=kube-dns, %w", err)
+	}
+	for i := range dnsServices.Items {
+		// Headless services, e.g. of a DNS deployment's metrics, don't have a cluster IP
+		if kubeDNSIP, err := clusterIP(&dnsServices.Items[i]); err == nil {
+			return kubeDNSIP, nil
+		}
 	}
-	kubeDNSIP := net.ParseIP(dnsService.Spec.ClusterIP)
-	if kubeDNSIP == nil {
-		return nil, fmt.Errorf("parsing cluster IP")
+	return nil, fmt.Errorf("kube-dns service not found and no service is labeled k8s-app=kube-dns")
+}
+
+// clusterIP returns the cluster IP of the service, which is of the primary IP family of dual-stack services
+func clusterIP(service *corev1.Service) (net.IP, error) {
+	ip := net.ParseIP(service.Spec.ClusterIP)
+	if ip == nil {
+		return nil, fmt.Errorf("parsing cluster IP %q of service %s", service.Spec.ClusterIP, service.Name)
 	}
-	return kubeDNSIP, nil
+	return ip, nil
 }
 
 func SetDurationAndExpiry(ctx context.Context, provider *stscreds.AssumeRoleProvider) {
@@ -20,6 +20,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -32,16 +34,101 @@ func init() {
 
 type optionsKey struct{}
 
+const (
+	// MetadataOptionsPolicyDefault applies the metadata settings to EC2NodeClasses that don't set the metadata options
+	MetadataOptionsPolicyDefault = "default"
+	// MetadataOptionsPolicyEnforce applies the metadata settings to all EC2NodeClasses, overriding their metadata options
+	MetadataOptionsPolicyEnforce = "enforce"
+)
+
 type Options struct {
 	AssumeRoleARN           string
 	AssumeRoleDuration      time.Duration
 	ClusterCABundle         string
 	ClusterName             string
 	ClusterEndpoint         string
+	ClusterDNS              string
 	IsolatedVPC             bool
 	VMMemoryOverheadPercent float64
-	InterruptionQueue       string
-	ReservedENIs            int
+	// VMMemoryOverheadPercentOverrides replaces VMMemoryOverheadPercent for specific instance types or instance families
+	VMMemoryOverheadPercentOverrides map[string]float64
+	InterruptionQueue                string
+	ReservedENIs                     int
+	AllocatableEstimation            bool
+	InterruptionQueueShared          bool
+	TargetGroupDeregistration        bool
+	// InstanceSelectionWeights maps the name of an instance selection scorer to its weight
+	InstanceSelectionWeights map[string]float64
+	// DeprioritizedInstanceTypes are the categories of instance types that are only launched when no other instance
+	// type is compatible
+	DeprioritizedInstanceTypes []string
+	AWSDNSSuffix               string
+	AWSFIPSEndpoints           bool
+	InstanceTypeSnapshotFile   string
+	// MaxLaunchTemplatesPerHour, MaxCreateFleetRequestsPerHour and MaxInstancesPerHour cap the EC2 resources that are
+	// created in any trailing hour. A value of 0 disables the cap.
+	MaxLaunchTemplatesPerHour     int
+	MaxCreateFleetRequestsPerHour int
+	MaxInstancesPerHour           int
+	// MaxConcurrentInterruptionDrains limits how many nodes are drained at once for spot interruption warnings. A
+	// value of 0 disables the limit.
+	MaxConcurrentInterruptionDrains int
+	VPCCNIWarmTargets               bool
+	BreakGlassDebug                 bool
+	TracingEndpoint                 string
+	TracingSampleRatio              float64
+	MetadataHTTPTokens              string
+	MetadataHTTPPutResponseHopLimit int
+	MetadataOptionsPolicy           string
+	OnDemandBackstop                bool
+	AdoptUnmanagedInstances         bool
+	VCPUQuotaReporting              bool
+	VCPUQuotaFiltering              bool
+	LaunchTemplateDataPatches       bool
+	CreateFleetBatchIdleDuration    time.Duration
+	CreateFleetBatchMaxDuration     time.Duration
+	CreateFleetBatchMaxItems        int
+	StuckInstanceDeadline           time.Duration
+	InterruptionDeadLetterQueue     string
+	InterruptionQueueMaxReceives    int
+	WarmPools                       bool
+	Hibernation                     bool
+	// InstanceTypeAllowList and InstanceTypeDenyList filter the instance types that Karpenter can launch, regardless of
+	// NodePool requirements
+	InstanceTypeAllowList           []string
+	InstanceTypeDenyList            []string
+	TerminationApproval             bool
+	TerminationNotificationEventBus string
+	LaunchAuditLog                  string
+	ZoneFailover                    bool
+	NodeClaimLaunchOverrides        bool
+	NodeRepair                      bool
+	NodeRepairRebootAttempts        int
+	NodeRepairRebootTimeout         time.Duration
+	ReadOnly                        bool
+	AMIDeprecationWarningWindow     time.Duration
+	AMIDeprecationStrict            bool
+	PodRestartCost                  bool
+	Prewarm                         bool
+	// LaunchTemplateGarbageCollectionAge is how old the launch templates of the cluster that aren't used anymore must be
+	// before they're deleted. If 0, they aren't garbage collected.
+	LaunchTemplateGarbageCollectionAge time.Duration
+	// OutpostInstancePrices maps instance types to the price that is used for their offerings on AWS Outposts, whose
+	// capacity isn't priced by the pricing API
+	OutpostInstancePrices map[string]float64
+	// ICEBackoffDurations maps capacity types to how long their offerings are unavailable after an insufficient capacity
+	// error, and ICEBackoffMaxDurations to how long this grows to while an offering keeps failing
+	ICEBackoffDurations    map[string]time.Duration
+	ICEBackoffMaxDurations map[string]time.Duration
+
+	vmMemoryOverheadPercentOverrides string
+	instanceSelectionWeights         string
+	deprioritizedInstanceTypes       string
+	instanceTypeAllowList            string
+	instanceTypeDenyList             string
+	outpostInstancePrices            string
+	iceBackoffDurations              string
+	iceBackoffMaxDurations           string
 }
 
 func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
@@ -50,10 +137,63 @@ func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
 	fs.StringVar(&o.ClusterCABundle, "cluster-ca-bundle", env.WithDefaultString("CLUSTER_CA_BUNDLE", ""), "Cluster CA bundle for nodes to use for TLS connections with the API server. If not set, this is taken from the controller's TLS configuration.")
 	fs.StringVar(&o.ClusterName, "cluster-name", env.WithDefaultString("CLUSTER_NAME", ""), "[REQUIRED] The kubernetes cluster name for resource discovery.")
 	fs.StringVar(&o.ClusterEndpoint, "cluster-endpoint", env.WithDefaultString("CLUSTER_ENDPOINT", ""), "The external kubernetes cluster endpoint for new nodes to connect with. If not specified, will discover the cluster endpoint using DescribeCluster API.")
+	fs.StringVar(&o.ClusterDNS, "cluster-dns", env.WithDefaultString("CLUSTER_DNS", ""), "The IP address of the cluster DNS service that nodes are configured with. If not set, this is discovered from the kube-dns service, or from the service labeled k8s-app=kube-dns, in the kube-system namespace. The clusterDNS of a NodePool's kubelet configuration takes precedence.")
 	fs.BoolVarWithEnv(&o.IsolatedVPC, "isolated-vpc", "ISOLATED_VPC", false, "If true, then assume we can't reach AWS services which don't have a VPC endpoint. This also has the effect of disabling look-ups to the AWS on-demand pricing endpoint.")
 	fs.Float64Var(&o.VMMemoryOverheadPercent, "vm-memory-overhead-percent", env.WithDefaultFloat64("VM_MEMORY_OVERHEAD_PERCENT", 0.075), "The VM memory overhead as a percent that will be subtracted from the total memory for all instance types.")
+	fs.StringVar(&o.vmMemoryOverheadPercentOverrides, "vm-memory-overhead-percent-overrides", env.WithDefaultString("VM_MEMORY_OVERHEAD_PERCENT_OVERRIDES", ""), "Comma separated list of instance types or instance families and the VM memory overhead percent to use for them instead of vm-memory-overhead-percent, e.g. t3=0.09,m5.24xlarge=0.05. Instance types take precedence over instance families.")
 	fs.StringVar(&o.InterruptionQueue, "interruption-queue", env.WithDefaultString("INTERRUPTION_QUEUE", ""), "Interruption queue is the name of the SQS queue used for processing interruption events from EC2. Interruption handling is disabled if not specified. Enabling interruption handling may require additional permissions on the controller service account. Additional permissions are outlined in the docs.")
 	fs.IntVar(&o.ReservedENIs, "reserved-enis", env.WithDefaultInt("RESERVED_ENIS", 0), "Reserved ENIs are not included in the calculations for max-pods or kube-reserved. This is most often used in the VPC CNI custom networking setup https://docs.aws.amazon.com/eks/latest/userguide/cni-custom-network.html.")
+	fs.BoolVarWithEnv(&o.AllocatableEstimation, "allocatable-estimation", "ALLOCATABLE_ESTIMATION", false, "If true, then continuously compare the capacity and allocatable of registered nodes with the instance type estimates, publish the estimation error as metrics, and record a suggested VM_MEMORY_OVERHEAD_PERCENT in the karpenter-allocatable-estimation ConfigMap.")
+	fs.BoolVarWithEnv(&o.InterruptionQueueShared, "interruption-queue-shared", "INTERRUPTION_QUEUE_SHARED", false, "If true, then the interruption queue is assumed to be shared with other clusters. Messages for instances that are tagged as owned by another cluster are returned to the queue instead of being deleted.")
+	fs.BoolVarWithEnv(&o.TargetGroupDeregistration, "target-group-deregistration", "TARGET_GROUP_DEREGISTRATION", false, "If true, then instances are deregistered from load balancer target groups when their NodeClaim starts terminating, and aren't terminated until the target groups' deregistration delay has elapsed.")
+	fs.StringVar(&o.instanceSelectionWeights, "instance-selection-weights", env.WithDefaultString("INSTANCE_SELECTION_WEIGHTS", ""), "Comma separated list of instance selection scorers and their weights used to prioritize the instance types and zones passed to CreateFleet, e.g. price=1,flexibility=0.5,interruption-risk=0.5,zone-balance=0.25. Scorers are price, flexibility, interruption-risk, zone-balance and zone-suitability. If not set, EC2 Fleet selects the lowest priced offering for on-demand and the lowest priced offering with the most capacity for spot.")
+	fs.StringVar(&o.deprioritizedInstanceTypes, "deprioritized-instance-types", env.WithDefaultString("DEPRIORITIZED_INSTANCE_TYPES", "metal,nvidia-gpu,amd-gpu,aws-neuron,habana-gaudi"), "Comma separated list of the categories of instance types that are only launched when no other instance type is compatible. Categories are metal, nvidia-gpu, amd-gpu, aws-neuron, habana-gaudi and xen. NodePools can override the list with the karpenter.k8s.aws/deprioritized-instance-types annotation.")
+	fs.StringVar(&o.AWSDNSSuffix, "aws-dns-suffix", env.WithDefaultString("AWS_DNS_SUFFIX", ""), "The DNS suffix of the AWS service endpoints, e.g. c2s.ic.gov. If set, Karpenter sends AWS API requests to <service>.<region>.<suffix>. If not set, the endpoints of the region's partition are used.")
+	fs.BoolVarWithEnv(&o.AWSFIPSEndpoints, "aws-fips-endpoints", "AWS_FIPS_ENDPOINTS", false, "If true, then Karpenter sends AWS API requests to the FIPS endpoints of each service, e.g. ec2-fips.us-east-1.amazonaws.com, as they're resolved by the AWS SDK. The pricing API doesn't have FIPS endpoints, so the on-demand prices that ship with Karpenter are used instead.")
+	fs.StringVar(&o.InstanceTypeSnapshotFile, "instance-type-snapshot-file", env.WithDefaultString("INSTANCE_TYPE_SNAPSHOT_FILE", ""), "Path to an instance type snapshot, optionally gzip compressed, that is generated with the instance-type-snapshot tool. If set, instance types, offerings and prices are read from the snapshot rather than discovered from the EC2 and pricing APIs.")
+	fs.IntVar(&o.MaxLaunchTemplatesPerHour, "max-launch-templates-per-hour", env.WithDefaultInt("MAX_LAUNCH_TEMPLATES_PER_HOUR", 0), "The maximum number of launch templates that Karpenter creates in any trailing hour. Once the cap is reached, launches fail with an event on the NodeClaim until the oldest creations age out. Set to 0 for no cap.")
+	fs.IntVar(&o.MaxCreateFleetRequestsPerHour, "max-create-fleet-requests-per-hour", env.WithDefaultInt("MAX_CREATE_FLEET_REQUESTS_PER_HOUR", 0), "The maximum number of CreateFleet requests that Karpenter creates in any trailing hour. Once the cap is reached, launches fail with an event on the NodeClaim until the oldest creations age out. Set to 0 for no cap.")
+	fs.IntVar(&o.MaxInstancesPerHour, "max-instances-per-hour", env.WithDefaultInt("MAX_INSTANCES_PER_HOUR", 0), "The maximum number of instances that Karpenter creates in any trailing hour. Once the cap is reached, launches fail with an event on the NodeClaim until the oldest creations age out. Set to 0 for no cap.")
+	fs.IntVar(&o.MaxConcurrentInterruptionDrains, "max-concurrent-interruption-drains", env.WithDefaultInt("MAX_CONCURRENT_INTERRUPTION_DRAINS", 0), "The maximum number of nodes that are drained at once because of spot interruption warnings. Nodes past their interruption deadline are always drained. Requires interruption-queue. Set to 0 for no limit.")
+	fs.BoolVarWithEnv(&o.VPCCNIWarmTargets, "vpc-cni-warm-targets", "VPC_CNI_WARM_TARGETS", false, "If true, then the WARM_IP_TARGET and MINIMUM_IP_TARGET of the aws-node DaemonSet are read, and the pod density of instance types is limited to the IPs of their ENIs, reduced so that the VPC CNI can keep WARM_IP_TARGET IPs warm on full nodes, unless prefix delegation is enabled. Subnet IP usage of launches is projected with the warm IPs included.")
+	fs.BoolVarWithEnv(&o.BreakGlassDebug, "break-glass-debug", "BREAK_GLASS_DEBUG", false, "If true, then NodeClaims annotated with karpenter.k8s.aws/debug: \"true\" are granted temporary access to their instance through Session Manager, or through an EC2 Instance Connect Endpoint that Karpenter creates if the SSM agent isn't online. The connection info is reported in an event on the NodeClaim.")
+	fs.StringVar(&o.TracingEndpoint, "tracing-endpoint", env.WithDefaultString("TRACING_ENDPOINT", ""), "The host:port of an OTLP gRPC collector that spans of controller reconciles, AWS API calls and API server writes are exported to. Tracing is disabled if unset. OTEL_EXPORTER_OTLP_* environment variables configure the exporter further.")
+	fs.Float64Var(&o.TracingSampleRatio, "tracing-sample-ratio", env.WithDefaultFloat64("TRACING_SAMPLE_RATIO", 1), "The ratio of traces that are sampled when tracing-endpoint is set, between 0 and 1. Traces that are started by a sampled parent are always sampled.")
+	fs.StringVar(&o.MetadataHTTPTokens, "metadata-http-tokens", env.WithDefaultString("METADATA_HTTP_TOKENS", ""), "The httpTokens instance metadata option (required or optional) of launched instances. Applied to EC2NodeClasses that don't set it, or to all EC2NodeClasses if metadata-options-policy is enforce. Not applied if unset.")
+	fs.IntVar(&o.MetadataHTTPPutResponseHopLimit, "metadata-http-put-response-hop-limit", env.WithDefaultInt("METADATA_HTTP_PUT_RESPONSE_HOP_LIMIT", 0), "The httpPutResponseHopLimit instance metadata option (1 to 64) of launched instances. Applied to EC2NodeClasses that don't set it, or to all EC2NodeClasses if metadata-options-policy is enforce. Set to 0 to not apply it.")
+	fs.StringVar(&o.MetadataOptionsPolicy, "metadata-options-policy", env.WithDefaultString("METADATA_OPTIONS_POLICY", MetadataOptionsPolicyDefault), "How metadata-http-tokens and metadata-http-put-response-hop-limit are applied. With default, they only apply to EC2NodeClasses that don't set the option. With enforce, they override the EC2NodeClass and a warning event is published on EC2NodeClasses whose options are overridden.")
+	fs.BoolVarWithEnv(&o.OnDemandBackstop, "on-demand-backstop", "ON_DEMAND_BACKSTOP", false, "If true, then a spot launch that fails for lack of capacity is immediately retried as on-demand when the NodeClaim allows on-demand, rather than waiting for the next provisioning loop.")
+	fs.BoolVarWithEnv(&o.AdoptUnmanagedInstances, "adopt-unmanaged-instances", "ADOPT_UNMANAGED_INSTANCES", false, "If true, then instances that are tagged as managed by the cluster but that don't have a NodeClaim, such as after an etcd restore, are adopted by recreating their NodeClaims from their NodePools rather than being garbage collected.")
+	fs.BoolVarWithEnv(&o.VCPUQuotaReporting, "vcpu-quota-reporting", "VCPU_QUOTA_REPORTING", false, "If true, then the vCPU usage of the account is periodically compared with its EC2 vCPU service quotas, exported as metrics, and pending pods are warned when launching capacity for them is likely to be rejected for quota.")
+	fs.BoolVarWithEnv(&o.VCPUQuotaFiltering, "vcpu-quota-filtering", "VCPU_QUOTA_FILTERING", false, "If true, then offerings of instance types with more vCPUs than are left under the EC2 vCPU service quota of their capacity type and instance family class aren't launched, rather than failing with VcpuLimitExceeded. Requires vcpu-quota-reporting.")
+	fs.BoolVarWithEnv(&o.LaunchTemplateDataPatches, "launch-template-data-patches", "LAUNCH_TEMPLATE_DATA_PATCHES", false, "If true, then EC2NodeClasses annotated with karpenter.k8s.aws/launch-template-data-patch have that JSON patch applied to the data of their launch templates, for EC2 features that EC2NodeClasses don't model yet.")
+	fs.DurationVar(&o.CreateFleetBatchIdleDuration, "create-fleet-batch-idle-duration", env.WithDefaultDuration("CREATE_FLEET_BATCH_IDLE_DURATION", 35*time.Millisecond), "The maximum amount of time with no new launches that if exceeded ends the current CreateFleet batching window. If launches arrive faster than this time, the batching window will be extended up to the create-fleet-batch-max-duration.")
+	fs.DurationVar(&o.CreateFleetBatchMaxDuration, "create-fleet-batch-max-duration", env.WithDefaultDuration("CREATE_FLEET_BATCH_MAX_DURATION", time.Second), "The maximum length of a CreateFleet batching window. The longer this is, the more launches can be batched into a single CreateFleet request, at the cost of launch latency.")
+	fs.IntVar(&o.CreateFleetBatchMaxItems, "create-fleet-batch-max-items", env.WithDefaultInt("CREATE_FLEET_BATCH_MAX_ITEMS", 1000), "The maximum number of instances that are launched by a single batched CreateFleet request.")
+	fs.DurationVar(&o.StuckInstanceDeadline, "stuck-instance-deadline", env.WithDefaultDuration("STUCK_INSTANCE_DEADLINE", 0), "How long an instance can be pending before it's terminated and its NodeClaim is deleted so that it's replaced. If not set, pending instances are never terminated.")
+	fs.StringVar(&o.InterruptionDeadLetterQueue, "interruption-dead-letter-queue", env.WithDefaultString("INTERRUPTION_DEAD_LETTER_QUEUE", ""), "The name of the SQS queue that quarantined interruption messages are forwarded to before they're deleted from the interruption queue. Requires interruption-queue. If not set, quarantined messages are only logged.")
+	fs.IntVar(&o.InterruptionQueueMaxReceives, "interruption-queue-max-receives", env.WithDefaultInt("INTERRUPTION_QUEUE_MAX_RECEIVES", 0), "The number of times an interruption message is received without being handled before it's quarantined, i.e. logged, forwarded to the interruption-dead-letter-queue if it's set, and deleted from the interruption queue. Messages that can't be parsed are quarantined when they're first received. If not set, messages that can be parsed are retried until they expire from the queue.")
+	fs.BoolVarWithEnv(&o.WarmPools, "warm-pools", "WARM_POOLS", false, "If true, then NodePools annotated with karpenter.k8s.aws/warm-pool-size keep that many instances launched, bootstrapped and then stopped, and provisioning starts one of these before launching a new instance.")
+	fs.BoolVarWithEnv(&o.Hibernation, "hibernation", "HIBERNATION", false, "If true, then the on-demand instances of NodeClaims annotated with karpenter.k8s.aws/hibernation=enabled are launched with hibernation configured when their instance type and volumes support it, are hibernated rather than terminated when the NodeClaim is deleted, e.g. by consolidation, and are resumed for the next NodeClaim of their NodePool before launching a new instance.")
+	fs.StringVar(&o.instanceTypeAllowList, "instance-type-allow-list", env.WithDefaultString("INSTANCE_TYPE_ALLOW_LIST", ""), "Comma separated list of the instance types that Karpenter may launch, regardless of NodePool requirements. Entries are instance types, instance families, glob patterns of either (e.g. m5.*, c7*), or the metal, burstable and previous-generation classes. If not set, all instance types are allowed.")
+	fs.StringVar(&o.instanceTypeDenyList, "instance-type-deny-list", env.WithDefaultString("INSTANCE_TYPE_DENY_LIST", ""), "Comma separated list of the instance types that Karpenter never launches, regardless of NodePool requirements. Entries are instance types, instance families, glob patterns of either (e.g. m5.*, c7*), or the metal, burstable and previous-generation classes. The deny list takes precedence over the allow list.")
+	fs.BoolVarWithEnv(&o.TerminationApproval, "termination-approval", "TERMINATION_APPROVAL", false, "If true, then the instances of drained NodeClaims aren't terminated until the NodeClaim is annotated with karpenter.k8s.aws/termination-approved=true, so that external tooling can checkpoint them first.")
+	fs.StringVar(&o.TerminationNotificationEventBus, "termination-notification-event-bus", env.WithDefaultString("TERMINATION_NOTIFICATION_EVENT_BUS", ""), "The name or ARN of an EventBridge event bus that an event is put on when the instance of a NodeClaim has been drained and is about to be terminated.")
+	fs.StringVar(&o.LaunchAuditLog, "launch-audit-log", env.WithDefaultString("LAUNCH_AUDIT_LOG", ""), "The path of a file, or an s3://bucket/prefix URL, that a JSON record of every CreateFleet request, the instances it launched and the errors it returned is written to. If not set, launches aren't audited.")
+	fs.BoolVarWithEnv(&o.ZoneFailover, "zone-failover", "ZONE_FAILOVER", false, "If true, then zones that EC2 reports as impaired, or where launches of several instance families fail for lack of capacity within a few minutes, are temporarily removed from the offerings so that provisioning shifts to the healthy zones.")
+	fs.BoolVarWithEnv(&o.NodeClaimLaunchOverrides, "nodeclaim-launch-overrides", "NODECLAIM_LAUNCH_OVERRIDES", false, "If true, then NodeClaims annotated with karpenter.k8s.aws/ami-id-override or karpenter.k8s.aws/instance-profile-override are launched with that AMI or instance profile instead of the ones of their EC2NodeClass, e.g. to test a new AMI on a single node.")
+	fs.BoolVarWithEnv(&o.NodeRepair, "node-repair", "NODE_REPAIR", false, "If true, then the instances of nodes that stay NotReady are rebooted, and the NodeClaims of nodes that a reboot doesn't recover are deleted so that they're replaced.")
+	fs.IntVar(&o.NodeRepairRebootAttempts, "node-repair-reboot-attempts", env.WithDefaultInt("NODE_REPAIR_REBOOT_ATTEMPTS", 1), "The number of times that the instance of a NotReady node is rebooted before its NodeClaim is replaced, when node repair is enabled. Set to 0 to replace NotReady nodes without rebooting them.")
+	fs.DurationVar(&o.NodeRepairRebootTimeout, "node-repair-reboot-timeout", env.WithDefaultDuration("NODE_REPAIR_REBOOT_TIMEOUT", 5*time.Minute), "How long a rebooted node has to become Ready again before it's rebooted again or replaced, when node repair is enabled.")
+	fs.BoolVarWithEnv(&o.ReadOnly, "read-only", "READ_ONLY", false, "If true, then Karpenter doesn't call mutating AWS APIs. The launches and terminations that it would have performed are logged, counted by metrics and published as events on their NodeClaims instead, and nodes aren't disrupted, so that its decisions can be evaluated against a cluster that another autoscaler manages.")
+	fs.DurationVar(&o.AMIDeprecationWarningWindow, "ami-deprecation-warning-window", env.WithDefaultDuration("AMI_DEPRECATION_WARNING_WINDOW", 14*24*time.Hour), "How long before the deprecation of an AMI that an EC2NodeClass uses a warning event is published on the EC2NodeClass. A warning event is always published once the AMI is deprecated.")
+	fs.BoolVarWithEnv(&o.AMIDeprecationStrict, "ami-deprecation-strict", "AMI_DEPRECATION_STRICT", false, "If true, then deprecated AMIs are removed from the status of the EC2NodeClasses that select them, so that no instances are launched with them. EC2NodeClasses whose AMIs are all deprecated aren't ready.")
+	fs.BoolVarWithEnv(&o.PodRestartCost, "pod-restart-cost", "POD_RESTART_COST", false, "If true, then the karpenter.k8s.aws/restart-cost annotation of pods and namespaces is translated into the controller.kubernetes.io/pod-deletion-cost of pods, so that consolidation disrupts the nodes of pods that are slow to restart last. The pod deletion cost also changes which pods ReplicaSets delete first when they scale down.")
+	fs.BoolVarWithEnv(&o.Prewarm, "prewarm", "PREWARM", false, "If true, then Karpenter holds capacity with placeholder pods for NodePools annotated with a karpenter.k8s.aws/prewarm-schedule while each scheduled window is active.")
+	fs.DurationVar(&o.LaunchTemplateGarbageCollectionAge, "launch-template-garbage-collection-age", env.WithDefaultDuration("LAUNCH_TEMPLATE_GARBAGE_COLLECTION_AGE", 0), "If set, then the launch templates tagged with the cluster name that are older than this, aren't cached and that no instance references are deleted, e.g. 168h. These are leaked when a cluster is deleted and recreated without uninstalling Karpenter. If not set, launch templates are only deleted as they expire from the cache.")
+	fs.StringVar(&o.outpostInstancePrices, "outpost-instance-prices", env.WithDefaultString("OUTPOST_INSTANCE_PRICES", ""), "Comma separated list of instance types and the hourly price of their offerings on AWS Outposts, e.g. m5.xlarge=0.1,c5.2xlarge=0.2. Outpost capacity isn't priced by the pricing API, so instance types that aren't listed are offered on Outposts at a price of 0.")
+	fs.StringVar(&o.iceBackoffDurations, "ice-backoff-durations", env.WithDefaultString("ICE_BACKOFF_DURATIONS", ""), "Comma separated list of capacity types and how long their offerings are unavailable after an insufficient capacity error, e.g. spot=5m,on-demand=10m. Capacity types that aren't listed are unavailable for 3m.")
+	fs.StringVar(&o.iceBackoffMaxDurations, "ice-backoff-max-durations", env.WithDefaultString("ICE_BACKOFF_MAX_DURATIONS", ""), "Comma separated list of capacity types and the longest that their offerings are unavailable after repeated insufficient capacity errors, e.g. on-demand=1h. The backoff of an offering doubles each time it fails again within twice its last backoff, up to this duration. Capacity types that aren't listed don't back off exponentially.")
 }
 
 func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
@@ -63,6 +203,34 @@ func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
 		}
 		return fmt.Errorf("parsing flags, %w", err)
 	}
+	overrides, err := ParseVMMemoryOverheadPercentOverrides(o.vmMemoryOverheadPercentOverrides)
+	if err != nil {
+		return fmt.Errorf("parsing vm-memory-overhead-percent-overrides, %w", err)
+	}
+	o.VMMemoryOverheadPercentOverrides = overrides
+	weights, err := ParseInstanceSelectionWeights(o.instanceSelectionWeights)
+	if err != nil {
+		return fmt.Errorf("parsing instance-selection-weights, %w", err)
+	}
+	o.InstanceSelectionWeights = weights
+	o.DeprioritizedInstanceTypes = ParseDeprioritizedInstanceTypes(o.deprioritizedInstanceTypes)
+	o.InstanceTypeAllowList = parseList(o.instanceTypeAllowList)
+	o.InstanceTypeDenyList = parseList(o.instanceTypeDenyList)
+	prices, err := ParseOutpostInstancePrices(o.outpostInstancePrices)
+	if err != nil {
+		return fmt.Errorf("parsing outpost-instance-prices, %w", err)
+	}
+	o.OutpostInstancePrices = prices
+	backoffs, err := ParseICEBackoffDurations(o.iceBackoffDurations)
+	if err != nil {
+		return fmt.Errorf("parsing ice-backoff-durations, %w", err)
+	}
+	o.ICEBackoffDurations = backoffs
+	maxBackoffs, err := ParseICEBackoffDurations(o.iceBackoffMaxDurations)
+	if err != nil {
+		return fmt.Errorf("parsing ice-backoff-max-durations, %w", err)
+	}
+	o.ICEBackoffMaxDurations = maxBackoffs
 	if err := o.Validate(); err != nil {
 		return fmt.Errorf("validating options, %w", err)
 	}
@@ -73,6 +241,75 @@ func (o *Options) ToContext(ctx context.Context) context.Context {
 	return ToContext(ctx, o)
 }
 
+// ParseVMMemoryOverheadPercentOverrides parses a comma separated list of <instance type or family>=<percent> pairs
+func ParseVMMemoryOverheadPercentOverrides(str string) (map[string]float64, error) {
+	return parseFloatPairs(str, "instance type or family", "percent")
+}
+
+// ParseInstanceSelectionWeights parses a comma separated list of <scorer>=<weight> pairs
+func ParseInstanceSelectionWeights(str string) (map[string]float64, error) {
+	return parseFloatPairs(str, "scorer", "weight")
+}
+
+// ParseOutpostInstancePrices parses a comma separated list of <instance type>=<price> pairs
+func ParseOutpostInstancePrices(str string) (map[string]float64, error) {
+	return parseFloatPairs(str, "instance type", "price")
+}
+
+// ParseICEBackoffDurations parses a comma separated list of <capacity type>=<duration> pairs
+func ParseICEBackoffDurations(str string) (map[string]time.Duration, error) {
+	pairs := map[string]time.Duration{}
+	for _, pair := range strings.Split(str, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected <capacity type>=<duration>, got %q", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("parsing duration for %q, %w", key, err)
+		}
+		pairs[strings.TrimSpace(key)] = d
+	}
+	return pairs, nil
+}
+
+// ParseDeprioritizedInstanceTypes parses a comma separated list of instance type categories
+func ParseDeprioritizedInstanceTypes(str string) []string {
+	return parseList(str)
+}
+
+func parseList(str string) []string {
+	values := []string{}
+	for _, value := range strings.Split(str, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func parseFloatPairs(str string, keyName string, valueName string) (map[string]float64, error) {
+	pairs := map[string]float64{}
+	for _, pair := range strings.Split(str, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected <%s>=<%s>, got %q", keyName, valueName, pair)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s for %q, %w", valueName, key, err)
+		}
+		pairs[strings.TrimSpace(key)] = f
+	}
+	return pairs, nil
+}
+
 func ToContext(ctx context.Context, opts *Options) context.Context {
 	return context.WithValue(ctx, optionsKey{}, opts)
 }
@@ -16,18 +16,53 @@ package options
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"path"
+	"strings"
 	"time"
 
 	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
+// instanceSelectionScorers are the scorers that can be weighted through instance-selection-weights
+var instanceSelectionScorers = sets.New("price", "flexibility", "interruption-risk", "zone-balance", "zone-suitability")
+
+// metadataHTTPTokens are the values of metadata-http-tokens, where an empty value doesn't apply the setting
+var metadataHTTPTokens = sets.New("", "required", "optional")
+
+// instanceTypeCategories are the categories of instance types that can be deprioritized through
+// deprioritized-instance-types
+var instanceTypeCategories = sets.New("metal", "nvidia-gpu", "amd-gpu", "aws-neuron", "habana-gaudi", "xen")
+
+// iceBackoffCapacityTypes are the capacity types that can be configured through ice-backoff-durations and
+// ice-backoff-max-durations
+var iceBackoffCapacityTypes = sets.New("spot", "on-demand")
+
 func (o Options) Validate() error {
 	return multierr.Combine(
 		o.validateEndpoint(),
+		o.validateClusterDNS(),
 		o.validateVMMemoryOverheadPercent(),
 		o.validateAssumeRoleDuration(),
 		o.validateReservedENIs(),
+		o.validateInstanceSelectionWeights(),
+		o.validateDeprioritizedInstanceTypes(),
+		o.validateInstanceTypeLists(),
+		o.validateLaunchAuditLog(),
+		o.validateCreationLimits(),
+		o.validateMaxConcurrentInterruptionDrains(),
+		o.validateInterruptionQuarantine(),
+		o.validateNodeRepair(),
+		o.validateStuckInstanceDeadline(),
+		o.validateCreateFleetBatching(),
+		o.validateLaunchTemplateGarbageCollectionAge(),
+		o.validateVCPUQuotaFiltering(),
+		o.validateOutpostInstancePrices(),
+		o.validateICEBackoffDurations(),
+		o.validateTracingSampleRatio(),
+		o.validateMetadataOptions(),
 		o.validateRequiredFields(),
 	)
 }
@@ -52,10 +87,22 @@ func (o Options) validateEndpoint() error {
 	return nil
 }
 
+func (o Options) validateClusterDNS() error {
+	if o.ClusterDNS != "" && net.ParseIP(o.ClusterDNS) == nil {
+		return fmt.Errorf("%q is not a valid cluster-dns IP address", o.ClusterDNS)
+	}
+	return nil
+}
+
 func (o Options) validateVMMemoryOverheadPercent() error {
 	if o.VMMemoryOverheadPercent < 0 {
 		return fmt.Errorf("vm-memory-overhead-percent cannot be negative")
 	}
+	for key, percent := range o.VMMemoryOverheadPercentOverrides {
+		if percent < 0 || percent >= 1 {
+			return fmt.Errorf("vm-memory-overhead-percent-overrides for %q must be in the range [0, 1)", key)
+		}
+	}
 	return nil
 }
 
@@ -66,6 +113,173 @@ func (o Options) validateReservedENIs() error {
 	return nil
 }
 
+func (o Options) validateCreationLimits() (errs error) {
+	for flag, limit := range map[string]int{
+		"max-launch-templates-per-hour":      o.MaxLaunchTemplatesPerHour,
+		"max-create-fleet-requests-per-hour": o.MaxCreateFleetRequestsPerHour,
+		"max-instances-per-hour":             o.MaxInstancesPerHour,
+	} {
+		if limit < 0 {
+			errs = multierr.Append(errs, fmt.Errorf("%s cannot be negative", flag))
+		}
+	}
+	return errs
+}
+
+func (o Options) validateMaxConcurrentInterruptionDrains() error {
+	if o.MaxConcurrentInterruptionDrains < 0 {
+		return fmt.Errorf("max-concurrent-interruption-drains cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateInterruptionQuarantine() (errs error) {
+	if o.InterruptionQueueMaxReceives < 0 {
+		errs = multierr.Append(errs, fmt.Errorf("interruption-queue-max-receives cannot be negative"))
+	}
+	if o.InterruptionDeadLetterQueue != "" && o.InterruptionQueue == "" {
+		errs = multierr.Append(errs, fmt.Errorf("interruption-dead-letter-queue requires interruption-queue"))
+	}
+	return errs
+}
+
+func (o Options) validateNodeRepair() (errs error) {
+	if o.NodeRepairRebootAttempts < 0 {
+		errs = multierr.Append(errs, fmt.Errorf("node-repair-reboot-attempts cannot be negative"))
+	}
+	if o.NodeRepairRebootTimeout <= 0 {
+		errs = multierr.Append(errs, fmt.Errorf("node-repair-reboot-timeout must be positive"))
+	}
+	return errs
+}
+
+func (o Options) validateStuckInstanceDeadline() error {
+	if o.StuckInstanceDeadline < 0 {
+		return fmt.Errorf("stuck-instance-deadline cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateCreateFleetBatching() (errs error) {
+	if o.CreateFleetBatchIdleDuration <= 0 {
+		errs = multierr.Append(errs, fmt.Errorf("create-fleet-batch-idle-duration must be positive"))
+	}
+	if o.CreateFleetBatchMaxDuration < o.CreateFleetBatchIdleDuration {
+		errs = multierr.Append(errs, fmt.Errorf("create-fleet-batch-max-duration cannot be less than create-fleet-batch-idle-duration"))
+	}
+	if o.CreateFleetBatchMaxItems <= 0 {
+		errs = multierr.Append(errs, fmt.Errorf("create-fleet-batch-max-items must be positive"))
+	}
+	return errs
+}
+
+func (o Options) validateLaunchTemplateGarbageCollectionAge() error {
+	if o.LaunchTemplateGarbageCollectionAge < 0 {
+		return fmt.Errorf("launch-template-garbage-collection-age cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateVCPUQuotaFiltering() error {
+	if o.VCPUQuotaFiltering && !o.VCPUQuotaReporting {
+		return fmt.Errorf("vcpu-quota-filtering requires vcpu-quota-reporting")
+	}
+	return nil
+}
+
+func (o Options) validateOutpostInstancePrices() error {
+	for instanceType, price := range o.OutpostInstancePrices {
+		if price < 0 {
+			return fmt.Errorf("outpost-instance-prices for %q cannot be negative", instanceType)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateICEBackoffDurations() error {
+	for name, durations := range map[string]map[string]time.Duration{
+		"ice-backoff-durations":     o.ICEBackoffDurations,
+		"ice-backoff-max-durations": o.ICEBackoffMaxDurations,
+	} {
+		for capacityType, d := range durations {
+			if !iceBackoffCapacityTypes.Has(capacityType) {
+				return fmt.Errorf("%s has an unknown capacity type %q, expected one of %v", name, capacityType, sets.List(iceBackoffCapacityTypes))
+			}
+			if d <= 0 {
+				return fmt.Errorf("%s for %q must be positive", name, capacityType)
+			}
+		}
+	}
+	for capacityType, maxBackoff := range o.ICEBackoffMaxDurations {
+		if backoff, ok := o.ICEBackoffDurations[capacityType]; ok && maxBackoff < backoff {
+			return fmt.Errorf("ice-backoff-max-durations for %q cannot be less than its ice-backoff-durations", capacityType)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateTracingSampleRatio() error {
+	if o.TracingSampleRatio < 0 || o.TracingSampleRatio > 1 {
+		return fmt.Errorf("tracing-sample-ratio must be in the range [0, 1]")
+	}
+	return nil
+}
+
+func (o Options) validateMetadataOptions() (errs error) {
+	if !metadataHTTPTokens.Has(o.MetadataHTTPTokens) {
+		errs = multierr.Append(errs, fmt.Errorf("metadata-http-tokens must be one of required or optional"))
+	}
+	if o.MetadataHTTPPutResponseHopLimit < 0 || o.MetadataHTTPPutResponseHopLimit > 64 {
+		errs = multierr.Append(errs, fmt.Errorf("metadata-http-put-response-hop-limit must be in the range [1, 64], or 0 to not apply it"))
+	}
+	if o.MetadataOptionsPolicy != MetadataOptionsPolicyDefault && o.MetadataOptionsPolicy != MetadataOptionsPolicyEnforce {
+		errs = multierr.Append(errs, fmt.Errorf("metadata-options-policy must be one of %s or %s", MetadataOptionsPolicyDefault, MetadataOptionsPolicyEnforce))
+	}
+	return errs
+}
+
+func (o Options) validateInstanceSelectionWeights() error {
+	for scorer, weight := range o.InstanceSelectionWeights {
+		if !instanceSelectionScorers.Has(scorer) {
+			return fmt.Errorf("instance-selection-weights contains unknown scorer %q, expected one of %v", scorer, sets.List(instanceSelectionScorers))
+		}
+		if weight < 0 {
+			return fmt.Errorf("instance-selection-weights for %q cannot be negative", scorer)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateDeprioritizedInstanceTypes() error {
+	for _, category := range o.DeprioritizedInstanceTypes {
+		if !instanceTypeCategories.Has(category) {
+			return fmt.Errorf("deprioritized-instance-types contains unknown category %q, expected one of %v", category, sets.List(instanceTypeCategories))
+		}
+	}
+	return nil
+}
+
+func (o Options) validateInstanceTypeLists() error {
+	for flag, list := range map[string][]string{"instance-type-allow-list": o.InstanceTypeAllowList, "instance-type-deny-list": o.InstanceTypeDenyList} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s contains invalid pattern %q, %w", flag, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (o Options) validateLaunchAuditLog() error {
+	if !strings.HasPrefix(o.LaunchAuditLog, "s3://") {
+		return nil
+	}
+	if u, err := url.Parse(o.LaunchAuditLog); err != nil || u.Host == "" {
+		return fmt.Errorf("launch-audit-log %q must be a path or an s3://bucket/prefix URL", o.LaunchAuditLog)
+	}
+	return nil
+}
+
 func (o Options) validateRequiredFields() error {
 	if o.ClusterName == "" {
 		return fmt.Errorf("missing field, cluster-name")
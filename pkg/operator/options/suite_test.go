@@ -62,21 +62,127 @@ var _ = Describe("Options", func() {
 			"--cluster-ca-bundle", "env-bundle",
 			"--cluster-name", "env-cluster",
 			"--cluster-endpoint", "https://env-cluster",
+			"--cluster-dns", "10.0.0.10",
 			"--isolated-vpc",
 			"--vm-memory-overhead-percent", "0.1",
+			"--vm-memory-overhead-percent-overrides", "t3=0.09,m5.24xlarge=0.05",
 			"--interruption-queue", "env-cluster",
-			"--reserved-enis", "10")
+			"--reserved-enis", "10",
+			"--allocatable-estimation",
+			"--interruption-queue-shared",
+			"--target-group-deregistration",
+			"--instance-selection-weights", "price=1,zone-balance=0.5",
+			"--deprioritized-instance-types", "metal,xen",
+			"--aws-dns-suffix", "c2s.ic.gov",
+			"--aws-fips-endpoints",
+			"--instance-type-snapshot-file", "/etc/karpenter/snapshot.json.gz",
+			"--max-launch-templates-per-hour", "20",
+			"--max-create-fleet-requests-per-hour", "500",
+			"--max-instances-per-hour", "200",
+			"--max-concurrent-interruption-drains", "5",
+			"--vpc-cni-warm-targets",
+			"--break-glass-debug",
+			"--tracing-endpoint", "otel-collector:4317",
+			"--tracing-sample-ratio", "0.5",
+			"--metadata-http-tokens", "required",
+			"--metadata-http-put-response-hop-limit", "1",
+			"--metadata-options-policy", "enforce",
+			"--on-demand-backstop",
+			"--adopt-unmanaged-instances",
+			"--vcpu-quota-reporting",
+			"--vcpu-quota-filtering",
+			"--launch-template-data-patches",
+			"--create-fleet-batch-idle-duration", "50ms",
+			"--create-fleet-batch-max-duration", "2s",
+			"--create-fleet-batch-max-items", "500",
+			"--stuck-instance-deadline", "20m",
+			"--interruption-dead-letter-queue", "dlq",
+			"--interruption-queue-max-receives", "3",
+			"--warm-pools",
+			"--hibernation",
+			"--instance-type-allow-list", "m5,c5",
+			"--instance-type-deny-list", "metal,t*",
+			"--termination-approval",
+			"--termination-notification-event-bus", "termination",
+			"--launch-audit-log", "/var/log/karpenter/launches.jsonl",
+			"--zone-failover",
+			"--nodeclaim-launch-overrides",
+			"--node-repair",
+			"--node-repair-reboot-attempts", "2",
+			"--node-repair-reboot-timeout", "10m",
+			"--read-only",
+			"--ami-deprecation-warning-window", "72h",
+			"--ami-deprecation-strict",
+			"--pod-restart-cost",
+			"--prewarm",
+			"--launch-template-garbage-collection-age", "168h",
+			"--outpost-instance-prices", "m5.xlarge=0.1,c5.2xlarge=0.2",
+			"--ice-backoff-durations", "spot=5m,on-demand=10m",
+			"--ice-backoff-max-durations", "on-demand=1h")
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
-			AssumeRoleARN:           lo.ToPtr("env-role"),
-			AssumeRoleDuration:      lo.ToPtr(20 * time.Minute),
-			ClusterCABundle:         lo.ToPtr("env-bundle"),
-			ClusterName:             lo.ToPtr("env-cluster"),
-			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
-			IsolatedVPC:             lo.ToPtr(true),
-			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
-			InterruptionQueue:       lo.ToPtr("env-cluster"),
-			ReservedENIs:            lo.ToPtr(10),
+			AssumeRoleARN:                      lo.ToPtr("env-role"),
+			AssumeRoleDuration:                 lo.ToPtr(20 * time.Minute),
+			ClusterCABundle:                    lo.ToPtr("env-bundle"),
+			ClusterName:                        lo.ToPtr("env-cluster"),
+			ClusterEndpoint:                    lo.ToPtr("https://env-cluster"),
+			ClusterDNS:                         lo.ToPtr("10.0.0.10"),
+			IsolatedVPC:                        lo.ToPtr(true),
+			VMMemoryOverheadPercent:            lo.ToPtr[float64](0.1),
+			VMMemoryOverheadPercentOverrides:   map[string]float64{"t3": 0.09, "m5.24xlarge": 0.05},
+			InterruptionQueue:                  lo.ToPtr("env-cluster"),
+			ReservedENIs:                       lo.ToPtr(10),
+			AllocatableEstimation:              lo.ToPtr(true),
+			InterruptionQueueShared:            lo.ToPtr(true),
+			TargetGroupDeregistration:          lo.ToPtr(true),
+			InstanceSelectionWeights:           map[string]float64{"price": 1, "zone-balance": 0.5},
+			DeprioritizedInstanceTypes:         []string{"metal", "xen"},
+			AWSDNSSuffix:                       lo.ToPtr("c2s.ic.gov"),
+			AWSFIPSEndpoints:                   lo.ToPtr(true),
+			InstanceTypeSnapshotFile:           lo.ToPtr("/etc/karpenter/snapshot.json.gz"),
+			MaxLaunchTemplatesPerHour:          lo.ToPtr(20),
+			MaxCreateFleetRequestsPerHour:      lo.ToPtr(500),
+			MaxInstancesPerHour:                lo.ToPtr(200),
+			MaxConcurrentInterruptionDrains:    lo.ToPtr(5),
+			VPCCNIWarmTargets:                  lo.ToPtr(true),
+			BreakGlassDebug:                    lo.ToPtr(true),
+			TracingEndpoint:                    lo.ToPtr("otel-collector:4317"),
+			TracingSampleRatio:                 lo.ToPtr(0.5),
+			MetadataHTTPTokens:                 lo.ToPtr("required"),
+			MetadataHTTPPutResponseHopLimit:    lo.ToPtr(1),
+			MetadataOptionsPolicy:              lo.ToPtr("enforce"),
+			OnDemandBackstop:                   lo.ToPtr(true),
+			AdoptUnmanagedInstances:            lo.ToPtr(true),
+			VCPUQuotaReporting:                 lo.ToPtr(true),
+			VCPUQuotaFiltering:                 lo.ToPtr(true),
+			LaunchTemplateDataPatches:          lo.ToPtr(true),
+			CreateFleetBatchIdleDuration:       lo.ToPtr(50 * time.Millisecond),
+			CreateFleetBatchMaxDuration:        lo.ToPtr(2 * time.Second),
+			CreateFleetBatchMaxItems:           lo.ToPtr(500),
+			StuckInstanceDeadline:              lo.ToPtr(20 * time.Minute),
+			InterruptionDeadLetterQueue:        lo.ToPtr("dlq"),
+			InterruptionQueueMaxReceives:       lo.ToPtr(3),
+			WarmPools:                          lo.ToPtr(true),
+			Hibernation:                        lo.ToPtr(true),
+			InstanceTypeAllowList:              []string{"m5", "c5"},
+			InstanceTypeDenyList:               []string{"metal", "t*"},
+			TerminationApproval:                lo.ToPtr(true),
+			TerminationNotificationEventBus:    lo.ToPtr("termination"),
+			LaunchAuditLog:                     lo.ToPtr("/var/log/karpenter/launches.jsonl"),
+			ZoneFailover:                       lo.ToPtr(true),
+			NodeClaimLaunchOverrides:           lo.ToPtr(true),
+			NodeRepair:                         lo.ToPtr(true),
+			NodeRepairRebootAttempts:           lo.ToPtr(2),
+			NodeRepairRebootTimeout:            lo.ToPtr(10 * time.Minute),
+			ReadOnly:                           lo.ToPtr(true),
+			AMIDeprecationWarningWindow:        lo.ToPtr(72 * time.Hour),
+			AMIDeprecationStrict:               lo.ToPtr(true),
+			PodRestartCost:                     lo.ToPtr(true),
+			Prewarm:                            lo.ToPtr(true),
+			LaunchTemplateGarbageCollectionAge: lo.ToPtr(168 * time.Hour),
+			OutpostInstancePrices:              map[string]float64{"m5.xlarge": 0.1, "c5.2xlarge": 0.2},
+			ICEBackoffDurations:                map[string]time.Duration{"spot": 5 * time.Minute, "on-demand": 10 * time.Minute},
+			ICEBackoffMaxDurations:             map[string]time.Duration{"on-demand": time.Hour},
 		}))
 	})
 	It("should correctly fallback to env vars when CLI flags aren't set", func() {
@@ -85,10 +191,63 @@ var _ = Describe("Options", func() {
 		os.Setenv("CLUSTER_CA_BUNDLE", "env-bundle")
 		os.Setenv("CLUSTER_NAME", "env-cluster")
 		os.Setenv("CLUSTER_ENDPOINT", "https://env-cluster")
+		os.Setenv("CLUSTER_DNS", "10.0.0.10")
 		os.Setenv("ISOLATED_VPC", "true")
 		os.Setenv("VM_MEMORY_OVERHEAD_PERCENT", "0.1")
+		os.Setenv("VM_MEMORY_OVERHEAD_PERCENT_OVERRIDES", "t3=0.09,m5.24xlarge=0.05")
 		os.Setenv("INTERRUPTION_QUEUE", "env-cluster")
 		os.Setenv("RESERVED_ENIS", "10")
+		os.Setenv("ALLOCATABLE_ESTIMATION", "true")
+		os.Setenv("INTERRUPTION_QUEUE_SHARED", "true")
+		os.Setenv("TARGET_GROUP_DEREGISTRATION", "true")
+		os.Setenv("INSTANCE_SELECTION_WEIGHTS", "price=1,zone-balance=0.5")
+		os.Setenv("DEPRIORITIZED_INSTANCE_TYPES", "metal,xen")
+		os.Setenv("AWS_DNS_SUFFIX", "sc2s.sgov.gov")
+		os.Setenv("AWS_FIPS_ENDPOINTS", "true")
+		os.Setenv("INSTANCE_TYPE_SNAPSHOT_FILE", "/etc/karpenter/snapshot.json")
+		os.Setenv("MAX_LAUNCH_TEMPLATES_PER_HOUR", "30")
+		os.Setenv("MAX_CREATE_FLEET_REQUESTS_PER_HOUR", "600")
+		os.Setenv("MAX_INSTANCES_PER_HOUR", "300")
+		os.Setenv("MAX_CONCURRENT_INTERRUPTION_DRAINS", "10")
+		os.Setenv("VPC_CNI_WARM_TARGETS", "true")
+		os.Setenv("BREAK_GLASS_DEBUG", "true")
+		os.Setenv("TRACING_ENDPOINT", "otel-collector.monitoring:4317")
+		os.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+		os.Setenv("METADATA_HTTP_TOKENS", "optional")
+		os.Setenv("METADATA_HTTP_PUT_RESPONSE_HOP_LIMIT", "2")
+		os.Setenv("METADATA_OPTIONS_POLICY", "enforce")
+		os.Setenv("ON_DEMAND_BACKSTOP", "true")
+		os.Setenv("ADOPT_UNMANAGED_INSTANCES", "true")
+		os.Setenv("VCPU_QUOTA_REPORTING", "true")
+		os.Setenv("VCPU_QUOTA_FILTERING", "true")
+		os.Setenv("LAUNCH_TEMPLATE_DATA_PATCHES", "true")
+		os.Setenv("CREATE_FLEET_BATCH_IDLE_DURATION", "60ms")
+		os.Setenv("CREATE_FLEET_BATCH_MAX_DURATION", "3s")
+		os.Setenv("CREATE_FLEET_BATCH_MAX_ITEMS", "600")
+		os.Setenv("STUCK_INSTANCE_DEADLINE", "30m")
+		os.Setenv("INTERRUPTION_DEAD_LETTER_QUEUE", "dlq")
+		os.Setenv("INTERRUPTION_QUEUE_MAX_RECEIVES", "3")
+		os.Setenv("WARM_POOLS", "true")
+		os.Setenv("HIBERNATION", "true")
+		os.Setenv("INSTANCE_TYPE_ALLOW_LIST", "m5,c5")
+		os.Setenv("INSTANCE_TYPE_DENY_LIST", "metal,t*")
+		os.Setenv("TERMINATION_APPROVAL", "true")
+		os.Setenv("TERMINATION_NOTIFICATION_EVENT_BUS", "termination")
+		os.Setenv("LAUNCH_AUDIT_LOG", "/var/log/karpenter/launches.jsonl")
+		os.Setenv("ZONE_FAILOVER", "true")
+		os.Setenv("NODECLAIM_LAUNCH_OVERRIDES", "true")
+		os.Setenv("NODE_REPAIR", "true")
+		os.Setenv("NODE_REPAIR_REBOOT_ATTEMPTS", "3")
+		os.Setenv("NODE_REPAIR_REBOOT_TIMEOUT", "15m")
+		os.Setenv("READ_ONLY", "true")
+		os.Setenv("AMI_DEPRECATION_WARNING_WINDOW", "48h")
+		os.Setenv("AMI_DEPRECATION_STRICT", "true")
+		os.Setenv("POD_RESTART_COST", "true")
+		os.Setenv("PREWARM", "true")
+		os.Setenv("LAUNCH_TEMPLATE_GARBAGE_COLLECTION_AGE", "336h")
+		os.Setenv("OUTPOST_INSTANCE_PRICES", "m5.xlarge=0.3")
+		os.Setenv("ICE_BACKOFF_DURATIONS", "spot=1m")
+		os.Setenv("ICE_BACKOFF_MAX_DURATIONS", "spot=30m")
 
 		// Add flags after we set the environment variables so that the parsing logic correctly refers
 		// to the new environment variable values
@@ -96,15 +255,68 @@ var _ = Describe("Options", func() {
 		err := opts.Parse(fs)
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
-			AssumeRoleARN:           lo.ToPtr("env-role"),
-			AssumeRoleDuration:      lo.ToPtr(20 * time.Minute),
-			ClusterCABundle:         lo.ToPtr("env-bundle"),
-			ClusterName:             lo.ToPtr("env-cluster"),
-			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
-			IsolatedVPC:             lo.ToPtr(true),
-			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
-			InterruptionQueue:       lo.ToPtr("env-cluster"),
-			ReservedENIs:            lo.ToPtr(10),
+			AssumeRoleARN:                      lo.ToPtr("env-role"),
+			AssumeRoleDuration:                 lo.ToPtr(20 * time.Minute),
+			ClusterCABundle:                    lo.ToPtr("env-bundle"),
+			ClusterName:                        lo.ToPtr("env-cluster"),
+			ClusterEndpoint:                    lo.ToPtr("https://env-cluster"),
+			ClusterDNS:                         lo.ToPtr("10.0.0.10"),
+			IsolatedVPC:                        lo.ToPtr(true),
+			VMMemoryOverheadPercent:            lo.ToPtr[float64](0.1),
+			VMMemoryOverheadPercentOverrides:   map[string]float64{"t3": 0.09, "m5.24xlarge": 0.05},
+			InterruptionQueue:                  lo.ToPtr("env-cluster"),
+			ReservedENIs:                       lo.ToPtr(10),
+			AllocatableEstimation:              lo.ToPtr(true),
+			InterruptionQueueShared:            lo.ToPtr(true),
+			TargetGroupDeregistration:          lo.ToPtr(true),
+			InstanceSelectionWeights:           map[string]float64{"price": 1, "zone-balance": 0.5},
+			DeprioritizedInstanceTypes:         []string{"metal", "xen"},
+			AWSDNSSuffix:                       lo.ToPtr("sc2s.sgov.gov"),
+			AWSFIPSEndpoints:                   lo.ToPtr(true),
+			InstanceTypeSnapshotFile:           lo.ToPtr("/etc/karpenter/snapshot.json"),
+			MaxLaunchTemplatesPerHour:          lo.ToPtr(30),
+			MaxCreateFleetRequestsPerHour:      lo.ToPtr(600),
+			MaxInstancesPerHour:                lo.ToPtr(300),
+			MaxConcurrentInterruptionDrains:    lo.ToPtr(10),
+			VPCCNIWarmTargets:                  lo.ToPtr(true),
+			BreakGlassDebug:                    lo.ToPtr(true),
+			TracingEndpoint:                    lo.ToPtr("otel-collector.monitoring:4317"),
+			TracingSampleRatio:                 lo.ToPtr(0.25),
+			MetadataHTTPTokens:                 lo.ToPtr("optional"),
+			MetadataHTTPPutResponseHopLimit:    lo.ToPtr(2),
+			MetadataOptionsPolicy:              lo.ToPtr("enforce"),
+			OnDemandBackstop:                   lo.ToPtr(true),
+			AdoptUnmanagedInstances:            lo.ToPtr(true),
+			VCPUQuotaReporting:                 lo.ToPtr(true),
+			VCPUQuotaFiltering:                 lo.ToPtr(true),
+			LaunchTemplateDataPatches:          lo.ToPtr(true),
+			CreateFleetBatchIdleDuration:       lo.ToPtr(60 * time.Millisecond),
+			CreateFleetBatchMaxDuration:        lo.ToPtr(3 * time.Second),
+			CreateFleetBatchMaxItems:           lo.ToPtr(600),
+			StuckInstanceDeadline:              lo.ToPtr(30 * time.Minute),
+			InterruptionDeadLetterQueue:        lo.ToPtr("dlq"),
+			InterruptionQueueMaxReceives:       lo.ToPtr(3),
+			WarmPools:                          lo.ToPtr(true),
+			Hibernation:                        lo.ToPtr(true),
+			InstanceTypeAllowList:              []string{"m5", "c5"},
+			InstanceTypeDenyList:               []string{"metal", "t*"},
+			TerminationApproval:                lo.ToPtr(true),
+			TerminationNotificationEventBus:    lo.ToPtr("termination"),
+			LaunchAuditLog:                     lo.ToPtr("/var/log/karpenter/launches.jsonl"),
+			ZoneFailover:                       lo.ToPtr(true),
+			NodeClaimLaunchOverrides:           lo.ToPtr(true),
+			NodeRepair:                         lo.ToPtr(true),
+			NodeRepairRebootAttempts:           lo.ToPtr(3),
+			NodeRepairRebootTimeout:            lo.ToPtr(15 * time.Minute),
+			ReadOnly:                           lo.ToPtr(true),
+			AMIDeprecationWarningWindow:        lo.ToPtr(48 * time.Hour),
+			AMIDeprecationStrict:               lo.ToPtr(true),
+			PodRestartCost:                     lo.ToPtr(true),
+			Prewarm:                            lo.ToPtr(true),
+			LaunchTemplateGarbageCollectionAge: lo.ToPtr(336 * time.Hour),
+			OutpostInstancePrices:              map[string]float64{"m5.xlarge": 0.3},
+			ICEBackoffDurations:                map[string]time.Duration{"spot": time.Minute},
+			ICEBackoffMaxDurations:             map[string]time.Duration{"spot": 30 * time.Minute},
 		}))
 	})
 
@@ -124,14 +336,122 @@ var _ = Describe("Options", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--cluster-endpoint", "00000000000000000000000.gr7.us-west-2.eks.amazonaws.com")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when clusterDNS isn't an IP address", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--cluster-dns", "kube-dns.kube-system")
+			Expect(err).To(HaveOccurred())
+		})
 		It("should fail when vmMemoryOverheadPercent is negative", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overhead-percent", "-0.01")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when a vmMemoryOverheadPercentOverrides entry is malformed", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overhead-percent-overrides", "t3")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when a vmMemoryOverheadPercentOverrides percent is out of range", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overhead-percent-overrides", "t3=1.5")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an instanceSelectionWeights scorer is unknown", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-selection-weights", "price=1,popularity=1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an instanceSelectionWeights weight is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-selection-weights", "price=-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when a deprioritizedInstanceTypes category is unknown", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--deprioritized-instance-types", "metal,fpga")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when the launchAuditLog S3 URL doesn't have a bucket", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--launch-audit-log", "s3:///launches")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an instanceTypeDenyList pattern is malformed", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-type-deny-list", "m5.[large")
+			Expect(err).To(HaveOccurred())
+		})
 		It("should fail when reservedENIs is negative", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--reserved-enis", "-1")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when maxInstancesPerHour is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-instances-per-hour", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxConcurrentInterruptionDrains is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-concurrent-interruption-drains", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionQueueMaxReceives is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue", "queue", "--interruption-queue-max-receives", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionDeadLetterQueue is set without interruptionQueue", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-dead-letter-queue", "dlq")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeRepairRebootAttempts is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-repair-reboot-attempts", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeRepairRebootTimeout isn't positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-repair-reboot-timeout", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when stuckInstanceDeadline is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--stuck-instance-deadline", "-1m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when createFleetBatchIdleDuration isn't positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--create-fleet-batch-idle-duration", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when createFleetBatchMaxDuration is less than createFleetBatchIdleDuration", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--create-fleet-batch-idle-duration", "2s", "--create-fleet-batch-max-duration", "1s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when createFleetBatchMaxItems isn't positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--create-fleet-batch-max-items", "0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when vcpuQuotaFiltering is enabled without vcpuQuotaReporting", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vcpu-quota-filtering")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when launchTemplateGarbageCollectionAge is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--launch-template-garbage-collection-age", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an outpostInstancePrices price is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--outpost-instance-prices", "m5.xlarge=-0.1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when iceBackoffDurations has an unknown capacity type", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--ice-backoff-durations", "reserved=5m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when iceBackoffMaxDurations is less than iceBackoffDurations", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--ice-backoff-durations", "spot=10m", "--ice-backoff-max-durations", "spot=5m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when tracingSampleRatio is out of range", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--tracing-sample-ratio", "1.5")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when metadataHTTPTokens is invalid", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--metadata-http-tokens", "disabled")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when metadataHTTPPutResponseHopLimit is out of range", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--metadata-http-put-response-hop-limit", "65")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when metadataOptionsPolicy is invalid", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--metadata-options-policy", "strict")
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
 
@@ -142,8 +462,61 @@ func expectOptionsEqual(optsA *options.Options, optsB *options.Options) {
 	Expect(optsA.ClusterCABundle).To(Equal(optsB.ClusterCABundle))
 	Expect(optsA.ClusterName).To(Equal(optsB.ClusterName))
 	Expect(optsA.ClusterEndpoint).To(Equal(optsB.ClusterEndpoint))
+	Expect(optsA.ClusterDNS).To(Equal(optsB.ClusterDNS))
 	Expect(optsA.IsolatedVPC).To(Equal(optsB.IsolatedVPC))
 	Expect(optsA.VMMemoryOverheadPercent).To(Equal(optsB.VMMemoryOverheadPercent))
+	Expect(optsA.VMMemoryOverheadPercentOverrides).To(Equal(optsB.VMMemoryOverheadPercentOverrides))
 	Expect(optsA.InterruptionQueue).To(Equal(optsB.InterruptionQueue))
 	Expect(optsA.ReservedENIs).To(Equal(optsB.ReservedENIs))
+	Expect(optsA.AllocatableEstimation).To(Equal(optsB.AllocatableEstimation))
+	Expect(optsA.InterruptionQueueShared).To(Equal(optsB.InterruptionQueueShared))
+	Expect(optsA.TargetGroupDeregistration).To(Equal(optsB.TargetGroupDeregistration))
+	Expect(optsA.InstanceSelectionWeights).To(Equal(optsB.InstanceSelectionWeights))
+	Expect(optsA.DeprioritizedInstanceTypes).To(Equal(optsB.DeprioritizedInstanceTypes))
+	Expect(optsA.AWSDNSSuffix).To(Equal(optsB.AWSDNSSuffix))
+	Expect(optsA.AWSFIPSEndpoints).To(Equal(optsB.AWSFIPSEndpoints))
+	Expect(optsA.InstanceTypeSnapshotFile).To(Equal(optsB.InstanceTypeSnapshotFile))
+	Expect(optsA.MaxLaunchTemplatesPerHour).To(Equal(optsB.MaxLaunchTemplatesPerHour))
+	Expect(optsA.MaxCreateFleetRequestsPerHour).To(Equal(optsB.MaxCreateFleetRequestsPerHour))
+	Expect(optsA.MaxInstancesPerHour).To(Equal(optsB.MaxInstancesPerHour))
+	Expect(optsA.MaxConcurrentInterruptionDrains).To(Equal(optsB.MaxConcurrentInterruptionDrains))
+	Expect(optsA.VPCCNIWarmTargets).To(Equal(optsB.VPCCNIWarmTargets))
+	Expect(optsA.BreakGlassDebug).To(Equal(optsB.BreakGlassDebug))
+	Expect(optsA.TracingEndpoint).To(Equal(optsB.TracingEndpoint))
+	Expect(optsA.TracingSampleRatio).To(Equal(optsB.TracingSampleRatio))
+	Expect(optsA.MetadataHTTPTokens).To(Equal(optsB.MetadataHTTPTokens))
+	Expect(optsA.MetadataHTTPPutResponseHopLimit).To(Equal(optsB.MetadataHTTPPutResponseHopLimit))
+	Expect(optsA.MetadataOptionsPolicy).To(Equal(optsB.MetadataOptionsPolicy))
+	Expect(optsA.OnDemandBackstop).To(Equal(optsB.OnDemandBackstop))
+	Expect(optsA.AdoptUnmanagedInstances).To(Equal(optsB.AdoptUnmanagedInstances))
+	Expect(optsA.VCPUQuotaReporting).To(Equal(optsB.VCPUQuotaReporting))
+	Expect(optsA.VCPUQuotaFiltering).To(Equal(optsB.VCPUQuotaFiltering))
+	Expect(optsA.LaunchTemplateDataPatches).To(Equal(optsB.LaunchTemplateDataPatches))
+	Expect(optsA.CreateFleetBatchIdleDuration).To(Equal(optsB.CreateFleetBatchIdleDuration))
+	Expect(optsA.CreateFleetBatchMaxDuration).To(Equal(optsB.CreateFleetBatchMaxDuration))
+	Expect(optsA.CreateFleetBatchMaxItems).To(Equal(optsB.CreateFleetBatchMaxItems))
+	Expect(optsA.StuckInstanceDeadline).To(Equal(optsB.StuckInstanceDeadline))
+	Expect(optsA.InterruptionDeadLetterQueue).To(Equal(optsB.InterruptionDeadLetterQueue))
+	Expect(optsA.InterruptionQueueMaxReceives).To(Equal(optsB.InterruptionQueueMaxReceives))
+	Expect(optsA.WarmPools).To(Equal(optsB.WarmPools))
+	Expect(optsA.Hibernation).To(Equal(optsB.Hibernation))
+	Expect(optsA.InstanceTypeAllowList).To(Equal(optsB.InstanceTypeAllowList))
+	Expect(optsA.InstanceTypeDenyList).To(Equal(optsB.InstanceTypeDenyList))
+	Expect(optsA.TerminationApproval).To(Equal(optsB.TerminationApproval))
+	Expect(optsA.TerminationNotificationEventBus).To(Equal(optsB.TerminationNotificationEventBus))
+	Expect(optsA.LaunchAuditLog).To(Equal(optsB.LaunchAuditLog))
+	Expect(optsA.ZoneFailover).To(Equal(optsB.ZoneFailover))
+	Expect(optsA.NodeClaimLaunchOverrides).To(Equal(optsB.NodeClaimLaunchOverrides))
+	Expect(optsA.NodeRepair).To(Equal(optsB.NodeRepair))
+	Expect(optsA.NodeRepairRebootAttempts).To(Equal(optsB.NodeRepairRebootAttempts))
+	Expect(optsA.NodeRepairRebootTimeout).To(Equal(optsB.NodeRepairRebootTimeout))
+	Expect(optsA.ReadOnly).To(Equal(optsB.ReadOnly))
+	Expect(optsA.AMIDeprecationWarningWindow).To(Equal(optsB.AMIDeprecationWarningWindow))
+	Expect(optsA.AMIDeprecationStrict).To(Equal(optsB.AMIDeprecationStrict))
+	Expect(optsA.PodRestartCost).To(Equal(optsB.PodRestartCost))
+	Expect(optsA.Prewarm).To(Equal(optsB.Prewarm))
+	Expect(optsA.LaunchTemplateGarbageCollectionAge).To(Equal(optsB.LaunchTemplateGarbageCollectionAge))
+	Expect(optsA.OutpostInstancePrices).To(Equal(optsB.OutpostInstancePrices))
+	Expect(optsA.ICEBackoffDurations).To(Equal(optsB.ICEBackoffDurations))
+	Expect(optsA.ICEBackoffMaxDurations).To(Equal(optsB.ICEBackoffMaxDurations))
 }
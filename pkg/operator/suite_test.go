@@ -17,17 +17,38 @@ package operator_test
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	ec2v2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	ssmv2 "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/jonathan-innis/aws-sdk-go-prometheus/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/iampolicy"
 	awscontext "github.com/aws/karpenter-provider-aws/pkg/operator"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -101,4 +122,93 @@ var _ = Describe("Operator", func() {
 		_, err := awscontext.ResolveClusterEndpoint(ctx, fakeEKSAPI)
 		Expect(err).To(HaveOccurred())
 	})
+	It("should discover the IP of the kube-dns service", func() {
+		kubeDNSIP, err := awscontext.KubeDNSIP(ctx, kubernetesfake.NewSimpleClientset(
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"}, Spec: corev1.ServiceSpec{ClusterIP: "172.20.0.10"}},
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeDNSIP.String()).To(Equal("172.20.0.10"))
+	})
+	It("should discover the IP of the service labeled k8s-app=kube-dns if there's no kube-dns service", func() {
+		kubeDNSIP, err := awscontext.KubeDNSIP(ctx, kubernetesfake.NewSimpleClientset(
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "coredns-metrics", Namespace: "kube-system", Labels: map[string]string{"k8s-app": "kube-dns"}}, Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system", Labels: map[string]string{"k8s-app": "kube-dns"}}, Spec: corev1.ServiceSpec{ClusterIP: "fd4b:121b:812b::a"}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "kube-system"}, Spec: corev1.ServiceSpec{ClusterIP: "172.20.0.20"}},
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeDNSIP.String()).To(Equal("fd4b:121b:812b::a"))
+	})
+	It("should fail to discover the IP of the kube-dns service if no service is found", func() {
+		_, err := awscontext.KubeDNSIP(ctx, kubernetesfake.NewSimpleClientset())
+		Expect(err).To(HaveOccurred())
+	})
+	It("should resolve AWS service endpoints under a custom DNS suffix", func() {
+		resolved, err := awscontext.EndpointResolver("c2s.ic.gov").EndpointFor("ec2", "us-iso-east-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved.URL).To(Equal("https://ec2.us-iso-east-1.c2s.ic.gov"))
+		Expect(resolved.SigningRegion).To(Equal("us-iso-east-1"))
+		Expect(resolved.SigningName).To(Equal("ec2"))
+		Expect(resolved.PartitionID).To(Equal("aws-iso"))
+	})
+	It("should resolve endpoints in regions that the SDK doesn't know about", func() {
+		resolved, err := awscontext.EndpointResolver("cloud.adc-e.uk").EndpointFor("ssm", "eu-isoe-west-9")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved.URL).To(Equal("https://ssm.eu-isoe-west-9.cloud.adc-e.uk"))
+		Expect(resolved.PartitionID).To(Equal("aws-iso-e"))
+	})
+	It("should resolve FIPS endpoints under a custom DNS suffix", func() {
+		resolved, err := awscontext.EndpointResolver("c2s.ic.gov").EndpointFor("ec2", "us-iso-east-1", endpoints.UseFIPSEndpointOption)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved.URL).To(Equal("https://ec2-fips.us-iso-east-1.c2s.ic.gov"))
+	})
+	It("should use FIPS endpoints if enabled via configuration", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			AWSFIPSEndpoints: lo.ToPtr(true),
+		}))
+		sess := session.Must(session.NewSession(awscontext.NewConfig(ctx).WithRegion("us-west-2")))
+		Expect(ec2.New(sess).Endpoint).To(Equal("https://ec2-fips.us-west-2.amazonaws.com"))
+		Expect(ssm.New(sess).Endpoint).To(Equal("https://ssm-fips.us-west-2.amazonaws.com"))
+		Expect(sqs.New(sess).Endpoint).To(Equal("https://sqs-fips.us-west-2.amazonaws.com"))
+		Expect(pricing.NewAPI(ctx, awscontext.NewConfigV2(ctx, sess, iampolicy.NewRecorder()), "us-west-2")).To(BeNil())
+	})
+	It("should not use FIPS endpoints by default", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		sess := session.Must(session.NewSession(awscontext.NewConfig(ctx).WithRegion("us-west-2")))
+		Expect(ec2.New(sess).Endpoint).To(Equal("https://ec2.us-west-2.amazonaws.com"))
+	})
+	It("should create aws-sdk-go-v2 clients with the region, credentials and endpoint options of the session", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSDNSSuffix: lo.ToPtr("c2s.ic.gov")}))
+		sess := session.Must(session.NewSession(awscontext.NewConfig(ctx).WithRegion("us-iso-east-1").WithCredentials(credentials.NewStaticCredentials("test-id", "test-secret", ""))))
+		recorder := iampolicy.NewRecorder()
+		cfg := awscontext.NewConfigV2(ctx, sess, recorder)
+		var sent []*http.Request
+		cfg.HTTPClient = smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			sent = append(sent, r)
+			return &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+		})
+		_, err := awscontext.NewSSMClient(ctx, cfg).GetParameter(ctx, &ssmv2.GetParameterInput{Name: lo.ToPtr("test")})
+		Expect(err).To(HaveOccurred())
+		Expect(sent).To(HaveLen(1))
+		Expect(sent[0].URL.Host).To(Equal("ssm.us-iso-east-1.c2s.ic.gov"))
+		Expect(sent[0].Header.Get("Authorization")).To(ContainSubstring("Credential=test-id/"))
+		Expect(sent[0].Header.Get("User-Agent")).To(ContainSubstring("karpenter.sh-"))
+		Expect(recorder.Actions()).To(ConsistOf("ssm:GetParameter"))
+	})
+	It("should create an aws-sdk-go-v2 EC2 client that retries adaptively and records the metrics of its calls", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		sess := session.Must(session.NewSession(awscontext.NewConfig(ctx).WithRegion("us-west-2").WithCredentials(credentials.NewStaticCredentials("test-id", "test-secret", ""))))
+		cfg := awscontext.NewConfigV2(ctx, sess, iampolicy.NewRecorder())
+		cfg.HTTPClient = smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("<DescribeInstancesResponse></DescribeInstancesResponse>"))}, nil
+		})
+		client := awscontext.NewEC2Client(ctx, cfg)
+		Expect(client.Options().Retryer).To(BeAssignableToTypeOf(&retry.AdaptiveMode{}))
+		Expect(client.Options().Retryer.MaxAttempts()).To(Equal(awscontext.EC2MaxAttempts))
+
+		labels := prometheus.Labels{"service": "EC2", "action": "DescribeInstances", "code": "200"}
+		requests := testutil.ToFloat64(common.TotalRequests.With(labels))
+		_, err := client.DescribeInstances(ctx, &ec2v2.DescribeInstancesInput{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testutil.ToFloat64(common.TotalRequests.With(labels))).To(Equal(requests + 1))
+	})
 })
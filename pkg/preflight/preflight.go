@@ -0,0 +1,296 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package preflight checks that the controller is allowed to make the AWS API calls that it depends on, with dry-run
+// calls and calls for resources that don't exist, so that missing IAM permissions are reported at startup rather than
+// as an obscure failure on the first launch.
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awspricing "github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	StatusAllowed = "Allowed"
+	StatusDenied  = "Denied"
+	// StatusUnknown is the status of a check whose call failed for a reason other than authorization, e.g. a
+	// throttle, so the permission couldn't be determined either way
+	StatusUnknown = "Unknown"
+
+	// RecheckInterval is how long after a check that found missing permissions that the permissions are checked again,
+	// so that readiness recovers once the permissions are granted
+	RecheckInterval = 5 * time.Minute
+
+	// name is the name of the resources that the checks refer to, which are never created
+	name = "karpenter-preflight"
+)
+
+// deniedCodes are the error codes that AWS APIs return when the caller isn't authorized for a call
+var deniedCodes = sets.New("UnauthorizedOperation", "AccessDenied", "AccessDeniedException")
+
+// requiredActions are the actions that no launch can succeed without. Missing one of them fails readiness, while the
+// others are only reported, since they aren't needed by every configuration (e.g. ssm:GetParameter isn't needed when
+// AMIs are selected by ID, and iam:GetInstanceProfile isn't needed when instance profiles are managed outside Karpenter).
+// ec2:CreateFleet and ec2:CreateLaunchTemplate are only reported as well, since policies usually condition them on the
+// request tags, which the dry runs can only approximate.
+var requiredActions = sets.New("ec2:DescribeInstances")
+
+// Result is the outcome of checking the permission for an action
+type Result struct {
+	Action string `json:"action"`
+	Status string `json:"status"`
+	// Required is true if launches can't succeed without the action
+	Required bool   `json:"required"`
+	Error    string `json:"error,omitempty"`
+}
+
+type check struct {
+	action string
+	// enabled reports whether the action is used with the options of the controller
+	enabled func(*Checker, *options.Options) bool
+	call    func(context.Context, *Checker) error
+	// allowedCodes are error codes that are only returned once the call is authorized, e.g. for dry-run calls or for
+	// resources that don't exist
+	allowedCodes sets.Set[string]
+}
+
+var checks = []check{
+	{
+		action: "ec2:CreateFleet",
+		call: func(ctx context.Context, c *Checker) error {
+			_, err := c.ec2api.CreateFleetWithContext(ctx, &ec2.CreateFleetInput{
+				DryRun: aws.Bool(true),
+				Type:   aws.String(ec2.FleetTypeInstant),
+				LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{{
+					LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{LaunchTemplateName: aws.String(name), Version: aws.String("$Latest")},
+				}},
+				TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
+					DefaultTargetCapacityType: aws.String(ec2.DefaultTargetCapacityTypeOnDemand),
+					TotalTargetCapacity:       aws.Int64(1),
+				},
+				TagSpecifications: []*ec2.TagSpecification{
+					{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: tags(ctx)},
+					{ResourceType: aws.String(ec2.ResourceTypeVolume), Tags: tags(ctx)},
+					{ResourceType: aws.String(ec2.ResourceTypeFleet), Tags: tags(ctx)},
+				},
+			})
+			return err
+		},
+		allowedCodes: sets.New("DryRunOperation"),
+	},
+	{
+		action: "ec2:CreateLaunchTemplate",
+		call: func(ctx context.Context, c *Checker) error {
+			_, err := c.ec2api.CreateLaunchTemplateWithContext(ctx, &ec2.CreateLaunchTemplateInput{
+				DryRun:             aws.Bool(true),
+				LaunchTemplateName: aws.String(name),
+				LaunchTemplateData: &ec2.RequestLaunchTemplateData{},
+				TagSpecifications:  []*ec2.TagSpecification{{ResourceType: aws.String(ec2.ResourceTypeLaunchTemplate), Tags: tags(ctx)}},
+			})
+			return err
+		},
+		allowedCodes: sets.New("DryRunOperation"),
+	},
+	{
+		action: "ec2:DescribeInstances",
+		call: func(ctx context.Context, c *Checker) error {
+			_, err := c.ec2api.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{DryRun: aws.Bool(true)})
+			return err
+		},
+		allowedCodes: sets.New("DryRunOperation"),
+	},
+	{
+		action: "ssm:GetParameter",
+		call: func(ctx context.Context, c *Checker) error {
+			// The public parameters that AMIs are resolved from are under /aws/service/
+			_, err := c.ssmapi.GetParameterWithContext(ctx, &ssm.GetParameterInput{Name: aws.String(fmt.Sprintf("/aws/service/%s", name))})
+			return err
+		},
+		allowedCodes: sets.New(ssm.ErrCodeParameterNotFound),
+	},
+	{
+		// The pricing API isn't available in every partition, e.g. GovCloud
+		action:  "pricing:GetProducts",
+		enabled: func(c *Checker, o *options.Options) bool { return !o.IsolatedVPC && c.pricingapi != nil },
+		call: func(ctx context.Context, c *Checker) error {
+			_, err := c.pricingapi.GetProducts(ctx, &awspricing.GetProductsInput{
+				ServiceCode: awsv2.String("AmazonEC2"),
+				MaxResults:  awsv2.Int32(1),
+			})
+			return err
+		},
+	},
+	{
+		action:  "sqs:GetQueueUrl",
+		enabled: func(_ *Checker, o *options.Options) bool { return o.InterruptionQueue != "" },
+		call: func(ctx context.Context, c *Checker) error {
+			_, err := c.sqsapi.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(options.FromContext(ctx).InterruptionQueue)})
+			return err
+		},
+	},
+	{
+		action: "iam:GetInstanceProfile",
+		call: func(ctx context.Context, c *Checker) error {
+			_, err := c.iamapi.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String(name)})
+			return err
+		},
+		allowedCodes: sets.New(iam.ErrCodeNoSuchEntityException),
+	},
+}
+
+// tags are the tags that Karpenter launches resources with, which policies condition the launch actions on
+func tags(ctx context.Context) []*ec2.Tag {
+	return utils.MergeTags(map[string]string{
+		fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName): "owned",
+		corev1beta1.NodePoolLabelKey:       name,
+		corev1beta1.ManagedByAnnotationKey: options.FromContext(ctx).ClusterName,
+	})
+}
+
+// Checker checks the permissions of the controller, and keeps the results of the last check for readiness
+type Checker struct {
+	ec2api     ec2iface.EC2API
+	ssmapi     ssmiface.SSMAPI
+	pricingapi pricing.API
+	sqsapi     sqsiface.SQSAPI
+	iamapi     iamiface.IAMAPI
+	clk        clock.Clock
+	// ctx is the context that the permissions are checked again with when the readiness check is stale
+	ctx context.Context
+
+	mu        sync.RWMutex
+	results   []Result
+	checkedAt time.Time
+	// checking is held while a check that was started by a readiness probe is in progress
+	checking sync.Mutex
+}
+
+func NewChecker(ctx context.Context, ec2api ec2iface.EC2API, ssmapi ssmiface.SSMAPI, pricingapi pricing.API, sqsapi sqsiface.SQSAPI, iamapi iamiface.IAMAPI, clk clock.Clock) *Checker {
+	return &Checker{
+		ec2api:     ec2api,
+		ssmapi:     ssmapi,
+		pricingapi: pricingapi,
+		sqsapi:     sqsapi,
+		iamapi:     iamapi,
+		clk:        clk,
+		ctx:        ctx,
+	}
+}
+
+// Check checks each of the permissions that the controller's options need, logs the actions that are missing, and
+// returns the results
+func (c *Checker) Check(ctx context.Context) []Result {
+	var results []Result
+	for _, ch := range checks {
+		if ch.enabled != nil && !ch.enabled(c, options.FromContext(ctx)) {
+			continue
+		}
+		result := Result{Action: ch.action, Status: StatusAllowed, Required: requiredActions.Has(ch.action)}
+		if err := ch.call(ctx, c); err != nil {
+			code, ok := awserrors.ErrorCode(err)
+			switch {
+			case ok && ch.allowedCodes.Has(code):
+			case ok && deniedCodes.Has(code):
+				result.Status = StatusDenied
+				result.Error = err.Error()
+			default:
+				result.Status = StatusUnknown
+				result.Error = err.Error()
+			}
+		}
+		results = append(results, result)
+	}
+
+	c.mu.Lock()
+	c.results = results
+	c.checkedAt = c.clk.Now()
+	c.mu.Unlock()
+
+	denied := lo.Filter(results, func(r Result, _ int) bool { return r.Status == StatusDenied })
+	for _, r := range denied {
+		log.FromContext(ctx).WithValues("action", r.Action, "required", r.Required).Error(errors.New(r.Error), "missing permission")
+	}
+	for _, r := range lo.Filter(results, func(r Result, _ int) bool { return r.Status == StatusUnknown }) {
+		log.FromContext(ctx).WithValues("action", r.Action).V(1).Info(fmt.Sprintf("unable to check permission, %s", r.Error))
+	}
+	if len(denied) == 0 {
+		log.FromContext(ctx).WithValues("actions", len(results)).V(1).Info("checked permissions")
+	}
+	return results
+}
+
+// Missing returns the required actions that the last check found to be denied
+func (c *Checker) Missing() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return lo.FilterMap(c.results, func(r Result, _ int) (string, bool) { return r.Action, r.Required && r.Status == StatusDenied })
+}
+
+// ReadyzCheck fails while the last check found required actions to be denied. Once the last check is older than the
+// RecheckInterval, the permissions are checked again in the background, so that readiness recovers without a restart.
+func (c *Checker) ReadyzCheck(_ *http.Request) error {
+	missing := c.Missing()
+	if len(missing) == 0 {
+		return nil
+	}
+	c.mu.RLock()
+	stale := c.clk.Since(c.checkedAt) > RecheckInterval
+	c.mu.RUnlock()
+	if stale && c.checking.TryLock() {
+		go func() {
+			defer c.checking.Unlock()
+			c.Check(c.ctx)
+		}()
+	}
+	return fmt.Errorf("missing permissions for %s", strings.Join(missing, ", "))
+}
+
+// NewHandler returns a handler that checks the permissions when it's requested, and serves the results as JSON
+func NewHandler(ctx context.Context, checker *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(checker.Check(ctx)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	awspricing "github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	clock "k8s.io/utils/clock/testing"
+
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/preflight"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var fakeClock *clock.FakeClock
+var ec2api *fake.EC2API
+var ssmapi *fake.SSMAPI
+var pricingapi *fake.PricingAPI
+var sqsapi *fake.SQSAPI
+var iamapi *fake.IAMAPI
+var checker *preflight.Checker
+
+func TestPreflight(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Preflight")
+}
+
+var _ = BeforeSuite(func() {
+	ec2api = fake.NewEC2API()
+	ssmapi = fake.NewSSMAPI()
+	pricingapi = &fake.PricingAPI{}
+	sqsapi = &fake.SQSAPI{}
+	iamapi = fake.NewIAMAPI()
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr("test-queue")}))
+	ec2api.Reset()
+	ssmapi.Reset()
+	pricingapi.Reset()
+	sqsapi.Reset()
+	iamapi.Reset()
+
+	// Every permission is allowed until a test denies it
+	dryRun := awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+	ec2api.CreateFleetBehavior.Error.Set(dryRun, fake.MaxCalls(0))
+	ec2api.DescribeInstancesBehavior.Error.Set(dryRun, fake.MaxCalls(0))
+	ec2api.NextError.Set(dryRun)
+	ssmapi.WantErr = awserr.New("ParameterNotFound", "parameter not found", nil)
+	pricingapi.GetProductsOutput.Set(&awspricing.GetProductsOutput{})
+
+	fakeClock = clock.NewFakeClock(time.Now())
+	checker = preflight.NewChecker(ctx, ec2api, ssmapi, pricingapi, sqsapi, iamapi, fakeClock)
+})
+
+func statuses(results []preflight.Result) map[string]string {
+	return lo.SliceToMap(results, func(r preflight.Result) (string, string) { return r.Action, r.Status })
+}
+
+var _ = Describe("Preflight", func() {
+	It("should allow the actions that dry-run and not-found calls succeed for", func() {
+		Expect(statuses(checker.Check(ctx))).To(Equal(map[string]string{
+			"ec2:CreateFleet":          preflight.StatusAllowed,
+			"ec2:CreateLaunchTemplate": preflight.StatusAllowed,
+			"ec2:DescribeInstances":    preflight.StatusAllowed,
+			"ssm:GetParameter":         preflight.StatusAllowed,
+			"pricing:GetProducts":      preflight.StatusAllowed,
+			"sqs:GetQueueUrl":          preflight.StatusAllowed,
+			"iam:GetInstanceProfile":   preflight.StatusAllowed,
+		}))
+		Expect(checker.Missing()).To(BeEmpty())
+		Expect(checker.ReadyzCheck(nil)).To(Succeed())
+	})
+	It("should report the actions that are denied", func() {
+		ec2api.CreateFleetBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), fake.MaxCalls(0))
+		iamapi.GetInstanceProfileBehavior.Error.Set(awserr.New("AccessDenied", "not authorized to perform: iam:GetInstanceProfile", nil), fake.MaxCalls(0))
+		results := checker.Check(ctx)
+		Expect(statuses(results)).To(HaveKeyWithValue("ec2:CreateFleet", preflight.StatusDenied))
+		Expect(statuses(results)).To(HaveKeyWithValue("iam:GetInstanceProfile", preflight.StatusDenied))
+		Expect(statuses(results)).To(HaveKeyWithValue("ec2:CreateLaunchTemplate", preflight.StatusAllowed))
+	})
+	It("should report the actions whose permission couldn't be determined", func() {
+		pricingapi.GetProductsOutput.Reset()
+		Expect(statuses(checker.Check(ctx))).To(HaveKeyWithValue("pricing:GetProducts", preflight.StatusUnknown))
+		Expect(checker.ReadyzCheck(nil)).To(Succeed())
+	})
+	It("should not check the actions that the options don't use", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr(""), IsolatedVPC: lo.ToPtr(true)}))
+		results := checker.Check(ctx)
+		Expect(statuses(results)).ToNot(HaveKey("sqs:GetQueueUrl"))
+		Expect(statuses(results)).ToNot(HaveKey("pricing:GetProducts"))
+	})
+	It("should not check the pricing API in partitions that don't have one", func() {
+		results := preflight.NewChecker(ctx, ec2api, ssmapi, nil, sqsapi, iamapi, fakeClock).Check(ctx)
+		Expect(statuses(results)).ToNot(HaveKey("pricing:GetProducts"))
+		Expect(statuses(results)).To(HaveKeyWithValue("ec2:CreateFleet", preflight.StatusAllowed))
+	})
+	It("should only fail readiness for the actions that launches need", func() {
+		iamapi.GetInstanceProfileBehavior.Error.Set(awserr.New("AccessDenied", "not authorized to perform: iam:GetInstanceProfile", nil), fake.MaxCalls(0))
+		checker.Check(ctx)
+		Expect(checker.ReadyzCheck(nil)).To(Succeed())
+
+		ec2api.DescribeInstancesBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), fake.MaxCalls(0))
+		checker.Check(ctx)
+		Expect(checker.Missing()).To(ConsistOf("ec2:DescribeInstances"))
+		Expect(checker.ReadyzCheck(nil)).To(MatchError(ContainSubstring("ec2:DescribeInstances")))
+	})
+	It("should check the permissions again once a failed readiness check is stale", func() {
+		ec2api.DescribeInstancesBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), fake.MaxCalls(0))
+		checker.Check(ctx)
+		Expect(checker.ReadyzCheck(nil)).ToNot(Succeed())
+
+		ec2api.DescribeInstancesBehavior.Error.Set(awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil), fake.MaxCalls(0))
+		Expect(checker.ReadyzCheck(nil)).ToNot(Succeed())
+		fakeClock.Step(preflight.RecheckInterval + time.Second)
+		Expect(checker.ReadyzCheck(nil)).ToNot(Succeed())
+		Eventually(func() error { return checker.ReadyzCheck(nil) }).Should(Succeed())
+	})
+	It("should check the permissions when the handler is requested", func() {
+		ec2api.CreateFleetBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), fake.MaxCalls(0))
+		recorder := httptest.NewRecorder()
+		preflight.NewHandler(ctx, checker).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/preflight", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var results []preflight.Result
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &results)).To(Succeed())
+		Expect(statuses(results)).To(HaveKeyWithValue("ec2:CreateFleet", preflight.StatusDenied))
+	})
+	It("should only report the launch actions that are denied", func() {
+		ec2api.CreateFleetBehavior.Error.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil), fake.MaxCalls(0))
+		ec2api.NextError.Set(awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil))
+		results := checker.Check(ctx)
+		Expect(statuses(results)).To(HaveKeyWithValue("ec2:CreateFleet", preflight.StatusDenied))
+		Expect(statuses(results)).To(HaveKeyWithValue("ec2:CreateLaunchTemplate", preflight.StatusDenied))
+		Expect(checker.Missing()).To(BeEmpty())
+		Expect(checker.ReadyzCheck(nil)).To(Succeed())
+	})
+	It("should dry run the launch actions with the tags that policies condition them on", func() {
+		// The fake only records the inputs of calls that don't fail
+		ec2api.CreateFleetBehavior.Error.Reset()
+		ec2api.CreateFleetBehavior.Output.Set(&ec2.CreateFleetOutput{})
+		ec2api.NextError.Reset()
+		checker.Check(ctx)
+		expected := map[string]string{
+			"kubernetes.io/cluster/" + options.FromContext(ctx).ClusterName: "owned",
+			"karpenter.sh/nodepool":   "karpenter-preflight",
+			"karpenter.sh/managed-by": options.FromContext(ctx).ClusterName,
+		}
+		Expect(ec2api.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+		createFleetInput := ec2api.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(createFleetInput.TagSpecifications).To(HaveLen(3))
+		for _, spec := range createFleetInput.TagSpecifications {
+			Expect(tagMap(spec.Tags)).To(Equal(expected))
+		}
+		Expect(ec2api.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(1))
+		createLaunchTemplateInput := ec2api.CalledWithCreateLaunchTemplateInput.Pop()
+		Expect(createLaunchTemplateInput.TagSpecifications).To(HaveLen(1))
+		Expect(tagMap(createLaunchTemplateInput.TagSpecifications[0].Tags)).To(Equal(expected))
+	})
+})
+
+func tagMap(tags []*ec2.Tag) map[string]string {
+	return lo.SliceToMap(tags, func(t *ec2.Tag) (string, string) { return lo.FromPtr(t.Key), lo.FromPtr(t.Value) })
+}
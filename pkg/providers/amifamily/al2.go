@@ -36,41 +36,63 @@ type AL2 struct {
 }
 
 // DefaultAMIs returns the AMI name, and Requirements, with an SSM query
-func (a AL2) DefaultAMIs(version string) []DefaultAMIOutput {
+func (a AL2) DefaultAMIs(version string, releaseVersion string) []DefaultAMIOutput {
+	// Each release is published under a dated alias alongside the "recommended" alias, e.g. amazon-eks-gpu-node-1.30-v20240703.
+	// The GPU AMI ships both the NVIDIA and the Neuron drivers.
+	release := func(variant string) string {
+		if releaseVersion == "" {
+			return "recommended"
+		}
+		return fmt.Sprintf("amazon-eks-%snode-%s-%s", variant, version, releaseVersion)
+	}
 	return []DefaultAMIOutput{
 		{
-			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/recommended/image_id", version),
+			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/%s/image_id", version, release("")),
 			Requirements: scheduling.NewRequirements(
 				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
 				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpDoesNotExist),
 				scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpDoesNotExist),
 			),
+			Variant: VariantStandard,
 		},
 		{
-			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/recommended/image_id", version),
+			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/%s/image_id", version, release("gpu-")),
 			Requirements: scheduling.NewRequirements(
 				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
 				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpExists),
 			),
+			Variant: VariantNvidia,
 		},
 		{
-			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/recommended/image_id", version),
+			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/%s/image_id", version, release("gpu-")),
 			Requirements: scheduling.NewRequirements(
 				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
 				scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpExists),
 			),
+			Variant: VariantNeuron,
 		},
 		{
-			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-%s/recommended/image_id", version, corev1beta1.ArchitectureArm64),
+			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-%s/%s/image_id", version, corev1beta1.ArchitectureArm64, release(corev1beta1.ArchitectureArm64+"-")),
 			Requirements: scheduling.NewRequirements(
 				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureArm64),
 				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpDoesNotExist),
 				scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpDoesNotExist),
 			),
+			Variant: VariantStandard,
 		},
 	}
 }
 
+// LatestReleaseVersionQuery returns the SSM parameter that holds the version of the recommended release
+func (a AL2) LatestReleaseVersionQuery(version string) string {
+	return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/recommended/release_version", version)
+}
+
+// ParseReleaseVersion converts a release_version parameter (e.g. 1.30.0-20240703) into a release version (e.g. v20240703)
+func (a AL2) ParseReleaseVersion(value string) string {
+	return parseEKSReleaseVersion(value)
+}
+
 // UserData returns the exact same string for equivalent input,
 // even if elements of those inputs are in differing orders,
 // guaranteeing it won't cause spurious hash differences.
@@ -86,6 +108,7 @@ func (a AL2) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints []
 			CABundle:            caBundle,
 			CustomUserData:      customUserData,
 			InstanceStorePolicy: instanceStorePolicy,
+			ContainerRegistries: a.Options.ContainerRegistries,
 		},
 	}
 }
@@ -32,23 +32,69 @@ type AL2023 struct {
 	*Options
 }
 
-func (a AL2023) DefaultAMIs(version string) []DefaultAMIOutput {
+func (a AL2023) DefaultAMIs(version string, releaseVersion string) []DefaultAMIOutput {
+	query := func(arch string, variant string) string {
+		release := "recommended"
+		if releaseVersion != "" {
+			release = fmt.Sprintf("amazon-eks-node-al2023-%s-%s-%s-%s", arch, variant, version, releaseVersion)
+		}
+		return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/%s/%s/%s/image_id", version, arch, variant, release)
+	}
 	return []DefaultAMIOutput{
 		{
-			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version),
+			Query: query("x86_64", VariantStandard),
+			Requirements: scheduling.NewRequirements(
+				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
+				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpDoesNotExist),
+				scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpDoesNotExist),
+			),
+			Variant: VariantStandard,
+		},
+		{
+			Query: query("x86_64", VariantNvidia),
+			Requirements: scheduling.NewRequirements(
+				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
+				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpExists),
+			),
+			Variant: VariantNvidia,
+		},
+		{
+			Query: query("x86_64", VariantNeuron),
 			Requirements: scheduling.NewRequirements(
 				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
+				scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpExists),
+			),
+			Variant: VariantNeuron,
+		},
+		{
+			Query: query("arm64", VariantStandard),
+			Requirements: scheduling.NewRequirements(
+				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureArm64),
+				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpDoesNotExist),
+				scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpDoesNotExist),
 			),
+			Variant: VariantStandard,
 		},
 		{
-			Query: fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/arm64/standard/recommended/image_id", version),
+			Query: query("arm64", VariantNvidia),
 			Requirements: scheduling.NewRequirements(
 				scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureArm64),
+				scheduling.NewRequirement(v1beta1.LabelInstanceGPUCount, v1.NodeSelectorOpExists),
 			),
+			Variant: VariantNvidia,
 		},
 	}
 }
 
+// LatestReleaseVersionQuery returns the SSM parameter that holds the version of the recommended release
+func (a AL2023) LatestReleaseVersionQuery(version string) string {
+	return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/release_version", version)
+}
+
+func (a AL2023) ParseReleaseVersion(value string) string {
+	return parseEKSReleaseVersion(value)
+}
+
 func (a AL2023) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints []v1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1beta1.InstanceStorePolicy) bootstrap.Bootstrapper {
 	return bootstrap.Nodeadm{
 		Options: bootstrap.Options{
@@ -62,6 +108,7 @@ func (a AL2023) UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints
 			AWSENILimitedPodDensity: false,
 			CustomUserData:          customUserData,
 			InstanceStorePolicy:     instanceStorePolicy,
+			ContainerRegistries:     a.Options.ContainerRegistries,
 		},
 	}
 }
@@ -18,18 +18,20 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
-	"github.com/aws/aws-sdk-go/service/ssm"
-	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
@@ -42,15 +44,23 @@ import (
 
 type Provider interface {
 	List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (AMIs, error)
+	LatestReleaseVersion(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (string, error)
+	DeprecationTimes(ctx context.Context, ids []string) (map[string]time.Time, error)
+}
+
+// SSMAPI is the part of the aws-sdk-go-v2 SSM client that the provider resolves the parameters of default AMIs with
+type SSMAPI interface {
+	GetParameter(context.Context, *ssm.GetParameterInput, ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
 }
 
 type DefaultProvider struct {
 	sync.Mutex
 	cache           *cache.Cache
-	ssm             ssmiface.SSMAPI
+	ssm             SSMAPI
 	ec2api          ec2iface.EC2API
 	cm              *pretty.ChangeMonitor
 	versionProvider version.Provider
+	clock           clock.Clock
 }
 
 type AMI struct {
@@ -58,6 +68,10 @@ type AMI struct {
 	AmiID        string
 	CreationDate string
 	Requirements scheduling.Requirements
+	// Variant is set for default AMIs
+	Variant string
+	// ENASupport is nil if it isn't known whether the AMI has ENA enabled
+	ENASupport *bool
 }
 
 type AMIs []AMI
@@ -89,13 +103,14 @@ func MapToInstanceTypes(instanceTypes []*cloudprovider.InstanceType, amis []v1be
 	return amiIDs
 }
 
-func NewDefaultProvider(versionProvider version.Provider, ssm ssmiface.SSMAPI, ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(clk clock.Clock, versionProvider version.Provider, ssm SSMAPI, ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		cache:           cache,
 		ssm:             ssm,
 		ec2api:          ec2api,
 		cm:              pretty.NewChangeMonitor(),
 		versionProvider: versionProvider,
+		clock:           clk,
 	}
 }
 
@@ -112,7 +127,7 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 			return nil, err
 		}
 	} else {
-		amis, err = p.getAMIs(ctx, nodeClass.Spec.AMISelectorTerms)
+		amis, err = p.getAMIs(ctx, nodeClass.Spec.AMISelectorTerms, MinimumAge(nodeClass), BootOptionsFor(nodeClass))
 		if err != nil {
 			return nil, err
 		}
@@ -127,7 +142,13 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 }
 
 func (p *DefaultProvider) getDefaultAMIs(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (res AMIs, err error) {
-	if images, ok := p.cache.Get(lo.FromPtr(nodeClass.Spec.AMIFamily)); ok {
+	releaseVersion := ReleaseVersion(nodeClass)
+	bootOptions := BootOptionsFor(nodeClass)
+	key := lo.Ternary(releaseVersion == "", lo.FromPtr(nodeClass.Spec.AMIFamily), fmt.Sprintf("%s-%s", lo.FromPtr(nodeClass.Spec.AMIFamily), releaseVersion))
+	if bootOptions != (BootOptions{}) {
+		key = fmt.Sprintf("%s-%s", key, bootOptions)
+	}
+	if images, ok := p.cache.Get(key); ok {
 		// Ensure what's returned from this function is a deep-copy of AMIs so alterations
 		// to the data don't affect the original
 		return append(AMIs{}, images.(AMIs)...), nil
@@ -137,15 +158,16 @@ func (p *DefaultProvider) getDefaultAMIs(ctx context.Context, nodeClass *v1beta1
 	if err != nil {
 		return nil, fmt.Errorf("getting kubernetes version %w", err)
 	}
-	defaultAMIs := amiFamily.DefaultAMIs(kubernetesVersion)
+	defaultAMIs := amiFamily.DefaultAMIs(kubernetesVersion, releaseVersion)
 	for _, ami := range defaultAMIs {
 		if id, err := p.resolveSSMParameter(ctx, ami.Query); err != nil {
 			log.FromContext(ctx).WithValues("query", ami.Query).Error(err, "failed discovering amis from ssm")
 		} else {
-			res = append(res, AMI{AmiID: id, Requirements: ami.Requirements})
+			res = append(res, AMI{AmiID: id, Requirements: ami.Requirements, Variant: ami.Variant})
 		}
 	}
 	// Resolve Name and CreationDate information into the DefaultAMIs
+	compatible := sets.New[string]()
 	if err = p.ec2api.DescribeImagesPagesWithContext(ctx, &ec2.DescribeImagesInput{
 		Filters:    []*ec2.Filter{{Name: aws.String("image-id"), Values: aws.StringSlice(lo.Map(res, func(a AMI, _ int) string { return a.AmiID }))}},
 		MaxResults: aws.Int64(500),
@@ -155,33 +177,99 @@ func (p *DefaultProvider) getDefaultAMIs(ctx context.Context, nodeClass *v1beta1
 				if res[j].AmiID == aws.StringValue(page.Images[i].ImageId) {
 					res[j].Name = aws.StringValue(page.Images[i].Name)
 					res[j].CreationDate = aws.StringValue(page.Images[i].CreationDate)
+					res[j].ENASupport = page.Images[i].EnaSupport
 				}
 			}
+			if bootOptions.Compatible(page.Images[i]) {
+				compatible.Insert(aws.StringValue(page.Images[i].ImageId))
+			}
 		}
 		return true
 	}); err != nil {
 		return nil, fmt.Errorf("describing images, %w", err)
 	}
-	p.cache.SetDefault(lo.FromPtr(nodeClass.Spec.AMIFamily), res)
+	if bootOptions != (BootOptions{}) {
+		res = lo.Filter(res, func(a AMI, _ int) bool { return compatible.Has(a.AmiID) })
+	}
+	p.cache.SetDefault(key, res)
 	return res, nil
 }
 
+// LatestReleaseVersion returns the newest release of the default AMIs for the EC2NodeClass's AMIFamily. An empty
+// release version is returned if the AMIFamily's default AMIs can't be pinned to a release.
+func (p *DefaultProvider) LatestReleaseVersion(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) (string, error) {
+	amiFamily, ok := GetAMIFamily(nodeClass.Spec.AMIFamily, &Options{}).(ReleaseVersionedAMIFamily)
+	if !ok {
+		return "", nil
+	}
+	kubernetesVersion, err := p.versionProvider.Get(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting kubernetes version %w", err)
+	}
+	query := amiFamily.LatestReleaseVersionQuery(kubernetesVersion)
+	if version, ok := p.cache.Get(query); ok {
+		return version.(string), nil
+	}
+	value, err := p.resolveSSMParameter(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	version := amiFamily.ParseReleaseVersion(value)
+	p.cache.SetDefault(query, version)
+	return version, nil
+}
+
+// DeprecationTimes returns the times that the AMIs are, or were, deprecated at. AMIs that aren't scheduled to be
+// deprecated are omitted.
+func (p *DefaultProvider) DeprecationTimes(ctx context.Context, ids []string) (map[string]time.Time, error) {
+	if len(ids) == 0 {
+		return map[string]time.Time{}, nil
+	}
+	ids = sets.List(sets.New(ids...))
+	key := fmt.Sprintf("deprecation-%s", strings.Join(ids, ","))
+	if times, ok := p.cache.Get(key); ok {
+		return times.(map[string]time.Time), nil
+	}
+	times := map[string]time.Time{}
+	if err := p.ec2api.DescribeImagesPagesWithContext(ctx, &ec2.DescribeImagesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("image-id"), Values: aws.StringSlice(ids)}},
+		// AMIs that were deprecated are only described to accounts other than their owner if they're included explicitly
+		IncludeDeprecated: aws.Bool(true),
+		MaxResults:        aws.Int64(500),
+	}, func(page *ec2.DescribeImagesOutput, _ bool) bool {
+		for _, image := range page.Images {
+			if deprecationTime, err := time.Parse(time.RFC3339, aws.StringValue(image.DeprecationTime)); err == nil {
+				times[aws.StringValue(image.ImageId)] = deprecationTime
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("describing images, %w", err)
+	}
+	p.cache.SetDefault(key, times)
+	return times, nil
+}
+
 func (p *DefaultProvider) resolveSSMParameter(ctx context.Context, ssmQuery string) (string, error) {
-	output, err := p.ssm.GetParameterWithContext(ctx, &ssm.GetParameterInput{Name: aws.String(ssmQuery)})
+	output, err := p.ssm.GetParameter(ctx, &ssm.GetParameterInput{Name: lo.ToPtr(ssmQuery)})
 	if err != nil {
 		return "", fmt.Errorf("getting ssm parameter %q, %w", ssmQuery, err)
 	}
-	ami := aws.StringValue(output.Parameter.Value)
+	ami := lo.FromPtr(output.Parameter.Value)
 	return ami, nil
 }
 
-func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelectorTerm) (AMIs, error) {
+func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelectorTerm, minimumAge time.Duration, bootOptions BootOptions) (AMIs, error) {
 	filterAndOwnerSets := GetFilterAndOwnerSets(terms)
 	hash, err := hashstructure.Hash(filterAndOwnerSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
 		return nil, err
 	}
-	if images, ok := p.cache.Get(fmt.Sprintf("%d", hash)); ok {
+	key := lo.Ternary(minimumAge == 0, fmt.Sprintf("%d", hash), fmt.Sprintf("%d-%s", hash, minimumAge))
+	if bootOptions != (BootOptions{}) {
+		key = fmt.Sprintf("%s-%s", key, bootOptions)
+	}
+	if images, ok := p.cache.Get(key); ok {
 		// Ensure what's returned from this function is a deep-copy of AMIs so alterations
 		// to the data don't affect the original
 		return append(AMIs{}, images.(AMIs)...), nil
@@ -195,13 +283,27 @@ func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelect
 			MaxResults: aws.Int64(1000),
 		}, func(page *ec2.DescribeImagesOutput, _ bool) bool {
 			for i := range page.Images {
+				// Skip any images that can't boot with the EC2NodeClass's boot options so that a compatible image is selected instead
+				if !bootOptions.Compatible(page.Images[i]) {
+					continue
+				}
 				reqs := p.getRequirementsFromImage(page.Images[i])
 				if !v1beta1.WellKnownArchitectures.Has(reqs.Get(v1.LabelArchStable).Any()) {
 					continue
 				}
 				reqsHash := lo.Must(hashstructure.Hash(reqs.NodeSelectorRequirements(), hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true}))
-				// If the proposed image is newer, store it so that we can return it
+				// If the proposed image is newer, store it so that we can return it. Images that haven't reached the minimum
+				// age are only stored when no image with the same requirements has, so that the caller can keep the AMI it
+				// previously resolved for them rather than dropping them.
 				if v, ok := images[reqsHash]; ok {
+					candidateOldEnough := minimumAge == 0 || IsOlderThan(p.clock, lo.FromPtr(page.Images[i].CreationDate), minimumAge)
+					existingOldEnough := minimumAge == 0 || IsOlderThan(p.clock, v.CreationDate, minimumAge)
+					if existingOldEnough != candidateOldEnough {
+						if candidateOldEnough {
+							images[reqsHash] = p.imageToAMI(page.Images[i], reqs)
+						}
+						continue
+					}
 					candidateCreationTime, _ := time.Parse(time.RFC3339, lo.FromPtr(page.Images[i].CreationDate))
 					existingCreationTime, _ := time.Parse(time.RFC3339, v.CreationDate)
 					if existingCreationTime == candidateCreationTime && lo.FromPtr(page.Images[i].Name) < v.Name {
@@ -211,22 +313,89 @@ func (p *DefaultProvider) getAMIs(ctx context.Context, terms []v1beta1.AMISelect
 						continue
 					}
 				}
-				images[reqsHash] = AMI{
-					Name:         lo.FromPtr(page.Images[i].Name),
-					AmiID:        lo.FromPtr(page.Images[i].ImageId),
-					CreationDate: lo.FromPtr(page.Images[i].CreationDate),
-					Requirements: reqs,
-				}
+				images[reqsHash] = p.imageToAMI(page.Images[i], reqs)
 			}
 			return true
 		}); err != nil {
 			return nil, fmt.Errorf("describing images, %w", err)
 		}
 	}
-	p.cache.SetDefault(fmt.Sprintf("%d", hash), AMIs(lo.Values(images)))
+	p.cache.SetDefault(key, AMIs(lo.Values(images)))
 	return lo.Values(images), nil
 }
 
+func (p *DefaultProvider) imageToAMI(image *ec2.Image, reqs scheduling.Requirements) AMI {
+	return AMI{
+		Name:         lo.FromPtr(image.Name),
+		AmiID:        lo.FromPtr(image.ImageId),
+		CreationDate: lo.FromPtr(image.CreationDate),
+		Requirements: reqs,
+		ENASupport:   image.EnaSupport,
+	}
+}
+
+// MinimumAge returns the minimum age an AMI must reach before it's adopted by the EC2NodeClass, or 0 if there is none
+func MinimumAge(nodeClass *v1beta1.EC2NodeClass) time.Duration {
+	if nodeClass.Spec.AMISelectionPolicy == nil || nodeClass.Spec.AMISelectionPolicy.MinimumAge == nil {
+		return 0
+	}
+	return nodeClass.Spec.AMISelectionPolicy.MinimumAge.Duration
+}
+
+// BootOptions are the boot requirements that an EC2NodeClass places on its AMIs
+type BootOptions struct {
+	Mode     v1beta1.BootMode
+	NitroTPM bool
+}
+
+func BootOptionsFor(nodeClass *v1beta1.EC2NodeClass) BootOptions {
+	return BootOptions{Mode: lo.FromPtr(nodeClass.Spec.BootMode), NitroTPM: lo.FromPtr(nodeClass.Spec.NitroTPM)}
+}
+
+func (b BootOptions) String() string {
+	return fmt.Sprintf("%s-%t", b.Mode, b.NitroTPM)
+}
+
+// Compatible returns true if instances launched from the image boot with the required boot mode and NitroTPM support.
+// Images without a boot mode boot with the default for their architecture, which is uefi for arm64 and legacy-bios
+// for x86_64. Images that prefer uefi only boot with legacy-bios on instance types that don't support uefi, so they
+// aren't considered compatible with legacy-bios.
+func (b BootOptions) Compatible(image *ec2.Image) bool {
+	if b.NitroTPM && aws.StringValue(image.TpmSupport) != ec2.TpmSupportValuesV20 {
+		return false
+	}
+	bootMode := aws.StringValue(image.BootMode)
+	if bootMode == "" {
+		bootMode = lo.Ternary(aws.StringValue(image.Architecture) == ec2.ArchitectureValuesArm64, ec2.BootModeValuesUefi, ec2.BootModeValuesLegacyBios)
+	}
+	switch b.Mode {
+	case v1beta1.BootModeUEFI:
+		return bootMode == ec2.BootModeValuesUefi || bootMode == ec2.BootModeValuesUefiPreferred
+	case v1beta1.BootModeLegacyBIOS:
+		return bootMode == ec2.BootModeValuesLegacyBios
+	}
+	return true
+}
+
+// ReleaseVersion returns the release version that the EC2NodeClass's default AMIs are pinned to, or an empty string
+// if the latest recommended release should be used.
+func ReleaseVersion(nodeClass *v1beta1.EC2NodeClass) string {
+	if nodeClass.Spec.AMISelectionPolicy == nil {
+		return ""
+	}
+	return lo.FromPtr(nodeClass.Spec.AMISelectionPolicy.ReleaseVersion)
+}
+
+// IsOlderThan returns true if the RFC3339 creation date is at least the given age. Creation dates that can't be parsed
+// are treated as old enough so that images with unexpected metadata aren't silently excluded.
+func IsOlderThan(clk clock.Clock, creationDate string, age time.Duration) bool {
+	created, err := time.Parse(time.RFC3339, creationDate)
+	if err != nil {
+		return true
+	}
+	return clk.Since(created) >= age
+}
+
 type FiltersAndOwners struct {
 	Filters []*ec2.Filter
 	Owners  []string
@@ -284,5 +453,7 @@ func (p *DefaultProvider) getRequirementsFromImage(ec2Image *ec2.Image) scheduli
 		architecture = value
 	}
 	requirements.Add(scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, architecture))
+	// Images can declare the versions of their accelerator drivers so that pods can require compatible drivers
+	requirements.Add(driverRequirements(ec2Image.Tags)...)
 	return requirements
 }
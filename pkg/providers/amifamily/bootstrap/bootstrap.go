@@ -41,6 +41,8 @@ type Options struct {
 	ContainerRuntime        *string
 	CustomUserData          *string
 	InstanceStorePolicy     *v1beta1.InstanceStorePolicy
+	ContainerRegistries     *v1beta1.ContainerRegistries
+	Bottlerocket            *v1beta1.BottlerocketConfiguration
 }
 
 func (o Options) kubeletExtraArgs() (args []string) {
@@ -75,6 +75,20 @@ func (b Bottlerocket) Script() (string, error) {
 		}
 	}
 
+	if image := b.pauseImage(); image != "" {
+		s.Settings.Kubernetes.PodInfraContainerImage = lo.ToPtr(image)
+	}
+	// Mirrors of the EC2NodeClass replace the mirrors of the same registry in custom UserData
+	for _, mirror := range b.registryMirrors() {
+		s.Settings.ContainerRegistry.Mirrors = append(lo.Reject(s.Settings.ContainerRegistry.Mirrors, func(m BottlerocketRegistryMirror, _ int) bool {
+			return lo.FromPtr(m.Registry) == mirror.Registry
+		}), BottlerocketRegistryMirror{Registry: lo.ToPtr(mirror.Registry), Endpoint: mirror.Endpoints})
+	}
+
+	if b.Bottlerocket != nil {
+		b.applyConfiguration(s)
+	}
+
 	s.Settings.Kubernetes.NodeTaints = map[string][]string{}
 	for _, taint := range b.Taints {
 		s.Settings.Kubernetes.NodeTaints[taint.Key] = append(s.Settings.Kubernetes.NodeTaints[taint.Key], fmt.Sprintf("%s:%s", taint.Value, taint.Effect))
@@ -85,3 +99,38 @@ func (b Bottlerocket) Script() (string, error) {
 	}
 	return base64.StdEncoding.EncodeToString(script), nil
 }
+
+// applyConfiguration renders the structured Bottlerocket settings of the EC2NodeClass, which take precedence over the
+// same settings in custom UserData
+func (b Bottlerocket) applyConfiguration(c *BottlerocketConfig) {
+	if kernel := b.Bottlerocket.Kernel; kernel != nil {
+		if kernel.Lockdown != nil {
+			c.SetSetting(*kernel.Lockdown, "kernel", "lockdown")
+		}
+		for key, value := range kernel.Sysctls {
+			c.SetSetting(value, "kernel", "sysctl", key)
+		}
+	}
+	for name, hostContainer := range b.Bottlerocket.HostContainers {
+		if hostContainer.Source != nil {
+			c.SetSetting(*hostContainer.Source, "host-containers", name, "source")
+		}
+		if hostContainer.Enabled != nil {
+			c.SetSetting(*hostContainer.Enabled, "host-containers", name, "enabled")
+		}
+		if hostContainer.Superpowered != nil {
+			c.SetSetting(*hostContainer.Superpowered, "host-containers", name, "superpowered")
+		}
+		if hostContainer.UserData != nil {
+			c.SetSetting(*hostContainer.UserData, "host-containers", name, "user-data")
+		}
+	}
+	if updates := b.Bottlerocket.Updates; updates != nil {
+		if updates.VersionLock != nil {
+			c.SetSetting(*updates.VersionLock, "updates", "version-lock")
+		}
+		if updates.IgnoreWaves != nil {
+			c.SetSetting(*updates.IgnoreWaves, "updates", "ignore-waves")
+		}
+	}
+}
@@ -38,7 +38,8 @@ type BottlerocketConfig struct {
 // BottlerocketSettings is a subset of all configuration in https://github.com/bottlerocket-os/bottlerocket/blob/d427c40931cba6e6bedc5b75e9c084a6e1818db9/sources/models/src/lib.rs#L260
 // These settings apply across all K8s versions that karpenter supports.
 type BottlerocketSettings struct {
-	Kubernetes BottlerocketKubernetes `toml:"kubernetes"`
+	Kubernetes        BottlerocketKubernetes        `toml:"kubernetes"`
+	ContainerRegistry BottlerocketContainerRegistry `toml:"container-registry"`
 }
 
 // BottlerocketKubernetes is k8s specific configuration for bottlerocket api
@@ -77,6 +78,17 @@ type BottlerocketKubernetes struct {
 	ShutdownGracePeriodForCriticalPods *string                                   `toml:"shutdown-grace-period-for-critical-pods,omitempty"`
 	ClusterDomain                      *string                                   `toml:"cluster-domain,omitempty"`
 	SeccompDefault                     *bool                                     `toml:"seccomp-default,omitempty"`
+	PodInfraContainerImage             *string                                   `toml:"pod-infra-container-image,omitempty"`
+}
+
+// BottlerocketContainerRegistry is the configuration of the registries that the container runtime pulls images from
+type BottlerocketContainerRegistry struct {
+	Mirrors []BottlerocketRegistryMirror `toml:"mirrors,omitempty"`
+}
+
+type BottlerocketRegistryMirror struct {
+	Registry *string  `toml:"registry,omitempty"`
+	Endpoint []string `toml:"endpoint,omitempty"`
 }
 
 type BottlerocketStaticPod struct {
@@ -109,10 +121,38 @@ func (c *BottlerocketConfig) UnmarshalTOML(data []byte) error {
 	return nil
 }
 
+// SetSetting sets the untyped setting at the path of keys, e.g. kernel.lockdown, overwriting the setting from custom
+// UserData. Tables along the path are created as needed.
+func (c *BottlerocketConfig) SetSetting(value interface{}, path ...string) {
+	if c.SettingsRaw == nil {
+		c.SettingsRaw = map[string]interface{}{}
+	}
+	table := c.SettingsRaw
+	for _, key := range path[:len(path)-1] {
+		next, ok := table[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			table[key] = next
+		}
+		table = next
+	}
+	table[path[len(path)-1]] = value
+}
+
 func (c *BottlerocketConfig) MarshalTOML() ([]byte, error) {
 	if c.SettingsRaw == nil {
 		c.SettingsRaw = map[string]interface{}{}
 	}
 	c.SettingsRaw["kubernetes"] = c.Settings.Kubernetes
+	// Only the mirrors of the container registry settings are modeled, so the rest of them, like credentials, are
+	// preserved from the untyped settings
+	if len(c.Settings.ContainerRegistry.Mirrors) > 0 {
+		containerRegistry, ok := c.SettingsRaw["container-registry"].(map[string]interface{})
+		if !ok {
+			containerRegistry = map[string]interface{}{}
+		}
+		containerRegistry["mirrors"] = c.Settings.ContainerRegistry.Mirrors
+		c.SettingsRaw["container-registry"] = containerRegistry
+	}
 	return toml.Marshal(c)
 }
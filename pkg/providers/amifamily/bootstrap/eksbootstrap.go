@@ -62,6 +62,11 @@ func (e EKS) eksBootstrapScript() string {
 	var userData bytes.Buffer
 	userData.WriteString("#!/bin/bash -xe\n")
 	userData.WriteString("exec > >(tee /var/log/user-data.log|logger -t user-data -s 2>/dev/console) 2>&1\n")
+	userData.WriteString(e.registryMirrorsScript())
+	if image := e.pauseImage(); image != "" {
+		// bootstrap.sh only fills in the sandbox image of the containerd config if it's still the placeholder
+		userData.WriteString(fmt.Sprintf("sed -i 's,SANDBOX_IMAGE,%s,' /etc/eks/containerd/containerd-config.toml\n", image))
+	}
 	// Due to the way bootstrap.sh is written, parameters should not be passed to it with an equal sign
 	userData.WriteString(fmt.Sprintf("/etc/eks/bootstrap.sh '%s' --apiserver-endpoint '%s' %s", e.ClusterName, e.ClusterEndpoint, caBundleArg))
 
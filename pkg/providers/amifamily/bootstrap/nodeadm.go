@@ -45,10 +45,17 @@ func (n Nodeadm) Script() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("parsing custom UserData, %w", err)
 	}
-	mimeArchive := mime.Archive(append([]mime.Entry{{
+	entries := []mime.Entry{{
 		ContentType: mime.ContentTypeNodeConfig,
 		Content:     nodeConfigYAML,
-	}}, customEntries...))
+	}}
+	if script := n.registryMirrorsScript(); script != "" {
+		entries = append(entries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash -xe\n" + script,
+		})
+	}
+	mimeArchive := mime.Archive(append(entries, customEntries...))
 	userData, err := mimeArchive.Serialize()
 	if err != nil {
 		return "", err
@@ -86,6 +93,9 @@ func (n Nodeadm) getNodeConfigYAML() (string, error) {
 	if lo.FromPtr(n.InstanceStorePolicy) == v1beta1.InstanceStorePolicyRAID0 {
 		config.Spec.Instance.LocalStorage.Strategy = admv1alpha1.LocalStorageRAID0
 	}
+	if image := n.pauseImage(); image != "" {
+		config.Spec.Containerd.Config = fmt.Sprintf("[plugins.\"io.containerd.grpc.v1.cri\"]\nsandbox_image = %q\n", image)
+	}
 	inlineConfig, err := n.generateInlineKubeletConfiguration()
 	if err != nil {
 		return "", err
@@ -116,9 +126,11 @@ func (n Nodeadm) generateInlineKubeletConfiguration() (map[string]runtime.RawExt
 		return nil, err
 	}
 	if len(n.Taints) != 0 {
-		kubeConfigMap["registerWithTaints"] = runtime.RawExtension{
-			Raw: lo.Must(json.Marshal(n.Taints)),
+		taints, err := json.Marshal(n.Taints)
+		if err != nil {
+			return nil, err
 		}
+		kubeConfigMap["registerWithTaints"] = runtime.RawExtension{Raw: taints}
 	}
 	return kubeConfigMap, nil
 }
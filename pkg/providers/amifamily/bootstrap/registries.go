@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// containerdCertsDir is the directory that containerd reads the hosts.toml of each registry from
+const containerdCertsDir = "/etc/containerd/certs.d"
+
+func (o Options) pauseImage() string {
+	if o.ContainerRegistries == nil {
+		return ""
+	}
+	return lo.FromPtr(o.ContainerRegistries.PauseImage)
+}
+
+func (o Options) registryMirrors() []v1beta1.RegistryMirror {
+	if o.ContainerRegistries == nil {
+		return nil
+	}
+	return o.ContainerRegistries.Mirrors
+}
+
+// registryMirrorsScript returns shell commands that write a containerd hosts.toml for each registry mirror
+func (o Options) registryMirrorsScript() string {
+	var script strings.Builder
+	for _, mirror := range o.registryMirrors() {
+		dir := path.Join(containerdCertsDir, mirror.Registry)
+		script.WriteString(fmt.Sprintf("mkdir -p '%s'\n", dir))
+		script.WriteString(fmt.Sprintf("cat > '%s' <<'EOF'\n%sEOF\n", path.Join(dir, "hosts.toml"), registryHostsTOML(mirror)))
+	}
+	return script.String()
+}
+
+// registryHostsTOML returns the containerd hosts.toml that pulls and resolves the images of a registry through its
+// mirrors, see https://github.com/containerd/containerd/blob/main/docs/hosts.md
+func registryHostsTOML(mirror v1beta1.RegistryMirror) string {
+	server := "https://" + mirror.Registry
+	// Images of docker.io are served from registry-1.docker.io
+	if mirror.Registry == "docker.io" {
+		server = "https://registry-1.docker.io"
+	}
+	var hosts strings.Builder
+	hosts.WriteString(fmt.Sprintf("server = %q\n", server))
+	for _, endpoint := range mirror.Endpoints {
+		hosts.WriteString(fmt.Sprintf("\n[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", endpoint))
+	}
+	return hosts.String()
+}
@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	core "k8s.io/api/core/v1"
+
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+// TemplateVariables are the values that can be referenced from an EC2NodeClass's userData with Go template syntax,
+// e.g. {{ .ClusterName }}. They're evaluated when the launch template is resolved, so a single userData can be
+// shared by every NodePool that references the EC2NodeClass.
+type TemplateVariables struct {
+	ClusterName     string
+	ClusterEndpoint string
+	NodeClassName   string
+	NodePoolName    string
+	NodeLabels      map[string]string
+	Taints          []core.Taint
+	KubeletConfig   *corev1beta1.KubeletConfiguration
+	CapacityType    string
+	Architecture    string
+	AMIID           string
+	InstanceTypes   []string
+	MaxPods         int
+	ClusterDNS      string
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"toJSON": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// RenderUserData substitutes the template variables into the userData. UserData without template actions is
+// returned unchanged.
+func RenderUserData(userData *string, vars TemplateVariables) (*string, error) {
+	if userData == nil || !strings.Contains(*userData, "{{") {
+		return userData, nil
+	}
+	t, err := template.New("userData").Funcs(templateFuncs).Option("missingkey=error").Parse(*userData)
+	if err != nil {
+		return nil, fmt.Errorf("parsing userData template, %w", err)
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, vars); err != nil {
+		return nil, fmt.Errorf("rendering userData template, %w", err)
+	}
+	rendered := buf.String()
+	return &rendered, nil
+}
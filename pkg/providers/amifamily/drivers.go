@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// DriverLabels are the labels that describe the accelerator drivers of the AMI that a node was launched with
+var DriverLabels = []string{v1beta1.LabelAMIGPUDriverVersion, v1beta1.LabelAMICUDAVersion}
+
+// driverRequirements returns the requirements that the driver version tags of an image declare. Tags that can't be
+// parsed are ignored, so the image is treated as if it didn't declare the version.
+func driverRequirements(tags []*ec2.Tag) []*scheduling.Requirement {
+	var requirements []*scheduling.Requirement
+	for _, tag := range tags {
+		var label, value string
+		var err error
+		switch aws.StringValue(tag.Key) {
+		case v1beta1.TagGPUDriverVersion:
+			label = v1beta1.LabelAMIGPUDriverVersion
+			value, err = gpuDriverMajorVersion(aws.StringValue(tag.Value))
+		case v1beta1.TagCUDAVersion:
+			label = v1beta1.LabelAMICUDAVersion
+			value, err = cudaVersion(aws.StringValue(tag.Value))
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		requirements = append(requirements, scheduling.NewRequirement(label, v1.NodeSelectorOpIn, value))
+	}
+	return requirements
+}
+
+// gpuDriverMajorVersion parses the major version of a GPU driver version, e.g. 550 for 550.54.15
+func gpuDriverMajorVersion(version string) (string, error) {
+	major, _, _ := strings.Cut(strings.TrimSpace(version), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("invalid gpu driver version %q", version)
+	}
+	return strconv.Itoa(n), nil
+}
+
+// cudaVersion encodes a CUDA version like CUDA_VERSION, e.g. 12040 for 12.4. Patch versions are ignored.
+func cudaVersion(version string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("invalid cuda version %q", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 0 {
+		return "", fmt.Errorf("invalid cuda version %q", version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 0 || minor >= 100 {
+		return "", fmt.Errorf("invalid cuda version %q", version)
+	}
+	return strconv.Itoa(1000*major + 10*minor), nil
+}
+
+// DriverRequirements returns the driver labels that nodes of the instance type can have, given the AMIs that the
+// instance type can be launched with. A label is only returned if every AMI that the instance type can be launched
+// with declares it, since nodes that are launched with an AMI that doesn't declare it won't have the label.
+func DriverRequirements(instanceTypeRequirements scheduling.Requirements, amis []v1beta1.AMI) scheduling.Requirements {
+	compatible := lo.FilterMap(amis, func(ami v1beta1.AMI, _ int) (scheduling.Requirements, bool) {
+		requirements := scheduling.NewNodeSelectorRequirements(ami.Requirements...)
+		return requirements, instanceTypeRequirements.Compatible(requirements, scheduling.AllowUndefinedWellKnownLabels) == nil
+	})
+	requirements := scheduling.NewRequirements()
+	if len(compatible) == 0 {
+		return requirements
+	}
+	for _, label := range DriverLabels {
+		if !lo.EveryBy(compatible, func(r scheduling.Requirements) bool { return r.Has(label) }) {
+			continue
+		}
+		values := lo.Uniq(lo.FlatMap(compatible, func(r scheduling.Requirements, _ int) []string { return r.Get(label).Values() }))
+		requirements.Add(scheduling.NewRequirement(label, v1.NodeSelectorOpIn, values...))
+	}
+	return requirements
+}
+
+// DriverCompatible returns the AMIs whose drivers satisfy the driver requirements of the NodeClaim. AMIs that don't
+// declare a driver label only satisfy requirements that allow the label to be absent.
+func DriverCompatible(amis []v1beta1.AMI, requirements scheduling.Requirements) []v1beta1.AMI {
+	constrained := scheduling.NewRequirements(lo.FilterMap(DriverLabels, func(label string, _ int) (*scheduling.Requirement, bool) {
+		return requirements.Get(label), requirements.Has(label)
+	})...)
+	if len(constrained) == 0 {
+		return amis
+	}
+	return lo.Filter(amis, func(ami v1beta1.AMI, _ int) bool {
+		return scheduling.NewNodeSelectorRequirements(ami.Requirements...).Compatible(constrained) == nil
+	})
+}
+
+// DriverLabelsFor returns the driver labels of nodes that are launched with the AMI
+func DriverLabelsFor(ami v1beta1.AMI) map[string]string {
+	labels := map[string]string{}
+	for _, requirement := range ami.Requirements {
+		if lo.Contains(DriverLabels, requirement.Key) && requirement.Operator == v1.NodeSelectorOpIn && len(requirement.Values) == 1 {
+			labels[requirement.Key] = requirement.Values[0]
+		}
+	}
+	return labels
+}
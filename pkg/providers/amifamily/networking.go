@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// SupportsNetworking returns true if the instance type can be launched with the AMI that it maps to, which is the
+// first AMI whose requirements are compatible with the instance type, as in MapToInstanceTypes. Instance types that
+// don't support ENA fail to launch with AMIs that have ENA enabled, and instance types that require ENA fail to launch
+// with AMIs that don't. Instance types are assumed to be supported if the AMI's ENA support isn't known.
+func SupportsNetworking(info *ec2.InstanceTypeInfo, instanceTypeRequirements scheduling.Requirements, amis []v1beta1.AMI) bool {
+	ami, ok := lo.Find(amis, func(ami v1beta1.AMI) bool {
+		return instanceTypeRequirements.Compatible(scheduling.NewNodeSelectorRequirements(ami.Requirements...), scheduling.AllowUndefinedWellKnownLabels) == nil
+	})
+	if !ok || ami.ENASupport == nil || info.NetworkInfo == nil {
+		return true
+	}
+	switch aws.StringValue(info.NetworkInfo.EnaSupport) {
+	case ec2.EnaSupportUnsupported:
+		return !*ami.ENASupport
+	case ec2.EnaSupportRequired:
+		return *ami.ENASupport
+	}
+	return true
+}
@@ -17,6 +17,8 @@ package amifamily
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -40,8 +42,17 @@ var DefaultEBS = v1beta1.BlockDevice{
 	VolumeSize: lo.ToPtr(resource.MustParse("20Gi")),
 }
 
+// hibernationHeadroom is the space that's left on the root volume for the OS on top of the contents of memory that
+// instances save to it when they're hibernated
+var hibernationHeadroom = resource.MustParse("4Gi")
+
 // Resolver is able to fill-in dynamic launch template parameters
-type Resolver struct {
+type Resolver interface {
+	Resolve(*v1beta1.EC2NodeClass, *corev1beta1.NodeClaim, []*cloudprovider.InstanceType, string, *Options) ([]*LaunchTemplate, error)
+}
+
+// DefaultResolver resolves the launch template parameters from the AMIs of the AMI Provider
+type DefaultResolver struct {
 	amiProvider Provider
 }
 
@@ -53,13 +64,24 @@ type Options struct {
 	InstanceProfile     string
 	CABundle            *string `hash:"ignore"`
 	InstanceStorePolicy *v1beta1.InstanceStorePolicy
+	ContainerRegistries *v1beta1.ContainerRegistries
+	Bottlerocket        *v1beta1.BottlerocketConfiguration
 	// Level-triggered fields that may change out of sync.
 	SecurityGroups           []v1beta1.SecurityGroup
 	Tags                     map[string]string
 	Labels                   map[string]string `hash:"ignore"`
 	KubeDNSIP                net.IP
 	AssociatePublicIPAddress *bool
-	NodeClassName            string
+	// AssociateCarrierIPAddress is set instead of AssociatePublicIPAddress for instances in Wavelength Zones, which
+	// reach the internet through the carrier gateway of their zone
+	AssociateCarrierIPAddress *bool
+	PrimaryNetworkInterface   *v1beta1.PrimaryNetworkInterface
+	NodeClassName             string
+	// LaunchTemplateDataPatch is a JSON patch that's applied to the data of the launch template, for EC2 features that
+	// EC2NodeClasses don't model yet
+	LaunchTemplateDataPatch string
+	// Hibernation allows launch templates to be configured for hibernation, for NodeClaims that enable it
+	Hibernation bool `hash:"ignore"`
 }
 
 // LaunchTemplate holds the dynamically generated launch template parameters
@@ -73,11 +95,28 @@ type LaunchTemplate struct {
 	DetailedMonitoring  bool
 	EFACount            int
 	CapacityType        string
+	// DisableAPITermination enables EC2 termination protection on the instances launched from the launch template
+	DisableAPITermination bool
+	// AdditionalNetworkInterfaces are created in the subnets of a single zone, so launch templates with them can only
+	// launch instances in that zone
+	AdditionalNetworkInterfaces []NetworkInterface
+	// HibernationConfigured launches the instances of the launch template with hibernation configured, so that they can
+	// be hibernated rather than terminated
+	HibernationConfigured bool
+}
+
+// NetworkInterface is an additional network interface that's created and attached to the instances that are launched
+// from a launch template
+type NetworkInterface struct {
+	DeviceIndex         int64
+	SubnetID            string
+	SecurityGroupIDs    []string
+	DeleteOnTermination *bool
 }
 
 // AMIFamily can be implemented to override the default logic for generating dynamic launch template parameters
 type AMIFamily interface {
-	DefaultAMIs(version string) []DefaultAMIOutput
+	DefaultAMIs(version string, releaseVersion string) []DefaultAMIOutput
 	UserData(kubeletConfig *corev1beta1.KubeletConfiguration, taints []core.Taint, labels map[string]string, caBundle *string, instanceTypes []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1beta1.InstanceStorePolicy) bootstrap.Bootstrapper
 	DefaultBlockDeviceMappings() []*v1beta1.BlockDeviceMapping
 	DefaultMetadataOptions() *v1beta1.MetadataOptions
@@ -85,9 +124,33 @@ type AMIFamily interface {
 	FeatureFlags() FeatureFlags
 }
 
+// ReleaseVersionedAMIFamily can be implemented by AMIFamilies whose default AMIs can be pinned to a release version
+type ReleaseVersionedAMIFamily interface {
+	// LatestReleaseVersionQuery returns the SSM parameter that holds the latest release for the kubernetes version
+	LatestReleaseVersionQuery(version string) string
+	// ParseReleaseVersion converts the value of the LatestReleaseVersionQuery parameter into a release version
+	ParseReleaseVersion(value string) string
+}
+
+// parseEKSReleaseVersion converts an EKS optimized AMI release_version parameter (e.g. 1.30.0-20240703) into the
+// version that's used in the AMI's release alias (e.g. v20240703)
+func parseEKSReleaseVersion(value string) string {
+	parts := strings.Split(value, "-")
+	return fmt.Sprintf("v%s", parts[len(parts)-1])
+}
+
+// Variants of default AMIs. Accelerated instance types need the drivers of their accelerators, which each AMI family
+// publishes in a separate variant of its default AMIs.
+const (
+	VariantStandard = "standard"
+	VariantNvidia   = "nvidia"
+	VariantNeuron   = "neuron"
+)
+
 type DefaultAMIOutput struct {
 	Query        string
 	Requirements scheduling.Requirements
+	Variant      string
 }
 
 // FeatureFlags describes whether the features below are enabled for a given AMIFamily
@@ -110,23 +173,29 @@ func (d DefaultFamily) FeatureFlags() FeatureFlags {
 	}
 }
 
-// NewResolver constructs a new launch template Resolver
-func NewResolver(amiProvider Provider) *Resolver {
-	return &Resolver{
+// NewDefaultResolver constructs a new launch template Resolver
+func NewDefaultResolver(amiProvider Provider) *DefaultResolver {
+	return &DefaultResolver{
 		amiProvider: amiProvider,
 	}
 }
 
 // Resolve generates launch templates using the static options and dynamically generates launch template parameters.
 // Multiple ResolvedTemplates are returned based on the instanceTypes passed in to support special AMIs for certain instance types like GPUs.
-func (r Resolver) Resolve(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, options *Options) ([]*LaunchTemplate, error) {
+func (r DefaultResolver) Resolve(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, options *Options) ([]*LaunchTemplate, error) {
 	amiFamily := GetAMIFamily(nodeClass.Spec.AMIFamily, options)
 	if len(nodeClass.Status.AMIs) == 0 {
 		return nil, fmt.Errorf("no amis exist given constraints")
 	}
-	mappedAMIs := MapToInstanceTypes(instanceTypes, nodeClass.Status.AMIs)
+	amis := DriverCompatible(nodeClass.Status.AMIs, scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...))
+	if len(amis) == 0 {
+		return nil, fmt.Errorf("no amis have drivers that satisfy the requirements of the nodeclaim")
+	}
+	mappedAMIs := MapToInstanceTypes(instanceTypes, amis)
 	if len(mappedAMIs) == 0 {
-		return nil, fmt.Errorf("no instance types satisfy requirements of amis %v", lo.Uniq(lo.Map(nodeClass.Status.AMIs, func(a v1beta1.AMI, _ int) string { return a.ID })))
+		return nil, fmt.Errorf("no instance types satisfy requirements of amis %v, instance types have architectures %v",
+			lo.Uniq(lo.Map(nodeClass.Status.AMIs, func(a v1beta1.AMI, _ int) string { return a.ID })),
+			lo.Uniq(lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return architecture(it) })))
 	}
 	var resolvedTemplates []*LaunchTemplate
 	for amiID, instanceTypes := range mappedAMIs {
@@ -134,13 +203,21 @@ func (r Resolver) Resolve(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta
 		// we need to pass down the max-pods calculation to the kubelet.
 		// This requires that we resolve a unique launch template per max-pods value.
 		// Similarly, instance types configured with EfAs require unique launch templates depending on the number of
-		// EFAs they support.
+		// EFAs they support. Launch templates are also split by architecture, so that an AMI whose requirements don't
+		// constrain the architecture is never shared by instance types that can't all boot it. Instance types that
+		// support hibernation, and whose memory fits on the root volume, get their own launch templates when the NodeClaim
+		// can be hibernated, since EC2 rejects launches of other instance types with hibernation configured.
 		type launchTemplateParams struct {
-			efaCount int
-			maxPods  int
+			efaCount     int
+			maxPods      int
+			architecture string
+			hibernation  bool
 		}
+		hibernationVolumeSize := hibernationVolumeSize(nodeClass, nodeClaim, capacityType, amiFamily, options)
 		paramsToInstanceTypes := lo.GroupBy(instanceTypes, func(instanceType *cloudprovider.InstanceType) launchTemplateParams {
 			return launchTemplateParams{
+				architecture: architecture(instanceType),
+				hibernation:  hibernatable(instanceType, hibernationVolumeSize),
 				efaCount: lo.Ternary(
 					lo.Contains(lo.Keys(nodeClaim.Spec.Resources.Requests), v1beta1.ResourceEFA),
 					int(lo.ToPtr(instanceType.Capacity[v1beta1.ResourceEFA]).Value()),
@@ -154,12 +231,102 @@ func (r Resolver) Resolve(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta
 			if err != nil {
 				return nil, err
 			}
+			resolved.HibernationConfigured = params.hibernation
 			resolvedTemplates = append(resolvedTemplates, resolved)
 		}
 	}
 	return resolvedTemplates, nil
 }
 
+func architecture(instanceType *cloudprovider.InstanceType) string {
+	return instanceType.Requirements.Get(core.LabelArchStable).Any()
+}
+
+// hibernationVolumeSize returns the size of the root volume that the instances of the NodeClaim save the contents of
+// memory to when they're hibernated, or nil if they can't be launched with hibernation configured. Only on-demand
+// instances can be hibernated, and EC2 requires their EBS volumes to be encrypted so that the contents of memory aren't
+// written to disk in the clear. The root volume is the first default block device mapping of the AMI family, or the
+// block device mapping marked as the root volume for AMI families without defaults.
+func hibernationVolumeSize(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, capacityType string, amiFamily AMIFamily, options *Options) *resource.Quantity {
+	if !options.Hibernation || capacityType != corev1beta1.CapacityTypeOnDemand || nodeClaim.Annotations[v1beta1.AnnotationHibernation] != v1beta1.HibernationEnabled {
+		return nil
+	}
+	blockDeviceMappings := lo.Ternary(len(nodeClass.Spec.BlockDeviceMappings) != 0, nodeClass.Spec.BlockDeviceMappings, amiFamily.DefaultBlockDeviceMappings())
+	if len(blockDeviceMappings) == 0 || !lo.EveryBy(blockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping) bool {
+		return bdm.EBS != nil && aws.BoolValue(bdm.EBS.Encrypted)
+	}) {
+		return nil
+	}
+	rootVolume, ok := lo.Find(blockDeviceMappings, func(bdm *v1beta1.BlockDeviceMapping) bool {
+		if defaults := amiFamily.DefaultBlockDeviceMappings(); len(defaults) != 0 {
+			return aws.StringValue(bdm.DeviceName) == aws.StringValue(defaults[0].DeviceName)
+		}
+		return bdm.RootVolume
+	})
+	if !ok {
+		return nil
+	}
+	return rootVolume.EBS.VolumeSize
+}
+
+// hibernatable returns whether instances of the instance type can be launched with hibernation configured, given the
+// size of the root volume that they save the contents of memory to. EC2 requires the root volume to be large enough
+// for the contents of memory on top of the OS, so hibernationHeadroom is left for the OS.
+func hibernatable(instanceType *cloudprovider.InstanceType, volumeSize *resource.Quantity) bool {
+	if volumeSize == nil || !instanceType.Requirements.Get(v1beta1.LabelInstanceHibernationSupported).Has("true") {
+		return false
+	}
+	memory, err := strconv.ParseInt(instanceType.Requirements.Get(v1beta1.LabelInstanceMemory).Any(), 10, 64)
+	if err != nil {
+		return false
+	}
+	required := resource.NewQuantity(memory*1024*1024, resource.BinarySI)
+	required.Add(hibernationHeadroom)
+	return volumeSize.Cmp(*required) >= 0
+}
+
+// KubeletOverlay returns the EC2NodeClass's kubelet overlay for the capacity type, or nil if it doesn't have one
+func KubeletOverlay(nodeClass *v1beta1.EC2NodeClass, capacityType string) *v1beta1.KubeletOverlay {
+	if nodeClass.Spec.KubeletOverlays == nil {
+		return nil
+	}
+	switch capacityType {
+	case corev1beta1.CapacityTypeSpot:
+		return nodeClass.Spec.KubeletOverlays.Spot
+	case corev1beta1.CapacityTypeOnDemand:
+		return nodeClass.Spec.KubeletOverlays.OnDemand
+	}
+	return nil
+}
+
+// ApplyKubeletOverlay returns a copy of the kubelet configuration with the overlay applied on top of it. The maps of
+// the overlay are merged by key, so the overlay only needs to set the reservations and thresholds that it changes.
+func ApplyKubeletOverlay(kubeletConfig *corev1beta1.KubeletConfiguration, overlay *v1beta1.KubeletOverlay) *corev1beta1.KubeletConfiguration {
+	if overlay == nil {
+		return kubeletConfig
+	}
+	kubeletConfig = lo.Ternary(kubeletConfig == nil, &corev1beta1.KubeletConfiguration{}, kubeletConfig).DeepCopy()
+	if len(overlay.SystemReserved) != 0 {
+		kubeletConfig.SystemReserved = lo.Assign(kubeletConfig.SystemReserved, overlay.SystemReserved)
+	}
+	if len(overlay.KubeReserved) != 0 {
+		kubeletConfig.KubeReserved = lo.Assign(kubeletConfig.KubeReserved, overlay.KubeReserved)
+	}
+	if len(overlay.EvictionHard) != 0 {
+		kubeletConfig.EvictionHard = lo.Assign(kubeletConfig.EvictionHard, overlay.EvictionHard)
+	}
+	if len(overlay.EvictionSoft) != 0 {
+		kubeletConfig.EvictionSoft = lo.Assign(kubeletConfig.EvictionSoft, overlay.EvictionSoft)
+	}
+	if len(overlay.EvictionSoftGracePeriod) != 0 {
+		kubeletConfig.EvictionSoftGracePeriod = lo.Assign(kubeletConfig.EvictionSoftGracePeriod, overlay.EvictionSoftGracePeriod)
+	}
+	if overlay.EvictionMaxPodGracePeriod != nil {
+		kubeletConfig.EvictionMaxPodGracePeriod = overlay.EvictionMaxPodGracePeriod
+	}
+	return kubeletConfig
+}
+
 func GetAMIFamily(amiFamily *string, options *Options) AMIFamily {
 	switch aws.StringValue(amiFamily) {
 	case v1beta1.AMIFamilyBottlerocket:
@@ -188,7 +355,7 @@ func (o Options) DefaultMetadataOptions() *v1beta1.MetadataOptions {
 	}
 }
 
-func (r Resolver) defaultClusterDNS(opts *Options, kubeletConfig *corev1beta1.KubeletConfiguration) *corev1beta1.KubeletConfiguration {
+func (r DefaultResolver) defaultClusterDNS(opts *Options, kubeletConfig *corev1beta1.KubeletConfiguration) *corev1beta1.KubeletConfiguration {
 	if opts.KubeDNSIP == nil {
 		return kubeletConfig
 	}
@@ -205,7 +372,15 @@ func (r Resolver) defaultClusterDNS(opts *Options, kubeletConfig *corev1beta1.Ku
 	return newKubeletConfig
 }
 
-func (r Resolver) resolveLaunchTemplate(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string,
+// clusterDNS returns the cluster DNS IP that the kubelet is configured with, which is the only one that bootstrappers use
+func clusterDNS(kubeletConfig *corev1beta1.KubeletConfiguration) string {
+	if len(kubeletConfig.ClusterDNS) == 0 {
+		return ""
+	}
+	return kubeletConfig.ClusterDNS[0]
+}
+
+func (r DefaultResolver) resolveLaunchTemplate(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string,
 	amiFamily AMIFamily, amiID string, maxPods int, efaCount int, options *Options) (*LaunchTemplate, error) {
 	kubeletConfig := &corev1beta1.KubeletConfiguration{}
 	if nodeClaim.Spec.Kubelet != nil {
@@ -213,18 +388,39 @@ func (r Resolver) resolveLaunchTemplate(nodeClass *v1beta1.EC2NodeClass, nodeCla
 			return nil, err
 		}
 	}
+	kubeletConfig = ApplyKubeletOverlay(kubeletConfig, KubeletOverlay(nodeClass, capacityType))
 	if kubeletConfig.MaxPods == nil {
 		kubeletConfig.MaxPods = lo.ToPtr(int32(maxPods))
 	}
+	kubeletConfig = r.defaultClusterDNS(options, kubeletConfig)
+	taints := append(nodeClaim.Spec.Taints, nodeClaim.Spec.StartupTaints...)
+	userData, err := bootstrap.RenderUserData(nodeClass.Spec.UserData, bootstrap.TemplateVariables{
+		ClusterName:     options.ClusterName,
+		ClusterEndpoint: options.ClusterEndpoint,
+		NodeClassName:   nodeClass.Name,
+		NodePoolName:    nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+		NodeLabels:      options.Labels,
+		Taints:          taints,
+		KubeletConfig:   kubeletConfig,
+		CapacityType:    capacityType,
+		Architecture:    architecture(instanceTypes[0]),
+		AMIID:           amiID,
+		InstanceTypes:   lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }),
+		MaxPods:         int(lo.FromPtr(kubeletConfig.MaxPods)),
+		ClusterDNS:      clusterDNS(kubeletConfig),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resolving userData for EC2NodeClass %q, %w", nodeClass.Name, err)
+	}
 	resolved := &LaunchTemplate{
 		Options: options,
 		UserData: amiFamily.UserData(
-			r.defaultClusterDNS(options, kubeletConfig),
-			append(nodeClaim.Spec.Taints, nodeClaim.Spec.StartupTaints...),
+			kubeletConfig,
+			taints,
 			options.Labels,
 			options.CABundle,
 			instanceTypes,
-			nodeClass.Spec.UserData,
+			userData,
 			options.InstanceStorePolicy,
 		),
 		BlockDeviceMappings: nodeClass.Spec.BlockDeviceMappings,
@@ -235,6 +431,9 @@ func (r Resolver) resolveLaunchTemplate(nodeClass *v1beta1.EC2NodeClass, nodeCla
 		EFACount:            efaCount,
 		CapacityType:        capacityType,
 	}
+	if nodeClaim.Annotations[v1beta1.AnnotationTerminationProtection] == v1beta1.TerminationProtectionEnabled {
+		resolved.DisableAPITermination = true
+	}
 	if len(resolved.BlockDeviceMappings) == 0 {
 		resolved.BlockDeviceMappings = amiFamily.DefaultBlockDeviceMappings()
 	}
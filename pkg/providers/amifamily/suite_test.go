@@ -60,6 +60,7 @@ const (
 	arm64AMI       = "arm64-ami-id"
 	amd64NvidiaAMI = "amd64-nvidia-ami-id"
 	arm64NvidiaAMI = "arm64-nvidia-ami-id"
+	amd64NeuronAMI = "amd64-neuron-ami-id"
 )
 
 var _ = BeforeSuite(func() {
@@ -146,11 +147,54 @@ var _ = Describe("AMIProvider", func() {
 		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
 		awsEnv.SSMAPI.Parameters = map[string]string{
 			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version): amd64AMI,
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia/recommended/image_id", version):   amd64NvidiaAMI,
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/neuron/recommended/image_id", version):   amd64NeuronAMI,
 			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/arm64/standard/recommended/image_id", version):  arm64AMI,
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/arm64/nvidia/recommended/image_id", version):    arm64NvidiaAMI,
 		}
 		amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(amis).To(HaveLen(2))
+		Expect(amis).To(HaveLen(5))
+	})
+	It("should resolve accelerated AMI variants for accelerated instance types", func() {
+		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
+		awsEnv.SSMAPI.Parameters = map[string]string{
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version): amd64AMI,
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia/recommended/image_id", version):   amd64NvidiaAMI,
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/neuron/recommended/image_id", version):   amd64NeuronAMI,
+		}
+		amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		variants := lo.SliceToMap(amis, func(a amifamily.AMI) (string, string) { return a.AmiID, a.Variant })
+		Expect(variants).To(Equal(map[string]string{
+			amd64AMI:       amifamily.VariantStandard,
+			amd64NvidiaAMI: amifamily.VariantNvidia,
+			amd64NeuronAMI: amifamily.VariantNeuron,
+		}))
+		for _, ami := range amis {
+			gpu := ami.Requirements.Get(v1beta1.LabelInstanceGPUCount)
+			accelerator := ami.Requirements.Get(v1beta1.LabelInstanceAcceleratorCount)
+			switch ami.Variant {
+			case amifamily.VariantStandard:
+				Expect(gpu.Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+				Expect(accelerator.Operator()).To(Equal(v1.NodeSelectorOpDoesNotExist))
+			case amifamily.VariantNvidia:
+				Expect(gpu.Operator()).To(Equal(v1.NodeSelectorOpExists))
+			case amifamily.VariantNeuron:
+				Expect(accelerator.Operator()).To(Equal(v1.NodeSelectorOpExists))
+			}
+		}
+	})
+	It("should resolve accelerated AMI variants at the pinned release (AL2023)", func() {
+		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
+		nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{ReleaseVersion: lo.ToPtr("v20240703")}
+		awsEnv.SSMAPI.Parameters = map[string]string{
+			fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia/amazon-eks-node-al2023-x86_64-nvidia-%s-v20240703/image_id", version, version): amd64NvidiaAMI,
+		}
+		amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(1))
+		Expect(amis[0].Variant).To(Equal(amifamily.VariantNvidia))
 	})
 	It("should succeed to resolve AMIs (Bottlerocket)", func() {
 		nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
@@ -198,6 +242,81 @@ var _ = Describe("AMIProvider", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(amis).To(HaveLen(0))
 	})
+	Context("Release Version", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.AMISelectionPolicy = &v1beta1.AMISelectionPolicy{ReleaseVersion: lo.ToPtr("v20240703")}
+		})
+		It("should resolve AMIs at the pinned release (AL2)", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/amazon-eks-node-%s-v20240703/image_id", version, version):             amd64AMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/amazon-eks-gpu-node-%s-v20240703/image_id", version, version):     amd64NvidiaAMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-arm64/amazon-eks-arm64-node-%s-v20240703/image_id", version, version): arm64AMI,
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(4))
+		})
+		It("should resolve AMIs at the pinned release (AL2023)", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/amazon-eks-node-al2023-x86_64-standard-%s-v20240703/image_id", version, version): amd64AMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/arm64/standard/amazon-eks-node-al2023-arm64-standard-%s-v20240703/image_id", version, version):   arm64AMI,
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(2))
+		})
+		It("should resolve AMIs at the pinned release (Bottlerocket)", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+			nodeClass.Spec.AMISelectionPolicy.ReleaseVersion = lo.ToPtr("1.20.3")
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/1.20.3/image_id", version):        amd64AMI,
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-nvidia/x86_64/1.20.3/image_id", version): amd64NvidiaAMI,
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/arm64/1.20.3/image_id", version):         arm64AMI,
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-nvidia/arm64/1.20.3/image_id", version):  arm64NvidiaAMI,
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(6))
+		})
+		It("should return the latest release version", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2/recommended/release_version", version): fmt.Sprintf("%s.0-20240710", version),
+			}
+			Expect(awsEnv.AMIProvider.LatestReleaseVersion(ctx, nodeClass)).To(Equal("v20240710"))
+
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/latest/image_version", version): "1.20.4-5d9ac849",
+			}
+			Expect(awsEnv.AMIProvider.LatestReleaseVersion(ctx, nodeClass)).To(Equal("1.20.4"))
+		})
+		It("should return an empty release version for families that can't be pinned", func() {
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyUbuntu
+			Expect(awsEnv.AMIProvider.LatestReleaseVersion(ctx, nodeClass)).To(BeEmpty())
+		})
+	})
+	It("should return the deprecation times of AMIs, including AMIs that were deprecated", func() {
+		deprecationTime := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+		awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{
+			{
+				Name:            aws.String("deprecated-ami"),
+				ImageId:         aws.String("ami-deprecated"),
+				DeprecationTime: aws.String(deprecationTime.Format(time.RFC3339)),
+			},
+			{
+				Name:    aws.String("current-ami"),
+				ImageId: aws.String("ami-current"),
+			},
+		}})
+		deprecationTimes, err := awsEnv.AMIProvider.DeprecationTimes(ctx, []string{"ami-deprecated", "ami-current"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deprecationTimes).To(Equal(map[string]time.Time{"ami-deprecated": deprecationTime}))
+		input := awsEnv.EC2API.CalledWithDescribeImagesInput.Pop()
+		Expect(aws.BoolValue(input.IncludeDeprecated)).To(BeTrue())
+	})
 	It("should not cause data races when calling Get() simultaneously", func() {
 		nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{
 			{
@@ -288,6 +407,74 @@ var _ = Describe("AMIProvider", func() {
 			Expect(amis).To(HaveLen(1))
 		})
 	})
+	Context("Boot Options", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []*ec2.Image{
+					{
+						Name:         aws.String("uefi"),
+						ImageId:      aws.String("ami-uefi"),
+						CreationDate: aws.String("2022-08-15T12:00:00Z"),
+						Architecture: aws.String("x86_64"),
+						BootMode:     aws.String(ec2.BootModeValuesUefi),
+						TpmSupport:   aws.String(ec2.TpmSupportValuesV20),
+					},
+					{
+						Name:         aws.String("uefi-preferred"),
+						ImageId:      aws.String("ami-uefi-preferred"),
+						CreationDate: aws.String("2022-08-14T12:00:00Z"),
+						Architecture: aws.String("x86_64"),
+						BootMode:     aws.String(ec2.BootModeValuesUefiPreferred),
+					},
+					{
+						Name:         aws.String("legacy-bios"),
+						ImageId:      aws.String("ami-legacy-bios"),
+						CreationDate: aws.String("2022-08-13T12:00:00Z"),
+						Architecture: aws.String("x86_64"),
+					},
+					{
+						Name:         aws.String("arm64"),
+						ImageId:      aws.String("ami-arm64"),
+						CreationDate: aws.String("2022-08-12T12:00:00Z"),
+						Architecture: aws.String("arm64"),
+					},
+				},
+			})
+		})
+		It("should only resolve AMIs that boot with the uefi boot mode", func() {
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeUEFI)
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			// The newest compatible AMI is selected for each architecture
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf("ami-uefi", "ami-arm64"))
+		})
+		It("should fall back to an older AMI when the newest doesn't boot with the boot mode", func() {
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeLegacyBIOS)
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf("ami-legacy-bios"))
+		})
+		It("should only resolve AMIs that support NitroTPM 2.0", func() {
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeUEFI)
+			nodeClass.Spec.NitroTPM = lo.ToPtr(true)
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf("ami-uefi"))
+		})
+		It("should only resolve default AMIs that boot with the boot mode", func() {
+			nodeClass.Spec.AMISelectorTerms = nil
+			nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeUEFI)
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version): "ami-legacy-bios",
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/arm64/standard/recommended/image_id", version):  "ami-arm64",
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf("ami-arm64"))
+		})
+	})
 	Context("AMI Tag Requirements", func() {
 		var img *ec2.Image
 		BeforeEach(func() {
@@ -328,6 +515,91 @@ var _ = Describe("AMIProvider", func() {
 			}))
 		})
 	})
+	Context("AMI Driver Requirements", func() {
+		It("should resolve driver version tags as requirements", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{{
+				Name:         aws.String(amd64NvidiaAMI),
+				ImageId:      aws.String("amd64-nvidia-ami-id"),
+				CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+				Architecture: aws.String("x86_64"),
+				Tags: []*ec2.Tag{
+					{Key: aws.String(v1beta1.TagGPUDriverVersion), Value: aws.String("550.54.15")},
+					{Key: aws.String(v1beta1.TagCUDAVersion), Value: aws.String("12.4")},
+				},
+			}}})
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Requirements.Get(v1beta1.LabelAMIGPUDriverVersion).Values()).To(ConsistOf("550"))
+			Expect(amis[0].Requirements.Get(v1beta1.LabelAMICUDAVersion).Values()).To(ConsistOf("12040"))
+		})
+		It("should ignore driver version tags that can't be parsed", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []*ec2.Image{{
+				Name:         aws.String(amd64NvidiaAMI),
+				ImageId:      aws.String("amd64-nvidia-ami-id"),
+				CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+				Architecture: aws.String("x86_64"),
+				Tags: []*ec2.Tag{
+					{Key: aws.String(v1beta1.TagGPUDriverVersion), Value: aws.String("latest")},
+					{Key: aws.String(v1beta1.TagCUDAVersion), Value: aws.String("12")},
+				},
+			}}})
+			nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Requirements.Has(v1beta1.LabelAMIGPUDriverVersion)).To(BeFalse())
+			Expect(amis[0].Requirements.Has(v1beta1.LabelAMICUDAVersion)).To(BeFalse())
+		})
+		Context("Mixed Driver Fleets", func() {
+			var amis []v1beta1.AMI
+			BeforeEach(func() {
+				amis = []v1beta1.AMI{
+					{ID: "ami-cuda-12-4", Requirements: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}},
+						{Key: v1beta1.LabelAMICUDAVersion, Operator: v1.NodeSelectorOpIn, Values: []string{"12040"}},
+					}},
+					{ID: "ami-cuda-12-2", Requirements: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}},
+						{Key: v1beta1.LabelAMICUDAVersion, Operator: v1.NodeSelectorOpIn, Values: []string{"12020"}},
+					}},
+					{ID: "ami-arm64", Requirements: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureArm64}},
+					}},
+				}
+			})
+			It("should offer the driver versions of every AMI that an instance type can be launched with", func() {
+				requirements := amifamily.DriverRequirements(scheduling.NewRequirements(
+					scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
+				), amis)
+				Expect(requirements.Get(v1beta1.LabelAMICUDAVersion).Values()).To(ConsistOf("12040", "12020"))
+				Expect(requirements.Has(v1beta1.LabelAMIGPUDriverVersion)).To(BeFalse())
+			})
+			It("should not offer driver versions when an AMI that the instance type can be launched with doesn't declare them", func() {
+				requirements := amifamily.DriverRequirements(scheduling.NewRequirements(
+					scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureArm64),
+				), amis)
+				Expect(requirements.Has(v1beta1.LabelAMICUDAVersion)).To(BeFalse())
+			})
+			It("should only launch with AMIs whose drivers satisfy the nodeclaim", func() {
+				compatible := amifamily.DriverCompatible(amis, scheduling.NewRequirements(
+					scheduling.NewRequirement(v1beta1.LabelAMICUDAVersion, v1.NodeSelectorOpGt, "12030"),
+				))
+				Expect(lo.Map(compatible, func(a v1beta1.AMI, _ int) string { return a.ID })).To(ConsistOf("ami-cuda-12-4"))
+			})
+			It("should launch with any AMI when the nodeclaim doesn't constrain drivers", func() {
+				compatible := amifamily.DriverCompatible(amis, scheduling.NewRequirements(
+					scheduling.NewRequirement(v1.LabelArchStable, v1.NodeSelectorOpIn, corev1beta1.ArchitectureAmd64),
+				))
+				Expect(compatible).To(HaveLen(3))
+			})
+			It("should label nodes with the driver versions of their AMI", func() {
+				Expect(amifamily.DriverLabelsFor(amis[0])).To(Equal(map[string]string{v1beta1.LabelAMICUDAVersion: "12040"}))
+				Expect(amifamily.DriverLabelsFor(amis[2])).To(BeEmpty())
+			})
+		})
+	})
 	Context("AMI Selectors", func() {
 		// When you tag public or shared resources, the tags you assign are available only to your AWS account; no other AWS account will have access to those tags
 		// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/Using_Tags.html#tag-restrictions
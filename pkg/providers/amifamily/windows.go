@@ -41,7 +41,7 @@ type Windows struct {
 	Build   string
 }
 
-func (w Windows) DefaultAMIs(version string) []DefaultAMIOutput {
+func (w Windows) DefaultAMIs(version string, _ string) []DefaultAMIOutput {
 	return []DefaultAMIOutput{
 		{
 			Query: fmt.Sprintf("/aws/service/ami-windows-latest/Windows_Server-%s-English-%s-EKS_Optimized-%s/image_id", w.Version, v1beta1.WindowsCore, version),
@@ -50,6 +50,7 @@ func (w Windows) DefaultAMIs(version string) []DefaultAMIOutput {
 				scheduling.NewRequirement(v1.LabelOSStable, v1.NodeSelectorOpIn, string(v1.Windows)),
 				scheduling.NewRequirement(v1.LabelWindowsBuild, v1.NodeSelectorOpIn, w.Build),
 			),
+			Variant: VariantStandard,
 		},
 	}
 }
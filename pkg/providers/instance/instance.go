@@ -16,26 +16,29 @@ package instance
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"math"
 	"sort"
-	"strings"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
-	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/auditlog"
 	"github.com/aws/karpenter-provider-aws/pkg/batcher"
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
@@ -43,6 +46,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/recommendation"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -51,63 +55,119 @@ import (
 
 const (
 	instanceTypeFlexibilityThreshold = 5 // falling back to on-demand without flexibility risks insufficient capacity errors
-	maxInstanceTypes                 = 60
 )
 
 var (
-	instanceStateFilter = &ec2.Filter{
-		Name:   aws.String("instance-state-name"),
-		Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning, ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped, ec2.InstanceStateNameShuttingDown}),
+	instanceStateFilter = ec2types.Filter{
+		Name: aws.String("instance-state-name"),
+		Values: []string{
+			string(ec2types.InstanceStateNamePending),
+			string(ec2types.InstanceStateNameRunning),
+			string(ec2types.InstanceStateNameStopping),
+			string(ec2types.InstanceStateNameStopped),
+			string(ec2types.InstanceStateNameShuttingDown),
+		},
 	}
 )
 
+// EC2API is the part of the aws-sdk-go-v2 EC2 client that the provider launches and manages instances with
+type EC2API interface {
+	batcher.EC2Client
+	CreateTags(context.Context, *ec2.CreateTagsInput, ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DeleteTags(context.Context, *ec2.DeleteTagsInput, ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error)
+	GetConsoleOutput(context.Context, *ec2.GetConsoleOutputInput, ...func(*ec2.Options)) (*ec2.GetConsoleOutputOutput, error)
+	ModifyInstanceAttribute(context.Context, *ec2.ModifyInstanceAttributeInput, ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error)
+	StartInstances(context.Context, *ec2.StartInstancesInput, ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	StopInstances(context.Context, *ec2.StopInstancesInput, ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+}
+
 type Provider interface {
 	Create(context.Context, *v1beta1.EC2NodeClass, *corev1beta1.NodeClaim, []*cloudprovider.InstanceType) (*Instance, error)
 	Get(context.Context, string) (*Instance, error)
 	List(context.Context) ([]*Instance, error)
 	Delete(context.Context, string) error
 	CreateTags(context.Context, string, map[string]string) error
+	GetConsoleOutput(context.Context, string) (string, error)
+	SetTerminationProtection(context.Context, string, bool) error
+	LaunchWarm(context.Context, *v1beta1.EC2NodeClass, *corev1beta1.NodeClaim, []*cloudprovider.InstanceType) (*Instance, error)
+	ListWarm(context.Context) ([]*Instance, error)
+	Stop(context.Context, string) error
+	Hibernate(context.Context, string, string) error
+	ListHibernated(context.Context) ([]*Instance, error)
 }
 
 type DefaultProvider struct {
 	region                 string
-	ec2api                 ec2iface.EC2API
+	ec2api                 EC2API
 	unavailableOfferings   *cache.UnavailableOfferings
+	zoneHealth             *cache.ZoneHealth
+	creationLimits         *cache.CreationLimits
+	zoneScores             *cache.ZoneScores
 	instanceTypeProvider   instancetype.Provider
 	subnetProvider         subnet.Provider
 	launchTemplateProvider launchtemplate.Provider
 	ec2Batcher             *batcher.EC2API
+	scorer                 *WeightedScorer
+	zoneBalanceScorer      *ZoneBalanceScorer
+	auditLogger            auditlog.Logger
+	clock                  clock.Clock
+	// stoppedInstancesMu keeps concurrent launches from starting the same warm or hibernated instance
+	stoppedInstancesMu sync.Mutex
 }
 
-func NewDefaultProvider(ctx context.Context, region string, ec2api ec2iface.EC2API, unavailableOfferings *cache.UnavailableOfferings,
-	instanceTypeProvider instancetype.Provider, subnetProvider subnet.Provider, launchTemplateProvider launchtemplate.Provider) *DefaultProvider {
+func NewDefaultProvider(ctx context.Context, region string, ec2api EC2API, unavailableOfferings *cache.UnavailableOfferings, zoneHealth *cache.ZoneHealth,
+	creationLimits *cache.CreationLimits, zoneScores *cache.ZoneScores, instanceTypeProvider instancetype.Provider, subnetProvider subnet.Provider, launchTemplateProvider launchtemplate.Provider,
+	auditLogger auditlog.Logger, clk clock.Clock) *DefaultProvider {
+	zoneBalanceScorer := NewZoneBalanceScorer()
 	return &DefaultProvider{
 		region:                 region,
 		ec2api:                 ec2api,
 		unavailableOfferings:   unavailableOfferings,
+		zoneHealth:             zoneHealth,
+		creationLimits:         creationLimits,
+		zoneScores:             zoneScores,
 		instanceTypeProvider:   instanceTypeProvider,
 		subnetProvider:         subnetProvider,
 		launchTemplateProvider: launchTemplateProvider,
 		ec2Batcher:             batcher.EC2(ctx, ec2api),
+		scorer:                 NewWeightedScorer(PriceScorer{}, FlexibilityScorer{}, InterruptionRiskScorer{}, zoneBalanceScorer, ZoneSuitabilityScorer{zoneScores: zoneScores}),
+		zoneBalanceScorer:      zoneBalanceScorer,
+		auditLogger:            auditLogger,
+		clock:                  clk,
 	}
 }
 
 func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) (*Instance, error) {
-	schedulingRequirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	// Only filter the instances if there are no minValues in the requirement.
-	if !schedulingRequirements.HasMinValues() {
-		instanceTypes = p.filterInstanceTypes(nodeClaim, instanceTypes)
+	if options.FromContext(ctx).WarmPools {
+		if instance := p.startWarmInstance(ctx, nodeClaim, instanceTypes); instance != nil {
+			return instance, nil
+		}
 	}
-	instanceTypes, err := cloudprovider.InstanceTypes(instanceTypes).Truncate(schedulingRequirements, maxInstanceTypes)
+	if options.FromContext(ctx).Hibernation && nodeClaim.Annotations[v1beta1.AnnotationHibernation] == v1beta1.HibernationEnabled {
+		if instance := p.resumeHibernatedInstance(ctx, nodeClaim, instanceTypes); instance != nil {
+			return instance, nil
+		}
+	}
+	schedulingRequirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	instanceTypes, capacityType, err := recommendation.SelectInstanceTypes(schedulingRequirements, deprioritizedInstanceTypes(ctx, nodeClaim), instanceTypes)
 	if err != nil {
 		return nil, fmt.Errorf("truncating instance types, %w", err)
 	}
-	tags := getTags(ctx, nodeClass, nodeClaim)
-	fleetInstance, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+	tags, err := getTags(ctx, nodeClass, nodeClaim)
+	if err != nil {
+		return nil, err
+	}
+	fleetInstance, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
 	if awserrors.IsLaunchTemplateNotFound(err) {
 		// retry once if launch template is not found. This allows karpenter to generate a new LT if the
 		// cache was out-of-sync on the first try
-		fleetInstance, err = p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, tags)
+		fleetInstance, err = p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
+	}
+	if p.shouldBackstopOnDemand(ctx, nodeClaim, instanceTypes, capacityType, err) {
+		// EC2 Fleet doesn't fall back between capacity types within a single request, so spot that couldn't be
+		// fulfilled is backstopped with an on-demand request immediately rather than on the next provisioning loop
+		log.FromContext(ctx).WithValues("error", err).V(1).Info("spot capacity unavailable, launching on-demand backstop")
+		fleetInstance, err = p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, corev1beta1.CapacityTypeOnDemand, tags)
 	}
 	if err != nil {
 		return nil, err
@@ -118,8 +178,8 @@ func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1beta1.EC2Node
 
 func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error) {
 	out, err := p.ec2Batcher.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-		InstanceIds: aws.StringSlice([]string{id}),
-		Filters:     []*ec2.Filter{instanceStateFilter},
+		InstanceIds: []string{id},
+		Filters:     []ec2types.Filter{instanceStateFilter},
 	})
 	if awserrors.IsNotFound(err) {
 		return nil, cloudprovider.NewNodeClaimNotFoundError(err)
@@ -134,41 +194,47 @@ func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error)
 	if len(instances) != 1 {
 		return nil, fmt.Errorf("expected a single instance, %w", err)
 	}
+	// Hibernated instances are no longer linked to their NodeClaim, so its termination completes
+	if _, ok := instances[0].Tags[v1beta1.TagHibernated]; ok {
+		return nil, cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance is hibernated"))
+	}
 	return instances[0], nil
 }
 
 func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
-	var out = &ec2.DescribeInstancesOutput{}
-	err := p.ec2api.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
+	out, err := p.describeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
 			{
 				Name:   aws.String("tag-key"),
-				Values: aws.StringSlice([]string{corev1beta1.NodePoolLabelKey}),
+				Values: []string{corev1beta1.NodePoolLabelKey},
 			},
 			{
 				Name:   aws.String("tag-key"),
-				Values: aws.StringSlice([]string{v1beta1.LabelNodeClass}),
+				Values: []string{v1beta1.LabelNodeClass},
 			},
 			{
 				Name:   aws.String("tag-key"),
-				Values: aws.StringSlice([]string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)}),
+				Values: []string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)},
 			},
 			instanceStateFilter,
 		},
-	}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
-		out.Reservations = append(out.Reservations, page.Reservations...)
-		return true
 	})
 	if err != nil {
 		return nil, fmt.Errorf("describing ec2 instances, %w", err)
 	}
 	instances, err := instancesFromOutput(out)
-	return instances, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	// Warm pool and hibernated instances don't have NodeClaims until they're started, so they're left to the warm pool
+	// and hibernation controllers
+	return lo.Reject(instances, func(i *Instance, _ int) bool {
+		_, warm := i.Tags[v1beta1.TagWarmPool]
+		_, hibernated := i.Tags[v1beta1.TagHibernated]
+		return warm || hibernated
+	}), cloudprovider.IgnoreNodeClaimNotFoundError(err)
 }
 
 func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
 	if _, err := p.ec2Batcher.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-		InstanceIds: []*string{aws.String(id)},
+		InstanceIds: []string{id},
 	}); err != nil {
 		if awserrors.IsNotFound(err) {
 			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance already terminated"))
@@ -185,11 +251,11 @@ func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
 }
 
 func (p *DefaultProvider) CreateTags(ctx context.Context, id string, tags map[string]string) error {
-	ec2Tags := lo.MapToSlice(tags, func(key, value string) *ec2.Tag {
-		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	ec2Tags := lo.MapToSlice(tags, func(key, value string) ec2types.Tag {
+		return ec2types.Tag{Key: aws.String(key), Value: aws.String(value)}
 	})
-	if _, err := p.ec2api.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
-		Resources: aws.StringSlice([]string{id}),
+	if _, err := p.ec2api.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{id},
 		Tags:      ec2Tags,
 	}); err != nil {
 		if awserrors.IsNotFound(err) {
@@ -200,77 +266,387 @@ func (p *DefaultProvider) CreateTags(ctx context.Context, id string, tags map[st
 	return nil
 }
 
-func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, tags map[string]string) (*ec2.CreateFleetInstance, error) {
-	capacityType := p.getCapacityType(nodeClaim, instanceTypes)
-	zonalSubnets, err := p.subnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, capacityType)
+// SetTerminationProtection enables or disables EC2 termination protection on the instance
+func (p *DefaultProvider) SetTerminationProtection(ctx context.Context, id string, enabled bool) error {
+	if _, err := p.ec2api.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:            aws.String(id),
+		DisableApiTermination: &ec2types.AttributeBooleanValue{Value: aws.Bool(enabled)},
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("setting termination protection, %w", err))
+		}
+		return fmt.Errorf("setting termination protection, %w", err)
+	}
+	return nil
+}
+
+// LaunchWarm launches an on-demand instance for the warm pool of the NodeClaim's NodePool. The instance bootstraps and
+// joins the cluster like any other, and is stopped by the warm pool controller once its node is ready.
+func (p *DefaultProvider) LaunchWarm(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) (*Instance, error) {
+	schedulingRequirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	instanceTypes, _, err := recommendation.SelectInstanceTypes(schedulingRequirements, deprioritizedInstanceTypes(ctx, nodeClaim), instanceTypes)
+	if err != nil {
+		return nil, fmt.Errorf("truncating instance types, %w", err)
+	}
+	// The node of the instance is registered with the warm pool taint so that no pods are scheduled to it before it's
+	// started for a NodeClaim
+	nodeClaim = nodeClaim.DeepCopy()
+	nodeClaim.Spec.Taints = append(nodeClaim.Spec.Taints, v1beta1.WarmPoolNoScheduleTaint)
+	// Spot instances can't be stopped, so warm pools are only ever on-demand
+	tags, err := getTags(ctx, nodeClass, nodeClaim)
+	if err != nil {
+		return nil, err
+	}
+	tags = lo.Assign(tags, map[string]string{v1beta1.TagWarmPool: nodeClaim.Labels[corev1beta1.NodePoolLabelKey]})
+	fleetInstance, err := p.launchInstance(ctx, nodeClass, nodeClaim, instanceTypes, corev1beta1.CapacityTypeOnDemand, tags)
+	if err != nil {
+		return nil, err
+	}
+	return NewInstanceFromFleet(fleetInstance, tags, false), nil
+}
+
+// ListWarm returns the instances of the warm pools of the cluster, whether they're still bootstrapping or stopped
+func (p *DefaultProvider) ListWarm(ctx context.Context) ([]*Instance, error) {
+	return p.listTagged(ctx, ec2types.Filter{
+		Name:   aws.String("tag-key"),
+		Values: []string{v1beta1.TagWarmPool},
+	}, instanceStateFilter)
+}
+
+// ListHibernated returns the hibernated instances of the cluster, whether they're still stopping or stopped
+func (p *DefaultProvider) ListHibernated(ctx context.Context) ([]*Instance, error) {
+	return p.listTagged(ctx, ec2types.Filter{
+		Name:   aws.String("tag-key"),
+		Values: []string{v1beta1.TagHibernated},
+	}, instanceStateFilter)
+}
+
+func (p *DefaultProvider) listTagged(ctx context.Context, filters ...ec2types.Filter) ([]*Instance, error) {
+	out, err := p.describeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: append([]ec2types.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)},
+			},
+		}, filters...),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing ec2 instances, %w", err)
+	}
+	instances, err := instancesFromOutput(out)
+	return instances, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+}
+
+// describeInstances returns the reservations of all the pages of instances that match the input
+func (p *DefaultProvider) describeInstances(ctx context.Context, input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	out := &ec2.DescribeInstancesOutput{}
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2api, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out.Reservations = append(out.Reservations, page.Reservations...)
+	}
+	return out, nil
+}
+
+// Stop stops the instance, keeping its EBS volumes so that it can be started again
+func (p *DefaultProvider) Stop(ctx context.Context, id string) error {
+	if _, err := p.ec2api.StopInstances(ctx, &ec2.StopInstancesInput{
+		InstanceIds: []string{id},
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("stopping instance, %w", err))
+		}
+		return fmt.Errorf("stopping instance, %w", err)
+	}
+	return nil
+}
+
+// Hibernate tags the instance as a hibernated instance of the NodePool and hibernates it, so that it can be resumed
+// for a later NodeClaim of the NodePool. The instance must have been launched with hibernation configured.
+func (p *DefaultProvider) Hibernate(ctx context.Context, id string, nodePool string) error {
+	if err := p.CreateTags(ctx, id, map[string]string{
+		v1beta1.TagHibernated:      nodePool,
+		v1beta1.TagHibernationTime: p.clock.Now().Format(time.RFC3339),
+	}); err != nil {
+		return err
+	}
+	if _, err := p.ec2api.StopInstances(ctx, &ec2.StopInstancesInput{
+		InstanceIds: []string{id},
+		Hibernate:   aws.Bool(true),
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("hibernating instance, %w", err))
+		}
+		return fmt.Errorf("hibernating instance, %w", err)
+	}
+	return nil
+}
+
+// startWarmInstance starts a stopped instance of the warm pool of the NodeClaim's NodePool whose instance type and
+// zone are compatible with the NodeClaim, and returns nil if there isn't one. Errors are logged rather than returned
+// so that the NodeClaim is launched through CreateFleet instead.
+func (p *DefaultProvider) startWarmInstance(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) *Instance {
+	// The warm pool tag is removed before the instance is started so that the warm pool controller doesn't stop it
+	// again. If the start then fails, the instance has neither a NodeClaim nor the tag and is garbage collected.
+	instance := p.startStoppedInstance(ctx, nodeClaim, instanceTypes, v1beta1.TagWarmPool)
+	if instance != nil {
+		log.FromContext(ctx).WithValues("id", instance.ID, "instance-type", instance.Type, "zone", instance.Zone).V(1).Info("started warm pool instance")
+	}
+	return instance
+}
+
+// resumeHibernatedInstance resumes a hibernated instance of the NodeClaim's NodePool whose instance type and zone are
+// compatible with the NodeClaim, and returns nil if there isn't one. Errors are logged rather than returned so that
+// the NodeClaim is launched through CreateFleet instead.
+func (p *DefaultProvider) resumeHibernatedInstance(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) *Instance {
+	// The hibernation tags are removed before the instance is started so that it's linked to the NodeClaim again. If
+	// the start then fails, the instance has neither a NodeClaim nor the tags and is garbage collected.
+	instance := p.startStoppedInstance(ctx, nodeClaim, instanceTypes, v1beta1.TagHibernated, v1beta1.TagHibernationTime)
+	if instance != nil {
+		log.FromContext(ctx).WithValues("id", instance.ID, "instance-type", instance.Type, "zone", instance.Zone).V(1).Info("resumed hibernated instance")
+	}
+	return instance
+}
+
+// startStoppedInstance starts a stopped instance whose first tag key is set to the name of the NodeClaim's NodePool
+// and whose instance type and zone are compatible with the NodeClaim, after removing the tag keys from it
+func (p *DefaultProvider) startStoppedInstance(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, tagKeys ...string) *Instance {
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	if !reqs.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeOnDemand) {
+		return nil
+	}
+	p.stoppedInstancesMu.Lock()
+	defer p.stoppedInstancesMu.Unlock()
+	instances, err := p.listTagged(ctx,
+		ec2types.Filter{
+			Name:   aws.String(fmt.Sprintf("tag:%s", tagKeys[0])),
+			Values: []string{nodeClaim.Labels[corev1beta1.NodePoolLabelKey]},
+		},
+		ec2types.Filter{
+			Name:   aws.String("instance-state-name"),
+			Values: []string{string(ec2types.InstanceStateNameStopped)},
+		},
+	)
+	if err != nil {
+		log.FromContext(ctx).WithValues("tag", tagKeys[0]).Error(err, "failed listing stopped instances")
+		return nil
+	}
+	instanceTypeNames := sets.New(lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })...)
+	instance, ok := lo.Find(instances, func(i *Instance) bool {
+		return instanceTypeNames.Has(i.Type) && reqs.Get(v1.LabelTopologyZone).Has(i.Zone)
+	})
+	if !ok {
+		return nil
+	}
+	if _, err = p.ec2api.DeleteTags(ctx, &ec2.DeleteTagsInput{
+		Resources: []string{instance.ID},
+		Tags:      lo.Map(tagKeys, func(k string, _ int) ec2types.Tag { return ec2types.Tag{Key: aws.String(k)} }),
+	}); err != nil {
+		log.FromContext(ctx).WithValues("id", instance.ID).Error(err, "failed untagging stopped instance")
+		return nil
+	}
+	if _, err = p.ec2api.StartInstances(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []string{instance.ID},
+	}); err != nil {
+		log.FromContext(ctx).WithValues("id", instance.ID).Error(err, "failed starting stopped instance")
+		return nil
+	}
+	instance.State = string(ec2types.InstanceStateNamePending)
+	instance.Tags = lo.OmitByKeys(instance.Tags, tagKeys)
+	return instance
+}
+
+// GetConsoleOutput returns the most recent serial console output of the instance, which includes the output of its
+// bootstrap and userdata
+func (p *DefaultProvider) GetConsoleOutput(ctx context.Context, id string) (string, error) {
+	out, err := p.ec2api.GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(id),
+	})
+	if err != nil {
+		if awserrors.IsNotFound(err) {
+			return "", cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("getting console output, %w", err))
+		}
+		return "", fmt.Errorf("getting console output, %w", err)
+	}
+	output, err := base64.StdEncoding.DecodeString(aws.ToString(out.Output))
+	if err != nil {
+		return "", fmt.Errorf("decoding console output, %w", err)
+	}
+	return string(output), nil
+}
+
+func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, tags map[string]string) (*ec2types.CreateFleetInstance, error) {
+	zonalSubnets, err := p.subnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, nodeClaim.Labels[corev1beta1.NodePoolLabelKey], instanceTypes, capacityType)
 	if err != nil {
 		return nil, fmt.Errorf("getting subnets, %w", err)
 	}
 
 	// Get Launch Template Configs, which may differ due to GPU or Architecture requirements
+	start := time.Now()
 	launchTemplateConfigs, err := p.getLaunchTemplateConfigs(ctx, nodeClass, nodeClaim, instanceTypes, zonalSubnets, capacityType, tags)
 	if err != nil {
 		return nil, fmt.Errorf("getting launch template configs, %w", err)
 	}
+	ObserveLaunchPhase(LaunchPhaseLaunchTemplate, nodeClaim.Labels[corev1beta1.NodePoolLabelKey], capacityType, time.Since(start))
 	if err := p.checkODFallback(nodeClaim, instanceTypes, launchTemplateConfigs); err != nil {
 		log.FromContext(ctx).Error(err, "failed while checking on-demand fallback")
 	}
+	allocationStrategy, prioritizable := configuredAllocationStrategy(nodeClass, capacityType)
+	// Priorities are ignored by strategies that don't honor them, so we only compute them for strategies that do
+	prioritized := prioritizable && p.prioritizeOverrides(ctx, nodeClaim, launchTemplateConfigs, instanceTypes, capacityType)
+	if allocationStrategy == "" {
+		allocationStrategy = defaultAllocationStrategy(capacityType, prioritized)
+	}
+	volumeTags, err := getTags(ctx, nodeClass, nodeClaim, nodeClass.Spec.VolumeTags)
+	if err != nil {
+		return nil, err
+	}
+	fleetTags, err := getTags(ctx, nodeClass, nodeClaim, nodeClass.Spec.FleetTags)
+	if err != nil {
+		return nil, err
+	}
 	// Create fleet
 	createFleetInput := &ec2.CreateFleetInput{
-		Type:                  aws.String(ec2.FleetTypeInstant),
+		Type:                  ec2types.FleetTypeInstant,
 		Context:               nodeClass.Spec.Context,
 		LaunchTemplateConfigs: launchTemplateConfigs,
-		TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-			DefaultTargetCapacityType: aws.String(capacityType),
-			TotalTargetCapacity:       aws.Int64(1),
+		TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+			DefaultTargetCapacityType: ec2types.DefaultTargetCapacityType(capacityType),
+			TotalTargetCapacity:       aws.Int32(1),
 		},
-		TagSpecifications: []*ec2.TagSpecification{
-			{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: utils.MergeTags(tags)},
-			{ResourceType: aws.String(ec2.ResourceTypeVolume), Tags: utils.MergeTags(tags)},
-			{ResourceType: aws.String(ec2.ResourceTypeFleet), Tags: utils.MergeTags(tags)},
+		TagSpecifications: []ec2types.TagSpecification{
+			{ResourceType: ec2types.ResourceTypeInstance, Tags: utils.MergeTagsV2(tags)},
+			{ResourceType: ec2types.ResourceTypeVolume, Tags: utils.MergeTagsV2(volumeTags)},
+			{ResourceType: ec2types.ResourceTypeFleet, Tags: utils.MergeTagsV2(fleetTags)},
 		},
 	}
 	if capacityType == corev1beta1.CapacityTypeSpot {
-		createFleetInput.SpotOptions = &ec2.SpotOptionsRequest{AllocationStrategy: aws.String(ec2.SpotAllocationStrategyPriceCapacityOptimized)}
+		setSpotMaxPrices(ctx, nodeClaim, instanceTypes, launchTemplateConfigs)
+		createFleetInput.SpotOptions = &ec2types.SpotOptionsRequest{AllocationStrategy: ec2types.SpotAllocationStrategy(allocationStrategy)}
 	} else {
-		createFleetInput.OnDemandOptions = &ec2.OnDemandOptionsRequest{AllocationStrategy: aws.String(ec2.FleetOnDemandAllocationStrategyLowestPrice)}
+		createFleetInput.OnDemandOptions = &ec2types.OnDemandOptionsRequest{AllocationStrategy: ec2types.FleetOnDemandAllocationStrategy(allocationStrategy)}
 	}
 
+	if err := p.creationLimits.Reserve(ctx, cache.CreatedResourceCreateFleetRequest); err != nil {
+		return nil, err
+	}
+	if err := p.creationLimits.Reserve(ctx, cache.CreatedResourceInstance); err != nil {
+		p.creationLimits.Release(cache.CreatedResourceCreateFleetRequest)
+		return nil, err
+	}
+	start = time.Now()
 	createFleetOutput, err := p.ec2Batcher.CreateFleet(ctx, createFleetInput)
+	if err == nil {
+		ObserveLaunchPhase(LaunchPhaseCreateFleet, nodeClaim.Labels[corev1beta1.NodePoolLabelKey], capacityType, time.Since(start))
+	}
+	p.auditLogger.Log(ctx, auditlog.NewLaunch(ctx, nodeClaim, createFleetInput, createFleetOutput, err))
 	p.subnetProvider.UpdateInflightIPs(createFleetInput, createFleetOutput, instanceTypes, lo.Values(zonalSubnets), capacityType)
+	if err != nil || len(createFleetOutput.Instances) == 0 || len(createFleetOutput.Instances[0].InstanceIds) == 0 {
+		p.creationLimits.Release(cache.CreatedResourceInstance)
+	}
 	if err != nil {
 		if awserrors.IsLaunchTemplateNotFound(err) {
 			for _, lt := range launchTemplateConfigs {
-				p.launchTemplateProvider.InvalidateCache(ctx, aws.StringValue(lt.LaunchTemplateSpecification.LaunchTemplateName), aws.StringValue(lt.LaunchTemplateSpecification.LaunchTemplateId))
+				p.launchTemplateProvider.InvalidateCache(ctx, aws.ToString(lt.LaunchTemplateSpecification.LaunchTemplateName), aws.ToString(lt.LaunchTemplateSpecification.LaunchTemplateId))
 			}
 			return nil, fmt.Errorf("creating fleet %w", err)
 		}
-		var reqFailure awserr.RequestFailure
-		if errors.As(err, &reqFailure) {
-			return nil, fmt.Errorf("creating fleet %w (%s)", err, reqFailure.RequestID())
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			RecordFleetErrors(nodeClaim.Labels[corev1beta1.NodePoolLabelKey], capacityType, apiErr.ErrorCode())
+			// The errors of the aws-sdk-go-v2 client already include the ID of the request
+			return nil, awserrors.NewFleetError(fmt.Errorf("creating fleet %w", err), apiErr.ErrorCode())
 		}
 		return nil, fmt.Errorf("creating fleet %w", err)
 	}
-	p.updateUnavailableOfferingsCache(ctx, createFleetOutput.Errors, capacityType)
+	p.updateUnavailableOfferingsCache(ctx, createFleetOutput.Errors, zonalSubnets, capacityType)
+	RecordFleetErrors(nodeClaim.Labels[corev1beta1.NodePoolLabelKey], capacityType, lo.Map(createFleetOutput.Errors, func(err ec2types.CreateFleetError, _ int) string {
+		return aws.ToString(err.ErrorCode)
+	})...)
 	if len(createFleetOutput.Instances) == 0 || len(createFleetOutput.Instances[0].InstanceIds) == 0 {
 		return nil, combineFleetErrors(createFleetOutput.Errors)
 	}
-	return createFleetOutput.Instances[0], nil
+	if err = p.terminateUnexpectedInstanceType(ctx, createFleetOutput.Instances[0], instanceTypes); err != nil {
+		return nil, err
+	}
+	if overrides := createFleetOutput.Instances[0].LaunchTemplateAndOverrides; overrides != nil && overrides.Overrides != nil && options.FromContext(ctx).ZoneFailover {
+		p.zoneHealth.RecordSuccess(aws.ToString(overrides.Overrides.AvailabilityZone))
+	}
+	if overrides := createFleetOutput.Instances[0].LaunchTemplateAndOverrides; overrides != nil && overrides.Overrides != nil && options.FromContext(ctx).InstanceSelectionWeights[ScorerZoneBalance] > 0 {
+		p.zoneBalanceScorer.Launched(createFleetOutput.Instances[0].InstanceIds[0], aws.ToString(overrides.Overrides.AvailabilityZone))
+	}
+	return &createFleetOutput.Instances[0], nil
+}
+
+// terminateUnexpectedInstanceType terminates the launched instance when its instance type isn't one of the requested
+// instance types, since Karpenter can't model the capacity of the node it would register
+func (p *DefaultProvider) terminateUnexpectedInstanceType(ctx context.Context, out ec2types.CreateFleetInstance, instanceTypes []*cloudprovider.InstanceType) error {
+	if lo.ContainsBy(instanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == string(out.InstanceType) }) {
+		return nil
+	}
+	id := out.InstanceIds[0]
+	if err := p.Delete(ctx, id); err != nil && !cloudprovider.IsNodeClaimNotFoundError(err) {
+		return fmt.Errorf("terminating instance %s with unexpected instance type, %w", id, err)
+	}
+	return awserrors.NewUnexpectedInstanceTypeError(id, string(out.InstanceType))
 }
 
-func getTags(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim) map[string]string {
+// setSpotMaxPrices caps the price that EC2 Fleet pays for each spot override at the max price of the NodeClaim's
+// NodePool, so that a spot price which rose after the offerings were filtered still isn't paid
+func setSpotMaxPrices(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest) {
+	spotMaxPrice := instancetype.NewSpotMaxPrice(ctx, nodeClaim.Annotations)
+	if !spotMaxPrice.IsSet() {
+		return
+	}
+	instanceTypesByName := lo.SliceToMap(instanceTypes, func(it *cloudprovider.InstanceType) (string, *cloudprovider.InstanceType) { return it.Name, it })
+	for _, ltc := range launchTemplateConfigs {
+		for i := range ltc.Overrides {
+			override := &ltc.Overrides[i]
+			it, ok := instanceTypesByName[string(override.InstanceType)]
+			if !ok {
+				continue
+			}
+			if maxPrice, ok := spotMaxPrice.For(it, aws.ToString(override.AvailabilityZone)); ok {
+				override.MaxPrice = aws.String(strconv.FormatFloat(maxPrice, 'f', -1, 64))
+			}
+		}
+	}
+}
+
+// getTags returns the tags of an ec2 resource, which are the EC2NodeClass tags, overridden by any resource-specific tags,
+// and Karpenter's static tags. It fails if the tags wouldn't leave room for the tags that Karpenter reserves.
+func getTags(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim, resourceTags ...map[string]string) (map[string]string, error) {
 	staticTags := map[string]string{
 		fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName): "owned",
 		corev1beta1.NodePoolLabelKey:       nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
 		corev1beta1.ManagedByAnnotationKey: options.FromContext(ctx).ClusterName,
 		v1beta1.LabelNodeClass:             nodeClass.Name,
 	}
-	return lo.Assign(nodeClass.Spec.Tags, staticTags)
+	tags := lo.Assign(append([]map[string]string{nodeClass.Spec.Tags}, resourceTags...)...)
+	if err := validateTagLimit(tags); err != nil {
+		return nil, err
+	}
+	return lo.Assign(tags, staticTags), nil
 }
 
-func (p *DefaultProvider) checkODFallback(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, launchTemplateConfigs []*ec2.FleetLaunchTemplateConfigRequest) error {
+// validateTagLimit fails when the user tags would push a resource over the EC2 tag limit once Karpenter's tags are
+// applied. Admission rejects EC2NodeClasses with too many tags, but resource-specific tags are merged with the
+// EC2NodeClass tags and EC2NodeClasses may predate the validation, so the limit is checked again before launching.
+func validateTagLimit(tags map[string]string) error {
+	// A user-specified Name tag replaces the one Karpenter would otherwise apply, so it doesn't count against the limit
+	if n, limit := len(lo.OmitByKeys(tags, []string{v1beta1.TagName})), v1beta1.MaxTags-v1beta1.ReservedTags; n > limit {
+		return fmt.Errorf("%d tags specified but at most %d, not counting Name, are allowed since Karpenter reserves %d of the %d tags EC2 allows on a resource", n, limit, v1beta1.ReservedTags, v1beta1.MaxTags)
+	}
+	return nil
+}
+
+func (p *DefaultProvider) checkODFallback(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest) error {
 	// only evaluate for on-demand fallback if the capacity type for the request is OD and both OD and spot are allowed in requirements
-	if p.getCapacityType(nodeClaim, instanceTypes) != corev1beta1.CapacityTypeOnDemand || !scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	if recommendation.CapacityType(requirements, instanceTypes) != corev1beta1.CapacityTypeOnDemand || !requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
 		return nil
 	}
 
@@ -278,8 +654,8 @@ func (p *DefaultProvider) checkODFallback(nodeClaim *corev1beta1.NodeClaim, inst
 	instanceTypeZones := map[string]struct{}{}
 	for _, ltc := range launchTemplateConfigs {
 		for _, override := range ltc.Overrides {
-			if override.InstanceType != nil {
-				instanceTypeZones[*override.InstanceType] = struct{}{}
+			if override.InstanceType != "" {
+				instanceTypeZones[string(override.InstanceType)] = struct{}{}
 			}
 		}
 
@@ -292,8 +668,8 @@ func (p *DefaultProvider) checkODFallback(nodeClaim *corev1beta1.NodeClaim, inst
 }
 
 func (p *DefaultProvider) getLaunchTemplateConfigs(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim,
-	instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*subnet.Subnet, capacityType string, tags map[string]string) ([]*ec2.FleetLaunchTemplateConfigRequest, error) {
-	var launchTemplateConfigs []*ec2.FleetLaunchTemplateConfigRequest
+	instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*subnet.Subnet, capacityType string, tags map[string]string) ([]ec2types.FleetLaunchTemplateConfigRequest, error) {
+	var launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest
 	launchTemplates, err := p.launchTemplateProvider.EnsureAll(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
 	if err != nil {
 		return nil, fmt.Errorf("getting launch templates, %w", err)
@@ -301,9 +677,14 @@ func (p *DefaultProvider) getLaunchTemplateConfigs(ctx context.Context, nodeClas
 	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
 	requirements[corev1beta1.CapacityTypeLabelKey] = scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, capacityType)
 	for _, launchTemplate := range launchTemplates {
-		launchTemplateConfig := &ec2.FleetLaunchTemplateConfigRequest{
-			Overrides: p.getOverrides(launchTemplate.InstanceTypes, zonalSubnets, requirements, launchTemplate.ImageID),
-			LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
+		subnets := zonalSubnets
+		// Launch templates with additional network interfaces can only launch instances in the zone of their subnets
+		if launchTemplate.Zone != "" {
+			subnets = lo.PickByKeys(zonalSubnets, []string{launchTemplate.Zone})
+		}
+		launchTemplateConfig := ec2types.FleetLaunchTemplateConfigRequest{
+			Overrides: p.getOverrides(launchTemplate.InstanceTypes, subnets, requirements, launchTemplate.ImageID),
+			LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 				LaunchTemplateName: aws.String(launchTemplate.Name),
 				Version:            aws.String("$Latest"),
 			},
@@ -320,7 +701,7 @@ func (p *DefaultProvider) getLaunchTemplateConfigs(ctx context.Context, nodeClas
 
 // getOverrides creates and returns launch template overrides for the cross product of InstanceTypes and subnets (with subnets being constrained by
 // zones and the offerings in InstanceTypes)
-func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*subnet.Subnet, reqs scheduling.Requirements, image string) []*ec2.FleetLaunchTemplateOverridesRequest {
+func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*subnet.Subnet, reqs scheduling.Requirements, image string) []ec2types.FleetLaunchTemplateOverridesRequest {
 	// Unwrap all the offerings to a flat slice that includes a pointer
 	// to the parent instance type name
 	type offeringWithParentName struct {
@@ -337,7 +718,7 @@ func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceTy
 		})
 		unwrappedOfferings = append(unwrappedOfferings, ofs...)
 	}
-	var overrides []*ec2.FleetLaunchTemplateOverridesRequest
+	var overrides []ec2types.FleetLaunchTemplateOverridesRequest
 	for _, offering := range unwrappedOfferings {
 		if reqs.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
 			continue
@@ -346,8 +727,8 @@ func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceTy
 		if !ok {
 			continue
 		}
-		overrides = append(overrides, &ec2.FleetLaunchTemplateOverridesRequest{
-			InstanceType: aws.String(offering.parentInstanceTypeName),
+		overrides = append(overrides, ec2types.FleetLaunchTemplateOverridesRequest{
+			InstanceType: ec2types.InstanceType(offering.parentInstanceTypeName),
 			SubnetId:     lo.ToPtr(subnet.ID),
 			ImageId:      aws.String(image),
 			// This is technically redundant, but is useful if we have to parse insufficient capacity errors from
@@ -358,129 +739,133 @@ func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceTy
 	return overrides
 }
 
-func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, errors []*ec2.CreateFleetError, capacityType string) {
-	for _, err := range errors {
-		if awserrors.IsUnfulfillableCapacity(err) {
-			p.unavailableOfferings.MarkUnavailableForFleetErr(ctx, err, capacityType)
-		}
+// configuredAllocationStrategy returns the allocation strategy that the EC2NodeClass configures for the capacity type,
+// or an empty string if it doesn't configure one, and whether the strategy honors override priorities
+func configuredAllocationStrategy(nodeClass *v1beta1.EC2NodeClass, capacityType string) (string, bool) {
+	if nodeClass.Spec.AllocationStrategy == nil {
+		return "", true
 	}
-}
-
-// getCapacityType selects spot if both constraints are flexible and there is an
-// available offering. The AWS Cloud Provider defaults to [ on-demand ], so spot
-// must be explicitly included in capacity type requirements.
-func (p *DefaultProvider) getCapacityType(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) string {
-	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	if requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
-		requirements[corev1beta1.CapacityTypeLabelKey] = scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeSpot)
-		for _, instanceType := range instanceTypes {
-			for _, offering := range instanceType.Offerings.Available() {
-				if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil {
-					return corev1beta1.CapacityTypeSpot
-				}
-			}
-		}
+	if capacityType == corev1beta1.CapacityTypeSpot {
+		strategy := aws.ToString(nodeClass.Spec.AllocationStrategy.Spot)
+		return strategy, strategy == "" || strategy == string(ec2types.SpotAllocationStrategyCapacityOptimizedPrioritized)
 	}
-	return corev1beta1.CapacityTypeOnDemand
+	strategy := aws.ToString(nodeClass.Spec.AllocationStrategy.OnDemand)
+	return strategy, strategy == "" || strategy == string(ec2types.FleetOnDemandAllocationStrategyPrioritized)
 }
 
-// filterInstanceTypes is used to provide filtering on the list of potential instance types to further limit it to those
-// that make the most sense given our specific AWS cloudprovider.
-func (p *DefaultProvider) filterInstanceTypes(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
-	instanceTypes = filterExoticInstanceTypes(instanceTypes)
-	// If we could potentially launch either a spot or on-demand node, we want to filter out the spot instance types that
-	// are more expensive than the cheapest on-demand type.
-	if p.isMixedCapacityLaunch(nodeClaim, instanceTypes) {
-		instanceTypes = filterUnwantedSpot(instanceTypes)
+// defaultAllocationStrategy returns the allocation strategy that is used for the capacity type when the EC2NodeClass
+// doesn't configure one
+func defaultAllocationStrategy(capacityType string, prioritized bool) string {
+	if capacityType == corev1beta1.CapacityTypeSpot {
+		return string(lo.Ternary(prioritized, ec2types.SpotAllocationStrategyCapacityOptimizedPrioritized, ec2types.SpotAllocationStrategyPriceCapacityOptimized))
 	}
-	return instanceTypes
+	return string(lo.Ternary(prioritized, ec2types.FleetOnDemandAllocationStrategyPrioritized, ec2types.FleetOnDemandAllocationStrategyLowestPrice))
 }
 
-// isMixedCapacityLaunch returns true if nodepools and available offerings could potentially allow either a spot or
-// and on-demand node to launch
-func (p *DefaultProvider) isMixedCapacityLaunch(nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType) bool {
-	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
-	// requirements must allow both
-	if !requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) ||
-		!requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeOnDemand) {
-		return false
+// prioritizeOverrides assigns a priority to each launch template override according to the configured instance
+// selection weights and orders the overrides of each launch template by it. Zones are also ranked by their suitability
+// score for NodeClaims of interruption sensitive NodePools, unless the zone-suitability weight is configured. It returns
+// false if no weights apply, in which case the overrides are left unprioritized.
+func (p *DefaultProvider) prioritizeOverrides(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest,
+	instanceTypes []*cloudprovider.InstanceType, capacityType string) bool {
+	nodePool := nodeClaim.Labels[corev1beta1.NodePoolLabelKey]
+	weights := options.FromContext(ctx).InstanceSelectionWeights
+	if _, ok := weights[ScorerZoneSuitability]; !ok {
+		if _, scored := p.zoneScores.Get(nodePool); scored {
+			weights = lo.Assign(weights, map[string]float64{ScorerZoneSuitability: 1})
+		}
 	}
-	hasSpotOfferings := false
-	hasODOffering := false
-	if requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
-		for _, instanceType := range instanceTypes {
-			for _, offering := range instanceType.Offerings.Available() {
-				if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
-					continue
-				}
-				if offering.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeSpot {
-					hasSpotOfferings = true
-				} else {
-					hasODOffering = true
-				}
+	instanceTypesByName := lo.SliceToMap(instanceTypes, func(it *cloudprovider.InstanceType) (string, *cloudprovider.InstanceType) { return it.Name, it })
+	var candidates []Candidate
+	var overrides []*ec2types.FleetLaunchTemplateOverridesRequest
+	for _, ltc := range launchTemplateConfigs {
+		for i := range ltc.Overrides {
+			override := &ltc.Overrides[i]
+			it, ok := instanceTypesByName[string(override.InstanceType)]
+			if !ok {
+				continue
 			}
+			offering, ok := lo.Find(it.Offerings.Available(), func(o cloudprovider.Offering) bool {
+				return o.Requirements.Get(v1.LabelTopologyZone).Any() == aws.ToString(override.AvailabilityZone) &&
+					o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == capacityType
+			})
+			if !ok {
+				continue
+			}
+			candidates = append(candidates, Candidate{InstanceType: it, Offering: offering, NodePool: nodePool})
+			overrides = append(overrides, override)
 		}
 	}
-	return hasSpotOfferings && hasODOffering
+	order, prioritized := p.scorer.Prioritize(ctx, candidates, weights)
+	if !prioritized {
+		return false
+	}
+	for priority, i := range order {
+		overrides[i].Priority = aws.Float64(float64(priority))
+	}
+	for _, ltc := range launchTemplateConfigs {
+		sort.SliceStable(ltc.Overrides, func(i, j int) bool {
+			return aws.ToFloat64(ltc.Overrides[i].Priority) < aws.ToFloat64(ltc.Overrides[j].Priority)
+		})
+	}
+	return true
 }
 
-// filterUnwantedSpot is used to filter out spot types that are more expensive than the cheapest on-demand type that we
-// could launch during mixed capacity-type launches
-func filterUnwantedSpot(instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
-	cheapestOnDemand := math.MaxFloat64
-	// first, find the price of our cheapest available on-demand instance type that could support this node
-	for _, it := range instanceTypes {
-		for _, o := range it.Offerings.Available() {
-			if o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeOnDemand && o.Price < cheapestOnDemand {
-				cheapestOnDemand = o.Price
+func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, errors []ec2types.CreateFleetError, zonalSubnets map[string]*subnet.Subnet, capacityType string) {
+	for _, err := range errors {
+		if awserrors.IsUnfulfillableCapacity(err) {
+			// The capacity of an Outpost is separate from the capacity of the zone that it's anchored to, so the
+			// offering is only removed from the Outpost
+			if zonalSubnet, ok := zonalSubnets[aws.ToString(err.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)]; ok && zonalSubnet.OutpostARN != "" {
+				p.unavailableOfferings.MarkUnavailable(ctx, aws.ToString(err.ErrorCode), string(err.LaunchTemplateAndOverrides.Overrides.InstanceType),
+					utils.OutpostID(zonalSubnet.OutpostARN), capacityType)
+				continue
+			}
+			p.unavailableOfferings.MarkUnavailableForFleetErr(ctx, err, capacityType)
+			// capacity errors across many instance families in the same zone are a sign that the zone is impaired
+			if options.FromContext(ctx).ZoneFailover {
+				p.zoneHealth.RecordFailure(ctx, aws.ToString(err.ErrorCode),
+					string(err.LaunchTemplateAndOverrides.Overrides.InstanceType), aws.ToString(err.LaunchTemplateAndOverrides.Overrides.AvailabilityZone))
 			}
 		}
 	}
-
-	// Filter out any types where the cheapest offering, which should be spot, is more expensive than the cheapest
-	// on-demand instance type that would have worked. This prevents us from getting a larger more-expensive spot
-	// instance type compared to the cheapest sufficiently large on-demand instance type
-	instanceTypes = lo.Filter(instanceTypes, func(item *cloudprovider.InstanceType, index int) bool {
-		available := item.Offerings.Available()
-		if len(available) == 0 {
-			return false
-		}
-		return available.Cheapest().Price <= cheapestOnDemand
-	})
-	return instanceTypes
 }
 
-// filterExoticInstanceTypes is used to eliminate less desirable instance types (like GPUs) from the list of possible instance types when
-// a set of more appropriate instance types would work. If a set of more desirable instance types is not found, then the original slice
-// of instance types are returned.
-func filterExoticInstanceTypes(instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
-	var genericInstanceTypes []*cloudprovider.InstanceType
-	for _, it := range instanceTypes {
-		// deprioritize metal even if our opinionated filter isn't applied due to something like an instance family
-		// requirement
-		if _, ok := lo.Find(it.Requirements.Get(v1beta1.LabelInstanceSize).Values(), func(size string) bool { return strings.Contains(size, "metal") }); ok {
-			continue
-		}
-		if !resources.IsZero(it.Capacity[v1beta1.ResourceAWSNeuron]) ||
-			!resources.IsZero(it.Capacity[v1beta1.ResourceAMDGPU]) ||
-			!resources.IsZero(it.Capacity[v1beta1.ResourceNVIDIAGPU]) ||
-			!resources.IsZero(it.Capacity[v1beta1.ResourceHabanaGaudi]) {
-			continue
+// shouldBackstopOnDemand returns true if a spot launch failed for lack of capacity and the on-demand backstop is
+// enabled, the NodeClaim allows on-demand, and there is an available on-demand offering that it could launch
+func (p *DefaultProvider) shouldBackstopOnDemand(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, capacityType string, err error) bool {
+	if !options.FromContext(ctx).OnDemandBackstop || capacityType != corev1beta1.CapacityTypeSpot || !cloudprovider.IsInsufficientCapacityError(err) {
+		return false
+	}
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	if !requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeOnDemand) {
+		return false
+	}
+	requirements[corev1beta1.CapacityTypeLabelKey] = scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeOnDemand)
+	for _, instanceType := range instanceTypes {
+		for _, offering := range instanceType.Offerings.Available() {
+			if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil {
+				return true
+			}
 		}
-		genericInstanceTypes = append(genericInstanceTypes, it)
 	}
-	// if we got some subset of instance types, then prefer to use those
-	if len(genericInstanceTypes) != 0 {
-		return genericInstanceTypes
+	return false
+}
+
+// deprioritizedInstanceTypes returns the categories of instance types that are deprioritized for the NodeClaim. The
+// NodePool's annotation, which is propagated to its NodeClaims, takes precedence over the setting.
+func deprioritizedInstanceTypes(ctx context.Context, nodeClaim *corev1beta1.NodeClaim) sets.Set[string] {
+	if value, ok := nodeClaim.Annotations[v1beta1.AnnotationDeprioritizedInstanceTypes]; ok {
+		return sets.New(options.ParseDeprioritizedInstanceTypes(value)...)
 	}
-	return instanceTypes
+	return sets.New(options.FromContext(ctx).DeprioritizedInstanceTypes...)
 }
 
 func instancesFromOutput(out *ec2.DescribeInstancesOutput) ([]*Instance, error) {
 	if len(out.Reservations) == 0 {
 		return nil, cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance not found"))
 	}
-	instances := lo.Flatten(lo.Map(out.Reservations, func(r *ec2.Reservation, _ int) []*ec2.Instance {
+	instances := lo.Flatten(lo.Map(out.Reservations, func(r ec2types.Reservation, _ int) []ec2types.Instance {
 		return r.Instances
 	}))
 	if len(instances) == 0 {
@@ -488,23 +873,26 @@ func instancesFromOutput(out *ec2.DescribeInstancesOutput) ([]*Instance, error)
 	}
 	// Get a consistent ordering for instances
 	sort.Slice(instances, func(i, j int) bool {
-		return aws.StringValue(instances[i].InstanceId) < aws.StringValue(instances[j].InstanceId)
+		return aws.ToString(instances[i].InstanceId) < aws.ToString(instances[j].InstanceId)
 	})
-	return lo.Map(instances, func(i *ec2.Instance, _ int) *Instance { return NewInstance(i) }), nil
+	return lo.Map(instances, func(i ec2types.Instance, _ int) *Instance { return NewInstance(&i) }), nil
 }
 
-func combineFleetErrors(errors []*ec2.CreateFleetError) (errs error) {
+// combineFleetErrors combines the errors of a CreateFleet request that didn't launch an instance into a FleetError,
+// which is categorized by the error codes
+func combineFleetErrors(errors []ec2types.CreateFleetError) (errs error) {
 	unique := sets.NewString()
 	for _, err := range errors {
-		unique.Insert(fmt.Sprintf("%s: %s", aws.StringValue(err.ErrorCode), aws.StringValue(err.ErrorMessage)))
+		unique.Insert(fmt.Sprintf("%s: %s", aws.ToString(err.ErrorCode), aws.ToString(err.ErrorMessage)))
 	}
 	for errorCode := range unique {
 		errs = multierr.Append(errs, fmt.Errorf(errorCode))
 	}
+	codes := lo.Map(errors, func(err ec2types.CreateFleetError, _ int) string { return aws.ToString(err.ErrorCode) })
 	// If all the Fleet errors are ICE errors then we should wrap the combined error in the generic ICE error
-	iceErrorCount := lo.CountBy(errors, func(err *ec2.CreateFleetError) bool { return awserrors.IsUnfulfillableCapacity(err) })
+	iceErrorCount := lo.CountBy(errors, func(err ec2types.CreateFleetError) bool { return awserrors.IsUnfulfillableCapacity(err) })
 	if iceErrorCount == len(errors) {
-		return cloudprovider.NewInsufficientCapacityError(fmt.Errorf("with fleet error(s), %w", errs))
+		return awserrors.NewFleetError(cloudprovider.NewInsufficientCapacityError(fmt.Errorf("with fleet error(s), %w", errs)), codes...)
 	}
-	return fmt.Errorf("with fleet error(s), %w", errs)
+	return awserrors.NewFleetError(fmt.Errorf("with fleet error(s), %w", errs), codes...)
 }
@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	phaseLabel             = "phase"
+	categoryLabel          = "category"
+	errorCodeLabel         = "error_code"
+
+	// LaunchPhaseLaunchTemplate is resolving the launch templates of a launch, which creates the ones that don't exist
+	LaunchPhaseLaunchTemplate = "launch_template"
+	// LaunchPhaseCreateFleet is the CreateFleet call of a launch, including the time that it waits to be batched
+	LaunchPhaseCreateFleet = "create_fleet"
+	// LaunchPhaseInstanceRunning is from when the NodeClaim is launched until DescribeInstances reports its instance as
+	// running
+	LaunchPhaseInstanceRunning = "instance_running"
+	// LaunchPhaseRegistration is from when the NodeClaim is launched until its node registers
+	LaunchPhaseRegistration = "registration"
+	// LaunchPhaseInitialization is from when the node of the NodeClaim registers until it's initialized
+	LaunchPhaseInitialization = "initialization"
+)
+
+var (
+	LaunchPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "launch_phase_duration_seconds",
+			Help:      "Duration of each phase of a node launch, based on phase, nodepool, and capacity type.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 20, 30, 45, 60, 90, 120, 180, 300, 600},
+		},
+		[]string{
+			phaseLabel,
+			metrics.NodePoolLabel,
+			metrics.CapacityTypeLabel,
+		},
+	)
+	FleetErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "create_fleet_errors_total",
+			Help:      "Number of errors returned by CreateFleet requests, based on the category and code of the error, nodepool, and capacity type. Each error code is counted once per request.",
+		},
+		[]string{
+			categoryLabel,
+			errorCodeLabel,
+			metrics.NodePoolLabel,
+			metrics.CapacityTypeLabel,
+		},
+	)
+	StuckInstancesTerminatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "stuck_instances_terminated_total",
+			Help:      "Number of instances terminated because they were stuck in a phase of their launch for longer than the stuck-instance-deadline, based on phase, nodepool, and capacity type.",
+		},
+		[]string{
+			phaseLabel,
+			metrics.NodePoolLabel,
+			metrics.CapacityTypeLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(LaunchPhaseDuration, FleetErrorsTotal, StuckInstancesTerminatedTotal)
+}
+
+// ObserveLaunchPhase records the duration of a phase of a launch of the NodePool
+func ObserveLaunchPhase(phase, nodePool, capacityType string, duration time.Duration) {
+	LaunchPhaseDuration.With(prometheus.Labels{
+		phaseLabel:                phase,
+		metrics.NodePoolLabel:     nodePool,
+		metrics.CapacityTypeLabel: capacityType,
+	}).Observe(duration.Seconds())
+}
+
+// RecordFleetErrors counts the error codes that a CreateFleet request of the NodePool returned
+func RecordFleetErrors(nodePool, capacityType string, codes ...string) {
+	for _, code := range lo.Uniq(codes) {
+		FleetErrorsTotal.With(prometheus.Labels{
+			categoryLabel:             awserrors.FleetErrorCategory(code),
+			errorCodeLabel:            code,
+			metrics.NodePoolLabel:     nodePool,
+			metrics.CapacityTypeLabel: capacityType,
+		}).Inc()
+	}
+}
+
+// RecordStuckInstanceTerminated counts an instance of the NodePool that was terminated because it was stuck in the phase
+func RecordStuckInstanceTerminated(phase, nodePool, capacityType string) {
+	StuckInstancesTerminatedTotal.With(prometheus.Labels{
+		phaseLabel:                phase,
+		metrics.NodePoolLabel:     nodePool,
+		metrics.CapacityTypeLabel: capacityType,
+	}).Inc()
+}
@@ -0,0 +1,188 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+const (
+	ScorerPrice            = "price"
+	ScorerFlexibility      = "flexibility"
+	ScorerInterruptionRisk = "interruption-risk"
+	ScorerZoneBalance      = "zone-balance"
+	ScorerZoneSuitability  = "zone-suitability"
+
+	// zoneBalanceWindow is how long a launch counts towards the balance of its zone
+	zoneBalanceWindow = time.Hour
+)
+
+// Candidate is an offering of an instance type that CreateFleet could launch
+type Candidate struct {
+	InstanceType *cloudprovider.InstanceType
+	Offering     cloudprovider.Offering
+	// NodePool is the name of the NodePool of the NodeClaim that's being launched
+	NodePool string
+}
+
+func (c Candidate) Zone() string {
+	return c.Offering.Requirements.Get(v1.LabelTopologyZone).Any()
+}
+
+func (c Candidate) CapacityType() string {
+	return c.Offering.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any()
+}
+
+// Scorer scores candidates relative to each other. Scores are in the range [0, 1], where a higher score is preferred,
+// and are returned in the same order as the candidates.
+type Scorer interface {
+	Name() string
+	Score(context.Context, []Candidate) []float64
+}
+
+// WeightedScorer prioritizes candidates by the sum of their scores from each scorer, weighted by the
+// instance-selection-weights setting. Scorers without a weight aren't evaluated.
+type WeightedScorer struct {
+	scorers []Scorer
+}
+
+func NewWeightedScorer(scorers ...Scorer) *WeightedScorer {
+	return &WeightedScorer{scorers: scorers}
+}
+
+// Prioritize returns the indices of the candidates ordered from most to least preferred. Ties keep their original
+// order. If no scorer has a weight, it returns false, leaving the choice to CreateFleet.
+func (w *WeightedScorer) Prioritize(ctx context.Context, candidates []Candidate, weights map[string]float64) ([]int, bool) {
+	scorers := lo.Filter(w.scorers, func(s Scorer, _ int) bool { return weights[s.Name()] > 0 })
+	if len(scorers) == 0 {
+		return nil, false
+	}
+	totals := make([]float64, len(candidates))
+	for _, scorer := range scorers {
+		for i, score := range scorer.Score(ctx, candidates) {
+			totals[i] += weights[scorer.Name()] * score
+		}
+	}
+	indices := lo.Range(len(candidates))
+	sort.SliceStable(indices, func(i, j int) bool { return totals[indices[i]] > totals[indices[j]] })
+	return indices, true
+}
+
+// normalize scales values into [0, 1] so that the lowest value scores 1 and the highest scores 0, or all values
+// score 1 if they're equal
+func normalize(values []float64) []float64 {
+	lowest, highest := lo.Min(values), lo.Max(values)
+	return lo.Map(values, func(v float64, _ int) float64 {
+		if highest == lowest {
+			return 1
+		}
+		return (highest - v) / (highest - lowest)
+	})
+}
+
+// PriceScorer prefers cheaper offerings
+type PriceScorer struct{}
+
+func (PriceScorer) Name() string { return ScorerPrice }
+
+func (PriceScorer) Score(_ context.Context, candidates []Candidate) []float64 {
+	return normalize(lo.Map(candidates, func(c Candidate, _ int) float64 { return c.Offering.Price }))
+}
+
+// FlexibilityScorer prefers instance types that can be launched in more zones, so that a follow-up launch of the same
+// instance type is less likely to be constrained by capacity in a single zone
+type FlexibilityScorer struct{}
+
+func (FlexibilityScorer) Name() string { return ScorerFlexibility }
+
+func (FlexibilityScorer) Score(_ context.Context, candidates []Candidate) []float64 {
+	zones := map[string]map[string]struct{}{}
+	for _, c := range candidates {
+		zones[c.InstanceType.Name] = lo.Assign(zones[c.InstanceType.Name], map[string]struct{}{c.Zone(): {}})
+	}
+	// Negate the counts so that the instance types with the most zones score highest
+	return normalize(lo.Map(candidates, func(c Candidate, _ int) float64 { return -float64(len(zones[c.InstanceType.Name])) }))
+}
+
+// InterruptionRiskScorer prefers offerings that are less likely to be interrupted. On-demand offerings aren't
+// interrupted. The risk of a spot offering is estimated from its price relative to the on-demand price of the same
+// instance type, since spot prices approach on-demand prices as spare capacity in a pool shrinks.
+type InterruptionRiskScorer struct{}
+
+func (InterruptionRiskScorer) Name() string { return ScorerInterruptionRisk }
+
+func (InterruptionRiskScorer) Score(_ context.Context, candidates []Candidate) []float64 {
+	return lo.Map(candidates, func(c Candidate, _ int) float64 {
+		if c.CapacityType() != corev1beta1.CapacityTypeSpot {
+			return 1
+		}
+		onDemand, ok := lo.Find(c.InstanceType.Offerings, func(o cloudprovider.Offering) bool {
+			return o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeOnDemand && o.Price > 0
+		})
+		if !ok {
+			// Without an on-demand price the risk is unknown, so the offering is neither preferred nor avoided
+			return 0.5
+		}
+		return 1 - lo.Clamp(c.Offering.Price/onDemand.Price, 0, 1)
+	})
+}
+
+// ZoneBalanceScorer prefers zones where fewer instances have recently been launched, spreading launches across zones
+// rather than concentrating them in the cheapest one
+type ZoneBalanceScorer struct {
+	launches *cache.Cache
+}
+
+func NewZoneBalanceScorer() *ZoneBalanceScorer {
+	return &ZoneBalanceScorer{launches: cache.New(zoneBalanceWindow, time.Minute)}
+}
+
+func (*ZoneBalanceScorer) Name() string { return ScorerZoneBalance }
+
+func (z *ZoneBalanceScorer) Score(_ context.Context, candidates []Candidate) []float64 {
+	counts := lo.CountValues(lo.MapToSlice(z.launches.Items(), func(_ string, item cache.Item) string { return item.Object.(string) }))
+	return normalize(lo.Map(candidates, func(c Candidate, _ int) float64 { return float64(counts[c.Zone()]) }))
+}
+
+// Launched records that an instance was launched into a zone
+func (z *ZoneBalanceScorer) Launched(instanceID string, zone string) {
+	z.launches.SetDefault(instanceID, zone)
+}
+
+// ZoneSuitabilityScorer prefers the zones with the highest suitability score for the NodePool, which combines the spot
+// placement score of the zone with the free IPs of its subnets. Candidates of NodePools that haven't been scored
+// aren't ranked.
+type ZoneSuitabilityScorer struct {
+	zoneScores *awscache.ZoneScores
+}
+
+func (ZoneSuitabilityScorer) Name() string { return ScorerZoneSuitability }
+
+func (z ZoneSuitabilityScorer) Score(_ context.Context, candidates []Candidate) []float64 {
+	return lo.Map(candidates, func(c Candidate, _ int) float64 {
+		scores, _ := z.zoneScores.Get(c.NodePool)
+		return scores[c.Zone()]
+	})
+}
@@ -16,16 +16,20 @@ package instance_test
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -63,7 +67,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.LaunchRamps, awsEnv.TerminationHookProvider, clock.RealClock{})
 })
 
 var _ = AfterSuite(func() {
@@ -130,6 +134,537 @@ var _ = Describe("InstanceProvider", func() {
 		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(instance).To(BeNil())
 	})
+	It("should mark offerings unavailable on the Outpost when the Outpost is out of capacity", func() {
+		nodeClass.Status.Subnets = []v1beta1.Subnet{
+			{
+				ID:         "subnet-test4",
+				Zone:       "test-zone-1a-local",
+				ZoneID:     "tstz1-1alocal",
+				ZoneType:   v1beta1.ZoneTypeLocalZone,
+				OutpostARN: "arn:aws:outposts:us-west-2:111122223333:outpost/op-0123456789abcdef0",
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		awsEnv.EC2API.CreateFleetBehavior.Output.Set(&ec2.CreateFleetOutput{
+			Errors: []*ec2.CreateFleetError{
+				{
+					ErrorCode: aws.String("InsufficientCapacityOnOutpost"),
+					LaunchTemplateAndOverrides: &ec2.LaunchTemplateAndOverridesResponse{
+						Overrides: &ec2.FleetLaunchTemplateOverrides{
+							InstanceType:     aws.String("m5.large"),
+							AvailabilityZone: aws.String("test-zone-1a-local"),
+						},
+					},
+				},
+			},
+		})
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.large" })
+
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		Expect(instance).To(BeNil())
+		Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("m5.large", "op-0123456789abcdef0", corev1beta1.CapacityTypeOnDemand)).To(BeTrue())
+		Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("m5.large", "test-zone-1a-local", corev1beta1.CapacityTypeOnDemand)).To(BeFalse())
+	})
+	It("should audit the CreateFleet requests of launches", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		awsEnv.EC2API.InsufficientCapacityPools.Set([]fake.CapacityPool{
+			{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: "m5.xlarge", Zone: "test-zone-1a"},
+		})
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+		nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+		}
+
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.AuditLogger.Launches.Len()).To(Equal(1))
+		launch := awsEnv.AuditLogger.Launches.Pop()
+		Expect(launch.NodeClaim).To(Equal(nodeClaim.Name))
+		Expect(launch.NodePool).To(Equal(nodePool.Name))
+		Expect(launch.CapacityType).To(Equal(corev1beta1.CapacityTypeOnDemand))
+		Expect(launch.InstanceIDs).To(ConsistOf(instance.ID))
+		Expect(launch.LaunchTemplates).ToNot(BeEmpty())
+		for _, lt := range launch.LaunchTemplates {
+			Expect(lt.Name).ToNot(BeEmpty())
+			for _, override := range lt.Overrides {
+				Expect(override.InstanceType).To(Equal("m5.xlarge"))
+				Expect(override.SubnetID).ToNot(BeEmpty())
+			}
+		}
+		Expect(launch.FleetErrors).To(ContainElement(HaveField("AvailabilityZone", "test-zone-1a")))
+	})
+	It("should record the duration of the launch template and CreateFleet phases of launches", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+		}
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		for _, phase := range []string{instance.LaunchPhaseLaunchTemplate, instance.LaunchPhaseCreateFleet} {
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_launch_phase_duration_seconds", map[string]string{
+				"phase":         phase,
+				"nodepool":      nodePool.Name,
+				"capacity_type": corev1beta1.CapacityTypeOnDemand,
+			})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetHistogram().GetSampleCount()).To(BeNumerically("==", 1))
+		}
+	})
+	Context("On-Demand Backstop", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand}}},
+			}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			awsEnv.EC2API.InsufficientCapacityPools.Set([]fake.CapacityPool{
+				{CapacityType: corev1beta1.CapacityTypeSpot, InstanceType: "m5.xlarge", Zone: "test-zone-1a"},
+				{CapacityType: corev1beta1.CapacityTypeSpot, InstanceType: "m5.xlarge", Zone: "test-zone-1b"},
+				{CapacityType: corev1beta1.CapacityTypeSpot, InstanceType: "m5.xlarge", Zone: "test-zone-1c"},
+			})
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+		})
+		It("should return an ICE error when spot is unavailable and the backstop is disabled", func() {
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(instance).To(BeNil())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+		It("should launch on-demand in the same call when spot is unavailable", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{OnDemandBackstop: lo.ToPtr(true)}))
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.CapacityType).To(Equal(corev1beta1.CapacityTypeOnDemand))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(2))
+			inputs := lo.Map(lo.Range(2), func(_ int, _ int) *ec2.CreateFleetInput {
+				return awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			})
+			Expect(lo.Map(inputs, func(in *ec2.CreateFleetInput, _ int) string {
+				return aws.StringValue(in.TargetCapacitySpecification.DefaultTargetCapacityType)
+			})).To(ConsistOf(corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand))
+		})
+		It("should not launch on-demand when the NodeClaim only allows spot", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{OnDemandBackstop: lo.ToPtr(true)}))
+			nodeClaim.Spec.Requirements[0].Values = []string{corev1beta1.CapacityTypeSpot}
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(instance).To(BeNil())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Warm Pools", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		storeWarmInstance := func(instanceType, zone, state string) string {
+			instanceID := fake.InstanceID()
+			awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+				State: &ec2.InstanceState{Name: aws.String(state)},
+				Tags: []*ec2.Tag{
+					{Key: aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)), Value: aws.String("owned")},
+					{Key: aws.String(corev1beta1.NodePoolLabelKey), Value: aws.String(nodePool.Name)},
+					{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String(nodeClass.Name)},
+					{Key: aws.String(v1beta1.TagWarmPool), Value: aws.String(nodePool.Name)},
+				},
+				Placement:    &ec2.Placement{AvailabilityZone: aws.String(zone)},
+				LaunchTime:   aws.Time(time.Now().Add(-time.Hour)),
+				InstanceId:   aws.String(instanceID),
+				InstanceType: aws.String(instanceType),
+			})
+			return instanceID
+		}
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{WarmPools: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+		})
+		It("should launch warm pool instances on-demand with the warm pool tag", func() {
+			_, err := awsEnv.InstanceProvider.LaunchWarm(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(input.TargetCapacitySpecification.DefaultTargetCapacityType)).To(Equal(corev1beta1.CapacityTypeOnDemand))
+			tagSpecification, ok := lo.Find(input.TagSpecifications, func(t *ec2.TagSpecification) bool {
+				return aws.StringValue(t.ResourceType) == ec2.ResourceTypeInstance
+			})
+			Expect(ok).To(BeTrue())
+			Expect(tagSpecification.Tags).To(ContainElement(&ec2.Tag{Key: aws.String(v1beta1.TagWarmPool), Value: aws.String(nodePool.Name)}))
+		})
+		It("should register the nodes of warm pool instances with the warm pool taint", func() {
+			_, err := awsEnv.InstanceProvider.LaunchWarm(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				userData, err := base64.StdEncoding.DecodeString(aws.StringValue(input.LaunchTemplateData.UserData))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(userData)).To(ContainSubstring(v1beta1.WarmPoolNoScheduleTaint.Key))
+			})
+			Expect(nodeClaim.Spec.Taints).ToNot(ContainElement(v1beta1.WarmPoolNoScheduleTaint))
+		})
+		It("should list warm pool instances separately from the instances of NodeClaims", func() {
+			id := storeWarmInstance("m5.xlarge", "test-zone-1a", ec2.InstanceStateNameStopped)
+			instances, err := awsEnv.InstanceProvider.List(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(BeEmpty())
+			instances, err = awsEnv.InstanceProvider.ListWarm(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(HaveLen(1))
+			Expect(instances[0].ID).To(Equal(id))
+		})
+		It("should start a stopped warm pool instance rather than launching an instance", func() {
+			id := storeWarmInstance("m5.xlarge", "test-zone-1a", ec2.InstanceStateNameStopped)
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.ID).To(Equal(id))
+			Expect(instance.State).To(Equal(ec2.InstanceStateNamePending))
+			Expect(instance.Tags).ToNot(HaveKey(v1beta1.TagWarmPool))
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+			// The instance is owned by the NodeClaim now, so it's listed with the other instances
+			instances, err := awsEnv.InstanceProvider.List(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(HaveLen(1))
+		})
+		It("should not start warm pool instances that are still bootstrapping", func() {
+			storeWarmInstance("m5.xlarge", "test-zone-1a", ec2.InstanceStateNameRunning)
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+		It("should not start warm pool instances of incompatible instance types or zones", func() {
+			storeWarmInstance("m5.large", "test-zone-1a", ec2.InstanceStateNameStopped)
+			storeWarmInstance("m5.xlarge", "test-zone-1b", ec2.InstanceStateNameStopped)
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"test-zone-1a"}}},
+			}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+		It("should not start warm pool instances for NodeClaims that require spot", func() {
+			storeWarmInstance("m5.xlarge", "test-zone-1a", ec2.InstanceStateNameStopped)
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(0))
+		})
+		It("should not start warm pool instances when warm pools are disabled", func() {
+			ctx = options.ToContext(ctx, test.Options())
+			storeWarmInstance("m5.xlarge", "test-zone-1a", ec2.InstanceStateNameStopped)
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+	})
+	Context("Hibernation", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		storeHibernatedInstance := func(instanceType, zone string) string {
+			instanceID := fake.InstanceID()
+			awsEnv.EC2API.Instances.Store(instanceID, &ec2.Instance{
+				State: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameStopped)},
+				Tags: []*ec2.Tag{
+					{Key: aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)), Value: aws.String("owned")},
+					{Key: aws.String(corev1beta1.NodePoolLabelKey), Value: aws.String(nodePool.Name)},
+					{Key: aws.String(v1beta1.LabelNodeClass), Value: aws.String(nodeClass.Name)},
+					{Key: aws.String(v1beta1.TagHibernated), Value: aws.String(nodePool.Name)},
+					{Key: aws.String(v1beta1.TagHibernationTime), Value: aws.String(time.Now().Format(time.RFC3339))},
+				},
+				HibernationOptions: &ec2.HibernationOptions{Configured: aws.Bool(true)},
+				Placement:          &ec2.Placement{AvailabilityZone: aws.String(zone)},
+				LaunchTime:         aws.Time(time.Now().Add(-time.Hour)),
+				InstanceId:         aws.String(instanceID),
+				InstanceType:       aws.String(instanceType),
+			})
+			return instanceID
+		}
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{Hibernation: lo.ToPtr(true)}))
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1beta1.AnnotationHibernation: v1beta1.HibernationEnabled})
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+		})
+		It("should tag and hibernate instances", func() {
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.InstanceProvider.Hibernate(ctx, instance.ID, nodePool.Name)).To(Succeed())
+			Expect(awsEnv.EC2API.StopInstancesBehavior.Calls()).To(Equal(1))
+			Expect(aws.BoolValue(awsEnv.EC2API.StopInstancesBehavior.CalledWithInput.Pop().Hibernate)).To(BeTrue())
+			instances, err := awsEnv.InstanceProvider.ListHibernated(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(HaveLen(1))
+			Expect(instances[0].Tags).To(HaveKeyWithValue(v1beta1.TagHibernated, nodePool.Name))
+			Expect(instances[0].Tags).To(HaveKey(v1beta1.TagHibernationTime))
+		})
+		It("should not get or list hibernated instances as the instances of NodeClaims", func() {
+			id := storeHibernatedInstance("m5.xlarge", "test-zone-1a")
+			_, err := awsEnv.InstanceProvider.Get(ctx, id)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			instances, err := awsEnv.InstanceProvider.List(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(BeEmpty())
+		})
+		It("should resume a hibernated instance rather than launching an instance", func() {
+			id := storeHibernatedInstance("m5.xlarge", "test-zone-1a")
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.ID).To(Equal(id))
+			Expect(instance.State).To(Equal(ec2.InstanceStateNamePending))
+			Expect(instance.Tags).ToNot(HaveKey(v1beta1.TagHibernated))
+			Expect(instance.Tags).ToNot(HaveKey(v1beta1.TagHibernationTime))
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+			_, err = awsEnv.InstanceProvider.Get(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should not resume hibernated instances for NodeClaims that don't enable hibernation", func() {
+			storeHibernatedInstance("m5.xlarge", "test-zone-1a")
+			delete(nodeClaim.Annotations, v1beta1.AnnotationHibernation)
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+		It("should not resume hibernated instances of incompatible instance types", func() {
+			storeHibernatedInstance("m5.large", "test-zone-1a")
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+	})
+	It("should fail to launch when the EC2NodeClass has more tags than can be applied", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		// Admission rejects this many tags, so they're only set in-memory to mimic an EC2NodeClass that predates validation
+		nodeClass.Spec.Tags = lo.SliceToMap(lo.Range(v1beta1.MaxTags-v1beta1.ReservedTags+1), func(i int) (string, string) {
+			return fmt.Sprintf("tag-%02d", i), "value"
+		})
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("tags specified"))
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+	})
+	It("should fail to launch when the EC2NodeClass and volume tags together have more tags than can be applied", func() {
+		nodeClass.Spec.Tags = lo.SliceToMap(lo.Range(v1beta1.MaxTags-v1beta1.ReservedTags), func(i int) (string, string) {
+			return fmt.Sprintf("tag-%02d", i), "value"
+		})
+		nodeClass.Spec.Tags[v1beta1.TagName] = "name"
+		nodeClass.Spec.VolumeTags = map[string]string{"backup": "true"}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).To(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+	})
+	It("should apply volume and fleet tags to their resources in addition to the EC2NodeClass tags", func() {
+		nodeClass.Spec.Tags = map[string]string{"cost-center": "instances", "team": "a"}
+		nodeClass.Spec.VolumeTags = map[string]string{"cost-center": "volumes", "backup": "true"}
+		nodeClass.Spec.FleetTags = map[string]string{"cost-center": "fleets"}
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		tags := lo.SliceToMap(createFleetInput.TagSpecifications, func(ts *ec2.TagSpecification) (string, map[string]string) {
+			return aws.StringValue(ts.ResourceType), lo.SliceToMap(ts.Tags, func(t *ec2.Tag) (string, string) { return *t.Key, *t.Value })
+		})
+		Expect(tags[ec2.ResourceTypeInstance]).To(HaveKeyWithValue("cost-center", "instances"))
+		Expect(tags[ec2.ResourceTypeInstance]).ToNot(HaveKey("backup"))
+		Expect(tags[ec2.ResourceTypeVolume]).To(HaveKeyWithValue("cost-center", "volumes"))
+		Expect(tags[ec2.ResourceTypeVolume]).To(HaveKeyWithValue("backup", "true"))
+		Expect(tags[ec2.ResourceTypeVolume]).To(HaveKeyWithValue("team", "a"))
+		Expect(tags[ec2.ResourceTypeVolume]).To(HaveKeyWithValue(corev1beta1.NodePoolLabelKey, nodePool.Name))
+		Expect(tags[ec2.ResourceTypeFleet]).To(HaveKeyWithValue("cost-center", "fleets"))
+		Expect(tags[ec2.ResourceTypeFleet]).ToNot(HaveKey("backup"))
+	})
+	Context("Instance Selection Scoring", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		priceOf := func(override *ec2.FleetLaunchTemplateOverridesRequest, capacityType string) float64 {
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool {
+				return it.Name == aws.StringValue(override.InstanceType)
+			})
+			Expect(ok).To(BeTrue())
+			offering, ok := lo.Find(it.Offerings, func(o corecloudprovider.Offering) bool {
+				return o.Requirements.Get(v1.LabelTopologyZone).Any() == aws.StringValue(override.AvailabilityZone) &&
+					o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == capacityType
+			})
+			Expect(ok).To(BeTrue())
+			return offering.Price
+		}
+		prioritizedOverrides := func(input *ec2.CreateFleetInput) []*ec2.FleetLaunchTemplateOverridesRequest {
+			overrides := lo.FlatMap(input.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []*ec2.FleetLaunchTemplateOverridesRequest {
+				return ltc.Overrides
+			})
+			sort.Slice(overrides, func(i, j int) bool {
+				return aws.Float64Value(overrides[i].Priority) < aws.Float64Value(overrides[j].Priority)
+			})
+			return overrides
+		}
+		It("should not prioritize overrides when no weights are configured", func() {
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyLowestPrice))
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).To(BeNil())
+				}
+			}
+		})
+		It("should prioritize cheaper on-demand offerings when price is weighted", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceSelectionWeights: map[string]float64{instance.ScorerPrice: 1}}))
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyPrioritized))
+			overrides := prioritizedOverrides(createFleetInput)
+			Expect(overrides).ToNot(BeEmpty())
+			for i := 1; i < len(overrides); i++ {
+				Expect(overrides[i].Priority).ToNot(BeNil())
+				Expect(priceOf(overrides[i-1], corev1beta1.CapacityTypeOnDemand)).To(BeNumerically("<=", priceOf(overrides[i], corev1beta1.CapacityTypeOnDemand)))
+			}
+		})
+		It("should prioritize spot offerings with the largest discount when interruption risk is weighted", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceSelectionWeights: map[string]float64{instance.ScorerInterruptionRisk: 1}}))
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyCapacityOptimizedPrioritized))
+			overrides := prioritizedOverrides(createFleetInput)
+			Expect(overrides).ToNot(BeEmpty())
+			ratioOf := func(override *ec2.FleetLaunchTemplateOverridesRequest) float64 {
+				return priceOf(override, corev1beta1.CapacityTypeSpot) / priceOf(override, corev1beta1.CapacityTypeOnDemand)
+			}
+			for i := 1; i < len(overrides); i++ {
+				Expect(ratioOf(overrides[i-1])).To(BeNumerically("<=", ratioOf(overrides[i])))
+			}
+		})
+		It("should prioritize zones with fewer recent launches when zone balance is weighted", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceSelectionWeights: map[string]float64{instance.ScorerZoneBalance: 1}}))
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+			first, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			second, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.Zone).ToNot(Equal(first.Zone))
+		})
+		It("should rank zones by suitability for NodeClaims of scored NodePools without configured weights", func() {
+			awsEnv.ZoneScores.Set(nodePool.Name, map[string]float64{"test-zone-1a": 0.2, "test-zone-1b": 0.9, "test-zone-1c": 0.5})
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyCapacityOptimizedPrioritized))
+			zones := lo.Map(prioritizedOverrides(createFleetInput), func(o *ec2.FleetLaunchTemplateOverridesRequest, _ int) string {
+				return aws.StringValue(o.AvailabilityZone)
+			})
+			Expect(zones).To(Equal([]string{"test-zone-1b", "test-zone-1a"}))
+		})
+	})
+	Context("Allocation Strategy", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+		})
+		create := func() *ec2.CreateFleetInput {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			return awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		}
+		It("should launch spot instances with the configured allocation strategy", func() {
+			nodeClass.Spec.AllocationStrategy = &v1beta1.AllocationStrategy{Spot: aws.String(ec2.SpotAllocationStrategyCapacityOptimized)}
+			createFleetInput := create()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyCapacityOptimized))
+		})
+		It("should launch on-demand instances with the configured allocation strategy", func() {
+			nodeClaim.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+			}
+			nodeClass.Spec.AllocationStrategy = &v1beta1.AllocationStrategy{OnDemand: aws.String(ec2.FleetOnDemandAllocationStrategyPrioritized)}
+			createFleetInput := create()
+			Expect(aws.StringValue(createFleetInput.OnDemandOptions.AllocationStrategy)).To(Equal(ec2.FleetOnDemandAllocationStrategyPrioritized))
+		})
+		It("should use the default strategy for a capacity type without a configured allocation strategy", func() {
+			nodeClass.Spec.AllocationStrategy = &v1beta1.AllocationStrategy{OnDemand: aws.String(ec2.FleetOnDemandAllocationStrategyPrioritized)}
+			createFleetInput := create()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyPriceCapacityOptimized))
+		})
+		It("should not prioritize overrides for an allocation strategy that ignores priorities", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceSelectionWeights: map[string]float64{instance.ScorerPrice: 1}}))
+			nodeClass.Spec.AllocationStrategy = &v1beta1.AllocationStrategy{Spot: aws.String(ec2.SpotAllocationStrategyLowestPrice)}
+			createFleetInput := create()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyLowestPrice))
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(override.Priority).To(BeNil())
+				}
+			}
+		})
+		It("should prioritize overrides for an allocation strategy that honors priorities", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceSelectionWeights: map[string]float64{instance.ScorerPrice: 1}}))
+			nodeClass.Spec.AllocationStrategy = &v1beta1.AllocationStrategy{Spot: aws.String(ec2.SpotAllocationStrategyCapacityOptimizedPrioritized)}
+			createFleetInput := create()
+			Expect(aws.StringValue(createFleetInput.SpotOptions.AllocationStrategy)).To(Equal(ec2.SpotAllocationStrategyCapacityOptimizedPrioritized))
+			Expect(lo.SomeBy(createFleetInput.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest) bool {
+				return lo.SomeBy(ltc.Overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest) bool { return o.Priority != nil })
+			})).To(BeTrue())
+		})
+	})
 	It("should return all NodePool-owned instances from List", func() {
 		ids := sets.New[string]()
 		// Provision instances that have the karpenter.sh/nodepool key
@@ -61,6 +61,11 @@ func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string,
 	profileName := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
 	tags := lo.Assign(m.InstanceProfileTags(options.FromContext(ctx).ClusterName), map[string]string{v1.LabelTopologyRegion: p.region})
 
+	// In read-only mode the instance profile isn't created, and the name that it would have is returned so that the
+	// NodeClass is ready for the launches that are reported instead of performed
+	if options.FromContext(ctx).ReadOnly {
+		return profileName, nil
+	}
 	// An instance profile exists for this NodeClass
 	if _, ok := p.cache.Get(string(m.GetUID())); ok {
 		return profileName, nil
@@ -107,6 +112,9 @@ func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string,
 }
 
 func (p *DefaultProvider) Delete(ctx context.Context, m ResourceOwner) error {
+	if options.FromContext(ctx).ReadOnly {
+		return nil
+	}
 	profileName := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
 	out, err := p.iamapi.GetInstanceProfileWithContext(ctx, &iam.GetInstanceProfileInput{
 		InstanceProfileName: aws.String(profileName),
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+)
+
+// Classes of instance types that the instance-type-allow-list and instance-type-deny-list settings can refer to
+const (
+	InstanceTypeClassMetal              = "metal"
+	InstanceTypeClassBurstable          = "burstable"
+	InstanceTypeClassPreviousGeneration = "previous-generation"
+)
+
+// instanceTypeClasses match the instance types of each class
+var instanceTypeClasses = map[string]func(*ec2.InstanceTypeInfo) bool{
+	InstanceTypeClassMetal:     func(info *ec2.InstanceTypeInfo) bool { return aws.BoolValue(info.BareMetal) },
+	InstanceTypeClassBurstable: func(info *ec2.InstanceTypeInfo) bool { return aws.BoolValue(info.BurstablePerformanceSupported) },
+	InstanceTypeClassPreviousGeneration: func(info *ec2.InstanceTypeInfo) bool {
+		return info.CurrentGeneration != nil && !aws.BoolValue(info.CurrentGeneration)
+	},
+}
+
+// matchesInstanceTypeList returns true if any entry of the list is the instance type, its family, a glob pattern of
+// either, or a class that the instance type belongs to
+func matchesInstanceTypeList(info *ec2.InstanceTypeInfo, list []string) bool {
+	name := aws.StringValue(info.InstanceType)
+	family, _, _ := strings.Cut(name, ".")
+	return lo.SomeBy(list, func(entry string) bool {
+		if class, ok := instanceTypeClasses[entry]; ok {
+			return class(info)
+		}
+		return lo.SomeBy([]string{name, family}, func(s string) bool {
+			matched, _ := path.Match(entry, s)
+			return matched
+		})
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -28,9 +29,11 @@ import (
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -41,15 +44,22 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
+// nitroTPMVersion is the NitroTPM version that the NitroTPM EC2NodeClass setting requires
+const nitroTPMVersion = "2.0"
+
 type Provider interface {
 	LivenessProbe(*http.Request) error
 	List(context.Context, *corev1beta1.KubeletConfiguration, *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error)
+	ListCompatible(context.Context, *corev1beta1.NodeClaim, *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error)
 	UpdateInstanceTypes(ctx context.Context) error
 	UpdateInstanceTypeOfferings(ctx context.Context) error
 }
@@ -59,6 +69,8 @@ type DefaultProvider struct {
 	ec2api          ec2iface.EC2API
 	subnetProvider  subnet.Provider
 	pricingProvider pricing.Provider
+	// snapshot replaces the instance types and offerings that are discovered from the EC2 API when it's set
+	snapshot *snapshot.Snapshot
 
 	// Values stored *before* considering insufficient capacity errors from the unavailableOfferings cache.
 	// Fully initialized Instance Types are also cached based on the set of all instance types, zones, unavailableOfferings cache,
@@ -74,6 +86,10 @@ type DefaultProvider struct {
 	instanceTypesCache *cache.Cache
 
 	unavailableOfferings *awscache.UnavailableOfferings
+	blockedOfferings     *awscache.BlockedOfferings
+	zoneHealth           *awscache.ZoneHealth
+	vpcCNI               *awscache.VPCCNI
+	vcpuQuotaHeadroom    *awscache.VCPUQuotaHeadroom
 	cm                   *pretty.ChangeMonitor
 	// instanceTypesSeqNum is a monotonically increasing change counter used to avoid the expensive hashing operation on instance types
 	instanceTypesSeqNum uint64
@@ -82,8 +98,10 @@ type DefaultProvider struct {
 }
 
 func NewDefaultProvider(region string, instanceTypesCache *cache.Cache, ec2api ec2iface.EC2API, subnetProvider subnet.Provider,
-	unavailableOfferingsCache *awscache.UnavailableOfferings, pricingProvider pricing.Provider) *DefaultProvider {
+	unavailableOfferingsCache *awscache.UnavailableOfferings, blockedOfferings *awscache.BlockedOfferings, zoneHealth *awscache.ZoneHealth, vpcCNI *awscache.VPCCNI,
+	vcpuQuotaHeadroom *awscache.VCPUQuotaHeadroom, pricingProvider pricing.Provider, snapshot *snapshot.Snapshot) *DefaultProvider {
 	return &DefaultProvider{
+		snapshot:              snapshot,
 		ec2api:                ec2api,
 		region:                region,
 		subnetProvider:        subnetProvider,
@@ -92,12 +110,55 @@ func NewDefaultProvider(region string, instanceTypesCache *cache.Cache, ec2api e
 		instanceTypeOfferings: map[string]sets.Set[string]{},
 		instanceTypesCache:    instanceTypesCache,
 		unavailableOfferings:  unavailableOfferingsCache,
+		blockedOfferings:      blockedOfferings,
+		zoneHealth:            zoneHealth,
+		vpcCNI:                vpcCNI,
+		vcpuQuotaHeadroom:     vcpuQuotaHeadroom,
 		cm:                    pretty.NewChangeMonitor(),
 		instanceTypesSeqNum:   0,
 	}
 }
 
 func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfiguration, nodeClass *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
+	_, instanceTypes, err := p.list(ctx, kc, nodeClass)
+	return instanceTypes, err
+}
+
+// ListCompatible returns the instance types that the NodeClaim can launch as, which are the instance types that are
+// compatible with its requirements, have an available offering that's compatible with them within its spot max price,
+// and fit its resource requests. A batch of identical pending pods produces many NodeClaims with the same scheduling
+// spec, so the result is memoized on the instance types that it was filtered from and the hash of that spec.
+func (p *DefaultProvider) ListCompatible(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, nodeClass *v1beta1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
+	listKey, instanceTypes, err := p.list(ctx, nodeClaim.Spec.Kubelet, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	specHash, err := hashstructure.Hash([]interface{}{
+		nodeClaim.Spec.Requirements,
+		nodeClaim.Spec.Resources.Requests,
+		lo.PickByKeys(nodeClaim.Annotations, []string{v1beta1.AnnotationSpotMaxPrice, v1beta1.AnnotationSpotMaxPricePercent}),
+	}, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return nil, fmt.Errorf("hashing nodeclaim scheduling spec, %w", err)
+	}
+	key := fmt.Sprintf("compatible-%s-%016x", listKey, specHash)
+	if item, ok := p.instanceTypesCache.Get(key); ok {
+		return append([]*cloudprovider.InstanceType{}, item.([]*cloudprovider.InstanceType)...), nil
+	}
+	instanceTypes = NewSpotMaxPrice(ctx, nodeClaim.Annotations).Apply(instanceTypes)
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	compatible := lo.Filter(instanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
+		return reqs.Compatible(i.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil &&
+			len(i.Offerings.Compatible(reqs).Available()) > 0 &&
+			resources.Fits(nodeClaim.Spec.Resources.Requests, i.Allocatable())
+	})
+	p.instanceTypesCache.SetDefault(key, compatible)
+	return append([]*cloudprovider.InstanceType{}, compatible...), nil
+}
+
+// list returns the instance types along with the key that they're cached under, which changes whenever any input that
+// the instance types are computed from changes
+func (p *DefaultProvider) list(ctx context.Context, kc *corev1beta1.KubeletConfiguration, nodeClass *v1beta1.EC2NodeClass) (string, []*cloudprovider.InstanceType, error) {
 	p.muInstanceTypeInfo.RLock()
 	p.muInstanceTypeOfferings.RLock()
 	defer p.muInstanceTypeInfo.RUnlock()
@@ -107,37 +168,62 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 		kc = &corev1beta1.KubeletConfiguration{}
 	}
 	if len(p.instanceTypesInfo) == 0 {
-		return nil, fmt.Errorf("no instance types found")
+		return "", nil, fmt.Errorf("no instance types found")
 	}
 	if len(p.instanceTypeOfferings) == 0 {
-		return nil, fmt.Errorf("no instance types offerings found")
+		return "", nil, fmt.Errorf("no instance types offerings found")
 	}
 	if len(nodeClass.Status.Subnets) == 0 {
-		return nil, fmt.Errorf("no subnets found")
+		return "", nil, fmt.Errorf("no subnets found")
 	}
 
 	subnetZones := sets.New(lo.Map(nodeClass.Status.Subnets, func(s v1beta1.Subnet, _ int) string {
 		return aws.StringValue(&s.Zone)
 	})...)
 
+	// Offerings in impaired zones are unavailable, unless every zone of the EC2NodeClass is impaired, in which case
+	// launches are still attempted rather than failing outright
+	impairedZones := p.zoneHealth.ImpairedZones()
+	if subnetZones.Difference(impairedZones).Len() == 0 {
+		impairedZones = sets.New[string]()
+	}
+
 	// Compute fully initialized instance types hash key
 	subnetZonesHash, _ := hashstructure.Hash(subnetZones, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	kcHash, _ := hashstructure.Hash(kc, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	blockDeviceMappingsHash, _ := hashstructure.Hash(nodeClass.Spec.BlockDeviceMappings, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	key := fmt.Sprintf("%d-%d-%d-%016x-%016x-%016x-%s-%s",
+	kubeletOverlaysHash, _ := hashstructure.Hash(nodeClass.Spec.KubeletOverlays, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	// The driver labels of instance types depend on the requirements of the AMIs, and whether instance types are
+	// supported depends on the ENA support of the AMIs
+	amiRequirementsHash, _ := hashstructure.Hash(lo.Map(nodeClass.Status.AMIs, func(a v1beta1.AMI, _ int) v1beta1.AMI {
+		return v1beta1.AMI{Requirements: a.Requirements, ENASupport: a.ENASupport}
+	}), hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	allowList, denyList := options.FromContext(ctx).InstanceTypeAllowList, options.FromContext(ctx).InstanceTypeDenyList
+	instanceTypeListsHash, _ := hashstructure.Hash([][]string{allowList, denyList}, hashstructure.FormatV2, nil)
+	// The impaired zones are part of the key since impairments from launch failures expire without notice
+	key := fmt.Sprintf("%d-%d-%d-%d-%d-%d-%016x-%016x-%016x-%016x-%016x-%016x-%s-%s-%s-%t-%s",
 		p.instanceTypesSeqNum,
 		p.instanceTypeOfferingsSeqNum,
 		p.unavailableOfferings.SeqNum,
+		p.blockedOfferings.SeqNum,
+		p.vpcCNI.SeqNum,
+		p.vcpuQuotaHeadroom.SeqNum,
 		subnetZonesHash,
 		kcHash,
+		kubeletOverlaysHash,
 		blockDeviceMappingsHash,
+		amiRequirementsHash,
+		instanceTypeListsHash,
 		aws.StringValue((*string)(nodeClass.Spec.InstanceStorePolicy)),
 		aws.StringValue(nodeClass.Spec.AMIFamily),
+		lo.FromPtr(nodeClass.Spec.BootMode),
+		lo.FromPtr(nodeClass.Spec.NitroTPM),
+		strings.Join(sets.List(impairedZones), ","),
 	)
 	if item, ok := p.instanceTypesCache.Get(key); ok {
 		// Ensure what's returned from this function is a shallow-copy of the slice (not a deep-copy of the data itself)
 		// so that modifications to the ordering of the data don't affect the original
-		return append([]*cloudprovider.InstanceType{}, item.([]*cloudprovider.InstanceType)...), nil
+		return key, append([]*cloudprovider.InstanceType{}, item.([]*cloudprovider.InstanceType)...), nil
 	}
 
 	// Get all zones across all offerings
@@ -152,7 +238,22 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 		log.FromContext(ctx).WithValues("zones", allZones.UnsortedList()).V(1).Info("discovered zones")
 	}
 	amiFamily := amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{})
-	result := lo.Map(p.instanceTypesInfo, func(i *ec2.InstanceTypeInfo, _ int) *cloudprovider.InstanceType {
+	// The allow and deny lists are enforced before any requirements are evaluated, so that no NodePool can opt out of them
+	notAllowed := lo.CountBy(p.instanceTypesInfo, func(i *ec2.InstanceTypeInfo) bool {
+		return len(allowList) != 0 && !matchesInstanceTypeList(i, allowList)
+	})
+	denied := lo.CountBy(p.instanceTypesInfo, func(i *ec2.InstanceTypeInfo) bool { return matchesInstanceTypeList(i, denyList) })
+	instanceTypesFiltered.With(prometheus.Labels{listLabel: "allow"}).Set(float64(notAllowed))
+	instanceTypesFiltered.With(prometheus.Labels{listLabel: "deny"}).Set(float64(denied))
+	instanceTypesInfo := lo.Filter(p.instanceTypesInfo, func(i *ec2.InstanceTypeInfo, _ int) bool {
+		if len(allowList) != 0 && !matchesInstanceTypeList(i, allowList) || matchesInstanceTypeList(i, denyList) {
+			return false
+		}
+		return supportsBootOptions(i, nodeClass)
+	})
+	warmTargets, hasWarmTargets := p.vpcCNI.WarmTargets()
+	var unsupportedNetworking []string
+	result := lo.FilterMap(instanceTypesInfo, func(i *ec2.InstanceTypeInfo, _ int) (*cloudprovider.InstanceType, bool) {
 		instanceTypeVCPU.With(prometheus.Labels{
 			instanceTypeLabel: *i.InstanceType,
 		}).Set(float64(aws.Int64Value(i.VCpuInfo.DefaultVCpus)))
@@ -164,14 +265,46 @@ func (p *DefaultProvider) List(ctx context.Context, kc *corev1beta1.KubeletConfi
 		// Any changes to the values passed into the NewInstanceType method will require making updates to the cache key
 		// so that Karpenter is able to cache the set of InstanceTypes based on values that alter the set of instance types
 		// !!! Important !!!
-		return NewInstanceType(ctx, i, p.region,
+		maxPods := lo.Ternary(hasWarmTargets, networkLimitedMaxPods(ctx, i, amiFamily, kc.MaxPods, warmTargets), kc.MaxPods)
+		it := NewInstanceType(ctx, i, p.region,
 			nodeClass.Spec.BlockDeviceMappings, nodeClass.Spec.InstanceStorePolicy,
-			kc.MaxPods, kc.PodsPerCore, kc.KubeReserved, kc.SystemReserved, kc.EvictionHard, kc.EvictionSoft,
-			amiFamily, p.createOfferings(ctx, i, allZones, p.instanceTypeOfferings[aws.StringValue(i.InstanceType)], nodeClass.Status.Subnets),
+			maxPods, kc.PodsPerCore, kc.KubeReserved, kc.SystemReserved, kc.EvictionHard, kc.EvictionSoft,
+			amiFamily, p.createOfferings(ctx, i, allZones, impairedZones, p.instanceTypeOfferings[aws.StringValue(i.InstanceType)], nodeClass.Status.Subnets),
 		)
+		// Instance types don't know which capacity type they'll launch as, so their overhead covers the kubelet overlay
+		// of each capacity type of the EC2NodeClass
+		for _, capacityType := range []string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand} {
+			if overlay := amifamily.KubeletOverlay(nodeClass, capacityType); overlay != nil {
+				okc := amifamily.ApplyKubeletOverlay(kc, overlay)
+				it.Overhead = maxOverhead(it.Overhead, computeOverhead(ctx, i, nodeClass.Spec.BlockDeviceMappings, nodeClass.Spec.InstanceStorePolicy,
+					maxPods, kc.PodsPerCore, okc.KubeReserved, okc.SystemReserved, okc.EvictionHard, okc.EvictionSoft, amiFamily))
+			}
+		}
+		it.Requirements.Add(amifamily.DriverRequirements(it.Requirements, nodeClass.Status.AMIs).Values()...)
+		if !amifamily.SupportsNetworking(i, it.Requirements, nodeClass.Status.AMIs) {
+			unsupportedNetworking = append(unsupportedNetworking, it.Name)
+			return nil, false
+		}
+		return it, true
 	})
+	if p.cm.HasChanged(fmt.Sprintf("unsupported-networking/%s", nodeClass.Name), unsupportedNetworking) {
+		log.FromContext(ctx).WithValues("instance-types", unsupportedNetworking).V(1).Info("excluded instance types whose ena support isn't compatible with the amis")
+	}
 	p.instanceTypesCache.SetDefault(key, result)
-	return result, nil
+	return key, result, nil
+}
+
+// supportsBootOptions returns true if the instance type can boot with the EC2NodeClass's boot mode and NitroTPM
+// settings
+func supportsBootOptions(info *ec2.InstanceTypeInfo, nodeClass *v1beta1.EC2NodeClass) bool {
+	if nodeClass.Spec.BootMode != nil && !lo.Contains(aws.StringValueSlice(info.SupportedBootModes), string(*nodeClass.Spec.BootMode)) {
+		return false
+	}
+	if lo.FromPtr(nodeClass.Spec.NitroTPM) {
+		return aws.StringValue(info.NitroTpmSupport) == ec2.NitroTpmSupportSupported &&
+			info.NitroTpmInfo != nil && lo.Contains(aws.StringValueSlice(info.NitroTpmInfo.SupportedVersions), nitroTPMVersion)
+	}
+	return true
 }
 
 func (p *DefaultProvider) LivenessProbe(req *http.Request) error {
@@ -190,7 +323,9 @@ func (p *DefaultProvider) UpdateInstanceTypes(ctx context.Context) error {
 	defer p.muInstanceTypeInfo.Unlock()
 	var instanceTypes []*ec2.InstanceTypeInfo
 
-	if err := p.ec2api.DescribeInstanceTypesPagesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
+	if p.snapshot != nil {
+		instanceTypes = p.snapshot.InstanceTypes
+	} else if err := p.ec2api.DescribeInstanceTypesPagesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
 		Filters: []*ec2.Filter{
 			{
 				Name:   aws.String("supported-virtualization-type"),
@@ -230,7 +365,9 @@ func (p *DefaultProvider) UpdateInstanceTypeOfferings(ctx context.Context) error
 
 	// Get offerings from EC2
 	instanceTypeOfferings := map[string]sets.Set[string]{}
-	if err := p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, &ec2.DescribeInstanceTypeOfferingsInput{LocationType: aws.String("availability-zone")},
+	if p.snapshot != nil {
+		instanceTypeOfferings = lo.MapValues(p.snapshot.Offerings, func(zones []string, _ string) sets.Set[string] { return sets.New(zones...) })
+	} else if err := p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, &ec2.DescribeInstanceTypeOfferingsInput{LocationType: aws.String("availability-zone")},
 		func(output *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
 			for _, offering := range output.InstanceTypeOfferings {
 				if _, ok := instanceTypeOfferings[aws.StringValue(offering.InstanceType)]; !ok {
@@ -252,30 +389,61 @@ func (p *DefaultProvider) UpdateInstanceTypeOfferings(ctx context.Context) error
 	return nil
 }
 
+// ExportSnapshot adds the current instance types and their offerings to the snapshot
+func (p *DefaultProvider) ExportSnapshot(s *snapshot.Snapshot) {
+	p.muInstanceTypeInfo.RLock()
+	defer p.muInstanceTypeInfo.RUnlock()
+	p.muInstanceTypeOfferings.RLock()
+	defer p.muInstanceTypeOfferings.RUnlock()
+
+	s.InstanceTypes = p.instanceTypesInfo
+	s.Offerings = lo.MapValues(p.instanceTypeOfferings, func(zones sets.Set[string], _ string) []string { return sets.List(zones) })
+}
+
 // createOfferings creates a set of mutually exclusive offerings for a given instance type. This provider maintains an
 // invariant that each offering is mutually exclusive. Specifically, there is an offering for each permutation of zone
 // and capacity type. ZoneID is also injected into the offering requirements, when available, but there is a 1-1
-// mapping between zone and zoneID so this does not change the number of offerings.
+// mapping between zone and zoneID so this does not change the number of offerings. The same holds for the Outpost ID of
+// zones whose subnets are on an Outpost, which only have on-demand offerings.
 //
-// Each requirement on the offering is guaranteed to have a single value. To get the value for a requirement on an
-// offering, you can do the following thanks to this invariant:
+// Each requirement on the offering is guaranteed to have a single value, except for the Outpost ID of offerings in the
+// region, which must not exist. To get the value for a requirement on an offering, you can do the following thanks to
+// this invariant:
 //
 //	offering.Requirements.Get(v1.TopologyLabelZone).Any()
-func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2.InstanceTypeInfo, zones, instanceTypeZones sets.Set[string], subnets []v1beta1.Subnet) []cloudprovider.Offering {
+func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2.InstanceTypeInfo, zones, impairedZones, instanceTypeZones sets.Set[string], subnets []v1beta1.Subnet) []cloudprovider.Offering {
 	var offerings []cloudprovider.Offering
 	for zone := range zones {
+		zonalSubnet := subnet.ZonalSubnet(subnets, zone)
+		outpostID := utils.OutpostID(zonalSubnet.OutpostARN)
 		// while usage classes should be a distinct set, there's no guarantee of that
 		for capacityType := range sets.NewString(aws.StringValueSlice(instanceType.SupportedUsageClasses)...) {
+			// Outposts only run on-demand instances
+			if outpostID != "" && capacityType != ec2.UsageClassTypeOnDemand {
+				continue
+			}
 			// exclude any offerings that have recently seen an insufficient capacity error from EC2
 			isUnavailable := p.unavailableOfferings.IsUnavailable(*instanceType.InstanceType, zone, capacityType)
+			// exclude any offerings that an operator has explicitly blocked
+			isBlocked := p.blockedOfferings.IsBlocked(*instanceType.InstanceType, zone)
+			// exclude any offerings in zones that are impaired
+			isImpaired := impairedZones.Has(zone)
+			// exclude any offerings that would exceed the vCPU service quota of their capacity type and family class
+			exceedsQuota := options.FromContext(ctx).VCPUQuotaFiltering &&
+				!p.vcpuQuotaHeadroom.Fits(capacityType, quota.FamilyClass(*instanceType.InstanceType), aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus))
 			var price float64
 			var ok bool
-			switch capacityType {
-			case ec2.UsageClassTypeSpot:
+			switch {
+			case outpostID != "":
+				// The capacity of an Outpost isn't priced by the pricing API, and is offered wherever EC2 reports the
+				// instance type in the zone of the Outpost until a launch on the Outpost fails for lack of capacity
+				price, ok = options.FromContext(ctx).OutpostInstancePrices[*instanceType.InstanceType], true
+				isUnavailable = isUnavailable || p.unavailableOfferings.IsUnavailable(*instanceType.InstanceType, outpostID, capacityType)
+			case capacityType == ec2.UsageClassTypeSpot:
 				price, ok = p.pricingProvider.SpotPrice(*instanceType.InstanceType, zone)
-			case ec2.UsageClassTypeOnDemand:
-				price, ok = p.pricingProvider.OnDemandPrice(*instanceType.InstanceType)
-			case "capacity-block":
+			case capacityType == ec2.UsageClassTypeOnDemand:
+				price, ok = p.pricingProvider.ZonalOnDemandPrice(*instanceType.InstanceType, zone)
+			case capacityType == "capacity-block":
 				// ignore since karpenter doesn't support it yet, but do not log an unknown capacity type error
 				continue
 			default:
@@ -283,10 +451,7 @@ func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2
 				continue
 			}
 
-			subnet, hasSubnet := lo.Find(subnets, func(s v1beta1.Subnet) bool {
-				return s.Zone == zone
-			})
-			available := !isUnavailable && ok && instanceTypeZones.Has(zone) && hasSubnet
+			available := !isUnavailable && !isBlocked && !isImpaired && !exceedsQuota && ok && instanceTypeZones.Has(zone) && zonalSubnet.ID != ""
 			offering := cloudprovider.Offering{
 				Requirements: scheduling.NewRequirements(
 					scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, capacityType),
@@ -295,8 +460,17 @@ func (p *DefaultProvider) createOfferings(ctx context.Context, instanceType *ec2
 				Price:     price,
 				Available: available,
 			}
-			if subnet.ZoneID != "" {
-				offering.Requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyZoneID, v1.NodeSelectorOpIn, subnet.ZoneID))
+			if zonalSubnet.ZoneID != "" {
+				offering.Requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyZoneID, v1.NodeSelectorOpIn, zonalSubnet.ZoneID))
+			}
+			if zonalSubnet.ZoneType != "" {
+				offering.Requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyZoneType, v1.NodeSelectorOpIn, zonalSubnet.ZoneType))
+			}
+			// Offerings in the region can't satisfy pods that require an Outpost
+			if outpostID != "" {
+				offering.Requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyOutpostID, v1.NodeSelectorOpIn, outpostID))
+			} else {
+				offering.Requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyOutpostID, v1.NodeSelectorOpDoesNotExist))
 			}
 			offerings = append(offerings, offering)
 			instanceTypeOfferingAvailable.With(prometheus.Labels{
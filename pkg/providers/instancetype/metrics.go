@@ -26,6 +26,7 @@ const (
 	instanceTypeLabel      = "instance_type"
 	capacityTypeLabel      = "capacity_type"
 	zoneLabel              = "zone"
+	listLabel              = "list"
 )
 
 var (
@@ -76,8 +77,19 @@ var (
 			capacityTypeLabel,
 			zoneLabel,
 		})
+	instanceTypesFiltered = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_types_filtered",
+			Help:      "Number of instance types excluded by the instance-type-allow-list and instance-type-deny-list settings, based on the list that excluded them.",
+		},
+		[]string{
+			listLabel,
+		},
+	)
 )
 
 func init() {
-	crmetrics.Registry.MustRegister(instanceTypeVCPU, instanceTypeMemory, instanceTypeOfferingAvailable, instanceTypeOfferingPriceEstimate)
+	crmetrics.Registry.MustRegister(instanceTypeVCPU, instanceTypeMemory, instanceTypeOfferingAvailable, instanceTypeOfferingPriceEstimate, instanceTypesFiltered)
 }
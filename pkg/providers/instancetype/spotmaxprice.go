@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// SpotMaxPrice is the cap on the hourly price of spot instances that's set with the spot-max-price and
+// spot-max-price-percent annotations of a NodePool's template. When both are set, the lower cap applies.
+type SpotMaxPrice struct {
+	absolute *float64
+	percent  *float64
+}
+
+// NewSpotMaxPrice returns the spot max price of the annotations. Invalid annotations are logged and ignored, since
+// retrying won't fix them.
+func NewSpotMaxPrice(ctx context.Context, annotations map[string]string) SpotMaxPrice {
+	parse := func(key string) *float64 {
+		value, ok := annotations[key]
+		if !ok {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil && (f <= 0 || math.IsInf(f, 0) || math.IsNaN(f)) {
+			err = fmt.Errorf("must be a positive number")
+		}
+		if err != nil {
+			log.FromContext(ctx).WithValues("annotation", key, "value", value).Error(err, "invalid spot max price")
+			return nil
+		}
+		return &f
+	}
+	return SpotMaxPrice{
+		absolute: parse(v1beta1.AnnotationSpotMaxPrice),
+		percent:  parse(v1beta1.AnnotationSpotMaxPricePercent),
+	}
+}
+
+// IsSet returns true if spot prices are capped
+func (s SpotMaxPrice) IsSet() bool {
+	return s.absolute != nil || s.percent != nil
+}
+
+// For returns the maximum price of a spot instance of the instance type in the zone, and false if it isn't capped or
+// the on-demand price that the cap is relative to isn't known
+func (s SpotMaxPrice) For(it *cloudprovider.InstanceType, zone string) (float64, bool) {
+	var caps []float64
+	if s.absolute != nil {
+		caps = append(caps, *s.absolute)
+	}
+	if s.percent != nil {
+		if price, ok := onDemandPrice(it, zone); ok {
+			caps = append(caps, price*(*s.percent)/100)
+		}
+	}
+	if len(caps) == 0 {
+		return 0, false
+	}
+	return lo.Min(caps), true
+}
+
+// Apply returns the instance types with the spot offerings whose price exceeds the cap marked unavailable, so that
+// capacity is only planned and launched within the cap. Instance types are shared between callers, so the affected
+// ones are copied rather than modified.
+func (s SpotMaxPrice) Apply(instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	if !s.IsSet() {
+		return instanceTypes
+	}
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		exceeds := func(o cloudprovider.Offering) bool {
+			if !o.Available || o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() != corev1beta1.CapacityTypeSpot {
+				return false
+			}
+			maxPrice, ok := s.For(it, o.Requirements.Get(v1.LabelTopologyZone).Any())
+			return ok && o.Price > maxPrice
+		}
+		if !lo.ContainsBy(it.Offerings, exceeds) {
+			return it
+		}
+		return &cloudprovider.InstanceType{
+			Name:         it.Name,
+			Requirements: it.Requirements,
+			Capacity:     it.Capacity,
+			Overhead:     it.Overhead,
+			Offerings: lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+				o.Available = o.Available && !exceeds(o)
+				return o
+			}),
+		}
+	})
+}
+
+// onDemandPrice returns the on-demand price of the instance type in the zone, falling back to its lowest on-demand
+// price in any zone
+func onDemandPrice(it *cloudprovider.InstanceType, zone string) (float64, bool) {
+	onDemand := lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool {
+		return o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeOnDemand
+	})
+	if len(onDemand) == 0 {
+		return 0, false
+	}
+	if o, ok := lo.Find(onDemand, func(o cloudprovider.Offering) bool { return o.Requirements.Get(v1.LabelTopologyZone).Any() == zone }); ok {
+		return o.Price, true
+	}
+	return lo.Min(lo.Map(onDemand, func(o cloudprovider.Offering, _ int) float64 { return o.Price })), true
+}
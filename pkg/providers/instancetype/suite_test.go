@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/awslabs/operatorpkg/status"
 	"github.com/imdario/mergo"
@@ -55,11 +56,13 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 )
 
@@ -84,7 +87,7 @@ var _ = BeforeSuite(func() {
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.LaunchRamps, awsEnv.TerminationHookProvider, fakeClock)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster)
 })
@@ -226,6 +229,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Well Known to AWS
 			v1beta1.LabelInstanceHypervisor:                   "nitro",
 			v1beta1.LabelInstanceEncryptionInTransitSupported: "true",
+			v1beta1.LabelInstanceHibernationSupported:         "false",
 			v1beta1.LabelInstanceCategory:                     "g",
 			v1beta1.LabelInstanceGeneration:                   "4",
 			v1beta1.LabelInstanceFamily:                       "g4dn",
@@ -244,6 +248,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceAcceleratorManufacturer:      "aws",
 			v1beta1.LabelInstanceAcceleratorCount:             "1",
 			v1beta1.LabelTopologyZoneID:                       "tstz1-1a",
+			v1beta1.LabelTopologyZoneType:                     v1beta1.ZoneTypeAvailabilityZone,
 			// Deprecated Labels
 			v1.LabelFailureDomainBetaRegion: fake.DefaultRegion,
 			v1.LabelFailureDomainBetaZone:   "test-zone-1a",
@@ -254,8 +259,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1.LabelWindowsBuild:            v1beta1.Windows2022Build,
 		}
 
-		// Ensure that we're exercising all well known labels
-		Expect(lo.Keys(nodeSelector)).To(ContainElements(append(corev1beta1.WellKnownLabels.UnsortedList(), lo.Keys(corev1beta1.NormalizedLabels)...)))
+		// Ensure that we're exercising all well known labels except for the outpost label
+		Expect(lo.Keys(nodeSelector)).To(ContainElements(append(corev1beta1.WellKnownLabels.Difference(sets.New(
+			v1beta1.LabelTopologyOutpostID,
+		)).UnsortedList(), lo.Keys(corev1beta1.NormalizedLabels)...)))
 
 		var pods []*v1.Pod
 		for key, value := range nodeSelector {
@@ -281,6 +288,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Well Known to AWS
 			v1beta1.LabelInstanceHypervisor:                   "nitro",
 			v1beta1.LabelInstanceEncryptionInTransitSupported: "true",
+			v1beta1.LabelInstanceHibernationSupported:         "false",
 			v1beta1.LabelInstanceCategory:                     "g",
 			v1beta1.LabelInstanceGeneration:                   "4",
 			v1beta1.LabelInstanceFamily:                       "g4dn",
@@ -296,6 +304,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceGPUMemory:                    "16384",
 			v1beta1.LabelInstanceLocalNVME:                    "900",
 			v1beta1.LabelTopologyZoneID:                       "tstz1-1a",
+			v1beta1.LabelTopologyZoneType:                     v1beta1.ZoneTypeAvailabilityZone,
 			// Deprecated Labels
 			v1.LabelFailureDomainBetaRegion: fake.DefaultRegion,
 			v1.LabelFailureDomainBetaZone:   "test-zone-1a",
@@ -305,7 +314,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			"topology.ebs.csi.aws.com/zone": "test-zone-1a",
 		}
 
-		// Ensure that we're exercising all well known labels except for accelerator labels
+		// Ensure that we're exercising all well known labels except for accelerator and outpost labels
 		Expect(lo.Keys(nodeSelector)).To(ContainElements(
 			append(
 				corev1beta1.WellKnownLabels.Difference(sets.New(
@@ -313,6 +322,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					v1beta1.LabelInstanceAcceleratorName,
 					v1beta1.LabelInstanceAcceleratorManufacturer,
 					v1.LabelWindowsBuild,
+					v1beta1.LabelTopologyOutpostID,
 				)).UnsortedList(), lo.Keys(corev1beta1.NormalizedLabels)...)))
 
 		pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: nodeSelector})
@@ -334,6 +344,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Well Known to AWS
 			v1beta1.LabelInstanceHypervisor:                   "nitro",
 			v1beta1.LabelInstanceEncryptionInTransitSupported: "true",
+			v1beta1.LabelInstanceHibernationSupported:         "false",
 			v1beta1.LabelInstanceCategory:                     "inf",
 			v1beta1.LabelInstanceGeneration:                   "1",
 			v1beta1.LabelInstanceFamily:                       "inf1",
@@ -347,6 +358,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceAcceleratorManufacturer:      "aws",
 			v1beta1.LabelInstanceAcceleratorCount:             "1",
 			v1beta1.LabelTopologyZoneID:                       "tstz1-1a",
+			v1beta1.LabelTopologyZoneType:                     v1beta1.ZoneTypeAvailabilityZone,
 			// Deprecated Labels
 			v1.LabelFailureDomainBetaRegion: fake.DefaultRegion,
 			v1.LabelFailureDomainBetaZone:   "test-zone-1a",
@@ -356,7 +368,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			"topology.ebs.csi.aws.com/zone": "test-zone-1a",
 		}
 
-		// Ensure that we're exercising all well known labels except for gpu labels and nvme
+		// Ensure that we're exercising all well known labels except for gpu labels, nvme and the outpost label
 		expectedLabels := append(corev1beta1.WellKnownLabels.Difference(sets.New(
 			v1beta1.LabelInstanceGPUCount,
 			v1beta1.LabelInstanceGPUName,
@@ -364,6 +376,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 			v1beta1.LabelInstanceGPUMemory,
 			v1beta1.LabelInstanceLocalNVME,
 			v1.LabelWindowsBuild,
+			v1beta1.LabelTopologyOutpostID,
 		)).UnsortedList(), lo.Keys(corev1beta1.NormalizedLabels)...)
 		Expect(lo.Keys(nodeSelector)).To(ContainElements(expectedLabels))
 
@@ -577,6 +590,91 @@ var _ = Describe("InstanceTypeProvider", func() {
 			}
 		}
 	})
+	Context("Deprioritized Instance Types", func() {
+		var pod *v1.Pod
+		overrideInstanceTypes := func() []string {
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			return lo.FlatMap(call.LaunchTemplateConfigs, func(ltc *ec2.FleetLaunchTemplateConfigRequest, _ int) []string {
+				return lo.Map(ltc.Overrides, func(ovr *ec2.FleetLaunchTemplateOverridesRequest, _ int) string {
+					return aws.StringValue(ovr.InstanceType)
+				})
+			})
+		}
+		BeforeEach(func() {
+			pod = coretest.UnschedulablePod(coretest.PodOptions{
+				ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+					Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				},
+			})
+		})
+		It("should not de-prioritize metal when it isn't a deprioritized category", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{DeprioritizedInstanceTypes: []string{"nvidia-gpu"}}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			instanceTypes := overrideInstanceTypes()
+			Expect(instanceTypes).To(ContainElement(ContainSubstring("metal")))
+			Expect(instanceTypes).ToNot(ContainElement(HavePrefix("g4dn")))
+		})
+		It("should de-prioritize xen instance types when xen is a deprioritized category", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{DeprioritizedInstanceTypes: []string{"xen"}}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			instanceTypes := overrideInstanceTypes()
+			Expect(instanceTypes).ToNot(ContainElement("p3.8xlarge"))
+			Expect(instanceTypes).To(ContainElement(ContainSubstring("metal")))
+		})
+		It("should prefer the NodePool's deprioritized categories to the setting", func() {
+			nodePool.Spec.Template.Annotations = map[string]string{v1beta1.AnnotationDeprioritizedInstanceTypes: ""}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			instanceTypes := overrideInstanceTypes()
+			Expect(instanceTypes).To(ContainElement(ContainSubstring("metal")))
+			Expect(instanceTypes).To(ContainElement(HavePrefix("g4dn")))
+		})
+	})
+	Context("Instance Type Allow and Deny Lists", func() {
+		list := func() []string {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			return lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+		}
+		It("should exclude denied instance types, families and classes", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeDenyList: []string{"metal", "burstable", "c6g", "m5.x*"}}))
+			names := list()
+			Expect(names).ToNot(ContainElement(ContainSubstring("metal")))
+			Expect(names).ToNot(ContainElement(HavePrefix("t3.")))
+			Expect(names).ToNot(ContainElement(HavePrefix("c6g.")))
+			Expect(names).ToNot(ContainElement("m5.xlarge"))
+			Expect(names).To(ContainElement("m5.large"))
+		})
+		It("should only include allowed instance types", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeAllowList: []string{"m5", "c6g.large"}}))
+			names := list()
+			Expect(names).ToNot(BeEmpty())
+			for _, name := range names {
+				Expect(name).To(Or(HavePrefix("m5."), Equal("c6g.large")))
+			}
+		})
+		It("should prefer the deny list to the allow list", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeAllowList: []string{"m5"}, InstanceTypeDenyList: []string{"metal"}}))
+			Expect(list()).ToNot(ContainElement("m5.metal"))
+		})
+		It("should report the number of filtered instance types", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceTypeDenyList: []string{"m5.large", "m5.xlarge"}}))
+			list()
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_instance_types_filtered", map[string]string{"list": "deny"})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 2))
+			metric, ok = FindMetricWithLabelValues("karpenter_cloudprovider_instance_types_filtered", map[string]string{"list": "allow"})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 0))
+		})
+	})
 	It("should launch on metal", func() {
 		// add a nodePool requirement for instance type exists to remove our default filter for metal sizes
 		nodePool.Spec.Template.Spec.Requirements = append(nodePool.Spec.Template.Spec.Requirements, corev1beta1.NodeSelectorRequirementWithMinValues{
@@ -740,6 +838,50 @@ var _ = Describe("InstanceTypeProvider", func() {
 		}
 		Expect(nodeNames.Len()).To(Equal(1))
 	})
+	It("should advertise AWS Neuron resources and labels from the instance type's neuron info", func() {
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).To(BeNil())
+		it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "trn1.2xlarge" })
+		Expect(ok).To(BeTrue())
+		Expect(it.Capacity).To(HaveKeyWithValue(v1beta1.ResourceAWSNeuron, resource.MustParse("1")))
+		Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorName).Values()).To(ConsistOf("trainium"))
+		Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Values()).To(ConsistOf("aws"))
+		Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Values()).To(ConsistOf("1"))
+	})
+	It("should not combine accelerator labels from neuron info and inference accelerator info", func() {
+		out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+		Expect(err).To(BeNil())
+		instanceInfo := awsutil.CopyOf(out).(*ec2.DescribeInstanceTypesOutput)
+		inf1, ok := lo.Find(instanceInfo.InstanceTypes, func(info *ec2.InstanceTypeInfo) bool { return lo.FromPtr(info.InstanceType) == "inf1.2xlarge" })
+		Expect(ok).To(BeTrue())
+		inf1.NeuronInfo = &ec2.NeuronInfo{NeuronDevices: []*ec2.NeuronDeviceInfo{{Name: aws.String("Inferentia2"), Count: aws.Int64(2)}}}
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(instanceInfo)
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).To(BeNil())
+		it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "inf1.2xlarge" })
+		Expect(ok).To(BeTrue())
+		Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorName).Values()).To(ConsistOf("inferentia2"))
+		Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Values()).To(ConsistOf("aws"))
+		Expect(it.Requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Values()).To(ConsistOf("2"))
+	})
+	It("should not advertise more EFA interfaces than the instance type has network cards", func() {
+		out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+		Expect(err).To(BeNil())
+		instanceInfo := awsutil.CopyOf(out).(*ec2.DescribeInstanceTypesOutput)
+		dl1, ok := lo.Find(instanceInfo.InstanceTypes, func(info *ec2.InstanceTypeInfo) bool { return lo.FromPtr(info.InstanceType) == "dl1.24xlarge" })
+		Expect(ok).To(BeTrue())
+		dl1.NetworkInfo.EfaInfo.MaximumEfaInterfaces = aws.Int64(8)
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(instanceInfo)
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).To(BeNil())
+		it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "dl1.24xlarge" })
+		Expect(ok).To(BeTrue())
+		Expect(it.Capacity).To(HaveKeyWithValue(v1beta1.ResourceEFA, resource.MustParse("4")))
+	})
 	It("should launch instances for vpc.amazonaws.com/efa resource requests", func() {
 		nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
 			{
@@ -926,6 +1068,84 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 		ExpectScheduled(ctx, env.Client, pod)
 	})
+	It("should launch instances in the zone type that pods require", func() {
+		nodeClass.Status.Subnets = append(nodeClass.Status.Subnets, v1beta1.Subnet{
+			ID:       "subnet-test4",
+			Zone:     "test-zone-1a-local",
+			ZoneID:   "tstz1-1alocal",
+			ZoneType: v1beta1.ZoneTypeLocalZone,
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			NodeRequirements: []v1.NodeSelectorRequirement{{
+				Key:      v1beta1.LabelTopologyZoneType,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{v1beta1.ZoneTypeLocalZone},
+			}},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-1a-local"))
+		Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelTopologyZoneType, v1beta1.ZoneTypeLocalZone))
+	})
+	It("should not launch instances for pods that require a zone type without subnets", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			NodeRequirements: []v1.NodeSelectorRequirement{{
+				Key:      v1beta1.LabelTopologyZoneType,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{v1beta1.ZoneTypeWavelengthZone},
+			}},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectNotScheduled(ctx, env.Client, pod)
+	})
+	It("should launch on-demand instances onto an Outpost", func() {
+		nodeClass.Status.Subnets = append(nodeClass.Status.Subnets, v1beta1.Subnet{
+			ID:         "subnet-test4",
+			Zone:       "test-zone-1a-local",
+			ZoneID:     "tstz1-1alocal",
+			ZoneType:   v1beta1.ZoneTypeLocalZone,
+			OutpostARN: "arn:aws:outposts:us-west-2:111122223333:outpost/op-0123456789abcdef0",
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			NodeRequirements: []v1.NodeSelectorRequirement{{
+				Key:      v1beta1.LabelTopologyOutpostID,
+				Operator: v1.NodeSelectorOpIn,
+				Values:   []string{"op-0123456789abcdef0"},
+			}},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		Expect(node.Labels).To(HaveKeyWithValue(v1.LabelTopologyZone, "test-zone-1a-local"))
+		Expect(node.Labels).To(HaveKeyWithValue(v1beta1.LabelTopologyOutpostID, "op-0123456789abcdef0"))
+		Expect(node.Labels).To(HaveKeyWithValue(corev1beta1.CapacityTypeLabelKey, corev1beta1.CapacityTypeOnDemand))
+	})
+	It("should use the configured prices for Outpost offerings", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			OutpostInstancePrices: map[string]float64{"m5.large": 0.5},
+		}))
+		nodeClass.Status.Subnets = []v1beta1.Subnet{
+			{
+				ID:         "subnet-test4",
+				Zone:       "test-zone-1a-local",
+				ZoneID:     "tstz1-1alocal",
+				ZoneType:   v1beta1.ZoneTypeLocalZone,
+				OutpostARN: "arn:aws:outposts:us-west-2:111122223333:outpost/op-0123456789abcdef0",
+			},
+		}
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+		Expect(err).To(BeNil())
+		it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		Expect(it.Offerings.Available()).ToNot(BeEmpty())
+		for _, of := range it.Offerings.Available() {
+			Expect(of.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any()).To(Equal(corev1beta1.CapacityTypeOnDemand))
+			Expect(of.Requirements.Get(v1beta1.LabelTopologyOutpostID).Any()).To(Equal("op-0123456789abcdef0"))
+			Expect(of.Price).To(BeNumerically("==", 0.5))
+		}
+	})
 	Context("Overhead", func() {
 		var info *ec2.InstanceTypeInfo
 		BeforeEach(func() {
@@ -941,6 +1161,56 @@ var _ = Describe("InstanceTypeProvider", func() {
 			})
 			Expect(ok).To(BeTrue())
 		})
+		It("should reserve the largest overhead of the kubelet overlays of the capacity types", func() {
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{
+				SystemReserved: map[string]string{string(v1.ResourceCPU): "1", string(v1.ResourceMemory): "1Gi"},
+			}
+			nodeClass.Spec.KubeletOverlays = &v1beta1.KubeletOverlays{
+				Spot:     &v1beta1.KubeletOverlay{SystemReserved: map[string]string{string(v1.ResourceMemory): "3Gi"}},
+				OnDemand: &v1beta1.KubeletOverlay{SystemReserved: map[string]string{string(v1.ResourceCPU): "2"}},
+			}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(i *corecloudprovider.InstanceType) bool { return i.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Overhead.SystemReserved.Cpu().String()).To(Equal("2"))
+			Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("3Gi"))
+		})
+		Context("VM Memory Overhead", func() {
+			newInstanceType := func() *corecloudprovider.InstanceType {
+				return instancetype.NewInstanceType(ctx,
+					info,
+					fake.DefaultRegion,
+					nodeClass.Spec.BlockDeviceMappings,
+					nodeClass.Spec.InstanceStorePolicy,
+					nil, nil, nil, nil, nil, nil,
+					amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{}),
+					nil,
+				)
+			}
+			It("should use the global overhead percent when no override matches", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					VMMemoryOverheadPercent:          lo.ToPtr[float64](0.1),
+					VMMemoryOverheadPercentOverrides: map[string]float64{"c5": 0.05},
+				}))
+				// 16384Mi - ceil(16384Mi * 0.1)
+				Expect(newInstanceType().Capacity.Memory().String()).To(Equal("14745Mi"))
+			})
+			It("should use the instance family override", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					VMMemoryOverheadPercent:          lo.ToPtr[float64](0.1),
+					VMMemoryOverheadPercentOverrides: map[string]float64{"m5": 0.05},
+				}))
+				Expect(newInstanceType().Capacity.Memory().String()).To(Equal("15564Mi"))
+			})
+			It("should prefer the instance type override over the instance family override", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					VMMemoryOverheadPercent:          lo.ToPtr[float64](0.1),
+					VMMemoryOverheadPercentOverrides: map[string]float64{"m5": 0.05, "m5.xlarge": 0},
+				}))
+				Expect(newInstanceType().Capacity.Memory().String()).To(Equal("16Gi"))
+			})
+		})
 		Context("System Reserved Resources", func() {
 			It("should use defaults when no kubelet is specified", func() {
 				amiFamily := amifamily.GetAMIFamily(nodeClass.Spec.AMIFamily, &amifamily.Options{})
@@ -1696,6 +1966,49 @@ var _ = Describe("InstanceTypeProvider", func() {
 				}
 			}
 		})
+		It("should reserve the VPC CNI warm IP target from ENI limited pod density", func() {
+			awsEnv.VPCCNI.SetWarmTargets(&awscache.VPCCNIWarmTargets{WarmIPTarget: 5})
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 30))
+		})
+		It("should not reserve the VPC CNI warm IP target when prefix delegation is enabled", func() {
+			awsEnv.VPCCNI.SetWarmTargets(&awscache.VPCCNIWarmTargets{WarmIPTarget: 5, PrefixDelegation: true})
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 35))
+		})
+		It("should not raise a configured max-pods above the VPC CNI warm IP ceiling", func() {
+			awsEnv.VPCCNI.SetWarmTargets(&awscache.VPCCNIWarmTargets{WarmIPTarget: 5})
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{MaxPods: lo.ToPtr(int32(20))}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 20))
+		})
+		It("should cap a configured max-pods at the ENI limited pods when the VPC CNI doesn't use prefix delegation", func() {
+			awsEnv.VPCCNI.SetWarmTargets(&awscache.VPCCNIWarmTargets{})
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{MaxPods: lo.ToPtr(int32(50))}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 35))
+		})
+		It("should not cap a configured max-pods when the VPC CNI uses prefix delegation", func() {
+			awsEnv.VPCCNI.SetWarmTargets(&awscache.VPCCNIWarmTargets{PrefixDelegation: true})
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{MaxPods: lo.ToPtr(int32(50))}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 50))
+		})
 		It("shouldn't report more resources than are actually available on instances", func() {
 			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{
 				Subnets: []*ec2.Subnet{
@@ -2000,6 +2313,254 @@ var _ = Describe("InstanceTypeProvider", func() {
 			Expect(instanceTypeNames.Has("m5.xlarge"))
 		})
 	})
+	Context("Blocked Offerings", func() {
+		It("should mark blocked offerings as unavailable in the blocked zone", func() {
+			awsEnv.BlockedOfferingsCache.Set([]awscache.BlockedOffering{{InstanceType: "m5.xlarge", Zone: "test-zone-1a", Expiration: time.Now().Add(time.Hour)}})
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			for _, o := range it.Offerings {
+				Expect(o.Available).To(Equal(o.Requirements.Get(v1.LabelTopologyZone).Any() != "test-zone-1a"))
+			}
+		})
+		It("should mark blocked offerings as unavailable in all zones when no zone is specified", func() {
+			awsEnv.BlockedOfferingsCache.Set([]awscache.BlockedOffering{{InstanceType: "m5.xlarge", Expiration: time.Now().Add(time.Hour)}})
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Offerings.Available()).To(HaveLen(0))
+		})
+		It("should ignore expired blocked offerings", func() {
+			awsEnv.BlockedOfferingsCache.Set([]awscache.BlockedOffering{{InstanceType: "m5.xlarge", Expiration: time.Now().Add(-time.Hour)}})
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Offerings.Available()).ToNot(HaveLen(0))
+		})
+	})
+	Context("VCPU Quota Filtering", func() {
+		BeforeEach(func() {
+			awsEnv.VCPUQuotaHeadroom.Set(map[string]map[string]float64{
+				corev1beta1.CapacityTypeOnDemand: {quota.FamilyClassStandard: 4},
+				corev1beta1.CapacityTypeSpot:     {quota.FamilyClassStandard: 64},
+			})
+		})
+		It("should mark offerings that don't fit under their vcpu quota as unavailable", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{VCPUQuotaReporting: lo.ToPtr(true), VCPUQuotaFiltering: lo.ToPtr(true)}))
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.2xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Offerings.Available()).ToNot(HaveLen(0))
+			for _, o := range it.Offerings {
+				Expect(o.Available).To(Equal(o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeSpot))
+			}
+			it, ok = lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(lo.Filter(it.Offerings.Available(), func(o corecloudprovider.Offering, _ int) bool {
+				return o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeOnDemand
+			})).ToNot(HaveLen(0))
+		})
+		It("should not filter offerings when quota filtering is disabled", func() {
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.2xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(lo.Filter(it.Offerings.Available(), func(o corecloudprovider.Offering, _ int) bool {
+				return o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeOnDemand
+			})).ToNot(HaveLen(0))
+		})
+	})
+	Context("Zone Health", func() {
+		It("should mark offerings as unavailable in zones that EC2 reports as impaired", func() {
+			awsEnv.ZoneHealth.SetReported(map[string]string{"test-zone-1a": "impaired"})
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			for _, it := range instanceTypes {
+				for _, o := range it.Offerings.Available() {
+					Expect(o.Requirements.Get(v1.LabelTopologyZone).Any()).ToNot(Equal("test-zone-1a"))
+				}
+			}
+		})
+		It("should mark offerings as unavailable in zones where several instance families failed to launch", func() {
+			for _, instanceType := range []string{"m5.xlarge", "c6g.large", "t4g.medium"} {
+				awsEnv.ZoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", instanceType, "test-zone-1b")
+			}
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+			Expect(ok).To(BeTrue())
+			for _, o := range it.Offerings {
+				Expect(o.Available).To(Equal(o.Requirements.Get(v1.LabelTopologyZone).Any() != "test-zone-1b"))
+			}
+		})
+		It("should not mark a zone as impaired when a single instance family failed to launch", func() {
+			for _, instanceType := range []string{"m5.large", "m5.xlarge", "m5.2xlarge"} {
+				awsEnv.ZoneHealth.RecordFailure(ctx, "InsufficientInstanceCapacity", instanceType, "test-zone-1b")
+			}
+			Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1b")).To(BeFalse())
+		})
+		It("should keep offerings available when every zone is impaired", func() {
+			awsEnv.ZoneHealth.SetReported(map[string]string{"test-zone-1a": "impaired", "test-zone-1b": "impaired", "test-zone-1c": "unavailable"})
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).To(BeNil())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Offerings.Available()).ToNot(HaveLen(0))
+		})
+		It("should provision in a healthy zone once capacity errors mark a zone as impaired", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ZoneFailover: lo.ToPtr(true)}))
+			awsEnv.EC2API.InsufficientCapacityPools.Set(lo.Map([]string{"m5.large", "t3.large", "m6idn.32xlarge"}, func(instanceType string, _ int) fake.CapacityPool {
+				return fake.CapacityPool{CapacityType: corev1beta1.CapacityTypeOnDemand, InstanceType: instanceType, Zone: "test-zone-1a"}
+			}))
+			nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large", "t3.large", "m6idn.32xlarge"}}},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			for _, instanceType := range []string{"m5.large", "t3.large", "m6idn.32xlarge"} {
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{v1.LabelTopologyZone: "test-zone-1a", v1.LabelInstanceTypeStable: instanceType}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+			}
+			Expect(awsEnv.ZoneHealth.IsImpaired("test-zone-1a")).To(BeTrue())
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels[v1.LabelTopologyZone]).ToNot(Equal("test-zone-1a"))
+		})
+	})
+	Context("Boot Options", func() {
+		names := func(instanceTypes []*corecloudprovider.InstanceType) []string {
+			return lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+		}
+		It("should exclude instance types that don't support the uefi boot mode", func() {
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeUEFI)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).ToNot(ContainElement("p3.8xlarge"))
+			Expect(names(instanceTypes)).To(ContainElements("m5.large", "t4g.medium"))
+		})
+		It("should exclude instance types that don't support the legacy-bios boot mode", func() {
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeLegacyBIOS)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).ToNot(ContainElements("c6g.large", "t4g.medium"))
+			Expect(names(instanceTypes)).To(ContainElements("m5.large", "p3.8xlarge"))
+		})
+		It("should exclude instance types that don't support NitroTPM 2.0", func() {
+			nodeClass.Spec.BootMode = lo.ToPtr(v1beta1.BootModeUEFI)
+			nodeClass.Spec.NitroTPM = lo.ToPtr(true)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).ToNot(ContainElements("m5.metal", "dl1.24xlarge", "p3.8xlarge"))
+			Expect(names(instanceTypes)).To(ContainElements("m5.large", "c6g.large"))
+		})
+		It("should not exclude instance types when no boot options are specified", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).To(ContainElements("m5.metal", "p3.8xlarge", "c6g.large"))
+		})
+	})
+	Context("ENA Support", func() {
+		names := func(instanceTypes []*corecloudprovider.InstanceType) []string {
+			return lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+		}
+		BeforeEach(func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			instanceInfo := awsutil.CopyOf(out).(*ec2.DescribeInstanceTypesOutput)
+			for _, info := range instanceInfo.InstanceTypes {
+				switch lo.FromPtr(info.InstanceType) {
+				case "m5.large", "c6g.large":
+					info.NetworkInfo.EnaSupport = aws.String(ec2.EnaSupportRequired)
+				case "m5.xlarge", "t4g.medium":
+					info.NetworkInfo.EnaSupport = aws.String(ec2.EnaSupportUnsupported)
+				}
+			}
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(instanceInfo)
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		})
+		It("should exclude instance types that require ena when their ami doesn't have it enabled", func() {
+			for i := range nodeClass.Status.AMIs {
+				nodeClass.Status.AMIs[i].ENASupport = lo.ToPtr(false)
+			}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).ToNot(ContainElements("m5.large", "c6g.large"))
+			Expect(names(instanceTypes)).To(ContainElements("m5.xlarge", "t4g.medium", "t3.large"))
+		})
+		It("should exclude instance types that don't support ena when their ami has it enabled", func() {
+			for i := range nodeClass.Status.AMIs {
+				nodeClass.Status.AMIs[i].ENASupport = lo.ToPtr(true)
+			}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).ToNot(ContainElements("m5.xlarge", "t4g.medium"))
+			Expect(names(instanceTypes)).To(ContainElements("m5.large", "c6g.large", "t3.large"))
+		})
+		It("should only exclude instance types based on the ami that they're launched with", func() {
+			// Only the arm64 AMI has ENA enabled
+			for i := range nodeClass.Status.AMIs {
+				nodeClass.Status.AMIs[i].ENASupport = lo.ToPtr(lo.Contains(nodeClass.Status.AMIs[i].Requirements[0].Values, corev1beta1.ArchitectureArm64))
+			}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).ToNot(ContainElements("m5.large", "t4g.medium"))
+			Expect(names(instanceTypes)).To(ContainElements("m5.xlarge", "c6g.large"))
+		})
+		It("should not exclude instance types when the ena support of the amis isn't known", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(instanceTypes)).To(ContainElements("m5.large", "m5.xlarge", "c6g.large", "t4g.medium"))
+		})
+	})
+	Context("Network Bandwidth", func() {
+		BeforeEach(func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			instanceInfo := awsutil.CopyOf(out).(*ec2.DescribeInstanceTypesOutput)
+			for _, info := range instanceInfo.InstanceTypes {
+				if lo.FromPtr(info.InstanceType) == "m5.large" {
+					info.NetworkInfo.NetworkCards = []*ec2.NetworkCardInfo{{BaselineBandwidthInGbps: aws.Float64(100)}}
+				}
+			}
+			// An instance type that isn't in the generated bandwidth table
+			known, ok := lo.Find(instanceInfo.InstanceTypes, func(info *ec2.InstanceTypeInfo) bool {
+				return lo.FromPtr(info.InstanceType) == "m5.xlarge"
+			})
+			Expect(ok).To(BeTrue())
+			unknown := awsutil.CopyOf(known).(*ec2.InstanceTypeInfo)
+			unknown.InstanceType = aws.String("m5.unknown")
+			unknown.NetworkInfo.NetworkCards = []*ec2.NetworkCardInfo{
+				{BaselineBandwidthInGbps: aws.Float64(12.5)},
+				{BaselineBandwidthInGbps: aws.Float64(12.5)},
+			}
+			instanceInfo.InstanceTypes = append(instanceInfo.InstanceTypes, unknown)
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(instanceInfo)
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: fake.MakeInstanceOfferings(instanceInfo.InstanceTypes),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		})
+		bandwidth := func(name string) string {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == name })
+			Expect(ok).To(BeTrue())
+			return it.Requirements.Get(v1beta1.LabelInstanceNetworkBandwidth).Any()
+		}
+		It("should use the generated bandwidth of known instance types", func() {
+			Expect(bandwidth("m5.large")).To(Equal(fmt.Sprint(instancetype.InstanceTypeBandwidthMegabits["m5.large"])))
+		})
+		It("should fall back to the baseline bandwidth of the network cards of unknown instance types", func() {
+			Expect(bandwidth("m5.unknown")).To(Equal("25000"))
+		})
+	})
 	Context("CapacityType", func() {
 		It("should default to on-demand", func() {
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -2326,6 +2887,62 @@ var _ = Describe("InstanceTypeProvider", func() {
 			uniqueInstanceTypeList(instanceTypeResult)
 		})
 	})
+	Context("Compatible Instance Types", func() {
+		var nodeClaim *corev1beta1.NodeClaim
+		BeforeEach(func() {
+			nodeClaim = coretest.NodeClaim(corev1beta1.NodeClaim{
+				Spec: corev1beta1.NodeClaimSpec{
+					Requirements: []corev1beta1.NodeSelectorRequirementWithMinValues{
+						{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m5.large", "m5.xlarge", "c5.large"}}},
+						{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+					},
+					Resources: corev1beta1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+					},
+				},
+			})
+		})
+		It("should only return instance types that are compatible with the nodeclaim", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.large", "m5.xlarge", "c5.large"))
+		})
+		It("should return the same instance types for nodeclaims with the same scheduling spec", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			other := nodeClaim.DeepCopy()
+			other.Name = "other"
+			other.Spec.Requirements = lo.Reverse(other.Spec.Requirements)
+			otherInstanceTypes, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, other, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(otherInstanceTypes).To(Equal(instanceTypes))
+		})
+		It("should not share the returned slice between callers", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			instanceTypes[0] = nil
+			instanceTypes, err = awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(instanceTypes).ToNot(ContainElement(BeNil()))
+		})
+		It("should return different instance types when the scheduling spec changes", func() {
+			_, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			nodeClaim.Spec.Resources.Requests[v1.ResourceCPU] = resource.MustParse("3")
+			instanceTypes, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.xlarge"))
+		})
+		It("should return different instance types when the spot max price changes", func() {
+			nodeClaim.Spec.Requirements[1].Values = []string{corev1beta1.CapacityTypeSpot}
+			_, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			nodeClaim.Annotations = map[string]string{v1beta1.AnnotationSpotMaxPrice: "0.000001"}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.ListCompatible(ctx, nodeClaim, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(instanceTypes).To(HaveLen(0))
+		})
+	})
 	It("should not cause data races when calling List() simultaneously", func() {
 		mu := sync.RWMutex{}
 		var instanceTypeOrder []string
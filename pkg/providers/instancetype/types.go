@@ -31,6 +31,7 @@ import (
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 
@@ -58,11 +59,8 @@ func NewInstanceType(ctx context.Context, info *ec2.InstanceTypeInfo, region str
 		Requirements: computeRequirements(info, offerings, region, amiFamily),
 		Offerings:    offerings,
 		Capacity:     computeCapacity(ctx, info, amiFamily, blockDeviceMappings, instanceStorePolicy, maxPods, podsPerCore),
-		Overhead: &cloudprovider.InstanceTypeOverhead{
-			KubeReserved:      kubeReservedResources(cpu(info), pods(ctx, info, amiFamily, maxPods, podsPerCore), ENILimitedPods(ctx, info), amiFamily, kubeReserved),
-			SystemReserved:    systemReservedResources(systemReserved),
-			EvictionThreshold: evictionThreshold(memory(ctx, info), ephemeralStorage(info, amiFamily, blockDeviceMappings, instanceStorePolicy), amiFamily, evictionHard, evictionSoft),
-		},
+		Overhead: computeOverhead(ctx, info, blockDeviceMappings, instanceStorePolicy, maxPods, podsPerCore,
+			kubeReserved, systemReserved, evictionHard, evictionSoft, amiFamily),
 	}
 	if it.Requirements.Compatible(scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelOSStable, v1.NodeSelectorOpIn, string(v1.Windows)))) == nil {
 		it.Capacity[v1beta1.ResourcePrivateIPv4Address] = *privateIPv4Address(info)
@@ -70,6 +68,26 @@ func NewInstanceType(ctx context.Context, info *ec2.InstanceTypeInfo, region str
 	return it
 }
 
+func computeOverhead(ctx context.Context, info *ec2.InstanceTypeInfo,
+	blockDeviceMappings []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy, maxPods *int32, podsPerCore *int32,
+	kubeReserved map[string]string, systemReserved map[string]string, evictionHard map[string]string, evictionSoft map[string]string,
+	amiFamily amifamily.AMIFamily) *cloudprovider.InstanceTypeOverhead {
+	return &cloudprovider.InstanceTypeOverhead{
+		KubeReserved:      kubeReservedResources(cpu(info), pods(ctx, info, amiFamily, maxPods, podsPerCore), ENILimitedPods(ctx, info), amiFamily, kubeReserved),
+		SystemReserved:    systemReservedResources(systemReserved),
+		EvictionThreshold: evictionThreshold(memory(ctx, info), ephemeralStorage(info, amiFamily, blockDeviceMappings, instanceStorePolicy), amiFamily, evictionHard, evictionSoft),
+	}
+}
+
+// maxOverhead returns an overhead that reserves the most of each resource that any of the overheads reserves
+func maxOverhead(overheads ...*cloudprovider.InstanceTypeOverhead) *cloudprovider.InstanceTypeOverhead {
+	return &cloudprovider.InstanceTypeOverhead{
+		KubeReserved:      resources.MaxResources(lo.Map(overheads, func(o *cloudprovider.InstanceTypeOverhead, _ int) v1.ResourceList { return o.KubeReserved })...),
+		SystemReserved:    resources.MaxResources(lo.Map(overheads, func(o *cloudprovider.InstanceTypeOverhead, _ int) v1.ResourceList { return o.SystemReserved })...),
+		EvictionThreshold: resources.MaxResources(lo.Map(overheads, func(o *cloudprovider.InstanceTypeOverhead, _ int) v1.ResourceList { return o.EvictionThreshold })...),
+	}
+}
+
 //nolint:gocyclo
 func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Offerings, region string, amiFamily amifamily.AMIFamily) scheduling.Requirements {
 	requirements := scheduling.NewRequirements(
@@ -106,6 +124,7 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 		scheduling.NewRequirement(v1beta1.LabelInstanceAcceleratorCount, v1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1beta1.LabelInstanceHypervisor, v1.NodeSelectorOpIn, aws.StringValue(info.Hypervisor)),
 		scheduling.NewRequirement(v1beta1.LabelInstanceEncryptionInTransitSupported, v1.NodeSelectorOpIn, fmt.Sprint(aws.BoolValue(info.NetworkInfo.EncryptionInTransitSupported))),
+		scheduling.NewRequirement(v1beta1.LabelInstanceHibernationSupported, v1.NodeSelectorOpIn, fmt.Sprint(aws.BoolValue(info.HibernationSupported))),
 	)
 	// Only add zone-id label when available in offerings. It may not be available if a user has upgraded from a
 	// previous version of Karpenter w/o zone-id support and the nodeclass subnet status has not yet updated.
@@ -115,6 +134,23 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 	}); len(zoneIDs) != 0 {
 		requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyZoneID, v1.NodeSelectorOpIn, zoneIDs...))
 	}
+	// Zone types are only added when available in offerings for the same reason as zone-ids, so that instance types are
+	// only compatible with the zone types, e.g. local-zone, that they're offered in
+	if zoneTypes := lo.FilterMap(offerings.Available(), func(o cloudprovider.Offering, _ int) (string, bool) {
+		if !o.Requirements.Has(v1beta1.LabelTopologyZoneType) {
+			return "", false
+		}
+		return o.Requirements.Get(v1beta1.LabelTopologyZoneType).Any(), true
+	}); len(zoneTypes) != 0 {
+		requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyZoneType, v1.NodeSelectorOpIn, zoneTypes...))
+	}
+	// Instance types that are only offered in the region can't satisfy pods that require an Outpost. Instance types that
+	// are offered on an Outpost are matched to pods that require one by their offerings.
+	if lo.NoneBy(offerings.Available(), func(o cloudprovider.Offering) bool {
+		return o.Requirements.Get(v1beta1.LabelTopologyOutpostID).Operator() == v1.NodeSelectorOpIn
+	}) {
+		requirements.Add(scheduling.NewRequirement(v1beta1.LabelTopologyOutpostID, v1.NodeSelectorOpDoesNotExist))
+	}
 	// Instance Type Labels
 	instanceFamilyParts := instanceTypeScheme.FindStringSubmatch(aws.StringValue(info.InstanceType))
 	if len(instanceFamilyParts) == 4 {
@@ -130,7 +166,7 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 		requirements[v1beta1.LabelInstanceLocalNVME].Insert(fmt.Sprint(aws.Int64Value(info.InstanceStorageInfo.TotalSizeInGB)))
 	}
 	// Network bandwidth
-	if bandwidth, ok := InstanceTypeBandwidthMegabits[aws.StringValue(info.InstanceType)]; ok {
+	if bandwidth, ok := networkBandwidth(info); ok {
 		requirements[v1beta1.LabelInstanceNetworkBandwidth].Insert(fmt.Sprint(bandwidth))
 	}
 	// GPU Labels
@@ -141,21 +177,28 @@ func computeRequirements(info *ec2.InstanceTypeInfo, offerings cloudprovider.Off
 		requirements.Get(v1beta1.LabelInstanceGPUCount).Insert(fmt.Sprint(aws.Int64Value(gpu.Count)))
 		requirements.Get(v1beta1.LabelInstanceGPUMemory).Insert(fmt.Sprint(aws.Int64Value(gpu.MemoryInfo.SizeInMiB)))
 	}
-	// Accelerators
-	if info.InferenceAcceleratorInfo != nil && len(info.InferenceAcceleratorInfo.Accelerators) == 1 {
-		accelerator := info.InferenceAcceleratorInfo.Accelerators[0]
-		requirements.Get(v1beta1.LabelInstanceAcceleratorName).Insert(lowerKabobCase(aws.StringValue(accelerator.Name)))
-		requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Insert(lowerKabobCase(aws.StringValue(accelerator.Manufacturer)))
-		requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Insert(fmt.Sprint(aws.Int64Value(accelerator.Count)))
-	}
 	// Windows Build Version Labels
 	if family, ok := amiFamily.(*amifamily.Windows); ok {
 		requirements.Get(v1.LabelWindowsBuild).Insert(family.Build)
 	}
+	// Accelerators, taken from a single source so that the accelerator labels stay single-valued. Inferentia and
+	// Trainium instance types may report their devices in both NeuronInfo and InferenceAcceleratorInfo, so NeuronInfo
+	// is preferred when it's present.
+	switch {
+	case info.NeuronInfo != nil && len(info.NeuronInfo.NeuronDevices) == 1:
+		device := info.NeuronInfo.NeuronDevices[0]
+		requirements.Get(v1beta1.LabelInstanceAcceleratorName).Insert(lowerKabobCase(aws.StringValue(device.Name)))
+		requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Insert(lowerKabobCase("AWS"))
+		requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Insert(fmt.Sprint(aws.Int64Value(device.Count)))
+	case info.InferenceAcceleratorInfo != nil && len(info.InferenceAcceleratorInfo.Accelerators) == 1:
+		accelerator := info.InferenceAcceleratorInfo.Accelerators[0]
+		requirements.Get(v1beta1.LabelInstanceAcceleratorName).Insert(lowerKabobCase(aws.StringValue(accelerator.Name)))
+		requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Insert(lowerKabobCase(aws.StringValue(accelerator.Manufacturer)))
+		requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Insert(fmt.Sprint(aws.Int64Value(accelerator.Count)))
 	// Trn1 Accelerators
-	// TODO: remove function once DescribeInstanceTypes contains the accelerator data
+	// TODO: remove once all regions return NeuronInfo from DescribeInstanceTypes
 	// Values found from: https://aws.amazon.com/ec2/instance-types/trn1/
-	if strings.HasPrefix(*info.InstanceType, "trn1") {
+	case info.NeuronInfo == nil && strings.HasPrefix(*info.InstanceType, "trn1"):
 		requirements.Get(v1beta1.LabelInstanceAcceleratorName).Insert(lowerKabobCase("Inferentia"))
 		requirements.Get(v1beta1.LabelInstanceAcceleratorManufacturer).Insert(lowerKabobCase("AWS"))
 		requirements.Get(v1beta1.LabelInstanceAcceleratorCount).Insert(fmt.Sprint(awsNeurons(info)))
@@ -221,10 +264,25 @@ func memory(ctx context.Context, info *ec2.InstanceTypeInfo) *resource.Quantity
 	}
 	mem := resources.Quantity(fmt.Sprintf("%dMi", sizeInMib))
 	// Account for VM overhead in calculation
-	mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*options.FromContext(ctx).VMMemoryOverheadPercent/1024/1024)))))
+	mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*vmMemoryOverheadPercent(ctx, info)/1024/1024)))))
 	return mem
 }
 
+// vmMemoryOverheadPercent returns the VM memory overhead percent for the instance type. A single percent tends to
+// underestimate the overhead of small instance types and overestimate it on large ones, so operators can override it
+// for an instance type or a whole instance family.
+func vmMemoryOverheadPercent(ctx context.Context, info *ec2.InstanceTypeInfo) float64 {
+	overrides := options.FromContext(ctx).VMMemoryOverheadPercentOverrides
+	instanceType := aws.StringValue(info.InstanceType)
+	if percent, ok := overrides[instanceType]; ok {
+		return percent
+	}
+	if percent, ok := overrides[strings.Split(instanceType, ".")[0]]; ok {
+		return percent
+	}
+	return options.FromContext(ctx).VMMemoryOverheadPercent
+}
+
 // Setting ephemeral-storage to be either the default value, what is defined in blockDeviceMappings, or the combined size of local store volumes.
 func ephemeralStorage(info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily, blockDeviceMappings []*v1beta1.BlockDeviceMapping, instanceStorePolicy *v1beta1.InstanceStorePolicy) *resource.Quantity {
 	// If local store disks have been configured for node ephemeral-storage, use the total size of the disks.
@@ -296,11 +354,15 @@ func amdGPUs(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(count))
 }
 
-// TODO: remove trn1 hardcode values once DescribeInstanceTypes contains the accelerator data
+// TODO: remove trn1 hardcode values once all regions return NeuronInfo from DescribeInstanceTypes
 // Values found from: https://aws.amazon.com/ec2/instance-types/trn1/
 func awsNeurons(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	count := int64(0)
-	if *info.InstanceType == "trn1.2xlarge" {
+	if info.NeuronInfo != nil {
+		for _, device := range info.NeuronInfo.NeuronDevices {
+			count += lo.FromPtr(device.Count)
+		}
+	} else if *info.InstanceType == "trn1.2xlarge" {
 		count = int64(1)
 	} else if *info.InstanceType == "trn1.32xlarge" {
 		count = int64(16)
@@ -326,10 +388,35 @@ func habanaGaudis(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(count))
 }
 
+// networkBandwidth returns the baseline network bandwidth of the instance type in megabits. Instance types that aren't
+// in the generated bandwidth table, such as ones released after it was generated, fall back to the sum of the baseline
+// bandwidth that DescribeInstanceTypes reports for each of their network cards.
+func networkBandwidth(info *ec2.InstanceTypeInfo) (int64, bool) {
+	if bandwidth, ok := InstanceTypeBandwidthMegabits[aws.StringValue(info.InstanceType)]; ok {
+		return bandwidth, true
+	}
+	if info.NetworkInfo == nil {
+		return 0, false
+	}
+	gbps := lo.SumBy(info.NetworkInfo.NetworkCards, func(card *ec2.NetworkCardInfo) float64 {
+		return aws.Float64Value(card.BaselineBandwidthInGbps)
+	})
+	if gbps <= 0 {
+		return 0, false
+	}
+	return int64(math.Round(gbps * 1000)), true
+}
+
+// efas returns the number of EFA interfaces that Karpenter can attach to the instance type. Launch templates place a
+// single EFA interface on each network card (see launchtemplate.generateNetworkInterfaces), so the count is bounded by
+// the number of network cards even if EC2 reports that more EFA interfaces are supported.
 func efas(info *ec2.InstanceTypeInfo) *resource.Quantity {
 	count := int64(0)
 	if info.NetworkInfo != nil && info.NetworkInfo.EfaInfo != nil {
 		count = lo.FromPtr(info.NetworkInfo.EfaInfo.MaximumEfaInterfaces)
+		if networkCards := int64(len(info.NetworkInfo.NetworkCards)); networkCards > 0 {
+			count = lo.Min([]int64{count, networkCards})
+		}
 	}
 	return resources.Quantity(fmt.Sprint(count))
 }
@@ -341,6 +428,8 @@ func ENILimitedPods(ctx context.Context, info *ec2.InstanceTypeInfo) *resource.Q
 
 	// VPC CNI only uses the default network interface
 	// https://github.com/aws/amazon-vpc-cni-k8s/blob/3294231c0dce52cfe473bf6c62f47956a3b333b6/scripts/gen_vpc_ip_limits.go#L162
+	// EFA interfaces don't reduce this count: the primary EFA interface is still usable by the VPC CNI and every other EFA
+	// interface is attached to a separate network card.
 	networkInterfaces := *info.NetworkInfo.NetworkCards[*info.NetworkInfo.DefaultNetworkCardIndex].MaximumNetworkInterfaces
 	usableNetworkInterfaces := lo.Max([]int64{networkInterfaces - int64(options.FromContext(ctx).ReservedENIs), 0})
 	if usableNetworkInterfaces == 0 {
@@ -443,6 +532,22 @@ func pods(ctx context.Context, info *ec2.InstanceTypeInfo, amiFamily amifamily.A
 	return resources.Quantity(fmt.Sprint(count))
 }
 
+// networkLimitedMaxPods returns the max pods of the instance type when the settings of the VPC CNI are known, so that
+// pods aren't packed onto a node beyond the IPs of its ENIs, where they'd be stuck ContainerCreating: the ENI limited
+// pods less the WARM_IP_TARGET free IPs that the VPC CNI keeps on every node, unless the configured max pods are lower.
+// The configured max pods are returned as-is when pod density isn't ENI limited or when the VPC CNI assigns prefixes,
+// since neither is bounded by the IPs of the node's ENIs.
+func networkLimitedMaxPods(ctx context.Context, info *ec2.InstanceTypeInfo, amiFamily amifamily.AMIFamily, maxPods *int32, warmTargets awscache.VPCCNIWarmTargets) *int32 {
+	if !amiFamily.FeatureFlags().SupportsENILimitedPodDensity || warmTargets.PrefixDelegation {
+		return maxPods
+	}
+	ceiling := int32(lo.Max([]int64{ENILimitedPods(ctx, info).Value() - lo.Max([]int64{warmTargets.WarmIPTarget, 0}), 0}))
+	if maxPods != nil && *maxPods < ceiling {
+		return maxPods
+	}
+	return &ceiling
+}
+
 func lowerKabobCase(s string) string {
 	return strings.ToLower(strings.ReplaceAll(s, " ", "-"))
 }
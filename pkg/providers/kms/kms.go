@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/patrickmn/go-cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	ReasonKeyNotFound        = "KMSKeyNotFound"
+	ReasonKeyNotEnabled      = "KMSKeyNotEnabled"
+	ReasonKeyNotSymmetric    = "KMSKeyNotSymmetric"
+	ReasonGrantNotPermitted  = "KMSGrantNotPermitted"
+	dryRunOperationErrorCode = "DryRunOperationException"
+	accessDeniedErrorCode    = "AccessDeniedException"
+)
+
+// UnusableKeyError is returned for a key that volumes can't be encrypted with, so launches that use it would fail
+type UnusableKeyError struct {
+	KeyID   string
+	Reason  string
+	Message string
+}
+
+func (e *UnusableKeyError) Error() string {
+	return fmt.Sprintf("kms key %s %s", e.KeyID, e.Message)
+}
+
+func IsUnusableKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ukErr *UnusableKeyError
+	return errors.As(err, &ukErr)
+}
+
+type Provider interface {
+	Validate(context.Context, string) error
+}
+
+type DefaultProvider struct {
+	kmsapi    kmsiface.KMSAPI
+	region    string
+	accountID string
+	cache     *cache.Cache
+}
+
+func NewDefaultProvider(kmsapi kmsiface.KMSAPI, region string, accountID string, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		kmsapi:    kmsapi,
+		region:    region,
+		accountID: accountID,
+		cache:     cache,
+	}
+}
+
+// Validate returns an UnusableKeyError if the key doesn't exist, isn't enabled, isn't a symmetric encryption key, or
+// can't be granted to EC2 by Karpenter, which EC2 does on Karpenter's behalf to attach volumes that are encrypted with
+// it. Other errors mean that the key couldn't be validated either way. Keys that Karpenter isn't allowed to describe
+// are still checked for grants, since describing a key isn't needed to launch with it.
+func (p *DefaultProvider) Validate(ctx context.Context, keyID string) error {
+	if cached, ok := p.cache.Get(keyID); ok {
+		if cached == nil {
+			return nil
+		}
+		return cached.(error)
+	}
+	err := p.validate(ctx, keyID)
+	// Errors that aren't about the key, like throttles, aren't cached so that the key is validated again
+	if err == nil || IsUnusableKeyError(err) {
+		p.cache.SetDefault(keyID, err)
+	}
+	return err
+}
+
+func (p *DefaultProvider) validate(ctx context.Context, keyID string) error {
+	out, err := p.kmsapi.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		if unusable := p.unusable(keyID, err); unusable != nil {
+			return unusable
+		}
+		if errorCode(err) != accessDeniedErrorCode {
+			return fmt.Errorf("describing kms key %s, %w", keyID, err)
+		}
+		log.FromContext(ctx).WithValues("kms-key", keyID).V(1).Info("not allowed to describe kms key, only checking grants")
+	} else {
+		metadata := out.KeyMetadata
+		if state := aws.StringValue(metadata.KeyState); state != kms.KeyStateEnabled {
+			return &UnusableKeyError{KeyID: keyID, Reason: ReasonKeyNotEnabled, Message: fmt.Sprintf("is in the %s state", state)}
+		}
+		if aws.StringValue(metadata.KeySpec) != kms.KeySpecSymmetricDefault || aws.StringValue(metadata.KeyUsage) != kms.KeyUsageTypeEncryptDecrypt {
+			return &UnusableKeyError{KeyID: keyID, Reason: ReasonKeyNotSymmetric, Message: fmt.Sprintf("is a %s key for %s, volumes can only be encrypted with symmetric encryption keys",
+				aws.StringValue(metadata.KeySpec), aws.StringValue(metadata.KeyUsage))}
+		}
+	}
+	// The account can't be resolved in every environment, and a grant needs a grantee
+	if p.accountID == "" {
+		return nil
+	}
+	if _, err = p.kmsapi.CreateGrantWithContext(ctx, &kms.CreateGrantInput{
+		DryRun:           aws.Bool(true),
+		KeyId:            aws.String(keyID),
+		GranteePrincipal: aws.String(fmt.Sprintf("arn:%s:iam::%s:root", utils.Partition(p.region), p.accountID)),
+		Operations:       aws.StringSlice([]string{kms.GrantOperationDecrypt, kms.GrantOperationGenerateDataKeyWithoutPlaintext}),
+	}); err != nil {
+		if errorCode(err) == dryRunOperationErrorCode {
+			return nil
+		}
+		if unusable := p.unusable(keyID, err); unusable != nil {
+			return unusable
+		}
+		if errorCode(err) == accessDeniedErrorCode {
+			return &UnusableKeyError{KeyID: keyID, Reason: ReasonGrantNotPermitted, Message: "can't be granted to EC2, allow kms:CreateGrant in the key policy and the controller policy"}
+		}
+		return fmt.Errorf("creating grant for kms key %s, %w", keyID, err)
+	}
+	return nil
+}
+
+// unusable returns an UnusableKeyError for the errors that KMS returns for keys that don't exist or aren't enabled
+func (p *DefaultProvider) unusable(keyID string, err error) error {
+	switch errorCode(err) {
+	case kms.ErrCodeNotFoundException:
+		return &UnusableKeyError{KeyID: keyID, Reason: ReasonKeyNotFound, Message: "doesn't exist"}
+	case kms.ErrCodeDisabledException, kms.ErrCodeInvalidStateException:
+		return &UnusableKeyError{KeyID: keyID, Reason: ReasonKeyNotEnabled, Message: "isn't enabled"}
+	}
+	return nil
+}
+
+func errorCode(err error) string {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func (p *DefaultProvider) Reset() {
+	p.cache.Flush()
+}
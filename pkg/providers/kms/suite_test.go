@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awskms "github.com/aws/aws-sdk-go/service/kms"
+	"github.com/patrickmn/go-cache"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var kmsapi *fake.KMSAPI
+var kmsProvider *kms.DefaultProvider
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KMSProvider")
+}
+
+var _ = BeforeSuite(func() {
+	kmsapi = fake.NewKMSAPI()
+	kmsProvider = kms.NewDefaultProvider(kmsapi, fake.DefaultRegion, fake.DefaultAccount, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+})
+
+var _ = BeforeEach(func() {
+	kmsapi.Reset()
+	kmsProvider.Reset()
+})
+
+func expectUnusable(err error, reason string) {
+	GinkgoHelper()
+	unusable := &kms.UnusableKeyError{}
+	Expect(err).To(BeAssignableToTypeOf(unusable))
+	Expect(err.(*kms.UnusableKeyError).Reason).To(Equal(reason))
+}
+
+var _ = Describe("KMSProvider", func() {
+	It("should allow an enabled symmetric key that can be granted", func() {
+		Expect(kmsProvider.Validate(ctx, "test-key")).To(Succeed())
+		Expect(kmsapi.CreateGrantBehavior.Calls()).To(Equal(1))
+		grant := kmsapi.CreateGrantBehavior.CalledWithInput.Pop()
+		Expect(aws.BoolValue(grant.DryRun)).To(BeTrue())
+		Expect(aws.StringValue(grant.GranteePrincipal)).To(Equal("arn:aws:iam::" + fake.DefaultAccount + ":root"))
+	})
+	It("should reject a key that doesn't exist", func() {
+		kmsapi.DescribeKeyBehavior.Error.Set(awserr.New(awskms.ErrCodeNotFoundException, "not found", nil))
+		expectUnusable(kmsProvider.Validate(ctx, "test-key"), kms.ReasonKeyNotFound)
+	})
+	It("should reject a key that is pending deletion", func() {
+		kmsapi.DescribeKeyBehavior.Output.Set(&awskms.DescribeKeyOutput{KeyMetadata: &awskms.KeyMetadata{
+			KeyState: aws.String(awskms.KeyStatePendingDeletion),
+			KeySpec:  aws.String(awskms.KeySpecSymmetricDefault),
+			KeyUsage: aws.String(awskms.KeyUsageTypeEncryptDecrypt),
+		}})
+		expectUnusable(kmsProvider.Validate(ctx, "test-key"), kms.ReasonKeyNotEnabled)
+	})
+	It("should reject an asymmetric key", func() {
+		kmsapi.DescribeKeyBehavior.Output.Set(&awskms.DescribeKeyOutput{KeyMetadata: &awskms.KeyMetadata{
+			KeyState: aws.String(awskms.KeyStateEnabled),
+			KeySpec:  aws.String(awskms.KeySpecRsa2048),
+			KeyUsage: aws.String(awskms.KeyUsageTypeSignVerify),
+		}})
+		expectUnusable(kmsProvider.Validate(ctx, "test-key"), kms.ReasonKeyNotSymmetric)
+	})
+	It("should reject a key that can't be granted", func() {
+		kmsapi.CreateGrantBehavior.Error.Set(awserr.New("AccessDeniedException", "not authorized", nil))
+		expectUnusable(kmsProvider.Validate(ctx, "test-key"), kms.ReasonGrantNotPermitted)
+	})
+	It("should still check grants for a key that can't be described", func() {
+		kmsapi.DescribeKeyBehavior.Error.Set(awserr.New("AccessDeniedException", "not authorized", nil))
+		Expect(kmsProvider.Validate(ctx, "test-key")).To(Succeed())
+		Expect(kmsapi.CreateGrantBehavior.Calls()).To(Equal(1))
+	})
+	It("should return other errors without caching them", func() {
+		kmsapi.DescribeKeyBehavior.Error.Set(awserr.New("ThrottlingException", "rate exceeded", nil))
+		err := kmsProvider.Validate(ctx, "test-key")
+		Expect(err).To(HaveOccurred())
+		Expect(kms.IsUnusableKeyError(err)).To(BeFalse())
+		Expect(kmsProvider.Validate(ctx, "test-key")).To(Succeed())
+	})
+	It("should cache the result of validating a key", func() {
+		kmsapi.DescribeKeyBehavior.Error.Set(awserr.New(awskms.ErrCodeNotFoundException, "not found", nil))
+		expectUnusable(kmsProvider.Validate(ctx, "test-key"), kms.ReasonKeyNotFound)
+		expectUnusable(kmsProvider.Validate(ctx, "test-key"), kms.ReasonKeyNotFound)
+		Expect(kmsapi.DescribeKeyBehavior.Calls()).To(Equal(1))
+	})
+})
@@ -0,0 +1,106 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// managedLaunchTemplateDataFields are the fields of the launch template data that Karpenter sets, either in the
+// launch template or in the overrides of CreateFleet, which launch template data patches can't change
+var managedLaunchTemplateDataFields = sets.New(
+	"BlockDeviceMappings",
+	"DisableApiTermination",
+	"IamInstanceProfile",
+	"ImageId",
+	"InstanceMarketOptions",
+	"InstanceType",
+	"MetadataOptions",
+	"Monitoring",
+	"NetworkInterfaces",
+	"SecurityGroupIds",
+	"SecurityGroups",
+	"TagSpecifications",
+	"UserData",
+)
+
+// LaunchTemplateDataPatch returns the launch template data patch of the EC2NodeClass, which is empty when launch
+// template data patches aren't enabled
+func LaunchTemplateDataPatch(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) string {
+	if !options.FromContext(ctx).LaunchTemplateDataPatches {
+		return ""
+	}
+	return nodeClass.Annotations[v1beta1.AnnotationLaunchTemplateDataPatch]
+}
+
+// ValidateLaunchTemplateDataPatch returns an error when the patch isn't a JSON patch of fields of the launch template
+// data, or when it patches a field that Karpenter manages
+func ValidateLaunchTemplateDataPatch(patch string) error {
+	_, err := applyLaunchTemplateDataPatch(&ec2types.RequestLaunchTemplateData{}, patch)
+	return err
+}
+
+// applyLaunchTemplateDataPatch applies the JSON patch to the launch template data. Paths are the names of the fields
+// of the launch template data as they appear in the EC2 API, e.g. /CpuOptions or /MaintenanceOptions/AutoRecovery.
+func applyLaunchTemplateDataPatch(data *ec2types.RequestLaunchTemplateData, patch string) (*ec2types.RequestLaunchTemplateData, error) {
+	operations, err := jsonpatch.DecodePatch([]byte(patch))
+	if err != nil {
+		return nil, fmt.Errorf("decoding launch template data patch, %w", err)
+	}
+	for _, operation := range operations {
+		paths := []string{}
+		if path, err := operation.Path(); err == nil {
+			paths = append(paths, path)
+		}
+		if from, err := operation.From(); err == nil {
+			paths = append(paths, from)
+		}
+		for _, path := range paths {
+			field := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+			if field == "" {
+				return nil, fmt.Errorf("patching the launch template data as a whole isn't supported")
+			}
+			if managedLaunchTemplateDataFields.Has(field) {
+				return nil, fmt.Errorf("patching %s conflicts with the launch template data that Karpenter manages", field)
+			}
+		}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling launch template data, %w", err)
+	}
+	if raw, err = operations.Apply(raw); err != nil {
+		return nil, fmt.Errorf("applying launch template data patch, %w", err)
+	}
+	patched := &ec2types.RequestLaunchTemplateData{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	// Fields that aren't part of the launch template data would otherwise be dropped without an error
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(patched); err != nil {
+		return nil, fmt.Errorf("decoding patched launch template data, %w", err)
+	}
+	return patched, nil
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -28,20 +29,23 @@ import (
 	"go.uber.org/multierr"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
@@ -50,31 +54,53 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
+// launchTemplateConsistencyWindow is how long after their creation launch templates stay cached even though
+// DescribeLaunchTemplates doesn't list them yet
+const launchTemplateConsistencyWindow = time.Minute
+
+// launchTemplateIDTagKey is the tag that EC2 adds to instances that are launched from a launch template
+const launchTemplateIDTagKey = "aws:ec2launchtemplate:id"
+
 type Provider interface {
 	EnsureAll(context.Context, *v1beta1.EC2NodeClass, *corev1beta1.NodeClaim,
 		[]*cloudprovider.InstanceType, string, map[string]string) ([]*LaunchTemplate, error)
 	DeleteAll(context.Context, *v1beta1.EC2NodeClass) error
 	InvalidateCache(context.Context, string, string)
+	SyncCache(context.Context) error
+	DeleteUnreferenced(context.Context, time.Duration) error
 	ResolveClusterCIDR(context.Context) error
 }
 
+// EC2API is the part of the aws-sdk-go-v2 EC2 client that the provider manages launch templates with
+type EC2API interface {
+	CreateLaunchTemplate(context.Context, *ec2.CreateLaunchTemplateInput, ...func(*ec2.Options)) (*ec2.CreateLaunchTemplateOutput, error)
+	DescribeLaunchTemplates(context.Context, *ec2.DescribeLaunchTemplatesInput, ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error)
+	DeleteLaunchTemplate(context.Context, *ec2.DeleteLaunchTemplateInput, ...func(*ec2.Options)) (*ec2.DeleteLaunchTemplateOutput, error)
+	DescribeInstances(context.Context, *ec2.DescribeInstancesInput, ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
 type LaunchTemplate struct {
 	Name          string
 	InstanceTypes []*cloudprovider.InstanceType
 	ImageID       string
+	// Zone is set for launch templates that can only launch instances in a single zone, because their additional network
+	// interfaces are created in the subnets of the zone
+	Zone string
 }
 
 type DefaultProvider struct {
 	sync.Mutex
-	ec2api                ec2iface.EC2API
+	ec2api                EC2API
 	eksapi                eksiface.EKSAPI
-	amiFamily             *amifamily.Resolver
+	amiFamily             amifamily.Resolver
 	securityGroupProvider securitygroup.Provider
 	subnetProvider        subnet.Provider
 	cache                 *cache.Cache
+	creationLimits        *awscache.CreationLimits
 	cm                    *pretty.ChangeMonitor
 	KubeDNSIP             net.IP
 	CABundle              *string
@@ -82,9 +108,9 @@ type DefaultProvider struct {
 	ClusterCIDR           atomic.Pointer[string]
 }
 
-func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api ec2iface.EC2API, eksapi eksiface.EKSAPI, amiFamily *amifamily.Resolver,
+func NewDefaultProvider(ctx context.Context, cache *cache.Cache, creationLimits *awscache.CreationLimits, ec2api EC2API, eksapi eksiface.EKSAPI, amiFamily amifamily.Resolver,
 	securityGroupProvider securitygroup.Provider, subnetProvider subnet.Provider,
-	caBundle *string, startAsync <-chan struct{}, kubeDNSIP net.IP, clusterEndpoint string) *DefaultProvider {
+	caBundle *string, kubeDNSIP net.IP, clusterEndpoint string) *DefaultProvider {
 	l := &DefaultProvider{
 		ec2api:                ec2api,
 		eksapi:                eksapi,
@@ -92,21 +118,13 @@ func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api ec2iface
 		securityGroupProvider: securityGroupProvider,
 		subnetProvider:        subnetProvider,
 		cache:                 cache,
+		creationLimits:        creationLimits,
 		CABundle:              caBundle,
 		cm:                    pretty.NewChangeMonitor(),
 		KubeDNSIP:             kubeDNSIP,
 		ClusterEndpoint:       clusterEndpoint,
 	}
 	l.cache.OnEvicted(l.cachedEvictedFunc(ctx))
-	go func() {
-		// only hydrate cache once elected leader
-		select {
-		case <-startAsync:
-		case <-ctx.Done():
-			return
-		}
-		l.hydrateCache(ctx)
-	}()
 	return l
 }
 
@@ -120,35 +138,214 @@ func (p *DefaultProvider) EnsureAll(ctx context.Context, nodeClass *v1beta1.EC2N
 	if err != nil {
 		return nil, err
 	}
+	// Wavelength Zones don't support public IP addresses, so NodeClaims that are launched in them are given a carrier IP
+	// address from the carrier gateway of their zone instead
+	if launchesInWavelengthZones(nodeClaim) {
+		options.AssociateCarrierIPAddress, options.AssociatePublicIPAddress = options.AssociatePublicIPAddress, nil
+	}
 	resolvedLaunchTemplates, err := p.amiFamily.Resolve(nodeClass, nodeClaim, instanceTypes, capacityType, options)
 	if err != nil {
 		return nil, err
 	}
+	// Without additional network interfaces, launch templates aren't specific to a zone, which is represented by an empty one
+	zonalNetworkInterfaces := map[string][]amifamily.NetworkInterface{"": nil}
+	if len(nodeClass.Spec.AdditionalNetworkInterfaces) != 0 {
+		if zonalNetworkInterfaces, err = additionalNetworkInterfaces(nodeClass, nodeClaim); err != nil {
+			return nil, err
+		}
+	}
+	zones := lo.Keys(zonalNetworkInterfaces)
+	sort.Strings(zones)
 	var launchTemplates []*LaunchTemplate
 	for _, resolvedLaunchTemplate := range resolvedLaunchTemplates {
-		// Ensure the launch template exists, or create it
-		ec2LaunchTemplate, err := p.ensureLaunchTemplate(ctx, resolvedLaunchTemplate)
-		if err != nil {
-			return nil, err
+		resolvedLaunchTemplate.MetadataOptions = MetadataOptions(ctx, nodeClass, resolvedLaunchTemplate.MetadataOptions)
+		for _, zone := range zones {
+			zonalLaunchTemplate := *resolvedLaunchTemplate
+			zonalLaunchTemplate.AdditionalNetworkInterfaces = zonalNetworkInterfaces[zone]
+			// Ensure the launch template exists, or create it
+			ec2LaunchTemplate, err := p.ensureLaunchTemplate(ctx, &zonalLaunchTemplate)
+			if err != nil {
+				return nil, err
+			}
+			launchTemplates = append(launchTemplates, &LaunchTemplate{Name: *ec2LaunchTemplate.LaunchTemplateName, InstanceTypes: resolvedLaunchTemplate.InstanceTypes, ImageID: resolvedLaunchTemplate.AMIID, Zone: zone})
 		}
-		launchTemplates = append(launchTemplates, &LaunchTemplate{Name: *ec2LaunchTemplate.LaunchTemplateName, InstanceTypes: resolvedLaunchTemplate.InstanceTypes, ImageID: resolvedLaunchTemplate.AMIID})
 	}
 	return launchTemplates, nil
 }
 
+// additionalNetworkInterfaces returns the additional network interfaces of the EC2NodeClass in each of the zones that the
+// NodeClaim can launch in and in which every interface has a subnet. The interfaces are created in the subnet of their
+// zone that has the most available IPs.
+func additionalNetworkInterfaces(nodeClass *v1beta1.EC2NodeClass, nodeClaim *corev1beta1.NodeClaim) (map[string][]amifamily.NetworkInterface, error) {
+	if len(nodeClass.Status.AdditionalNetworkInterfaces) != len(nodeClass.Spec.AdditionalNetworkInterfaces) {
+		return nil, cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("additional network interfaces haven't resolved"))
+	}
+	zones := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1.LabelTopologyZone)
+	zonalNetworkInterfaces := map[string][]amifamily.NetworkInterface{}
+	for i, networkInterface := range nodeClass.Spec.AdditionalNetworkInterfaces {
+		status := nodeClass.Status.AdditionalNetworkInterfaces[i]
+		securityGroups := lo.Ternary(len(networkInterface.SecurityGroupSelectorTerms) != 0, status.SecurityGroups, nodeClass.Status.SecurityGroups)
+		if len(securityGroups) == 0 {
+			return nil, cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("no security groups are present in the status of network interface %d", networkInterface.DeviceIndex))
+		}
+		// The subnets in the status are ordered by their available IPs, so the first subnet of each zone is used
+		for _, subnet := range status.Subnets {
+			if !zones.Has(subnet.Zone) || lo.ContainsBy(zonalNetworkInterfaces[subnet.Zone], func(ni amifamily.NetworkInterface) bool {
+				return ni.DeviceIndex == networkInterface.DeviceIndex
+			}) {
+				continue
+			}
+			zonalNetworkInterfaces[subnet.Zone] = append(zonalNetworkInterfaces[subnet.Zone], amifamily.NetworkInterface{
+				DeviceIndex:         networkInterface.DeviceIndex,
+				SubnetID:            subnet.ID,
+				SecurityGroupIDs:    lo.Map(securityGroups, func(s v1beta1.SecurityGroup, _ int) string { return s.ID }),
+				DeleteOnTermination: lo.ToPtr(lo.FromPtrOr(networkInterface.DeleteOnTermination, true)),
+			})
+		}
+	}
+	zonalNetworkInterfaces = lo.PickBy(zonalNetworkInterfaces, func(_ string, networkInterfaces []amifamily.NetworkInterface) bool {
+		return len(networkInterfaces) == len(nodeClass.Spec.AdditionalNetworkInterfaces)
+	})
+	if len(zonalNetworkInterfaces) == 0 {
+		return nil, fmt.Errorf("no zone has subnets for every additional network interface")
+	}
+	return zonalNetworkInterfaces, nil
+}
+
 // InvalidateCache deletes a launch template from cache if it exists
 func (p *DefaultProvider) InvalidateCache(ctx context.Context, ltName string, ltID string) {
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("launch-template-name", ltName, "launch-template-id", ltID))
 	p.Lock()
 	defer p.Unlock()
+	p.invalidate(ctx, ltName)
+}
+
+// SyncCache reconciles the cache with the launch templates that Karpenter created for the cluster. Launch templates that
+// were deleted out of band are invalidated, so that they're recreated by the next launch rather than failing it, and
+// launch templates that aren't cached yet, e.g. after a restart, are added to the cache.
+func (p *DefaultProvider) SyncCache(ctx context.Context) error {
+	clusterName := options.FromContext(ctx).ClusterName
+	launchTemplates := map[string]*ec2types.LaunchTemplate{}
+	paginator := ec2.NewDescribeLaunchTemplatesPaginator(p.ec2api, &ec2.DescribeLaunchTemplatesInput{
+		Filters: []ec2types.Filter{{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.TagManagedLaunchTemplate)), Values: []string{clusterName}}},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing launch templates, %w", err)
+		}
+		for i := range output.LaunchTemplates {
+			launchTemplates[aws.ToString(output.LaunchTemplates[i].LaunchTemplateName)] = &output.LaunchTemplates[i]
+		}
+	}
+	p.Lock()
+	defer p.Unlock()
+	for name, item := range p.cache.Items() {
+		if _, ok := launchTemplates[name]; ok {
+			continue
+		}
+		// DescribeLaunchTemplates is eventually consistent, so launch templates that were just created may not be listed yet
+		if createTime := item.Object.(*ec2types.LaunchTemplate).CreateTime; createTime != nil && time.Since(*createTime) < launchTemplateConsistencyWindow {
+			continue
+		}
+		p.invalidate(log.IntoContext(ctx, log.FromContext(ctx).WithValues("launch-template-name", name)), name)
+	}
+	// Launch templates that are already cached keep their expiration, so that unused launch templates still expire
+	for name, lt := range launchTemplates {
+		_ = p.cache.Add(name, lt, cache.DefaultExpiration)
+	}
+	return nil
+}
+
+// invalidate deletes a launch template from the cache without deleting it from EC2. The caller must hold the lock.
+func (p *DefaultProvider) invalidate(ctx context.Context, name string) {
+	if _, ok := p.cache.Get(name); !ok {
+		return
+	}
 	defer p.cache.OnEvicted(p.cachedEvictedFunc(ctx))
 	p.cache.OnEvicted(nil)
 	log.FromContext(ctx).V(1).Info("invalidating launch template in the cache because it no longer exists")
-	p.cache.Delete(ltName)
+	p.cache.Delete(name)
+	LaunchTemplateCacheEventsTotal.With(prometheus.Labels{cacheEventLabel: CacheEventInvalidation}).Inc()
 }
 
-func LaunchTemplateName(options *amifamily.LaunchTemplate) string {
-	return fmt.Sprintf("%s/%d", apis.Group, lo.Must(hashstructure.Hash(options, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})))
+// DeleteUnreferenced deletes the launch templates of the cluster that are older than the given age, but aren't cached
+// and that no instance references. These are leaked when Karpenter isn't running to delete them as they expire from
+// the cache, e.g. when a cluster is deleted and recreated with the same name without uninstalling Karpenter.
+func (p *DefaultProvider) DeleteUnreferenced(ctx context.Context, age time.Duration) error {
+	clusterName := options.FromContext(ctx).ClusterName
+	var launchTemplates []ec2types.LaunchTemplate
+	launchTemplatesPaginator := ec2.NewDescribeLaunchTemplatesPaginator(p.ec2api, &ec2.DescribeLaunchTemplatesInput{
+		Filters: []ec2types.Filter{{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.TagManagedLaunchTemplate)), Values: []string{clusterName}}},
+	})
+	for launchTemplatesPaginator.HasMorePages() {
+		output, err := launchTemplatesPaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing launch templates, %w", err)
+		}
+		launchTemplates = append(launchTemplates, lo.Filter(output.LaunchTemplates, func(lt ec2types.LaunchTemplate, _ int) bool {
+			return lt.CreateTime != nil && time.Since(*lt.CreateTime) > age
+		})...)
+	}
+	if len(launchTemplates) == 0 {
+		return nil
+	}
+	// Instances that are launched from a launch template are tagged with its ID by EC2
+	referenced := sets.New[string]()
+	instancesPaginator := ec2.NewDescribeInstancesPaginator(p.ec2api, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("tag-key"), Values: []string{launchTemplateIDTagKey}},
+			{Name: aws.String("instance-state-name"), Values: []string{
+				string(ec2types.InstanceStateNamePending),
+				string(ec2types.InstanceStateNameRunning),
+				string(ec2types.InstanceStateNameStopping),
+				string(ec2types.InstanceStateNameStopped),
+				string(ec2types.InstanceStateNameShuttingDown),
+			}},
+		},
+	})
+	for instancesPaginator.HasMorePages() {
+		output, err := instancesPaginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing instances, %w", err)
+		}
+		for _, reservation := range output.Reservations {
+			for _, instance := range reservation.Instances {
+				if tag, ok := lo.Find(instance.Tags, func(t ec2types.Tag) bool { return aws.ToString(t.Key) == launchTemplateIDTagKey }); ok {
+					referenced.Insert(aws.ToString(tag.Value))
+				}
+			}
+		}
+	}
+	p.Lock()
+	defer p.Unlock()
+	var deleted []string
+	var deleteErr error
+	for _, lt := range launchTemplates {
+		if _, ok := p.cache.Get(aws.ToString(lt.LaunchTemplateName)); ok || referenced.Has(aws.ToString(lt.LaunchTemplateId)) {
+			continue
+		}
+		if _, err := p.ec2api.DeleteLaunchTemplate(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateId: lt.LaunchTemplateId}); awserrors.IgnoreNotFound(err) != nil {
+			deleteErr = multierr.Append(deleteErr, err)
+			continue
+		}
+		deleted = append(deleted, aws.ToString(lt.LaunchTemplateName))
+	}
+	if len(deleted) > 0 {
+		log.FromContext(ctx).WithValues("launchTemplates", utils.PrettySlice(deleted, 5)).V(1).Info("deleted unreferenced launch templates")
+	}
+	if deleteErr != nil {
+		return fmt.Errorf("deleting launch templates, %w", deleteErr)
+	}
+	return nil
+}
+
+func LaunchTemplateName(options *amifamily.LaunchTemplate) (string, error) {
+	hash, err := hashstructure.Hash(options, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	if err != nil {
+		return "", fmt.Errorf("hashing launch template options, %w", err)
+	}
+	return fmt.Sprintf("%s/%d", apis.Group, hash), nil
 }
 
 func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, labels, tags map[string]string) (*amifamily.Options, error) {
@@ -180,32 +377,42 @@ func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1bet
 		ClusterCIDR:              p.ClusterCIDR.Load(),
 		InstanceProfile:          instanceProfile,
 		InstanceStorePolicy:      nodeClass.Spec.InstanceStorePolicy,
+		ContainerRegistries:      nodeClass.Spec.ContainerRegistries,
+		Bottlerocket:             nodeClass.Spec.Bottlerocket,
 		SecurityGroups:           nodeClass.Status.SecurityGroups,
 		Tags:                     tags,
 		Labels:                   labels,
 		CABundle:                 p.CABundle,
 		KubeDNSIP:                p.KubeDNSIP,
 		AssociatePublicIPAddress: nodeClass.Spec.AssociatePublicIPAddress,
+		PrimaryNetworkInterface:  nodeClass.Spec.PrimaryNetworkInterface,
 		NodeClassName:            nodeClass.Name,
+		LaunchTemplateDataPatch:  LaunchTemplateDataPatch(ctx, nodeClass),
+		Hibernation:              options.FromContext(ctx).Hibernation,
 	}, nil
 }
 
-func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (*ec2.LaunchTemplate, error) {
-	var launchTemplate *ec2.LaunchTemplate
-	name := LaunchTemplateName(options)
+func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (*ec2types.LaunchTemplate, error) {
+	var launchTemplate *ec2types.LaunchTemplate
+	name, err := LaunchTemplateName(options)
+	if err != nil {
+		return nil, err
+	}
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("launch-template-name", name))
 	// Read from cache
 	if launchTemplate, ok := p.cache.Get(name); ok {
+		LaunchTemplateCacheEventsTotal.With(prometheus.Labels{cacheEventLabel: CacheEventHit}).Inc()
 		p.cache.SetDefault(name, launchTemplate)
-		return launchTemplate.(*ec2.LaunchTemplate), nil
+		return launchTemplate.(*ec2types.LaunchTemplate), nil
 	}
+	LaunchTemplateCacheEventsTotal.With(prometheus.Labels{cacheEventLabel: CacheEventMiss}).Inc()
 	// Attempt to find an existing LT.
-	output, err := p.ec2api.DescribeLaunchTemplatesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
-		LaunchTemplateNames: []*string{aws.String(name)},
+	output, err := p.ec2api.DescribeLaunchTemplates(ctx, &ec2.DescribeLaunchTemplatesInput{
+		LaunchTemplateNames: []string{name},
 	})
 	// Create LT if one doesn't exist
 	if awserrors.IsNotFound(err) {
-		launchTemplate, err = p.createLaunchTemplate(ctx, options)
+		launchTemplate, err = p.createLaunchTemplate(ctx, name, options)
 		if err != nil {
 			return nil, fmt.Errorf("creating launch template, %w", err)
 		}
@@ -217,106 +424,153 @@ func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *ami
 		if p.cm.HasChanged("launchtemplate-"+name, name) {
 			log.FromContext(ctx).V(1).Info("discovered launch template")
 		}
-		launchTemplate = output.LaunchTemplates[0]
+		launchTemplate = &output.LaunchTemplates[0]
 	}
 	p.cache.SetDefault(name, launchTemplate)
 	return launchTemplate, nil
 }
 
-func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (*ec2.LaunchTemplate, error) {
+func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, name string, options *amifamily.LaunchTemplate) (*ec2types.LaunchTemplate, error) {
 	userData, err := options.UserData.Script()
 	if err != nil {
 		return nil, err
 	}
-	launchTemplateDataTags := []*ec2.LaunchTemplateTagSpecificationRequest{
-		{ResourceType: aws.String(ec2.ResourceTypeNetworkInterface), Tags: utils.MergeTags(options.Tags)},
+	launchTemplateDataTags := []ec2types.LaunchTemplateTagSpecificationRequest{
+		{ResourceType: ec2types.ResourceTypeNetworkInterface, Tags: utils.MergeTagsV2(options.Tags)},
 	}
 	// Add the spot-instances-request tag if trying to launch spot capacity
 	if options.CapacityType == corev1beta1.CapacityTypeSpot {
-		launchTemplateDataTags = append(launchTemplateDataTags, &ec2.LaunchTemplateTagSpecificationRequest{ResourceType: aws.String(ec2.ResourceTypeSpotInstancesRequest), Tags: utils.MergeTags(options.Tags)})
+		launchTemplateDataTags = append(launchTemplateDataTags, ec2types.LaunchTemplateTagSpecificationRequest{ResourceType: ec2types.ResourceTypeSpotInstancesRequest, Tags: utils.MergeTagsV2(options.Tags)})
 	}
 	networkInterfaces := p.generateNetworkInterfaces(options)
-	output, err := p.ec2api.CreateLaunchTemplateWithContext(ctx, &ec2.CreateLaunchTemplateInput{
-		LaunchTemplateName: aws.String(LaunchTemplateName(options)),
-		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
-			BlockDeviceMappings: p.blockDeviceMappings(options.BlockDeviceMappings),
-			IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
-				Name: aws.String(options.InstanceProfile),
-			},
-			Monitoring: &ec2.LaunchTemplatesMonitoringRequest{
-				Enabled: aws.Bool(options.DetailedMonitoring),
-			},
-			// If the network interface is defined, the security groups are defined within it
-			SecurityGroupIds: lo.Ternary(networkInterfaces != nil, nil, lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) })),
-			UserData:         aws.String(userData),
-			ImageId:          aws.String(options.AMIID),
-			MetadataOptions: &ec2.LaunchTemplateInstanceMetadataOptionsRequest{
-				HttpEndpoint:            options.MetadataOptions.HTTPEndpoint,
-				HttpProtocolIpv6:        options.MetadataOptions.HTTPProtocolIPv6,
-				HttpPutResponseHopLimit: options.MetadataOptions.HTTPPutResponseHopLimit,
-				HttpTokens:              options.MetadataOptions.HTTPTokens,
-			},
-			NetworkInterfaces: networkInterfaces,
-			TagSpecifications: launchTemplateDataTags,
+	launchTemplateData := &ec2types.RequestLaunchTemplateData{
+		BlockDeviceMappings: p.blockDeviceMappings(options.BlockDeviceMappings),
+		IamInstanceProfile: &ec2types.LaunchTemplateIamInstanceProfileSpecificationRequest{
+			Name: aws.String(options.InstanceProfile),
+		},
+		Monitoring: &ec2types.LaunchTemplatesMonitoringRequest{
+			Enabled: aws.Bool(options.DetailedMonitoring),
 		},
-		TagSpecifications: []*ec2.TagSpecification{
+		DisableApiTermination: lo.Ternary(options.DisableAPITermination, aws.Bool(true), nil),
+		// If the network interface is defined, the security groups are defined within it
+		SecurityGroupIds: lo.Ternary(networkInterfaces != nil, nil, lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) string { return s.ID })),
+		UserData:         aws.String(userData),
+		ImageId:          aws.String(options.AMIID),
+		MetadataOptions: &ec2types.LaunchTemplateInstanceMetadataOptionsRequest{
+			HttpEndpoint:            ec2types.LaunchTemplateInstanceMetadataEndpointState(lo.FromPtr(options.MetadataOptions.HTTPEndpoint)),
+			HttpProtocolIpv6:        ec2types.LaunchTemplateInstanceMetadataProtocolIpv6(lo.FromPtr(options.MetadataOptions.HTTPProtocolIPv6)),
+			HttpPutResponseHopLimit: int32Ptr(options.MetadataOptions.HTTPPutResponseHopLimit),
+			HttpTokens:              ec2types.LaunchTemplateHttpTokensState(lo.FromPtr(options.MetadataOptions.HTTPTokens)),
+		},
+		NetworkInterfaces: networkInterfaces,
+		TagSpecifications: launchTemplateDataTags,
+	}
+	if options.HibernationConfigured {
+		launchTemplateData.HibernationOptions = &ec2types.LaunchTemplateHibernationOptionsRequest{Configured: aws.Bool(true)}
+	}
+	if options.LaunchTemplateDataPatch != "" {
+		if launchTemplateData, err = applyLaunchTemplateDataPatch(launchTemplateData, options.LaunchTemplateDataPatch); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.creationLimits.Reserve(ctx, awscache.CreatedResourceLaunchTemplate); err != nil {
+		return nil, err
+	}
+	output, err := p.ec2api.CreateLaunchTemplate(ctx, &ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: aws.String(name),
+		LaunchTemplateData: launchTemplateData,
+		TagSpecifications: []ec2types.TagSpecification{
 			{
-				ResourceType: aws.String(ec2.ResourceTypeLaunchTemplate),
-				Tags:         utils.MergeTags(options.Tags, map[string]string{v1beta1.TagManagedLaunchTemplate: options.ClusterName, v1beta1.LabelNodeClass: options.NodeClassName}),
+				ResourceType: ec2types.ResourceTypeLaunchTemplate,
+				Tags:         utils.MergeTagsV2(options.Tags, map[string]string{v1beta1.TagManagedLaunchTemplate: options.ClusterName, v1beta1.LabelNodeClass: options.NodeClassName}),
 			},
 		},
 	})
 	if err != nil {
+		p.creationLimits.Release(awscache.CreatedResourceLaunchTemplate)
 		return nil, err
 	}
-	log.FromContext(ctx).WithValues("id", aws.StringValue(output.LaunchTemplate.LaunchTemplateId)).V(1).Info("created launch template")
+	log.FromContext(ctx).WithValues("id", aws.ToString(output.LaunchTemplate.LaunchTemplateId)).V(1).Info("created launch template")
 	return output.LaunchTemplate, nil
 }
 
 // generateNetworkInterfaces generates network interfaces for the launch template.
-func (p *DefaultProvider) generateNetworkInterfaces(options *amifamily.LaunchTemplate) []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+func (p *DefaultProvider) generateNetworkInterfaces(options *amifamily.LaunchTemplate) []ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+	additionalNetworkInterfaces := lo.Map(options.AdditionalNetworkInterfaces, func(ni amifamily.NetworkInterface, _ int) ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+		return ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			DeviceIndex:         aws.Int32(int32(ni.DeviceIndex)),
+			SubnetId:            aws.String(ni.SubnetID),
+			Groups:              ni.SecurityGroupIDs,
+			DeleteOnTermination: ni.DeleteOnTermination,
+		}
+	})
 	if options.EFACount != 0 {
-		return lo.Times(options.EFACount, func(i int) *ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
-			return &ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
-				NetworkCardIndex: lo.ToPtr(int64(i)),
+		return append(lo.Times(options.EFACount, func(i int) ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+			networkInterface := ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+				NetworkCardIndex: lo.ToPtr(int32(i)),
 				// Some networking magic to ensure that one network card has higher priority than all the others (important if an instance needs a public IP w/o adding an EIP to every network card)
-				DeviceIndex:   lo.ToPtr(lo.Ternary[int64](i == 0, 0, 1)),
-				InterfaceType: lo.ToPtr(ec2.NetworkInterfaceTypeEfa),
-				Groups:        lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) }),
+				DeviceIndex:   lo.ToPtr(lo.Ternary[int32](i == 0, 0, 1)),
+				InterfaceType: lo.ToPtr(string(ec2types.NetworkInterfaceTypeEfa)),
+				Groups:        lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) string { return s.ID }),
 				// Instances launched with multiple pre-configured network interfaces cannot set AssociatePublicIPAddress to true. This is an EC2 limitation. However, this does not apply for instances
 				// with a single EFA network interface, and we should support those use cases. Launch failures with multiple enis should be considered user misconfiguration.
 				AssociatePublicIpAddress: options.AssociatePublicIPAddress,
 			}
-		})
+			if i == 0 {
+				networkInterface = withPrimaryNetworkInterface(networkInterface, options.PrimaryNetworkInterface)
+			}
+			return networkInterface
+		}), additionalNetworkInterfaces...)
 	}
 
-	if options.AssociatePublicIPAddress != nil {
-		return []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
-			{
-				AssociatePublicIpAddress: options.AssociatePublicIPAddress,
-				DeviceIndex:              aws.Int64(0),
-				Groups:                   lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) *string { return aws.String(s.ID) }),
-			},
-		}
+	// The primary network interface has to be defined when there are additional network interfaces, since the security
+	// groups of the launch template can't be set alongside network interfaces
+	if options.AssociatePublicIPAddress != nil || options.AssociateCarrierIPAddress != nil || options.PrimaryNetworkInterface != nil || len(additionalNetworkInterfaces) != 0 {
+		return append([]ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			withPrimaryNetworkInterface(ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+				AssociatePublicIpAddress:  options.AssociatePublicIPAddress,
+				AssociateCarrierIpAddress: options.AssociateCarrierIPAddress,
+				DeviceIndex:               aws.Int32(0),
+				Groups:                    lo.Map(options.SecurityGroups, func(s v1beta1.SecurityGroup, _ int) string { return s.ID }),
+			}, options.PrimaryNetworkInterface),
+		}, additionalNetworkInterfaces...)
 	}
 	return nil
 }
 
-func (p *DefaultProvider) blockDeviceMappings(blockDeviceMappings []*v1beta1.BlockDeviceMapping) []*ec2.LaunchTemplateBlockDeviceMappingRequest {
+// launchesInWavelengthZones returns true if the requirements of the NodeClaim only allow it to be launched in
+// Wavelength Zones
+func launchesInWavelengthZones(nodeClaim *corev1beta1.NodeClaim) bool {
+	zoneTypes := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(v1beta1.LabelTopologyZoneType)
+	return zoneTypes.Operator() == v1.NodeSelectorOpIn && zoneTypes.Len() == 1 && zoneTypes.Has(v1beta1.ZoneTypeWavelengthZone)
+}
+
+// withPrimaryNetworkInterface requests the secondary addresses that the EC2NodeClass configures for the primary network
+// interface on the network interface
+func withPrimaryNetworkInterface(networkInterface ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest,
+	primaryNetworkInterface *v1beta1.PrimaryNetworkInterface) ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+	if primaryNetworkInterface != nil {
+		networkInterface.SecondaryPrivateIpAddressCount = int32Ptr(primaryNetworkInterface.SecondaryPrivateIPAddressCount)
+		networkInterface.Ipv4PrefixCount = int32Ptr(primaryNetworkInterface.IPv4PrefixCount)
+	}
+	return networkInterface
+}
+
+func (p *DefaultProvider) blockDeviceMappings(blockDeviceMappings []*v1beta1.BlockDeviceMapping) []ec2types.LaunchTemplateBlockDeviceMappingRequest {
 	if len(blockDeviceMappings) == 0 {
 		// The EC2 API fails with empty slices and expects nil.
 		return nil
 	}
-	var blockDeviceMappingsRequest []*ec2.LaunchTemplateBlockDeviceMappingRequest
+	var blockDeviceMappingsRequest []ec2types.LaunchTemplateBlockDeviceMappingRequest
 	for _, blockDeviceMapping := range blockDeviceMappings {
-		blockDeviceMappingsRequest = append(blockDeviceMappingsRequest, &ec2.LaunchTemplateBlockDeviceMappingRequest{
+		blockDeviceMappingsRequest = append(blockDeviceMappingsRequest, ec2types.LaunchTemplateBlockDeviceMappingRequest{
 			DeviceName: blockDeviceMapping.DeviceName,
-			Ebs: &ec2.LaunchTemplateEbsBlockDeviceRequest{
+			Ebs: &ec2types.LaunchTemplateEbsBlockDeviceRequest{
 				DeleteOnTermination: blockDeviceMapping.EBS.DeleteOnTermination,
 				Encrypted:           blockDeviceMapping.EBS.Encrypted,
-				VolumeType:          blockDeviceMapping.EBS.VolumeType,
-				Iops:                blockDeviceMapping.EBS.IOPS,
-				Throughput:          blockDeviceMapping.EBS.Throughput,
+				VolumeType:          ec2types.VolumeType(lo.FromPtr(blockDeviceMapping.EBS.VolumeType)),
+				Iops:                int32Ptr(blockDeviceMapping.EBS.IOPS),
+				Throughput:          int32Ptr(blockDeviceMapping.EBS.Throughput),
 				KmsKeyId:            blockDeviceMapping.EBS.KMSKeyID,
 				SnapshotId:          blockDeviceMapping.EBS.SnapshotID,
 				VolumeSize:          p.volumeSize(blockDeviceMapping.EBS.VolumeSize),
@@ -327,31 +581,20 @@ func (p *DefaultProvider) blockDeviceMappings(blockDeviceMappings []*v1beta1.Blo
 }
 
 // volumeSize returns a GiB scaled value from a resource quantity or nil if the resource quantity passed in is nil
-func (p *DefaultProvider) volumeSize(quantity *resource.Quantity) *int64 {
+func (p *DefaultProvider) volumeSize(quantity *resource.Quantity) *int32 {
 	if quantity == nil {
 		return nil
 	}
 	// Converts the value to Gi and rounds up the value to the nearest Gi
-	return aws.Int64(int64(math.Ceil(quantity.AsApproximateFloat64() / math.Pow(2, 30))))
+	return aws.Int32(int32(math.Ceil(quantity.AsApproximateFloat64() / math.Pow(2, 30))))
 }
 
-// hydrateCache queries for existing Launch Templates created by Karpenter for the current cluster and adds to the LT cache.
-// Any error during hydration will result in a panic
-func (p *DefaultProvider) hydrateCache(ctx context.Context) {
-	clusterName := options.FromContext(ctx).ClusterName
-	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("tag-key", v1beta1.TagManagedLaunchTemplate, "tag-value", clusterName))
-	if err := p.ec2api.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
-		Filters: []*ec2.Filter{{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.TagManagedLaunchTemplate)), Values: []*string{aws.String(clusterName)}}},
-	}, func(output *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
-		for _, lt := range output.LaunchTemplates {
-			p.cache.SetDefault(*lt.LaunchTemplateName, lt)
-		}
-		return true
-	}); err != nil {
-		log.FromContext(ctx).Error(err, "unable to hydrate the AWS launch template cache")
-	} else {
-		log.FromContext(ctx).WithValues("count", p.cache.ItemCount()).V(1).Info("hydrated launch template cache")
+// int32Ptr converts an integer of the EC2NodeClass to the integers of the EC2 API, which its validation keeps it within
+func int32Ptr(i *int64) *int32 {
+	if i == nil {
+		return nil
 	}
+	return aws.Int32(int32(*i))
 }
 
 func (p *DefaultProvider) cachedEvictedFunc(ctx context.Context) func(string, interface{}) {
@@ -361,21 +604,21 @@ func (p *DefaultProvider) cachedEvictedFunc(ctx context.Context) func(string, in
 		if _, expiration, _ := p.cache.GetWithExpiration(key); expiration.After(time.Now()) {
 			return
 		}
-		launchTemplate := lt.(*ec2.LaunchTemplate)
-		if _, err := p.ec2api.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateId: launchTemplate.LaunchTemplateId}); awserrors.IgnoreNotFound(err) != nil {
+		launchTemplate := lt.(*ec2types.LaunchTemplate)
+		if _, err := p.ec2api.DeleteLaunchTemplate(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateId: launchTemplate.LaunchTemplateId}); awserrors.IgnoreNotFound(err) != nil {
 			log.FromContext(ctx).WithValues("launch-template", launchTemplate.LaunchTemplateName).Error(err, "failed to delete launch template")
 			return
 		}
 		log.FromContext(ctx).WithValues(
-			"id", aws.StringValue(launchTemplate.LaunchTemplateId),
-			"name", aws.StringValue(launchTemplate.LaunchTemplateName),
+			"id", aws.ToString(launchTemplate.LaunchTemplateId),
+			"name", aws.ToString(launchTemplate.LaunchTemplateName),
 		).V(1).Info("deleted launch template")
 	}
 }
 
 func (p *DefaultProvider) getInstanceProfile(nodeClass *v1beta1.EC2NodeClass) (string, error) {
 	if nodeClass.Spec.InstanceProfile != nil {
-		return aws.StringValue(nodeClass.Spec.InstanceProfile), nil
+		return aws.ToString(nodeClass.Spec.InstanceProfile), nil
 	}
 	if nodeClass.Spec.Role != "" {
 		if nodeClass.Status.InstanceProfile == "" {
@@ -388,28 +631,30 @@ func (p *DefaultProvider) getInstanceProfile(nodeClass *v1beta1.EC2NodeClass) (s
 
 func (p *DefaultProvider) DeleteAll(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) error {
 	clusterName := options.FromContext(ctx).ClusterName
-	var ltNames []*string
-	if err := p.ec2api.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
-		Filters: []*ec2.Filter{
-			{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.TagManagedLaunchTemplate)), Values: []*string{aws.String(clusterName)}},
-			{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.LabelNodeClass)), Values: []*string{aws.String(nodeClass.Name)}},
+	var ltNames []string
+	paginator := ec2.NewDescribeLaunchTemplatesPaginator(p.ec2api, &ec2.DescribeLaunchTemplatesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.TagManagedLaunchTemplate)), Values: []string{clusterName}},
+			{Name: aws.String(fmt.Sprintf("tag:%s", v1beta1.LabelNodeClass)), Values: []string{nodeClass.Name}},
 		},
-	}, func(output *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("fetching launch templates, %w", err)
+		}
 		for _, lt := range output.LaunchTemplates {
-			ltNames = append(ltNames, lt.LaunchTemplateName)
+			ltNames = append(ltNames, aws.ToString(lt.LaunchTemplateName))
 		}
-		return true
-	}); err != nil {
-		return fmt.Errorf("fetching launch templates, %w", err)
 	}
 
 	var deleteErr error
 	for _, name := range ltNames {
-		_, err := p.ec2api.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateName: name})
+		_, err := p.ec2api.DeleteLaunchTemplate(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateName: aws.String(name)})
 		deleteErr = multierr.Append(deleteErr, err)
 	}
 	if len(ltNames) > 0 {
-		log.FromContext(ctx).WithValues("launchTemplates", utils.PrettySlice(aws.StringValueSlice(ltNames), 5)).V(1).Info("deleted launch templates")
+		log.FromContext(ctx).WithValues("launchTemplates", utils.PrettySlice(ltNames, 5)).V(1).Info("deleted launch templates")
 	}
 	if deleteErr != nil {
 		return fmt.Errorf("deleting launch templates, %w", deleteErr)
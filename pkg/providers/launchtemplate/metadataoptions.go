@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// MetadataOptions applies the metadata settings to the metadata options that were resolved for the EC2NodeClass. With
+// the default policy, settings only replace the options that the EC2NodeClass doesn't set. With the enforce policy,
+// they replace them regardless.
+func MetadataOptions(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, resolved *v1beta1.MetadataOptions) *v1beta1.MetadataOptions {
+	opts := options.FromContext(ctx)
+	enforce := opts.MetadataOptionsPolicy == options.MetadataOptionsPolicyEnforce
+	specified := lo.FromPtr(nodeClass.Spec.MetadataOptions)
+	metadataOptions := resolved.DeepCopy()
+	if opts.MetadataHTTPTokens != "" && (enforce || specified.HTTPTokens == nil) {
+		metadataOptions.HTTPTokens = aws.String(opts.MetadataHTTPTokens)
+	}
+	if opts.MetadataHTTPPutResponseHopLimit != 0 && (enforce || specified.HTTPPutResponseHopLimit == nil) {
+		metadataOptions.HTTPPutResponseHopLimit = aws.Int64(int64(opts.MetadataHTTPPutResponseHopLimit))
+	}
+	return metadataOptions
+}
+
+// OverriddenMetadataOptions returns the names of the metadata options that the EC2NodeClass sets to a different value
+// than the enforced metadata settings
+func OverriddenMetadataOptions(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) []string {
+	opts := options.FromContext(ctx)
+	if opts.MetadataOptionsPolicy != options.MetadataOptionsPolicyEnforce || nodeClass.Spec.MetadataOptions == nil {
+		return nil
+	}
+	var overridden []string
+	if httpTokens := nodeClass.Spec.MetadataOptions.HTTPTokens; opts.MetadataHTTPTokens != "" && httpTokens != nil && *httpTokens != opts.MetadataHTTPTokens {
+		overridden = append(overridden, "httpTokens")
+	}
+	if hopLimit := nodeClass.Spec.MetadataOptions.HTTPPutResponseHopLimit; opts.MetadataHTTPPutResponseHopLimit != 0 && hopLimit != nil && *hopLimit != int64(opts.MetadataHTTPPutResponseHopLimit) {
+		overridden = append(overridden, "httpPutResponseHopLimit")
+	}
+	return overridden
+}
@@ -0,0 +1,52 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	cacheEventLabel        = "event"
+
+	// CacheEventHit is a launch template that was resolved from the cache
+	CacheEventHit = "hit"
+	// CacheEventMiss is a launch template that wasn't cached, so it was described or created
+	CacheEventMiss = "miss"
+	// CacheEventInvalidation is a cached launch template that was invalidated because it no longer exists
+	CacheEventInvalidation = "invalidation"
+)
+
+var (
+	LaunchTemplateCacheEventsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "launch_template_cache_events_total",
+			Help:      "Number of launch template cache events, based on whether the event is a hit, miss, or invalidation.",
+		},
+		[]string{
+			cacheEventLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(LaunchTemplateCacheEventsTotal)
+}
@@ -57,6 +57,7 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
@@ -93,7 +94,7 @@ var _ = BeforeSuite(func() {
 
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.LaunchRamps, awsEnv.TerminationHookProvider, fakeClock)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster)
 })
@@ -185,6 +186,22 @@ var _ = Describe("LaunchTemplate Provider", func() {
 		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
 		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
 	})
+	It("should stop creating launch templates once the hourly launch template cap is reached", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxLaunchTemplatesPerHour: lo.ToPtr(1)}))
+		pods := lo.Map([]string{corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand}, func(capacityType string, _ int) *v1.Pod {
+			return coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []v1.NodeSelectorRequirement{
+				{
+					Key:      corev1beta1.CapacityTypeLabelKey,
+					Operator: v1.NodeSelectorOpIn,
+					Values:   []string{capacityType},
+				},
+			}})
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pods...)
+		Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(1))
+		Expect(awsEnv.CreationLimits.Count(awscache.CreatedResourceLaunchTemplate)).To(Equal(1))
+	})
 	It("should create unique launch templates for multiple identical nodeClasses", func() {
 		nodeClass2 := test.EC2NodeClass(v1beta1.EC2NodeClass{
 			Status: v1beta1.EC2NodeClassStatus{
@@ -407,14 +424,15 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				{Tags: map[string]string{"test-key": "test-value"}},
 				{KubeDNSIP: net.ParseIP("192.0.0.2")},
 				{AssociatePublicIPAddress: lo.ToPtr(true)},
+				{PrimaryNetworkInterface: &v1beta1.PrimaryNetworkInterface{IPv4PrefixCount: lo.ToPtr(int64(1))}},
 				{NodeClassName: "test-name"},
 			}
 			launchtemplateResult := []string{}
 			for _, option := range options {
 				lt := &amifamily.LaunchTemplate{Options: option}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, lo.Must(launchtemplate.LaunchTemplateName(lt)))
 			}
-			Expect(len(launchtemplateResult)).To(BeNumerically("==", 11))
+			Expect(len(launchtemplateResult)).To(BeNumerically("==", 12))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
 		})
 		It("should not generate different launch template names based on CABundle and Labels", func() {
@@ -426,10 +444,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, option := range options {
 				lt := &amifamily.LaunchTemplate{Options: option}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, lo.Must(launchtemplate.LaunchTemplateName(lt)))
 			}
 			Expect(len(lo.Uniq(launchtemplateResult))).To(BeNumerically("==", 1))
-			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(launchtemplate.LaunchTemplateName(&amifamily.LaunchTemplate{Options: &amifamily.Options{}})))
+			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(lo.Must(launchtemplate.LaunchTemplateName(&amifamily.LaunchTemplate{Options: &amifamily.Options{}}))))
 		})
 		It("should generate different launch template names based on kubelet configuration", func() {
 			kubeletChanges := []*corev1beta1.KubeletConfiguration{
@@ -443,7 +461,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, kubelet := range kubeletChanges {
 				lt := &amifamily.LaunchTemplate{UserData: bootstrap.EKS{Options: bootstrap.Options{KubeletConfig: kubelet}}}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, lo.Must(launchtemplate.LaunchTemplateName(lt)))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 6))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -464,7 +482,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, option := range bootstrapOptions {
 				lt := &amifamily.LaunchTemplate{UserData: bootstrap.EKS{Options: *option}}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, lo.Must(launchtemplate.LaunchTemplateName(lt)))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 10))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -480,7 +498,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			}
 			launchtemplateResult := []string{}
 			for _, lt := range launchtemplates {
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, lo.Must(launchtemplate.LaunchTemplateName(lt)))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 6))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -492,10 +510,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			}
 			launchtemplateResult := []string{}
 			for _, lt := range launchtemplates {
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, lo.Must(launchtemplate.LaunchTemplateName(lt)))
 			}
 			Expect(len(lo.Uniq(launchtemplateResult))).To(BeNumerically("==", 1))
-			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(launchtemplate.LaunchTemplateName(&amifamily.LaunchTemplate{})))
+			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(lo.Must(launchtemplate.LaunchTemplateName(&amifamily.LaunchTemplate{}))))
 		})
 	})
 	Context("Labels", func() {
@@ -1171,6 +1189,58 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				}
 			})
 		})
+		It("should apply the kubelet overlay of the capacity type on top of the NodePool's kubelet configuration", func() {
+			nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeSpot}}},
+			}
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{
+				SystemReserved: map[string]string{
+					string(v1.ResourceCPU):    "500m",
+					string(v1.ResourceMemory): "1Gi",
+				},
+			}
+			nodeClass.Spec.KubeletOverlays = &v1beta1.KubeletOverlays{
+				Spot: &v1beta1.KubeletOverlay{
+					SystemReserved: map[string]string{string(v1.ResourceMemory): "2Gi"},
+					EvictionHard:   map[string]string{"memory.available": "10%"},
+				},
+				OnDemand: &v1beta1.KubeletOverlay{
+					EvictionHard: map[string]string{"memory.available": "5%"},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(`--eviction-hard="memory.available<10%"`)
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+				Expect(err).To(BeNil())
+				arg := "--system-reserved="
+				i := strings.Index(string(userData), arg)
+				rem := string(userData)[(i + len(arg)):]
+				i = strings.Index(rem, "'")
+				Expect(rem[:i]).To(ContainSubstring("cpu=500m"))
+				Expect(rem[:i]).To(ContainSubstring("memory=2Gi"))
+			})
+			// The NodePool's kubelet configuration isn't modified by the overlay
+			Expect(nodePool.Spec.Template.Spec.Kubelet.SystemReserved).To(HaveKeyWithValue(string(v1.ResourceMemory), "1Gi"))
+		})
+		It("should not apply the kubelet overlay of another capacity type", func() {
+			nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
+				{NodeSelectorRequirement: v1.NodeSelectorRequirement{Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand}}},
+			}
+			nodeClass.Spec.KubeletOverlays = &v1beta1.KubeletOverlays{
+				Spot: &v1beta1.KubeletOverlay{
+					EvictionHard: map[string]string{"memory.available": "10%"},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("--eviction-hard")
+		})
 		It("should pass eviction hard threshold values when specified", func() {
 			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{
 				EvictionHard: map[string]string{
@@ -1309,6 +1379,15 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			ExpectScheduled(ctx, env.Client, pod)
 			ExpectLaunchTemplatesCreatedWithUserDataContaining("--dns-cluster-ip '10.0.100.10'")
 		})
+		It("should prefer the clusterDNS of the NodePool over the discovered kube-dns IP", func() {
+			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{ClusterDNS: []string{"fd4b:121b:812b::a"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--dns-cluster-ip 'fd4b:121b:812b::a'", "--ip-family ipv6")
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("10.0.100.10")
+		})
 		It("should pass ImageGCHighThresholdPercent when specified", func() {
 			nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{
 				ImageGCHighThresholdPercent: aws.Int32(50),
@@ -1436,6 +1515,43 @@ var _ = Describe("LaunchTemplate Provider", func() {
 					Expect(config.Settings.Kubernetes.SystemReserved[v1.ResourceEphemeralStorage.String()]).To(Equal("10Gi"))
 				})
 			})
+			It("should render the Bottlerocket settings of the EC2NodeClass over custom user data", func() {
+				nodeClass.Spec.UserData = lo.ToPtr(`
+[settings.kernel.sysctl]
+"net.core.somaxconn" = "128"
+"vm.max_map_count" = "65530"
+
+[settings.host-containers.admin]
+superpowered = true
+`)
+				nodeClass.Spec.Bottlerocket = &v1beta1.BottlerocketConfiguration{
+					Kernel: &v1beta1.BottlerocketKernel{
+						Lockdown: lo.ToPtr("integrity"),
+						Sysctls:  map[string]string{"net.core.somaxconn": "1024"},
+					},
+					HostContainers: map[string]v1beta1.BottlerocketHostContainer{"admin": {Enabled: lo.ToPtr(true)}},
+					Updates:        &v1beta1.BottlerocketUpdates{VersionLock: lo.ToPtr("v1.20.0")},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					config := &bootstrap.BottlerocketConfig{}
+					Expect(config.UnmarshalTOML(userData)).To(Succeed())
+					Expect(config.SettingsRaw["kernel"]).To(Equal(map[string]interface{}{
+						"lockdown": "integrity",
+						"sysctl":   map[string]interface{}{"net.core.somaxconn": "1024", "vm.max_map_count": "65530"},
+					}))
+					Expect(config.SettingsRaw["host-containers"]).To(Equal(map[string]interface{}{
+						"admin": map[string]interface{}{"enabled": true, "superpowered": true},
+					}))
+					Expect(config.SettingsRaw["updates"]).To(Equal(map[string]interface{}{"version-lock": "v1.20.0"}))
+				})
+			})
 			It("should override kube reserved values in user data", func() {
 				ExpectApplied(ctx, env.Client, nodeClass)
 				nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{
@@ -1958,7 +2074,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				}})
 				nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
 				ExpectApplied(ctx, env.Client, nodeClass)
-				controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+				controller := status.NewController(env.Client, fakeClock, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, fake.DefaultAccount, awsEnv.InstanceTypesProvider, awsEnv.VPCCNI, events.NewRecorder(&record.FakeRecorder{}))
 				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 				nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{
 					{
@@ -2029,6 +2145,142 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				Expect(*input.LaunchTemplateData.ImageId).To(ContainSubstring("test-ami"))
 			})
 		})
+		Context("UserData Templating", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.AMISelectorTerms = []v1beta1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyCustom
+				nodeClass.Status.AMIs = []v1beta1.AMI{
+					{
+						ID: "ami-123",
+						Requirements: []v1.NodeSelectorRequirement{
+							{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.ArchitectureAmd64}},
+						},
+					},
+				}
+			})
+			It("should substitute template variables into the userData", func() {
+				nodeClass.Spec.UserData = aws.String(`cluster={{ .ClusterName }} nodepool={{ .NodePoolName }} nodeclass={{ .NodeClassName }} arch={{ .Architecture }} ami={{ .AMIID }}`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserData(fmt.Sprintf("cluster=test-cluster nodepool=%s nodeclass=%s arch=amd64 ami=ami-123", nodePool.Name, nodeClass.Name))
+			})
+			It("should substitute node labels and taints into the userData", func() {
+				nodePool.Spec.Template.Spec.Taints = []v1.Taint{{Key: "example.com/dedicated", Value: "team-a", Effect: v1.TaintEffectNoSchedule}}
+				nodeClass.Spec.UserData = aws.String(`capacity={{ index .NodeLabels "karpenter.sh/capacity-type" }}{{ range .Taints }} taint={{ .Key }}:{{ .Effect }}{{ end }}`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining("taint=example.com/dedicated:NoSchedule")
+				ExpectLaunchTemplatesCreatedWithUserDataNotContaining("{{")
+			})
+			It("should substitute the kubelet configuration into the userData", func() {
+				nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{MaxPods: aws.Int32(42)}
+				nodeClass.Spec.UserData = aws.String(`max-pods={{ .MaxPods }} kubelet={{ toJSON .KubeletConfig }}`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining("max-pods=42", `"maxPods":42`)
+			})
+			It("should substitute the cluster DNS into the userData", func() {
+				nodeClass.Spec.UserData = aws.String(`cluster-dns={{ .ClusterDNS }}`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining("cluster-dns=10.0.100.10")
+			})
+			It("should merge templated userData with the AL2 bootstrap script", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+				nodeClass.Spec.AMISelectorTerms = nil
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho {{ .ClusterName }}\n")
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining("echo test-cluster", "/etc/eks/bootstrap.sh")
+			})
+			It("should fail to launch when the userData references an unknown variable", func() {
+				nodeClass.Spec.UserData = aws.String(`{{ .Unknown }}`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
+			})
+		})
+		Context("Container Registries", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.ContainerRegistries = &v1beta1.ContainerRegistries{
+					PauseImage: lo.ToPtr("111122223333.dkr.ecr.us-west-2.amazonaws.com/eks/pause:3.9"),
+					Mirrors: []v1beta1.RegistryMirror{{
+						Registry:  "docker.io",
+						Endpoints: []string{"https://mirror.example.com"},
+					}},
+				}
+			})
+			It("should configure the pause image and registry mirrors on AL2", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					"sed -i 's,SANDBOX_IMAGE,111122223333.dkr.ecr.us-west-2.amazonaws.com/eks/pause:3.9,' /etc/eks/containerd/containerd-config.toml",
+					"cat > '/etc/containerd/certs.d/docker.io/hosts.toml'",
+					`server = "https://registry-1.docker.io"`,
+					`[host."https://mirror.example.com"]`,
+				)
+			})
+			It("should configure the pause image and registry mirrors on AL2023", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyAL2023
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+					Expect(configs[0].Spec.Containerd.Config).To(ContainSubstring(`sandbox_image = "111122223333.dkr.ecr.us-west-2.amazonaws.com/eks/pause:3.9"`))
+					Expect(userData).To(ContainSubstring(`[host."https://mirror.example.com"]`))
+				}
+			})
+			It("should configure the pause image and registry mirrors on Bottlerocket", func() {
+				nodeClass.Spec.AMIFamily = &v1beta1.AMIFamilyBottlerocket
+				nodeClass.Spec.UserData = lo.ToPtr(`
+[[settings.container-registry.mirrors]]
+registry = "docker.io"
+endpoint = ["https://other-mirror.example.com"]
+
+[[settings.container-registry.mirrors]]
+registry = "public.ecr.aws"
+endpoint = ["https://ecr-mirror.example.com"]
+
+[[settings.container-registry.credentials]]
+registry = "docker.io"
+username = "user"
+`)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					config := &bootstrap.BottlerocketConfig{}
+					Expect(config.UnmarshalTOML(userData)).To(Succeed())
+					Expect(config.Settings.Kubernetes.PodInfraContainerImage).To(Equal(lo.ToPtr("111122223333.dkr.ecr.us-west-2.amazonaws.com/eks/pause:3.9")))
+					Expect(config.Settings.ContainerRegistry.Mirrors).To(ConsistOf(
+						bootstrap.BottlerocketRegistryMirror{Registry: lo.ToPtr("public.ecr.aws"), Endpoint: []string{"https://ecr-mirror.example.com"}},
+						bootstrap.BottlerocketRegistryMirror{Registry: lo.ToPtr("docker.io"), Endpoint: []string{"https://mirror.example.com"}},
+					))
+					Expect(config.SettingsRaw["container-registry"]).To(HaveKey("credentials"))
+				})
+			})
+		})
 		Context("Public IP Association", func() {
 			DescribeTable(
 				"should set 'AssociatePublicIPAddress' based on EC2NodeClass",
@@ -2052,6 +2304,176 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				Entry("AssociatePublicIPAddress is false (EFA)", false, false, true),
 			)
 		})
+		Context("Primary Network Interface", func() {
+			efaPod := func() *v1.Pod {
+				return coretest.UnschedulablePod(coretest.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1beta1.ResourceEFA: resource.MustParse("2")},
+						Limits:   v1.ResourceList{v1beta1.ResourceEFA: resource.MustParse("2")},
+					},
+				})
+			}
+			It("should not configure network interfaces by default", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(BeNil())
+				Expect(input.LaunchTemplateData.SecurityGroupIds).ToNot(BeEmpty())
+			})
+			It("should request secondary private IP addresses on the primary network interface", func() {
+				nodeClass.Spec.PrimaryNetworkInterface = &v1beta1.PrimaryNetworkInterface{SecondaryPrivateIPAddressCount: lo.ToPtr(int64(9))}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+				Expect(aws.Int64Value(input.LaunchTemplateData.NetworkInterfaces[0].DeviceIndex)).To(BeNumerically("==", 0))
+				Expect(aws.Int64Value(input.LaunchTemplateData.NetworkInterfaces[0].SecondaryPrivateIpAddressCount)).To(BeNumerically("==", 9))
+				Expect(input.LaunchTemplateData.NetworkInterfaces[0].Ipv4PrefixCount).To(BeNil())
+				Expect(input.LaunchTemplateData.NetworkInterfaces[0].Groups).ToNot(BeEmpty())
+				// Security groups are defined within the network interface
+				Expect(input.LaunchTemplateData.SecurityGroupIds).To(BeNil())
+			})
+			It("should request IPv4 prefixes on the primary network interface", func() {
+				nodeClass.Spec.PrimaryNetworkInterface = &v1beta1.PrimaryNetworkInterface{IPv4PrefixCount: lo.ToPtr(int64(2))}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+				Expect(aws.Int64Value(input.LaunchTemplateData.NetworkInterfaces[0].Ipv4PrefixCount)).To(BeNumerically("==", 2))
+				Expect(input.LaunchTemplateData.NetworkInterfaces[0].SecondaryPrivateIpAddressCount).To(BeNil())
+			})
+			It("should only request addresses on the first EFA interface", func() {
+				nodeClass.Spec.PrimaryNetworkInterface = &v1beta1.PrimaryNetworkInterface{IPv4PrefixCount: lo.ToPtr(int64(2))}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := efaPod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(len(input.LaunchTemplateData.NetworkInterfaces)).To(BeNumerically(">", 1))
+				for _, networkInterface := range input.LaunchTemplateData.NetworkInterfaces {
+					if aws.Int64Value(networkInterface.NetworkCardIndex) == 0 {
+						Expect(aws.Int64Value(networkInterface.Ipv4PrefixCount)).To(BeNumerically("==", 2))
+					} else {
+						Expect(networkInterface.Ipv4PrefixCount).To(BeNil())
+					}
+				}
+			})
+		})
+		Context("Additional Network Interfaces", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.AdditionalNetworkInterfaces = []v1beta1.AdditionalNetworkInterface{
+					{
+						DeviceIndex:                1,
+						SubnetSelectorTerms:        []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"network-interface": "appliance"}}},
+						SecurityGroupSelectorTerms: []v1beta1.SecurityGroupSelectorTerm{{Tags: map[string]string{"network-interface": "appliance"}}},
+					},
+				}
+				nodeClass.Status.AdditionalNetworkInterfaces = []v1beta1.AdditionalNetworkInterfaceStatus{
+					{
+						DeviceIndex: 1,
+						Subnets: []v1beta1.Subnet{
+							{ID: "subnet-appliance1", Zone: "test-zone-1a"},
+							{ID: "subnet-appliance2", Zone: "test-zone-1a"},
+							{ID: "subnet-appliance3", Zone: "test-zone-1b"},
+						},
+						SecurityGroups: []v1beta1.SecurityGroup{{ID: "sg-appliance"}},
+					},
+				}
+			})
+			It("should create a launch template with the additional network interfaces for each zone", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+				subnets := sets.New[string]()
+				awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(2))
+					primary, additional := input.LaunchTemplateData.NetworkInterfaces[0], input.LaunchTemplateData.NetworkInterfaces[1]
+					Expect(aws.Int64Value(primary.DeviceIndex)).To(BeNumerically("==", 0))
+					Expect(aws.StringValueSlice(primary.Groups)).To(ConsistOf("sg-test1", "sg-test2", "sg-test3"))
+					Expect(aws.Int64Value(additional.DeviceIndex)).To(BeNumerically("==", 1))
+					Expect(aws.StringValueSlice(additional.Groups)).To(ConsistOf("sg-appliance"))
+					Expect(aws.BoolValue(additional.DeleteOnTermination)).To(BeTrue())
+					subnets.Insert(aws.StringValue(additional.SubnetId))
+					// Security groups are defined within the network interfaces
+					Expect(input.LaunchTemplateData.SecurityGroupIds).To(BeNil())
+				})
+				// The interface is created in the first subnet of each zone
+				Expect(sets.List(subnets)).To(ConsistOf("subnet-appliance1", "subnet-appliance3"))
+			})
+			It("should only launch instances in the zone of the subnets of the launch template", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+				zones := sets.New[string]()
+				for _, launchTemplateConfig := range createFleetInput.LaunchTemplateConfigs {
+					ltZones := sets.New(lo.Map(launchTemplateConfig.Overrides, func(o *ec2.FleetLaunchTemplateOverridesRequest, _ int) string {
+						return aws.StringValue(o.AvailabilityZone)
+					})...)
+					Expect(ltZones.Len()).To(Equal(1))
+					zones = zones.Union(ltZones)
+				}
+				Expect(sets.List(zones)).To(ConsistOf("test-zone-1a", "test-zone-1b"))
+			})
+			It("should use the security groups of the nodeclass when the interface doesn't select any", func() {
+				nodeClass.Spec.AdditionalNetworkInterfaces[0].SecurityGroupSelectorTerms = nil
+				nodeClass.Spec.AdditionalNetworkInterfaces[0].DeleteOnTermination = lo.ToPtr(false)
+				nodeClass.Status.AdditionalNetworkInterfaces[0].SecurityGroups = nil
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(2))
+				Expect(aws.StringValueSlice(input.LaunchTemplateData.NetworkInterfaces[1].Groups)).To(ConsistOf("sg-test1", "sg-test2", "sg-test3"))
+				Expect(aws.BoolValue(input.LaunchTemplateData.NetworkInterfaces[1].DeleteOnTermination)).To(BeFalse())
+			})
+			It("should not launch when no zone has subnets for every additional network interface", func() {
+				nodeClass.Spec.AdditionalNetworkInterfaces = append(nodeClass.Spec.AdditionalNetworkInterfaces, v1beta1.AdditionalNetworkInterface{
+					DeviceIndex:         2,
+					SubnetSelectorTerms: []v1beta1.SubnetSelectorTerm{{Tags: map[string]string{"network-interface": "management"}}},
+				})
+				nodeClass.Status.AdditionalNetworkInterfaces = append(nodeClass.Status.AdditionalNetworkInterfaces, v1beta1.AdditionalNetworkInterfaceStatus{
+					DeviceIndex: 2,
+					Subnets:     []v1beta1.Subnet{{ID: "subnet-management", Zone: "test-zone-1c"}},
+				})
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+			})
+		})
+		Context("EFA", func() {
+			It("should place a single EFA interface on each network card", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1beta1.ResourceEFA: resource.MustParse("2")},
+						Limits:   v1.ResourceList{v1beta1.ResourceEFA: resource.MustParse("2")},
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+				// dl1.24xlarge has four network cards and supports four EFA interfaces
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(4))
+				for i, ni := range input.LaunchTemplateData.NetworkInterfaces {
+					Expect(lo.FromPtr(ni.NetworkCardIndex)).To(BeNumerically("==", i))
+					Expect(lo.FromPtr(ni.DeviceIndex)).To(BeNumerically("==", lo.Ternary(i == 0, 0, 1)))
+					Expect(lo.FromPtr(ni.InterfaceType)).To(Equal(ec2.NetworkInterfaceTypeEfa))
+				}
+				Expect(input.LaunchTemplateData.SecurityGroupIds).To(BeEmpty())
+			})
+		})
 		Context("Kubelet Args", func() {
 			It("should specify the --dns-cluster-ip flag when clusterDNSIP is set", func() {
 				nodePool.Spec.Template.Spec.Kubelet = &corev1beta1.KubeletConfiguration{ClusterDNS: []string{"10.0.10.100"}}
@@ -2128,6 +2550,121 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			})
 		})
 	})
+	Context("Launch Template Data Patches", func() {
+		BeforeEach(func() {
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationLaunchTemplateDataPatch: `[{"op": "add", "path": "/CpuOptions", "value": {"CoreCount": 2, "ThreadsPerCore": 1}}]`}
+		})
+		It("should apply the patch to the launch template data", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateDataPatches: lo.ToPtr(true)}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.Int64Value(ltInput.LaunchTemplateData.CpuOptions.CoreCount)).To(BeNumerically("==", 2))
+				Expect(aws.Int64Value(ltInput.LaunchTemplateData.CpuOptions.ThreadsPerCore)).To(BeNumerically("==", 1))
+				// The fields that Karpenter manages are kept
+				Expect(ltInput.LaunchTemplateData.ImageId).ToNot(BeNil())
+				Expect(ltInput.LaunchTemplateData.UserData).ToNot(BeNil())
+			})
+		})
+		It("should not apply the patch when launch template data patches aren't enabled", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.CpuOptions).To(BeNil())
+			})
+		})
+		It("should not launch when the patch conflicts with the fields that Karpenter manages", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateDataPatches: lo.ToPtr(true)}))
+			nodeClass.Annotations = map[string]string{v1beta1.AnnotationLaunchTemplateDataPatch: `[{"op": "replace", "path": "/UserData", "value": ""}]`}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeZero())
+		})
+	})
+	Context("Metadata Options", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.MetadataOptions = &v1beta1.MetadataOptions{
+				HTTPEndpoint:            aws.String("enabled"),
+				HTTPProtocolIPv6:        aws.String("disabled"),
+				HTTPPutResponseHopLimit: aws.Int64(2),
+				HTTPTokens:              aws.String("optional"),
+			}
+		})
+		It("should use the metadata options of the EC2NodeClass when no metadata settings are set", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.MetadataOptions.HttpTokens)).To(Equal("optional"))
+				Expect(aws.Int64Value(ltInput.LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit)).To(BeNumerically("==", 2))
+			})
+		})
+		It("should not override the metadata options of the EC2NodeClass with the default policy", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				MetadataHTTPTokens:              lo.ToPtr("required"),
+				MetadataHTTPPutResponseHopLimit: lo.ToPtr(1),
+			}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.MetadataOptions.HttpTokens)).To(Equal("optional"))
+				Expect(aws.Int64Value(ltInput.LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit)).To(BeNumerically("==", 2))
+			})
+		})
+		It("should override the metadata options of the EC2NodeClass with the enforce policy", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				MetadataHTTPTokens:              lo.ToPtr("required"),
+				MetadataHTTPPutResponseHopLimit: lo.ToPtr(1),
+				MetadataOptionsPolicy:           lo.ToPtr(options.MetadataOptionsPolicyEnforce),
+			}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.MetadataOptions.HttpTokens)).To(Equal("required"))
+				Expect(aws.Int64Value(ltInput.LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit)).To(BeNumerically("==", 1))
+				Expect(aws.StringValue(ltInput.LaunchTemplateData.MetadataOptions.HttpEndpoint)).To(Equal("enabled"))
+			})
+		})
+		It("should only set the metadata options that the EC2NodeClass doesn't set with the default policy", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				MetadataHTTPTokens:              lo.ToPtr("required"),
+				MetadataHTTPPutResponseHopLimit: lo.ToPtr(1),
+			}))
+			nodeClass.Spec.MetadataOptions.HTTPPutResponseHopLimit = nil
+			resolved := launchtemplate.MetadataOptions(ctx, nodeClass, &v1beta1.MetadataOptions{
+				HTTPEndpoint:            aws.String("enabled"),
+				HTTPProtocolIPv6:        aws.String("disabled"),
+				HTTPPutResponseHopLimit: aws.Int64(2),
+				HTTPTokens:              aws.String("optional"),
+			})
+			Expect(aws.StringValue(resolved.HTTPTokens)).To(Equal("optional"))
+			Expect(aws.Int64Value(resolved.HTTPPutResponseHopLimit)).To(BeNumerically("==", 1))
+		})
+		It("should return the metadata options that the enforce policy overrides", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				MetadataHTTPTokens:              lo.ToPtr("required"),
+				MetadataHTTPPutResponseHopLimit: lo.ToPtr(2),
+				MetadataOptionsPolicy:           lo.ToPtr(options.MetadataOptionsPolicyEnforce),
+			}))
+			Expect(launchtemplate.OverriddenMetadataOptions(ctx, nodeClass)).To(ConsistOf("httpTokens"))
+		})
+	})
 })
 
 // ExpectTags verifies that the expected tags are a subset of the tags found
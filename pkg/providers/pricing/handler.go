@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Prices are the hourly prices that the pricing provider currently uses
+type Prices struct {
+	// OnDemand are the regional on-demand prices, keyed by instance type
+	OnDemand map[string]float64 `json:"onDemand"`
+	// ZonalOnDemand are the on-demand prices of Local Zones and Wavelength Zones, keyed by zone, then instance type
+	ZonalOnDemand map[string]map[string]float64 `json:"zonalOnDemand,omitempty"`
+	// Spot are the spot prices, keyed by instance type, then zone
+	Spot map[string]map[string]float64 `json:"spot,omitempty"`
+	// SpotPricingUpdated is false until spot prices were retrieved, in which case spot prices fall back to the
+	// on-demand prices
+	SpotPricingUpdated bool `json:"spotPricingUpdated"`
+}
+
+// NewHandler returns a read-only HTTP handler that serves the prices of the provider as JSON
+func NewHandler(provider Provider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(provider.Prices()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
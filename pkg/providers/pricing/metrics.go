@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	instanceTypeLabel      = "instance_type"
+	capacityTypeLabel      = "capacity_type"
+	zoneLabel              = "zone"
+)
+
+var (
+	// InstanceTypePriceEstimate is the hourly price that the pricing provider knows for an instance type. Regional
+	// on-demand prices are labeled with the region as their zone.
+	InstanceTypePriceEstimate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "instance_type_price_estimate",
+			Help:      "Estimated hourly price of an instance type known by the pricing provider, based on instance type, capacity type, and zone. Regional on-demand prices are labeled with the region as their zone.",
+		},
+		[]string{
+			instanceTypeLabel,
+			capacityTypeLabel,
+			zoneLabel,
+		},
+	)
+	// SpotPriceStaleness is how long ago the spot prices of a zone were last refreshed from DescribeSpotPriceHistory
+	SpotPriceStaleness = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "spot_price_staleness_seconds",
+			Help:      "Seconds since the spot prices of a zone were last refreshed from the EC2 spot price history, based on zone.",
+		},
+		[]string{
+			zoneLabel,
+		},
+	)
+	// PricingLastUpdated is when the prices of a capacity type were last updated without a failure
+	PricingLastUpdated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "pricing_last_updated_timestamp_seconds",
+			Help:      "Unix timestamp of the last update of the on-demand or spot prices in which every page was retrieved, based on capacity type.",
+		},
+		[]string{
+			capacityTypeLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(InstanceTypePriceEstimate, SpotPriceStaleness, PricingLastUpdated)
+}
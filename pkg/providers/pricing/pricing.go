@@ -15,7 +15,6 @@ limitations under the License.
 package pricing
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -28,27 +27,49 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
-	"github.com/aws/aws-sdk-go/service/pricing"
-	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/lo"
+	lop "github.com/samber/lo/parallel"
 	"go.uber.org/multierr"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
 var initialOnDemandPrices = lo.Assign(InitialOnDemandPricesAWS, InitialOnDemandPricesUSGov, InitialOnDemandPricesCN)
 
+// maxAttempts is the number of times a pricing call is attempted. Prices are only updated in the background, and a
+// retried page is cheap, so calls are attempted more often than other calls are.
+const maxAttempts = 5
+
+// API is the part of the aws-sdk-go-v2 pricing client that the provider retrieves on-demand prices with
+type API interface {
+	GetProducts(context.Context, *pricing.GetProductsInput, ...func(*pricing.Options)) (*pricing.GetProductsOutput, error)
+}
+
+// EC2API is the part of the aws-sdk-go-v2 EC2 client that the provider retrieves spot prices and zones with
+type EC2API interface {
+	DescribeAvailabilityZones(context.Context, *ec2.DescribeAvailabilityZonesInput, ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error)
+	DescribeSpotPriceHistory(context.Context, *ec2.DescribeSpotPriceHistoryInput, ...func(*ec2.Options)) (*ec2.DescribeSpotPriceHistoryOutput, error)
+}
+
 type Provider interface {
 	LivenessProbe(*http.Request) error
 	InstanceTypes() []string
 	OnDemandPrice(string) (float64, bool)
+	ZonalOnDemandPrice(string, string) (float64, bool)
 	SpotPrice(string, string) (float64, bool)
 	UpdateOnDemandPricing(context.Context) error
 	UpdateSpotPricing(context.Context) error
+	Prices() Prices
 }
 
 // DefaultProvider provides actual pricing data to the AWS cloud provider to allow it to make more informed decisions
@@ -58,17 +79,23 @@ type Provider interface {
 // fails, the previous pricing information is retained and used which may be the static initial pricing data if pricing
 // updates never succeed.
 type DefaultProvider struct {
-	ec2     ec2iface.EC2API
-	pricing pricingiface.PricingAPI
+	ec2     EC2API
+	pricing API
 	region  string
 	cm      *pretty.ChangeMonitor
+	// snapshot replaces the pricing data that's discovered from the pricing and EC2 APIs when it's set
+	snapshot *snapshot.Snapshot
 
 	muOnDemand     sync.RWMutex
 	onDemandPrices map[string]float64
+	// zonalOnDemandPrices are the on-demand prices of Local Zones and Wavelength Zones, keyed by zone, then instance type
+	zonalOnDemandPrices map[string]map[string]float64
 
 	muSpot             sync.RWMutex
 	spotPrices         map[string]zonal
 	spotPricingUpdated bool
+	// spotPricesRefreshedAt is when the spot prices of each zone were last returned by DescribeSpotPriceHistory
+	spotPricesRefreshedAt map[string]time.Time
 }
 
 // zonalPricing is used to capture the per-zone price
@@ -88,29 +115,46 @@ func newZonalPricing(defaultPrice float64) zonal {
 	return z
 }
 
-// NewPricingAPI returns a pricing API configured based on a particular region
-func NewAPI(sess *session.Session, region string) pricingiface.PricingAPI {
-	if sess == nil {
+// NewAPI returns a pricing API configured based on a particular region. The pricing API is only available in the aws
+// and aws-cn partitions, and doesn't have FIPS endpoints, so nil is returned for configs in other partitions, e.g.
+// GovCloud, or when FIPS endpoints are used, which use the static on-demand prices instead. The aws partition's pricing
+// API also has the prices of GovCloud regions.
+func NewAPI(ctx context.Context, cfg aws.Config, region string, optFns ...func(*pricing.Options)) API {
+	if partition := utils.Partition(cfg.Region); partition != endpoints.AwsPartitionID && partition != endpoints.AwsCnPartitionID {
+		return nil
+	}
+	// The pricing API doesn't have FIPS endpoints
+	if options.FromContext(ctx).AWSFIPSEndpoints {
 		return nil
 	}
-	// pricing API doesn't have an endpoint in all regions
-	pricingAPIRegion := "us-east-1"
-	if strings.HasPrefix(region, "ap-") {
-		pricingAPIRegion = "ap-south-1"
-	} else if strings.HasPrefix(region, "cn-") {
-		pricingAPIRegion = "cn-northwest-1"
-	} else if strings.HasPrefix(region, "eu-") {
-		pricingAPIRegion = "eu-central-1"
+	return pricing.NewFromConfig(cfg, append([]func(*pricing.Options){func(o *pricing.Options) {
+		o.Region = APIRegion(region)
+		o.Retryer = retry.NewStandard(func(o *retry.StandardOptions) { o.MaxAttempts = maxAttempts })
+	}}, optFns...)...)
+}
+
+// APIRegion returns the region of the pricing API endpoint that the prices of a region are retrieved from, since the
+// pricing API doesn't have an endpoint in all regions
+func APIRegion(region string) string {
+	switch {
+	case utils.Partition(region) == endpoints.AwsCnPartitionID:
+		return "cn-northwest-1"
+	case strings.HasPrefix(region, "ap-"):
+		return "ap-south-1"
+	case strings.HasPrefix(region, "eu-"):
+		return "eu-central-1"
+	default:
+		return "us-east-1"
 	}
-	return pricing.New(sess, &aws.Config{Region: aws.String(pricingAPIRegion)})
 }
 
-func NewDefaultProvider(_ context.Context, pricing pricingiface.PricingAPI, ec2Api ec2iface.EC2API, region string) *DefaultProvider {
+func NewDefaultProvider(_ context.Context, pricing API, ec2Api EC2API, region string, snapshot *snapshot.Snapshot) *DefaultProvider {
 	p := &DefaultProvider{
-		region:  region,
-		ec2:     ec2Api,
-		pricing: pricing,
-		cm:      pretty.NewChangeMonitor(),
+		region:   region,
+		ec2:      ec2Api,
+		pricing:  pricing,
+		cm:       pretty.NewChangeMonitor(),
+		snapshot: snapshot,
 	}
 	// sets the pricing data from the static default state for the provider
 	p.Reset()
@@ -139,6 +183,19 @@ func (p *DefaultProvider) OnDemandPrice(instanceType string) (float64, bool) {
 	return price, true
 }
 
+// ZonalOnDemandPrice returns the last known on-demand price for a given instance type in a zone. Local Zones and
+// Wavelength Zones are priced separately from their parent region, so their price is returned when it's known, and the
+// regional price otherwise.
+func (p *DefaultProvider) ZonalOnDemandPrice(instanceType string, zone string) (float64, bool) {
+	p.muOnDemand.RLock()
+	if price, ok := p.zonalOnDemandPrices[zone][instanceType]; ok {
+		p.muOnDemand.RUnlock()
+		return price, true
+	}
+	p.muOnDemand.RUnlock()
+	return p.OnDemandPrice(instanceType)
+}
+
 // SpotPrice returns the last known spot price for a given instance type and zone, returning an error
 // if there is no known spot pricing for that instance type or zone
 func (p *DefaultProvider) SpotPrice(instanceType string, zone string) (float64, bool) {
@@ -162,12 +219,34 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	var onDemandPrices, onDemandMetalPrices map[string]float64
 	var onDemandErr, onDemandMetalErr error
 
+	if p.snapshot != nil {
+		if p.cm.HasChanged("on-demand-prices", nil) {
+			log.FromContext(ctx).WithValues("created-at", p.snapshot.CreatedAt).V(1).Info("using on-demand pricing information from the instance type snapshot")
+		}
+		p.muOnDemand.RLock()
+		defer p.muOnDemand.RUnlock()
+		p.publishOnDemandPrices()
+		return nil
+	}
 	// if we are in isolated vpc, skip updating on demand pricing
 	// as pricing api may not be available
 	if options.FromContext(ctx).IsolatedVPC {
 		if p.cm.HasChanged("on-demand-prices", nil) {
 			log.FromContext(ctx).V(1).Info("running in an isolated VPC, on-demand pricing information will not be updated")
 		}
+		p.muOnDemand.RLock()
+		defer p.muOnDemand.RUnlock()
+		p.publishOnDemandPrices()
+		return nil
+	}
+	// the pricing API isn't available in every partition, e.g. GovCloud
+	if p.pricing == nil {
+		if p.cm.HasChanged("on-demand-prices", nil) {
+			log.FromContext(ctx).WithValues("partition", utils.Partition(p.region)).V(1).Info("pricing API isn't available in the partition, on-demand pricing information will not be updated")
+		}
+		p.muOnDemand.RLock()
+		defer p.muOnDemand.RUnlock()
+		p.publishOnDemandPrices()
 		return nil
 	}
 
@@ -178,14 +257,14 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	go func() {
 		defer wg.Done()
 		onDemandPrices, onDemandErr = p.fetchOnDemandPricing(ctx,
-			&pricing.Filter{
+			pricingtypes.Filter{
 				Field: aws.String("tenancy"),
-				Type:  aws.String("TERM_MATCH"),
+				Type:  pricingtypes.FilterTypeTermMatch,
 				Value: aws.String("Shared"),
 			},
-			&pricing.Filter{
+			pricingtypes.Filter{
 				Field: aws.String("productFamily"),
-				Type:  aws.String("TERM_MATCH"),
+				Type:  pricingtypes.FilterTypeTermMatch,
 				Value: aws.String("Compute Instance"),
 			})
 	}()
@@ -195,20 +274,40 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	go func() {
 		defer wg.Done()
 		onDemandMetalPrices, onDemandMetalErr = p.fetchOnDemandPricing(ctx,
-			&pricing.Filter{
+			pricingtypes.Filter{
 				Field: aws.String("tenancy"),
-				Type:  aws.String("TERM_MATCH"),
+				Type:  pricingtypes.FilterTypeTermMatch,
 				Value: aws.String("Dedicated"),
 			},
-			&pricing.Filter{
+			pricingtypes.Filter{
 				Field: aws.String("productFamily"),
-				Type:  aws.String("TERM_MATCH"),
+				Type:  pricingtypes.FilterTypeTermMatch,
 				Value: aws.String("Compute Instance (bare metal)"),
 			})
 	}()
 
+	// on-demand prices of Local Zones and Wavelength Zones, which are listed under their own location
+	var zonalPrices map[string]map[string]float64
+	var zonalErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		zonalPrices, zonalErr = p.fetchZonalOnDemandPricing(ctx)
+	}()
+
 	wg.Wait()
 
+	// Zonal prices fall back to the regional prices, so failing to update them doesn't fail the update
+	if zonalErr != nil {
+		log.FromContext(ctx).Error(zonalErr, "failed updating local zone and wavelength zone on-demand pricing")
+	}
+	p.zonalOnDemandPrices = lo.Assign(p.zonalOnDemandPrices, zonalPrices)
+
+	// Prices are updated per instance type, and the prices that were retrieved before a failure are kept, so that a
+	// failed page only leaves the prices that it would have updated stale rather than all of them
+	p.onDemandPrices = lo.Assign(p.onDemandPrices, onDemandPrices, onDemandMetalPrices)
+	p.publishOnDemandPrices()
+
 	err := multierr.Append(onDemandErr, onDemandMetalErr)
 	if err != nil {
 		return fmt.Errorf("retreiving on-demand pricing data, %w", err)
@@ -218,67 +317,156 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 		return fmt.Errorf("no on-demand pricing found")
 	}
 
-	p.onDemandPrices = lo.Assign(onDemandPrices, onDemandMetalPrices)
+	PricingLastUpdated.With(prometheus.Labels{capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeOnDemand)}).SetToCurrentTime()
 	if p.cm.HasChanged("on-demand-prices", p.onDemandPrices) {
 		log.FromContext(ctx).WithValues("instance-type-count", len(p.onDemandPrices)).V(1).Info("updated on-demand pricing")
 	}
 	return nil
 }
 
-func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFilters ...*pricing.Filter) (map[string]float64, error) {
+// publishOnDemandPrices replaces the on-demand price estimates with the current prices. The caller must hold muOnDemand.
+func (p *DefaultProvider) publishOnDemandPrices() {
+	InstanceTypePriceEstimate.DeletePartialMatch(prometheus.Labels{capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeOnDemand)})
+	for instanceType, price := range p.onDemandPrices {
+		InstanceTypePriceEstimate.With(prometheus.Labels{
+			instanceTypeLabel: instanceType,
+			capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeOnDemand),
+			zoneLabel:         p.region,
+		}).Set(price)
+	}
+	for zone, prices := range p.zonalOnDemandPrices {
+		for instanceType, price := range prices {
+			InstanceTypePriceEstimate.With(prometheus.Labels{
+				instanceTypeLabel: instanceType,
+				capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeOnDemand),
+				zoneLabel:         zone,
+			}).Set(price)
+		}
+	}
+}
+
+// fetchZonalOnDemandPricing returns the on-demand prices of the Local Zones and Wavelength Zones of the region, keyed by
+// zone. Local Zones are priced by their zone group, e.g. us-west-2-lax-1, and Wavelength Zones by their zone name. The
+// locations are fetched in parallel, and the prices of the locations that were fetched are returned along with the
+// errors of the others.
+func (p *DefaultProvider) fetchZonalOnDemandPricing(ctx context.Context) (map[string]map[string]float64, error) {
+	out, err := p.ec2.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{})
+	if err != nil {
+		return nil, fmt.Errorf("describing availability zones, %w", err)
+	}
+	locations := map[string][]string{}
+	for _, az := range out.AvailabilityZones {
+		var location string
+		switch aws.ToString(az.ZoneType) {
+		case "local-zone":
+			location = aws.ToString(az.GroupName)
+		case "wavelength-zone":
+			location = aws.ToString(az.ZoneName)
+		}
+		if location != "" {
+			locations[location] = append(locations[location], aws.ToString(az.ZoneName))
+		}
+	}
+	var mu sync.Mutex
+	prices := map[string]map[string]float64{}
+	var errs error
+	lop.ForEach(lo.Keys(locations), func(location string, _ int) {
+		locationPrices, err := p.fetchOnDemandPricingForLocation(ctx, location,
+			pricingtypes.Filter{
+				Field: aws.String("tenancy"),
+				Type:  pricingtypes.FilterTypeTermMatch,
+				Value: aws.String("Shared"),
+			},
+			pricingtypes.Filter{
+				Field: aws.String("productFamily"),
+				Type:  pricingtypes.FilterTypeTermMatch,
+				Value: aws.String("Compute Instance"),
+			})
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("retrieving on-demand pricing data for %s, %w", location, err))
+			return
+		}
+		// zones without their own prices use the regional prices
+		if len(locationPrices) == 0 {
+			return
+		}
+		for _, zone := range locations[location] {
+			prices[zone] = locationPrices
+		}
+	})
+	return prices, errs
+}
+
+func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFilters ...pricingtypes.Filter) (map[string]float64, error) {
+	return p.fetchOnDemandPricingForLocation(ctx, p.region, additionalFilters...)
+}
+
+// fetchOnDemandPricingForLocation returns the on-demand prices of a region, or of a Local Zone or Wavelength Zone, which
+// the pricing API lists under their own region code. If a page fails, the prices of the pages before it are returned
+// along with the error.
+func (p *DefaultProvider) fetchOnDemandPricingForLocation(ctx context.Context, regionCode string, additionalFilters ...pricingtypes.Filter) (map[string]float64, error) {
 	prices := map[string]float64{}
-	filters := append([]*pricing.Filter{
+	filters := append([]pricingtypes.Filter{
 		{
 			Field: aws.String("regionCode"),
-			Type:  aws.String("TERM_MATCH"),
-			Value: aws.String(p.region),
+			Type:  pricingtypes.FilterTypeTermMatch,
+			Value: aws.String(regionCode),
 		},
 		{
 			Field: aws.String("serviceCode"),
-			Type:  aws.String("TERM_MATCH"),
+			Type:  pricingtypes.FilterTypeTermMatch,
 			Value: aws.String("AmazonEC2"),
 		},
 		{
 			Field: aws.String("preInstalledSw"),
-			Type:  aws.String("TERM_MATCH"),
+			Type:  pricingtypes.FilterTypeTermMatch,
 			Value: aws.String("NA"),
 		},
 		{
 			Field: aws.String("operatingSystem"),
-			Type:  aws.String("TERM_MATCH"),
+			Type:  pricingtypes.FilterTypeTermMatch,
 			Value: aws.String("Linux"),
 		},
 		{
 			Field: aws.String("capacitystatus"),
-			Type:  aws.String("TERM_MATCH"),
+			Type:  pricingtypes.FilterTypeTermMatch,
 			Value: aws.String("Used"),
 		},
 		{
 			Field: aws.String("marketoption"),
-			Type:  aws.String("TERM_MATCH"),
+			Type:  pricingtypes.FilterTypeTermMatch,
 			Value: aws.String("OnDemand"),
 		}},
 		additionalFilters...)
 
-	err := p.pricing.GetProductsPagesWithContext(
-		ctx,
-		&pricing.GetProductsInput{
-			Filters:     filters,
-			ServiceCode: aws.String("AmazonEC2"),
-		},
-		p.onDemandPage(ctx, prices),
-	)
-	if err != nil {
-		return nil, err
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	record := p.onDemandPage(ctx, prices, &mu, &wg)
+	paginator := pricing.NewGetProductsPaginator(p.pricing, &pricing.GetProductsInput{
+		Filters:     filters,
+		ServiceCode: aws.String("AmazonEC2"),
+	})
+	var err error
+	for paginator.HasMorePages() {
+		var output *pricing.GetProductsOutput
+		if output, err = paginator.NextPage(ctx); err != nil {
+			break
+		}
+		record(output)
 	}
-
-	return prices, nil
+	wg.Wait()
+	return prices, err
 }
 
-func (p *DefaultProvider) spotPage(ctx context.Context, prices map[string]map[string]float64) func(output *ec2.DescribeSpotPriceHistoryOutput, b bool) bool {
-	return func(output *ec2.DescribeSpotPriceHistoryOutput, b bool) bool {
+// spotPage records the newest spot price of each instance type and zone. An instance type can have a record for each
+// of the product descriptions that we query for, and the records of a zone aren't ordered by time.
+func (p *DefaultProvider) spotPage(ctx context.Context, prices map[string]map[string]float64) func(output *ec2.DescribeSpotPriceHistoryOutput) {
+	timestamps := map[string]map[string]time.Time{}
+	return func(output *ec2.DescribeSpotPriceHistoryOutput) {
 		for _, sph := range output.SpotPriceHistory {
-			spotPriceStr := aws.StringValue(sph.SpotPrice)
+			spotPriceStr := aws.ToString(sph.SpotPrice)
 			spotPrice, err := strconv.ParseFloat(spotPriceStr, 64)
 			// these errors shouldn't occur, but if pricing API does have an error, we ignore the record
 			if err != nil {
@@ -288,22 +476,28 @@ func (p *DefaultProvider) spotPage(ctx context.Context, prices map[string]map[st
 			if sph.Timestamp == nil {
 				continue
 			}
-			instanceType := aws.StringValue(sph.InstanceType)
-			az := aws.StringValue(sph.AvailabilityZone)
+			instanceType := string(sph.InstanceType)
+			az := aws.ToString(sph.AvailabilityZone)
 			_, ok := prices[instanceType]
 			if !ok {
 				prices[instanceType] = map[string]float64{}
+				timestamps[instanceType] = map[string]time.Time{}
+			}
+			if timestamp, ok := timestamps[instanceType][az]; ok && timestamp.After(*sph.Timestamp) {
+				continue
 			}
 			prices[instanceType][az] = spotPrice
+			timestamps[instanceType][az] = *sph.Timestamp
 		}
-		return true
 	}
 }
 
+// onDemandPage decodes each page in its own goroutine, so that a page is decoded while the next one is fetched, and
+// records its prices under the mutex. The caller must wait on the WaitGroup before reading the prices.
 // turning off cyclo here, it measures as a 12 due to all of the type checks of the pricing data which returns a deeply
 // nested map[string]interface{}
 // nolint: gocyclo
-func (p *DefaultProvider) onDemandPage(ctx context.Context, prices map[string]float64) func(output *pricing.GetProductsOutput, b bool) bool {
+func (p *DefaultProvider) onDemandPage(ctx context.Context, prices map[string]float64, mu *sync.Mutex, wg *sync.WaitGroup) func(output *pricing.GetProductsOutput) {
 	// this isn't the full pricing struct, just the portions we care about
 	type priceItem struct {
 		Product struct {
@@ -320,77 +514,101 @@ func (p *DefaultProvider) onDemandPage(ctx context.Context, prices map[string]fl
 		}
 	}
 
-	return func(output *pricing.GetProductsOutput, b bool) bool {
-		currency := "USD"
-		if strings.HasPrefix(p.region, "cn-") {
-			currency = "CNY"
-		}
-		for _, outer := range output.PriceList {
-			var buf bytes.Buffer
-			enc := json.NewEncoder(&buf)
-			if err := enc.Encode(outer); err != nil {
-				log.FromContext(ctx).Error(err, "failed encoding pricing data")
-			}
-			dec := json.NewDecoder(&buf)
-			var pItem priceItem
-			if err := dec.Decode(&pItem); err != nil {
-				log.FromContext(ctx).Error(err, "failed decoding pricing data")
-			}
-			if pItem.Product.Attributes.InstanceType == "" {
-				continue
-			}
-			for _, term := range pItem.Terms.OnDemand {
-				for _, v := range term.PriceDimensions {
-					price, err := strconv.ParseFloat(v.PricePerUnit[currency], 64)
-					if err != nil || price == 0 {
-						continue
+	currency := "USD"
+	if utils.Partition(p.region) == endpoints.AwsCnPartitionID {
+		currency = "CNY"
+	}
+	return func(output *pricing.GetProductsOutput) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pagePrices := map[string]float64{}
+			for _, outer := range output.PriceList {
+				var pItem priceItem
+				if err := json.Unmarshal([]byte(outer), &pItem); err != nil {
+					log.FromContext(ctx).Error(err, "failed decoding pricing data")
+				}
+				if pItem.Product.Attributes.InstanceType == "" {
+					continue
+				}
+				for _, term := range pItem.Terms.OnDemand {
+					for _, v := range term.PriceDimensions {
+						price, err := strconv.ParseFloat(v.PricePerUnit[currency], 64)
+						if err != nil || price == 0 {
+							continue
+						}
+						pagePrices[pItem.Product.Attributes.InstanceType] = price
 					}
-					prices[pItem.Product.Attributes.InstanceType] = price
 				}
 			}
-		}
-		return true
+			mu.Lock()
+			defer mu.Unlock()
+			for instanceType, price := range pagePrices {
+				prices[instanceType] = price
+			}
+		}()
 	}
 }
 
 // nolint: gocyclo
 func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	prices := map[string]map[string]float64{}
+	if p.snapshot != nil {
+		if p.cm.HasChanged("spot-prices", nil) {
+			log.FromContext(ctx).WithValues("created-at", p.snapshot.CreatedAt).V(1).Info("using spot pricing information from the instance type snapshot")
+		}
+		p.muSpot.RLock()
+		defer p.muSpot.RUnlock()
+		p.publishSpotPrices()
+		return nil
+	}
 
 	p.muSpot.Lock()
 	defer p.muSpot.Unlock()
-	err := p.ec2.DescribeSpotPriceHistoryPagesWithContext(
-		ctx,
-		&ec2.DescribeSpotPriceHistoryInput{
-			ProductDescriptions: []*string{
-				aws.String("Linux/UNIX"),
-				aws.String("Linux/UNIX (Amazon VPC)"),
-			},
-			// get the latest spot price for each instance type
-			StartTime: aws.Time(time.Now()),
+	// Staleness is published whether or not the refresh succeeds, so that it keeps growing while refreshes fail
+	defer p.publishSpotPriceStaleness()
+	record := p.spotPage(ctx, prices)
+	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(p.ec2, &ec2.DescribeSpotPriceHistoryInput{
+		ProductDescriptions: []string{
+			"Linux/UNIX",
+			"Linux/UNIX (Amazon VPC)",
 		},
-		p.spotPage(ctx, prices),
-	)
-
-	if err != nil {
-		return fmt.Errorf("retrieving spot pricing data, %w", err)
-	}
-	if len(prices) == 0 {
-		return fmt.Errorf("no spot pricing found")
+		// get the latest spot price for each instance type
+		StartTime: aws.Time(time.Now()),
+	})
+	var err error
+	for paginator.HasMorePages() {
+		var output *ec2.DescribeSpotPriceHistoryOutput
+		if output, err = paginator.NextPage(ctx); err != nil {
+			break
+		}
+		record(output)
 	}
 
+	// The prices of the pages that were retrieved before a failure are kept, so that a failed page only leaves the
+	// prices that it would have updated stale rather than all of them
 	totalOfferings := 0
+	refreshedAt := time.Now()
 	for it, zoneData := range prices {
 		if _, ok := p.spotPrices[it]; !ok {
 			p.spotPrices[it] = newZonalPricing(0)
 		}
 		for zone, price := range zoneData {
 			p.spotPrices[it].prices[zone] = price
+			p.spotPricesRefreshedAt[zone] = refreshedAt
 		}
 		totalOfferings += len(zoneData)
 	}
+	if err != nil {
+		return fmt.Errorf("retrieving spot pricing data, %w", err)
+	}
+	if len(prices) == 0 {
+		return fmt.Errorf("no spot pricing found")
+	}
 
 	p.spotPricingUpdated = true
+	PricingLastUpdated.With(prometheus.Labels{capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeSpot)}).SetToCurrentTime()
+	p.publishSpotPrices()
 	if p.cm.HasChanged("spot-prices", p.spotPrices) {
 		log.FromContext(ctx).WithValues(
 			"instance-type-count", len(p.onDemandPrices),
@@ -399,6 +617,53 @@ func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	return nil
 }
 
+// publishSpotPrices replaces the spot price estimates with the current zonal spot prices. The caller must hold muSpot.
+func (p *DefaultProvider) publishSpotPrices() {
+	InstanceTypePriceEstimate.DeletePartialMatch(prometheus.Labels{capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeSpot)})
+	for instanceType, z := range p.spotPrices {
+		for zone, price := range z.prices {
+			InstanceTypePriceEstimate.With(prometheus.Labels{
+				instanceTypeLabel: instanceType,
+				capacityTypeLabel: string(ec2types.DefaultTargetCapacityTypeSpot),
+				zoneLabel:         zone,
+			}).Set(price)
+		}
+	}
+}
+
+// publishSpotPriceStaleness reports how long ago the spot prices of each zone were refreshed. Zones whose prices stop
+// being returned by DescribeSpotPriceHistory keep their last known prices, which become increasingly stale. The caller
+// must hold muSpot.
+func (p *DefaultProvider) publishSpotPriceStaleness() {
+	for zone, refreshedAt := range p.spotPricesRefreshedAt {
+		SpotPriceStaleness.With(prometheus.Labels{zoneLabel: zone}).Set(time.Since(refreshedAt).Seconds())
+	}
+}
+
+// Prices returns a copy of the current on-demand and spot prices
+func (p *DefaultProvider) Prices() Prices {
+	p.muOnDemand.RLock()
+	defer p.muOnDemand.RUnlock()
+	p.muSpot.RLock()
+	defer p.muSpot.RUnlock()
+
+	return Prices{
+		OnDemand:      lo.Assign(p.onDemandPrices),
+		ZonalOnDemand: lo.MapValues(p.zonalOnDemandPrices, func(prices map[string]float64, _ string) map[string]float64 { return lo.Assign(prices) }),
+		Spot: lo.PickBy(lo.MapValues(p.spotPrices, func(z zonal, _ string) map[string]float64 { return lo.Assign(z.prices) }),
+			func(_ string, prices map[string]float64) bool { return len(prices) > 0 }),
+		SpotPricingUpdated: p.spotPricingUpdated,
+	}
+}
+
+// ExportSnapshot adds the current on-demand and spot prices to the snapshot
+func (p *DefaultProvider) ExportSnapshot(s *snapshot.Snapshot) {
+	prices := p.Prices()
+	s.OnDemandPrices = prices.OnDemand
+	s.ZonalOnDemandPrices = prices.ZonalOnDemand
+	s.SpotPrices = prices.Spot
+}
+
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	// ensure we don't deadlock and nolint for the empty critical section
 	p.muOnDemand.Lock()
@@ -418,6 +683,22 @@ func populateInitialSpotPricing(pricing map[string]float64) map[string]zonal {
 }
 
 func (p *DefaultProvider) Reset() {
+	if p.snapshot != nil && len(p.snapshot.OnDemandPrices) > 0 {
+		p.onDemandPrices = p.snapshot.OnDemandPrices
+		p.zonalOnDemandPrices = p.snapshot.ZonalOnDemandPrices
+		p.spotPrices = populateInitialSpotPricing(p.snapshot.OnDemandPrices)
+		for it, zoneData := range p.snapshot.SpotPrices {
+			if _, ok := p.spotPrices[it]; !ok {
+				p.spotPrices[it] = newZonalPricing(0)
+			}
+			for zone, price := range zoneData {
+				p.spotPrices[it].prices[zone] = price
+			}
+		}
+		p.spotPricingUpdated = len(p.snapshot.SpotPrices) > 0
+		p.spotPricesRefreshedAt = map[string]time.Time{}
+		return
+	}
 	// see if we've got region specific pricing data
 	staticPricing, ok := initialOnDemandPrices[p.region]
 	if !ok {
@@ -426,7 +707,9 @@ func (p *DefaultProvider) Reset() {
 	}
 
 	p.onDemandPrices = staticPricing
+	p.zonalOnDemandPrices = nil
 	// default our spot pricing to the same as the on-demand pricing until a price update
 	p.spotPrices = populateInitialSpotPricing(staticPricing)
 	p.spotPricingUpdated = false
+	p.spotPricesRefreshedAt = map[string]time.Time{}
 }
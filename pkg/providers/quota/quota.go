@@ -0,0 +1,238 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+// Family classes group instance families that share an EC2 vCPU service quota
+const (
+	FamilyClassStandard   = "standard"
+	FamilyClassF          = "f"
+	FamilyClassG          = "g"
+	FamilyClassInf        = "inf"
+	FamilyClassP          = "p"
+	FamilyClassX          = "x"
+	FamilyClassDL         = "dl"
+	FamilyClassTrn        = "trn"
+	FamilyClassHPC        = "hpc"
+	FamilyClassHighMemory = "high-memory"
+)
+
+// quotaCodes are the codes of the EC2 service quotas that limit the running vCPUs of each family class
+var quotaCodes = map[string]map[string]string{
+	corev1beta1.CapacityTypeOnDemand: {
+		FamilyClassStandard:   "L-1216C47A",
+		FamilyClassF:          "L-74FC7D96",
+		FamilyClassG:          "L-DB2E81BA",
+		FamilyClassInf:        "L-1945791B",
+		FamilyClassP:          "L-417A185B",
+		FamilyClassX:          "L-7295265B",
+		FamilyClassDL:         "L-6E869C2A",
+		FamilyClassTrn:        "L-2C3B7624",
+		FamilyClassHPC:        "L-F7808C92",
+		FamilyClassHighMemory: "L-43DA4232",
+	},
+	corev1beta1.CapacityTypeSpot: {
+		FamilyClassStandard: "L-34B43A08",
+		FamilyClassF:        "L-88CF9481",
+		FamilyClassG:        "L-3819A6DF",
+		FamilyClassInf:      "L-B5D1601B",
+		FamilyClassP:        "L-7212CCBC",
+		FamilyClassX:        "L-E3A00192",
+		FamilyClassDL:       "L-85EED4F7",
+		FamilyClassTrn:      "L-6B0D517C",
+	},
+}
+
+// categoryClasses maps the instance categories that don't count against the standard quota to their family class
+var categoryClasses = map[string]string{
+	"f":   FamilyClassF,
+	"g":   FamilyClassG,
+	"vt":  FamilyClassG,
+	"inf": FamilyClassInf,
+	"p":   FamilyClassP,
+	"x":   FamilyClassX,
+	"dl":  FamilyClassDL,
+	"trn": FamilyClassTrn,
+	"hpc": FamilyClassHPC,
+	"u":   FamilyClassHighMemory,
+}
+
+var categoryScheme = regexp.MustCompile(`^[a-z]+`)
+
+// FamilyClass returns the family class of the vCPU quota that an instance type counts against, e.g. "g" for
+// g5.xlarge. Instance types of the A, C, D, H, I, M, R, T and Z families count against the standard quota.
+func FamilyClass(instanceType string) string {
+	return CategoryClass(categoryScheme.FindString(instanceType))
+}
+
+// CategoryClass returns the family class of the vCPU quota that instance types of an instance category count against
+func CategoryClass(category string) string {
+	if class, ok := categoryClasses[category]; ok {
+		return class
+	}
+	return FamilyClassStandard
+}
+
+// Key identifies a vCPU quota
+type Key struct {
+	CapacityType string
+	FamilyClass  string
+}
+
+// VCPUUsage is the vCPU usage of the account against one of its vCPU quotas
+type VCPUUsage struct {
+	Key
+	Quota float64
+	Used  float64
+}
+
+// Remaining returns how many more vCPUs can be launched before the quota is reached
+func (u VCPUUsage) Remaining() float64 {
+	return max(u.Quota-u.Used, 0)
+}
+
+// Utilization returns the fraction of the quota that's in use
+func (u VCPUUsage) Utilization() float64 {
+	if u.Quota <= 0 {
+		return 1
+	}
+	return u.Used / u.Quota
+}
+
+type Provider interface {
+	VCPUUsage(context.Context) ([]VCPUUsage, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	servicequotasapi servicequotasiface.ServiceQuotasAPI
+	ec2api           ec2iface.EC2API
+	cache            *cache.Cache
+}
+
+const quotasCacheKey = "vcpu-quotas"
+
+func NewDefaultProvider(servicequotasapi servicequotasiface.ServiceQuotasAPI, ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		servicequotasapi: servicequotasapi,
+		ec2api:           ec2api,
+		cache:            cache,
+	}
+}
+
+// VCPUUsage returns the vCPU usage of all running and pending instances in the account and region against each of the
+// vCPU quotas of the account, sorted by capacity type and family class. Quotas change rarely, so they're cached, but
+// usage is read on every call.
+func (p *DefaultProvider) VCPUUsage(ctx context.Context) ([]VCPUUsage, error) {
+	quotas, err := p.quotas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	used, err := p.used(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := lo.MapToSlice(quotas, func(key Key, quota float64) VCPUUsage {
+		return VCPUUsage{Key: key, Quota: quota, Used: used[key]}
+	})
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].CapacityType != usage[j].CapacityType {
+			return usage[i].CapacityType < usage[j].CapacityType
+		}
+		return usage[i].FamilyClass < usage[j].FamilyClass
+	})
+	return usage, nil
+}
+
+func (p *DefaultProvider) quotas(ctx context.Context) (map[Key]float64, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if quotas, ok := p.cache.Get(quotasCacheKey); ok {
+		return quotas.(map[Key]float64), nil
+	}
+	keys := map[string]Key{}
+	for capacityType, codes := range quotaCodes {
+		for class, code := range codes {
+			keys[code] = Key{CapacityType: capacityType, FamilyClass: class}
+		}
+	}
+	quotas := map[Key]float64{}
+	if err := p.servicequotasapi.ListServiceQuotasPagesWithContext(ctx, &servicequotas.ListServiceQuotasInput{
+		ServiceCode: aws.String("ec2"),
+	}, func(page *servicequotas.ListServiceQuotasOutput, _ bool) bool {
+		for _, quota := range page.Quotas {
+			if key, ok := keys[aws.StringValue(quota.QuotaCode)]; ok && quota.Value != nil {
+				quotas[key] = aws.Float64Value(quota.Value)
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("listing ec2 service quotas, %w", err)
+	}
+	p.cache.SetDefault(quotasCacheKey, quotas)
+	return quotas, nil
+}
+
+// used returns the vCPUs of the running and pending instances in the account that count against each quota. Stopped
+// instances don't count against vCPU quotas.
+func (p *DefaultProvider) used(ctx context.Context) (map[Key]float64, error) {
+	used := map[Key]float64{}
+	if err := p.ec2api.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning}),
+			},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.CpuOptions == nil {
+					continue
+				}
+				key := Key{
+					CapacityType: lo.Ternary(aws.StringValue(instance.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot, corev1beta1.CapacityTypeSpot, corev1beta1.CapacityTypeOnDemand),
+					FamilyClass:  FamilyClass(aws.StringValue(instance.InstanceType)),
+				}
+				used[key] += float64(aws.Int64Value(instance.CpuOptions.CoreCount) * aws.Int64Value(instance.CpuOptions.ThreadsPerCore))
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("describing instances, %w", err)
+	}
+	return used, nil
+}
+
+func (p *DefaultProvider) Reset() {
+	p.cache.Flush()
+}
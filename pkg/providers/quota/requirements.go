@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// standardCategories are the instance categories that count against the standard quota
+var standardCategories = []string{"a", "c", "d", "h", "i", "im", "is", "m", "r", "t", "z"}
+
+// acceleratorClasses are the family classes of the instance types that have each kind of accelerator
+var acceleratorClasses = map[v1.ResourceName][]string{
+	v1beta1.ResourceNVIDIAGPU:   {FamilyClassG, FamilyClassP},
+	v1beta1.ResourceAMDGPU:      {FamilyClassG},
+	v1beta1.ResourceAWSNeuron:   {FamilyClassInf, FamilyClassTrn},
+	v1beta1.ResourceHabanaGaudi: {FamilyClassDL},
+}
+
+// Keys returns the vCPU quotas that instances which satisfy the requirements and have the requested resources could
+// count against
+func Keys(requirements scheduling.Requirements, requests v1.ResourceList) []Key {
+	classes := sets.New(lo.Values(categoryClasses)...).Insert(FamilyClassStandard)
+	for resource, accelerated := range acceleratorClasses {
+		if quantity, ok := requests[resource]; ok && !quantity.IsZero() {
+			classes = classes.Intersection(sets.New(accelerated...))
+		}
+	}
+	if requirements.Has(v1beta1.LabelInstanceCategory) {
+		requirement := requirements.Get(v1beta1.LabelInstanceCategory)
+		categories := append(lo.Keys(categoryClasses), standardCategories...)
+		classes = classes.Intersection(sets.New(lo.FilterMap(categories, func(category string, _ int) (string, bool) {
+			return CategoryClass(category), requirement.Has(category)
+		})...))
+	}
+	for _, key := range []string{v1beta1.LabelInstanceFamily, v1.LabelInstanceTypeStable} {
+		if requirement := requirements.Get(key); requirements.Has(key) && requirement.Operator() == v1.NodeSelectorOpIn {
+			classes = classes.Intersection(sets.New(lo.Map(requirement.Values(), func(value string, _ int) string { return FamilyClass(value) })...))
+		}
+	}
+	capacityTypes := lo.Filter([]string{corev1beta1.CapacityTypeOnDemand, corev1beta1.CapacityTypeSpot}, func(capacityType string, _ int) bool {
+		return !requirements.Has(corev1beta1.CapacityTypeLabelKey) || requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(capacityType)
+	})
+	var keys []Key
+	for _, capacityType := range capacityTypes {
+		for _, class := range sets.List(classes) {
+			keys = append(keys, Key{CapacityType: capacityType, FamilyClass: class})
+		}
+	}
+	return keys
+}
@@ -0,0 +1,177 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ec2api *fake.EC2API
+var servicequotasapi *fake.ServiceQuotasAPI
+var quotaProvider *quota.DefaultProvider
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "QuotaProvider")
+}
+
+var _ = BeforeSuite(func() {
+	ec2api = fake.NewEC2API()
+	servicequotasapi = fake.NewServiceQuotasAPI()
+	quotaProvider = quota.NewDefaultProvider(servicequotasapi, ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+})
+
+var _ = BeforeEach(func() {
+	ec2api.Reset()
+	servicequotasapi.Reset()
+	quotaProvider.Reset()
+})
+
+func instance(instanceType, lifecycle, state string, vcpus int64) *ec2.Instance {
+	return &ec2.Instance{
+		InstanceId:        aws.String(fake.InstanceID()),
+		InstanceType:      aws.String(instanceType),
+		InstanceLifecycle: lo.EmptyableToPtr(lifecycle),
+		State:             &ec2.InstanceState{Name: aws.String(state)},
+		CpuOptions:        &ec2.CpuOptions{CoreCount: aws.Int64(vcpus / 2), ThreadsPerCore: aws.Int64(2)},
+	}
+}
+
+var _ = Describe("QuotaProvider", func() {
+	Context("FamilyClass", func() {
+		DescribeTable("should map instance types to the family class of their quota",
+			func(instanceType, class string) {
+				Expect(quota.FamilyClass(instanceType)).To(Equal(class))
+			},
+			Entry("general purpose", "m5.large", quota.FamilyClassStandard),
+			Entry("storage optimized", "im4gn.large", quota.FamilyClassStandard),
+			Entry("nvidia gpu", "g5.xlarge", quota.FamilyClassG),
+			Entry("video transcoding", "vt1.3xlarge", quota.FamilyClassG),
+			Entry("inferentia", "inf2.xlarge", quota.FamilyClassInf),
+			Entry("trainium", "trn1.2xlarge", quota.FamilyClassTrn),
+			Entry("gaudi", "dl1.24xlarge", quota.FamilyClassDL),
+			Entry("memory optimized", "x2idn.16xlarge", quota.FamilyClassX),
+			Entry("high performance computing", "hpc7g.4xlarge", quota.FamilyClassHPC),
+			Entry("high memory", "u-6tb1.metal", quota.FamilyClassHighMemory),
+			Entry("fpga", "f1.2xlarge", quota.FamilyClassF),
+		)
+	})
+	Context("VCPUUsage", func() {
+		BeforeEach(func() {
+			servicequotasapi.ListServiceQuotasOutput.Set(&servicequotas.ListServiceQuotasOutput{Quotas: []*servicequotas.ServiceQuota{
+				fake.NewServiceQuota("L-1216C47A", 64),
+				fake.NewServiceQuota("L-34B43A08", 32),
+				fake.NewServiceQuota("L-DB2E81BA", 8),
+				// Unrelated quotas are ignored
+				fake.NewServiceQuota("L-0263D0A3", 5),
+			}})
+		})
+		It("should report the vcpus in use against each quota", func() {
+			for _, i := range []*ec2.Instance{
+				instance("m5.xlarge", "", ec2.InstanceStateNameRunning, 4),
+				instance("c5.2xlarge", "", ec2.InstanceStateNamePending, 8),
+				instance("m5.large", ec2.InstanceLifecycleTypeSpot, ec2.InstanceStateNameRunning, 2),
+				instance("g5.xlarge", "", ec2.InstanceStateNameRunning, 4),
+				// Stopped instances don't count against quotas
+				instance("m5.24xlarge", "", ec2.InstanceStateNameStopped, 96),
+			} {
+				ec2api.Instances.Store(aws.StringValue(i.InstanceId), i)
+			}
+			usage, err := quotaProvider.VCPUUsage(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(usage).To(Equal([]quota.VCPUUsage{
+				{Key: quota.Key{CapacityType: corev1beta1.CapacityTypeOnDemand, FamilyClass: quota.FamilyClassG}, Quota: 8, Used: 4},
+				{Key: quota.Key{CapacityType: corev1beta1.CapacityTypeOnDemand, FamilyClass: quota.FamilyClassStandard}, Quota: 64, Used: 12},
+				{Key: quota.Key{CapacityType: corev1beta1.CapacityTypeSpot, FamilyClass: quota.FamilyClassStandard}, Quota: 32, Used: 2},
+			}))
+			Expect(usage[1].Remaining()).To(BeNumerically("==", 52))
+			Expect(usage[0].Utilization()).To(BeNumerically("==", 0.5))
+		})
+		It("should cache quotas", func() {
+			_, err := quotaProvider.VCPUUsage(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = quotaProvider.VCPUUsage(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(servicequotasapi.ListServiceQuotasCalls.Len()).To(Equal(1))
+		})
+		It("should return an error when quotas can't be listed", func() {
+			servicequotasapi.NextError.Set(fmt.Errorf("access denied"))
+			_, err := quotaProvider.VCPUUsage(ctx)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Keys", func() {
+		allClasses := []string{
+			quota.FamilyClassDL, quota.FamilyClassF, quota.FamilyClassG, quota.FamilyClassHighMemory, quota.FamilyClassHPC,
+			quota.FamilyClassInf, quota.FamilyClassP, quota.FamilyClassStandard, quota.FamilyClassTrn, quota.FamilyClassX,
+		}
+		keys := func(capacityTypes []string, classes ...string) []quota.Key {
+			var keys []quota.Key
+			for _, capacityType := range capacityTypes {
+				for _, class := range classes {
+					keys = append(keys, quota.Key{CapacityType: capacityType, FamilyClass: class})
+				}
+			}
+			return keys
+		}
+		bothCapacityTypes := []string{corev1beta1.CapacityTypeOnDemand, corev1beta1.CapacityTypeSpot}
+
+		It("should return every quota for unconstrained pods", func() {
+			Expect(quota.Keys(scheduling.NewRequirements(), v1.ResourceList{})).To(ConsistOf(keys(bothCapacityTypes, allClasses...)))
+		})
+		It("should narrow the quotas by capacity type", func() {
+			requirements := scheduling.NewRequirements(scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeSpot))
+			Expect(quota.Keys(requirements, v1.ResourceList{})).To(ConsistOf(keys([]string{corev1beta1.CapacityTypeSpot}, allClasses...)))
+		})
+		It("should narrow the quotas by accelerator", func() {
+			requests := v1.ResourceList{v1beta1.ResourceNVIDIAGPU: resource.MustParse("1")}
+			Expect(quota.Keys(scheduling.NewRequirements(), requests)).To(ConsistOf(keys(bothCapacityTypes, quota.FamilyClassG, quota.FamilyClassP)))
+		})
+		It("should narrow the quotas by instance category", func() {
+			requirements := scheduling.NewRequirements(scheduling.NewRequirement(v1beta1.LabelInstanceCategory, v1.NodeSelectorOpIn, "c", "m", "g"))
+			Expect(quota.Keys(requirements, v1.ResourceList{})).To(ConsistOf(keys(bothCapacityTypes, quota.FamilyClassG, quota.FamilyClassStandard)))
+		})
+		It("should narrow the quotas by instance family and type", func() {
+			requirements := scheduling.NewRequirements(
+				scheduling.NewRequirement(v1beta1.LabelInstanceFamily, v1.NodeSelectorOpIn, "p4d", "m5"),
+				scheduling.NewRequirement(v1.LabelInstanceTypeStable, v1.NodeSelectorOpIn, "p4d.24xlarge"),
+			)
+			Expect(quota.Keys(requirements, v1.ResourceList{})).To(ConsistOf(keys(bothCapacityTypes, quota.FamilyClassP)))
+		})
+	})
+})
@@ -17,14 +17,14 @@ package securitygroup
 import (
 	"context"
 	"fmt"
-	"sync"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
@@ -33,17 +33,24 @@ import (
 )
 
 type Provider interface {
-	List(context.Context, *v1beta1.EC2NodeClass) ([]*ec2.SecurityGroup, error)
+	List(context.Context, *v1beta1.EC2NodeClass) ([]ec2types.SecurityGroup, error)
+}
+
+// EC2API is the part of the aws-sdk-go-v2 EC2 client that the provider describes security groups with
+type EC2API interface {
+	DescribeSecurityGroups(context.Context, *ec2.DescribeSecurityGroupsInput, ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
 }
 
 type DefaultProvider struct {
-	sync.Mutex
-	ec2api ec2iface.EC2API
+	// group deduplicates concurrent describes of the same security group selector, so NodeClasses that share it only
+	// describe the security groups once between them
+	group  singleflight.Group
+	ec2api EC2API
 	cache  *cache.Cache
 	cm     *pretty.ChangeMonitor
 }
 
-func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(ec2api EC2API, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		ec2api: ec2api,
 		cm:     pretty.NewChangeMonitor(),
@@ -52,10 +59,7 @@ func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache) *DefaultProv
 	}
 }
 
-func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) ([]*ec2.SecurityGroup, error) {
-	p.Lock()
-	defer p.Unlock()
-
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) ([]ec2types.SecurityGroup, error) {
 	// Get SecurityGroups
 	filterSets := getFilterSets(nodeClass.Spec.SecurityGroupSelectorTerms)
 	securityGroups, err := p.getSecurityGroups(ctx, filterSets)
@@ -64,59 +68,76 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 	}
 	if p.cm.HasChanged(fmt.Sprintf("security-groups/%s", nodeClass.Name), securityGroups) {
 		log.FromContext(ctx).
-			WithValues("security-groups", lo.Map(securityGroups, func(s *ec2.SecurityGroup, _ int) string {
-				return aws.StringValue(s.GroupId)
+			WithValues("security-groups", lo.Map(securityGroups, func(s ec2types.SecurityGroup, _ int) string {
+				return aws.ToString(s.GroupId)
 			})).
 			V(1).Info("discovered security groups")
 	}
 	return securityGroups, nil
 }
 
-func (p *DefaultProvider) getSecurityGroups(ctx context.Context, filterSets [][]*ec2.Filter) ([]*ec2.SecurityGroup, error) {
+func (p *DefaultProvider) getSecurityGroups(ctx context.Context, filterSets [][]ec2types.Filter) ([]ec2types.SecurityGroup, error) {
 	hash, err := hashstructure.Hash(filterSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
 		return nil, err
 	}
-	if sg, ok := p.cache.Get(fmt.Sprint(hash)); ok {
+	key := fmt.Sprint(hash)
+	if sg, ok := p.cache.Get(key); ok {
 		// Ensure what's returned from this function is a shallow-copy of the slice (not a deep-copy of the data itself)
 		// so that modifications to the ordering of the data don't affect the original
-		return append([]*ec2.SecurityGroup{}, sg.([]*ec2.SecurityGroup)...), nil
+		return append([]ec2types.SecurityGroup{}, sg.([]ec2types.SecurityGroup)...), nil
 	}
-	securityGroups := map[string]*ec2.SecurityGroup{}
+	result, err, _ := p.group.Do(key, func() (interface{}, error) {
+		// The security groups may have been cached by a describe that finished since the cache was checked
+		if sg, ok := p.cache.Get(key); ok {
+			return sg, nil
+		}
+		return p.describe(ctx, key, filterSets)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The result is shared between the callers that the describe was deduplicated for
+	return append([]ec2types.SecurityGroup{}, result.([]ec2types.SecurityGroup)...), nil
+}
+
+// describe describes the security groups that match the filter sets and caches them under the key
+func (p *DefaultProvider) describe(ctx context.Context, key string, filterSets [][]ec2types.Filter) ([]ec2types.SecurityGroup, error) {
+	securityGroups := map[string]ec2types.SecurityGroup{}
 	for _, filters := range filterSets {
-		output, err := p.ec2api.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+		output, err := p.ec2api.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{Filters: filters})
 		if err != nil {
-			return nil, fmt.Errorf("describing security groups %+v, %w", filterSets, err)
+			return nil, fmt.Errorf("describing security groups %s, %w", pretty.Concise(filterSets), err)
 		}
 		for i := range output.SecurityGroups {
 			securityGroups[lo.FromPtr(output.SecurityGroups[i].GroupId)] = output.SecurityGroups[i]
 		}
 	}
-	p.cache.SetDefault(fmt.Sprint(hash), lo.Values(securityGroups))
+	p.cache.SetDefault(key, lo.Values(securityGroups))
 	return lo.Values(securityGroups), nil
 }
 
-func getFilterSets(terms []v1beta1.SecurityGroupSelectorTerm) (res [][]*ec2.Filter) {
-	idFilter := &ec2.Filter{Name: aws.String("group-id")}
-	nameFilter := &ec2.Filter{Name: aws.String("group-name")}
+func getFilterSets(terms []v1beta1.SecurityGroupSelectorTerm) (res [][]ec2types.Filter) {
+	idFilter := ec2types.Filter{Name: aws.String("group-id")}
+	nameFilter := ec2types.Filter{Name: aws.String("group-name")}
 	for _, term := range terms {
 		switch {
 		case term.ID != "":
-			idFilter.Values = append(idFilter.Values, aws.String(term.ID))
+			idFilter.Values = append(idFilter.Values, term.ID)
 		case term.Name != "":
-			nameFilter.Values = append(nameFilter.Values, aws.String(term.Name))
+			nameFilter.Values = append(nameFilter.Values, term.Name)
 		default:
-			var filters []*ec2.Filter
+			var filters []ec2types.Filter
 			for k, v := range term.Tags {
 				if v == "*" {
-					filters = append(filters, &ec2.Filter{
+					filters = append(filters, ec2types.Filter{
 						Name:   aws.String("tag-key"),
-						Values: []*string{aws.String(k)},
+						Values: []string{k},
 					})
 				} else {
-					filters = append(filters, &ec2.Filter{
+					filters = append(filters, ec2types.Filter{
 						Name:   aws.String(fmt.Sprintf("tag:%s", k)),
-						Values: []*string{aws.String(v)},
+						Values: []string{v},
 					})
 				}
 			}
@@ -124,10 +145,10 @@ func getFilterSets(terms []v1beta1.SecurityGroupSelectorTerm) (res [][]*ec2.Filt
 		}
 	}
 	if len(idFilter.Values) > 0 {
-		res = append(res, []*ec2.Filter{idFilter})
+		res = append(res, []ec2types.Filter{idFilter})
 	}
 	if len(nameFilter.Values) > 0 {
-		res = append(res, []*ec2.Filter{nameFilter})
+		res = append(res, []ec2types.Filter{nameFilter})
 	}
 	return res
 }
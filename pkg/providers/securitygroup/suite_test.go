@@ -20,6 +20,7 @@ import (
 	"sync"
 	"testing"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
@@ -96,7 +97,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 	It("should default to the clusters security groups", func() {
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test1"),
 				GroupName: aws.String("securityGroup-test1"),
@@ -118,7 +119,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}})
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("test-sg-1"),
 				GroupName: aws.String("test-sgName-1"),
@@ -140,7 +141,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test1"),
 				GroupName: aws.String("securityGroup-test1"),
@@ -159,7 +160,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test1"),
 				GroupName: aws.String("securityGroup-test1"),
@@ -177,7 +178,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test1"),
 				GroupName: aws.String("securityGroup-test1"),
@@ -201,7 +202,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test1"),
 				GroupName: aws.String("securityGroup-test1"),
@@ -221,7 +222,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test2"),
 				GroupName: aws.String("securityGroup-test2"),
@@ -239,7 +240,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test2"),
 				GroupName: aws.String("securityGroup-test2"),
@@ -259,7 +260,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 		}
 		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
 		Expect(err).To(BeNil())
-		ExpectConsistsOfSecurityGroups([]*ec2.SecurityGroup{
+		ExpectConsistsOfSecurityGroups([]ec2types.SecurityGroup{
 			{
 				GroupId:   aws.String("sg-test3"),
 				GroupName: aws.String("securityGroup-test3"),
@@ -281,9 +282,11 @@ var _ = Describe("SecurityGroupProvider", func() {
 			}
 
 			for _, cachedObject := range awsEnv.SecurityGroupCache.Items() {
-				cachedSecurityGroup := cachedObject.Object.([]*ec2.SecurityGroup)
+				cachedSecurityGroup := cachedObject.Object.([]ec2types.SecurityGroup)
 				Expect(cachedSecurityGroup).To(HaveLen(1))
-				lo.Contains(expectedSecurityGroups, cachedSecurityGroup[0])
+				Expect(lo.ContainsBy(expectedSecurityGroups, func(sg *ec2.SecurityGroup) bool {
+					return lo.FromPtr(sg.GroupId) == lo.FromPtr(cachedSecurityGroup[0].GroupId)
+				})).To(BeTrue())
 			}
 		})
 		It("should resolve security groups from cache that are filtered by Name", func() {
@@ -300,9 +303,11 @@ var _ = Describe("SecurityGroupProvider", func() {
 			}
 
 			for _, cachedObject := range awsEnv.SecurityGroupCache.Items() {
-				cachedSecurityGroup := cachedObject.Object.([]*ec2.SecurityGroup)
+				cachedSecurityGroup := cachedObject.Object.([]ec2types.SecurityGroup)
 				Expect(cachedSecurityGroup).To(HaveLen(1))
-				lo.Contains(expectedSecurityGroups, cachedSecurityGroup[0])
+				Expect(lo.ContainsBy(expectedSecurityGroups, func(sg *ec2.SecurityGroup) bool {
+					return lo.FromPtr(sg.GroupId) == lo.FromPtr(cachedSecurityGroup[0].GroupId)
+				})).To(BeTrue())
 			}
 		})
 		It("should resolve security groups from cache that are filtered by tags", func() {
@@ -325,12 +330,28 @@ var _ = Describe("SecurityGroupProvider", func() {
 			}
 
 			for _, cachedObject := range awsEnv.SubnetCache.Items() {
-				cachedSecurityGroup := cachedObject.Object.([]*ec2.SecurityGroup)
+				cachedSecurityGroup := cachedObject.Object.([]ec2types.SecurityGroup)
 				Expect(cachedSecurityGroup).To(HaveLen(1))
-				lo.Contains(expectedSecurityGroups, cachedSecurityGroup[0])
+				Expect(lo.ContainsBy(expectedSecurityGroups, func(sg *ec2.SecurityGroup) bool {
+					return lo.FromPtr(sg.GroupId) == lo.FromPtr(cachedSecurityGroup[0].GroupId)
+				})).To(BeTrue())
 			}
 		})
 	})
+	It("should describe security groups once for NodeClasses that share a selector and are listed concurrently", func() {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(nodeClass *v1beta1.EC2NodeClass) {
+				defer wg.Done()
+				defer GinkgoRecover()
+				_, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+			}(test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: nodeClass.Spec}))
+		}
+		wg.Wait()
+		Expect(awsEnv.EC2API.CalledWithDescribeSecurityGroupsInput.Len()).To(Equal(1))
+	})
 	It("should not cause data races when calling List() simultaneously", func() {
 		wg := sync.WaitGroup{}
 		for i := 0; i < 10000; i++ {
@@ -346,11 +367,11 @@ var _ = Describe("SecurityGroupProvider", func() {
 				sort.Slice(securityGroups, func(i, j int) bool {
 					return *securityGroups[i].GroupId < *securityGroups[j].GroupId
 				})
-				Expect(securityGroups).To(BeEquivalentTo([]*ec2.SecurityGroup{
+				Expect(securityGroups).To(BeEquivalentTo([]ec2types.SecurityGroup{
 					{
 						GroupId:   lo.ToPtr("sg-test1"),
 						GroupName: lo.ToPtr("securityGroup-test1"),
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-security-group-1"),
@@ -364,7 +385,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 					{
 						GroupId:   lo.ToPtr("sg-test2"),
 						GroupName: lo.ToPtr("securityGroup-test2"),
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-security-group-2"),
@@ -378,7 +399,7 @@ var _ = Describe("SecurityGroupProvider", func() {
 					{
 						GroupId:   lo.ToPtr("sg-test3"),
 						GroupName: lo.ToPtr("securityGroup-test3"),
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-security-group-3"),
@@ -399,11 +420,11 @@ var _ = Describe("SecurityGroupProvider", func() {
 	})
 })
 
-func ExpectConsistsOfSecurityGroups(expected, actual []*ec2.SecurityGroup) {
+func ExpectConsistsOfSecurityGroups(expected, actual []ec2types.SecurityGroup) {
 	GinkgoHelper()
 	Expect(actual).To(HaveLen(len(expected)))
 	for _, elem := range expected {
-		_, ok := lo.Find(actual, func(s *ec2.SecurityGroup) bool {
+		_, ok := lo.Find(actual, func(s ec2types.SecurityGroup) bool {
 			return lo.FromPtr(s.GroupId) == lo.FromPtr(elem.GroupId) &&
 				lo.FromPtr(s.GroupName) == lo.FromPtr(elem.GroupName)
 		})
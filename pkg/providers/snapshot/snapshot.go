@@ -0,0 +1,103 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Snapshot holds the instance types, offerings and prices of a region at a point in time. Clusters that can't reach
+// the EC2 and pricing APIs, e.g. in isolated VPCs, load a snapshot instead of discovering this data at runtime.
+type Snapshot struct {
+	Region    string    `json:"region"`
+	CreatedAt time.Time `json:"createdAt"`
+	// InstanceTypes are the instance types returned by DescribeInstanceTypes
+	InstanceTypes []*ec2.InstanceTypeInfo `json:"instanceTypes"`
+	// Offerings are the zones that each instance type is offered in
+	Offerings map[string][]string `json:"offerings"`
+	// OnDemandPrices are the hourly on-demand prices of each instance type
+	OnDemandPrices map[string]float64 `json:"onDemandPrices"`
+	// ZonalOnDemandPrices are the hourly on-demand prices of each instance type in the Local Zones and Wavelength Zones
+	// whose prices differ from the region, keyed by zone
+	ZonalOnDemandPrices map[string]map[string]float64 `json:"zonalOnDemandPrices,omitempty"`
+	// SpotPrices are the latest hourly spot prices of each instance type in each zone
+	SpotPrices map[string]map[string]float64 `json:"spotPrices,omitempty"`
+}
+
+// Load reads a snapshot from a file, which may be gzip compressed so that it fits into a ConfigMap
+func Load(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening snapshot, %w", err)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read decodes a snapshot, decompressing it if it's gzip compressed
+func Read(r io.Reader) (*Snapshot, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing snapshot, %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	} else {
+		r = br
+	}
+	s := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("decoding snapshot, %w", err)
+	}
+	if len(s.InstanceTypes) == 0 {
+		return nil, fmt.Errorf("snapshot doesn't contain any instance types")
+	}
+	return s, nil
+}
+
+// Save writes the snapshot to a file, compressing it if the path ends in .gz
+func (s *Snapshot) Save(path string) error {
+	var buf bytes.Buffer
+	var w io.WriteCloser = nopCloser{&buf}
+	if strings.HasSuffix(path, ".gz") {
+		w = gzip.NewWriter(&buf)
+	}
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("encoding snapshot, %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("compressing snapshot, %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing snapshot, %w", err)
+	}
+	return nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
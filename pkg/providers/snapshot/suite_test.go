@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/snapshot"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSnapshot(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Snapshot")
+}
+
+var _ = Describe("Snapshot", func() {
+	var s *snapshot.Snapshot
+	BeforeEach(func() {
+		s = &snapshot.Snapshot{
+			Region:         "us-west-2",
+			CreatedAt:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+			InstanceTypes:  []*ec2.InstanceTypeInfo{{InstanceType: aws.String("m5.large"), VCpuInfo: &ec2.VCpuInfo{DefaultVCpus: aws.Int64(2)}}},
+			Offerings:      map[string][]string{"m5.large": {"us-west-2a", "us-west-2b"}},
+			OnDemandPrices: map[string]float64{"m5.large": 0.096},
+			SpotPrices:     map[string]map[string]float64{"m5.large": {"us-west-2a": 0.035}},
+		}
+	})
+	DescribeTable("should round-trip snapshots", func(name string) {
+		path := filepath.Join(GinkgoT().TempDir(), name)
+		Expect(s.Save(path)).To(Succeed())
+		loaded, err := snapshot.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(Equal(s))
+	},
+		Entry("uncompressed", "snapshot.json"),
+		Entry("compressed", "snapshot.json.gz"),
+	)
+	It("should fail to load snapshots without instance types", func() {
+		s.InstanceTypes = nil
+		path := filepath.Join(GinkgoT().TempDir(), "snapshot.json")
+		Expect(s.Save(path)).To(Succeed())
+		_, err := snapshot.Load(path)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail to load snapshots that aren't JSON", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "snapshot.json")
+		Expect(os.WriteFile(path, []byte("not-json"), 0600)).To(Succeed())
+		_, err := snapshot.Load(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
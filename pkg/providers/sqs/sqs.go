@@ -27,9 +27,12 @@ import (
 
 type Provider interface {
 	Name() string
+	GetQueueARN(context.Context) (string, error)
 	GetSQSMessages(context.Context) ([]*sqs.Message, error)
 	SendMessage(context.Context, interface{}) (string, error)
+	SendSQSMessage(context.Context, *sqs.Message) error
 	DeleteSQSMessage(context.Context, *sqs.Message) error
+	ReleaseSQSMessage(context.Context, *sqs.Message) error
 }
 
 type DefaultProvider struct {
@@ -50,6 +53,18 @@ func (p *DefaultProvider) Name() string {
 	return ss[len(ss)-1]
 }
 
+// GetQueueARN returns the ARN of the queue, which is the target of the EventBridge rules that route events to it
+func (p *DefaultProvider) GetQueueARN(ctx context.Context) (string, error) {
+	out, err := p.client.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameQueueArn)},
+		QueueUrl:       aws.String(p.queueURL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting sqs queue attributes, %w", err)
+	}
+	return aws.StringValue(out.Attributes[sqs.QueueAttributeNameQueueArn]), nil
+}
+
 func (p *DefaultProvider) GetSQSMessages(ctx context.Context) ([]*sqs.Message, error) {
 	input := &sqs.ReceiveMessageInput{
 		MaxNumberOfMessages: aws.Int64(10),
@@ -57,6 +72,7 @@ func (p *DefaultProvider) GetSQSMessages(ctx context.Context) ([]*sqs.Message, e
 		WaitTimeSeconds:     aws.Int64(20), // Seconds, maximum for long polling
 		AttributeNames: []*string{
 			aws.String(sqs.MessageSystemAttributeNameSentTimestamp),
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
 		},
 		MessageAttributeNames: []*string{
 			aws.String(sqs.QueueAttributeNameAll),
@@ -88,6 +104,19 @@ func (p *DefaultProvider) SendMessage(ctx context.Context, body interface{}) (st
 	return aws.StringValue(result.MessageId), nil
 }
 
+// SendSQSMessage sends a message that was received from another queue, with its body and attributes unchanged
+func (p *DefaultProvider) SendSQSMessage(ctx context.Context, msg *sqs.Message) error {
+	input := &sqs.SendMessageInput{
+		MessageBody:       msg.Body,
+		MessageAttributes: msg.MessageAttributes,
+		QueueUrl:          aws.String(p.queueURL),
+	}
+	if _, err := p.client.SendMessageWithContext(ctx, input); err != nil {
+		return fmt.Errorf("sending message to sqs queue, %w", err)
+	}
+	return nil
+}
+
 func (p *DefaultProvider) DeleteSQSMessage(ctx context.Context, msg *sqs.Message) error {
 	input := &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(p.queueURL),
@@ -99,3 +128,18 @@ func (p *DefaultProvider) DeleteSQSMessage(ctx context.Context, msg *sqs.Message
 	}
 	return nil
 }
+
+// ReleaseSQSMessage makes the message immediately visible to other consumers of the queue rather than waiting for the
+// visibility timeout to expire
+func (p *DefaultProvider) ReleaseSQSMessage(ctx context.Context, msg *sqs.Message) error {
+	input := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(p.queueURL),
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: aws.Int64(0),
+	}
+
+	if _, err := p.client.ChangeMessageVisibilityWithContext(ctx, input); err != nil {
+		return fmt.Errorf("changing message visibility in sqs queue, %w", err)
+	}
+	return nil
+}
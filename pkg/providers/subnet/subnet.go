@@ -17,19 +17,22 @@ package subnet
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"net/http"
 	"sync"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -39,29 +42,47 @@ import (
 
 type Provider interface {
 	LivenessProbe(*http.Request) error
-	List(context.Context, *v1beta1.EC2NodeClass) ([]*ec2.Subnet, error)
-	ZonalSubnetsForLaunch(context.Context, *v1beta1.EC2NodeClass, []*cloudprovider.InstanceType, string) (map[string]*Subnet, error)
+	List(context.Context, *v1beta1.EC2NodeClass) ([]ec2types.Subnet, error)
+	ZoneTypes(context.Context) (map[string]string, error)
+	ZonalSubnetsForLaunch(context.Context, *v1beta1.EC2NodeClass, string, []*cloudprovider.InstanceType, string) (map[string]*Subnet, error)
 	UpdateInflightIPs(*ec2.CreateFleetInput, *ec2.CreateFleetOutput, []*cloudprovider.InstanceType, []*Subnet, string)
 }
 
+// EC2API is the part of the aws-sdk-go-v2 EC2 client that the provider describes subnets and zones with
+type EC2API interface {
+	DescribeSubnets(context.Context, *ec2.DescribeSubnetsInput, ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+	DescribeAvailabilityZones(context.Context, *ec2.DescribeAvailabilityZonesInput, ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error)
+}
+
+// zoneTypesCacheKey is the key that the zone types are cached under, which can't collide with the hashes of the subnet
+// selector terms
+const zoneTypesCacheKey = "zone-types"
+
 type DefaultProvider struct {
 	sync.Mutex
-	ec2api                        ec2iface.EC2API
+	// group deduplicates concurrent describes of the same subnet selector, so NodeClasses that share it only describe
+	// the subnets once between them
+	group                         singleflight.Group
+	ec2api                        EC2API
 	cache                         *cache.Cache
 	availableIPAddressCache       *cache.Cache
 	associatePublicIPAddressCache *cache.Cache
+	vpcCNI                        *awscache.VPCCNI
 	cm                            *pretty.ChangeMonitor
 	inflightIPs                   map[string]int64
+	launches                      map[string]int64
 }
 
 type Subnet struct {
 	ID                      string
 	Zone                    string
 	ZoneID                  string
+	OutpostARN              string
 	AvailableIPAddressCount int64
 }
 
-func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache, availableIPAddressCache *cache.Cache, associatePublicIPAddressCache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(ec2api EC2API, cache *cache.Cache, availableIPAddressCache *cache.Cache, associatePublicIPAddressCache *cache.Cache,
+	vpcCNI *awscache.VPCCNI) *DefaultProvider {
 	return &DefaultProvider{
 		ec2api: ec2api,
 		cm:     pretty.NewChangeMonitor(),
@@ -70,48 +91,43 @@ func NewDefaultProvider(ec2api ec2iface.EC2API, cache *cache.Cache, availableIPA
 		cache:                         cache,
 		availableIPAddressCache:       availableIPAddressCache,
 		associatePublicIPAddressCache: associatePublicIPAddressCache,
+		vpcCNI:                        vpcCNI,
 		// inflightIPs is used to track IPs from known launched instances
 		inflightIPs: map[string]int64{},
+		// launches is used to spread launches across the subnets of a zone with the Balanced policy
+		launches: map[string]int64{},
 	}
 }
 
-func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) ([]*ec2.Subnet, error) {
-	p.Lock()
-	defer p.Unlock()
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeClass) ([]ec2types.Subnet, error) {
 	filterSets := getFilterSets(nodeClass.Spec.SubnetSelectorTerms)
 	if len(filterSets) == 0 {
-		return []*ec2.Subnet{}, nil
+		return []ec2types.Subnet{}, nil
 	}
 	hash, err := hashstructure.Hash(filterSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
 		return nil, err
 	}
-	if subnets, ok := p.cache.Get(fmt.Sprint(hash)); ok {
+	key := fmt.Sprint(hash)
+	if subnets, ok := p.cache.Get(key); ok {
 		// Ensure what's returned from this function is a shallow-copy of the slice (not a deep-copy of the data itself)
 		// so that modifications to the ordering of the data don't affect the original
-		return append([]*ec2.Subnet{}, subnets.([]*ec2.Subnet)...), nil
+		return append([]ec2types.Subnet{}, subnets.([]ec2types.Subnet)...), nil
 	}
-
-	// Ensure that all the subnets that are returned here are unique
-	subnets := map[string]*ec2.Subnet{}
-	for _, filters := range filterSets {
-		output, err := p.ec2api.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{Filters: filters})
-		if err != nil {
-			return nil, fmt.Errorf("describing subnets %s, %w", pretty.Concise(filters), err)
-		}
-		for i := range output.Subnets {
-			subnets[lo.FromPtr(output.Subnets[i].SubnetId)] = output.Subnets[i]
-			p.availableIPAddressCache.SetDefault(lo.FromPtr(output.Subnets[i].SubnetId), lo.FromPtr(output.Subnets[i].AvailableIpAddressCount))
-			p.associatePublicIPAddressCache.SetDefault(lo.FromPtr(output.Subnets[i].SubnetId), lo.FromPtr(output.Subnets[i].MapPublicIpOnLaunch))
-			// subnets can be leaked here, if a subnets is never called received from ec2
-			// we are accepting it for now, as this will be an insignificant amount of memory
-			delete(p.inflightIPs, lo.FromPtr(output.Subnets[i].SubnetId)) // remove any previously tracked IP addresses since we just refreshed from EC2
+	result, err, _ := p.group.Do(key, func() (interface{}, error) {
+		// The subnets may have been cached by a describe that finished since the cache was checked
+		if subnets, ok := p.cache.Get(key); ok {
+			return subnets, nil
 		}
+		return p.describe(ctx, key, filterSets)
+	})
+	if err != nil {
+		return nil, err
 	}
-	p.cache.SetDefault(fmt.Sprint(hash), lo.Values(subnets))
+	subnets := result.([]ec2types.Subnet)
 	if p.cm.HasChanged(fmt.Sprintf("subnets/%s", nodeClass.Name), subnets) {
 		log.FromContext(ctx).
-			WithValues("subnets", lo.Map(lo.Values(subnets), func(s *ec2.Subnet, _ int) v1beta1.Subnet {
+			WithValues("subnets", lo.Map(subnets, func(s ec2types.Subnet, _ int) v1beta1.Subnet {
 				return v1beta1.Subnet{
 					ID:     lo.FromPtr(s.SubnetId),
 					Zone:   lo.FromPtr(s.AvailabilityZone),
@@ -119,11 +135,63 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1beta1.EC2NodeCl
 				}
 			})).V(1).Info("discovered subnets")
 	}
+	// The result is shared between the callers that the describe was deduplicated for
+	return append([]ec2types.Subnet{}, subnets...), nil
+}
+
+// describe describes the subnets that match the filter sets and caches them under the key. Only the tracking of the
+// subnets' IP addresses is locked, so that describing subnets for one selector doesn't block another.
+func (p *DefaultProvider) describe(ctx context.Context, key string, filterSets [][]ec2types.Filter) ([]ec2types.Subnet, error) {
+	// Ensure that all the subnets that are returned here are unique
+	subnets := map[string]ec2types.Subnet{}
+	for _, filters := range filterSets {
+		output, err := p.ec2api.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: filters})
+		if err != nil {
+			return nil, fmt.Errorf("describing subnets %s, %w", pretty.Concise(filters), err)
+		}
+		for i := range output.Subnets {
+			subnets[lo.FromPtr(output.Subnets[i].SubnetId)] = output.Subnets[i]
+		}
+	}
+	p.Lock()
+	defer p.Unlock()
+	for id, subnet := range subnets {
+		p.availableIPAddressCache.SetDefault(id, int64(lo.FromPtr(subnet.AvailableIpAddressCount)))
+		p.associatePublicIPAddressCache.SetDefault(id, lo.FromPtr(subnet.MapPublicIpOnLaunch))
+		// subnets can be leaked here, if a subnets is never called received from ec2
+		// we are accepting it for now, as this will be an insignificant amount of memory
+		delete(p.inflightIPs, id) // remove any previously tracked IP addresses since we just refreshed from EC2
+	}
+	p.cache.SetDefault(key, lo.Values(subnets))
 	return lo.Values(subnets), nil
 }
 
-// ZonalSubnetsForLaunch returns a mapping of zone to the subnet with the most available IP addresses and deducts the passed ips from the available count
-func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, instanceTypes []*cloudprovider.InstanceType, capacityType string) (map[string]*Subnet, error) {
+// ZoneTypes returns the type of each zone of the region that the account is opted in to, keyed by zone name. The type
+// is one of availability-zone, local-zone, or wavelength-zone. Local Zones and Wavelength Zones have to be opted in to
+// before they can be launched in, so the zones that the account isn't opted in to are left out.
+func (p *DefaultProvider) ZoneTypes(ctx context.Context) (map[string]string, error) {
+	if zoneTypes, ok := p.cache.Get(zoneTypesCacheKey); ok {
+		return zoneTypes.(map[string]string), nil
+	}
+	output, err := p.ec2api.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		Filters: []ec2types.Filter{{
+			Name:   aws.String("opt-in-status"),
+			Values: []string{string(ec2types.AvailabilityZoneOptInStatusOptInNotRequired), string(ec2types.AvailabilityZoneOptInStatusOptedIn)},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing availability zones, %w", err)
+	}
+	zoneTypes := lo.SliceToMap(output.AvailabilityZones, func(az ec2types.AvailabilityZone) (string, string) {
+		return aws.ToString(az.ZoneName), aws.ToString(az.ZoneType)
+	})
+	p.cache.SetDefault(zoneTypesCacheKey, zoneTypes)
+	return zoneTypes, nil
+}
+
+// ZonalSubnetsForLaunch returns a mapping of zone to the subnet chosen by the subnet selection policy of the EC2NodeClass,
+// the subnet with the most available IP addresses by default, and deducts the passed ips from the available count
+func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *v1beta1.EC2NodeClass, nodePoolName string, instanceTypes []*cloudprovider.InstanceType, capacityType string) (map[string]*Subnet, error) {
 	if len(nodeClass.Status.Subnets) == 0 {
 		return nil, fmt.Errorf("no subnets matched selector %v", nodeClass.Spec.SubnetSelectorTerms)
 	}
@@ -139,26 +207,20 @@ func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *
 		}
 	}
 
+	policy := lo.FromPtrOr(nodeClass.Spec.SubnetSelectionPolicy, v1beta1.SubnetSelectionPolicyMostAvailableIPs)
 	for _, subnet := range nodeClass.Status.Subnets {
-		if v, ok := zonalSubnets[subnet.Zone]; ok {
-			currentZonalSubnetIPAddressCount := v.AvailableIPAddressCount
-			newZonalSubnetIPAddressCount := availableIPAddressCount[subnet.ID]
-			if ips, ok := p.inflightIPs[v.ID]; ok {
-				currentZonalSubnetIPAddressCount = ips
-			}
-			if ips, ok := p.inflightIPs[subnet.ID]; ok {
-				newZonalSubnetIPAddressCount = ips
-			}
-
-			if currentZonalSubnetIPAddressCount >= newZonalSubnetIPAddressCount {
-				continue
-			}
+		// The offerings of a zone are on the Outpost of its first subnet, so subnets that aren't are never launched in
+		if subnet.OutpostARN != ZonalSubnet(nodeClass.Status.Subnets, subnet.Zone).OutpostARN {
+			continue
 		}
-		zonalSubnets[subnet.Zone] = &Subnet{ID: subnet.ID, Zone: subnet.Zone, ZoneID: subnet.ZoneID, AvailableIPAddressCount: availableIPAddressCount[subnet.ID]}
+		if v, ok := zonalSubnets[subnet.Zone]; ok && !p.preferred(policy, nodePoolName, subnet.ID, v.ID, availableIPAddressCount) {
+			continue
+		}
+		zonalSubnets[subnet.Zone] = &Subnet{ID: subnet.ID, Zone: subnet.Zone, ZoneID: subnet.ZoneID, OutpostARN: subnet.OutpostARN, AvailableIPAddressCount: availableIPAddressCount[subnet.ID]}
 	}
 
 	for _, subnet := range zonalSubnets {
-		predictedIPsUsed := p.minPods(instanceTypes, scheduling.NewRequirements(
+		predictedIPsUsed := p.predictedIPs(instanceTypes, scheduling.NewRequirements(
 			scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, capacityType),
 			scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, subnet.Zone),
 		))
@@ -167,10 +229,55 @@ func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *
 			prevIPs = trackedIPs
 		}
 		p.inflightIPs[subnet.ID] = prevIPs - predictedIPsUsed
+		if policy == v1beta1.SubnetSelectionPolicyBalanced {
+			p.launches[subnet.ID]++
+		}
 	}
 	return zonalSubnets, nil
 }
 
+// ZonalSubnet returns the first of the subnets that is in the zone. The offerings of the zone are on the Outpost of
+// this subnet, or in the region if it isn't on an Outpost, so EC2NodeClasses that select both Outpost and regional
+// subnets of a zone only launch in the ones that match the first.
+func ZonalSubnet(subnets []v1beta1.Subnet, zone string) v1beta1.Subnet {
+	subnet, _ := lo.Find(subnets, func(s v1beta1.Subnet) bool {
+		return s.Zone == zone
+	})
+	return subnet
+}
+
+// preferred returns true if the candidate subnet should be chosen over the current subnet of the same zone
+func (p *DefaultProvider) preferred(policy v1beta1.SubnetSelectionPolicy, nodePoolName, candidate, current string, availableIPAddressCount map[string]int64) bool {
+	remaining := func(id string) int64 {
+		if ips, ok := p.inflightIPs[id]; ok {
+			return ips
+		}
+		return availableIPAddressCount[id]
+	}
+	switch policy {
+	case v1beta1.SubnetSelectionPolicyBalanced:
+		// Subnets that have run out of IP addresses are only used when every subnet in the zone has
+		if (remaining(candidate) > 0) != (remaining(current) > 0) {
+			return remaining(candidate) > 0
+		}
+		if p.launches[candidate] != p.launches[current] {
+			return p.launches[candidate] < p.launches[current]
+		}
+	case v1beta1.SubnetSelectionPolicyDedicatedPerNodePool:
+		// Rendezvous hashing keeps each NodePool in the same subnet as other subnets are added to or removed from the zone
+		if nodePoolName != "" {
+			return rendezvousHash(nodePoolName, candidate) > rendezvousHash(nodePoolName, current)
+		}
+	}
+	return remaining(candidate) > remaining(current)
+}
+
+func rendezvousHash(nodePoolName, subnetID string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(nodePoolName + "/" + subnetID))
+	return h.Sum64()
+}
+
 // UpdateInflightIPs is used to refresh the in-memory IP usage by adding back unused IPs after a CreateFleet response is returned
 func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInput, createFleetOutput *ec2.CreateFleetOutput, instanceTypes []*cloudprovider.InstanceType,
 	subnets []*Subnet, capacityType string) {
@@ -178,11 +285,8 @@ func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInp
 	defer p.Unlock()
 
 	// Process the CreateFleetInput to pull out all the requested subnetIDs
-	fleetInputSubnets := lo.Compact(lo.Uniq(lo.FlatMap(createFleetInput.LaunchTemplateConfigs, func(req *ec2.FleetLaunchTemplateConfigRequest, _ int) []string {
-		return lo.Map(req.Overrides, func(override *ec2.FleetLaunchTemplateOverridesRequest, _ int) string {
-			if override == nil {
-				return ""
-			}
+	fleetInputSubnets := lo.Compact(lo.Uniq(lo.FlatMap(createFleetInput.LaunchTemplateConfigs, func(req ec2types.FleetLaunchTemplateConfigRequest, _ int) []string {
+		return lo.Map(req.Overrides, func(override ec2types.FleetLaunchTemplateOverridesRequest, _ int) string {
 			return lo.FromPtr(override.SubnetId)
 		})
 	})))
@@ -190,8 +294,8 @@ func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInp
 	// Process the CreateFleetOutput to pull out all the fulfilled subnetIDs
 	var fleetOutputSubnets []string
 	if createFleetOutput != nil {
-		fleetOutputSubnets = lo.Compact(lo.Uniq(lo.Map(createFleetOutput.Instances, func(fleetInstance *ec2.CreateFleetInstance, _ int) string {
-			if fleetInstance == nil || fleetInstance.LaunchTemplateAndOverrides == nil || fleetInstance.LaunchTemplateAndOverrides.Overrides == nil {
+		fleetOutputSubnets = lo.Compact(lo.Uniq(lo.Map(createFleetOutput.Instances, func(fleetInstance ec2types.CreateFleetInstance, _ int) string {
+			if fleetInstance.LaunchTemplateAndOverrides == nil || fleetInstance.LaunchTemplateAndOverrides.Overrides == nil {
 				return ""
 			}
 			return lo.FromPtr(fleetInstance.LaunchTemplateAndOverrides.Overrides.SubnetId)
@@ -200,6 +304,11 @@ func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInp
 
 	// Find the subnets that were included in the input but not chosen by Fleet, so we need to add the inflight IPs back to them
 	subnetIDsToAddBackIPs, _ := lo.Difference(fleetInputSubnets, fleetOutputSubnets)
+	for _, id := range subnetIDsToAddBackIPs {
+		if p.launches[id] > 0 {
+			p.launches[id]--
+		}
+	}
 
 	// Aggregate all the cached subnets ip address count
 	cachedAvailableIPAddressMap := lo.MapEntries(p.availableIPAddressCache.Items(), func(k string, v cache.Item) (string, int64) {
@@ -223,11 +332,11 @@ func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInp
 		if originalSubnet.AvailableIPAddressCount == cachedIPAddressCount {
 			// other IPs deducted were opportunistic and need to be readded since Fleet didn't pick those subnets to launch into
 			if ips, ok := p.inflightIPs[originalSubnet.ID]; ok {
-				minPods := p.minPods(instanceTypes, scheduling.NewRequirements(
+				predictedIPsUsed := p.predictedIPs(instanceTypes, scheduling.NewRequirements(
 					scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, capacityType),
 					scheduling.NewRequirement(v1.LabelTopologyZone, v1.NodeSelectorOpIn, originalSubnet.Zone),
 				))
-				p.inflightIPs[originalSubnet.ID] = ips + minPods
+				p.inflightIPs[originalSubnet.ID] = ips + predictedIPsUsed
 			}
 		}
 	}
@@ -240,6 +349,17 @@ func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	return nil
 }
 
+// predictedIPs returns the IPs that a launch is projected to take from its subnet: one for each pod of the smallest
+// compatible instance type, plus the IPs that the VPC CNI keeps warm on the node
+func (p *DefaultProvider) predictedIPs(instanceTypes []*cloudprovider.InstanceType, reqs scheduling.Requirements) int64 {
+	pods := p.minPods(instanceTypes, reqs)
+	warmTargets, ok := p.vpcCNI.WarmTargets()
+	if !ok || pods == 0 || warmTargets.PrefixDelegation {
+		return pods
+	}
+	return lo.Max([]int64{pods + warmTargets.WarmIPTarget, warmTargets.MinimumIPTarget})
+}
+
 func (p *DefaultProvider) minPods(instanceTypes []*cloudprovider.InstanceType, reqs scheduling.Requirements) int64 {
 	// filter for instance types available in the zone and capacity type being requested
 	filteredInstanceTypes := lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
@@ -255,32 +375,38 @@ func (p *DefaultProvider) minPods(instanceTypes []*cloudprovider.InstanceType, r
 	return pods
 }
 
-func getFilterSets(terms []v1beta1.SubnetSelectorTerm) (res [][]*ec2.Filter) {
-	idFilter := &ec2.Filter{Name: aws.String("subnet-id")}
+func getFilterSets(terms []v1beta1.SubnetSelectorTerm) (res [][]ec2types.Filter) {
+	idFilter := ec2types.Filter{Name: aws.String("subnet-id")}
 	for _, term := range terms {
 		switch {
 		case term.ID != "":
-			idFilter.Values = append(idFilter.Values, aws.String(term.ID))
+			idFilter.Values = append(idFilter.Values, term.ID)
 		default:
-			var filters []*ec2.Filter
+			var filters []ec2types.Filter
 			for k, v := range term.Tags {
 				if v == "*" {
-					filters = append(filters, &ec2.Filter{
+					filters = append(filters, ec2types.Filter{
 						Name:   aws.String("tag-key"),
-						Values: []*string{aws.String(k)},
+						Values: []string{k},
 					})
 				} else {
-					filters = append(filters, &ec2.Filter{
+					filters = append(filters, ec2types.Filter{
 						Name:   aws.String(fmt.Sprintf("tag:%s", k)),
-						Values: []*string{aws.String(v)},
+						Values: []string{v},
 					})
 				}
 			}
+			if term.ZoneID != "" {
+				filters = append(filters, ec2types.Filter{
+					Name:   aws.String("availability-zone-id"),
+					Values: []string{term.ZoneID},
+				})
+			}
 			res = append(res, filters)
 		}
 	}
 	if len(idFilter.Values) > 0 {
-		res = append(res, []*ec2.Filter{idFilter})
+		res = append(res, []ec2types.Filter{idFilter})
 	}
 	return res
 }
@@ -20,6 +20,7 @@ import (
 	"sync"
 	"testing"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
@@ -102,12 +103,12 @@ var _ = Describe("SubnetProvider", func() {
 			}
 			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
 			Expect(err).To(BeNil())
-			ExpectConsistsOfSubnets([]*ec2.Subnet{
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
 				{
 					SubnetId:                lo.ToPtr("subnet-test1"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1a"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1a"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 			}, subnets)
 		})
@@ -122,18 +123,18 @@ var _ = Describe("SubnetProvider", func() {
 			}
 			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
 			Expect(err).To(BeNil())
-			ExpectConsistsOfSubnets([]*ec2.Subnet{
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
 				{
 					SubnetId:                lo.ToPtr("subnet-test1"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1a"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1a"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 				{
 					SubnetId:                lo.ToPtr("subnet-test2"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1b"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1b"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 			}, subnets)
 		})
@@ -150,18 +151,18 @@ var _ = Describe("SubnetProvider", func() {
 			}
 			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
 			Expect(err).To(BeNil())
-			ExpectConsistsOfSubnets([]*ec2.Subnet{
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
 				{
 					SubnetId:                lo.ToPtr("subnet-test1"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1a"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1a"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 				{
 					SubnetId:                lo.ToPtr("subnet-test2"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1b"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1b"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 			}, subnets)
 		})
@@ -173,12 +174,12 @@ var _ = Describe("SubnetProvider", func() {
 			}
 			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
 			Expect(err).To(BeNil())
-			ExpectConsistsOfSubnets([]*ec2.Subnet{
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
 				{
 					SubnetId:                lo.ToPtr("subnet-test1"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1a"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1a"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 			}, subnets)
 		})
@@ -193,18 +194,18 @@ var _ = Describe("SubnetProvider", func() {
 			}
 			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
 			Expect(err).To(BeNil())
-			ExpectConsistsOfSubnets([]*ec2.Subnet{
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
 				{
 					SubnetId:                lo.ToPtr("subnet-test1"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1a"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1a"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 				{
 					SubnetId:                lo.ToPtr("subnet-test2"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1b"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1b"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 			}, subnets)
 		})
@@ -217,12 +218,47 @@ var _ = Describe("SubnetProvider", func() {
 			}
 			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
 			Expect(err).To(BeNil())
-			ExpectConsistsOfSubnets([]*ec2.Subnet{
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
 				{
 					SubnetId:                lo.ToPtr("subnet-test2"),
 					AvailabilityZone:        lo.ToPtr("test-zone-1b"),
 					AvailabilityZoneId:      lo.ToPtr("tstz1-1b"),
-					AvailableIpAddressCount: lo.ToPtr[int64](100),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
+				},
+			}, subnets)
+		})
+		It("should discover subnets by zone ID", func() {
+			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{
+				{
+					ZoneID: "tstz1-1b",
+				},
+			}
+			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
+				{
+					SubnetId:                lo.ToPtr("subnet-test2"),
+					AvailabilityZone:        lo.ToPtr("test-zone-1b"),
+					AvailabilityZoneId:      lo.ToPtr("tstz1-1b"),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
+				},
+			}, subnets)
+		})
+		It("should discover subnets by tags intersected with zone ID", func() {
+			nodeClass.Spec.SubnetSelectorTerms = []v1beta1.SubnetSelectorTerm{
+				{
+					Tags:   map[string]string{"foo": "bar"},
+					ZoneID: "tstz1-1c",
+				},
+			}
+			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			ExpectConsistsOfSubnets([]ec2types.Subnet{
+				{
+					SubnetId:                lo.ToPtr("subnet-test3"),
+					AvailabilityZone:        lo.ToPtr("test-zone-1c"),
+					AvailabilityZoneId:      lo.ToPtr("tstz1-1c"),
+					AvailableIpAddressCount: lo.ToPtr[int32](100),
 				},
 			}, subnets)
 		})
@@ -242,9 +278,11 @@ var _ = Describe("SubnetProvider", func() {
 			}
 
 			for _, cachedObject := range awsEnv.SubnetCache.Items() {
-				cachedSubnet := cachedObject.Object.([]*ec2.Subnet)
+				cachedSubnet := cachedObject.Object.([]ec2types.Subnet)
 				Expect(cachedSubnet).To(HaveLen(1))
-				lo.Contains(expectedSubnets, cachedSubnet[0])
+				Expect(lo.ContainsBy(expectedSubnets, func(s *ec2.Subnet) bool {
+					return lo.FromPtr(s.SubnetId) == lo.FromPtr(cachedSubnet[0].SubnetId)
+				})).To(BeTrue())
 			}
 		})
 		It("should resolve subnets from cache that are filtered by tags", func() {
@@ -267,12 +305,28 @@ var _ = Describe("SubnetProvider", func() {
 			}
 
 			for _, cachedObject := range awsEnv.SubnetCache.Items() {
-				cachedSubnet := cachedObject.Object.([]*ec2.Subnet)
+				cachedSubnet := cachedObject.Object.([]ec2types.Subnet)
 				Expect(cachedSubnet).To(HaveLen(1))
-				lo.Contains(expectedSubnets, cachedSubnet[0])
+				Expect(lo.ContainsBy(expectedSubnets, func(s *ec2.Subnet) bool {
+					return lo.FromPtr(s.SubnetId) == lo.FromPtr(cachedSubnet[0].SubnetId)
+				})).To(BeTrue())
 			}
 		})
 	})
+	It("should describe subnets once for NodeClasses that share a selector and are listed concurrently", func() {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(nodeClass *v1beta1.EC2NodeClass) {
+				defer wg.Done()
+				defer GinkgoRecover()
+				_, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+			}(test.EC2NodeClass(v1beta1.EC2NodeClass{Spec: nodeClass.Spec}))
+		}
+		wg.Wait()
+		Expect(awsEnv.EC2API.CalledWithDescribeSubnetsInput.Len()).To(Equal(1))
+	})
 	It("should not cause data races when calling List() simultaneously", func() {
 		wg := sync.WaitGroup{}
 		for i := 0; i < 10000; i++ {
@@ -291,14 +345,14 @@ var _ = Describe("SubnetProvider", func() {
 					}
 					return *subnets[i].SubnetId < *subnets[j].SubnetId
 				})
-				Expect(subnets).To(BeEquivalentTo([]*ec2.Subnet{
+				Expect(subnets).To(BeEquivalentTo([]ec2types.Subnet{
 					{
 						AvailabilityZone:        lo.ToPtr("test-zone-1a"),
 						AvailabilityZoneId:      lo.ToPtr("tstz1-1a"),
-						AvailableIpAddressCount: lo.ToPtr[int64](100),
+						AvailableIpAddressCount: lo.ToPtr[int32](100),
 						SubnetId:                lo.ToPtr("subnet-test1"),
 						MapPublicIpOnLaunch:     lo.ToPtr(false),
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-subnet-1"),
@@ -312,11 +366,11 @@ var _ = Describe("SubnetProvider", func() {
 					{
 						AvailabilityZone:        lo.ToPtr("test-zone-1b"),
 						AvailabilityZoneId:      lo.ToPtr("tstz1-1b"),
-						AvailableIpAddressCount: lo.ToPtr[int64](100),
+						AvailableIpAddressCount: lo.ToPtr[int32](100),
 						MapPublicIpOnLaunch:     lo.ToPtr(true),
 						SubnetId:                lo.ToPtr("subnet-test2"),
 
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-subnet-2"),
@@ -330,9 +384,9 @@ var _ = Describe("SubnetProvider", func() {
 					{
 						AvailabilityZone:        lo.ToPtr("test-zone-1c"),
 						AvailabilityZoneId:      lo.ToPtr("tstz1-1c"),
-						AvailableIpAddressCount: lo.ToPtr[int64](100),
+						AvailableIpAddressCount: lo.ToPtr[int32](100),
 						SubnetId:                lo.ToPtr("subnet-test3"),
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-subnet-3"),
@@ -349,10 +403,10 @@ var _ = Describe("SubnetProvider", func() {
 					{
 						AvailabilityZone:        lo.ToPtr("test-zone-1a-local"),
 						AvailabilityZoneId:      lo.ToPtr("tstz1-1alocal"),
-						AvailableIpAddressCount: lo.ToPtr[int64](100),
+						AvailableIpAddressCount: lo.ToPtr[int32](100),
 						SubnetId:                lo.ToPtr("subnet-test4"),
 						MapPublicIpOnLaunch:     lo.ToPtr(true),
-						Tags: []*ec2.Tag{
+						Tags: []ec2types.Tag{
 							{
 								Key:   lo.ToPtr("Name"),
 								Value: lo.ToPtr("test-subnet-4"),
@@ -366,11 +420,11 @@ var _ = Describe("SubnetProvider", func() {
 	})
 })
 
-func ExpectConsistsOfSubnets(expected, actual []*ec2.Subnet) {
+func ExpectConsistsOfSubnets(expected, actual []ec2types.Subnet) {
 	GinkgoHelper()
 	Expect(actual).To(HaveLen(len(expected)))
 	for _, elem := range expected {
-		_, ok := lo.Find(actual, func(s *ec2.Subnet) bool {
+		_, ok := lo.Find(actual, func(s ec2types.Subnet) bool {
 			return lo.FromPtr(s.SubnetId) == lo.FromPtr(elem.SubnetId) &&
 				lo.FromPtr(s.AvailabilityZoneId) == lo.FromPtr(elem.AvailabilityZoneId) &&
 				lo.FromPtr(s.AvailabilityZone) == lo.FromPtr(elem.AvailabilityZone) &&
@@ -0,0 +1,137 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package targetgroup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	targetGroupsCacheKey = "target-groups"
+	// deregistrationDelayAttribute is the target group attribute that controls how long ELB waits for in-flight requests
+	// to complete before a deregistering target is removed
+	deregistrationDelayAttribute = "deregistration_delay.timeout_seconds"
+	// defaultDeregistrationDelay is the ELB default for deregistration_delay.timeout_seconds
+	defaultDeregistrationDelay = 300 * time.Second
+)
+
+type Provider interface {
+	Deregister(context.Context, string) (time.Duration, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	elbv2api elbv2iface.ELBV2API
+	cache    *cache.Cache
+}
+
+func NewDefaultProvider(elbv2api elbv2iface.ELBV2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		elbv2api: elbv2api,
+		cache:    cache,
+	}
+}
+
+// Deregister removes the instance from every target group that it's registered with and returns the longest
+// deregistration delay across those target groups. The instance keeps serving in-flight requests until that delay has
+// elapsed, so it shouldn't be terminated before then.
+func (p *DefaultProvider) Deregister(ctx context.Context, instanceID string) (time.Duration, error) {
+	targetGroups, err := p.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var delay time.Duration
+	for _, targetGroup := range targetGroups {
+		out, err := p.elbv2api.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: targetGroup.TargetGroupArn,
+			Targets:        []*elbv2.TargetDescription{{Id: aws.String(instanceID)}},
+		})
+		if err != nil {
+			return 0, fmt.Errorf("describing target health for %s, %w", aws.StringValue(targetGroup.TargetGroupArn), err)
+		}
+		// Targets that aren't registered are still described, but with an unused state
+		targets := lo.FilterMap(out.TargetHealthDescriptions, func(d *elbv2.TargetHealthDescription, _ int) (*elbv2.TargetDescription, bool) {
+			return d.Target, d.TargetHealth != nil && aws.StringValue(d.TargetHealth.State) != elbv2.TargetHealthStateEnumUnused
+		})
+		if len(targets) == 0 {
+			continue
+		}
+		if _, err = p.elbv2api.DeregisterTargetsWithContext(ctx, &elbv2.DeregisterTargetsInput{
+			TargetGroupArn: targetGroup.TargetGroupArn,
+			Targets:        targets,
+		}); err != nil {
+			return 0, fmt.Errorf("deregistering targets from %s, %w", aws.StringValue(targetGroup.TargetGroupArn), err)
+		}
+		log.FromContext(ctx).WithValues("target-group", aws.StringValue(targetGroup.TargetGroupName)).V(1).Info("deregistered instance from target group")
+		d, err := p.deregistrationDelay(ctx, targetGroup)
+		if err != nil {
+			return 0, err
+		}
+		delay = lo.Max([]time.Duration{delay, d})
+	}
+	return delay, nil
+}
+
+// list returns the target groups that route to instances. IP target groups are excluded since they
+// route directly to pods, which are already drained from the target group by pod deletion.
+func (p *DefaultProvider) list(ctx context.Context) ([]*elbv2.TargetGroup, error) {
+	p.Lock()
+	defer p.Unlock()
+	if targetGroups, ok := p.cache.Get(targetGroupsCacheKey); ok {
+		return targetGroups.([]*elbv2.TargetGroup), nil
+	}
+	var targetGroups []*elbv2.TargetGroup
+	if err := p.elbv2api.DescribeTargetGroupsPagesWithContext(ctx, &elbv2.DescribeTargetGroupsInput{}, func(out *elbv2.DescribeTargetGroupsOutput, _ bool) bool {
+		targetGroups = append(targetGroups, lo.Filter(out.TargetGroups, func(tg *elbv2.TargetGroup, _ int) bool {
+			return aws.StringValue(tg.TargetType) == elbv2.TargetTypeEnumInstance
+		})...)
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("describing target groups, %w", err)
+	}
+	p.cache.SetDefault(targetGroupsCacheKey, targetGroups)
+	return targetGroups, nil
+}
+
+func (p *DefaultProvider) deregistrationDelay(ctx context.Context, targetGroup *elbv2.TargetGroup) (time.Duration, error) {
+	out, err := p.elbv2api.DescribeTargetGroupAttributesWithContext(ctx, &elbv2.DescribeTargetGroupAttributesInput{
+		TargetGroupArn: targetGroup.TargetGroupArn,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("describing target group attributes for %s, %w", aws.StringValue(targetGroup.TargetGroupArn), err)
+	}
+	attribute, ok := lo.Find(out.Attributes, func(a *elbv2.TargetGroupAttribute) bool {
+		return aws.StringValue(a.Key) == deregistrationDelayAttribute
+	})
+	if !ok {
+		return defaultDeregistrationDelay, nil
+	}
+	seconds, err := strconv.Atoi(aws.StringValue(attribute.Value))
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s for %s, %w", deregistrationDelayAttribute, aws.StringValue(targetGroup.TargetGroupArn), err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
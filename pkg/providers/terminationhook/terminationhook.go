@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminationhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	// Source is the source of the events that are put on the termination notification event bus
+	Source = "karpenter.k8s.aws"
+	// DetailType is the detail type of the event that's put on the termination notification event bus when an
+	// instance has been drained and is about to be terminated
+	DetailType = "Karpenter Instance Termination Pending"
+)
+
+// Detail is the detail of a termination pending event
+type Detail struct {
+	ClusterName string `json:"cluster-name"`
+	InstanceID  string `json:"instance-id"`
+	NodeClaim   string `json:"nodeclaim"`
+	NodeName    string `json:"node-name,omitempty"`
+	NodePool    string `json:"nodepool,omitempty"`
+}
+
+type Provider interface {
+	Notify(context.Context, *corev1beta1.NodeClaim, string) error
+}
+
+type DefaultProvider struct {
+	eventBridgeAPI eventbridgeiface.EventBridgeAPI
+}
+
+func NewDefaultProvider(eventBridgeAPI eventbridgeiface.EventBridgeAPI) *DefaultProvider {
+	return &DefaultProvider{
+		eventBridgeAPI: eventBridgeAPI,
+	}
+}
+
+// Notify puts an event on the termination notification event bus that the instance of the NodeClaim has been drained
+// and is about to be terminated, so that external tooling like CMDBs and security scanners can checkpoint it first.
+// Nothing is published when no event bus is configured.
+func (p *DefaultProvider) Notify(ctx context.Context, nodeClaim *corev1beta1.NodeClaim, instanceID string) error {
+	eventBus := options.FromContext(ctx).TerminationNotificationEventBus
+	if eventBus == "" {
+		return nil
+	}
+	detail, err := json.Marshal(Detail{
+		ClusterName: options.FromContext(ctx).ClusterName,
+		InstanceID:  instanceID,
+		NodeClaim:   nodeClaim.Name,
+		NodeName:    nodeClaim.Status.NodeName,
+		NodePool:    nodeClaim.Labels[corev1beta1.NodePoolLabelKey],
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling event detail, %w", err)
+	}
+	out, err := p.eventBridgeAPI.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{{
+			EventBusName: aws.String(eventBus),
+			Source:       aws.String(Source),
+			DetailType:   aws.String(DetailType),
+			Detail:       aws.String(string(detail)),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("putting event, %w", err)
+	}
+	// PutEvents succeeds when individual entries fail, and reports them in the output instead
+	if aws.Int64Value(out.FailedEntryCount) > 0 && len(out.Entries) > 0 {
+		return fmt.Errorf("putting event, %s", aws.StringValue(out.Entries[0].ErrorMessage))
+	}
+	return nil
+}
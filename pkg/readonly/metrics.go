@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readonly
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	readOnlySubsystem = "read_only"
+	actionLabel       = "action"
+	decisionLabel     = "decision"
+
+	// DecisionLaunch is a launch of an instance for a NodeClaim that wasn't performed
+	DecisionLaunch = "launch"
+	// DecisionTerminate is a termination of the instance of a NodeClaim that wasn't performed
+	DecisionTerminate = "terminate"
+)
+
+var (
+	BlockedCallsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: readOnlySubsystem,
+			Name:      "blocked_calls_total",
+			Help:      "Number of calls to mutating AWS APIs that were blocked in read-only mode, based on the IAM action of the call.",
+		},
+		[]string{
+			actionLabel,
+		},
+	)
+	DecisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: readOnlySubsystem,
+			Name:      "decisions_total",
+			Help:      "Number of launches and terminations that would have been performed outside of read-only mode, based on the decision and nodepool. Each decision is counted once per NodeClaim.",
+		},
+		[]string{
+			decisionLabel,
+			metrics.NodePoolLabel,
+		},
+	)
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(BlockedCallsTotal, DecisionsTotal)
+}
+
+// RecordDecision counts a launch or termination of the NodePool that wasn't performed in read-only mode
+func RecordDecision(decision, nodePool string) {
+	DecisionsTotal.With(prometheus.Labels{
+		decisionLabel:         decision,
+		metrics.NodePoolLabel: nodePool,
+	}).Inc()
+}
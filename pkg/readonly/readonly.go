@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package readonly keeps the controller from calling mutating AWS APIs when it runs in read-only mode, so that its
+// decisions can be evaluated against a cluster without it changing any of the cluster's AWS resources.
+package readonly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// ErrCodeReadOnly is the code of the error that calls to mutating AWS APIs fail with in read-only mode
+const ErrCodeReadOnly = "ReadOnly"
+
+var (
+	// readOperationPrefixes are the prefixes of the names of AWS API operations that don't mutate resources
+	readOperationPrefixes = []string{"Describe", "Get", "List"}
+	// readOperations are the operations that don't mutate resources and that don't have one of the read prefixes
+	readOperations = []string{"ReceiveMessage"}
+)
+
+// WithReadOnly fails the AWS API calls made through the session that mutate resources before they're sent. The calls
+// fail while they're validated, so that they aren't retried.
+func WithReadOnly(sess *session.Session) *session.Session {
+	sess.Handlers.Validate.PushBackNamed(request.NamedHandler{
+		Name: "karpenter.readonly.BlockMutations",
+		Fn: func(r *request.Request) {
+			if IsReadOperation(r.Operation.Name) || isDryRun(r.Params) {
+				return
+			}
+			service := lo.Ternary(r.ClientInfo.SigningName != "", r.ClientInfo.SigningName, r.ClientInfo.ServiceName)
+			action := fmt.Sprintf("%s:%s", service, r.Operation.Name)
+			BlockedCallsTotal.WithLabelValues(action).Inc()
+			log.FromContext(r.Context()).WithValues("action", action).V(1).Info("blocked mutating call in read-only mode")
+			r.Error = awserr.New(ErrCodeReadOnly, fmt.Sprintf("%s isn't called in read-only mode", action), nil)
+		},
+	})
+	return sess
+}
+
+// WithReadOnlyV2 fails the calls of the aws-sdk-go-v2 clients created from the config that mutate resources before
+// they're sent, like WithReadOnly does for the calls made through a session
+func WithReadOnlyV2(cfg awsv2.Config) awsv2.Config {
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		// The middleware is added after the service metadata is registered, which the operation name is looked up from
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("karpenter.readonly.BlockMutations",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if IsReadOperation(awsmiddleware.GetOperationName(ctx)) || isDryRun(in.Parameters) {
+					return next.HandleInitialize(ctx, in)
+				}
+				action := utils.ActionV2(ctx)
+				BlockedCallsTotal.WithLabelValues(action).Inc()
+				log.FromContext(ctx).WithValues("action", action).V(1).Info("blocked mutating call in read-only mode")
+				return middleware.InitializeOutput{}, middleware.Metadata{}, &smithy.GenericAPIError{Code: ErrCodeReadOnly, Message: fmt.Sprintf("%s isn't called in read-only mode", action)}
+			}), middleware.After)
+	})
+	return cfg
+}
+
+// IsReadOperation returns whether the AWS API operation doesn't mutate resources
+func IsReadOperation(operation string) bool {
+	return lo.Contains(readOperations, operation) || lo.SomeBy(readOperationPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(operation, prefix)
+	})
+}
+
+// isDryRun returns whether the parameters are of an EC2 dry run, which checks permissions without mutating resources
+func isDryRun(input interface{}) bool {
+	params := reflect.Indirect(reflect.ValueOf(input))
+	if params.Kind() != reflect.Struct {
+		return false
+	}
+	field := params.FieldByName("DryRun")
+	if !field.IsValid() {
+		return false
+	}
+	switch dryRun := field.Interface().(type) {
+	case *bool:
+		return lo.FromPtr(dryRun)
+	case bool:
+		return dryRun
+	}
+	return false
+}
+
+// IsReadOnlyError returns whether the error is from a call to a mutating AWS API that was blocked in read-only mode
+func IsReadOnlyError(err error) bool {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() == ErrCodeReadOnly
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == ErrCodeReadOnly
+}
@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readonly_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	ssmv2 "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/readonly"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReadOnly(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ReadOnly")
+}
+
+var _ = Describe("ReadOnly", func() {
+	var sent []string
+	var sess *session.Session
+	BeforeEach(func() {
+		sent = nil
+		sess = session.Must(session.NewSession(&aws.Config{Region: aws.String("us-west-2"), Credentials: credentials.AnonymousCredentials}))
+		// Calls that are sent are recorded and then fail, rather than reaching AWS
+		sess.Handlers.Send.PushFront(func(r *request.Request) {
+			sent = append(sent, r.Operation.Name)
+			r.Error = fmt.Errorf("not sent")
+		})
+		sess = readonly.WithReadOnly(sess)
+	})
+	It("should send calls that don't mutate resources", func() {
+		_, err := ec2.New(sess).DescribeInstances(&ec2.DescribeInstancesInput{})
+		Expect(readonly.IsReadOnlyError(err)).To(BeFalse())
+		_, err = sqs.New(sess).ReceiveMessage(&sqs.ReceiveMessageInput{QueueUrl: aws.String("https://sqs.us-west-2.amazonaws.com/123456789012/test")})
+		Expect(readonly.IsReadOnlyError(err)).To(BeFalse())
+		Expect(sent).To(ContainElements("DescribeInstances", "ReceiveMessage"))
+	})
+	It("should fail calls that mutate resources without sending them", func() {
+		_, err := ec2.New(sess).TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{"i-123"})})
+		Expect(readonly.IsReadOnlyError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("ec2:TerminateInstances"))
+		_, err = sqs.New(sess).DeleteMessage(&sqs.DeleteMessageInput{QueueUrl: aws.String("https://sqs.us-west-2.amazonaws.com/123456789012/test"), ReceiptHandle: aws.String("handle")})
+		Expect(readonly.IsReadOnlyError(err)).To(BeTrue())
+		Expect(sent).To(BeEmpty())
+	})
+	It("should send dry runs of calls that mutate resources", func() {
+		_, err := ec2.New(sess).CreateTags(&ec2.CreateTagsInput{
+			DryRun:    aws.Bool(true),
+			Resources: aws.StringSlice([]string{"i-123"}),
+			Tags:      []*ec2.Tag{{Key: aws.String("key"), Value: aws.String("value")}},
+		})
+		Expect(readonly.IsReadOnlyError(err)).To(BeFalse())
+		_, err = ec2.New(sess).TerminateInstances(&ec2.TerminateInstancesInput{DryRun: aws.Bool(true), InstanceIds: aws.StringSlice([]string{"i-123"})})
+		Expect(readonly.IsReadOnlyError(err)).To(BeFalse())
+		Expect(sent).To(ContainElements("CreateTags", "TerminateInstances"))
+	})
+	It("should fail calls that mutate resources when dry run is disabled", func() {
+		_, err := ec2.New(sess).TerminateInstances(&ec2.TerminateInstancesInput{DryRun: aws.Bool(false), InstanceIds: aws.StringSlice([]string{"i-123"})})
+		Expect(readonly.IsReadOnlyError(err)).To(BeTrue())
+		Expect(sent).To(BeEmpty())
+	})
+	It("should fail the calls of aws-sdk-go-v2 clients that mutate resources without sending them", func() {
+		var sentV2 []string
+		ssmapi := ssmv2.NewFromConfig(readonly.WithReadOnlyV2(awsv2.Config{
+			Region:      "us-west-2",
+			Credentials: awsv2.AnonymousCredentials{},
+			// Calls that are sent are recorded and then fail with a client error, which isn't retried
+			HTTPClient: smithyhttp.ClientDoFunc(func(r *http.Request) (*http.Response, error) {
+				sentV2 = append(sentV2, r.Header.Get("X-Amz-Target"))
+				return &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+			}),
+		}))
+		_, err := ssmapi.GetParameter(context.Background(), &ssmv2.GetParameterInput{Name: lo.ToPtr("test")})
+		Expect(readonly.IsReadOnlyError(err)).To(BeFalse())
+		_, err = ssmapi.PutParameter(context.Background(), &ssmv2.PutParameterInput{Name: lo.ToPtr("test"), Value: lo.ToPtr("value")})
+		Expect(readonly.IsReadOnlyError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("ssm:PutParameter"))
+		Expect(sentV2).To(ConsistOf("AmazonSSM.GetParameter"))
+	})
+	It("should detect read-only errors that were wrapped", func() {
+		_, err := ec2.New(sess).CreateTags(&ec2.CreateTagsInput{
+			Resources: aws.StringSlice([]string{"i-123"}),
+			Tags:      []*ec2.Tag{{Key: aws.String("key"), Value: aws.String("value")}},
+		})
+		Expect(readonly.IsReadOnlyError(fmt.Errorf("tagging instance, %w", err))).To(BeTrue())
+		Expect(readonly.IsReadOnlyError(fmt.Errorf("tagging instance"))).To(BeFalse())
+	})
+	DescribeTable("should classify operations",
+		func(operation string, read bool) {
+			Expect(readonly.IsReadOperation(operation)).To(Equal(read))
+		},
+		Entry("describe", "DescribeSubnets", true),
+		Entry("get", "GetParameter", true),
+		Entry("list", "ListTagsForResource", true),
+		Entry("receive", "ReceiveMessage", true),
+		Entry("create", "CreateFleet", false),
+		Entry("tag", "CreateTags", false),
+		Entry("delete", "DeleteMessage", false),
+		Entry("start", "StartInstances", false),
+	)
+})
@@ -0,0 +1,210 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recommendation answers "what would Karpenter launch for these pods" in-process. It holds the instance
+// selection logic that the instance provider uses for launches, and a read-only scheduling simulation on top of it,
+// so that tools like cost estimators and admission policies can use them without a kube client, a controller manager,
+// or AWS credentials.
+package recommendation
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
+)
+
+// Recommendation is the capacity that Karpenter would launch for a set of pods
+type Recommendation struct {
+	// NodeClaims are the nodes that would be launched, in the order they were created during the simulation
+	NodeClaims []*NodeClaim
+	// PodErrors holds the reason that each pod that couldn't be scheduled to any of the nodes wasn't
+	PodErrors map[types.NamespacedName]error
+}
+
+// Price returns the combined hourly price of all the nodes that would be launched
+func (r *Recommendation) Price() float64 {
+	return lo.SumBy(r.NodeClaims, func(nc *NodeClaim) float64 { return nc.Offering.Price })
+}
+
+// NodeClaim is a node that would be launched for some of the pods
+type NodeClaim struct {
+	NodePoolName string
+	Requirements scheduling.Requirements
+	Pods         []*v1.Pod
+	// InstanceTypeOptions are the instance types that would be offered to EC2 Fleet for the launch
+	InstanceTypeOptions []*cloudprovider.InstanceType
+	CapacityType        string
+	// InstanceType and Offering are the cheapest of the options, which is what EC2 Fleet is most likely to launch for
+	// on-demand capacity
+	InstanceType *cloudprovider.InstanceType
+	Offering     cloudprovider.Offering
+
+	taints   scheduling.Taints
+	requests v1.ResourceList
+}
+
+// Recommender simulates scheduling pods to new nodes of a set of NodePools. It doesn't take existing nodes, daemonsets,
+// limits or topology spread into account, so its results are an estimate rather than a prediction.
+type Recommender struct {
+	nodePools     []*corev1beta1.NodePool
+	instanceTypes map[string][]*cloudprovider.InstanceType
+	deprioritized sets.Set[string]
+}
+
+// NewRecommender returns a Recommender for the NodePools, given the instance types that each NodePool can launch by
+// name (e.g. from the InstanceTypes of the cloud provider) and the deprioritized instance type categories
+func NewRecommender(nodePools []*corev1beta1.NodePool, instanceTypes map[string][]*cloudprovider.InstanceType, deprioritized ...string) *Recommender {
+	nodePools = append([]*corev1beta1.NodePool{}, nodePools...)
+	// NodePools with a higher weight are preferred, as they are by the scheduler
+	sort.SliceStable(nodePools, func(i, j int) bool {
+		return lo.FromPtr(nodePools[i].Spec.Weight) > lo.FromPtr(nodePools[j].Spec.Weight)
+	})
+	return &Recommender{
+		nodePools:     nodePools,
+		instanceTypes: instanceTypes,
+		deprioritized: sets.New(deprioritized...),
+	}
+}
+
+// Recommend returns the nodes that Karpenter would launch for the pods. Like the scheduler, it packs the largest pods
+// first, onto the first node that they fit on, and creates a node from the heaviest NodePool that it can when they
+// fit on none.
+func (r *Recommender) Recommend(pods ...*v1.Pod) (*Recommendation, error) {
+	pods = append([]*v1.Pod{}, pods...)
+	sort.SliceStable(pods, byCPUAndMemoryDescending(pods))
+
+	recommendation := &Recommendation{PodErrors: map[types.NamespacedName]error{}}
+	for _, pod := range pods {
+		if _, ok := lo.Find(recommendation.NodeClaims, func(nc *NodeClaim) bool { return nc.add(pod) == nil }); ok {
+			continue
+		}
+		var errs error
+		for _, nodePool := range r.nodePools {
+			nodeClaim := r.newNodeClaim(nodePool)
+			if err := nodeClaim.add(pod); err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("incompatible with nodepool %q, %w", nodePool.Name, err))
+				continue
+			}
+			recommendation.NodeClaims = append(recommendation.NodeClaims, nodeClaim)
+			errs = nil
+			break
+		}
+		if len(r.nodePools) == 0 {
+			errs = fmt.Errorf("no nodepools found")
+		}
+		if errs != nil {
+			recommendation.PodErrors[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = errs
+		}
+	}
+	for _, nodeClaim := range recommendation.NodeClaims {
+		if err := r.selectInstanceType(nodeClaim); err != nil {
+			return nil, fmt.Errorf("selecting instance types for nodepool %q, %w", nodeClaim.NodePoolName, err)
+		}
+	}
+	return recommendation, nil
+}
+
+func (r *Recommender) newNodeClaim(nodePool *corev1beta1.NodePool) *NodeClaim {
+	requirements := scheduling.NewRequirements()
+	requirements.Add(scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...).Values()...)
+	requirements.Add(scheduling.NewLabelRequirements(lo.Assign(nodePool.Spec.Template.Labels, map[string]string{
+		corev1beta1.NodePoolLabelKey: nodePool.Name,
+	})).Values()...)
+	return &NodeClaim{
+		NodePoolName:        nodePool.Name,
+		Requirements:        requirements,
+		InstanceTypeOptions: r.instanceTypes[nodePool.Name],
+		taints:              nodePool.Spec.Template.Spec.Taints,
+		requests:            v1.ResourceList{},
+	}
+}
+
+// add schedules the pod to the node if it tolerates the node's taints, and the node's requirements and the pod's
+// requests can still be met by one of the node's instance types
+func (n *NodeClaim) add(pod *v1.Pod) error {
+	if err := n.taints.Tolerates(pod); err != nil {
+		return err
+	}
+	podRequirements := scheduling.NewPodRequirements(pod)
+	if err := n.Requirements.Compatible(podRequirements, scheduling.AllowUndefinedWellKnownLabels); err != nil {
+		return err
+	}
+	requirements := scheduling.NewRequirements(n.Requirements.Values()...)
+	requirements.Add(podRequirements.Values()...)
+	requests := resources.Merge(n.requests, resources.RequestsForPods(pod))
+	instanceTypes := lo.Filter(n.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) bool {
+		return it.Requirements.Intersects(requirements) == nil &&
+			resources.Fits(requests, it.Allocatable()) &&
+			it.Offerings.Available().HasCompatible(requirements)
+	})
+	if len(instanceTypes) == 0 {
+		return fmt.Errorf("no instance type satisfied the requirements %s and resources %s", requirements, resources.String(requests))
+	}
+	if requirements.HasMinValues() {
+		if _, err := cloudprovider.InstanceTypes(instanceTypes).SatisfiesMinValues(requirements); err != nil {
+			return err
+		}
+	}
+	n.Requirements = requirements
+	n.requests = requests
+	n.InstanceTypeOptions = instanceTypes
+	n.Pods = append(n.Pods, pod)
+	return nil
+}
+
+// selectInstanceType narrows the node's instance types to the ones that would be offered to EC2 Fleet, and picks the
+// cheapest offering of them
+func (r *Recommender) selectInstanceType(n *NodeClaim) error {
+	instanceTypes, capacityType, err := SelectInstanceTypes(n.Requirements, r.deprioritized, n.InstanceTypeOptions)
+	if err != nil {
+		return err
+	}
+	n.InstanceTypeOptions = instanceTypes
+	n.CapacityType = capacityType
+	requirements := scheduling.NewRequirements(n.Requirements.Values()...)
+	requirements.Add(scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, capacityType))
+	for _, it := range instanceTypes {
+		offerings := it.Offerings.Available().Compatible(requirements)
+		if len(offerings) == 0 {
+			continue
+		}
+		if cheapest := offerings.Cheapest(); n.InstanceType == nil || cheapest.Price < n.Offering.Price {
+			n.InstanceType, n.Offering = it, cheapest
+		}
+	}
+	if n.InstanceType == nil {
+		return fmt.Errorf("no %s offering is available", capacityType)
+	}
+	return nil
+}
+
+func byCPUAndMemoryDescending(pods []*v1.Pod) func(i int, j int) bool {
+	return func(i, j int) bool {
+		lhs := resources.RequestsForPods(pods[i])
+		rhs := resources.RequestsForPods(pods[j])
+		if cpuCmp := resources.Cmp(lhs[v1.ResourceCPU], rhs[v1.ResourceCPU]); cpuCmp != 0 {
+			return cpuCmp > 0
+		}
+		return resources.Cmp(lhs[v1.ResourceMemory], rhs[v1.ResourceMemory]) > 0
+	}
+}
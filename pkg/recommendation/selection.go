@@ -0,0 +1,177 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommendation
+
+import (
+	"math"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+)
+
+// MaxInstanceTypes is the most instance types that are offered to EC2 Fleet for a single launch
+const MaxInstanceTypes = 60
+
+// Categories of instance types that can be deprioritized through the deprioritized-instance-types setting
+const (
+	InstanceTypeCategoryMetal       = "metal"
+	InstanceTypeCategoryNVIDIAGPU   = "nvidia-gpu"
+	InstanceTypeCategoryAMDGPU      = "amd-gpu"
+	InstanceTypeCategoryAWSNeuron   = "aws-neuron"
+	InstanceTypeCategoryHabanaGaudi = "habana-gaudi"
+	InstanceTypeCategoryXen         = "xen"
+)
+
+// acceleratorCategories maps the categories of accelerated instance types to the resource of their accelerator
+var acceleratorCategories = map[string]v1.ResourceName{
+	InstanceTypeCategoryNVIDIAGPU:   v1beta1.ResourceNVIDIAGPU,
+	InstanceTypeCategoryAMDGPU:      v1beta1.ResourceAMDGPU,
+	InstanceTypeCategoryAWSNeuron:   v1beta1.ResourceAWSNeuron,
+	InstanceTypeCategoryHabanaGaudi: v1beta1.ResourceHabanaGaudi,
+}
+
+// SelectInstanceTypes narrows the instance types that satisfy a NodeClaim's requirements to the ones that Karpenter
+// offers EC2 Fleet for its launch, and returns them with the capacity type that the launch requests
+func SelectInstanceTypes(requirements scheduling.Requirements, deprioritized sets.Set[string], instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, string, error) {
+	// Only filter the instances if there are no minValues in the requirement.
+	if !requirements.HasMinValues() {
+		instanceTypes = FilterInstanceTypes(requirements, deprioritized, instanceTypes)
+	}
+	instanceTypes, err := cloudprovider.InstanceTypes(instanceTypes).Truncate(requirements, MaxInstanceTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	return instanceTypes, CapacityType(requirements, instanceTypes), nil
+}
+
+// FilterInstanceTypes is used to provide filtering on the list of potential instance types to further limit it to those
+// that make the most sense given our specific AWS cloudprovider.
+func FilterInstanceTypes(requirements scheduling.Requirements, deprioritized sets.Set[string], instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	instanceTypes = FilterExoticInstanceTypes(deprioritized, instanceTypes)
+	// If we could potentially launch either a spot or on-demand node, we want to filter out the spot instance types that
+	// are more expensive than the cheapest on-demand type.
+	if IsMixedCapacityLaunch(requirements, instanceTypes) {
+		instanceTypes = FilterUnwantedSpot(instanceTypes)
+	}
+	return instanceTypes
+}
+
+// CapacityType selects spot if both constraints are flexible and there is an
+// available offering. The AWS Cloud Provider defaults to [ on-demand ], so spot
+// must be explicitly included in capacity type requirements.
+func CapacityType(requirements scheduling.Requirements, instanceTypes []*cloudprovider.InstanceType) string {
+	if requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) {
+		requirements = scheduling.NewRequirements(requirements.Values()...)
+		requirements[corev1beta1.CapacityTypeLabelKey] = scheduling.NewRequirement(corev1beta1.CapacityTypeLabelKey, v1.NodeSelectorOpIn, corev1beta1.CapacityTypeSpot)
+		for _, instanceType := range instanceTypes {
+			for _, offering := range instanceType.Offerings.Available() {
+				if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil {
+					return corev1beta1.CapacityTypeSpot
+				}
+			}
+		}
+	}
+	return corev1beta1.CapacityTypeOnDemand
+}
+
+// IsMixedCapacityLaunch returns true if nodepools and available offerings could potentially allow either a spot or
+// and on-demand node to launch
+func IsMixedCapacityLaunch(requirements scheduling.Requirements, instanceTypes []*cloudprovider.InstanceType) bool {
+	// requirements must allow both
+	if !requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeSpot) ||
+		!requirements.Get(corev1beta1.CapacityTypeLabelKey).Has(corev1beta1.CapacityTypeOnDemand) {
+		return false
+	}
+	hasSpotOfferings := false
+	hasODOffering := false
+	for _, instanceType := range instanceTypes {
+		for _, offering := range instanceType.Offerings.Available() {
+			if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
+				continue
+			}
+			if offering.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeSpot {
+				hasSpotOfferings = true
+			} else {
+				hasODOffering = true
+			}
+		}
+	}
+	return hasSpotOfferings && hasODOffering
+}
+
+// FilterUnwantedSpot is used to filter out spot types that are more expensive than the cheapest on-demand type that we
+// could launch during mixed capacity-type launches
+func FilterUnwantedSpot(instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	cheapestOnDemand := math.MaxFloat64
+	// first, find the price of our cheapest available on-demand instance type that could support this node
+	for _, it := range instanceTypes {
+		for _, o := range it.Offerings.Available() {
+			if o.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any() == corev1beta1.CapacityTypeOnDemand && o.Price < cheapestOnDemand {
+				cheapestOnDemand = o.Price
+			}
+		}
+	}
+
+	// Filter out any types where the cheapest offering, which should be spot, is more expensive than the cheapest
+	// on-demand instance type that would have worked. This prevents us from getting a larger more-expensive spot
+	// instance type compared to the cheapest sufficiently large on-demand instance type
+	instanceTypes = lo.Filter(instanceTypes, func(item *cloudprovider.InstanceType, index int) bool {
+		available := item.Offerings.Available()
+		if len(available) == 0 {
+			return false
+		}
+		return available.Cheapest().Price <= cheapestOnDemand
+	})
+	return instanceTypes
+}
+
+// IsDeprioritized returns true if the instance type belongs to any of the deprioritized categories
+func IsDeprioritized(categories sets.Set[string], it *cloudprovider.InstanceType) bool {
+	if categories.Has(InstanceTypeCategoryMetal) {
+		if _, ok := lo.Find(it.Requirements.Get(v1beta1.LabelInstanceSize).Values(), func(size string) bool { return strings.Contains(size, "metal") }); ok {
+			return true
+		}
+	}
+	if categories.Has(InstanceTypeCategoryXen) && it.Requirements.Get(v1beta1.LabelInstanceHypervisor).Has("xen") {
+		return true
+	}
+	for category, resource := range acceleratorCategories {
+		if categories.Has(category) && !resources.IsZero(it.Capacity[resource]) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterExoticInstanceTypes is used to eliminate less desirable instance types (like GPUs) from the list of possible instance types when
+// a set of more appropriate instance types would work. If a set of more desirable instance types is not found, then the original slice
+// of instance types are returned.
+func FilterExoticInstanceTypes(categories sets.Set[string], instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	// deprioritize even if our opinionated filter isn't applied due to something like an instance family requirement
+	genericInstanceTypes := lo.Reject(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool { return IsDeprioritized(categories, it) })
+	// if we got some subset of instance types, then prefer to use those
+	if len(genericInstanceTypes) != 0 {
+		return genericInstanceTypes
+	}
+	return instanceTypes
+}
@@ -0,0 +1,132 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommendation_test
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	corefake "sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/recommendation"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Recommendation")
+}
+
+var _ = Describe("Recommendation", func() {
+	var nodePool *corev1beta1.NodePool
+	var instanceTypes []*cloudprovider.InstanceType
+	var deprioritized []string
+
+	BeforeEach(func() {
+		nodePool = coretest.NodePool()
+		instanceTypes = corefake.InstanceTypes(8)
+		deprioritized = nil
+	})
+	pod := func(cpu string) *v1.Pod {
+		return coretest.UnschedulablePod(coretest.PodOptions{
+			ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}},
+		})
+	}
+	recommend := func(nodePools []*corev1beta1.NodePool, pods ...*v1.Pod) *recommendation.Recommendation {
+		recommender := recommendation.NewRecommender(nodePools, lo.SliceToMap(nodePools, func(np *corev1beta1.NodePool) (string, []*cloudprovider.InstanceType) {
+			return np.Name, instanceTypes
+		}), deprioritized...)
+		r, err := recommender.Recommend(pods...)
+		Expect(err).ToNot(HaveOccurred())
+		return r
+	}
+
+	It("should pack pods onto the cheapest instance type that fits them", func() {
+		r := recommend([]*corev1beta1.NodePool{nodePool}, pod("1"), pod("1"), pod("1"))
+		Expect(r.NodeClaims).To(HaveLen(1))
+		Expect(r.NodeClaims[0].Pods).To(HaveLen(3))
+		Expect(r.NodeClaims[0].NodePoolName).To(Equal(nodePool.Name))
+		// 3 vCPUs and the kube-reserved overhead need the 4 vCPU instance type
+		Expect(r.NodeClaims[0].InstanceType.Name).To(Equal("fake-it-3"))
+		Expect(r.NodeClaims[0].CapacityType).To(Equal(corev1beta1.CapacityTypeSpot))
+		Expect(r.Price()).To(Equal(r.NodeClaims[0].Offering.Price))
+		Expect(r.PodErrors).To(BeEmpty())
+	})
+	It("should launch more nodes when the pods don't fit on one", func() {
+		r := recommend([]*corev1beta1.NodePool{nodePool}, pod("6"), pod("6"), pod("1"))
+		Expect(r.NodeClaims).To(HaveLen(2))
+		Expect(r.NodeClaims[0].Pods).To(HaveLen(2))
+		Expect(r.NodeClaims[1].Pods).To(HaveLen(1))
+	})
+	It("should respect the capacity type requirements of the nodepool", func() {
+		nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: v1.NodeSelectorRequirement{
+			Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand},
+		}}}
+		r := recommend([]*corev1beta1.NodePool{nodePool}, pod("1"))
+		Expect(r.NodeClaims).To(HaveLen(1))
+		Expect(r.NodeClaims[0].CapacityType).To(Equal(corev1beta1.CapacityTypeOnDemand))
+	})
+	It("should prefer the nodepool with the highest weight that the pod tolerates", func() {
+		tainted := coretest.NodePool()
+		tainted.Spec.Weight = lo.ToPtr[int32](100)
+		tainted.Spec.Template.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+		weighted := coretest.NodePool()
+		weighted.Spec.Weight = lo.ToPtr[int32](50)
+		r := recommend([]*corev1beta1.NodePool{nodePool, tainted, weighted}, pod("1"))
+		Expect(r.NodeClaims).To(HaveLen(1))
+		Expect(r.NodeClaims[0].NodePoolName).To(Equal(weighted.Name))
+	})
+	It("should report the pods that can't be scheduled", func() {
+		p := pod("100")
+		r := recommend([]*corev1beta1.NodePool{nodePool}, p, pod("1"))
+		Expect(r.NodeClaims).To(HaveLen(1))
+		Expect(r.PodErrors).To(HaveKey(types.NamespacedName{Namespace: p.Namespace, Name: p.Name}))
+	})
+	It("should not schedule pods to nodes whose requirements they conflict with", func() {
+		nodePool.Spec.Template.Labels = map[string]string{"team": "a"}
+		p := pod("1")
+		p.Spec.NodeSelector = map[string]string{"team": "b"}
+		r := recommend([]*corev1beta1.NodePool{nodePool}, p)
+		Expect(r.NodeClaims).To(BeEmpty())
+		Expect(r.PodErrors).To(HaveLen(1))
+	})
+	It("should avoid deprioritized instance types when others are compatible", func() {
+		nodePool.Spec.Template.Spec.Requirements = []corev1beta1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: v1.NodeSelectorRequirement{
+			Key: corev1beta1.CapacityTypeLabelKey, Operator: v1.NodeSelectorOpIn, Values: []string{corev1beta1.CapacityTypeOnDemand},
+		}}}
+		instanceTypes = append(instanceTypes, corefake.NewInstanceType(corefake.InstanceTypeOptions{
+			Name: "gpu",
+			Resources: v1.ResourceList{
+				v1.ResourceCPU:   resource.MustParse("1"),
+				"nvidia.com/gpu": resource.MustParse("1"),
+			},
+		}))
+		r := recommend([]*corev1beta1.NodePool{nodePool}, pod("100m"))
+		Expect(r.NodeClaims).To(HaveLen(1))
+		Expect(lo.Map(r.NodeClaims[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).To(ContainElement("gpu"))
+		deprioritized = []string{recommendation.InstanceTypeCategoryNVIDIAGPU}
+		r = recommend([]*corev1beta1.NodePool{nodePool}, pod("100m"))
+		Expect(r.NodeClaims).To(HaveLen(1))
+		Expect(lo.Map(r.NodeClaims[0].InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })).ToNot(ContainElement("gpu"))
+	})
+})
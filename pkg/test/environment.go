@@ -21,6 +21,7 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
 
 	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 
@@ -30,10 +31,12 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/terminationhook"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
 	coretest "sigs.k8s.io/karpenter/pkg/test"
@@ -47,23 +50,34 @@ func init() {
 
 type Environment struct {
 	// API
-	EC2API     *fake.EC2API
-	EKSAPI     *fake.EKSAPI
-	SSMAPI     *fake.SSMAPI
-	IAMAPI     *fake.IAMAPI
-	PricingAPI *fake.PricingAPI
+	EC2API         *fake.EC2API
+	EKSAPI         *fake.EKSAPI
+	SSMAPI         *fake.SSMAPI
+	IAMAPI         *fake.IAMAPI
+	PricingAPI     *fake.PricingAPI
+	EventBridgeAPI *fake.EventBridgeAPI
+	KMSAPI         *fake.KMSAPI
+	AuditLogger    *fake.AuditLogger
 
 	// Cache
 	EC2Cache                      *cache.Cache
 	KubernetesVersionCache        *cache.Cache
 	InstanceTypeCache             *cache.Cache
 	UnavailableOfferingsCache     *awscache.UnavailableOfferings
+	BlockedOfferingsCache         *awscache.BlockedOfferings
+	ZoneHealth                    *awscache.ZoneHealth
+	CreationLimits                *awscache.CreationLimits
+	LaunchRamps                   *awscache.LaunchRamps
+	ZoneScores                    *awscache.ZoneScores
+	VPCCNI                        *awscache.VPCCNI
+	VCPUQuotaHeadroom             *awscache.VCPUQuotaHeadroom
 	LaunchTemplateCache           *cache.Cache
 	SubnetCache                   *cache.Cache
 	AvailableIPAdressCache        *cache.Cache
 	AssociatePublicIPAddressCache *cache.Cache
 	SecurityGroupCache            *cache.Cache
 	InstanceProfileCache          *cache.Cache
+	KMSCache                      *cache.Cache
 
 	// Providers
 	InstanceTypesProvider   *instancetype.DefaultProvider
@@ -73,9 +87,11 @@ type Environment struct {
 	InstanceProfileProvider *instanceprofile.DefaultProvider
 	PricingProvider         *pricing.DefaultProvider
 	AMIProvider             *amifamily.DefaultProvider
-	AMIResolver             *amifamily.Resolver
+	AMIResolver             *amifamily.DefaultResolver
 	VersionProvider         *version.DefaultProvider
 	LaunchTemplateProvider  *launchtemplate.DefaultProvider
+	TerminationHookProvider *terminationhook.DefaultProvider
+	KMSProvider             *kms.DefaultProvider
 }
 
 func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment {
@@ -84,59 +100,81 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	eksapi := fake.NewEKSAPI()
 	ssmapi := fake.NewSSMAPI()
 	iamapi := fake.NewIAMAPI()
+	eventBridgeAPI := &fake.EventBridgeAPI{}
+	kmsapi := fake.NewKMSAPI()
+	auditLogger := &fake.AuditLogger{}
 
 	// cache
 	ec2Cache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	kubernetesVersionCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	instanceTypeCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
+	blockedOfferingsCache := awscache.NewBlockedOfferings(clock.RealClock{})
+	zoneHealth := awscache.NewZoneHealth(clock.RealClock{})
+	creationLimits := awscache.NewCreationLimits(clock.RealClock{})
+	launchRamps := awscache.NewLaunchRamps(clock.RealClock{})
+	zoneScores := awscache.NewZoneScores()
+	vpcCNI := awscache.NewVPCCNI()
+	vcpuQuotaHeadroom := awscache.NewVCPUQuotaHeadroom()
 	launchTemplateCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	subnetCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	availableIPAdressCache := cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval)
 	associatePublicIPAddressCache := cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval)
 	securityGroupCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	instanceProfileCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	kmsCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	fakePricingAPI := &fake.PricingAPI{}
 
+	// The providers that were migrated to aws-sdk-go-v2 are served by the same fake as the others
+	ec2v2api := fake.NewEC2V2API(ec2api)
+
 	// Providers
-	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2api, fake.DefaultRegion)
-	subnetProvider := subnet.NewDefaultProvider(ec2api, subnetCache, availableIPAdressCache, associatePublicIPAddressCache)
-	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, securityGroupCache)
+	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2v2api, fake.DefaultRegion, nil)
+	subnetProvider := subnet.NewDefaultProvider(ec2v2api, subnetCache, availableIPAdressCache, associatePublicIPAddressCache, vpcCNI)
+	securityGroupProvider := securitygroup.NewDefaultProvider(ec2v2api, securityGroupCache)
 	versionProvider := version.NewDefaultProvider(env.KubernetesInterface, kubernetesVersionCache)
 	instanceProfileProvider := instanceprofile.NewDefaultProvider(fake.DefaultRegion, iamapi, instanceProfileCache)
-	amiProvider := amifamily.NewDefaultProvider(versionProvider, ssmapi, ec2api, ec2Cache)
-	amiResolver := amifamily.NewResolver(amiProvider)
-	instanceTypesProvider := instancetype.NewDefaultProvider(fake.DefaultRegion, instanceTypeCache, ec2api, subnetProvider, unavailableOfferingsCache, pricingProvider)
+	amiProvider := amifamily.NewDefaultProvider(clock.RealClock{}, versionProvider, fake.NewSSMV2API(ssmapi), ec2api, ec2Cache)
+	amiResolver := amifamily.NewDefaultResolver(amiProvider)
+	instanceTypesProvider := instancetype.NewDefaultProvider(fake.DefaultRegion, instanceTypeCache, ec2api, subnetProvider, unavailableOfferingsCache, blockedOfferingsCache, zoneHealth, vpcCNI, vcpuQuotaHeadroom, pricingProvider, nil)
 	launchTemplateProvider :=
 		launchtemplate.NewDefaultProvider(
 			ctx,
 			launchTemplateCache,
-			ec2api,
+			creationLimits,
+			ec2v2api,
 			eksapi,
 			amiResolver,
 			securityGroupProvider,
 			subnetProvider,
 			lo.ToPtr("ca-bundle"),
-			make(chan struct{}),
 			net.ParseIP("10.0.100.10"),
 			"https://test-cluster",
 		)
 	instanceProvider :=
 		instance.NewDefaultProvider(ctx,
 			"",
-			ec2api,
+			ec2v2api,
 			unavailableOfferingsCache,
+			zoneHealth,
+			creationLimits,
+			zoneScores,
 			instanceTypesProvider,
 			subnetProvider,
 			launchTemplateProvider,
+			auditLogger,
+			clock.RealClock{},
 		)
 
 	return &Environment{
-		EC2API:     ec2api,
-		EKSAPI:     eksapi,
-		SSMAPI:     ssmapi,
-		IAMAPI:     iamapi,
-		PricingAPI: fakePricingAPI,
+		EC2API:         ec2api,
+		EKSAPI:         eksapi,
+		SSMAPI:         ssmapi,
+		IAMAPI:         iamapi,
+		PricingAPI:     fakePricingAPI,
+		EventBridgeAPI: eventBridgeAPI,
+		KMSAPI:         kmsapi,
+		AuditLogger:    auditLogger,
 
 		EC2Cache:                      ec2Cache,
 		KubernetesVersionCache:        kubernetesVersionCache,
@@ -146,7 +184,15 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		AssociatePublicIPAddressCache: associatePublicIPAddressCache,
 		SecurityGroupCache:            securityGroupCache,
 		InstanceProfileCache:          instanceProfileCache,
+		KMSCache:                      kmsCache,
 		UnavailableOfferingsCache:     unavailableOfferingsCache,
+		BlockedOfferingsCache:         blockedOfferingsCache,
+		ZoneHealth:                    zoneHealth,
+		CreationLimits:                creationLimits,
+		LaunchRamps:                   launchRamps,
+		ZoneScores:                    zoneScores,
+		VPCCNI:                        vpcCNI,
+		VCPUQuotaHeadroom:             vcpuQuotaHeadroom,
 
 		InstanceTypesProvider:   instanceTypesProvider,
 		InstanceProvider:        instanceProvider,
@@ -158,6 +204,8 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		AMIProvider:             amiProvider,
 		AMIResolver:             amiResolver,
 		VersionProvider:         versionProvider,
+		TerminationHookProvider: terminationhook.NewDefaultProvider(eventBridgeAPI),
+		KMSProvider:             kms.NewDefaultProvider(kmsapi, fake.DefaultRegion, fake.DefaultAccount, kmsCache),
 	}
 }
 
@@ -167,18 +215,29 @@ func (env *Environment) Reset() {
 	env.SSMAPI.Reset()
 	env.IAMAPI.Reset()
 	env.PricingAPI.Reset()
+	env.EventBridgeAPI.Reset()
+	env.KMSAPI.Reset()
+	env.AuditLogger.Reset()
 	env.PricingProvider.Reset()
 	env.InstanceTypesProvider.Reset()
 
 	env.EC2Cache.Flush()
 	env.KubernetesVersionCache.Flush()
 	env.UnavailableOfferingsCache.Flush()
+	env.BlockedOfferingsCache.Flush()
+	env.ZoneHealth.Flush()
+	env.CreationLimits.Flush()
+	env.LaunchRamps.Flush()
+	env.ZoneScores.Flush()
+	env.VPCCNI.Flush()
+	env.VCPUQuotaHeadroom.Flush()
 	env.LaunchTemplateCache.Flush()
 	env.SubnetCache.Flush()
 	env.AssociatePublicIPAddressCache.Flush()
 	env.AvailableIPAdressCache.Flush()
 	env.SecurityGroupCache.Flush()
 	env.InstanceProfileCache.Flush()
+	env.KMSCache.Flush()
 
 	mfs, err := crmetrics.Registry.Gather()
 	if err != nil {
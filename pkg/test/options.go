@@ -25,15 +25,68 @@ import (
 )
 
 type OptionsFields struct {
-	AssumeRoleARN           *string
-	AssumeRoleDuration      *time.Duration
-	ClusterCABundle         *string
-	ClusterName             *string
-	ClusterEndpoint         *string
-	IsolatedVPC             *bool
-	VMMemoryOverheadPercent *float64
-	InterruptionQueue       *string
-	ReservedENIs            *int
+	AssumeRoleARN                      *string
+	AssumeRoleDuration                 *time.Duration
+	ClusterCABundle                    *string
+	ClusterName                        *string
+	ClusterEndpoint                    *string
+	ClusterDNS                         *string
+	IsolatedVPC                        *bool
+	VMMemoryOverheadPercent            *float64
+	VMMemoryOverheadPercentOverrides   map[string]float64
+	InterruptionQueue                  *string
+	ReservedENIs                       *int
+	AllocatableEstimation              *bool
+	InterruptionQueueShared            *bool
+	TargetGroupDeregistration          *bool
+	InstanceSelectionWeights           map[string]float64
+	DeprioritizedInstanceTypes         []string
+	AWSDNSSuffix                       *string
+	AWSFIPSEndpoints                   *bool
+	InstanceTypeSnapshotFile           *string
+	MaxLaunchTemplatesPerHour          *int
+	MaxCreateFleetRequestsPerHour      *int
+	MaxInstancesPerHour                *int
+	MaxConcurrentInterruptionDrains    *int
+	VPCCNIWarmTargets                  *bool
+	BreakGlassDebug                    *bool
+	TracingEndpoint                    *string
+	TracingSampleRatio                 *float64
+	MetadataHTTPTokens                 *string
+	MetadataHTTPPutResponseHopLimit    *int
+	MetadataOptionsPolicy              *string
+	OnDemandBackstop                   *bool
+	AdoptUnmanagedInstances            *bool
+	VCPUQuotaReporting                 *bool
+	VCPUQuotaFiltering                 *bool
+	LaunchTemplateDataPatches          *bool
+	CreateFleetBatchIdleDuration       *time.Duration
+	CreateFleetBatchMaxDuration        *time.Duration
+	CreateFleetBatchMaxItems           *int
+	StuckInstanceDeadline              *time.Duration
+	InterruptionDeadLetterQueue        *string
+	InterruptionQueueMaxReceives       *int
+	WarmPools                          *bool
+	Hibernation                        *bool
+	InstanceTypeAllowList              []string
+	InstanceTypeDenyList               []string
+	TerminationApproval                *bool
+	TerminationNotificationEventBus    *string
+	LaunchAuditLog                     *string
+	ZoneFailover                       *bool
+	NodeClaimLaunchOverrides           *bool
+	NodeRepair                         *bool
+	NodeRepairRebootAttempts           *int
+	NodeRepairRebootTimeout            *time.Duration
+	ReadOnly                           *bool
+	AMIDeprecationWarningWindow        *time.Duration
+	AMIDeprecationStrict               *bool
+	PodRestartCost                     *bool
+	Prewarm                            *bool
+	LaunchTemplateGarbageCollectionAge *time.Duration
+	OutpostInstancePrices              map[string]float64
+	ICEBackoffDurations                map[string]time.Duration
+	ICEBackoffMaxDurations             map[string]time.Duration
 }
 
 func Options(overrides ...OptionsFields) *options.Options {
@@ -44,14 +97,67 @@ func Options(overrides ...OptionsFields) *options.Options {
 		}
 	}
 	return &options.Options{
-		AssumeRoleARN:           lo.FromPtrOr(opts.AssumeRoleARN, ""),
-		AssumeRoleDuration:      lo.FromPtrOr(opts.AssumeRoleDuration, 15*time.Minute),
-		ClusterCABundle:         lo.FromPtrOr(opts.ClusterCABundle, ""),
-		ClusterName:             lo.FromPtrOr(opts.ClusterName, "test-cluster"),
-		ClusterEndpoint:         lo.FromPtrOr(opts.ClusterEndpoint, "https://test-cluster"),
-		IsolatedVPC:             lo.FromPtrOr(opts.IsolatedVPC, false),
-		VMMemoryOverheadPercent: lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
-		InterruptionQueue:       lo.FromPtrOr(opts.InterruptionQueue, ""),
-		ReservedENIs:            lo.FromPtrOr(opts.ReservedENIs, 0),
+		AssumeRoleARN:                      lo.FromPtrOr(opts.AssumeRoleARN, ""),
+		AssumeRoleDuration:                 lo.FromPtrOr(opts.AssumeRoleDuration, 15*time.Minute),
+		ClusterCABundle:                    lo.FromPtrOr(opts.ClusterCABundle, ""),
+		ClusterName:                        lo.FromPtrOr(opts.ClusterName, "test-cluster"),
+		ClusterEndpoint:                    lo.FromPtrOr(opts.ClusterEndpoint, "https://test-cluster"),
+		ClusterDNS:                         lo.FromPtrOr(opts.ClusterDNS, ""),
+		IsolatedVPC:                        lo.FromPtrOr(opts.IsolatedVPC, false),
+		VMMemoryOverheadPercent:            lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
+		VMMemoryOverheadPercentOverrides:   opts.VMMemoryOverheadPercentOverrides,
+		InterruptionQueue:                  lo.FromPtrOr(opts.InterruptionQueue, ""),
+		ReservedENIs:                       lo.FromPtrOr(opts.ReservedENIs, 0),
+		AllocatableEstimation:              lo.FromPtrOr(opts.AllocatableEstimation, false),
+		InterruptionQueueShared:            lo.FromPtrOr(opts.InterruptionQueueShared, false),
+		TargetGroupDeregistration:          lo.FromPtrOr(opts.TargetGroupDeregistration, false),
+		InstanceSelectionWeights:           opts.InstanceSelectionWeights,
+		DeprioritizedInstanceTypes:         lo.Ternary(opts.DeprioritizedInstanceTypes != nil, opts.DeprioritizedInstanceTypes, []string{"metal", "nvidia-gpu", "amd-gpu", "aws-neuron", "habana-gaudi"}),
+		AWSDNSSuffix:                       lo.FromPtrOr(opts.AWSDNSSuffix, ""),
+		AWSFIPSEndpoints:                   lo.FromPtrOr(opts.AWSFIPSEndpoints, false),
+		InstanceTypeSnapshotFile:           lo.FromPtrOr(opts.InstanceTypeSnapshotFile, ""),
+		MaxLaunchTemplatesPerHour:          lo.FromPtrOr(opts.MaxLaunchTemplatesPerHour, 0),
+		MaxCreateFleetRequestsPerHour:      lo.FromPtrOr(opts.MaxCreateFleetRequestsPerHour, 0),
+		MaxInstancesPerHour:                lo.FromPtrOr(opts.MaxInstancesPerHour, 0),
+		MaxConcurrentInterruptionDrains:    lo.FromPtrOr(opts.MaxConcurrentInterruptionDrains, 0),
+		VPCCNIWarmTargets:                  lo.FromPtrOr(opts.VPCCNIWarmTargets, false),
+		BreakGlassDebug:                    lo.FromPtrOr(opts.BreakGlassDebug, false),
+		TracingEndpoint:                    lo.FromPtrOr(opts.TracingEndpoint, ""),
+		TracingSampleRatio:                 lo.FromPtrOr(opts.TracingSampleRatio, 1),
+		MetadataHTTPTokens:                 lo.FromPtrOr(opts.MetadataHTTPTokens, ""),
+		MetadataHTTPPutResponseHopLimit:    lo.FromPtrOr(opts.MetadataHTTPPutResponseHopLimit, 0),
+		MetadataOptionsPolicy:              lo.FromPtrOr(opts.MetadataOptionsPolicy, options.MetadataOptionsPolicyDefault),
+		OnDemandBackstop:                   lo.FromPtrOr(opts.OnDemandBackstop, false),
+		AdoptUnmanagedInstances:            lo.FromPtrOr(opts.AdoptUnmanagedInstances, false),
+		VCPUQuotaReporting:                 lo.FromPtrOr(opts.VCPUQuotaReporting, false),
+		VCPUQuotaFiltering:                 lo.FromPtrOr(opts.VCPUQuotaFiltering, false),
+		LaunchTemplateDataPatches:          lo.FromPtrOr(opts.LaunchTemplateDataPatches, false),
+		CreateFleetBatchIdleDuration:       lo.FromPtrOr(opts.CreateFleetBatchIdleDuration, 35*time.Millisecond),
+		CreateFleetBatchMaxDuration:        lo.FromPtrOr(opts.CreateFleetBatchMaxDuration, time.Second),
+		CreateFleetBatchMaxItems:           lo.FromPtrOr(opts.CreateFleetBatchMaxItems, 1000),
+		StuckInstanceDeadline:              lo.FromPtrOr(opts.StuckInstanceDeadline, 0),
+		InterruptionDeadLetterQueue:        lo.FromPtrOr(opts.InterruptionDeadLetterQueue, ""),
+		InterruptionQueueMaxReceives:       lo.FromPtrOr(opts.InterruptionQueueMaxReceives, 0),
+		WarmPools:                          lo.FromPtrOr(opts.WarmPools, false),
+		Hibernation:                        lo.FromPtrOr(opts.Hibernation, false),
+		InstanceTypeAllowList:              opts.InstanceTypeAllowList,
+		InstanceTypeDenyList:               opts.InstanceTypeDenyList,
+		TerminationApproval:                lo.FromPtrOr(opts.TerminationApproval, false),
+		TerminationNotificationEventBus:    lo.FromPtrOr(opts.TerminationNotificationEventBus, ""),
+		LaunchAuditLog:                     lo.FromPtrOr(opts.LaunchAuditLog, ""),
+		ZoneFailover:                       lo.FromPtrOr(opts.ZoneFailover, false),
+		NodeClaimLaunchOverrides:           lo.FromPtrOr(opts.NodeClaimLaunchOverrides, false),
+		NodeRepair:                         lo.FromPtrOr(opts.NodeRepair, false),
+		NodeRepairRebootAttempts:           lo.FromPtrOr(opts.NodeRepairRebootAttempts, 1),
+		NodeRepairRebootTimeout:            lo.FromPtrOr(opts.NodeRepairRebootTimeout, 5*time.Minute),
+		ReadOnly:                           lo.FromPtrOr(opts.ReadOnly, false),
+		AMIDeprecationWarningWindow:        lo.FromPtrOr(opts.AMIDeprecationWarningWindow, 14*24*time.Hour),
+		AMIDeprecationStrict:               lo.FromPtrOr(opts.AMIDeprecationStrict, false),
+		PodRestartCost:                     lo.FromPtrOr(opts.PodRestartCost, false),
+		Prewarm:                            lo.FromPtrOr(opts.Prewarm, false),
+		LaunchTemplateGarbageCollectionAge: lo.FromPtrOr(opts.LaunchTemplateGarbageCollectionAge, 0),
+		OutpostInstancePrices:              opts.OutpostInstancePrices,
+		ICEBackoffDurations:                opts.ICEBackoffDurations,
+		ICEBackoffMaxDurations:             opts.ICEBackoffMaxDurations,
 	}
 }
@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Client starts a span for each write that is made to the API server through the client. Reads are served from the
+// informer cache and aren't traced.
+type Client struct {
+	client.Client
+}
+
+func NewClient(kubeClient client.Client) *Client {
+	return &Client{Client: kubeClient}
+}
+
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) (err error) {
+	ctx, span := c.start(ctx, "Create", obj, "")
+	defer func() { End(span, err) }()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) (err error) {
+	ctx, span := c.start(ctx, "Update", obj, "")
+	defer func() { End(span, err) }()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) (err error) {
+	ctx, span := c.start(ctx, "Patch", obj, "")
+	defer func() { End(span, err) }()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) (err error) {
+	ctx, span := c.start(ctx, "Delete", obj, "")
+	defer func() { End(span, err) }()
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *Client) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) (err error) {
+	ctx, span := c.start(ctx, "DeleteAllOf", obj, "")
+	defer func() { End(span, err) }()
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *Client) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c *Client) SubResource(subResource string) client.SubResourceClient {
+	return &subResourceClient{SubResourceClient: c.Client.SubResource(subResource), client: c, subResource: subResource}
+}
+
+func (c *Client) start(ctx context.Context, verb string, obj client.Object, subResource string) (context.Context, trace.Span) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	name := "kube." + verb + " " + kind
+	if subResource != "" {
+		name += "/" + subResource
+	}
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("k8s.kind", kind),
+			attribute.String("k8s.namespace", obj.GetNamespace()),
+			attribute.String("k8s.name", obj.GetName()),
+		),
+	)
+}
+
+type subResourceClient struct {
+	client.SubResourceClient
+	client      *Client
+	subResource string
+}
+
+func (s *subResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) (err error) {
+	ctx, span := s.client.start(ctx, "Create", obj, s.subResource)
+	defer func() { End(span, err) }()
+	return s.SubResourceClient.Create(ctx, obj, subResource, opts...)
+}
+
+func (s *subResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) (err error) {
+	ctx, span := s.client.start(ctx, "Update", obj, s.subResource)
+	defer func() { End(span, err) }()
+	return s.SubResourceClient.Update(ctx, obj, opts...)
+}
+
+func (s *subResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) (err error) {
+	ctx, span := s.client.start(ctx, "Patch", obj, s.subResource)
+	defer func() { End(span, err) }()
+	return s.SubResourceClient.Patch(ctx, obj, patch, opts...)
+}
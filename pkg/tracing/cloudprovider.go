@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// CloudProvider starts a span for each call that the core controllers make to the cloud provider. The reconciles of the
+// core controllers aren't traced, so these spans are the roots that the AWS API calls of a launch or termination are
+// nested under.
+type CloudProvider struct {
+	cloudprovider.CloudProvider
+}
+
+func DecorateCloudProvider(cloudProvider cloudprovider.CloudProvider) cloudprovider.CloudProvider {
+	return &CloudProvider{CloudProvider: cloudProvider}
+}
+
+func (c *CloudProvider) Create(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (_ *v1beta1.NodeClaim, err error) {
+	ctx, span := Tracer().Start(ctx, "cloudprovider.Create", trace.WithAttributes(nodeClaimAttributes(nodeClaim)...))
+	defer func() { End(span, err) }()
+	return c.CloudProvider.Create(ctx, nodeClaim)
+}
+
+func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (err error) {
+	ctx, span := Tracer().Start(ctx, "cloudprovider.Delete", trace.WithAttributes(nodeClaimAttributes(nodeClaim)...))
+	defer func() { End(span, err) }()
+	return c.CloudProvider.Delete(ctx, nodeClaim)
+}
+
+func (c *CloudProvider) Get(ctx context.Context, providerID string) (_ *v1beta1.NodeClaim, err error) {
+	ctx, span := Tracer().Start(ctx, "cloudprovider.Get", trace.WithAttributes(attribute.String("provider_id", providerID)))
+	defer func() { End(span, err) }()
+	return c.CloudProvider.Get(ctx, providerID)
+}
+
+func (c *CloudProvider) List(ctx context.Context) (_ []*v1beta1.NodeClaim, err error) {
+	ctx, span := Tracer().Start(ctx, "cloudprovider.List")
+	defer func() { End(span, err) }()
+	return c.CloudProvider.List(ctx)
+}
+
+func (c *CloudProvider) GetInstanceTypes(ctx context.Context, nodePool *v1beta1.NodePool) (_ []*cloudprovider.InstanceType, err error) {
+	ctx, span := Tracer().Start(ctx, "cloudprovider.GetInstanceTypes", trace.WithAttributes(attribute.String("nodepool", nodePool.Name)))
+	defer func() { End(span, err) }()
+	return c.CloudProvider.GetInstanceTypes(ctx, nodePool)
+}
+
+func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *v1beta1.NodeClaim) (reason cloudprovider.DriftReason, err error) {
+	ctx, span := Tracer().Start(ctx, "cloudprovider.IsDrifted", trace.WithAttributes(nodeClaimAttributes(nodeClaim)...))
+	defer func() {
+		span.SetAttributes(attribute.String("drift_reason", string(reason)))
+		End(span, err)
+	}()
+	return c.CloudProvider.IsDrifted(ctx, nodeClaim)
+}
+
+func nodeClaimAttributes(nodeClaim *v1beta1.NodeClaim) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("nodeclaim", nodeClaim.Name),
+		attribute.String("nodepool", nodeClaim.Labels[v1beta1.NodePoolLabelKey]),
+		attribute.String("provider_id", nodeClaim.Status.ProviderID),
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type reconciler struct {
+	name       string
+	reconciler reconcile.Reconciler
+}
+
+// Reconciler starts a span for each reconcile of the named controller. AWS API calls and API server writes that are
+// made with the context of the reconcile are nested under it.
+func Reconciler(name string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &reconciler{name: name, reconciler: r}
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (result reconcile.Result, err error) {
+	ctx, span := Tracer().Start(ctx, "reconcile "+r.name, trace.WithAttributes(
+		attribute.String("controller", r.name),
+		attribute.String("k8s.namespace", req.Namespace),
+		attribute.String("k8s.name", req.Name),
+	))
+	defer func() {
+		span.SetAttributes(
+			attribute.Bool("requeue", result.Requeue || result.RequeueAfter > 0),
+			attribute.String("requeue_after", result.RequeueAfter.String()),
+		)
+		End(span, err)
+	}()
+	return r.reconciler.Reconcile(ctx, req)
+}
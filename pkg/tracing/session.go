@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// awsSpanKey is the context key of the span of an AWS API call. The span is looked up through its own key rather than
+// trace.SpanFromContext, so that a request that fails before it is built doesn't end the span of its caller.
+type awsSpanKey struct{}
+
+// WithTracing starts a span for each AWS API call that is made through the session. The span is a child of the span
+// in the context of the call, so calls that are made with the context of a reconcile are nested under it.
+func WithTracing(sess *session.Session) *session.Session {
+	sess.Handlers.Build.PushFrontNamed(request.NamedHandler{
+		Name: "karpenter.tracing.StartSpan",
+		Fn: func(r *request.Request) {
+			ctx, span := Tracer().Start(r.Context(), fmt.Sprintf("%s.%s", r.ClientInfo.ServiceID, r.Operation.Name),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.RPCSystemKey.String("aws-api"),
+					semconv.RPCService(r.ClientInfo.ServiceID),
+					semconv.RPCMethod(r.Operation.Name),
+				),
+			)
+			r.SetContext(context.WithValue(ctx, awsSpanKey{}, span))
+		},
+	})
+	sess.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "karpenter.tracing.EndSpan",
+		Fn: func(r *request.Request) {
+			span, ok := r.Context().Value(awsSpanKey{}).(trace.Span)
+			if !ok {
+				return
+			}
+			span.SetAttributes(
+				attribute.String("aws.request_id", r.RequestID),
+				attribute.Int("aws.retry_count", r.RetryCount),
+			)
+			if r.HTTPResponse != nil {
+				span.SetAttributes(semconv.HTTPStatusCode(r.HTTPResponse.StatusCode))
+			}
+			End(span, r.Error)
+		},
+	})
+	return sess
+}
+
+// WithTracingV2 starts a span for each call of the aws-sdk-go-v2 clients created from the config, like WithTracing does
+// for the calls made through a session. The span covers every attempt of the call.
+func WithTracingV2(cfg awsv2.Config) awsv2.Config {
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("karpenter.tracing.Span",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (out middleware.InitializeOutput, metadata middleware.Metadata, err error) {
+				service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+				ctx, span := Tracer().Start(ctx, fmt.Sprintf("%s.%s", service, operation),
+					trace.WithSpanKind(trace.SpanKindClient),
+					trace.WithAttributes(
+						semconv.RPCSystemKey.String("aws-api"),
+						semconv.RPCService(service),
+						semconv.RPCMethod(operation),
+					),
+				)
+				defer func() { End(span, err) }()
+				out, metadata, err = next.HandleInitialize(ctx, in)
+				if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+					span.SetAttributes(attribute.String("aws.request_id", requestID))
+				}
+				if response, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+					span.SetAttributes(semconv.HTTPStatusCode(response.StatusCode))
+				}
+				return out, metadata, err
+			}), middleware.After)
+	})
+	return cfg
+}
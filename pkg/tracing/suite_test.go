@@ -0,0 +1,208 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	corecloudproviderfake "sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/tracing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var recorder *tracetest.SpanRecorder
+var ec2api *ec2.EC2
+
+func TestTracing(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tracing")
+}
+
+var _ = BeforeEach(func() {
+	recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// EC2 calls fail with a dry run error rather than being sent
+	ec2api = ec2.New(tracing.WithTracing(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	}))))
+	ec2api.Handlers.Send.Clear()
+	ec2api.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusPreconditionFailed, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+		r.Error = awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+	})
+})
+
+var _ = Describe("Tracing", func() {
+	Context("Reconciler", func() {
+		It("should start a span for each reconcile", func() {
+			r := tracing.Reconciler("test.controller", reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{Requeue: true}, nil
+			}))
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name()).To(Equal("reconcile test.controller"))
+			Expect(spans[0].Attributes()).To(ContainElements(
+				attribute.String("controller", "test.controller"),
+				attribute.String("k8s.namespace", "default"),
+				attribute.String("k8s.name", "test"),
+				attribute.Bool("requeue", true),
+			))
+			Expect(spans[0].Status().Code).To(Equal(codes.Unset))
+		})
+		It("should record the error of a reconcile", func() {
+			r := tracing.Reconciler("test.controller", reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				return reconcile.Result{}, fmt.Errorf("failed")
+			}))
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+			Expect(spans[0].Status().Description).To(Equal("failed"))
+		})
+	})
+	Context("Session", func() {
+		It("should nest spans of AWS API calls under the span of the reconcile", func() {
+			r := tracing.Reconciler("test.controller", reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				_, err := ec2api.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{})
+				return reconcile.Result{}, err
+			}))
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name()).To(Equal("EC2.DescribeInstances"))
+			Expect(spans[0].Attributes()).To(ContainElements(
+				attribute.String("rpc.system", "aws-api"),
+				attribute.String("rpc.service", "EC2"),
+				attribute.String("rpc.method", "DescribeInstances"),
+				attribute.Int("http.status_code", http.StatusPreconditionFailed),
+			))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+			Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+			Expect(spans[1].Name()).To(Equal("reconcile test.controller"))
+		})
+		It("should not end the span of the caller when an AWS API call fails validation", func() {
+			spanCtx, span := tracing.Tracer().Start(ctx, "caller")
+			_, err := ec2api.TerminateInstancesWithContext(spanCtx, &ec2.TerminateInstancesInput{})
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Ended()).To(BeEmpty())
+			span.End()
+			Expect(recorder.Ended()).To(HaveLen(1))
+		})
+	})
+	Context("CloudProvider", func() {
+		It("should start a span for each call to the cloud provider", func() {
+			nodeClaim := coretest.NodeClaim(corev1beta1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{corev1beta1.NodePoolLabelKey: "default"}},
+				Status:     corev1beta1.NodeClaimStatus{ProviderID: "aws:///us-west-2a/i-01234567890123456"},
+			})
+			cloudProvider := tracing.DecorateCloudProvider(corecloudproviderfake.NewCloudProvider())
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).ToNot(Succeed())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name()).To(Equal("cloudprovider.Delete"))
+			Expect(spans[0].Attributes()).To(ContainElements(
+				attribute.String("nodeclaim", nodeClaim.Name),
+				attribute.String("nodepool", "default"),
+				attribute.String("provider_id", "aws:///us-west-2a/i-01234567890123456"),
+			))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		})
+	})
+	Context("Client", func() {
+		var kubeClient client.Client
+		var pod *v1.Pod
+
+		BeforeEach(func() {
+			pod = &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+			kubeClient = tracing.NewClient(crfake.NewClientBuilder().WithStatusSubresource(&v1.Pod{}).Build())
+		})
+		It("should start a span for each write", func() {
+			Expect(kubeClient.Create(ctx, pod)).To(Succeed())
+			Expect(kubeClient.Delete(ctx, pod)).To(Succeed())
+
+			spans := recorder.Ended()
+			Expect(lo.Map(spans, func(s sdktrace.ReadOnlySpan, _ int) string { return s.Name() })).To(Equal([]string{"kube.Create Pod", "kube.Delete Pod"}))
+			Expect(spans[0].Attributes()).To(ContainElements(
+				attribute.String("k8s.kind", "Pod"),
+				attribute.String("k8s.namespace", "default"),
+				attribute.String("k8s.name", "test"),
+			))
+		})
+		It("should start a span for each status write", func() {
+			Expect(kubeClient.Create(ctx, pod)).To(Succeed())
+			stored := pod.DeepCopy()
+			pod.Status.Phase = v1.PodRunning
+			Expect(kubeClient.Status().Patch(ctx, pod, client.MergeFrom(stored))).To(Succeed())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[1].Name()).To(Equal("kube.Patch Pod/status"))
+		})
+		It("should not start a span for reads", func() {
+			Expect(kubeClient.Create(ctx, pod)).To(Succeed())
+			Expect(kubeClient.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+			Expect(recorder.Ended()).To(HaveLen(1))
+		})
+		It("should record the error of a write", func() {
+			Expect(kubeClient.Delete(ctx, pod)).ToNot(Succeed())
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		})
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// instrumentationName is the name of the tracer that all spans are started from
+const instrumentationName = "github.com/aws/karpenter-provider-aws"
+
+// shutdownTimeout bounds the time spent flushing buffered spans when the operator exits
+const shutdownTimeout = 5 * time.Second
+
+// ClusterNameAttribute is the resource attribute that identifies the cluster of the exported spans
+const ClusterNameAttribute = attribute.Key("k8s.cluster.name")
+
+// Tracer returns the tracer that spans are started from. Spans aren't recorded unless Start has registered a tracer
+// provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start registers a tracer provider that exports spans to the OTLP collector at the tracing endpoint, and flushes it
+// when the context is done. Exporter settings that aren't covered by the operator's options, like headers and TLS,
+// are read from the standard OTEL_EXPORTER_OTLP_* environment variables.
+func Start(ctx context.Context) error {
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(options.FromContext(ctx).TracingEndpoint))
+	if err != nil {
+		return fmt.Errorf("creating otlp trace exporter, %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.FromContext(ctx).TracingSampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("karpenter"),
+			ClusterNameAttribute.String(options.FromContext(ctx).ClusterName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			log.FromContext(ctx).Error(err, "failed flushing spans")
+		}
+	}()
+	return nil
+}
+
+// End records the error, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
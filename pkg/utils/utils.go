@@ -15,19 +15,39 @@ limitations under the License.
 package utils
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
 
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/samber/lo"
 )
 
 var (
-	instanceIDRegex = regexp.MustCompile(`aws:///(?P<AZ>.*)/(?P<InstanceID>.*)`)
+	// The host is empty in provider IDs set by the cloud-controller-manager, but some node bootstrappers set it to the region
+	instanceIDRegex = regexp.MustCompile(`aws://[^/]*/(?P<AZ>.*)/(?P<InstanceID>.*)`)
+	// partitionPrefixes map region prefixes to partitions that the SDK's endpoint model doesn't know about yet
+	partitionPrefixes = map[string]string{
+		"cn-":      endpoints.AwsCnPartitionID,
+		"us-gov-":  endpoints.AwsUsGovPartitionID,
+		"us-iso-":  endpoints.AwsIsoPartitionID,
+		"us-isob-": endpoints.AwsIsoBPartitionID,
+		"eu-isoe-": endpoints.AwsIsoEPartitionID,
+		"us-isof-": endpoints.AwsIsoFPartitionID,
+	}
 )
 
+// ProviderID returns the provider ID of an instance. The cloud-controller-manager uses the aws scheme in every
+// partition, so the partition isn't part of the provider ID.
+func ProviderID(zone, instanceID string) string {
+	return fmt.Sprintf("aws:///%s/%s", zone, instanceID)
+}
+
 // ParseInstanceID parses the provider ID stored on the node to get the instance ID
 // associated with a node
 func ParseInstanceID(providerID string) (string, error) {
@@ -43,6 +63,42 @@ func ParseInstanceID(providerID string) (string, error) {
 	return "", fmt.Errorf("parsing instance id %s", providerID)
 }
 
+// Partition returns the partition of the region, e.g. aws-us-gov for us-gov-west-1
+func Partition(region string) string {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition.ID()
+	}
+	for prefix, partition := range partitionPrefixes {
+		if strings.HasPrefix(region, prefix) {
+			return partition
+		}
+	}
+	return endpoints.AwsPartitionID
+}
+
+// ServicePrincipal returns the principal of an AWS service in the partition of the region, e.g. ec2.amazonaws.com.cn
+// for cn-north-1. The service principals of the other partitions, including GovCloud, use the amazonaws.com suffix.
+func ServicePrincipal(service, region string) string {
+	if Partition(region) == endpoints.AwsCnPartitionID {
+		return fmt.Sprintf("%s.amazonaws.com.cn", service)
+	}
+	return fmt.Sprintf("%s.amazonaws.com", service)
+}
+
+// OutpostID returns the ID of an Outpost, e.g. op-0123456789abcdef0, from its ARN, or an empty string if the ARN is empty
+func OutpostID(outpostARN string) string {
+	_, id, _ := strings.Cut(outpostARN, "/")
+	return id
+}
+
+// ActionV2 returns the IAM action, e.g. ssm:GetParameter, of the aws-sdk-go-v2 call that the middleware context
+// belongs to. The service prefix of the action is the lowercase service ID without spaces, which matches the prefix for
+// the services whose clients have been migrated to aws-sdk-go-v2.
+func ActionV2(ctx context.Context) string {
+	service := strings.ToLower(strings.ReplaceAll(awsmiddleware.GetServiceID(ctx), " ", ""))
+	return fmt.Sprintf("%s:%s", service, awsmiddleware.GetOperationName(ctx))
+}
+
 // MergeTags takes a variadic list of maps and merges them together into a list of
 // EC2 tags to be passed into EC2 API calls
 func MergeTags(tags ...map[string]string) []*ec2.Tag {
@@ -51,6 +107,13 @@ func MergeTags(tags ...map[string]string) []*ec2.Tag {
 	})
 }
 
+// MergeTagsV2 merges the maps into a list of the EC2 tags of aws-sdk-go-v2 calls
+func MergeTagsV2(tags ...map[string]string) []ec2types.Tag {
+	return lo.MapToSlice(lo.Assign(tags...), func(k, v string) ec2types.Tag {
+		return ec2types.Tag{Key: aws.String(k), Value: aws.String(v)}
+	})
+}
+
 // PrettySlice truncates a slice after a certain number of max items to ensure
 // that the Slice isn't too long
 func PrettySlice[T any](s []T, maxItems int) string {
@@ -28,7 +28,6 @@ import (
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
-	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -100,9 +99,9 @@ var _ = Describe("Tags", func() {
 				g.Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationInstanceTagged, "true"))
 			}, time.Minute)
 
-			nodeInstance := instance.NewInstance(lo.ToPtr(env.GetInstance(node.Name)))
-			Expect(nodeInstance.Tags).To(HaveKeyWithValue("Name", node.Name))
-			Expect(nodeInstance.Tags).To(HaveKey("karpenter.sh/nodeclaim"))
+			nodeInstanceTags := tagMap(env.GetInstance(node.Name).Tags)
+			Expect(nodeInstanceTags).To(HaveKeyWithValue("Name", node.Name))
+			Expect(nodeInstanceTags).To(HaveKey("karpenter.sh/nodeclaim"))
 		})
 
 		It("shouldn't overwrite custom Name tags", func() {
@@ -135,9 +134,9 @@ var _ = Describe("Tags", func() {
 				g.Expect(node.Annotations).To(HaveKeyWithValue(v1beta1.AnnotationInstanceTagged, "true"))
 			}, time.Minute)
 
-			nodeInstance := instance.NewInstance(lo.ToPtr(env.GetInstance(node.Name)))
-			Expect(nodeInstance.Tags).To(HaveKeyWithValue("Name", "custom-name"))
-			Expect(nodeInstance.Tags).To(HaveKey("karpenter.sh/nodeclaim"))
+			nodeInstanceTags := tagMap(env.GetInstance(node.Name).Tags)
+			Expect(nodeInstanceTags).To(HaveKeyWithValue("Name", "custom-name"))
+			Expect(nodeInstanceTags).To(HaveKey("karpenter.sh/nodeclaim"))
 		})
 	})
 })
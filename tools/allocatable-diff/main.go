@@ -78,6 +78,9 @@ func main() {
 		op.GetClient(),
 		op.AMIProvider,
 		op.SecurityGroupProvider,
+		op.LaunchRamps,
+		op.TerminationHookProvider,
+		op.Clock,
 	)
 	instanceTypes := lo.Must(cloudProvider.GetInstanceTypes(ctx, nil))
 
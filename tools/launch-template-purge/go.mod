@@ -0,0 +1,7 @@
+module github.com/aws/karpenter-provider-aws/tools/launch-template-purge
+
+go 1.22.4
+
+require github.com/aws/aws-sdk-go v1.54.6
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
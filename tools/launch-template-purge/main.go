@@ -0,0 +1,101 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+// tagManagedLaunchTemplate is the tag that Karpenter adds to the launch templates that it creates, with the name of the
+// cluster as its value
+const tagManagedLaunchTemplate = "karpenter.k8s.aws/cluster"
+
+var clusterName string
+var region string
+var dryRun bool
+var force bool
+
+func init() {
+	flag.StringVar(&clusterName, "cluster-name", "", "name of the cluster whose launch templates are deleted")
+	flag.StringVar(&region, "region", "", "region of the cluster, defaults to the region of the AWS configuration")
+	flag.BoolVar(&dryRun, "dry-run", true, "list the launch templates that would be deleted without deleting them")
+	flag.BoolVar(&force, "force", false, "delete the launch templates even if the cluster still exists")
+	flag.Parse()
+}
+
+func main() {
+	if clusterName == "" {
+		log.Fatalf("cluster name cannot be empty")
+	}
+	ctx := context.Background()
+	config := aws.Config{}
+	if region != "" {
+		config.Region = aws.String(region)
+	}
+	sess := session.Must(session.NewSessionWithOptions(session.Options{Config: config, SharedConfigState: session.SharedConfigEnable}))
+
+	// The launch templates of a cluster that still exists may be in use by its Karpenter
+	if _, err := eks.New(sess).DescribeClusterWithContext(ctx, &eks.DescribeClusterInput{Name: aws.String(clusterName)}); err == nil {
+		if !force {
+			log.Fatalf("cluster %q still exists, use --force to delete its launch templates anyway", clusterName)
+		}
+	} else if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != eks.ErrCodeResourceNotFoundException {
+		if !force {
+			log.Fatalf("describing cluster %q, %s", clusterName, err)
+		}
+	}
+
+	ec2api := ec2.New(sess)
+	var launchTemplates []*ec2.LaunchTemplate
+	if err := ec2api.DescribeLaunchTemplatesPagesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{{Name: aws.String(fmt.Sprintf("tag:%s", tagManagedLaunchTemplate)), Values: []*string{aws.String(clusterName)}}},
+	}, func(output *ec2.DescribeLaunchTemplatesOutput, _ bool) bool {
+		launchTemplates = append(launchTemplates, output.LaunchTemplates...)
+		return true
+	}); err != nil {
+		log.Fatalf("describing launch templates, %s", err)
+	}
+
+	var failed int
+	for _, lt := range launchTemplates {
+		if dryRun {
+			fmt.Printf("would delete %s (%s)\n", aws.StringValue(lt.LaunchTemplateName), aws.StringValue(lt.LaunchTemplateId))
+			continue
+		}
+		if _, err := ec2api.DeleteLaunchTemplateWithContext(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateId: lt.LaunchTemplateId}); err != nil {
+			log.Printf("deleting launch template %s, %s", aws.StringValue(lt.LaunchTemplateName), err)
+			failed++
+			continue
+		}
+		fmt.Printf("deleted %s (%s)\n", aws.StringValue(lt.LaunchTemplateName), aws.StringValue(lt.LaunchTemplateId))
+	}
+	if dryRun {
+		fmt.Printf("found %d launch templates for cluster %q, run with --dry-run=false to delete them\n", len(launchTemplates), clusterName)
+		return
+	}
+	if failed > 0 {
+		log.Fatalf("failed to delete %d of %d launch templates", failed, len(launchTemplates))
+	}
+	fmt.Printf("deleted %d launch templates for cluster %q\n", len(launchTemplates), clusterName)
+}
@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	coreoperator "sigs.k8s.io/karpenter/pkg/operator"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/unavailableofferings"
+	"github.com/aws/karpenter-provider-aws/pkg/operator"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+)
+
+var clusterName string
+var nodePoolFile string
+var namespace string
+var output string
+var outFile string
+
+func init() {
+	flag.StringVar(&clusterName, "cluster-name", "", "cluster name to use when discovering the subnets and pricing of instance types")
+	flag.StringVar(&nodePoolFile, "nodepool", "", "file that holds the NodePool spec to report the instance types of, whose EC2NodeClass must exist in the cluster")
+	flag.StringVar(&namespace, "namespace", "karpenter", "namespace of Karpenter, where it persists the offerings that recently returned insufficient capacity errors")
+	flag.StringVar(&output, "output", "csv", "format of the report, csv or json")
+	flag.StringVar(&outFile, "out-file", "", "file to output the report to, instead of stdout")
+	flag.Parse()
+}
+
+func main() {
+	if clusterName == "" {
+		log.Fatalf("cluster name cannot be empty")
+	}
+	if nodePoolFile == "" {
+		log.Fatalf("nodepool cannot be empty")
+	}
+	if output != "csv" && output != "json" {
+		log.Fatalf("output must be csv or json")
+	}
+	nodePool := &corev1beta1.NodePool{}
+	lo.Must0(yaml.UnmarshalStrict(lo.Must(os.ReadFile(nodePoolFile)), nodePool))
+	if nodePool.Spec.Template.Spec.NodeClassRef == nil {
+		log.Fatalf("nodepool must reference an EC2NodeClass")
+	}
+
+	restConfig := config.GetConfigOrDie()
+	kubeClient := lo.Must(client.New(restConfig, client.Options{}))
+	ctx := context.Background()
+	ctx = options.ToContext(ctx, &options.Options{ClusterName: clusterName})
+
+	nodeClass := &v1beta1.EC2NodeClass{}
+	lo.Must0(kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass))
+
+	ctx, op := operator.NewOperator(ctx, &coreoperator.Operator{
+		Manager:             lo.Must(manager.New(restConfig, manager.Options{})),
+		KubernetesInterface: kubernetes.NewForConfigOrDie(restConfig),
+	})
+	lo.Must0(op.PricingProvider.UpdateOnDemandPricing(ctx))
+	lo.Must0(op.PricingProvider.UpdateSpotPricing(ctx))
+	lo.Must0(op.InstanceTypesProvider.UpdateInstanceTypes(ctx))
+	lo.Must0(op.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx))
+	instanceTypes := lo.Must(op.InstanceTypesProvider.List(ctx, nodePool.Spec.Template.Spec.Kubelet, nodeClass))
+	instanceTypes = instancetype.NewSpotMaxPrice(ctx, nodePool.Spec.Template.Annotations).Apply(instanceTypes)
+
+	rows := Report(nodePool, instanceTypes, unavailableOfferings(ctx, kubeClient))
+
+	var w io.Writer = os.Stdout
+	if outFile != "" {
+		file := lo.Must(os.Create(outFile))
+		defer file.Close()
+		w = file
+	}
+	if output == "json" {
+		lo.Must0(WriteJSON(w, rows))
+	} else {
+		lo.Must0(WriteCSV(w, rows))
+	}
+}
+
+// unavailableOfferings returns the offerings that Karpenter has persisted from its insufficient capacity error cache,
+// or none if Karpenter hasn't persisted any
+func unavailableOfferings(ctx context.Context, kubeClient client.Client) []cache.UnavailableOffering {
+	cm := &v1.ConfigMap{}
+	if err := kubeClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: unavailableofferings.ConfigMapName}, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.Fatalf("getting unavailable offerings configmap, %s", err)
+	}
+	var offerings []cache.UnavailableOffering
+	if data := cm.Data[unavailableofferings.ConfigMapKey]; data != "" {
+		lo.Must0(json.Unmarshal([]byte(data), &offerings))
+	}
+	return offerings
+}
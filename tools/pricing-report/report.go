@@ -0,0 +1,159 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	corev1beta1 "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+// Row is the pricing of an instance type in a zone that the NodePool can launch
+type Row struct {
+	InstanceType string  `json:"instanceType"`
+	Zone         string  `json:"zone"`
+	VCPU         int64   `json:"vcpu"`
+	MemoryGiB    float64 `json:"memoryGiB"`
+	// Prices are hourly, and are only set for the capacity types that the NodePool can launch and that are offered in
+	// the zone
+	OnDemandPrice        *float64 `json:"onDemandPrice,omitempty"`
+	OnDemandPricePerVCPU *float64 `json:"onDemandPricePerVCPU,omitempty"`
+	SpotPrice            *float64 `json:"spotPrice,omitempty"`
+	SpotPricePerVCPU     *float64 `json:"spotPricePerVCPU,omitempty"`
+	// UnavailableUntil are the times until which Karpenter won't launch the offerings of the capacity types, because
+	// they recently returned insufficient capacity errors
+	OnDemandUnavailableUntil *time.Time `json:"onDemandUnavailableUntil,omitempty"`
+	SpotUnavailableUntil     *time.Time `json:"spotUnavailableUntil,omitempty"`
+}
+
+// Report returns a row for each zone of each instance type that the NodePool can launch, sorted by instance type and
+// zone. The unavailable offerings are those that Karpenter persists from its insufficient capacity error cache.
+func Report(nodePool *corev1beta1.NodePool, instanceTypes []*cloudprovider.InstanceType, unavailable []cache.UnavailableOffering) []Row {
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+	requirements.Add(scheduling.NewLabelRequirements(nodePool.Spec.Template.Labels).Values()...)
+	unavailableUntil := lo.SliceToMap(unavailable, func(o cache.UnavailableOffering) (string, time.Time) {
+		return offeringKey(o.InstanceType, o.Zone, o.CapacityType), o.Expiration
+	})
+
+	var rows []Row
+	for _, it := range instanceTypes {
+		if it.Requirements.Compatible(requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
+			continue
+		}
+		vcpu := it.Capacity.Cpu().Value()
+		zones := map[string]*Row{}
+		for _, offering := range it.Offerings {
+			if requirements.Compatible(offering.Requirements, scheduling.AllowUndefinedWellKnownLabels) != nil {
+				continue
+			}
+			zone := offering.Requirements.Get(v1.LabelTopologyZone).Any()
+			capacityType := offering.Requirements.Get(corev1beta1.CapacityTypeLabelKey).Any()
+			row, ok := zones[zone]
+			if !ok {
+				row = &Row{
+					InstanceType: it.Name,
+					Zone:         zone,
+					VCPU:         vcpu,
+					MemoryGiB:    float64(it.Capacity.Memory().Value()) / (1 << 30),
+				}
+				zones[zone] = row
+			}
+			price, perVCPU := lo.ToPtr(offering.Price), lo.Ternary(vcpu > 0, lo.ToPtr(offering.Price/float64(vcpu)), nil)
+			var until *time.Time
+			if expiration, ok := unavailableUntil[offeringKey(it.Name, zone, capacityType)]; ok {
+				until = lo.ToPtr(expiration)
+			}
+			switch capacityType {
+			case corev1beta1.CapacityTypeOnDemand:
+				row.OnDemandPrice, row.OnDemandPricePerVCPU, row.OnDemandUnavailableUntil = price, perVCPU, until
+			case corev1beta1.CapacityTypeSpot:
+				row.SpotPrice, row.SpotPricePerVCPU, row.SpotUnavailableUntil = price, perVCPU, until
+			}
+		}
+		for _, row := range zones {
+			rows = append(rows, *row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].InstanceType != rows[j].InstanceType {
+			return rows[i].InstanceType < rows[j].InstanceType
+		}
+		return rows[i].Zone < rows[j].Zone
+	})
+	return rows
+}
+
+// WriteCSV writes the rows with a header, leaving the prices of capacity types that aren't offered empty
+func WriteCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Instance Type", "Zone", "vCPU", "Memory (GiB)", "On-Demand Price", "On-Demand Price per vCPU",
+		"Spot Price", "Spot Price per vCPU", "On-Demand Unavailable Until", "Spot Unavailable Until"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write([]string{
+			row.InstanceType,
+			row.Zone,
+			fmt.Sprint(row.VCPU),
+			fmt.Sprintf("%.2f", row.MemoryGiB),
+			formatPrice(row.OnDemandPrice),
+			formatPrice(row.OnDemandPricePerVCPU),
+			formatPrice(row.SpotPrice),
+			formatPrice(row.SpotPricePerVCPU),
+			formatTime(row.OnDemandUnavailableUntil),
+			formatTime(row.SpotUnavailableUntil),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the rows as an indented JSON array
+func WriteJSON(w io.Writer, rows []Row) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(lo.Ternary(rows == nil, []Row{}, rows))
+}
+
+func offeringKey(instanceType, zone, capacityType string) string {
+	return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
+}
+
+func formatPrice(price *float64) string {
+	if price == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.5f", *price)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -42,6 +42,7 @@ cat << EOF > controller-policy.json
                 "ec2:CreateLaunchTemplate",
                 "ec2:CreateFleet",
                 "ec2:DescribeSpotPriceHistory",
+                "ec2:GetSpotPlacementScores",
                 "pricing:GetProducts"
             ],
             "Effect": "Allow",